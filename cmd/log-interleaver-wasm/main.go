@@ -0,0 +1,122 @@
+//go:build js && wasm
+
+// Command log-interleaver-wasm is the browser build of log-interleaver. It registers a global
+// logInterleaver object whose functions interleave and plot files dropped into a page, entirely
+// client-side (see web/index.html):
+//
+//	const result = await logInterleaver.interleave(files, {config: yaml, timeFormat: ""})
+//	const plot = await logInterleaver.plot(files, {config: yaml, presets: "ptp4l"})
+//
+// Files are objects {name, data, lastModified}, with data a Uint8Array or string and
+// lastModified in milliseconds since the epoch (as File.lastModified). The functions return
+// promises of {output, lines, tags, html}, rejected with an Error on failure.
+//
+// Build with:
+//
+//	GOOS=js GOARCH=wasm go build -o web/log-interleaver.wasm ./cmd/log-interleaver-wasm
+package main
+
+import (
+	"context"
+	"log-interleaver/internal/web"
+	"syscall/js"
+	"time"
+	_ "time/tzdata" // Timezones of the config, which the browser sandbox has no database of
+)
+
+func main() {
+	js.Global().Set("logInterleaver", js.ValueOf(map[string]any{
+		"interleave": js.FuncOf(func(this js.Value, args []js.Value) any {
+			return run(args, web.Interleave)
+		}),
+		"plot": js.FuncOf(func(this js.Value, args []js.Value) any {
+			return run(args, web.Plot)
+		}),
+	}))
+	// Keep the program running for the callbacks
+	select {}
+}
+
+// run calls fn with the files and options of the arguments on its own goroutine, since a
+// callback blocking the event loop cannot wait for other goroutines, and returns a promise of
+// its result
+func run(args []js.Value, fn func(context.Context, []web.File, web.Options) (*web.Result, error)) js.Value {
+	var files []web.File
+	var opts web.Options
+	if len(args) > 0 {
+		files = convertFiles(args[0])
+	}
+	if len(args) > 1 {
+		opts = convertOptions(args[1])
+	}
+
+	executor := js.FuncOf(func(this js.Value, promise []js.Value) any {
+		resolve, reject := promise[0], promise[1]
+		go func() {
+			result, err := fn(context.Background(), files, opts)
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			tags := make([]any, len(result.Tags))
+			for k, tag := range result.Tags {
+				tags[k] = tag
+			}
+			resolve.Invoke(js.ValueOf(map[string]any{
+				"output": result.Output,
+				"lines":  result.Lines,
+				"tags":   tags,
+				"html":   result.HTML,
+			}))
+		}()
+		return nil
+	})
+	defer executor.Release()
+	return js.Global().Get("Promise").New(executor)
+}
+
+// convertFiles copies the file objects of an array
+func convertFiles(array js.Value) []web.File {
+	if array.Type() != js.TypeObject {
+		return nil
+	}
+	files := make([]web.File, array.Length())
+	for k := range files {
+		v := array.Index(k)
+		files[k].Name = stringField(v, "name")
+		switch data := v.Get("data"); data.Type() {
+		case js.TypeString:
+			files[k].Data = []byte(data.String())
+		case js.TypeObject:
+			files[k].Data = make([]byte, data.Get("length").Int())
+			js.CopyBytesToGo(files[k].Data, data)
+		}
+		if ms := v.Get("lastModified"); ms.Type() == js.TypeNumber {
+			files[k].ModTime = time.UnixMilli(int64(ms.Float()))
+		}
+	}
+	return files
+}
+
+// convertOptions reads the options object
+func convertOptions(v js.Value) web.Options {
+	if v.Type() != js.TypeObject {
+		return web.Options{}
+	}
+	return web.Options{
+		Config:     stringField(v, "config"),
+		Presets:    stringField(v, "presets"),
+		TimeFormat: stringField(v, "timeFormat"),
+		Tags:       stringField(v, "tags"),
+		Grep:       stringField(v, "grep"),
+		Theme:      stringField(v, "theme"),
+	}
+}
+
+// stringField returns a string property, or "" if it is not a string
+func stringField(v js.Value, name string) string {
+	if field := v.Get(name); field.Type() == js.TypeString {
+		return field.String()
+	}
+	return ""
+}
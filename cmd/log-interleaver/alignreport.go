@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/sink"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// writeAlignReport writes the alignment of the last process run to the -align-report location,
+// or to stderr for -, so it does not mix with output written to stdout
+func (o *inputOptions) writeAlignReport(iv *interleaver.Interleaver) error {
+	if o.alignReport == "" {
+		return nil
+	}
+	if o.alignReport == "-" {
+		printAlignReport(os.Stderr, iv)
+		return nil
+	}
+	out, err := sink.Open(o.alignReport)
+	if err != nil {
+		return fmt.Errorf("failed to create alignment report: %w", err)
+	}
+	defer out.Close()
+	printAlignReport(out, iv)
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write alignment report: %w", err)
+	}
+	return nil
+}
+
+// printAlignReport prints the reference tag and, per tag, the offset applied, how it was
+// determined, and the evidence it is based on
+func printAlignReport(w io.Writer, iv *interleaver.Interleaver) {
+	reference := iv.ReferenceTag()
+	if reference == "" {
+		reference = "none"
+	}
+	fmt.Fprintf(w, "Alignment reference: %s\n\n", reference)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Tag\tOffset\tDrift\tSource\tEvidence\n")
+	for _, o := range iv.Offsets() {
+		offset := o.Offset.String()
+		if o.Offset > 0 {
+			offset = "+" + offset
+		}
+		drift := ""
+		if o.DriftPPM != 0 {
+			drift = strconv.FormatFloat(o.DriftPPM, 'f', 2, 64) + " ppm"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", o.Tag, offset, drift, o.Source, o.Evidence)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,766 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runAnalyze prints statistics about the interleaved logs
+func runAnalyze(ctx context.Context, args []string) error {
+	fs := newFlagSet("analyze", "Print statistics about the interleaved logs: line counts per tag, timestamp\ncoverage, reordering, and metric extraction (when a config is available).")
+	input := addInputFlags(fs)
+	watch := addWatchFlags(fs)
+	output := fs.String("output", "-", "Output location for the analysis, or - for stdout")
+	format := fs.String("format", "text", "Analysis format: text, json, or yaml (pkg/report.AnalysisReport, for test automation)")
+	jsonOutput := fs.String("json", "", "Export analysis results as JSON (pkg/report.AnalysisReport)")
+	reorderWin := fs.Duration("reorder-window", 500*time.Millisecond, "Window for reordering out-of-order source lines")
+	gapThreshold := fs.Duration("gap-threshold", 0, "Report periods longer than this without lines from a tag as gaps (default: gap_threshold from the config, else 1m)")
+	burstGap := fs.Duration("burst-gap", analysis.DefaultBurstGap, "Group error and warning lines at most this far apart into one burst")
+	topBursts := fs.Int("top-bursts", 5, "Number of the largest error bursts to report (0 = all)")
+	stability := fs.Bool("stability", false, "Compute ADEV, MDEV, and TDEV of time error series (requires a config)")
+	stabilitySeries := fs.String("stability-series", "", "Comma-separated pattern names of time error series for -stability (default: te_report series from the config)")
+	taus := fs.String("tau", "", "Comma-separated observation intervals for -stability (e.g., 1s,10s,100s; default: octaves of the sample interval)")
+	phaseUnit := fs.String("phase-unit", "", "Unit of the time error values for -stability, -mtie, and -spectrum: ps, ns, us, ms, or s (default: the display unit of the series, or ns)")
+	stabilityPlot := fs.String("stability-plot", "", "Output location for a log-log plot of the -stability results (format from the extension)")
+	mtie := fs.Bool("mtie", false, "Compute the MTIE of time error series and check it against masks (requires a config)")
+	mtieSeries := fs.String("mtie-series", "", "Comma-separated pattern names of time error series for -mtie (default: te_report series from the config)")
+	mtieMasks := fs.String("mtie-masks", "class-c,class-d", "Comma-separated MTIE masks for -mtie: class-a, class-b, class-c, class-d, or masks from te_report.mtie_masks")
+	mtiePlot := fs.String("mtie-plot", "", "Output location for a log-log plot of the -mtie results with the masks (format from the extension)")
+	spectrum := fs.Bool("spectrum", false, "Compute the power spectral density of time error series and its peaks (requires a config)")
+	spectrumSeries := fs.String("spectrum-series", "", "Comma-separated pattern names of time error series for -spectrum (default: te_report series from the config)")
+	spectrumPlot := fs.String("spectrum-plot", "", "Output location for a log-log plot of the -spectrum results (format from the extension)")
+	correlate := fs.String("correlate", "", "Two comma-separated pattern names of series to correlate, reporting their Pearson correlation and cross-correlation lag (requires a config)")
+	maxLag := fs.Duration("max-lag", time.Minute, "Largest lag between the series to search with -correlate")
+	fs.Parse(args)
+	if *format != "text" && *format != "json" && *format != "yaml" {
+		return fmt.Errorf("invalid -format '%s', expected text, json, or yaml", *format)
+	}
+
+	return watch.run(ctx, input, func(ctx context.Context) error {
+		iv, cfg, err := input.newInterleaver()
+		if err != nil {
+			return err
+		}
+		iv.SetReorderWindow(*reorderWin)
+		lines, err := input.process(ctx, iv)
+		if err != nil {
+			return err
+		}
+
+		analysisReport, metrics, err := buildAnalysisReport(ctx, lines, iv.ReorderStats(), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to analyze logs: %w", err)
+		}
+		analysisReport.DuplicateLines = iv.Duplicates()
+
+		logger.Debug("Detecting gaps and error bursts")
+		if err := addAnomalies(analysisReport, lines, cfg, *gapThreshold, *burstGap, *topBursts); err != nil {
+			return err
+		}
+
+		if *stability {
+			logger.Debug("Computing stability")
+			if analysisReport.Stability, err = computeStability(metrics, cfg, *stabilitySeries, *taus, *phaseUnit); err != nil {
+				return fmt.Errorf("failed to compute stability: %w", err)
+			}
+			if *stabilityPlot != "" {
+				if err := visualizer.GenerateStabilityPlot(analysisReport.Stability, *stabilityPlot, ""); err != nil {
+					return fmt.Errorf("failed to generate stability plot: %w", err)
+				}
+				infof("Stability plot saved to: %s", *stabilityPlot)
+			}
+		}
+
+		if *mtie {
+			logger.Debug("Computing MTIE")
+			if analysisReport.MTIE, err = computeMTIE(metrics, cfg, *mtieSeries, *mtieMasks, *phaseUnit); err != nil {
+				return fmt.Errorf("failed to compute MTIE: %w", err)
+			}
+			if *mtiePlot != "" {
+				if err := visualizer.GenerateMTIEPlot(analysisReport.MTIE, *mtiePlot, ""); err != nil {
+					return fmt.Errorf("failed to generate MTIE plot: %w", err)
+				}
+				infof("MTIE plot saved to: %s", *mtiePlot)
+			}
+		}
+
+		if *spectrum {
+			logger.Debug("Computing spectrum")
+			if analysisReport.Spectrum, err = computeSpectrum(metrics, cfg, *spectrumSeries, *phaseUnit); err != nil {
+				return fmt.Errorf("failed to compute spectrum: %w", err)
+			}
+			if *spectrumPlot != "" {
+				if err := visualizer.GenerateSpectrumPlot(analysisReport.Spectrum, *spectrumPlot, ""); err != nil {
+					return fmt.Errorf("failed to generate spectrum plot: %w", err)
+				}
+				infof("Spectrum plot saved to: %s", *spectrumPlot)
+			}
+		}
+
+		if *correlate != "" {
+			logger.Debug("Computing correlation")
+			correlation, err := computeCorrelation(metrics, cfg, *correlate, *maxLag)
+			if err != nil {
+				return fmt.Errorf("failed to compute correlation: %w", err)
+			}
+			analysisReport.Correlations = append(analysisReport.Correlations, *correlation)
+		}
+
+		if *jsonOutput != "" {
+			if err := writeAnalysisJSON(analysisReport, *jsonOutput); err != nil {
+				return fmt.Errorf("failed to write analysis JSON: %w", err)
+			}
+			infof("Analysis exported to: %s", *jsonOutput)
+		}
+
+		out, err := sink.Open(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer out.Close()
+
+		switch *format {
+		case "json":
+			err = encodeAnalysisJSON(out, analysisReport)
+		case "yaml":
+			err = encodeYAML(out, analysisReport)
+		default:
+			analyzeLogs(analysisReport, cfg, out)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode analysis: %w", err)
+		}
+
+		// Closing the output flushes remote sinks (S3, HTTP PUT)
+		if err := out.Close(); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	})
+}
+
+// buildAnalysisReport builds the analysis report, including metric extraction statistics
+// when a config is available. The extracted metrics are returned for further analysis.
+func buildAnalysisReport(ctx context.Context, lines []*parser.LogLine, reorderStats map[string]interleaver.ReorderStats, cfg *config.VisualizationConfig) (*report.AnalysisReport, map[string][]pattern.MetricPoint, error) {
+	var metrics map[string][]pattern.MetricPoint
+	var extractionStats map[string]pattern.ExtractionStats
+	var seriesOrder []string
+	valueSeries := make(map[string]bool)
+	if cfg != nil && len(cfg.Patterns) > 0 {
+		logger.Debug("Extracting metrics", "patterns", len(cfg.Patterns))
+		var err error
+		if metrics, extractionStats, err = visualizer.ExtractMetricsWithStats(ctx, lines, cfg); err != nil {
+			return nil, nil, err
+		}
+		for _, p := range cfg.Patterns {
+			seriesOrder = append(seriesOrder, p.Name)
+			valueSeries[p.Name] = !p.IsEvent()
+		}
+	}
+
+	r := analysis.BuildReport(lines, reorderStats, metrics, extractionStats, seriesOrder, valueSeries)
+	var bmcaEvents []report.Event
+	r.Grandmasters, bmcaEvents = analysis.DetectBMCA(lines)
+	r.Events = append(r.Events, bmcaEvents...)
+	if cfg != nil {
+		for _, p := range cfg.Patterns {
+			if !p.HasStates() {
+				continue
+			}
+			if timeline := analysis.ComputeStateTimeline(p.Name, metrics[p.Name], p.LockedStates); timeline != nil {
+				r.States = append(r.States, *timeline)
+			}
+			phase := p.HoldoverPhase
+			if phase == "" && cfg.TEReport != nil && len(cfg.TEReport.Series) > 0 {
+				phase = cfg.TEReport.Series[0]
+			}
+			if holdovers := analysis.ComputeHoldovers(p.Name, metrics[p.Name], p.HoldoverStates, p.LockedStates, phase, metrics[phase]); holdovers != nil {
+				for _, q := range cfg.Patterns {
+					if q.Name == holdovers.PhaseSeries {
+						holdovers.PhaseUnit = q.DisplayUnit
+					}
+				}
+				r.Holdovers = append(r.Holdovers, *holdovers)
+			}
+		}
+	}
+	if cfg != nil && cfg.TEReport != nil && len(cfg.TEReport.Series) > 0 {
+		var err error
+		if r.TimeError, err = visualizer.ComputeTimeErrors(metrics, cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cfg != nil {
+		var err error
+		if r.Checks, err = evaluateAssertions(cfg, metrics); err != nil {
+			return nil, nil, err
+		}
+	}
+	return r, metrics, nil
+}
+
+// evaluateAssertions evaluates the assertions of the config against the extracted metrics
+func evaluateAssertions(cfg *config.VisualizationConfig, metrics map[string][]pattern.MetricPoint) ([]report.CheckResult, error) {
+	assertions, err := analysis.ParseAssertions(cfg.Assertions)
+	if err != nil {
+		return nil, err
+	}
+	var results []report.CheckResult
+	for _, a := range assertions {
+		if !hasPattern(cfg, a.Series) {
+			return nil, fmt.Errorf("assertion '%s': series '%s' is not a pattern in the config", a.Name, a.Series)
+		}
+		results = append(results, a.Evaluate(metrics))
+	}
+	return results, nil
+}
+
+// addAnomalies adds the gaps in the lines of each tag and the bursts of error and warning lines
+// to the analysis report. A zero gap threshold selects gap_threshold from the config, else the
+// default threshold.
+func addAnomalies(r *report.AnalysisReport, lines []*parser.LogLine, cfg *config.VisualizationConfig, gapThreshold, burstGap time.Duration, topBursts int) error {
+	if gapThreshold == 0 && cfg != nil {
+		gapThreshold = cfg.GapThreshold
+	}
+	if gapThreshold == 0 {
+		gapThreshold = analysis.DefaultGapThreshold
+	}
+	r.GapThresholdSeconds = gapThreshold.Seconds()
+	r.Events = append(r.Events, analysis.DetectGaps(lines, gapThreshold)...)
+
+	var severities []config.SeverityConfig
+	if cfg != nil {
+		severities = cfg.Severities
+	}
+	compiled, err := analysis.CompileSeverities(severities)
+	if err != nil {
+		return err
+	}
+	r.BurstGapSeconds = burstGap.Seconds()
+	r.Bursts, r.BurstCount = analysis.DetectBursts(lines, compiled, burstGap, topBursts)
+	return nil
+}
+
+// computeStability computes the stability of the given series (or the te_report series of
+// the config) at the given comma-separated taus
+func computeStability(metrics map[string][]pattern.MetricPoint, cfg *config.VisualizationConfig, seriesList, tauList, unit string) ([]report.StabilityStats, error) {
+	series, err := timeErrorSeries(cfg, seriesList, "stability")
+	if err != nil {
+		return nil, err
+	}
+
+	var taus []time.Duration
+	for _, item := range config.SplitList(tauList) {
+		tau, err := time.ParseDuration(item)
+		if err != nil || tau <= 0 {
+			return nil, fmt.Errorf("invalid tau '%s', expected a positive duration (e.g., 10s)", item)
+		}
+		taus = append(taus, tau)
+	}
+
+	var results []report.StabilityStats
+	for _, name := range series {
+		stats, err := analysis.ComputeStability(name, metrics[name], phaseUnit(cfg, name, unit), taus)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *stats)
+	}
+	return results, nil
+}
+
+// computeMTIE computes the MTIE of the given series (or the te_report series of the config)
+// and checks it against the comma-separated masks, built in or from te_report.mtie_masks
+func computeMTIE(metrics map[string][]pattern.MetricPoint, cfg *config.VisualizationConfig, seriesList, maskList, unit string) ([]report.MTIEStats, error) {
+	series, err := timeErrorSeries(cfg, seriesList, "mtie")
+	if err != nil {
+		return nil, err
+	}
+
+	available := make(map[string]analysis.MTIEMask)
+	for _, mask := range analysis.BuiltinMTIEMasks {
+		available[mask.Name] = mask
+	}
+	cutoffHz := 0.0
+	if cfg.TEReport != nil {
+		cutoffHz = cfg.TEReport.CutoffHz
+		for _, m := range cfg.TEReport.MTIEMasks {
+			mask := analysis.MTIEMask{Name: m.Name}
+			for _, pt := range m.Points {
+				mask.Limits = append(mask.Limits, report.MaskLimit{TauSeconds: pt.Tau.Seconds(), LimitNs: pt.LimitNs})
+			}
+			available[m.Name] = mask
+		}
+	}
+	var masks []analysis.MTIEMask
+	for _, name := range config.SplitList(maskList) {
+		mask, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown MTIE mask '%s', expected class-a, class-b, class-c, class-d, or a mask from te_report.mtie_masks", name)
+		}
+		masks = append(masks, mask)
+	}
+
+	var results []report.MTIEStats
+	for _, name := range series {
+		stats, err := analysis.ComputeMTIE(name, metrics[name], phaseUnit(cfg, name, unit), cutoffHz, masks)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *stats)
+	}
+	return results, nil
+}
+
+// computeSpectrum computes the power spectral density of the given series (or the te_report
+// series of the config)
+func computeSpectrum(metrics map[string][]pattern.MetricPoint, cfg *config.VisualizationConfig, seriesList, unit string) ([]report.SpectrumStats, error) {
+	series, err := timeErrorSeries(cfg, seriesList, "spectrum")
+	if err != nil {
+		return nil, err
+	}
+	var results []report.SpectrumStats
+	for _, name := range series {
+		stats, err := analysis.ComputeSpectrum(name, metrics[name], phaseUnit(cfg, name, unit))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *stats)
+	}
+	return results, nil
+}
+
+// computeCorrelation computes the correlation of the two series of the comma-separated list
+func computeCorrelation(metrics map[string][]pattern.MetricPoint, cfg *config.VisualizationConfig, list string, maxLag time.Duration) (*report.Correlation, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("-correlate requires a config with the patterns of the series")
+	}
+	series := config.SplitList(list)
+	if len(series) != 2 {
+		return nil, fmt.Errorf("invalid -correlate '%s', expected two comma-separated series", list)
+	}
+	for _, name := range series {
+		if !hasPattern(cfg, name) {
+			return nil, fmt.Errorf("correlate series '%s' is not a pattern in the config", name)
+		}
+	}
+	if maxLag < 0 {
+		return nil, fmt.Errorf("invalid -max-lag '%s', expected a non-negative duration", maxLag)
+	}
+	return analysis.ComputeCorrelation(series[0], metrics[series[0]], series[1], metrics[series[1]], maxLag)
+}
+
+// timeErrorSeries returns the time error series named in the comma-separated list, or the
+// te_report series of the config when the list is empty. The option names the analysis in errors.
+func timeErrorSeries(cfg *config.VisualizationConfig, list, option string) ([]string, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("-%s requires a config with the time error patterns", option)
+	}
+	series := config.SplitList(list)
+	if len(series) == 0 && cfg.TEReport != nil {
+		series = cfg.TEReport.Series
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no %s series given (use -%s-series or te_report.series in the config)", option, option)
+	}
+	for _, name := range series {
+		if !hasPattern(cfg, name) {
+			return nil, fmt.Errorf("%s series '%s' is not a pattern in the config", option, name)
+		}
+	}
+	return series, nil
+}
+
+// phaseUnit returns the unit of the values of a time error series: the -phase-unit flag, or else
+// the display unit of the pattern if it is a unit of time, or else ns
+func phaseUnit(cfg *config.VisualizationConfig, name, unit string) string {
+	if unit != "" {
+		return unit
+	}
+	for _, p := range cfg.Patterns {
+		if _, err := pattern.UnitScale(p.DisplayUnit, "s"); err == nil && p.Name == name {
+			return p.DisplayUnit
+		}
+	}
+	return "ns"
+}
+
+// hasPattern reports whether the config defines a pattern with the given name
+func hasPattern(cfg *config.VisualizationConfig, name string) bool {
+	for _, p := range cfg.Patterns {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAnalysisJSON writes the analysis report as JSON
+func writeAnalysisJSON(r *report.AnalysisReport, outputPath string) error {
+	out, err := sink.Open(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := encodeAnalysisJSON(out, r); err != nil {
+		return fmt.Errorf("failed to encode analysis: %w", err)
+	}
+	return out.Close()
+}
+
+// encodeAnalysisJSON writes the analysis report as indented JSON
+func encodeAnalysisJSON(w io.Writer, r *report.AnalysisReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// encodeYAML writes a value as YAML with the keys and field order of its JSON encoding, so the
+// YAML matches the documented JSON schema (JSON is YAML, read here into nodes to keep the order)
+func encodeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle clears the flow style and quoting of nodes read from JSON, leaving the encoder to
+// choose the block style and quote only where needed
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func analyzeLogs(r *report.AnalysisReport, cfg *config.VisualizationConfig, output io.Writer) {
+	// Basic statistics
+	fmt.Fprintf(output, "\n=== Analysis ===\n")
+	fmt.Fprintf(output, "Total log lines: %d\n", r.TotalLines)
+
+	// Count by tag
+	fmt.Fprintf(output, "\nLines by tag:\n")
+	for _, tag := range r.Tags {
+		fmt.Fprintf(output, "  %s: %d\n", tag.Tag, tag.Lines)
+	}
+
+	// Count lines with/without timestamps
+	fmt.Fprintf(output, "\nTimestamp coverage:\n")
+	fmt.Fprintf(output, "  With timestamp: %d\n", r.LinesWithTimestamp)
+	fmt.Fprintf(output, "  Without timestamp: %d\n", r.LinesWithoutTimestamp)
+	if r.DuplicateLines > 0 {
+		fmt.Fprintf(output, "  Duplicates dropped: %d\n", r.DuplicateLines)
+	}
+
+	// Out-of-order lines per source
+	fmt.Fprintf(output, "\nReordering by tag:\n")
+	for _, tag := range r.Tags {
+		fmt.Fprintf(output, "  %s: %d of %d lines out of order (max lateness %v, %d beyond window)\n",
+			tag.Tag, tag.Reordered, tag.LinesWithTimestamp,
+			time.Duration(tag.MaxLatenessSeconds*float64(time.Second)), tag.LateBeyondWindow)
+	}
+
+	// Periods without lines per tag
+	fmt.Fprintf(output, "\nLog gaps (longer than %v):\n", seconds(r.GapThresholdSeconds))
+	gaps := 0
+	for _, e := range r.Events {
+		if e.Type != analysis.EventGap {
+			continue
+		}
+		fmt.Fprintf(output, "  %s  %s: %s\n", e.Time.Format("15:04:05.000"), e.Tag, e.Message)
+		gaps++
+	}
+	if gaps == 0 {
+		fmt.Fprintf(output, "  none\n")
+	}
+
+	// Largest bursts of error and warning lines
+	fmt.Fprintf(output, "\nError bursts (lines at most %v apart", seconds(r.BurstGapSeconds))
+	if len(r.Bursts) < r.BurstCount {
+		fmt.Fprintf(output, ", largest %d of %d", len(r.Bursts), r.BurstCount)
+	}
+	fmt.Fprintf(output, "):\n")
+	if len(r.Bursts) == 0 {
+		fmt.Fprintf(output, "  none\n")
+	}
+	for _, b := range r.Bursts {
+		fmt.Fprintf(output, "  %s - %s  %d lines (%s) from %s\n", b.StartTime.Format("15:04:05.000"), b.EndTime.Format("15:04:05.000"),
+			b.Lines, formatSeverities(b.Severities), strings.Join(b.Tags, ", "))
+		for _, m := range b.Messages {
+			fmt.Fprintf(output, "    %dx %s: %s\n", m.Count, m.Tag, m.Message)
+		}
+	}
+
+	// Metric extraction report (when a config is available)
+	if len(r.Series) == 0 {
+		return
+	}
+	fmt.Fprintf(output, "\nMetric extraction:\n")
+	for i, series := range r.Series {
+		fmt.Fprintf(output, "  %s: %d matches, %d points", series.Name, series.Matches, series.Points)
+		if series.Duplicates > 0 {
+			fmt.Fprintf(output, " (%d duplicates collapsed, %s)", series.Duplicates, cfg.Patterns[i].Dedup)
+		}
+		if series.Filtered > 0 {
+			verb := "dropped"
+			if cfg.Patterns[i].Clamp {
+				verb = "clamped"
+			}
+			fmt.Fprintf(output, " (%d out of bounds %s)", series.Filtered, verb)
+		}
+		fmt.Fprintln(output)
+	}
+
+	// Value statistics table of the metric series
+	tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := false
+	for _, series := range r.Series {
+		v := series.Values
+		if v == nil {
+			continue
+		}
+		if !header {
+			fmt.Fprintf(output, "\nSeries statistics:\n")
+			fmt.Fprintf(tw, "Series\tPoints\tMin\tMax\tMean\tMedian\tP95\tP99\tStdDev\t\n")
+			header = true
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", series.Name, series.Points,
+			formatStat(v.Min), formatStat(v.Max), formatStat(v.Mean), formatStat(v.Median),
+			formatStat(v.P95), formatStat(v.P99), formatStat(v.StdDev))
+	}
+	tw.Flush()
+
+	// Assertions of the config
+	if len(r.Checks) > 0 {
+		fmt.Fprintf(output, "\nChecks:\n")
+		printChecks(output, r.Checks)
+	}
+
+	// State transition timelines of the state-mapped series
+	for _, st := range r.States {
+		fmt.Fprintf(output, "\nState timeline of %s (%d transitions, %d unlocks", st.Series, len(st.Transitions), st.Unlocks)
+		if st.TimeToLockSeconds != nil {
+			fmt.Fprintf(output, ", time to lock %v", seconds(*st.TimeToLockSeconds))
+		} else {
+			fmt.Fprintf(output, ", never locked")
+		}
+		fmt.Fprintf(output, "):\n")
+		fmt.Fprintf(output, "  %s  start in %s\n", st.StartTime.Format("15:04:05.000"), st.InitialState)
+		for _, t := range st.Transitions {
+			fmt.Fprintf(output, "  %s  %s -> %s (for %v)\n", t.Time.Format("15:04:05.000"), t.From, t.To, seconds(t.DurationSeconds))
+		}
+		tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "State\tTime\tShare\tEntries\t\n")
+		for _, d := range st.States {
+			fmt.Fprintf(tw, "%s\t%v\t%.1f%%\t%d\t\n", d.State, seconds(d.Seconds), 100*d.Fraction, d.Entries)
+		}
+		tw.Flush()
+	}
+
+	// Holdover episodes of the state-mapped series
+	for _, h := range r.Holdovers {
+		fmt.Fprintf(output, "\nHoldover of %s (%d episodes, %v in holdover, longest %v", h.Series, len(h.Episodes), seconds(h.TotalSeconds), seconds(h.LongestSeconds))
+		unit, column := "", "Max excursion"
+		if h.PhaseUnit != "" {
+			unit, column = " "+h.PhaseUnit, column+" ("+h.PhaseUnit+")"
+		}
+		if h.MaxExcursion != nil {
+			fmt.Fprintf(output, ", max excursion %s%s of %s", formatStat(*h.MaxExcursion), unit, h.PhaseSeries)
+		}
+		fmt.Fprintf(output, "):\n")
+		tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Start\tDuration\tFrom\tTo\tRecovery\t%s\t\n", column)
+		for _, e := range h.Episodes {
+			exit, recovery, excursion := e.ExitState, "never", ""
+			if exit == "" {
+				exit, recovery = "(ongoing)", ""
+			}
+			if e.RecoverySeconds != nil {
+				recovery = seconds(*e.RecoverySeconds).String()
+			}
+			if e.MaxExcursion != nil {
+				excursion = formatStat(*e.MaxExcursion)
+			}
+			fmt.Fprintf(tw, "%s\t%v\t%s\t%s\t%s\t%s\t\n", e.Start.Format("15:04:05.000"), seconds(e.DurationSeconds), e.EntryState, exit, recovery, excursion)
+		}
+		tw.Flush()
+	}
+
+	// Grandmasters selected by the BMCA of each ptp4l instance
+	for _, gm := range r.Grandmasters {
+		name := gm.Tag
+		if gm.Instance != "" {
+			name += " " + gm.Instance
+		}
+		fmt.Fprintf(output, "\nGrandmasters of %s (%d changes, %d new foreign masters):\n", name, gm.Changes, len(gm.ForeignMasters))
+		p, f := 0, 0
+		for p < len(gm.Periods) || f < len(gm.ForeignMasters) {
+			if f == len(gm.ForeignMasters) || p < len(gm.Periods) && !gm.ForeignMasters[f].Time.Before(gm.Periods[p].Start) {
+				period := gm.Periods[p]
+				local := ""
+				if period.Local {
+					local = " (local clock)"
+				}
+				fmt.Fprintf(output, "  %s  grandmaster %s%s for %v\n", period.Start.Format("15:04:05.000"), period.ClockIdentity, local, seconds(period.DurationSeconds))
+				p++
+				continue
+			}
+			foreign := gm.ForeignMasters[f]
+			fmt.Fprintf(output, "  %s  %s: new foreign master %s\n", foreign.Time.Format("15:04:05.000"), foreign.Port, foreign.PortIdentity)
+			f++
+		}
+	}
+
+	// Time error of the te_report series
+	if len(r.TimeError) > 0 {
+		te := r.TimeError[0]
+		fmt.Fprintf(output, "\nTime error (ITU-T G.8273.2, dTE split at %s Hz", formatStat(te.CutoffHz))
+		if te.CTEWindowSeconds > 0 {
+			fmt.Fprintf(output, ", cTE over %v windows", time.Duration(te.CTEWindowSeconds*float64(time.Second)))
+		}
+		fmt.Fprintf(output, "):\n")
+		tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Series\tSamples\tmax|TE|\tmax|TE_L|\tcTE\tdTE_L pk-pk\tdTE_H pk-pk\t\n")
+		for _, te := range r.TimeError {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", te.Series, te.Samples, formatStat(te.MaxAbsTE),
+				formatStat(te.MaxAbsTEL), formatStat(te.ConstantTE), formatStat(te.DynamicTELPkPk), formatStat(te.DynamicTEHPkPk))
+		}
+		tw.Flush()
+	}
+
+	// Stability (with -stability)
+	for _, st := range r.Stability {
+		fmt.Fprintf(output, "\nStability of %s (%d samples, tau0 %s):\n", st.Series, st.Samples, formatStat(st.Tau0Seconds)+"s")
+		tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Tau (s)\tADEV\tMDEV\tTDEV (%s)\t\n", st.Unit)
+		for _, pt := range st.Points {
+			fmt.Fprintf(tw, "%s\t%.3e\t%.3e\t%s\t\n", formatStat(pt.TauSeconds), pt.ADEV, pt.MDEV, formatStat(pt.TDEV))
+		}
+		tw.Flush()
+	}
+
+	// MTIE and mask checks (with -mtie)
+	for _, st := range r.MTIE {
+		fmt.Fprintf(output, "\nMTIE of %s (dTE_L, %s Hz low-pass, tau0 %ss):\n", st.Series, formatStat(st.CutoffHz), formatStat(st.Tau0Seconds))
+		tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Tau (s)\tMTIE (ns)\t\n")
+		for _, pt := range st.Points {
+			fmt.Fprintf(tw, "%s\t%s\t\n", formatStat(pt.TauSeconds), formatStat(pt.MTIENs))
+		}
+		tw.Flush()
+		for _, mask := range st.Masks {
+			failed := 0
+			for _, check := range mask.Checks {
+				if !check.Pass {
+					failed++
+				}
+			}
+			result := "PASS"
+			if !mask.Pass {
+				result = "FAIL"
+			}
+			fmt.Fprintf(output, "  Mask %s: %s (%d of %d points within the limit)\n", mask.Mask, result, len(mask.Checks)-failed, len(mask.Checks))
+			for _, check := range mask.Checks {
+				if !check.Pass {
+					fmt.Fprintf(output, "    tau %ss: MTIE %s ns exceeds %s ns\n", formatStat(check.TauSeconds), formatStat(check.MTIENs), formatStat(check.LimitNs))
+				}
+			}
+		}
+	}
+
+	// Power spectral density peaks (with -spectrum)
+	for _, st := range r.Spectrum {
+		fmt.Fprintf(output, "\nSpectrum of %s (%d samples, tau0 %ss, %d segments of %d, resolution %s Hz):\n",
+			st.Series, st.Samples, formatStat(st.Tau0Seconds), st.Segments, st.SegmentLength, formatStat(st.ResolutionHz))
+		if len(st.Peaks) == 0 {
+			fmt.Fprintf(output, "  No peaks 10 dB above the median\n")
+			continue
+		}
+		tw := tabwriter.NewWriter(output, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "Frequency (Hz)\tPeriod (s)\tPSD (%s²/Hz)\t\n", st.Unit)
+		for _, peak := range st.Peaks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", formatStat(peak.FrequencyHz), formatStat(peak.PeriodSeconds), formatStat(peak.PSD))
+		}
+		tw.Flush()
+	}
+
+	// Correlation of series pairs (with -correlate)
+	for _, c := range r.Correlations {
+		fmt.Fprintf(output, "\nCorrelation of %s and %s (%d samples, interval %ss):\n", c.SeriesA, c.SeriesB, c.Samples, formatStat(c.IntervalSeconds))
+		fmt.Fprintf(output, "  Pearson correlation: %.3f\n", c.Pearson)
+		follows := "in step"
+		switch {
+		case c.LagSeconds > 0:
+			follows = fmt.Sprintf("%s follows %s", c.SeriesB, c.SeriesA)
+		case c.LagSeconds < 0:
+			follows = fmt.Sprintf("%s follows %s", c.SeriesA, c.SeriesB)
+		}
+		fmt.Fprintf(output, "  Peak cross-correlation: %.3f at lag %s (%s; lags up to ±%s searched)\n",
+			c.LagCorrelation, seconds(c.LagSeconds), follows, seconds(c.MaxLagSeconds))
+	}
+}
+
+// seconds converts seconds to a duration for printing, rounded to milliseconds
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
+
+// formatStat formats a value statistic compactly, keeping integer values (typical of ns
+// offsets) short
+func formatStat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+// formatSeverities formats the line counts of a burst by severity, e.g. "12 error, 3 warning"
+func formatSeverities(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%d %s", counts[name], name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printChecks prints the results of assertions, one per line
+func printChecks(output io.Writer, checks []report.CheckResult) {
+	for _, c := range checks {
+		result := "PASS"
+		if !c.Passed {
+			result = "FAIL"
+		}
+		fmt.Fprintf(output, "  %s  %s: %s\n", result, c.Name, c.Message)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/pkg/pattern"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// apiLines is the response of /api/lines
+type apiLines struct {
+	Total   int                    `json:"total"`   // Lines of the log
+	Matched int                    `json:"matched"` // Lines passing the filters
+	Offset  int                    `json:"offset"`
+	Lines   []interleaver.JSONLine `json:"lines"`
+}
+
+// apiSeriesInfo describes a series in the response of /api/series
+type apiSeriesInfo struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`          // metric or event
+	Tag    string `json:"tag,omitempty"` // Tag filter of the pattern
+	Points int    `json:"points"`
+}
+
+// apiSeries is the response of /api/series/{name}
+type apiSeries struct {
+	apiSeriesInfo
+	Values []apiPoint `json:"values"`
+}
+
+type apiPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+	State string    `json:"state,omitempty"`
+}
+
+// apiError is the body of failed API requests
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// serveAPILines serves the interleaved lines as JSON, filtered by the tag (repeated or
+// comma-separated), from and to (RFC 3339), grep, and grep_v query parameters and paged by
+// offset and limit
+func (d *dashboard) serveAPILines(w http.ResponseWriter, r *http.Request) {
+	s, err := d.snapshot(false)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	query := r.URL.Query()
+	var tags []string
+	for _, value := range query["tag"] {
+		tags = append(tags, config.SplitList(value)...)
+	}
+	filter, err := interleaver.NewFilter(tags, nil, query.Get("grep"), query.Get("grep_v"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	from, to, err := apiTimeRange(query)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	offset, err := apiCount(query, "offset")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := apiCount(query, "limit")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	lines := filter.Apply(s.lines)
+	if !from.IsZero() || !to.IsZero() {
+		var inRange []*parser.LogLine
+		for _, line := range lines {
+			if t := line.GetTimestamp(); t != nil && inTimeRange(t.Time, from, to) {
+				inRange = append(inRange, line)
+			}
+		}
+		lines = inRange
+	}
+
+	resp := apiLines{Total: len(s.lines), Matched: len(lines), Offset: offset, Lines: []interleaver.JSONLine{}}
+	lines = lines[min(offset, len(lines)):]
+	if limit > 0 && limit < len(lines) {
+		lines = lines[:limit]
+	}
+	for _, line := range lines {
+		resp.Lines = append(resp.Lines, interleaver.NewJSONLine(line))
+	}
+	writeJSON(w, resp)
+}
+
+// serveAPISeriesList lists the series of the config with their point counts
+func (d *dashboard) serveAPISeriesList(w http.ResponseWriter, r *http.Request) {
+	s, err := d.snapshot(false)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	metrics, err := s.metrics(d)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	list := []apiSeriesInfo{}
+	if s.cfg != nil {
+		for _, p := range s.cfg.Patterns {
+			list = append(list, seriesInfo(p, len(metrics[p.Name])))
+		}
+	}
+	writeJSON(w, list)
+}
+
+// serveAPISeries serves the points of a series as JSON, limited to the from and to query
+// parameters (RFC 3339) and reduced to at most downsample points
+func (d *dashboard) serveAPISeries(w http.ResponseWriter, r *http.Request) {
+	s, err := d.snapshot(false)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	name := r.PathValue("name")
+	var series *config.PatternConfig
+	if s.cfg != nil {
+		for i := range s.cfg.Patterns {
+			if s.cfg.Patterns[i].Name == name {
+				series = &s.cfg.Patterns[i]
+				break
+			}
+		}
+	}
+	if series == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown series '%s'", name))
+		return
+	}
+
+	query := r.URL.Query()
+	from, to, err := apiTimeRange(query)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	downsample, err := apiCount(query, "downsample")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	metrics, err := s.metrics(d)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var points []pattern.MetricPoint
+	for _, pt := range metrics[name] {
+		if inTimeRange(pt.Time, from, to) {
+			points = append(points, pt)
+		}
+	}
+	// Events have no values to keep the shape of
+	if downsample > 0 && !series.IsEvent() {
+		points = visualizer.DownsampleLTTB(points, downsample)
+	}
+
+	resp := apiSeries{apiSeriesInfo: seriesInfo(*series, len(points)), Values: []apiPoint{}}
+	for _, pt := range points {
+		resp.Values = append(resp.Values, apiPoint{Time: pt.Time, Value: pt.Value, State: pt.State})
+	}
+	writeJSON(w, resp)
+}
+
+// serveAPIAnalysis serves the analysis as JSON, as written by analyze -json
+func (d *dashboard) serveAPIAnalysis(w http.ResponseWriter, r *http.Request) {
+	s, err := d.snapshot(false)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	summary, _, err := s.report(d)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, summary.Analysis)
+}
+
+// seriesInfo describes a series of the config with its point count
+func seriesInfo(p config.PatternConfig, points int) apiSeriesInfo {
+	info := apiSeriesInfo{Name: p.Name, Type: "metric", Tag: p.TagFilter, Points: points}
+	if p.IsEvent() {
+		info.Type = "event"
+	}
+	return info
+}
+
+// apiTimeRange parses the from and to query parameters, which are zero when not set
+func apiTimeRange(query url.Values) (from, to time.Time, err error) {
+	for _, param := range []struct {
+		name string
+		t    *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		if *param.t, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			return from, to, fmt.Errorf("invalid %s '%s', expected an RFC 3339 time (e.g., 2025-01-11T14:05:54Z)", param.name, value)
+		}
+	}
+	return from, to, nil
+}
+
+// inTimeRange reports whether t is within [from, to], where zero bounds are open
+func inTimeRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+}
+
+// apiCount parses a non-negative integer query parameter, which is 0 when not set
+func apiCount(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s '%s', expected a non-negative integer", name, value)
+	}
+	return n, nil
+}
+
+// writeJSON writes an API response
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("failed to encode response: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+// writeAPIError writes the error of a failed API request as JSON
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	data, _ := json.Marshal(apiError{Error: err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
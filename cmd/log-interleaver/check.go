@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log-interleaver/internal/sink"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/pkg/report"
+	"time"
+)
+
+// Exit statuses of the check command
+const (
+	checkFailed = 1 // At least one assertion failed
+	checkError  = 2 // The assertions could not be evaluated
+)
+
+// runCheck evaluates the assertions of the config and fails when any of them does not hold
+func runCheck(ctx context.Context, args []string) error {
+	fs := newFlagSet("check", "Evaluate the assertions of the config against the logs, for gating automated test runs.\nExits with status 1 when an assertion fails and 2 when the assertions cannot be evaluated.")
+	input := addInputFlags(fs)
+	output := fs.String("output", "-", "Output location for the results, or - for stdout")
+	format := fs.String("format", "text", "Result format: text, or json (pkg/report.CheckReport, with the failure list)")
+	fs.Parse(args)
+
+	checkReport, err := evaluateChecks(ctx, input, *format)
+	if err != nil {
+		return &exitStatus{code: checkError, err: err}
+	}
+
+	out, err := sink.Open(*output)
+	if err != nil {
+		return &exitStatus{code: checkError, err: fmt.Errorf("failed to create output file: %w", err)}
+	}
+	defer out.Close()
+
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(checkReport); err != nil {
+			return &exitStatus{code: checkError, err: fmt.Errorf("failed to encode check results: %w", err)}
+		}
+	} else {
+		printChecks(out, checkReport.Checks)
+		fmt.Fprintf(out, "%d of %d checks passed\n", len(checkReport.Checks)-len(checkReport.Failures), len(checkReport.Checks))
+	}
+	if err := out.Close(); err != nil {
+		return &exitStatus{code: checkError, err: fmt.Errorf("failed to write output: %w", err)}
+	}
+
+	if !checkReport.Passed {
+		return &exitStatus{code: checkFailed, err: fmt.Errorf("%d of %d checks failed", len(checkReport.Failures), len(checkReport.Checks))}
+	}
+	return nil
+}
+
+// evaluateChecks reads the logs and evaluates the assertions of the config
+func evaluateChecks(ctx context.Context, input *inputOptions, format string) (*report.CheckReport, error) {
+	if format != "text" && format != "json" {
+		return nil, fmt.Errorf("invalid -format '%s', expected text or json", format)
+	}
+
+	iv, cfg, err := input.newInterleaver()
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("config file '%s' not found", input.configPath)
+	}
+	if len(cfg.Assertions) == 0 {
+		return nil, fmt.Errorf("no assertions in the config (add them under assertions)")
+	}
+	lines, err := input.process(ctx, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := visualizer.ExtractMetrics(ctx, lines, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract metrics: %w", err)
+	}
+	checks, err := evaluateAssertions(cfg, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	checkReport := &report.CheckReport{GeneratedAt: time.Now().UTC(), Passed: true, Checks: checks, Failures: []report.CheckResult{}}
+	for _, c := range checks {
+		if !c.Passed {
+			checkReport.Passed = false
+			checkReport.Failures = append(checkReport.Failures, c)
+		}
+	}
+	return checkReport, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/kube"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// runCollect reads the logs of pods through the Kubernetes API into the log directory and
+// interleaves them
+func runCollect(ctx context.Context, args []string) error {
+	fs := newFlagSet("collect", "Read the container logs of pods through the Kubernetes API into the log directory, one file\nper container tagged by the container name, then interleave the directory. Arguments after --\nare passed to interleave, e.g.: collect -logs logs -- -output interleaved.log -config config.yaml")
+	log := addLogFlags(fs)
+	logDir := fs.String("logs", "logs", "Directory to write the log files to, which is then interleaved")
+	kubeconfig := fs.String("kubeconfig", "", "Path to the kubeconfig file (default: $KUBECONFIG, ~/.kube/config, or the in-cluster service account)")
+	contextName := fs.String("context", "", "Kubeconfig context (default: the current context)")
+	namespace := fs.String("namespace", "openshift-ptp", "Namespace of the pods")
+	selector := fs.String("selector", "app=linuxptp-daemon", "Label selector of the pods (empty = all pods of the namespace)")
+	podNames := fs.String("pods", "", "Comma-separated pod names to collect instead of selecting by label")
+	containers := fs.String("containers", "", "Comma-separated containers to collect (default: all)")
+	previous := fs.Bool("previous", false, "Also collect the log of the previous instance of restarted containers, tagged <tag>-previous")
+	since := fs.Duration("since", 0, "Only collect lines newer than this (e.g., 2h; 0 = all)")
+	tail := fs.Int("tail", 0, "Only collect the last lines of each log (0 = all)")
+	timestamps := fs.Bool("timestamps", false, "Prefix lines with the time the kubelet received them, for lines without timestamps of their own")
+	noInterleave := fs.Bool("no-interleave", false, "Only collect the logs")
+	fs.Parse(args)
+
+	if err := log.setup(); err != nil {
+		return err
+	}
+	path := *kubeconfig
+	if path == "" {
+		path = kube.DefaultKubeconfig()
+	}
+	client, err := kube.NewClient(path, *contextName)
+	if err != nil {
+		return err
+	}
+
+	pods, err := client.ListPods(ctx, *namespace, *selector)
+	if err != nil {
+		return err
+	}
+	if names := config.SplitList(*podNames); len(names) > 0 {
+		pods = slices.DeleteFunc(pods, func(pod kube.Pod) bool { return !slices.Contains(names, pod.Name) })
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no pods found in namespace '%s' matching the selection", *namespace)
+	}
+	if err := os.MkdirAll(*logDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	podsPerNode := make(map[string]int)
+	for _, pod := range pods {
+		podsPerNode[pod.Node]++
+	}
+	collected := 0
+	for _, pod := range pods {
+		for _, container := range pod.Containers {
+			if names := config.SplitList(*containers); len(names) > 0 && !slices.Contains(names, container.Name) {
+				continue
+			}
+			tag := containerTag(pod, container, len(pods) > 1, podsPerNode[pod.Node] > 1)
+			opts := kube.LogOptions{Since: *since, TailLines: *tail, Timestamps: *timestamps}
+			if err := collectLog(ctx, client, pod, container.Name, opts, filepath.Join(*logDir, tag+".log")); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				warnf("%v", err)
+				continue
+			}
+			collected++
+			if *previous && container.Restarts > 0 {
+				opts.Previous = true
+				if err := collectLog(ctx, client, pod, container.Name, opts, filepath.Join(*logDir, tag+"-previous.log")); err != nil {
+					warnf("%v", err)
+				}
+			}
+		}
+	}
+	if collected == 0 {
+		return fmt.Errorf("no container logs collected")
+	}
+	infof("Collected %d container logs from %d pods to: %s", collected, len(pods), *logDir)
+
+	if *noInterleave {
+		return nil
+	}
+	interleaveArgs := []string{"-logs", *logDir, "-log-format", log.format}
+	if log.verbose {
+		interleaveArgs = append(interleaveArgs, "-v")
+	}
+	if log.quiet {
+		interleaveArgs = append(interleaveArgs, "-q")
+	}
+	return runInterleave(ctx, append(interleaveArgs, fs.Args()...))
+}
+
+// containerTag returns the tag of a container log: the container name, prefixed with the node
+// name when several pods are collected, or with the pod name when the node runs several of them
+func containerTag(pod kube.Pod, container kube.Container, multiplePods, sharedNode bool) string {
+	if !multiplePods {
+		return container.Name
+	}
+	if pod.Node != "" && !sharedNode {
+		return pod.Node + "-" + container.Name
+	}
+	return pod.Name + "-" + container.Name
+}
+
+// collectLog writes the log of a container to a file
+func collectLog(ctx context.Context, client *kube.Client, pod kube.Pod, container string, opts kube.LogOptions, path string) error {
+	start := time.Now()
+	stream, err := client.Logs(ctx, pod.Namespace, pod.Name, container, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	n, err := io.Copy(file, stream)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write log of %s/%s: %w", pod.Name, container, err)
+	}
+	logger.Debug("Collected log", "pod", pod.Name, "container", container, "previous", opts.Previous, "file", path, "bytes", n, "elapsed", time.Since(start).Round(time.Millisecond).String())
+	return nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/archive"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/sink"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// runCompare compares the metric series of two runs
+func runCompare(ctx context.Context, args []string) error {
+	fs := newFlagSet("compare", "Compare the metric series of two runs, e.g., before and after a firmware or config change:\nthe per-series statistics (mean, max|value|, lock time, ...) of both and their deltas.\nEach run is an analysis JSON (analyze -json), a JSON export (export -json), or a log\ndirectory or archive, read with the input options.\n\nUsage: log-interleaver compare [options] <baseline> <candidate>")
+	input := addInputFlags(fs)
+	output := fs.String("output", "-", "Output location for the comparison, or - for stdout")
+	format := fs.String("format", "text", "Comparison format: text, or json (pkg/report.Comparison)")
+	plotOutput := fs.String("plot", "", "Output location for a plot overlaying the series of both runs (format from the extension; needs JSON exports or log directories)")
+	series := fs.String("series", "", "Comma-separated names of the series to compare (default: all)")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return fmt.Errorf("compare needs a baseline and a candidate run after the options, got %d arguments", fs.NArg())
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid -format '%s', expected text or json", *format)
+	}
+
+	var runs [2]*compareRun
+	for k, location := range fs.Args() {
+		run, err := loadCompareRun(ctx, input, location)
+		if err != nil {
+			return fmt.Errorf("failed to read run '%s': %w", location, err)
+		}
+		run.filter(config.SplitList(*series))
+		runs[k] = run
+	}
+
+	comparison := &report.Comparison{
+		GeneratedAt: time.Now().UTC(),
+		Baseline:    fs.Arg(0),
+		Candidate:   fs.Arg(1),
+		Series:      analysis.CompareRuns(runs[0].series, runs[1].series),
+	}
+
+	if *plotOutput != "" {
+		if runs[0].points == nil || runs[1].points == nil {
+			return fmt.Errorf("-plot needs the points of the series: compare JSON exports or log directories")
+		}
+		if err := visualizer.GenerateComparisonPlot(runs[0].points, runs[1].points, fs.Arg(0), fs.Arg(1), *plotOutput, ""); err != nil {
+			return fmt.Errorf("failed to generate comparison plot: %w", err)
+		}
+		infof("Comparison plot saved to: %s", *plotOutput)
+	}
+
+	out, err := sink.Open(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer out.Close()
+	if *format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(comparison); err != nil {
+			return fmt.Errorf("failed to encode comparison: %w", err)
+		}
+	} else {
+		printComparison(out, comparison)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// compareRun is a run of a comparison: the statistics of its series and, for JSON exports and
+// logs, their points
+type compareRun struct {
+	series []analysis.RunSeries
+	points []visualizer.SeriesData // Points as seconds since the start of the run (nil for analysis JSON)
+}
+
+// filter keeps the named series (all without names)
+func (r *compareRun) filter(names []string) {
+	if len(names) == 0 {
+		return
+	}
+	r.series = slices.DeleteFunc(r.series, func(s analysis.RunSeries) bool { return !slices.Contains(names, s.Name) })
+	r.points = slices.DeleteFunc(r.points, func(s visualizer.SeriesData) bool { return !slices.Contains(names, s.Name) })
+}
+
+// loadCompareRun reads a run from an analysis JSON, a JSON export, or a log directory or archive
+func loadCompareRun(ctx context.Context, input *inputOptions, location string) (*compareRun, error) {
+	if info, err := os.Stat(location); err == nil && info.IsDir() || archive.IsArchive(location) {
+		return logsRun(ctx, input, location)
+	}
+
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	var probe struct {
+		TotalLines *int `json:"total_lines"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if probe.TotalLines != nil {
+		var r report.AnalysisReport
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse analysis JSON: %w", err)
+		}
+		return analysisRun(&r), nil
+	}
+	var export struct {
+		StartTime time.Time               `json:"start_time"`
+		Series    []visualizer.SeriesData `json:"series"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON export: %w", err)
+	}
+	if export.Series == nil {
+		return nil, fmt.Errorf("neither an analysis JSON (analyze -json) nor a JSON export (export -json)")
+	}
+	return exportRun(export.StartTime, export.Series), nil
+}
+
+// analysisRun takes the series statistics of an analysis report
+func analysisRun(r *report.AnalysisReport) *compareRun {
+	run := &compareRun{}
+	for _, s := range r.Series {
+		var timeline *report.StateTimeline
+		for k := range r.States {
+			if r.States[k].Series == s.Name {
+				timeline = &r.States[k]
+			}
+		}
+		if stats := analysis.SeriesRunStats(s.Points, s.Values, timeline); stats != nil {
+			run.series = append(run.series, analysis.RunSeries{Name: s.Name, Stats: stats})
+		}
+	}
+	return run
+}
+
+// exportRun computes the series statistics of a JSON export, recovering the states of
+// state-mapped series from their mapping
+func exportRun(start time.Time, series []visualizer.SeriesData) *compareRun {
+	run := &compareRun{points: []visualizer.SeriesData{}}
+	for _, s := range series {
+		if s.Type == "event" || len(s.Y) == 0 {
+			continue
+		}
+		states := make(map[float64]string)
+		for state, value := range s.StateMapping {
+			states[value] = state
+		}
+		points := make([]pattern.MetricPoint, len(s.Y))
+		for k := range s.Y {
+			points[k] = pattern.MetricPoint{
+				Time:       start.Add(time.Duration(s.X[k] * float64(time.Second))),
+				Value:      s.Y[k],
+				SeriesName: s.Name,
+				State:      states[s.Y[k]],
+			}
+		}
+		run.series = append(run.series, seriesRun(s.Name, points, nil))
+		run.points = append(run.points, s)
+	}
+	return run
+}
+
+// logsRun reads the logs of a directory or archive with the input options and computes the
+// statistics of the series of the config
+func logsRun(ctx context.Context, input *inputOptions, location string) (*compareRun, error) {
+	opts := *input
+	opts.logDir, opts.fileList = location, ""
+	iv, cfg, err := opts.newInterleaver()
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("config file '%s' not found", input.configPath)
+	}
+	lines, err := opts.process(ctx, iv)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := visualizer.ExtractMetrics(ctx, lines, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract metrics: %w", err)
+	}
+
+	var start time.Time
+	for _, points := range metrics {
+		for _, pt := range points {
+			if start.IsZero() || pt.Time.Before(start) {
+				start = pt.Time
+			}
+		}
+	}
+	run := &compareRun{points: []visualizer.SeriesData{}}
+	for _, p := range cfg.Patterns {
+		points := metrics[p.Name]
+		if p.IsEvent() || len(points) == 0 {
+			continue
+		}
+		sort.SliceStable(points, func(a, b int) bool { return points[a].Time.Before(points[b].Time) })
+		run.series = append(run.series, seriesRun(p.Name, points, p.LockedStates))
+		s := visualizer.SeriesData{Name: p.Name, Unit: p.DisplayUnit, Step: p.Step, X: make([]float64, len(points)), Y: make([]float64, len(points))}
+		for k, pt := range points {
+			s.X[k] = pt.Time.Sub(start).Seconds()
+			s.Y[k] = pt.Value
+		}
+		run.points = append(run.points, s)
+	}
+	return run, nil
+}
+
+// seriesRun computes the statistics of the points of a series
+func seriesRun(name string, points []pattern.MetricPoint, locked []string) analysis.RunSeries {
+	timeline := analysis.ComputeStateTimeline(name, points, locked)
+	return analysis.RunSeries{Name: name, Stats: analysis.SeriesRunStats(len(points), analysis.ComputeValueStats(points), timeline)}
+}
+
+// printComparison prints the statistics of each series in both runs with their deltas
+func printComparison(w io.Writer, c *report.Comparison) {
+	fmt.Fprintf(w, "Baseline:  %s\nCandidate: %s\n", c.Baseline, c.Candidate)
+	if len(c.Series) == 0 {
+		fmt.Fprintf(w, "\nNo series with values in either run\n")
+		return
+	}
+	for _, s := range c.Series {
+		fmt.Fprintf(w, "\n%s:\n", s.Name)
+		if s.Delta == nil {
+			if s.Baseline == nil {
+				fmt.Fprintf(w, "  only in the candidate\n")
+			} else {
+				fmt.Fprintf(w, "  only in the baseline\n")
+			}
+			continue
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "  %-12s\tBaseline\tCandidate\tDelta\t\n", "")
+		fmt.Fprintf(tw, "  %-12s\t%d\t%d\t%s\t\n", "points", s.Baseline.Points, s.Candidate.Points, signedStat(float64(s.Delta.Points)))
+		rows := []struct {
+			name                       string
+			baseline, candidate, delta float64
+		}{
+			{"mean", s.Baseline.Mean, s.Candidate.Mean, s.Delta.Mean},
+			{"median", s.Baseline.Median, s.Candidate.Median, s.Delta.Median},
+			{"stddev", s.Baseline.StdDev, s.Candidate.StdDev, s.Delta.StdDev},
+			{"p99", s.Baseline.P99, s.Candidate.P99, s.Delta.P99},
+			{"max|value|", s.Baseline.MaxAbs, s.Candidate.MaxAbs, s.Delta.MaxAbs},
+		}
+		for _, row := range rows {
+			fmt.Fprintf(tw, "  %-12s\t%s\t%s\t%s\t\n", row.name, formatStat(row.baseline), formatStat(row.candidate), signedStat(row.delta))
+		}
+		if s.Delta.Unlocks != nil {
+			// Both runs have states, so a missing time to lock means the run never locked
+			delta := ""
+			if s.Delta.TimeToLockSeconds != nil {
+				delta = signedStat(*s.Delta.TimeToLockSeconds) + "s"
+			}
+			fmt.Fprintf(tw, "  %-12s\t%s\t%s\t%s\t\n", "time to lock", lockTime(s.Baseline.TimeToLockSeconds), lockTime(s.Candidate.TimeToLockSeconds), delta)
+			fmt.Fprintf(tw, "  %-12s\t%d\t%d\t%s\t\n", "unlocks", *s.Baseline.Unlocks, *s.Candidate.Unlocks, signedStat(float64(*s.Delta.Unlocks)))
+		}
+		tw.Flush()
+	}
+}
+
+// signedStat formats a delta with its sign
+func signedStat(v float64) string {
+	if v > 0 {
+		return "+" + formatStat(v)
+	}
+	return formatStat(v)
+}
+
+// lockTime formats the time to lock of a run, or "never" for state series that did not lock
+func lockTime(seconds *float64) string {
+	if seconds == nil {
+		return "never"
+	}
+	return strconv.FormatFloat(*seconds, 'f', -1, 64) + "s"
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/templates"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// runConfig runs the subcommands of the config command
+func runConfig(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "init" {
+		return fmt.Errorf("expected a config command: init (e.g., log-interleaver config init -logs logs -output config.yaml)")
+	}
+	return runConfigInit(ctx, args[1:])
+}
+
+// runConfigInit writes a draft config with patterns for the numeric fields of recurring lines
+func runConfigInit(ctx context.Context, args []string) error {
+	fs := newFlagSet("config init", "Write a draft config from sample logs. Recurring line templates are mined from the logs,\nand their numeric fields and small sets of states become patterns, with guessed names and tag\nfilters to refine.")
+	input := addInputFlags(fs)
+	output := fs.String("output", "-", "Output location for the config, or - for stdout")
+	force := fs.Bool("force", false, "Overwrite an existing output file")
+	minCount := fs.Int("min-count", 10, "Minimum number of lines of a template to suggest patterns for")
+	maxPatterns := fs.Int("max-patterns", 20, "Maximum number of patterns, the most frequent templates first")
+	similarity := fs.Float64("similarity", 0.5, "Fraction of equal tokens for a line to join a template (0-1)")
+	fs.Parse(args)
+
+	if *similarity <= 0 || *similarity > 1 {
+		return fmt.Errorf("invalid -similarity %g, expected a fraction between 0 and 1", *similarity)
+	}
+	if *output != "-" && !*force {
+		if _, err := os.Stat(*output); err == nil {
+			return fmt.Errorf("'%s' exists, use -force to overwrite it", *output)
+		}
+	}
+
+	iv, _, err := input.newInterleaver()
+	if err != nil {
+		return err
+	}
+	lines, err := input.process(ctx, iv)
+	if err != nil {
+		return err
+	}
+
+	miner := templates.NewMiner(*similarity)
+	fields := newFieldStats()
+	for _, line := range lines {
+		if line.Fields == nil && strings.HasPrefix(strings.TrimSpace(line.OriginalLine), "{") {
+			// Structured lines are only decoded for tags with a JSON timestamp field
+			line.Fields = parser.DecodeFields(strings.TrimSpace(line.OriginalLine))
+		}
+		if line.Fields != nil {
+			fields.add(line)
+			continue
+		}
+		miner.Add(line.Tag, templates.Message(line.OriginalLine), line.OriginalLine)
+	}
+
+	var drafts []*draftPattern
+	names := make(map[string]int)
+	for _, t := range miner.Templates(*minCount) {
+		if d := templateDraft(t, names); d != nil {
+			drafts = append(drafts, d)
+		}
+	}
+	drafts = append(drafts, fields.drafts(*minCount, names)...)
+	sort.SliceStable(drafts, func(i, j int) bool { return drafts[i].count > drafts[j].count })
+	if *maxPatterns > 0 && len(drafts) > *maxPatterns {
+		drafts = drafts[:*maxPatterns]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Draft config written by log-interleaver config init from %d lines of %d files.\n", len(lines), len(iv.InputFiles()))
+	b.WriteString("# Review the patterns: rename the series, drop the uninteresting ones, and set axes and\n")
+	b.WriteString("# colors. State series map their values to numbers as listed in state_mapping.\n")
+	b.WriteString("title: \"Log Analysis\"\n")
+	if formats := fields.timestampFormats(); len(formats) > 0 {
+		b.WriteString("\n# Timestamp fields of the structured (JSON) lines\ntimestamp_formats:\n")
+		for _, tf := range formats {
+			fmt.Fprintf(&b, "  - tag: %s\n    json_field: %s\n", strconv.Quote(tf[0]), strconv.Quote(tf[1]))
+		}
+	}
+	if len(drafts) == 0 {
+		fmt.Fprintf(&b, "\n# No recurring lines (at least -min-count %d) with varying numbers were found.\npatterns: []\n", *minCount)
+	} else {
+		b.WriteString("\npatterns:\n")
+		for _, d := range drafts {
+			d.write(&b)
+		}
+	}
+
+	if err := writeFile(*output, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if *output != "-" {
+		infof("Draft config with %d patterns written to: %s", len(drafts), *output)
+	}
+	return nil
+}
+
+// draftPattern is a suggested pattern of a draft config
+type draftPattern struct {
+	comment []string // Comment lines above the pattern
+	regex   string
+	field   string
+	tag     string
+	series  []draftSeries
+	count   int // Lines of the template
+}
+
+// draftSeries is a series of a suggested pattern
+type draftSeries struct {
+	name   string
+	group  int
+	states []string // State values, for state series
+	note   string   // Observed values
+}
+
+// stateValue matches the values of positions that are suggested as states (e.g., s2, LOCKED)
+var stateValue = regexp.MustCompile(`^[A-Za-z][\w-]*$`)
+
+// maxStates is the maximum number of distinct values of a state position
+const maxStates = 8
+
+// templateDraft suggests a pattern for the varying numbers and states of a template, or nil
+// if the template has none
+func templateDraft(t *templates.Template, names map[string]int) *draftPattern {
+	var positions []int
+	for i, tok := range t.Tokens {
+		if tok.Number && tok.Distinct() >= 2 || isStatePosition(tok) {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) == 0 {
+		return nil
+	}
+	regex := t.Regex(positions)
+	if !regexp.MustCompile(regex).MatchString(t.Example) {
+		return nil
+	}
+
+	d := &draftPattern{
+		comment: []string{fmt.Sprintf("%d lines of %s like:", t.Count, t.Tag), "  " + t.Example},
+		regex:   regex,
+		tag:     t.Tag,
+		count:   t.Count,
+	}
+	for group, pos := range positions {
+		tok := t.Tokens[pos]
+		s := draftSeries{name: uniqueName(t.Tag+" "+positionLabel(t, pos), names), group: group + 1}
+		if tok.Number {
+			s.note = fmt.Sprintf("%s to %s", formatValue(tok.Min), formatValue(tok.Max))
+		} else {
+			s.states = sortedValues(tok)
+			s.note = strings.Join(s.states, ", ")
+		}
+		d.series = append(d.series, s)
+	}
+	return d
+}
+
+// isStatePosition reports whether a position has a few distinct word values, such as servo or
+// port states
+func isStatePosition(tok *templates.Token) bool {
+	if tok.Text != templates.Wildcard || tok.Overflow || tok.Distinct() < 2 || tok.Distinct() > maxStates {
+		return false
+	}
+	for value := range tok.Values {
+		if !stateValue.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedValues returns the values of a position in natural order (s0 before s2 before s10)
+func sortedValues(tok *templates.Token) []string {
+	var values []string
+	for value := range tok.Values {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) < len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	return values
+}
+
+// labelWord matches the literal words that name the following value (e.g., "path delay:")
+var labelWord = regexp.MustCompile(`^[A-Za-z][A-Za-z_.-]*[:=]?$`)
+
+// positionLabel guesses the name of a value from the key of key=value numbers or the up to two
+// words before it, falling back to "state" and "value N"
+func positionLabel(t *templates.Template, pos int) string {
+	tok := t.Tokens[pos]
+	if key := strings.TrimRight(tok.Prefix, ":="); key != "" {
+		return key
+	}
+	var words []string
+	for i := pos - 1; i >= 0 && len(words) < 2; i-- {
+		prev := t.Tokens[i]
+		if prev.Number || prev.Text == templates.Wildcard || !labelWord.MatchString(prev.Text) {
+			break
+		}
+		words = append([]string{strings.TrimRight(prev.Text, ":=")}, words...)
+	}
+	if len(words) > 0 {
+		return strings.Join(words, " ")
+	}
+	if !tok.Number {
+		return "state"
+	}
+	return "value " + strconv.Itoa(pos+1)
+}
+
+// uniqueName returns the name, numbered from the second use on
+func uniqueName(name string, names map[string]int) string {
+	names[name]++
+	if n := names[name]; n > 1 {
+		return fmt.Sprintf("%s %d", name, n)
+	}
+	return name
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// write writes the pattern as YAML list entry
+func (d *draftPattern) write(b *strings.Builder) {
+	b.WriteString("\n")
+	for _, line := range d.comment {
+		fmt.Fprintf(b, "  # %s\n", line)
+	}
+	first := true
+	entry := func(key, value string) {
+		prefix := "    "
+		if first {
+			prefix, first = "  - ", false
+		}
+		fmt.Fprintf(b, "%s%s: %s\n", prefix, key, value)
+	}
+	if len(d.series) == 1 {
+		entry("name", strconv.Quote(d.series[0].name))
+	}
+	if d.regex != "" {
+		entry("regex", "'"+strings.ReplaceAll(d.regex, "'", "''")+"'")
+	}
+	if d.field != "" {
+		entry("field", strconv.Quote(d.field))
+	}
+	entry("tag_filter", strconv.Quote(d.tag))
+	if len(d.series) == 1 {
+		d.series[0].writeFields(b, "    ")
+		return
+	}
+	b.WriteString("    series:\n")
+	for _, s := range d.series {
+		fmt.Fprintf(b, "      - name: %s\n", strconv.Quote(s.name))
+		s.writeFields(b, "        ")
+	}
+}
+
+// writeFields writes the value group, state mapping, and observed values of a series
+func (s draftSeries) writeFields(b *strings.Builder, indent string) {
+	if s.group > 0 {
+		fmt.Fprintf(b, "%svalue_group: %d  # %s\n", indent, s.group, s.note)
+	} else if s.note != "" {
+		fmt.Fprintf(b, "%s# %s\n", indent, s.note)
+	}
+	if len(s.states) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%sstate_group: %d\n", indent, s.group)
+	var mapping []string
+	for i, state := range s.states {
+		mapping = append(mapping, fmt.Sprintf("%s: %d", state, i))
+	}
+	fmt.Fprintf(b, "%sstate_mapping: {%s}\n", indent, strings.Join(mapping, ", "))
+	fmt.Fprintf(b, "%sstep: true\n", indent)
+}
+
+// timeField matches the names of timestamp fields of structured lines, which are not suggested
+var timeField = regexp.MustCompile(`(?i)^@?(time|timestamp|ts|date|datetime)$`)
+
+// fieldStats summarizes the numeric fields of structured (JSON) lines by tag and field path
+type fieldStats struct {
+	fields     map[string]*fieldStat
+	order      []string
+	timeFields map[string]string // First timestamp field path by tag
+}
+
+type fieldStat struct {
+	tag, path string
+	count     int
+	distinct  map[float64]bool
+	min, max  float64
+}
+
+func newFieldStats() *fieldStats {
+	return &fieldStats{fields: make(map[string]*fieldStat), timeFields: make(map[string]string)}
+}
+
+func (fs *fieldStats) add(line *parser.LogLine) {
+	for path, value := range line.Fields {
+		last := path[strings.LastIndex(path, ".")+1:]
+		if timeField.MatchString(last) {
+			if _, ok := fs.timeFields[line.Tag]; !ok {
+				fs.timeFields[line.Tag] = path
+			}
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		key := line.Tag + "\x00" + path
+		stat, ok := fs.fields[key]
+		if !ok {
+			stat = &fieldStat{tag: line.Tag, path: path, distinct: make(map[float64]bool), min: v, max: v}
+			fs.fields[key] = stat
+			fs.order = append(fs.order, key)
+		}
+		stat.count++
+		stat.min, stat.max = min(stat.min, v), max(stat.max, v)
+		if len(stat.distinct) < 2 {
+			stat.distinct[v] = true
+		}
+	}
+}
+
+// drafts suggests a field pattern for each numeric field with varying values
+func (fs *fieldStats) drafts(minCount int, names map[string]int) []*draftPattern {
+	sort.Strings(fs.order) // Map iteration added the fields in random order
+	var drafts []*draftPattern
+	for _, key := range fs.order {
+		stat := fs.fields[key]
+		if stat.count < minCount || len(stat.distinct) < 2 {
+			continue
+		}
+		drafts = append(drafts, &draftPattern{
+			comment: []string{fmt.Sprintf("%d structured lines of %s with the field %s", stat.count, stat.tag, stat.path)},
+			field:   stat.path,
+			tag:     stat.tag,
+			series:  []draftSeries{{name: uniqueName(stat.tag+" "+stat.path, names), note: fmt.Sprintf("%s to %s", formatValue(stat.min), formatValue(stat.max))}},
+			count:   stat.count,
+		})
+	}
+	return drafts
+}
+
+// timestampFormats returns the tag and field path of the timestamp fields found, by tag
+func (fs *fieldStats) timestampFormats() [][2]string {
+	var formats [][2]string
+	for tag, path := range fs.timeFields {
+		formats = append(formats, [2]string{tag, path})
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i][0] < formats[j][0] })
+	return formats
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/loki"
+	"log-interleaver/internal/otlp"
+	"log-interleaver/internal/visualizer"
+	"maps"
+	"net/url"
+	"os"
+)
+
+// runExport exports the configured metrics to data files, interactive HTML, and time error reports
+func runExport(ctx context.Context, args []string) error {
+	fs := newFlagSet("export", "Export the metrics extracted by the config patterns. At least one output must be given.")
+	input := addInputFlags(fs)
+	watch := addWatchFlags(fs)
+	csvOutput := fs.String("csv", "", "Export time series data to CSV file")
+	jsonOutput := fs.String("json", "", "Export time series data to JSON file")
+	parquetOutput := fs.String("parquet", "", "Export the metric points in long format (one row per point) to Parquet file")
+	htmlOutput := fs.String("html", "", "Export interactive HTML plot (uses Plotly.js)")
+	logHTMLOutput := fs.String("log-html", "", "Export the interleaved log as an HTML viewer with search, tag toggles, and a time slider synchronized to the plot")
+	plotlyMode := fs.String("plotly", visualizer.PlotlyAuto, "Plotly.js source for -html and -log-html: auto (inline the -plotly-js or built-in bundle, else load it from the CDN), embed (inline it or fail, works offline), or cdn")
+	plotlyJS := fs.String("plotly-js", "", "Local Plotly.js bundle to inline into -html and -log-html (for builds without the embedded bundle)")
+	htmlTheme := fs.String("html-theme", "", "Color theme for -html and -log-html: light or dark (default: theme from the config, else light)")
+	htmlCSS := fs.String("html-css", "", "CSS file to inject into -html and -log-html after the built-in styles (e.g., to match a dashboard)")
+	teReport := fs.String("te-report", "", "Export time error report (G.8273.2 max|TE|, cTE, dTE) to JSON file")
+	teReportHTML := fs.String("te-report-html", "", "Export time error report as an HTML section")
+	openMetrics := fs.String("openmetrics", "", "Export the series as OpenMetrics text with timestamps (for promtool tsdb create-blocks-from openmetrics)")
+	remoteWrite := fs.String("remote-write", "", "Send the series with timestamps to a Prometheus remote-write endpoint URL")
+	pushGateway := fs.String("pushgateway", "", "Push the latest value of each series to a Prometheus Pushgateway URL")
+	pushJob := fs.String("push-job", "log-interleaver", "Job name for -pushgateway")
+	metricLabels := fs.String("metric-labels", "", "Extra labels for -openmetrics, -remote-write, -pushgateway, and -loki, and resource attributes for -otlp (e.g., host=node1,run=42)")
+	otlpEndpoint := fs.String("otlp", "", "Send the lines as OTel log records and the series as OTel metrics to an OTLP/HTTP endpoint (e.g., http://collector:4318)")
+	otlpSignals := fs.String("otlp-signals", "logs,metrics", "Comma-separated signals sent to -otlp: logs, metrics")
+	otlpHeaders := fs.String("otlp-headers", os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), "Comma-separated name=value headers of -otlp requests (default: $OTEL_EXPORTER_OTLP_HEADERS)")
+	lokiEndpoint := fs.String("loki", "", "Push the lines with their resolved timestamps to a Grafana Loki endpoint (e.g., http://loki:3100), labeled by tag, filename, and node")
+	lokiTenant := fs.String("loki-tenant", "", "Tenant of -loki pushes, sent as X-Scope-OrgID")
+	esTarget := fs.String("export-es", "", "Bulk-index the lines with their extracted metric values into an Elasticsearch or OpenSearch index (e.g., http://host:9200/ptp-logs)")
+	fs.Parse(args)
+
+	if *csvOutput == "" && *jsonOutput == "" && *parquetOutput == "" && *htmlOutput == "" && *logHTMLOutput == "" && *teReport == "" && *teReportHTML == "" &&
+		*openMetrics == "" && *remoteWrite == "" && *pushGateway == "" && *otlpEndpoint == "" && *lokiEndpoint == "" && *esTarget == "" {
+		fs.Usage()
+		return fmt.Errorf("no export output given (use -csv, -json, -parquet, -html, -log-html, -te-report, -te-report-html, -openmetrics, -remote-write, -pushgateway, -otlp, -loki, or -export-es)")
+	}
+	labels, err := visualizer.ParseMetricLabels(*metricLabels)
+	if err != nil {
+		return err
+	}
+	var otlpLogs, otlpMetrics bool
+	for _, signal := range config.SplitList(*otlpSignals) {
+		switch signal {
+		case "logs":
+			otlpLogs = true
+		case "metrics":
+			otlpMetrics = true
+		default:
+			return fmt.Errorf("invalid -otlp-signals '%s', expected logs or metrics", signal)
+		}
+	}
+	var exporter *otlp.Exporter
+	if *otlpEndpoint != "" {
+		headers, err := otlp.ParseHeaders(*otlpHeaders)
+		if err != nil {
+			return err
+		}
+		if exporter, err = otlp.NewExporter(*otlpEndpoint, headers, labels); err != nil {
+			return err
+		}
+	}
+
+	var pusher *loki.Pusher
+	if *lokiEndpoint != "" {
+		// The node label defaults to this host, for logs collected on it
+		lokiLabels := maps.Clone(labels)
+		if _, ok := lokiLabels["node"]; !ok {
+			if host, err := os.Hostname(); err == nil {
+				lokiLabels["node"] = host
+			}
+		}
+		if pusher, err = loki.NewPusher(*lokiEndpoint, *lokiTenant, lokiLabels); err != nil {
+			return err
+		}
+	}
+
+	return watch.run(ctx, input, func(ctx context.Context) error {
+		iv, cfg, err := input.newInterleaver()
+		if err != nil {
+			return err
+		}
+		lines, err := input.process(ctx, iv)
+		if err != nil {
+			return err
+		}
+
+		if *csvOutput != "" {
+			// Export to CSV
+			logger.Debug("Exporting", "format", "csv", "location", *csvOutput)
+			if err := visualizer.ExportData(ctx, lines, input.configPath, input.configOptions(), *csvOutput); err != nil {
+				return fmt.Errorf("failed to export CSV: %w", err)
+			}
+			infof("CSV data exported to: %s", *csvOutput)
+		}
+
+		if *jsonOutput != "" {
+			// Export to JSON
+			logger.Debug("Exporting", "format", "json", "location", *jsonOutput)
+			if err := visualizer.ExportJSON(ctx, lines, input.configPath, input.configOptions(), *jsonOutput); err != nil {
+				return fmt.Errorf("failed to export JSON: %w", err)
+			}
+			infof("JSON data exported to: %s", *jsonOutput)
+		}
+
+		if *parquetOutput != "" {
+			// Export to Parquet
+			logger.Debug("Exporting", "format", "parquet", "location", *parquetOutput)
+			if err := visualizer.ExportParquet(ctx, lines, input.configPath, input.configOptions(), *parquetOutput); err != nil {
+				return fmt.Errorf("failed to export Parquet: %w", err)
+			}
+			infof("Parquet data exported to: %s", *parquetOutput)
+		}
+
+		opts := visualizer.HTMLOptions{PlotlyMode: *plotlyMode, PlotlyJS: *plotlyJS, Theme: *htmlTheme}
+		if *htmlCSS != "" {
+			css, err := os.ReadFile(*htmlCSS)
+			if err != nil {
+				return fmt.Errorf("failed to read -html-css: %w", err)
+			}
+			opts.CSS = string(css)
+		}
+
+		if *htmlOutput != "" {
+			// Export interactive HTML
+			logger.Debug("Exporting", "format", "html", "location", *htmlOutput)
+			if err := visualizer.GenerateInteractiveHTMLWithOptions(ctx, lines, input.configPath, input.configOptions(), *htmlOutput, opts); err != nil {
+				return fmt.Errorf("failed to export HTML: %w", err)
+			}
+			infof("Interactive HTML plot saved to: %s", *htmlOutput)
+			infof("Open in a web browser to view and interact with the plot")
+		}
+
+		if *logHTMLOutput != "" {
+			// Export the HTML log viewer
+			logger.Debug("Exporting", "format", "log-html", "location", *logHTMLOutput)
+			if err := visualizer.GenerateLogViewerHTML(ctx, lines, cfg, *logHTMLOutput, opts); err != nil {
+				return fmt.Errorf("failed to export HTML log viewer: %w", err)
+			}
+			infof("HTML log viewer saved to: %s", *logHTMLOutput)
+		}
+
+		if *teReport != "" {
+			// Export time error report
+			logger.Debug("Exporting", "format", "te-report", "location", *teReport)
+			if err := visualizer.ExportTEReport(ctx, lines, input.configPath, input.configOptions(), *teReport); err != nil {
+				return fmt.Errorf("failed to export time error report: %w", err)
+			}
+			infof("Time error report exported to: %s", *teReport)
+		}
+
+		if *teReportHTML != "" {
+			// Export time error report HTML section
+			if err := visualizer.ExportTEReportHTML(ctx, lines, input.configPath, input.configOptions(), *teReportHTML); err != nil {
+				return fmt.Errorf("failed to export time error report: %w", err)
+			}
+			infof("Time error report HTML section saved to: %s", *teReportHTML)
+		}
+
+		if *openMetrics != "" {
+			// Export OpenMetrics text
+			if err := visualizer.ExportOpenMetrics(ctx, lines, input.configPath, input.configOptions(), *openMetrics, labels); err != nil {
+				return fmt.Errorf("failed to export OpenMetrics: %w", err)
+			}
+			infof("OpenMetrics data exported to: %s", *openMetrics)
+		}
+
+		if *remoteWrite != "" {
+			// Send to a remote-write endpoint
+			if err := visualizer.PushRemoteWrite(ctx, lines, input.configPath, input.configOptions(), *remoteWrite, labels); err != nil {
+				return fmt.Errorf("failed to export to remote write: %w", err)
+			}
+			infof("Series sent to remote-write endpoint: %s", *remoteWrite)
+		}
+
+		if *pushGateway != "" {
+			// Push the latest values to a Pushgateway
+			if err := visualizer.PushGateway(ctx, lines, input.configPath, input.configOptions(), *pushGateway, *pushJob, labels); err != nil {
+				return fmt.Errorf("failed to export to Pushgateway: %w", err)
+			}
+			infof("Latest values pushed to Pushgateway: %s", *pushGateway)
+		}
+
+		if exporter != nil && otlpLogs {
+			// Send the lines as OTel log records, with the severities of the burst analysis
+			var severities []config.SeverityConfig
+			if cfg != nil {
+				severities = cfg.Severities
+			}
+			compiled, err := analysis.CompileSeverities(severities)
+			if err != nil {
+				return err
+			}
+			if err := exporter.ExportLogs(ctx, lines, compiled); err != nil {
+				return fmt.Errorf("failed to export OTLP logs: %w", err)
+			}
+			infof("%d log records sent to OTLP endpoint: %s", len(lines), *otlpEndpoint)
+		}
+
+		if pusher != nil {
+			// Push the lines to Loki
+			sent, err := pusher.Push(ctx, lines)
+			if err != nil {
+				return fmt.Errorf("failed to push to Loki: %w", err)
+			}
+			infof("%d lines pushed to Loki: %s", sent, pusher.Location())
+		}
+
+		if *esTarget != "" {
+			// Bulk-index the lines into Elasticsearch
+			indexed, err := visualizer.ExportElasticsearch(ctx, lines, cfg, *esTarget)
+			if err != nil {
+				return fmt.Errorf("failed to export to Elasticsearch: %w", err)
+			}
+			infof("%d documents indexed into Elasticsearch: %s", indexed, redactURL(*esTarget))
+		}
+
+		if exporter != nil && otlpMetrics {
+			// Send the series as OTel metrics
+			if err := visualizer.ExportOTLPMetrics(ctx, lines, input.configPath, input.configOptions(), exporter); err != nil {
+				return fmt.Errorf("failed to export OTLP metrics: %w", err)
+			}
+			infof("Series sent to OTLP endpoint: %s", *otlpEndpoint)
+		}
+
+		return nil
+	})
+}
+
+// redactURL removes the password from a URL, for user-facing messages
+func redactURL(location string) string {
+	if u, err := url.Parse(location); err == nil {
+		return u.Redacted()
+	}
+	return location
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"log-interleaver/pkg/timestamp"
+	"os"
+	"time"
+)
+
+// runInterleave merges the log files into a single time-ordered stream
+func runInterleave(ctx context.Context, args []string) error {
+	fs := newFlagSet("interleave", "Merge log files into a single stream ordered by timestamp, each line prefixed\nwith its resolved timestamp and tag.")
+	input := addInputFlags(fs)
+	watch := addWatchFlags(fs)
+	output := fs.String("output", "-", "Output location: file path, file://, s3://bucket/key, http(s):// PUT URL, or - for stdout")
+	format := fs.String("format", "text", "Output format: text (timestamp and tag prefix) or jsonl (one JSON object per line)")
+	timeFormat := fs.String("time-format", timestamp.DefaultOutputFormat, "Go time layout for output timestamps in text format (e.g., \"2006-01-02 15:04:05.000000000\")")
+	lineTemplate := fs.String("line-template", "", "Go template of the output lines in text format, with .Time, .Timestamp, .Tag, .File, .LineNumber, .Raw, and .Fields (e.g., \"{{.Time}} [{{.Tag}}:{{.LineNumber}}] {{.Raw}}\")")
+	noColor := fs.Bool("no-color", false, "Disable coloring lines by tag when writing to a terminal")
+	splitBy := fs.Duration("split-by", 0, "Write the output into one file per time window of this duration (e.g., 1h), named by the window start (e.g., out-2026-01-11T14.log)")
+	reorderWin := fs.Duration("reorder-window", 500*time.Millisecond, "Window for reordering the lines of a stream read from stdin")
+	statePath := fs.String("state", "", "State file of incremental runs: only data appended to the log files since the last run is read, and appended to -output")
+	fs.Parse(args)
+
+	if *format != "text" && *format != "jsonl" {
+		return fmt.Errorf("invalid -format '%s', expected text or jsonl", *format)
+	}
+	var tmpl *interleaver.LineTemplate
+	if *lineTemplate != "" {
+		if *format != "text" {
+			return fmt.Errorf("-line-template only applies to -format text")
+		}
+		var err error
+		if tmpl, err = interleaver.NewLineTemplate(*lineTemplate, *timeFormat); err != nil {
+			return err
+		}
+	}
+	if *splitBy != 0 && *splitBy < time.Second {
+		return fmt.Errorf("invalid -split-by '%s', expected a duration of at least 1s", *splitBy)
+	}
+	if *splitBy != 0 && sink.IsStdout(*output) {
+		return fmt.Errorf("-split-by requires an -output location to name the window files after")
+	}
+
+	// A piped stream is merged as it is written, unless the whole output is needed first
+	input.streaming = input.readsStdin() && !watch.enabled && *statePath == "" && *splitBy == 0
+
+	return watch.run(ctx, input, func(ctx context.Context) error {
+		iv, cfg, err := input.newInterleaver()
+		if err != nil {
+			return err
+		}
+		iv.SetReorderWindow(*reorderWin)
+		var checkpoint *interleaver.Checkpoint
+		if *statePath != "" {
+			if checkpoint, err = interleaver.LoadCheckpoint(*statePath); err != nil {
+				return err
+			}
+			iv.SetCheckpoint(checkpoint)
+		}
+		lines, err := input.process(ctx, iv)
+		if err != nil {
+			return err
+		}
+
+		open := sink.Open
+		if checkpoint != nil {
+			open = sink.OpenAppend
+			warnLateLines(lines, checkpoint)
+		}
+		var out sink.Sink
+		var split *splitOutput
+		if *splitBy != 0 {
+			split = newSplitOutput(*output, *splitBy, open, lines)
+			defer split.Close()
+		} else {
+			if out, err = open(*output); err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer out.Close()
+		}
+
+		// Color lines by tag on terminals (NO_COLOR is honored as well, see https://no-color.org)
+		var colorizer *interleaver.Colorizer
+		if *format == "text" && !*noColor && os.Getenv("NO_COLOR") == "" && sink.IsTerminal(*output) {
+			var tagColors map[string]string
+			if cfg != nil {
+				tagColors = cfg.TagColors
+			}
+			if colorizer, err = interleaver.NewColorizer(tagColors); err != nil {
+				return err
+			}
+			tags := lineTags(lines)
+			if input.streaming {
+				tags = append(tags, input.stdinTagName())
+			}
+			colorizer.AssignColors(tags)
+		}
+
+		write := func(line *parser.LogLine) error {
+			w := io.Writer(out)
+			if split != nil {
+				if w, err = split.writer(line); err != nil {
+					return err
+				}
+			}
+			if *format == "jsonl" {
+				record, err := interleaver.FormatLineJSON(line)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(w, record)
+				return nil
+			}
+			formatted := interleaver.FormatLine(line, *timeFormat)
+			if tmpl != nil {
+				if formatted, err = tmpl.Format(line); err != nil {
+					return err
+				}
+			}
+			if colorizer != nil {
+				formatted = colorizer.Colorize(line.Tag, formatted)
+			}
+			fmt.Fprintln(w, formatted)
+			return nil
+		}
+		if input.streaming {
+			filter, err := input.filter()
+			if err != nil {
+				return err
+			}
+			err = iv.Stream(ctx, lines, stdinName, input.stdinTagName(), os.Stdin, func(line *parser.LogLine) error {
+				if !filter.Match(line) {
+					return nil
+				}
+				return write(line)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to stream stdin: %w", err)
+			}
+		} else {
+			for _, line := range lines {
+				if err := write(line); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Closing the output flushes remote sinks (S3, HTTP PUT)
+		if split != nil {
+			if err := split.Close(); err != nil {
+				return err
+			}
+			infof("%d window files written", split.files)
+		} else if err := out.Close(); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+
+		// The state only advances once the lines are written, so a failed run is repeated
+		if checkpoint != nil {
+			for k := len(lines) - 1; k >= 0; k-- {
+				if ts := lines[k].GetTimestamp(); ts != nil {
+					written := ts.Time
+					checkpoint.Written = &written
+					break
+				}
+			}
+			if err := checkpoint.Save(*statePath); err != nil {
+				return err
+			}
+			logger.Debug("Saved state", "location", *statePath, "lines", len(lines))
+		}
+		return nil
+	})
+}
+
+// warnLateLines warns about new lines older than the last line written by the previous
+// incremental run, which can only be appended out of order
+func warnLateLines(lines []*parser.LogLine, checkpoint *interleaver.Checkpoint) {
+	if checkpoint.Written == nil {
+		return
+	}
+	late := 0
+	for _, line := range lines {
+		if ts := line.GetTimestamp(); ts != nil && ts.Time.Before(*checkpoint.Written) {
+			late++
+		}
+	}
+	if late > 0 {
+		warnf("%d new lines are older than the output of the previous run and are appended out of order", late)
+	}
+}
+
+// lineTags returns the distinct tags of the lines
+func lineTags(lines []*parser.LogLine) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, line := range lines {
+		if !seen[line.Tag] {
+			seen[line.Tag] = true
+			tags = append(tags, line.Tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/internal/websocket"
+	"log-interleaver/pkg/pattern"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// liveHello is the first message of a live plot page: the start time of its X axis and the X
+// offset of the last point of each of its series (null for none)
+type liveHello struct {
+	StartTime time.Time           `json:"start_time"`
+	Last      map[string]*float64 `json:"last"`
+}
+
+// liveUpdate carries the new points of the series, as X offsets and values
+type liveUpdate struct {
+	Points map[string]*livePoints `json:"points"`
+}
+
+type livePoints struct {
+	X []float64 `json:"x"`
+	Y []float64 `json:"y"`
+}
+
+// serveLive streams the points extracted after the last points of a plot page over a WebSocket,
+// checking the inputs for changes every live interval
+func (d *dashboard) serveLive(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	msg, err := conn.ReadMessage()
+	if err != nil {
+		return
+	}
+	var hello liveHello
+	if err := json.Unmarshal(msg, &hello); err != nil || hello.Last == nil {
+		return
+	}
+
+	// Reading on detects the page going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(d.liveInterval)
+	defer ticker.Stop()
+	var last *snapshot
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+
+		s, err := d.snapshot(false)
+		if err != nil || s == last {
+			continue
+		}
+		last = s
+		metrics, err := s.metrics(d)
+		if err != nil {
+			continue
+		}
+
+		update := liveUpdate{Points: make(map[string]*livePoints)}
+		for name, lastX := range hello.Last {
+			var points *livePoints
+			for _, pt := range metrics[name] {
+				x := pt.Time.Sub(hello.StartTime).Seconds()
+				if lastX != nil && x <= *lastX {
+					continue
+				}
+				if points == nil {
+					points = &livePoints{}
+				}
+				points.X = append(points.X, x)
+				points.Y = append(points.Y, pt.Value)
+			}
+			if points != nil {
+				update.Points[name] = points
+				hello.Last[name] = &points.X[len(points.X)-1]
+			}
+		}
+		if len(update.Points) == 0 {
+			continue
+		}
+		data, err := json.Marshal(update)
+		if err != nil {
+			return
+		}
+		if err := conn.WriteText(data); err != nil {
+			return
+		}
+	}
+}
+
+// metrics returns the metric points of the snapshot in time order, extracting them on first use
+func (s *snapshot) metrics(d *dashboard) (map[string][]pattern.MetricPoint, error) {
+	s.metricsOnce.Do(func() {
+		if !s.hasPlots() {
+			return
+		}
+		if s.metricPoints, s.metricsErr = visualizer.ExtractMetrics(d.ctx, s.lines, s.cfg); s.metricsErr != nil {
+			return
+		}
+		for _, points := range s.metricPoints {
+			sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+		}
+	})
+	return s.metricPoints, s.metricsErr
+}
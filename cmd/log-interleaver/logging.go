@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log-interleaver/internal/visualizer"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logger writes the diagnostics of all subcommands to stderr: warnings, errors, the locations of
+// written outputs, and with -v the progress of the processing phases
+var logger = slog.New(newTextHandler(os.Stderr, slog.LevelInfo))
+
+// logOptions holds the flags that control the diagnostics on stderr
+type logOptions struct {
+	verbose bool
+	quiet   bool
+	format  string
+}
+
+// addLogFlags registers the verbosity and diagnostics format flags on a subcommand's flag set
+func addLogFlags(fs *flag.FlagSet) *logOptions {
+	opts := &logOptions{}
+	fs.BoolVar(&opts.verbose, "v", false, "Report progress on stderr: files parsed, lines processed, and the current phase")
+	fs.BoolVar(&opts.quiet, "q", false, "Only report errors on stderr")
+	fs.StringVar(&opts.format, "log-format", "text", "Format of the diagnostics on stderr: text or json (one JSON object per message)")
+	return opts
+}
+
+// setup replaces the logger according to the flags
+func (o *logOptions) setup() error {
+	level := slog.LevelInfo
+	switch {
+	case o.verbose && o.quiet:
+		return fmt.Errorf("-v and -q are mutually exclusive")
+	case o.verbose:
+		level = slog.LevelDebug
+	case o.quiet:
+		level = slog.LevelError
+	}
+	switch o.format {
+	case "text":
+		logger = slog.New(newTextHandler(os.Stderr, level))
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	default:
+		return fmt.Errorf("invalid -log-format '%s', expected text or json", o.format)
+	}
+	visualizer.SetLogger(logger)
+	return nil
+}
+
+// infof logs an informational message, such as the location of a written output
+func infof(format string, args ...any) {
+	logger.Info(fmt.Sprintf(format, args...))
+}
+
+// warnf logs a warning
+func warnf(format string, args ...any) {
+	logger.Warn(fmt.Sprintf(format, args...))
+}
+
+// textHandler writes messages for terminals: warnings and errors with a "Warning:" or "Error:"
+// prefix, progress messages with the time since the start, followed by the attributes as
+// key=value pairs
+type textHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+	start time.Time
+}
+
+func newTextHandler(w io.Writer, level slog.Level) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, w: w, level: level, start: time.Now()}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	case r.Level < slog.LevelInfo:
+		fmt.Fprintf(&b, "[%7.2fs] ", time.Since(h.start).Seconds())
+	}
+	b.WriteString(r.Message)
+	writeAttr := func(a slog.Attr) bool {
+		if value := a.Value.Resolve().String(); strings.ContainsAny(value, " \t\"=") {
+			fmt.Fprintf(&b, " %s=%q", a.Key, value)
+		} else {
+			fmt.Fprintf(&b, " %s=%s", a.Key, value)
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &next
+}
+
+// WithGroup is not used by the subcommands; groups are flattened into the attributes
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -1,188 +1,459 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log-interleaver/internal/archive"
 	"log-interleaver/internal/config"
 	"log-interleaver/internal/interleaver"
 	"log-interleaver/internal/parser"
-	"log-interleaver/internal/visualizer"
+	"log-interleaver/internal/plugin"
+	"log-interleaver/internal/remote"
 	"os"
-	"strconv"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Embedded timezone database for hosts without one (e.g., minimal containers)
 )
 
+// command is a log-interleaver subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+// commands lists the subcommands in the order shown in the usage text
+var commands = []*command{
+	{name: "interleave", summary: "Merge log files into a single time-ordered stream", run: runInterleave},
+	{name: "plot", summary: "Generate a static plot image of the configured metrics", run: runPlot},
+	{name: "export", summary: "Export metrics to CSV, JSON, interactive HTML, time error reports, Prometheus, or OTLP", run: runExport},
+	{name: "analyze", summary: "Print statistics about the interleaved logs", run: runAnalyze},
+	{name: "check", summary: "Evaluate the assertions of the config, exiting non-zero on failures", run: runCheck},
+	{name: "pattern-test", summary: "Dry-run the patterns of the config, with sample matching and non-matching lines", run: runPatternTest},
+	{name: "compare", summary: "Compare the series statistics of two runs (analysis JSON, JSON export, or logs)", run: runCompare},
+	{name: "report", summary: "Write an HTML or Markdown summary report for bug reports", run: runReport},
+	{name: "tui", summary: "Browse the interleaved log in the terminal, with tag filters, search, and a metric sparkline", run: runTUI},
+	{name: "serve", summary: "Serve a dashboard of the log, plots, and analysis over HTTP", run: runServe},
+	{name: "collect", summary: "Collect pod logs through the Kubernetes API and interleave them", run: runCollect},
+	{name: "config", summary: "Write a draft config from the line templates of sample logs (config init)", run: runConfig},
+}
+
 func main() {
-	var (
-		logDir      = flag.String("logs", "logs", "Directory containing log files")
-		output      = flag.String("output", "", "Output file (default: stdout)")
-		analyze     = flag.Bool("analyze", false, "Run analysis on interleaved logs")
-		noAutoAlign = flag.Bool("no-auto-align", false, "Disable automatic timezone alignment")
-		offsets     = flag.String("offset", "", "Comma-separated file offsets in format tag:hours (e.g., e825:5,e830:5)")
-		visualize   = flag.Bool("visualize", false, "Generate visualization plot")
-		configPath  = flag.String("config", "config.yaml", "Path to visualization config file (YAML)")
-		plotOutput  = flag.String("plot-output", "plot.png", "Output path for plot image")
-		exportCSV   = flag.String("export-csv", "", "Export time series data to CSV file")
-		exportJSON  = flag.String("export-json", "", "Export time series data to JSON file")
-		exportHTML  = flag.String("export-html", "", "Export interactive HTML plot (uses Plotly.js)")
-	)
-	flag.Parse()
-
-	// Create interleaver
-	iv := interleaver.NewInterleaver(*logDir)
-	iv.SetAutoAlign(!*noAutoAlign)
+	args := os.Args[1:]
 
-	// Parse manual offsets
-	if *offsets != "" {
-		offsetPairs := strings.Split(*offsets, ",")
-		for _, pair := range offsetPairs {
-			parts := strings.Split(strings.TrimSpace(pair), ":")
-			if len(parts) != 2 {
-				fmt.Fprintf(os.Stderr, "Warning: invalid offset format '%s', expected tag:hours\n", pair)
-				continue
-			}
-			tag := strings.TrimSpace(parts[0])
-			hours, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: invalid hours value '%s': %v\n", parts[1], err)
-				continue
-			}
-			iv.SetFileOffset(tag, hours)
+	// Without a subcommand, flags are interleave flags (e.g., "log-interleaver -logs logs")
+	name := "interleave"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	} else if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	if name == "help" {
+		if len(args) == 0 {
+			usage()
+			return
 		}
+		// "help <command>" shows the command's own help text
+		name, args = args[0], []string{"-h"}
 	}
 
-	// Process logs
-	lines, err := iv.Process()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error processing logs: %v\n", err)
-		os.Exit(1)
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n\n", name)
+		usage()
+		os.Exit(2)
 	}
 
-	// Output results
-	var outputFile *os.File
-	if *output != "" {
-		outputFile, err = os.Create(*output)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
-			os.Exit(1)
+	// Ctrl-C and SIGTERM cancel the processing; a second signal terminates right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := cmd.run(ctx, args); err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Error("interrupted")
+			os.Exit(130)
+		}
+		logger.Error(err.Error())
+		var status *exitStatus
+		if errors.As(err, &status) {
+			os.Exit(status.code)
 		}
-		defer outputFile.Close()
-	} else {
-		outputFile = os.Stdout
+		os.Exit(1)
 	}
+}
 
-	// Write interleaved logs if output file is specified
-	// (always write when -output is provided, regardless of -visualize flag)
-	if *output != "" {
-		for _, line := range lines {
-			formatted := interleaver.FormatLine(line)
-			fmt.Fprintln(outputFile, formatted)
-		}
-	} else if !*visualize {
-		// Only write to stdout if not visualizing and no output file specified
-		for _, line := range lines {
-			formatted := interleaver.FormatLine(line)
-			fmt.Fprintln(outputFile, formatted)
+// exitStatus is an error that ends the program with a specific exit status
+type exitStatus struct {
+	code int
+	err  error
+}
+
+func (e *exitStatus) Error() string { return e.err.Error() }
+
+func (e *exitStatus) Unwrap() error { return e.err }
+
+// findCommand returns the subcommand with the given name, or nil
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
 		}
 	}
+	return nil
+}
 
-	if *analyze {
-		// Run basic analysis
-		analyzeLogs(lines, outputFile)
+// usage prints the top-level help text
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: log-interleaver <command> [options]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun 'log-interleaver help <command>' or 'log-interleaver <command> -h' for the options of a command.\n")
+}
 
-	if *visualize {
-		// Generate visualization
-		if err := generateVisualization(lines, *configPath, *plotOutput); err != nil {
-			fmt.Fprintf(os.Stderr, "Error generating visualization: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Fprintf(os.Stderr, "Plot saved to: %s\n", *plotOutput)
+// newFlagSet creates the flag set of a subcommand with its own help text
+func newFlagSet(name, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: log-interleaver %s [options]\n\n%s\n\nOptions:\n", name, description)
+		fs.PrintDefaults()
 	}
+	return fs
+}
+
+// inputOptions holds the flags shared by all subcommands that read log files
+type inputOptions struct {
+	logDir       string
+	fileList     string
+	extensions   string
+	configPath   string
+	noAutoAlign  bool
+	offsets      string
+	alignRound   time.Duration
+	alignMethod  string
+	alignDrift   bool
+	alignReport  string
+	baseYear     int
+	includeTags  string
+	excludeTags  string
+	grep         string
+	grepV        string
+	dedupe       bool
+	tagPriority  string
+	gnssTags     string
+	multiline    bool
+	bootTimes    string
+	stdinTag     string
+	remoteTail   int
+	archivePaths []archive.Rule
+	presets      config.Presets
+	overrides    config.Overrides
+	log          *logOptions
+
+	inputFiles []interleaver.InputFile // Files read by the last process call
+	stdin      []byte                  // Stream read from stdin, kept for reruns (watch, serve)
+	stdinRead  bool
+	streaming  bool // Whether stdin is left to Stream rather than read in full by newInterleaver
+}
+
+// stdinName identifies the stdin source in the file of its lines and in the input files
+const stdinName = "stdin"
 
-	if *exportCSV != "" {
-		// Export to CSV
-		if err := exportToCSV(lines, *configPath, *exportCSV); err != nil {
-			fmt.Fprintf(os.Stderr, "Error exporting CSV: %v\n", err)
-			os.Exit(1)
+// addInputFlags registers the log input, alignment, and filter flags on a subcommand's flag set
+func addInputFlags(fs *flag.FlagSet) *inputOptions {
+	opts := &inputOptions{log: addLogFlags(fs)}
+	fs.StringVar(&opts.logDir, "logs", "logs", "Directory containing log files, a sosreport or must-gather archive (.tar, .tar.gz, .tar.bz2, .tar.xz), or - to read only stdin")
+	fs.Func("archive-path", "Path glob of log files to read from a -logs archive, as glob or glob=tag with $1, $2, ... for the segments matched by wildcards (e.g., '**/pods/*/*/*/logs/current.log=$2'); tried before the built-in journal, syslog, and PTP operator pod globs (repeatable)", func(spec string) error {
+		rule, err := archive.ParseRule(spec)
+		if err != nil {
+			return err
 		}
-		fmt.Fprintf(os.Stderr, "CSV data exported to: %s\n", *exportCSV)
+		opts.archivePaths = append(opts.archivePaths, rule)
+		return nil
+	})
+	fs.StringVar(&opts.stdinTag, "stdin-tag", "", "Read a piped stream from stdin as a source with this tag, merged with the log files (default with -logs -: stdin)")
+	fs.StringVar(&opts.fileList, "files", "", "Comma-separated list of log files to read instead of scanning -logs (e.g., a.log,b.out), including remote files as ssh://[user@]host:/path, tagged by host name")
+	fs.IntVar(&opts.remoteTail, "remote-tail", 0, "Only fetch the last lines of each remote (ssh://) file (0 = whole files)")
+	fs.StringVar(&opts.extensions, "extensions", ".txt,.log,.pcap,.pcapng", "Comma-separated file extensions read from -logs (empty = all files)")
+	fs.StringVar(&opts.configPath, "config", "config.yaml", "Path to config file (YAML)")
+	fs.BoolVar(&opts.noAutoAlign, "no-auto-align", false, "Disable automatic timezone alignment")
+	fs.StringVar(&opts.offsets, "offset", "", "Comma-separated file offsets in format tag:hours or tag:duration (e.g., e825:5,e830:+5h30m,gnss:-37s)")
+	fs.DurationVar(&opts.alignRound, "align-round", time.Hour, "Round automatic alignment offsets to this granularity (0 = no rounding)")
+	fs.StringVar(&opts.alignMethod, "align-method", "auto", "Automatic alignment method: first (first timestamps), events (shared event correlation), or auto (events, falling back to first)")
+	fs.BoolVar(&opts.alignDrift, "align-drift", false, "Also fit and correct a linear clock drift of the tags aligned by events, from shared events spread over at least 10m")
+	fs.StringVar(&opts.alignReport, "align-report", "", "Write the reference tag and, per tag, the offset applied with its source and evidence to this location, or - for stderr")
+	fs.IntVar(&opts.baseYear, "base-year", 0, "Year of the first yearless timestamp in each file (default: infer from full dates or file modification time)")
+	fs.StringVar(&opts.includeTags, "include-tags", "", "Comma-separated tags to keep in the output, analysis, and metrics (default: all)")
+	fs.StringVar(&opts.excludeTags, "exclude-tags", "", "Comma-separated tags to drop from the output, analysis, and metrics")
+	fs.StringVar(&opts.grep, "grep", "", "Only keep lines matching this regex")
+	fs.StringVar(&opts.grepV, "grep-v", "", "Drop lines matching this regex")
+	fs.BoolVar(&opts.dedupe, "dedupe", false, "Drop duplicate lines (same timestamp, tag, and content) from overlapping captures")
+	fs.StringVar(&opts.tagPriority, "tag-priority", "", "Comma-separated tag order for lines with equal timestamps (default: tag_priority from the config, then alphabetical)")
+	fs.StringVar(&opts.gnssTags, "gnss-tags", "", "Comma-separated tags of GNSS time source logs (NMEA sentences or gpsd JSON), anchored to the GNSS time of their sentences (default: gnss_tags from the config)")
+	fs.BoolVar(&opts.multiline, "multiline", false, "Attach timestamp-less continuation lines (stack traces, dumps) to the preceding timestamped line")
+	fs.StringVar(&opts.bootTimes, "boot-time", "", "Boot time (RFC 3339) for resolving kernel/uptime timestamps, either for all tags or as tag=time pairs (e.g., dmesg=2026-01-11T08:00:00Z)")
+	fs.Func("preset", "Add the bundled patterns of a daemon: "+strings.Join(config.PresetNames(), ", ")+", optionally for one tag as name:tag (e.g., ptp4l:e810; comma-separated, repeatable)", opts.presets.Add)
+	fs.Func("set", "Override a config value as path=value, e.g., patterns[0].color=red or gap_threshold=30s (repeatable)", opts.overrides.Add)
+	return opts
+}
+
+// process interleaves the log files and applies the tag and message filters.
+// Alignment uses all lines, so filtering does not change the resolved timestamps.
+func (o *inputOptions) process(ctx context.Context, iv *interleaver.Interleaver) ([]*parser.LogLine, error) {
+	filter, err := o.filter()
+	if err != nil {
+		return nil, err
 	}
 
-	if *exportJSON != "" {
-		// Export to JSON
-		if err := exportToJSON(lines, *configPath, *exportJSON); err != nil {
-			fmt.Fprintf(os.Stderr, "Error exporting JSON: %v\n", err)
-			os.Exit(1)
+	start := time.Now()
+	if archive.IsArchive(o.logDir) {
+		if err := o.addArchive(ctx, iv); err != nil {
+			return nil, err
 		}
-		fmt.Fprintf(os.Stderr, "JSON data exported to: %s\n", *exportJSON)
 	}
+	if err := o.addRemoteFiles(ctx, iv); err != nil {
+		return nil, err
+	}
+	lines, err := iv.Process(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process logs: %w", err)
+	}
+	o.inputFiles = iv.InputFiles()
+	if err := o.writeAlignReport(iv); err != nil {
+		return nil, err
+	}
+	kept := filter.Apply(lines)
+	logger.Debug("Processed logs", "files", len(iv.InputFiles()), "lines", len(lines), "kept", len(kept), "elapsed", time.Since(start).Round(time.Millisecond).String())
+	return kept, nil
+}
+
+// filter returns the tag and message filter of the flags
+func (o *inputOptions) filter() (*interleaver.Filter, error) {
+	return interleaver.NewFilter(config.SplitList(o.includeTags), config.SplitList(o.excludeTags), o.grep, o.grepV)
+}
 
-	if *exportHTML != "" {
-		// Export interactive HTML
-		if err := exportToHTML(lines, *configPath, *exportHTML); err != nil {
-			fmt.Fprintf(os.Stderr, "Error exporting HTML: %v\n", err)
-			os.Exit(1)
+// fingerprint identifies the state of the inputs by the size and modification time of the
+// config, the log directory (for added or removed files), and the log files read by the last
+// process call
+func (o *inputOptions) fingerprint() string {
+	paths := []string{o.configPath}
+	if o.fileList == "" && o.logDir != "-" {
+		paths = append(paths, o.logDir)
+	}
+	for _, f := range o.inputFiles {
+		if o.readsStdin() && f.Path == stdinName || remote.IsRemote(f.Path) {
+			// Not tracked: stdin is read once and merged again by every run, and remote files are
+			// fetched again by every run but can't be checked for changes without fetching them
+			continue
+		}
+		paths = append(paths, f.Path)
+	}
+	var b strings.Builder
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		} else {
+			fmt.Fprintf(&b, "%s:missing;", path)
 		}
-		fmt.Fprintf(os.Stderr, "Interactive HTML plot saved to: %s\n", *exportHTML)
-		fmt.Fprintf(os.Stderr, "Open in a web browser to view and interact with the plot\n")
 	}
+	return b.String()
 }
 
-func generateVisualization(lines []*parser.LogLine, configPath, outputPath string) error {
-	// Load configuration
-	cfg, err := config.LoadConfig(configPath)
+// addArchive adds the log files of the -logs archive as sources
+func (o *inputOptions) addArchive(ctx context.Context, iv *interleaver.Interleaver) error {
+	files, err := archive.Read(ctx, o.logDir, append(slices.Clone(o.archivePaths), archive.DefaultRules...))
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
-
-	// Create visualizer
-	viz := visualizer.NewVisualizer(cfg)
-
-	// Generate plot
-	return viz.GeneratePlot(lines, outputPath)
+	if len(files) == 0 {
+		return fmt.Errorf("no log files matching the archive paths found in '%s', use -archive-path", o.logDir)
+	}
+	for _, f := range files {
+		iv.AddReader(f.Name, f.Tag, bytes.NewReader(f.Data), f.ModTime)
+	}
+	logger.Debug("Read archive", "archive", o.logDir, "files", len(files))
+	return nil
 }
 
-func exportToCSV(lines []*parser.LogLine, configPath, outputPath string) error {
-	return visualizer.ExportData(lines, configPath, outputPath)
+// addRemoteFiles fetches the remote files of -files in parallel and adds them as sources
+func (o *inputOptions) addRemoteFiles(ctx context.Context, iv *interleaver.Interleaver) error {
+	var sources []remote.Source
+	for _, spec := range config.SplitList(o.fileList) {
+		if !remote.IsRemote(spec) {
+			continue
+		}
+		source, err := remote.Parse(spec)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	start := time.Now()
+	data, err := remote.FetchAll(ctx, sources, o.remoteTail)
+	if err != nil {
+		return err
+	}
+	for k, tag := range remote.Tags(sources) {
+		iv.AddReader(sources[k].Spec, tag, bytes.NewReader(data[k]), time.Time{})
+	}
+	logger.Debug("Fetched remote files", "files", len(sources), "elapsed", time.Since(start).Round(time.Millisecond).String())
+	return nil
 }
 
-func exportToJSON(lines []*parser.LogLine, configPath, outputPath string) error {
-	return visualizer.ExportJSON(lines, configPath, outputPath)
+// readsStdin reports whether stdin is one of the sources
+func (o *inputOptions) readsStdin() bool {
+	return o.logDir == "-" || o.stdinTag != ""
 }
 
-func exportToHTML(lines []*parser.LogLine, configPath, outputPath string) error {
-	return visualizer.GenerateInteractiveHTML(lines, configPath, outputPath)
+// stdinTagName returns the tag of the stdin source
+func (o *inputOptions) stdinTagName() string {
+	if o.stdinTag != "" {
+		return o.stdinTag
+	}
+	return stdinName
 }
 
-func analyzeLogs(lines []*parser.LogLine, output *os.File) {
-	// Basic statistics
-	fmt.Fprintf(output, "\n=== Analysis ===\n")
-	fmt.Fprintf(output, "Total log lines: %d\n", len(lines))
+// readStdin reads stdin to its end on first use, so reruns merge the same stream
+func (o *inputOptions) readStdin() ([]byte, error) {
+	if !o.stdinRead {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		o.stdin, o.stdinRead = data, true
+	}
+	return o.stdin, nil
+}
 
-	// Count by tag
-	tagCounts := make(map[string]int)
-	for _, line := range lines {
-		tagCounts[line.Tag]++
+// newInterleaver creates an interleaver configured from the input flags and the config file
+// (if present). The returned config is nil when the config file does not exist.
+func (o *inputOptions) newInterleaver() (*interleaver.Interleaver, *config.VisualizationConfig, error) {
+	if err := o.log.setup(); err != nil {
+		return nil, nil, err
+	}
+	logDir := o.logDir
+	if logDir == "-" || archive.IsArchive(logDir) {
+		logDir = ""
+	}
+	var paths []string
+	for _, path := range config.SplitList(o.fileList) {
+		if !remote.IsRemote(path) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 && o.fileList != "" {
+		// Remote files only
+		logDir = ""
+	}
+	iv := interleaver.NewInterleaver(logDir)
+	iv.SetLogger(logger)
+	iv.SetExtensions(strings.Split(o.extensions, ","))
+	if len(paths) > 0 {
+		iv.SetFiles(paths)
+	}
+	if o.readsStdin() && !o.streaming {
+		data, err := o.readStdin()
+		if err != nil {
+			return nil, nil, err
+		}
+		iv.AddReader(stdinName, o.stdinTagName(), bytes.NewReader(data), time.Time{})
+	}
+	iv.SetAutoAlign(!o.noAutoAlign)
+	iv.SetAlignRounding(o.alignRound)
+	switch method := interleaver.AlignMethod(o.alignMethod); method {
+	case interleaver.AlignFirstTimestamp, interleaver.AlignEvents, interleaver.AlignAuto:
+		iv.SetAlignMethod(method)
+	default:
+		return nil, nil, fmt.Errorf("invalid -align-method '%s', expected first, events, or auto", o.alignMethod)
+	}
+	iv.SetDriftCorrection(o.alignDrift)
+	iv.SetBaseYear(o.baseYear)
+	iv.SetDedupe(o.dedupe)
+	iv.SetGroupContinuations(o.multiline)
+	for _, spec := range config.SplitList(o.bootTimes) {
+		tag, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			tag, value = "", spec
+		}
+		bootTime, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid -boot-time '%s', expected an RFC 3339 time like 2026-01-11T08:00:00Z", spec)
+		}
+		iv.SetBootTime(tag, bootTime)
 	}
 
-	fmt.Fprintf(output, "\nLines by tag:\n")
-	for tag, count := range tagCounts {
-		fmt.Fprintf(output, "  %s: %d\n", tag, count)
+	// Parse manual offsets
+	if o.offsets != "" {
+		offsetPairs := strings.Split(o.offsets, ",")
+		for _, pair := range offsetPairs {
+			tag, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok {
+				warnf("invalid offset format '%s', expected tag:offset", pair)
+				continue
+			}
+			offset, err := interleaver.ParseOffset(value)
+			if err != nil {
+				logger.Warn(err.Error())
+				continue
+			}
+			iv.SetFileOffsetDuration(strings.TrimSpace(tag), offset)
+		}
 	}
 
-	// Count lines with/without timestamps
-	withTimestamp := 0
-	withoutTimestamp := 0
-	for _, line := range lines {
-		if line.GetTimestamp() != nil {
-			withTimestamp++
-		} else {
-			withoutTimestamp++
+	// Register user-defined timestamp formats and tag rules from the config (if present)
+	cfg, err := o.loadConfigIfExists()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg != nil {
+		resolvers := make(map[string]interleaver.TimestampResolver, len(cfg.Plugins))
+		for name, p := range plugin.FromConfig(cfg) {
+			resolvers[name] = p
 		}
+		if err := iv.ApplyConfig(cfg, resolvers); err != nil {
+			return nil, nil, err
+		}
+	}
+	if tags := config.SplitList(o.tagPriority); len(tags) > 0 {
+		iv.SetTagPriority(tags)
+	}
+	if tags := config.SplitList(o.gnssTags); len(tags) > 0 {
+		iv.SetGNSSTags(tags)
 	}
 
-	fmt.Fprintf(output, "\nTimestamp coverage:\n")
-	fmt.Fprintf(output, "  With timestamp: %d\n", withTimestamp)
-	fmt.Fprintf(output, "  Without timestamp: %d\n", withoutTimestamp)
+	return iv, cfg, nil
+}
+
+// loadConfigIfExists loads the config file, returning nil if it does not exist and no presets
+// are selected
+func (o *inputOptions) loadConfigIfExists() (*config.VisualizationConfig, error) {
+	if _, err := os.Stat(o.configPath); os.IsNotExist(err) && len(o.presets) == 0 {
+		return nil, nil
+	}
+	return config.LoadConfig(o.configPath, o.configOptions())
+}
+
+// configOptions returns the presets and overrides of the flags and the log directory of the
+// config
+func (o *inputOptions) configOptions() config.Options {
+	opts := config.Options{Presets: o.presets, Overrides: o.overrides}
+	if o.logDir != "-" {
+		opts.LogDir = o.logDir
+	}
+	return opts
 }
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/pkg/pattern"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxSampleWidth is the number of characters of a sample line shown by pattern-test
+const maxSampleWidth = 160
+
+// runPatternTest prints how the patterns of the config match the logs, with sample lines
+func runPatternTest(ctx context.Context, args []string) error {
+	fs := newFlagSet("pattern-test", "Dry-run the patterns of the config against the logs: for each pattern, the number of\nmatching lines, sample matches with their extracted values, and sample lines of its tag\nthat do not match. Exits with status 1 when a pattern matches no lines.")
+	input := addInputFlags(fs)
+	names := fs.String("pattern", "", "Comma-separated names of the patterns to test (default: all)")
+	samples := fs.Int("samples", 3, "Number of sample lines shown per category")
+	output := fs.String("output", "-", "Output location, or - for stdout")
+	fs.Parse(args)
+
+	iv, cfg, err := input.newInterleaver()
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return fmt.Errorf("config file '%s' not found", input.configPath)
+	}
+	var patterns []*config.PatternConfig
+	for i := range cfg.Patterns {
+		p := &cfg.Patterns[i]
+		if p.RollingStat != "" {
+			// Derived series of a rolling overlay match like their source
+			continue
+		}
+		if selected := config.SplitList(*names); len(selected) == 0 || slices.Contains(selected, p.Name) {
+			patterns = append(patterns, p)
+		}
+	}
+	if len(patterns) == 0 {
+		return fmt.Errorf("no patterns to test (the config has none, or -pattern names none of them)")
+	}
+	lines, err := input.process(ctx, iv)
+	if err != nil {
+		return err
+	}
+
+	results, err := testPatterns(ctx, lines, cfg, patterns, *samples)
+	if err != nil {
+		return err
+	}
+
+	out, err := sink.Open(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer out.Close()
+	unmatched := 0
+	for _, result := range results {
+		printPatternTest(out, result)
+		if result.matched == 0 {
+			unmatched++
+		}
+	}
+	fmt.Fprintf(out, "%d of %d patterns matched\n", len(results)-unmatched, len(results))
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	if unmatched > 0 {
+		return &exitStatus{code: 1, err: fmt.Errorf("%d of %d patterns matched no lines", unmatched, len(results))}
+	}
+	return nil
+}
+
+// patternTest is the result of testing one pattern against the logs
+type patternTest struct {
+	pattern     *config.PatternConfig
+	candidates  int // Lines of the tag filter
+	untimed     int // Candidate lines without a timestamp, which have no points
+	matched     int // Lines with points
+	points      int
+	noValue     int // Lines matching the regex without a value
+	nonMatching int
+	matches     []testSample
+	noValues    []testSample
+	nonMatches  []testSample
+}
+
+// testSample is a sample line with what the pattern made of it
+type testSample struct {
+	line   *parser.LogLine
+	detail string
+}
+
+// testPatterns matches the lines with the patterns, taking the points from the pattern matcher
+// so values are extracted exactly as for plots and exports
+func testPatterns(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig, patterns []*config.PatternConfig, samples int) ([]*patternTest, error) {
+	matcher, err := visualizer.NewPatternMatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := matcher.Prepare(ctx, lines); err != nil {
+		return nil, fmt.Errorf("failed to extract metrics: %w", err)
+	}
+	regexes := make([]*regexp.Regexp, len(patterns))
+	results := make([]*patternTest, len(patterns))
+	for k, p := range patterns {
+		results[k] = &patternTest{pattern: p}
+		if p.Regex != "" {
+			// Compiled by the matcher already, so the regex is valid
+			regexes[k] = regexp.MustCompile(p.Regex)
+		} else if p.Field != "" && p.Extractor == "" && p.Plugin == "" {
+			// Without a regex, the whole field value is the value
+			regexes[k] = regexp.MustCompile(`(?s)^.*$`)
+		}
+	}
+
+	for _, line := range lines {
+		pointsBySeries := make(map[string][]pattern.MetricPoint)
+		for _, point := range matcher.MatchLine(line) {
+			pointsBySeries[point.SeriesName] = append(pointsBySeries[point.SeriesName], point)
+		}
+		for k, p := range patterns {
+			result := results[k]
+			if p.TagFilter != "" && line.Tag != p.TagFilter {
+				continue
+			}
+			result.candidates++
+			if line.Timestamp == nil {
+				result.untimed++
+				continue
+			}
+			if points := pointsBySeries[p.Name]; len(points) > 0 {
+				result.matched++
+				result.points += len(points)
+				if len(result.matches) < samples {
+					result.matches = append(result.matches, testSample{line, pointValues(p, points)})
+				}
+				continue
+			}
+			text, ok := line.OriginalLine, true
+			if p.Field != "" {
+				text, ok = line.Fields[p.Field]
+			}
+			if matches := matchText(regexes[k], text, ok); matches != nil {
+				result.noValue++
+				if len(result.noValues) < samples {
+					result.noValues = append(result.noValues, testSample{line, missingValue(p, matches)})
+				}
+				continue
+			}
+			result.nonMatching++
+			if len(result.nonMatches) < samples {
+				result.nonMatches = append(result.nonMatches, testSample{line: line})
+			}
+		}
+	}
+	return results, ctx.Err()
+}
+
+// matchText returns the submatches of the regex in the text of a line, or nil without a regex
+// or text (a structured field missing from the line)
+func matchText(regex *regexp.Regexp, text string, ok bool) []string {
+	if regex == nil || !ok {
+		return nil
+	}
+	return regex.FindStringSubmatch(text)
+}
+
+// pointValues describes the points extracted from a line
+func pointValues(p *config.PatternConfig, points []pattern.MetricPoint) string {
+	var values []string
+	for _, pt := range points {
+		switch {
+		case p.IsEvent() && pt.State != "":
+			values = append(values, "event: "+pt.State)
+		case p.IsEvent():
+			values = append(values, "event")
+		case pt.State != "" && p.StateGroup == p.ValueGroup:
+			values = append(values, fmt.Sprintf("%s = %g", pt.State, pt.Value))
+		default:
+			value := strconv.FormatFloat(pt.Value, 'g', -1, 64)
+			if p.DisplayUnit != "" {
+				value += " " + p.DisplayUnit
+			}
+			if pt.State != "" {
+				value += " (state " + pt.State + ")"
+			}
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// missingValue explains why a line matching the regex of a pattern has no value
+func missingValue(p *config.PatternConfig, matches []string) string {
+	switch {
+	case p.Extractor != "":
+		return fmt.Sprintf("extractor '%s' has no %s value for the line", p.Extractor, p.ExtractorSeries)
+	case p.Plugin != "":
+		return fmt.Sprintf("plugin '%s' returned no %s value for the line", p.Plugin, p.PluginSeries)
+	case p.Regex == "":
+		return fmt.Sprintf("field %s '%s' is not a number", p.Field, matches[0])
+	case p.ValueGroup >= len(matches):
+		return fmt.Sprintf("the regex has no value_group %d", p.ValueGroup)
+	case p.StateGroup > 0 && p.StateGroup == p.ValueGroup:
+		return fmt.Sprintf("state '%s' is not in the state_mapping", matches[p.ValueGroup])
+	default:
+		return fmt.Sprintf("value_group %d '%s' is not a number", p.ValueGroup, matches[p.ValueGroup])
+	}
+}
+
+// printPatternTest prints the result of testing a pattern
+func printPatternTest(w io.Writer, result *patternTest) {
+	p := result.pattern
+	scope := "all tags"
+	if p.TagFilter != "" {
+		scope = "tag " + p.TagFilter
+	}
+	fmt.Fprintf(w, "Pattern '%s' (%s):\n", p.Name, scope)
+	if p.Regex != "" {
+		fmt.Fprintf(w, "  regex: %s\n", p.Regex)
+	}
+	fmt.Fprintf(w, "  %d of %d lines matched", result.matched, result.candidates)
+	if !p.IsEvent() {
+		fmt.Fprintf(w, " (%d points)", result.points)
+	}
+	fmt.Fprintln(w)
+	if result.untimed > 0 {
+		fmt.Fprintf(w, "  %d lines without a timestamp were skipped\n", result.untimed)
+	}
+	printSamples(w, "Matches", result.matched, result.matches)
+	printSamples(w, "Matched without a value", result.noValue, result.noValues)
+	printSamples(w, "Not matching", result.nonMatching, result.nonMatches)
+	fmt.Fprintln(w)
+}
+
+// printSamples prints the sample lines of a category with their details
+func printSamples(w io.Writer, title string, count int, samples []testSample) {
+	if len(samples) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  %s (%d):\n", title, count)
+	for _, s := range samples {
+		text := s.line.OriginalLine
+		if runes := []rune(text); len(runes) > maxSampleWidth {
+			text = string(runes[:maxSampleWidth]) + "..."
+		}
+		fmt.Fprintf(w, "    %s:%d: %s\n", s.line.File, s.line.LineNumber, text)
+		if s.detail != "" {
+			fmt.Fprintf(w, "      -> %s\n", s.detail)
+		}
+	}
+}
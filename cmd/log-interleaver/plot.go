@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"log-interleaver/internal/visualizer"
+	"strings"
+)
+
+// runPlot generates a static plot image of the configured metrics
+func runPlot(ctx context.Context, args []string) error {
+	fs := newFlagSet("plot", "Generate a static plot of the metrics extracted by the config patterns.\nThe image format follows the output extension (.png, .svg, .pdf, .jpg, ...) unless -plot-format is given.")
+	input := addInputFlags(fs)
+	watch := addWatchFlags(fs)
+	output := fs.String("output", "plot.png", "Output location for the plot image (with plots in the config: for the plots without an output, numbered, e.g., plot-1.png; - takes a single one)")
+	format := fs.String("plot-format", "", "Plot image format: "+strings.Join(visualizer.PlotFormats, ", ")+" (default: from the output extension, else png)")
+	fs.Parse(args)
+
+	return watch.run(ctx, input, func(ctx context.Context) error {
+		iv, _, err := input.newInterleaver()
+		if err != nil {
+			return err
+		}
+		lines, err := input.process(ctx, iv)
+		if err != nil {
+			return err
+		}
+
+		if err := generateVisualization(ctx, lines, input.configPath, input.configOptions(), *output, *format); err != nil {
+			return fmt.Errorf("failed to generate visualization: %w", err)
+		}
+		return nil
+	})
+}
+
+// generateVisualization generates the plot of the config at the output location, or each of
+// the plots of its plots section
+func generateVisualization(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath, format string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(configPath, configOpts)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(cfg.Plots) == 0 {
+		return generatePlot(ctx, lines, cfg, outputPath, format)
+	}
+	// Stdout takes a single image, so it only stands in for the output of one plot
+	unnamed := 0
+	for _, p := range cfg.Plots {
+		if p.Output == "" {
+			unnamed++
+		}
+	}
+	if outputPath == "-" && unnamed > 1 {
+		return fmt.Errorf("%d plots have no output, but only one can be written to stdout", unnamed)
+	}
+	for i := range cfg.Plots {
+		location := cfg.Plots[i].Output
+		if location == "" && outputPath == "-" {
+			location = outputPath
+		} else if location == "" {
+			location = sink.WithSuffix(outputPath, fmt.Sprintf("-%d", i+1))
+		}
+		if err := generatePlot(ctx, lines, cfg.Plots[i].Config(), location, format); err != nil {
+			return fmt.Errorf("plot %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// generatePlot generates the plot of a config at an output location
+func generatePlot(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig, location, format string) error {
+	logger.Debug("Generating plot", "location", location)
+	viz := visualizer.NewVisualizer(cfg)
+	viz.SetFormat(format)
+	if err := viz.GeneratePlot(ctx, lines, location); err != nil {
+		return err
+	}
+	infof("Plot saved to: %s", location)
+	return nil
+}
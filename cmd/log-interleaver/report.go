@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/pkg/report"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runReport writes a summary report of the run: metadata, alignment, statistics, plot, and anomalies
+func runReport(ctx context.Context, args []string) error {
+	fs := newFlagSet("report", "Write a self-contained summary report for bug reports: the run metadata, the alignment\noffsets applied, per-tag and metric statistics, the plot, and the detected gaps and error bursts.\nThe format follows the output extension (.html, .md) unless -format is given.")
+	input := addInputFlags(fs)
+	output := fs.String("output", "report.html", "Output location for the report")
+	format := fs.String("format", "", "Report format: "+strings.Join(visualizer.SummaryFormats, " or ")+" (default: markdown for .md outputs, else html)")
+	title := fs.String("title", "", "Report title (default: title from the config, else \"Log Report\")")
+	noPlot := fs.Bool("no-plot", false, "Leave the plot out of the report")
+	reorderWin := fs.Duration("reorder-window", 500*time.Millisecond, "Window for reordering out-of-order source lines")
+	gapThreshold := fs.Duration("gap-threshold", 0, "Report periods longer than this without lines from a tag as gaps (default: gap_threshold from the config, else 1m)")
+	burstGap := fs.Duration("burst-gap", analysis.DefaultBurstGap, "Group error and warning lines at most this far apart into one burst")
+	topBursts := fs.Int("top-bursts", 5, "Number of the largest error bursts to report (0 = all)")
+	fs.Parse(args)
+
+	if *format == "" {
+		*format = "html"
+		if ext := strings.ToLower(sink.Ext(*output)); ext == ".md" || ext == ".markdown" {
+			*format = "markdown"
+		}
+	}
+	if *format != "html" && *format != "markdown" {
+		return fmt.Errorf("invalid -format '%s', expected html or markdown", *format)
+	}
+
+	iv, cfg, err := input.newInterleaver()
+	if err != nil {
+		return err
+	}
+	iv.SetReorderWindow(*reorderWin)
+	lines, err := input.process(ctx, iv)
+	if err != nil {
+		return err
+	}
+
+	summary, err := buildSummary(ctx, iv, lines, cfg, *title, *gapThreshold, *burstGap, *topBursts)
+	if err != nil {
+		return err
+	}
+
+	// The plot of the configured metrics, as PNG
+	var plot []byte
+	if !*noPlot {
+		if plot, err = plotPNG(ctx, lines, cfg); err != nil {
+			return err
+		}
+	}
+
+	out, err := sink.Open(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer out.Close()
+
+	if *format == "html" {
+		err = visualizer.WriteSummaryHTML(summary, plot, out)
+	} else {
+		// Markdown can't embed images, so the plot is written next to the report
+		var plotLink string
+		if plot != nil {
+			plotPath := "report-plot.png"
+			if *output != "-" {
+				plotPath = strings.TrimSuffix(*output, sink.Ext(*output)) + "-plot.png"
+			}
+			if err := writeFile(plotPath, plot); err != nil {
+				return fmt.Errorf("failed to write plot: %w", err)
+			}
+			infof("Report plot saved to: %s", plotPath)
+			plotLink = path.Base(plotPath)
+		}
+		err = visualizer.WriteSummaryMarkdown(summary, plotLink, out)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	infof("Report saved to: %s", *output)
+	return nil
+}
+
+// buildSummary builds the summary report of processed lines: the analysis with anomalies, and
+// the input files and offsets of the interleaver
+func buildSummary(ctx context.Context, iv *interleaver.Interleaver, lines []*parser.LogLine, cfg *config.VisualizationConfig, title string, gapThreshold, burstGap time.Duration, topBursts int) (*report.Summary, error) {
+	analysisReport, _, err := buildAnalysisReport(ctx, lines, iv.ReorderStats(), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze logs: %w", err)
+	}
+	analysisReport.DuplicateLines = iv.Duplicates()
+	if err := addAnomalies(analysisReport, lines, cfg, gapThreshold, burstGap, topBursts); err != nil {
+		return nil, err
+	}
+
+	summary := &report.Summary{
+		Title:       "Log Report",
+		GeneratedAt: analysisReport.GeneratedAt,
+		Command:     commandLine(),
+		Analysis:    analysisReport,
+	}
+	if cfg != nil && cfg.Title != "" {
+		summary.Title = cfg.Title
+	}
+	if title != "" {
+		summary.Title = title
+	}
+	for _, f := range iv.InputFiles() {
+		summary.Files = append(summary.Files, report.InputFile{Path: f.Path, Tag: f.Tag})
+	}
+	for _, o := range iv.Offsets() {
+		summary.Offsets = append(summary.Offsets, report.AppliedOffset{Tag: o.Tag, OffsetSeconds: o.Offset.Seconds(), Source: string(o.Source), DriftPPM: o.DriftPPM, Evidence: o.Evidence})
+	}
+	return summary, nil
+}
+
+// plotPNG renders the plot of the configured metrics as PNG, or returns nil without patterns
+func plotPNG(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig) ([]byte, error) {
+	if cfg == nil || len(cfg.Patterns) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := visualizer.NewVisualizer(cfg).WritePlot(ctx, lines, &buf, "png"); err != nil {
+		return nil, fmt.Errorf("failed to generate plot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFile writes data to an output location
+func writeFile(location string, data []byte) error {
+	out, err := sink.Open(location)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(data); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// commandLine returns the command line of the run, quoting arguments with spaces
+func commandLine() string {
+	args := []string{"log-interleaver"}
+	for _, arg := range os.Args[1:] {
+		if strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"log-interleaver/pkg/timestamp"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runServe serves a dashboard of the interleaved logs, the plots, and the analysis report
+func runServe(ctx context.Context, args []string) error {
+	fs := newFlagSet("serve", "Serve a dashboard of the logs over HTTP: the interleaved log with search and tag filters,\nthe interactive and static plots, and the summary report and analysis. The logs are processed\nagain when a page is requested after the log files or the config changed.")
+	input := addInputFlags(fs)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on (use :8080 to accept remote connections)")
+	pageSize := fs.Int("page-size", 1000, "Number of log lines per page")
+	timeFormat := fs.String("time-format", timestamp.DefaultOutputFormat, "Go time layout for the timestamps of the log page")
+	reorderWin := fs.Duration("reorder-window", 500*time.Millisecond, "Window for reordering out-of-order source lines")
+	gapThreshold := fs.Duration("gap-threshold", 0, "Report periods longer than this without lines from a tag as gaps (default: gap_threshold from the config, else 1m)")
+	burstGap := fs.Duration("burst-gap", analysis.DefaultBurstGap, "Group error and warning lines at most this far apart into one burst")
+	topBursts := fs.Int("top-bursts", 5, "Number of the largest error bursts to report (0 = all)")
+	plotlyMode := fs.String("plotly", visualizer.PlotlyAuto, "Plotly.js source of the interactive plot: auto, embed, or cdn")
+	plotlyJS := fs.String("plotly-js", "", "Local Plotly.js bundle to inline into the interactive plot")
+	liveInterval := fs.Duration("live-interval", time.Second, "Interval of checking the inputs for new points to stream into open interactive plots")
+	fs.Parse(args)
+
+	if *pageSize <= 0 {
+		return fmt.Errorf("invalid -page-size %d, expected a positive number of lines", *pageSize)
+	}
+	if *liveInterval <= 0 {
+		return fmt.Errorf("invalid -live-interval %v, expected a positive duration", *liveInterval)
+	}
+
+	d := &dashboard{
+		ctx:          ctx,
+		input:        input,
+		pageSize:     *pageSize,
+		timeFormat:   *timeFormat,
+		reorderWin:   *reorderWin,
+		gapThreshold: *gapThreshold,
+		burstGap:     *burstGap,
+		topBursts:    *topBursts,
+		liveInterval: *liveInterval,
+		htmlOpts:     visualizer.HTMLOptions{PlotlyMode: *plotlyMode, PlotlyJS: *plotlyJS, LiveURL: "/live"},
+	}
+	// Process the logs before listening, so input errors are reported right away
+	if _, err := d.snapshot(false); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.serveLog)
+	mux.HandleFunc("/plot", d.servePlot)
+	mux.HandleFunc("/plot.png", d.servePlotImage)
+	mux.HandleFunc("/report", d.serveReport)
+	mux.HandleFunc("/analysis", d.serveAnalysis)
+	mux.HandleFunc("/reload", d.serveReload)
+	mux.HandleFunc("/live", d.serveLive)
+	mux.HandleFunc("/api/lines", d.serveAPILines)
+	mux.HandleFunc("/api/series", d.serveAPISeriesList)
+	mux.HandleFunc("/api/series/{name}", d.serveAPISeries)
+	mux.HandleFunc("/api/analysis", d.serveAPIAnalysis)
+
+	// Shut down on Ctrl-C, letting requests in progress finish
+	server := &http.Server{Addr: *addr, Handler: mux, BaseContext: func(net.Listener) context.Context { return ctx }}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	infof("Serving dashboard on http://%s", *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// dashboard serves the pages of the serve command from a snapshot of the processed logs
+type dashboard struct {
+	ctx          context.Context // Cancelled when the server shuts down
+	input        *inputOptions
+	pageSize     int
+	timeFormat   string
+	reorderWin   time.Duration
+	gapThreshold time.Duration
+	burstGap     time.Duration
+	topBursts    int
+	liveInterval time.Duration
+	htmlOpts     visualizer.HTMLOptions // With the live WebSocket path
+
+	mu      sync.Mutex
+	current *snapshot
+}
+
+// snapshot is the result of processing the logs, with the derived pages built on first use
+type snapshot struct {
+	iv          *interleaver.Interleaver
+	cfg         *config.VisualizationConfig
+	lines       []*parser.LogLine
+	tags        []string
+	numbers     map[*parser.LogLine]int // Line numbers in the interleaved log
+	loaded      time.Time
+	fingerprint string
+
+	once    sync.Once
+	summary *report.Summary
+	plot    []byte
+	err     error
+
+	metricsOnce  sync.Once
+	metricPoints map[string][]pattern.MetricPoint
+	metricsErr   error
+}
+
+// snapshot returns the current snapshot, processing the logs again when forced or when the
+// inputs changed since the last run
+func (d *dashboard) snapshot(force bool) (*snapshot, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.current != nil && !force && d.input.fingerprint() == d.current.fingerprint {
+		return d.current, nil
+	}
+
+	iv, cfg, err := d.input.newInterleaver()
+	if err != nil {
+		return nil, err
+	}
+	iv.SetReorderWindow(d.reorderWin)
+	lines, err := d.input.process(d.ctx, iv)
+	if err != nil {
+		return nil, err
+	}
+	tags := lineTags(lines)
+	sort.Strings(tags)
+	// Line numbers refer to the whole log, so they stay put while filtering
+	numbers := make(map[*parser.LogLine]int, len(lines))
+	for i, line := range lines {
+		numbers[line] = i + 1
+	}
+	d.current = &snapshot{iv: iv, cfg: cfg, lines: lines, tags: tags, numbers: numbers, loaded: time.Now(), fingerprint: d.input.fingerprint()}
+	infof("Processed %d lines from %d files", len(lines), len(iv.InputFiles()))
+	return d.current, nil
+}
+
+// report returns the summary report and plot of the snapshot, building them on first use
+func (s *snapshot) report(d *dashboard) (*report.Summary, []byte, error) {
+	s.once.Do(func() {
+		if s.summary, s.err = buildSummary(d.ctx, s.iv, s.lines, s.cfg, "", d.gapThreshold, d.burstGap, d.topBursts); s.err != nil {
+			return
+		}
+		s.plot, s.err = plotPNG(d.ctx, s.lines, s.cfg)
+	})
+	return s.summary, s.plot, s.err
+}
+
+// hasPlots reports whether the config has patterns to plot
+func (s *snapshot) hasPlots() bool {
+	return s.cfg != nil && len(s.cfg.Patterns) > 0
+}
+
+// serveLog serves a page of the interleaved log, filtered by the q (regex), v (excluded
+// regex), and tag query parameters
+func (d *dashboard) serveLog(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s, err := d.snapshot(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	selected := query["tag"]
+	page := &visualizer.LogPage{
+		Title:    "Log",
+		Query:    query.Get("q"),
+		Exclude:  query.Get("v"),
+		Total:    len(s.lines),
+		Loaded:   s.loaded.Format("15:04:05"),
+		HasPlots: s.hasPlots(),
+	}
+	if s.cfg != nil && s.cfg.Title != "" {
+		page.Title = s.cfg.Title
+	}
+	for i, tag := range s.tags {
+		page.Tags = append(page.Tags, visualizer.LogPageTag{Name: tag, Selected: slices.Contains(selected, tag), Color: visualizer.TagColor(i)})
+	}
+
+	lines := s.lines
+	if filter, err := interleaver.NewFilter(selected, nil, page.Query, page.Exclude); err != nil {
+		page.Error = err.Error()
+		lines = nil
+	} else {
+		lines = filter.Apply(lines)
+	}
+	page.Matched = len(lines)
+
+	page.Pages = max(1, (len(lines)+d.pageSize-1)/d.pageSize)
+	page.Page, _ = strconv.Atoi(query.Get("page"))
+	page.Page = min(max(page.Page, 1), page.Pages)
+	first := (page.Page - 1) * d.pageSize
+	for _, line := range lines[first:min(first+d.pageSize, len(lines))] {
+		text := line.OriginalLine
+		if len(line.Continuation) > 0 {
+			text += "\n" + strings.Join(line.Continuation, "\n")
+		}
+		var ts string
+		if t := line.GetTimestamp(); t != nil {
+			ts = timestamp.FormatTimestampLayout(t.Time, d.timeFormat)
+		}
+		page.Lines = append(page.Lines, visualizer.LogPageLine{
+			Number: s.numbers[line],
+			Time:   ts,
+			Tag:    line.Tag,
+			Color:  visualizer.TagColor(slices.Index(s.tags, line.Tag)),
+			Text:   text,
+			File:   fmt.Sprintf("%s:%d", line.File, line.LineNumber),
+		})
+	}
+	if page.Page > 1 {
+		page.PrevURL = pageURL(query, page.Page-1)
+	}
+	if page.Page < page.Pages {
+		page.NextURL = pageURL(query, page.Page+1)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := visualizer.WriteLogPageHTML(page, w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// pageURL returns the URL of another page of the log with the same filters
+func pageURL(query url.Values, page int) string {
+	q := url.Values{}
+	for key, values := range query {
+		q[key] = values
+	}
+	q.Set("page", strconv.Itoa(page))
+	return "/?" + q.Encode()
+}
+
+// servePlot serves the interactive plot
+func (d *dashboard) servePlot(w http.ResponseWriter, r *http.Request) {
+	s, err := d.snapshot(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !s.hasPlots() {
+		http.Error(w, "no patterns to plot in the config", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := visualizer.WriteInteractiveHTML(r.Context(), s.lines, s.cfg, w, d.htmlOpts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// servePlotImage serves the static plot as PNG
+func (d *dashboard) servePlotImage(w http.ResponseWriter, r *http.Request) {
+	s, err := d.snapshot(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, plot, err := s.report(d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if plot == nil {
+		http.Error(w, "no patterns to plot in the config", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(plot)
+}
+
+// serveReport serves the HTML summary report
+func (d *dashboard) serveReport(w http.ResponseWriter, r *http.Request) {
+	s, err := d.snapshot(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	summary, plot, err := s.report(d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := visualizer.WriteSummaryHTML(summary, plot, w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveAnalysis serves the analysis as text, as printed by the analyze command
+func (d *dashboard) serveAnalysis(w http.ResponseWriter, r *http.Request) {
+	s, err := d.snapshot(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	summary, _, err := s.report(d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	analyzeLogs(summary.Analysis, s.cfg, w)
+}
+
+// serveReload processes the logs again and returns to the log page
+func (d *dashboard) serveReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "reload requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := d.snapshot(true); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
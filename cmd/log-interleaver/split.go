@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"time"
+)
+
+// windowLayout returns the layout naming the windows of -split-by by their start, to the
+// precision of the window (colons are left out, as file names cannot have them everywhere)
+func windowLayout(window time.Duration) string {
+	switch {
+	case window%(24*time.Hour) == 0:
+		return "2006-01-02"
+	case window%time.Hour == 0:
+		return "2006-01-02T15"
+	case window%time.Minute == 0:
+		return "2006-01-02T1504"
+	default:
+		return "2006-01-02T150405"
+	}
+}
+
+// windowStart returns the start of the window of a time. Windows up to a day long start at the
+// midnight of the time's zone, so hourly files follow the local hours and daily files the local
+// days; longer windows are aligned to the zero time.
+func windowStart(t time.Time, window time.Duration) time.Time {
+	if window > 24*time.Hour {
+		return t.Truncate(window)
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return midnight.Add(t.Sub(midnight) / window * window)
+}
+
+// splitOutput writes the lines of each time window to an output of its own, named by the output
+// location with the start of the window. Lines without a timestamp go with the preceding line,
+// or with the first timestamped line at the start of the log.
+type splitOutput struct {
+	location string
+	window   time.Duration
+	open     func(string) (sink.Sink, error)
+	start    time.Time // Start of the window being written
+	out      sink.Sink
+	files    int // Outputs written
+}
+
+// newSplitOutput creates the split output of the lines
+func newSplitOutput(location string, window time.Duration, open func(string) (sink.Sink, error), lines []*parser.LogLine) *splitOutput {
+	s := &splitOutput{location: location, window: window, open: open}
+	for _, line := range lines {
+		if ts := line.GetTimestamp(); ts != nil {
+			s.start = windowStart(ts.Time, window)
+			break
+		}
+	}
+	return s
+}
+
+// writer returns the output of a line, closing the output of the previous window when the line
+// starts a new one
+func (s *splitOutput) writer(line *parser.LogLine) (io.Writer, error) {
+	start := s.start
+	if ts := line.GetTimestamp(); ts != nil {
+		start = windowStart(ts.Time, s.window)
+	}
+	if s.out != nil && start.Equal(s.start) {
+		return s.out, nil
+	}
+	if err := s.Close(); err != nil {
+		return nil, err
+	}
+	location := sink.WithSuffix(s.location, "-"+start.Format(windowLayout(s.window)))
+	out, err := s.open(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	logger.Debug("Writing window", "start", start, "location", location)
+	s.start, s.out = start, out
+	s.files++
+	return out, nil
+}
+
+// Close closes the output of the current window
+func (s *splitOutput) Close() error {
+	if s.out == nil {
+		return nil
+	}
+	out := s.out
+	s.out = nil
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
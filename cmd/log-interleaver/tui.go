@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/sink"
+	"log-interleaver/internal/tui"
+	"log-interleaver/internal/visualizer"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/timestamp"
+	"os"
+	"slices"
+)
+
+// runTUI browses the interleaved log in the terminal
+func runTUI(ctx context.Context, args []string) error {
+	fs := newFlagSet("tui", "Browse the interleaved log in the terminal: lines colored by tag, tag filters (1-9),\nincremental search (/, n, N), and a sparkline of a metric of the config (m) with a cursor\nat the time of the top line.")
+	input := addInputFlags(fs)
+	metric := fs.String("metric", "", "Pattern whose sparkline is shown first (default: the first pattern with values)")
+	timeFormat := fs.String("time-format", timestamp.DefaultOutputFormat, "Go time layout for the timestamps of the lines")
+	noColor := fs.Bool("no-color", false, "Disable coloring lines by tag")
+	fs.Parse(args)
+
+	if !sink.IsTerminal("-") {
+		return fmt.Errorf("tui needs a terminal on standard output")
+	}
+	iv, cfg, err := input.newInterleaver()
+	if err != nil {
+		return err
+	}
+	if *metric != "" && (cfg == nil || !hasPattern(cfg, *metric)) {
+		return fmt.Errorf("-metric series '%s' is not a pattern in the config", *metric)
+	}
+	lines, err := input.process(ctx, iv)
+	if err != nil {
+		return err
+	}
+
+	opts := tui.Options{Title: "log-interleaver", TimeFormat: *timeFormat}
+	if !*noColor && os.Getenv("NO_COLOR") == "" {
+		var tagColors map[string]string
+		if cfg != nil {
+			tagColors = cfg.TagColors
+		}
+		if opts.Colorizer, err = interleaver.NewColorizer(tagColors); err != nil {
+			return err
+		}
+		opts.Colorizer.AssignColors(lineTags(lines))
+	}
+	if cfg != nil && len(cfg.Patterns) > 0 {
+		if cfg.Title != "" {
+			opts.Title = cfg.Title
+		}
+		metrics, err := visualizer.ExtractMetrics(ctx, lines, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to extract metrics: %w", err)
+		}
+		for _, p := range cfg.Patterns {
+			points := metrics[p.Name]
+			if p.IsEvent() || len(points) == 0 {
+				continue
+			}
+			if p.Name == *metric {
+				opts.Metric = len(opts.Metrics)
+			}
+			points = slices.Clone(points)
+			slices.SortStableFunc(points, func(a, b pattern.MetricPoint) int { return a.Time.Compare(b.Time) })
+			opts.Metrics = append(opts.Metrics, tui.Metric{Name: p.Name, Unit: p.DisplayUnit, Points: points})
+		}
+	}
+
+	in, err := tui.OpenInput()
+	if err != nil {
+		return err
+	}
+	if in != os.Stdin {
+		defer in.Close()
+	}
+	return tui.NewBrowser(lines, opts).Run(ctx, in, os.Stdout)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+)
+
+// watchOptions holds the flags of the subcommands that regenerate their outputs on changes
+type watchOptions struct {
+	enabled  bool
+	interval time.Duration
+}
+
+// addWatchFlags registers the watch mode flags on a subcommand's flag set
+func addWatchFlags(fs *flag.FlagSet) *watchOptions {
+	opts := &watchOptions{}
+	fs.BoolVar(&opts.enabled, "watch", false, "Keep running and regenerate the outputs whenever the log files or the config change, polling their sizes and modification times every -watch-interval (stop with Ctrl-C)")
+	fs.DurationVar(&opts.interval, "watch-interval", time.Second, "Interval of checking the inputs for changes in -watch mode")
+	return opts
+}
+
+// run runs generate once, or in watch mode again after each change of the inputs until the
+// context is cancelled. Errors of a watched run are reported without stopping, so a config
+// being edited may be broken for a while.
+func (w *watchOptions) run(ctx context.Context, input *inputOptions, generate func(ctx context.Context) error) error {
+	if !w.enabled {
+		return generate(ctx)
+	}
+	// The fingerprint is taken before each run, so a change while generating triggers another
+	fingerprint := input.fingerprint()
+	for {
+		if err := generate(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.Error(err.Error())
+		}
+		logger.Debug("Watching for changes", "interval", w.interval.String())
+
+		// Wait for a change, then until the inputs stop changing for an interval, so files
+		// being written are read once they are complete
+		for changed := false; ; {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(w.interval):
+			}
+			current := input.fingerprint()
+			if current == fingerprint && changed {
+				break
+			}
+			changed, fingerprint = changed || current != fingerprint, current
+		}
+		infof("Inputs changed, regenerating")
+	}
+}
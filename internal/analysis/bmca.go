@@ -0,0 +1,132 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/report"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Event types of the BMCA decisions of ptp4l
+const (
+	EventGMChange      = "gm_change"      // Another grandmaster was selected
+	EventForeignMaster = "foreign_master" // A port heard the announce messages of a new master
+)
+
+var (
+	// clockIdentity matches a PTP clock identity as ptp4l prints it (e.g., 507c6f.fffe.1fb1a0)
+	clockIdentity      = `[0-9a-fA-F]{6}\.[0-9a-fA-F]{4}\.[0-9a-fA-F]{6}`
+	bestMasterRegex    = regexp.MustCompile(`selected best master clock (` + clockIdentity + `)`)
+	localMasterRegex   = regexp.MustCompile(`selected local clock (` + clockIdentity + `) as best master`)
+	foreignMasterRegex = regexp.MustCompile(`(port \d+(?: \([^)]*\))?): new foreign master (` + clockIdentity + `-\d+)`)
+	ptp4lInstanceRegex = regexp.MustCompile(`\[(ptp4l\.\d+\.config)(?::\d+)?\]`)
+)
+
+// DetectBMCA follows the best master clock algorithm decisions logged by ptp4l: the grandmaster
+// selected by each instance (a tag, or a ptp4l config within a tag) and the new foreign masters
+// its ports heard. It returns the grandmaster timeline of each instance, in the order the
+// instances first selected a grandmaster, and the events of the grandmaster changes (including
+// the first selection) and of the new foreign masters. A grandmaster is taken to stay selected
+// until another one is, or until the last line of the tag.
+func DetectBMCA(lines []*parser.LogLine) ([]report.GMTimeline, []report.Event) {
+	type instance struct {
+		timeline *report.GMTimeline
+		current  *report.GMPeriod
+	}
+	instances := make(map[string]*instance)
+	var order []string
+	lastSeen := make(map[string]time.Time)
+	var events []report.Event
+
+	for _, line := range lines {
+		ts := line.GetTimestamp()
+		if ts == nil {
+			continue
+		}
+		t := ts.Time
+		if t.After(lastSeen[line.Tag]) {
+			lastSeen[line.Tag] = t
+		}
+
+		var id string
+		local := false
+		if m := bestMasterRegex.FindStringSubmatch(line.OriginalLine); m != nil {
+			id = m[1]
+		} else if m := localMasterRegex.FindStringSubmatch(line.OriginalLine); m != nil {
+			id, local = m[1], true
+		}
+		foreign := foreignMasterRegex.FindStringSubmatch(line.OriginalLine)
+		if id == "" && foreign == nil {
+			continue
+		}
+
+		var config string
+		if m := ptp4lInstanceRegex.FindStringSubmatch(line.OriginalLine); m != nil {
+			config = m[1]
+		}
+		key := line.Tag + "\x00" + config
+		inst, ok := instances[key]
+		if !ok {
+			inst = &instance{timeline: &report.GMTimeline{Tag: line.Tag, Instance: config}}
+			instances[key] = inst
+			order = append(order, key)
+		}
+		where := ""
+		if config != "" {
+			where = config + ": "
+		}
+
+		if foreign != nil {
+			inst.timeline.ForeignMasters = append(inst.timeline.ForeignMasters, report.ForeignMaster{Time: t, Port: foreign[1], PortIdentity: foreign[2]})
+			events = append(events, report.Event{
+				Time:    t,
+				Type:    EventForeignMaster,
+				Tag:     line.Tag,
+				Message: fmt.Sprintf("%s%s: new foreign master %s", where, foreign[1], foreign[2]),
+			})
+			continue
+		}
+		if inst.current != nil && inst.current.ClockIdentity == id {
+			continue
+		}
+
+		message := "grandmaster " + gmName(id, local)
+		if inst.current != nil {
+			inst.current.End = t
+			inst.timeline.Changes++
+			message = fmt.Sprintf("grandmaster %s -> %s", gmName(inst.current.ClockIdentity, inst.current.Local), gmName(id, local))
+		}
+		inst.timeline.Periods = append(inst.timeline.Periods, report.GMPeriod{ClockIdentity: id, Local: local, Start: t})
+		inst.current = &inst.timeline.Periods[len(inst.timeline.Periods)-1]
+		events = append(events, report.Event{Time: t, Type: EventGMChange, Tag: line.Tag, Message: where + message})
+	}
+
+	var timelines []report.GMTimeline
+	for _, key := range order {
+		timeline := instances[key].timeline
+		if len(timeline.Periods) == 0 {
+			// Foreign masters only; no grandmaster was selected in the capture
+			timelines = append(timelines, *timeline)
+			continue
+		}
+		last := &timeline.Periods[len(timeline.Periods)-1]
+		last.End = lastSeen[timeline.Tag]
+		for k := range timeline.Periods {
+			p := &timeline.Periods[k]
+			p.DurationSeconds = p.End.Sub(p.Start).Seconds()
+		}
+		timelines = append(timelines, *timeline)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return timelines, events
+}
+
+// gmName names a grandmaster by its clock identity, marking the local clock
+func gmName(id string, local bool) string {
+	if local {
+		return id + " (local clock)"
+	}
+	return id
+}
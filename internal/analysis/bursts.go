@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/report"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBurstGap is the maximum spacing of the lines of a burst
+const DefaultBurstGap = 5 * time.Second
+
+// DefaultSeverities are the severities of the burst analysis when the config sets none: error
+// and warning keywords, and the E/W prefixes of glog lines
+var DefaultSeverities = []config.SeverityConfig{
+	{Name: "error", Regex: `(?i)\b(error|err|fatal|fail(ed|ure)?|panic)\b|^E\d{4} `},
+	{Name: "warning", Regex: `(?i)\bwarn(ing)?\b|^W\d{4} `},
+}
+
+// maxBurstMessages is the number of representative messages kept per burst
+const maxBurstMessages = 3
+
+// maxMessageLength is the length (in runes) at which representative messages are truncated
+const maxMessageLength = 200
+
+// Severity is a compiled severity of the burst analysis
+type Severity struct {
+	Name  string
+	Regex *regexp.Regexp
+}
+
+// CompileSeverities compiles the severity regexes, or the default ones when none are given
+func CompileSeverities(severities []config.SeverityConfig) ([]Severity, error) {
+	if len(severities) == 0 {
+		severities = DefaultSeverities
+	}
+	var compiled []Severity
+	for _, sev := range severities {
+		re, err := regexp.Compile(sev.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex of severity '%s': %w", sev.Name, err)
+		}
+		compiled = append(compiled, Severity{Name: sev.Name, Regex: re})
+	}
+	return compiled, nil
+}
+
+// digits matches the numbers that are ignored when grouping burst messages
+var digits = regexp.MustCompile(`\d+`)
+
+// DetectBursts groups the lines matching a severity into bursts: a line joins the current
+// burst when it is at most gap after the previous matching line. A line has the first
+// severity it matches. The top bursts by line count are returned (all with top 0 or less),
+// along with the number of bursts found.
+func DetectBursts(lines []*parser.LogLine, severities []Severity, gap time.Duration, top int) ([]report.Burst, int) {
+	type match struct {
+		line     *parser.LogLine
+		severity string
+	}
+	var matches []match
+	for _, line := range lines {
+		if line.GetTimestamp() == nil {
+			continue
+		}
+		for _, sev := range severities {
+			if sev.Regex.MatchString(line.OriginalLine) {
+				matches = append(matches, match{line: line, severity: sev.Name})
+				break
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].line.Timestamp.Time.Before(matches[j].line.Timestamp.Time)
+	})
+
+	var bursts []report.Burst
+	for start := 0; start < len(matches); {
+		end := start + 1
+		for end < len(matches) && matches[end].line.Timestamp.Time.Sub(matches[end-1].line.Timestamp.Time) <= gap {
+			end++
+		}
+
+		burst := report.Burst{
+			StartTime:  matches[start].line.Timestamp.Time,
+			EndTime:    matches[end-1].line.Timestamp.Time,
+			Lines:      end - start,
+			Severities: make(map[string]int),
+		}
+		burst.DurationSeconds = burst.EndTime.Sub(burst.StartTime).Seconds()
+		messages := make(map[string]*report.BurstMessage)
+		var order []string
+		tags := make(map[string]bool)
+		for _, m := range matches[start:end] {
+			burst.Severities[m.severity]++
+			if !tags[m.line.Tag] {
+				tags[m.line.Tag] = true
+				burst.Tags = append(burst.Tags, m.line.Tag)
+			}
+			key := m.line.Tag + "\x00" + digits.ReplaceAllString(m.line.OriginalLine, "#")
+			if msg, ok := messages[key]; ok {
+				msg.Count++
+				continue
+			}
+			messages[key] = &report.BurstMessage{Message: truncateMessage(m.line.OriginalLine), Tag: m.line.Tag, Count: 1}
+			order = append(order, key)
+		}
+		for _, key := range order {
+			burst.Messages = append(burst.Messages, *messages[key])
+		}
+		sort.SliceStable(burst.Messages, func(i, j int) bool {
+			return burst.Messages[i].Count > burst.Messages[j].Count
+		})
+		if len(burst.Messages) > maxBurstMessages {
+			burst.Messages = burst.Messages[:maxBurstMessages]
+		}
+		bursts = append(bursts, burst)
+		start = end
+	}
+
+	count := len(bursts)
+	sort.SliceStable(bursts, func(i, j int) bool {
+		return bursts[i].Lines > bursts[j].Lines
+	})
+	if top > 0 && len(bursts) > top {
+		bursts = bursts[:top]
+	}
+	return bursts, count
+}
+
+// truncateMessage shortens a message to maxMessageLength runes
+func truncateMessage(message string) string {
+	message = strings.TrimSpace(message)
+	if runes := []rune(message); len(runes) > maxMessageLength {
+		return string(runes[:maxMessageLength]) + "..."
+	}
+	return message
+}
@@ -0,0 +1,237 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckFunctions lists the functions of value assertions
+var CheckFunctions = []string{"min", "max", "max_abs", "mean", "median", "p95", "p99", "stddev", "count"}
+
+// Assertion is a parsed assertion: either a value function of a series compared to a
+// number (max_abs(offset) < 100), or a state condition that may be violated for at most a
+// duration (state(servo) != HOLDOVER for > 5s)
+type Assertion struct {
+	Name   string
+	Expr   string
+	Series string
+	Func   string // Value function, or "state"
+	Op     string
+	Number float64       // Right-hand side of value assertions
+	State  string        // Right-hand side of state assertions
+	ForOp  string        // ">" or ">=" when violations are tolerated up to a duration
+	For    time.Duration // Tolerated duration of violations
+}
+
+var (
+	// callAssertion matches "func(series) op number" and "state(series) op state [for > duration]"
+	callAssertion = regexp.MustCompile(`^\s*(\w+)\s*\((.*)\)\s*(<=|>=|==|!=|<|>)\s*(.+?)(?:\s+for\s*(>=|>)\s*(\S+))?\s*$`)
+	// bareAssertion matches the same without the series, for assertions with a series set
+	bareAssertion = regexp.MustCompile(`^\s*(\w+)\s*(<=|>=|==|!=|<|>)\s*(.+?)(?:\s+for\s*(>=|>)\s*(\S+))?\s*$`)
+)
+
+// ParseAssertions parses the assertions of the config
+func ParseAssertions(assertions []config.AssertionConfig) ([]*Assertion, error) {
+	var parsed []*Assertion
+	for _, ac := range assertions {
+		a, err := ParseAssertion(ac.Name, ac.Series, ac.Expr)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, a)
+	}
+	return parsed, nil
+}
+
+// ParseAssertion parses an assertion expression. The series of the expression defaults to the
+// given series, so "state != HOLDOVER for > 5s" works with a series set.
+func ParseAssertion(name, series, expr string) (*Assertion, error) {
+	a := &Assertion{Name: name, Expr: expr, Series: series}
+	if a.Name == "" {
+		a.Name = strings.TrimSpace(expr)
+	}
+
+	var rhs, forOp, forValue string
+	if m := callAssertion.FindStringSubmatch(expr); m != nil {
+		a.Func, a.Series, a.Op, rhs, forOp, forValue = m[1], strings.TrimSpace(m[2]), m[3], m[4], m[5], m[6]
+	} else if m := bareAssertion.FindStringSubmatch(expr); m != nil && series != "" {
+		a.Func, a.Op, rhs, forOp, forValue = m[1], m[2], m[3], m[4], m[5]
+	} else {
+		return nil, fmt.Errorf("invalid assertion '%s', expected func(series) op value (e.g., max_abs(offset) < 100 or state(servo) != HOLDOVER for > 5s)", expr)
+	}
+	if a.Series == "" {
+		return nil, fmt.Errorf("assertion '%s': no series given", a.Name)
+	}
+
+	if a.Func == "state" {
+		if a.Op != "==" && a.Op != "!=" {
+			return nil, fmt.Errorf("assertion '%s': invalid state operator '%s', expected == or !=", a.Name, a.Op)
+		}
+		a.State = rhs
+		if forOp != "" {
+			d, err := time.ParseDuration(forValue)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("assertion '%s': invalid duration '%s'", a.Name, forValue)
+			}
+			a.ForOp, a.For = forOp, d
+		}
+		return a, nil
+	}
+
+	if !isCheckFunction(a.Func) {
+		return nil, fmt.Errorf("assertion '%s': invalid function '%s', expected state or one of %s", a.Name, a.Func, strings.Join(CheckFunctions, ", "))
+	}
+	if forOp != "" {
+		return nil, fmt.Errorf("assertion '%s': only state assertions can have a for clause", a.Name)
+	}
+	number, err := strconv.ParseFloat(rhs, 64)
+	if err != nil {
+		return nil, fmt.Errorf("assertion '%s': invalid number '%s'", a.Name, rhs)
+	}
+	a.Number = number
+	return a, nil
+}
+
+// isCheckFunction reports whether name is a value function of assertions
+func isCheckFunction(name string) bool {
+	for _, f := range CheckFunctions {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate evaluates the assertion against the extracted metrics
+func (a *Assertion) Evaluate(metrics map[string][]pattern.MetricPoint) report.CheckResult {
+	result := report.CheckResult{Name: a.Name, Expr: a.Expr}
+	points, ok := metrics[a.Series]
+	if !ok {
+		result.Message = fmt.Sprintf("series '%s' is not a pattern in the config", a.Series)
+		return result
+	}
+	if a.Func == "state" {
+		return a.evaluateState(result, points)
+	}
+
+	var value float64
+	if a.Func == "count" {
+		value = float64(len(points))
+	} else {
+		stats := ComputeValueStats(points)
+		if stats == nil {
+			result.Message = fmt.Sprintf("series '%s' has no points", a.Series)
+			return result
+		}
+		switch a.Func {
+		case "min":
+			value = stats.Min
+		case "max":
+			value = stats.Max
+		case "max_abs":
+			value = math.Max(math.Abs(stats.Min), math.Abs(stats.Max))
+		case "mean":
+			value = stats.Mean
+		case "median":
+			value = stats.Median
+		case "p95":
+			value = stats.P95
+		case "p99":
+			value = stats.P99
+		case "stddev":
+			value = stats.StdDev
+		}
+	}
+	result.Value = &value
+	result.Passed = compare(value, a.Op, a.Number)
+	formatted := strconv.FormatFloat(value, 'g', 6, 64)
+	if result.Passed {
+		result.Message = fmt.Sprintf("%s = %s", a.Func, formatted)
+	} else {
+		result.Message = fmt.Sprintf("%s = %s, expected %s %s", a.Func, formatted, a.Op, strconv.FormatFloat(a.Number, 'g', -1, 64))
+	}
+	return result
+}
+
+// evaluateState checks the state condition, measuring the longest period it was violated
+func (a *Assertion) evaluateState(result report.CheckResult, points []pattern.MetricPoint) report.CheckResult {
+	segments := StateSegments(points)
+	if len(segments) == 0 {
+		result.Message = fmt.Sprintf("series '%s' has no states", a.Series)
+		return result
+	}
+
+	// Consecutive violating segments form one violation (e.g., s0 then s1 for "== s2")
+	var longest, current time.Duration
+	var longestStart, currentStart time.Time
+	violations := 0
+	violating := false
+	for _, seg := range segments {
+		matches := strings.EqualFold(seg.State, a.State)
+		if matches == (a.Op == "==") {
+			violating = false
+			continue
+		}
+		if !violating {
+			violating = true
+			violations++
+			currentStart, current = seg.Start, 0
+		}
+		current += seg.End.Sub(seg.Start)
+		if current > longest || longestStart.IsZero() {
+			longest, longestStart = current, currentStart
+		}
+	}
+
+	seconds := longest.Seconds()
+	result.Value = &seconds
+	switch {
+	case violations == 0:
+		result.Passed = true
+	case a.ForOp == ">":
+		result.Passed = longest <= a.For
+	case a.ForOp == ">=":
+		result.Passed = longest < a.For
+	}
+
+	condition := "state " + a.Op + " " + a.State
+	if violations == 0 {
+		result.Message = fmt.Sprintf("%s throughout", condition)
+		return result
+	}
+	result.Message = fmt.Sprintf("%s violated %d times, longest for %v from %s", condition, violations,
+		longest.Round(time.Millisecond), longestStart.UTC().Format("15:04:05.000"))
+	switch {
+	case a.ForOp == ">":
+		result.Message += fmt.Sprintf(" (tolerating up to %v)", a.For)
+	case a.ForOp == ">=":
+		result.Message += fmt.Sprintf(" (tolerating less than %v)", a.For)
+	}
+	return result
+}
+
+// compare applies a comparison operator
+func compare(value float64, op string, number float64) bool {
+	switch op {
+	case "<":
+		return value < number
+	case "<=":
+		return value <= number
+	case ">":
+		return value > number
+	case ">=":
+		return value >= number
+	case "==":
+		return value == number
+	case "!=":
+		return value != number
+	}
+	return false
+}
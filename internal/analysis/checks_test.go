@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"log-interleaver/pkg/pattern"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		expr   string
+		series string // Series of the assertion config
+		want   Assertion
+	}{
+		{
+			expr: "max_abs(offset) < 100",
+			want: Assertion{Series: "offset", Func: "max_abs", Op: "<", Number: 100},
+		},
+		{
+			expr: "  p99( path delay )>=-1.5e3 ",
+			want: Assertion{Series: "path delay", Func: "p99", Op: ">=", Number: -1500},
+		},
+		{
+			expr: "state(servo) != HOLDOVER for > 5s",
+			want: Assertion{Series: "servo", Func: "state", Op: "!=", State: "HOLDOVER", ForOp: ">", For: 5 * time.Second},
+		},
+		{
+			expr: "state(port state) == SLAVE",
+			want: Assertion{Series: "port state", Func: "state", Op: "==", State: "SLAVE"},
+		},
+		{
+			// The series of the config stands in for the one of the expression
+			expr:   "state == s2 for >= 1m",
+			series: "servo",
+			want:   Assertion{Series: "servo", Func: "state", Op: "==", State: "s2", ForOp: ">=", For: time.Minute},
+		},
+		{
+			expr:   "count > 0",
+			series: "offset",
+			want:   Assertion{Series: "offset", Func: "count", Op: ">", Number: 0},
+		},
+	}
+	for _, tt := range tests {
+		a, err := ParseAssertion("", tt.series, tt.expr)
+		if err != nil {
+			t.Errorf("ParseAssertion(%q): %v", tt.expr, err)
+			continue
+		}
+		want := tt.want
+		want.Name, want.Expr = a.Name, tt.expr
+		if *a != want {
+			t.Errorf("ParseAssertion(%q) = %+v, want %+v", tt.expr, *a, want)
+		}
+	}
+}
+
+func TestParseAssertionInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"no comparison", "max_abs(offset)"},
+		{"no series", "max_abs < 100"},
+		{"empty series", "max_abs() < 100"},
+		{"unknown function", "sum(offset) < 100"},
+		{"not a number", "max(offset) < high"},
+		{"state ordering", "state(servo) < s2"},
+		{"value for clause", "max(offset) < 100 for > 5s"},
+		{"invalid duration", "state(servo) == s2 for > soon"},
+		{"negative duration", "state(servo) == s2 for > -5s"},
+	}
+	for _, tt := range tests {
+		if a, err := ParseAssertion("", "", tt.expr); err == nil {
+			t.Errorf("%s: ParseAssertion(%q) = %+v, want an error", tt.name, tt.expr, *a)
+		}
+	}
+}
+
+func TestAssertionEvaluate(t *testing.T) {
+	states := []string{"s2", "s2", "s1", "s0", "s2", "s1", "s2"}
+	servo := make([]pattern.MetricPoint, len(states))
+	for i, state := range states {
+		servo[i] = pattern.MetricPoint{Time: seriesStart.Add(time.Duration(i) * time.Second), State: state}
+	}
+	metrics := map[string][]pattern.MetricPoint{
+		"offset": series(time.Second, -120, 40, 15),
+		"servo":  servo,
+	}
+	tests := []struct {
+		expr      string
+		want      bool
+		wantValue float64
+	}{
+		{"max_abs(offset) < 100", false, 120},
+		{"max(offset) < 100", true, 40},
+		{"count(offset) == 3", true, 3},
+		{"state(servo) == s2", false, 2},           // Out of s2 from 2 s to 4 s
+		{"state(servo) == s2 for > 2s", true, 2},   // Tolerated up to 2 s
+		{"state(servo) == s2 for >= 2s", false, 2}, // Tolerated below 2 s
+		{"state(servo) != FAULT", true, 0},
+	}
+	for _, tt := range tests {
+		a, err := ParseAssertion("", "", tt.expr)
+		if err != nil {
+			t.Fatalf("ParseAssertion(%q): %v", tt.expr, err)
+		}
+		result := a.Evaluate(metrics)
+		if result.Passed != tt.want || result.Value == nil || *result.Value != tt.wantValue {
+			t.Errorf("%s: passed=%v value %v (%s), want %v and %g", tt.expr, result.Passed, result.Value, result.Message, tt.want, tt.wantValue)
+		}
+	}
+
+	a, _ := ParseAssertion("", "", "max(delay) < 100")
+	if result := a.Evaluate(metrics); result.Passed || result.Value != nil {
+		t.Errorf("assertion of a missing series: passed=%v value %v, want a failure without value", result.Passed, result.Value)
+	}
+}
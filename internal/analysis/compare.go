@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"log-interleaver/pkg/report"
+	"math"
+)
+
+// RunSeries is a series of a compared run with its statistics
+type RunSeries struct {
+	Name  string
+	Stats *report.RunSeriesStats
+}
+
+// SeriesRunStats returns the compared statistics of a series from its point count, value
+// statistics, and state timeline (nil for series without states), or nil without values
+func SeriesRunStats(points int, values *report.ValueStats, timeline *report.StateTimeline) *report.RunSeriesStats {
+	if values == nil {
+		return nil
+	}
+	stats := &report.RunSeriesStats{
+		Points: points,
+		Mean:   values.Mean,
+		Median: values.Median,
+		StdDev: values.StdDev,
+		P99:    values.P99,
+		MaxAbs: math.Max(math.Abs(values.Min), math.Abs(values.Max)),
+	}
+	if timeline != nil {
+		stats.TimeToLockSeconds = timeline.TimeToLockSeconds
+		unlocks := timeline.Unlocks
+		stats.Unlocks = &unlocks
+	}
+	return stats
+}
+
+// CompareRuns compares the series of two runs, in the order of the baseline series followed by
+// the series only in the candidate
+func CompareRuns(baseline, candidate []RunSeries) []report.SeriesComparison {
+	var comparisons []report.SeriesComparison
+	index := make(map[string]int)
+	for _, s := range baseline {
+		index[s.Name] = len(comparisons)
+		comparisons = append(comparisons, report.SeriesComparison{Name: s.Name, Baseline: s.Stats})
+	}
+	for _, s := range candidate {
+		k, ok := index[s.Name]
+		if !ok {
+			k = len(comparisons)
+			comparisons = append(comparisons, report.SeriesComparison{Name: s.Name})
+		}
+		comparisons[k].Candidate = s.Stats
+	}
+	for k := range comparisons {
+		c := &comparisons[k]
+		if c.Baseline != nil && c.Candidate != nil {
+			c.Delta = deltaStats(c.Baseline, c.Candidate)
+		}
+	}
+	return comparisons
+}
+
+// deltaStats returns the change of the statistics from the baseline to the candidate
+func deltaStats(baseline, candidate *report.RunSeriesStats) *report.RunSeriesStats {
+	delta := &report.RunSeriesStats{
+		Points: candidate.Points - baseline.Points,
+		Mean:   candidate.Mean - baseline.Mean,
+		Median: candidate.Median - baseline.Median,
+		StdDev: candidate.StdDev - baseline.StdDev,
+		P99:    candidate.P99 - baseline.P99,
+		MaxAbs: candidate.MaxAbs - baseline.MaxAbs,
+	}
+	if baseline.TimeToLockSeconds != nil && candidate.TimeToLockSeconds != nil {
+		seconds := *candidate.TimeToLockSeconds - *baseline.TimeToLockSeconds
+		delta.TimeToLockSeconds = &seconds
+	}
+	if baseline.Unlocks != nil && candidate.Unlocks != nil {
+		unlocks := *candidate.Unlocks - *baseline.Unlocks
+		delta.Unlocks = &unlocks
+	}
+	return delta
+}
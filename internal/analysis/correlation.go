@@ -0,0 +1,129 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"math"
+	"sort"
+	"time"
+)
+
+// ComputeCorrelation computes the coupling of two series: the Pearson correlation of their values
+// and the lag at which their cross-correlation peaks, the effective delay between them. Both
+// series are resampled by linear interpolation onto a common grid over the time they overlap, at
+// the larger of their median sample intervals. Lags are searched up to maxLag (rounded to the
+// grid, and up to half the overlap); a positive lag means the second series follows the first.
+func ComputeCorrelation(nameA string, pointsA []pattern.MetricPoint, nameB string, pointsB []pattern.MetricPoint, maxLag time.Duration) (*report.Correlation, error) {
+	for _, s := range []struct {
+		name   string
+		points []pattern.MetricPoint
+	}{{nameA, pointsA}, {nameB, pointsB}} {
+		if len(s.points) < 3 {
+			return nil, fmt.Errorf("series '%s' has %d data points, at least 3 are needed", s.name, len(s.points))
+		}
+	}
+	a, b := sortedByTime(pointsA), sortedByTime(pointsB)
+
+	start, end := a[0].Time, a[len(a)-1].Time
+	if b[0].Time.After(start) {
+		start = b[0].Time
+	}
+	if b[len(b)-1].Time.Before(end) {
+		end = b[len(b)-1].Time
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("series '%s' and '%s' do not overlap in time", nameA, nameB)
+	}
+	step := math.Max(medianInterval(a), medianInterval(b))
+	if step <= 0 {
+		return nil, fmt.Errorf("series '%s' and '%s' have no distinct timestamps", nameA, nameB)
+	}
+	n := int(math.Floor(end.Sub(start).Seconds()/step+1e-9)) + 1
+	if n < 3 {
+		return nil, fmt.Errorf("series '%s' and '%s' overlap for %d samples, at least 3 are needed", nameA, nameB, n)
+	}
+	x, y := sampleAt(a, start, step, n), sampleAt(b, start, step, n)
+
+	zeroLag, ok := pearson(x, y)
+	if !ok {
+		return nil, fmt.Errorf("series '%s' or '%s' is constant over the overlap, their correlation is undefined", nameA, nameB)
+	}
+
+	lags := min(int(math.Round(maxLag.Seconds()/step)), n/2)
+	best, bestLag := zeroLag, 0
+	for k := 1; k <= lags; k++ {
+		for _, lag := range []int{k, -k} {
+			// The second series at i+lag against the first at i
+			r, ok := pearson(x[max(0, -lag):min(n, n-lag)], y[max(0, lag):min(n, n+lag)])
+			if ok && math.Abs(r) > math.Abs(best) {
+				best, bestLag = r, lag
+			}
+		}
+	}
+
+	return &report.Correlation{
+		SeriesA:         nameA,
+		SeriesB:         nameB,
+		Samples:         n,
+		IntervalSeconds: step,
+		Pearson:         zeroLag,
+		LagSeconds:      float64(bestLag) * step,
+		LagCorrelation:  best,
+		MaxLagSeconds:   float64(lags) * step,
+	}, nil
+}
+
+// sortedByTime returns a copy of the points sorted by time
+func sortedByTime(points []pattern.MetricPoint) []pattern.MetricPoint {
+	sorted := make([]pattern.MetricPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+	return sorted
+}
+
+// sampleAt linearly interpolates time-sorted points at n times spaced by step seconds from start,
+// holding the first and last values outside the points
+func sampleAt(points []pattern.MetricPoint, start time.Time, step float64, n int) []float64 {
+	x := make([]float64, n)
+	j := 0
+	for i := range x {
+		t := start.Add(time.Duration(float64(i) * step * float64(time.Second)))
+		for j < len(points)-2 && points[j+1].Time.Before(t) {
+			j++
+		}
+		t0, t1 := points[j].Time, points[j+1].Time
+		if !t1.After(t0) {
+			x[i] = points[j+1].Value
+			continue
+		}
+		frac := math.Max(0, math.Min(1, float64(t.Sub(t0))/float64(t1.Sub(t0))))
+		x[i] = points[j].Value + frac*(points[j+1].Value-points[j].Value)
+	}
+	return x
+}
+
+// pearson returns the Pearson correlation of paired values, or false if either is constant
+func pearson(x, y []float64) (float64, bool) {
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0, false
+	}
+	return sxy / math.Sqrt(sxx*syy), true
+}
@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/report"
+	"sort"
+	"time"
+)
+
+// DefaultGapThreshold is the gap threshold when neither the command line nor the config sets one
+const DefaultGapThreshold = time.Minute
+
+// EventGap is the event type of log gaps
+const EventGap = "gap"
+
+// DetectGaps returns the periods longer than threshold in which a tag produced no lines with a
+// timestamp, suggesting process death, lost rotations, or collection gaps. Silences at the
+// edges of the capture count too: from the first line of any tag to the first line of the tag,
+// and from the last line of the tag to the last line of any tag. Gaps are ordered by start time.
+func DetectGaps(lines []*parser.LogLine, threshold time.Duration) []report.Event {
+	if threshold <= 0 {
+		return nil
+	}
+
+	type span struct{ first, last time.Time }
+	spans := make(map[string]*span)
+	var captureStart, captureEnd time.Time
+	var gaps []report.Event
+	for _, line := range lines {
+		ts := line.GetTimestamp()
+		if ts == nil {
+			continue
+		}
+		t := ts.Time
+		if captureStart.IsZero() || t.Before(captureStart) {
+			captureStart = t
+		}
+		if t.After(captureEnd) {
+			captureEnd = t
+		}
+
+		s, ok := spans[line.Tag]
+		if !ok {
+			spans[line.Tag] = &span{first: t, last: t}
+			continue
+		}
+		if t.Sub(s.last) > threshold {
+			gaps = append(gaps, gapEvent(line.Tag, s.last, t, ""))
+		}
+		if t.After(s.last) {
+			s.last = t
+		}
+	}
+
+	for tag, s := range spans {
+		if s.first.Sub(captureStart) > threshold {
+			gaps = append(gaps, gapEvent(tag, captureStart, s.first, " at the start of the capture"))
+		}
+		if captureEnd.Sub(s.last) > threshold {
+			gaps = append(gaps, gapEvent(tag, s.last, captureEnd, " at the end of the capture"))
+		}
+	}
+
+	sort.SliceStable(gaps, func(i, j int) bool {
+		if !gaps[i].Time.Equal(gaps[j].Time) {
+			return gaps[i].Time.Before(gaps[j].Time)
+		}
+		return gaps[i].Tag < gaps[j].Tag
+	})
+	return gaps
+}
+
+// gapEvent creates the event of a gap of a tag from start to end
+func gapEvent(tag string, start, end time.Time, where string) report.Event {
+	duration := end.Sub(start)
+	return report.Event{
+		Time:            start,
+		Type:            EventGap,
+		Tag:             tag,
+		Message:         fmt.Sprintf("no lines for %v%s", duration.Round(time.Millisecond), where),
+		DurationSeconds: duration.Seconds(),
+	}
+}
@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"math"
+	"strings"
+)
+
+// IsHoldover reports whether a state is a holdover state: one of the given states
+// (case-insensitive), or without any, a state starting with HOLDOVER (e.g., DPLL holdover)
+func IsHoldover(state string, holdover []string) bool {
+	if len(holdover) == 0 {
+		return strings.HasPrefix(strings.ToUpper(state), "HOLDOVER")
+	}
+	return IsLocked(state, holdover)
+}
+
+// ComputeHoldovers computes the holdover episodes of a state-mapped series, or nil if the series
+// never enters holdover. Consecutive holdover states form one episode, which ends at the
+// transition to a state out of holdover (or at the last point of the series, if still in
+// holdover). The recovery time is from the end of an episode to the next locked state, with
+// locked states defaulting to DefaultLockedStates. With the points of a phase series, the
+// maximum phase excursion of each episode is the largest deviation of the phase within it from
+// the phase at its start (the last point at or before the start, else the first within).
+func ComputeHoldovers(name string, points []pattern.MetricPoint, holdover, locked []string, phaseName string, phase []pattern.MetricPoint) *report.HoldoverStats {
+	segments := StateSegments(points)
+	if len(locked) == 0 {
+		locked = DefaultLockedStates
+	}
+	if len(phase) == 0 {
+		phaseName = ""
+	}
+	phase = sortedByTime(phase)
+
+	stats := &report.HoldoverStats{Series: name, PhaseSeries: phaseName}
+	for i := 0; i < len(segments); i++ {
+		if !IsHoldover(segments[i].State, holdover) {
+			continue
+		}
+		episode := report.HoldoverEpisode{Start: segments[i].Start}
+		if i > 0 {
+			episode.EntryState = segments[i-1].State
+		}
+		for i+1 < len(segments) && IsHoldover(segments[i+1].State, holdover) {
+			i++
+		}
+		episode.End = segments[i].End
+		if i+1 < len(segments) {
+			episode.ExitState = segments[i+1].State
+			for _, seg := range segments[i+1:] {
+				if IsLocked(seg.State, locked) {
+					recovery := seg.Start.Sub(episode.End).Seconds()
+					episode.RecoverySeconds = &recovery
+					break
+				}
+			}
+		}
+		episode.DurationSeconds = episode.End.Sub(episode.Start).Seconds()
+		if phaseName != "" {
+			episode.MaxExcursion = phaseExcursion(phase, episode)
+		}
+
+		stats.TotalSeconds += episode.DurationSeconds
+		stats.LongestSeconds = math.Max(stats.LongestSeconds, episode.DurationSeconds)
+		if episode.MaxExcursion != nil && (stats.MaxExcursion == nil || *episode.MaxExcursion > *stats.MaxExcursion) {
+			stats.MaxExcursion = episode.MaxExcursion
+		}
+		stats.Episodes = append(stats.Episodes, episode)
+	}
+	if len(stats.Episodes) == 0 {
+		return nil
+	}
+	return stats
+}
+
+// phaseExcursion returns the largest deviation of the time-sorted phase points within an episode
+// from the phase at its start, or nil without points within the episode
+func phaseExcursion(phase []pattern.MetricPoint, episode report.HoldoverEpisode) *float64 {
+	var reference, excursion *float64
+	for k := range phase {
+		pt := &phase[k]
+		if !pt.Time.After(episode.Start) {
+			reference = &pt.Value
+		}
+		if pt.Time.Before(episode.Start) {
+			continue
+		}
+		if pt.Time.After(episode.End) {
+			break
+		}
+		if reference == nil {
+			reference = &pt.Value
+		}
+		if deviation := math.Abs(pt.Value - *reference); excursion == nil || deviation > *excursion {
+			excursion = &deviation
+		}
+	}
+	return excursion
+}
@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"log-interleaver/pkg/pattern"
+)
+
+// stateSeries returns points of the states, one per second from seriesStart
+func stateSeries(states ...string) []pattern.MetricPoint {
+	points := make([]pattern.MetricPoint, len(states))
+	for i, state := range states {
+		points[i] = pattern.MetricPoint{Time: seriesStart.Add(time.Duration(i) * time.Second), State: state}
+	}
+	return points
+}
+
+func TestComputeHoldovers(t *testing.T) {
+	tests := []struct {
+		name     string
+		states   []string
+		holdover []string
+		episodes []struct {
+			start, duration float64 // Seconds
+			entry, exit     string
+			recovery        float64 // Seconds, -1 = not locked again
+		}
+	}{
+		{
+			name:   "default holdover states",
+			states: []string{"LOCKED", "HOLDOVER", "HOLDOVER_IN_SPEC", "FREERUN", "LOCKED"},
+			episodes: []struct {
+				start, duration float64
+				entry, exit     string
+				recovery        float64
+			}{{1, 2, "LOCKED", "FREERUN", 1}},
+		},
+		{
+			name:     "configured holdover states",
+			states:   []string{"s2", "s1", "s2", "s2", "s1", "s1"},
+			holdover: []string{"s1"},
+			episodes: []struct {
+				start, duration float64
+				entry, exit     string
+				recovery        float64
+			}{{1, 1, "s2", "s2", 0}, {4, 1, "s2", "", -1}},
+		},
+		{
+			name:   "no holdover",
+			states: []string{"s0", "s1", "s2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := ComputeHoldovers("state", stateSeries(tt.states...), tt.holdover, nil, "", nil)
+			if len(tt.episodes) == 0 {
+				if stats != nil {
+					t.Fatalf("%d episodes, want none", len(stats.Episodes))
+				}
+				return
+			}
+			if stats == nil || len(stats.Episodes) != len(tt.episodes) {
+				t.Fatalf("episodes %+v, want %d", stats, len(tt.episodes))
+			}
+			for i, want := range tt.episodes {
+				got := stats.Episodes[i]
+				if start := got.Start.Sub(seriesStart).Seconds(); start != want.start || got.DurationSeconds != want.duration {
+					t.Errorf("episode %d at %gs for %gs, want at %gs for %gs", i, start, got.DurationSeconds, want.start, want.duration)
+				}
+				if got.EntryState != want.entry || got.ExitState != want.exit {
+					t.Errorf("episode %d from %q to %q, want from %q to %q", i, got.EntryState, got.ExitState, want.entry, want.exit)
+				}
+				switch {
+				case want.recovery < 0 && got.RecoverySeconds != nil:
+					t.Errorf("episode %d recovered after %gs, want no recovery", i, *got.RecoverySeconds)
+				case want.recovery >= 0 && (got.RecoverySeconds == nil || *got.RecoverySeconds != want.recovery):
+					t.Errorf("episode %d recovery %v, want %gs", i, got.RecoverySeconds, want.recovery)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeHoldoversPhaseExcursion(t *testing.T) {
+	states := stateSeries("LOCKED", "HOLDOVER", "HOLDOVER", "HOLDOVER", "LOCKED")
+	phase := series(time.Second, 5, 6, -20, 40, 0)
+	stats := ComputeHoldovers("state", states, nil, nil, "phase", phase)
+	if stats == nil || stats.MaxExcursion == nil {
+		t.Fatalf("no phase excursion in %+v", stats)
+	}
+	// The episode runs from 1 s to 4 s, so the excursion is from the 6 at its start
+	if got := *stats.MaxExcursion; got != 34 {
+		t.Errorf("max excursion %g, want 34", got)
+	}
+}
@@ -0,0 +1,162 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"math"
+	"sort"
+)
+
+// MTIEMask is an MTIE mask: limits in nanoseconds at increasing observation intervals, with the
+// limit between two points interpolated on log-log scales
+type MTIEMask struct {
+	Name   string
+	Limits []report.MaskLimit
+}
+
+// BuiltinMTIEMasks are the ITU-T G.8273.2 dTE_L MTIE masks of T-BC and T-TSC clocks under
+// constant temperature, for observation intervals of 1 s to 1000 s
+var BuiltinMTIEMasks = []MTIEMask{
+	{Name: "class-a", Limits: []report.MaskLimit{{TauSeconds: 1, LimitNs: 40}, {TauSeconds: 1000, LimitNs: 40}}},
+	{Name: "class-b", Limits: []report.MaskLimit{{TauSeconds: 1, LimitNs: 40}, {TauSeconds: 1000, LimitNs: 40}}},
+	{Name: "class-c", Limits: []report.MaskLimit{{TauSeconds: 1, LimitNs: 10}, {TauSeconds: 1000, LimitNs: 10}}},
+	// The recommendation leaves the class D dTE_L MTIE mask for further study; its max|TE_L|
+	// limit of 5 ns is used as a flat mask instead
+	{Name: "class-d", Limits: []report.MaskLimit{{TauSeconds: 1, LimitNs: 5}, {TauSeconds: 1000, LimitNs: 5}}},
+}
+
+// ComputeMTIE computes the MTIE of the low-frequency component (dTE_L) of a time error series
+// with values in the given unit, and checks it against the masks. The series is low-pass
+// filtered at cutoffHz (0 = default 0.1 Hz) and resampled onto a uniform grid at its median
+// sample interval (tau0). MTIE is computed at octave-spaced multiples of tau0 and at the mask
+// points; each mask is checked at the taus within its range.
+func ComputeMTIE(name string, points []pattern.MetricPoint, unit string, cutoffHz float64, masks []MTIEMask) (*report.MTIEStats, error) {
+	scale, err := phaseScale(unit)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("series '%s' has %d data points, at least 2 are needed", name, len(points))
+	}
+	if cutoffHz <= 0 {
+		cutoffHz = DefaultTECutoffHz
+	}
+
+	sorted := make([]pattern.MetricPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	tau0 := medianInterval(sorted)
+	if tau0 <= 0 {
+		return nil, fmt.Errorf("series '%s' has no distinct timestamps", name)
+	}
+
+	// Filter, convert to ns, and resample
+	low, _ := splitTE(sorted, cutoffHz)
+	filtered := make([]pattern.MetricPoint, len(sorted))
+	for i, pt := range sorted {
+		filtered[i] = pattern.MetricPoint{Time: pt.Time, Value: low[i] * scale / 1e-9}
+	}
+	x := resample(filtered, tau0)
+
+	// A window of n intervals spans n+1 samples
+	multiples := make(map[int]bool)
+	for n := 1; n < len(x); n *= 2 {
+		multiples[n] = true
+	}
+	for _, mask := range masks {
+		for _, limit := range mask.Limits {
+			if n := int(math.Round(limit.TauSeconds / tau0)); n >= 1 && n < len(x) {
+				multiples[n] = true
+			}
+		}
+	}
+	ns := make([]int, 0, len(multiples))
+	for n := range multiples {
+		ns = append(ns, n)
+	}
+	sort.Ints(ns)
+
+	stats := &report.MTIEStats{
+		Series:      name,
+		Samples:     len(x),
+		Tau0Seconds: tau0,
+		CutoffHz:    cutoffHz,
+	}
+	for _, n := range ns {
+		stats.Points = append(stats.Points, report.MTIEPoint{TauSeconds: float64(n) * tau0, MTIENs: mtieAt(x, n)})
+	}
+
+	for _, mask := range masks {
+		result := report.MaskResult{Mask: mask.Name, Limits: mask.Limits}
+		first := int(math.Round(mask.Limits[0].TauSeconds / tau0))
+		last := int(math.Round(mask.Limits[len(mask.Limits)-1].TauSeconds / tau0))
+		for i, n := range ns {
+			if n < first || n > last {
+				continue
+			}
+			pt := stats.Points[i]
+			limit := maskLimit(mask.Limits, pt.TauSeconds)
+			result.Checks = append(result.Checks, report.MaskCheck{
+				TauSeconds: pt.TauSeconds,
+				MTIENs:     pt.MTIENs,
+				LimitNs:    limit,
+				Pass:       pt.MTIENs <= limit,
+			})
+		}
+		result.Pass = len(result.Checks) > 0
+		for _, check := range result.Checks {
+			result.Pass = result.Pass && check.Pass
+		}
+		stats.Masks = append(stats.Masks, result)
+	}
+
+	return stats, nil
+}
+
+// mtieAt returns the MTIE over windows of n intervals: the largest peak-to-peak time error
+// within any window. The window extremes are tracked with monotonic queues.
+func mtieAt(x []float64, n int) float64 {
+	var maxQ, minQ []int
+	mtie := 0.0
+	for i := range x {
+		for len(maxQ) > 0 && x[maxQ[len(maxQ)-1]] <= x[i] {
+			maxQ = maxQ[:len(maxQ)-1]
+		}
+		maxQ = append(maxQ, i)
+		for len(minQ) > 0 && x[minQ[len(minQ)-1]] >= x[i] {
+			minQ = minQ[:len(minQ)-1]
+		}
+		minQ = append(minQ, i)
+
+		if maxQ[0] < i-n {
+			maxQ = maxQ[1:]
+		}
+		if minQ[0] < i-n {
+			minQ = minQ[1:]
+		}
+		if i >= n {
+			mtie = math.Max(mtie, x[maxQ[0]]-x[minQ[0]])
+		}
+	}
+	return mtie
+}
+
+// maskLimit returns the limit of a mask at tau, interpolated on log-log scales between the
+// mask points and clamped to the first and last points
+func maskLimit(limits []report.MaskLimit, tau float64) float64 {
+	if tau <= limits[0].TauSeconds {
+		return limits[0].LimitNs
+	}
+	for i := 1; i < len(limits); i++ {
+		a, b := limits[i-1], limits[i]
+		if tau <= b.TauSeconds {
+			frac := math.Log(tau/a.TauSeconds) / math.Log(b.TauSeconds/a.TauSeconds)
+			return a.LimitNs * math.Pow(b.LimitNs/a.LimitNs, frac)
+		}
+	}
+	return limits[len(limits)-1].LimitNs
+}
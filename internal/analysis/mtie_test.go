@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"log-interleaver/pkg/report"
+)
+
+func TestMTIEAt(t *testing.T) {
+	ramp := make([]float64, 20)
+	for i := range ramp {
+		ramp[i] = 2 * float64(i)
+	}
+	spike := make([]float64, 20)
+	spike[7] = 10
+	tests := []struct {
+		name string
+		x    []float64
+		n    int
+		want float64
+	}{
+		{"ramp over one interval", ramp, 1, 2},
+		{"ramp over five intervals", ramp, 5, 10},
+		{"ramp over the series", ramp, 19, 38},
+		{"spike", spike, 1, 10},
+		{"spike in a long window", spike, 12, 10},
+		{"window longer than the series", ramp, 25, 0},
+	}
+	for _, tt := range tests {
+		if got := mtieAt(tt.x, tt.n); got != tt.want {
+			t.Errorf("%s: mtieAt = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMaskLimit(t *testing.T) {
+	limits := []report.MaskLimit{{TauSeconds: 1, LimitNs: 10}, {TauSeconds: 100, LimitNs: 1000}, {TauSeconds: 1000, LimitNs: 1000}}
+	tests := []struct {
+		tau  float64
+		want float64
+	}{
+		{0.5, 10},   // Clamped to the first point
+		{1, 10},     // At a mask point
+		{10, 100},   // Log-log interpolation
+		{500, 1000}, // Flat segment
+		{5000, 1000},
+	}
+	for _, tt := range tests {
+		if got := maskLimit(limits, tt.tau); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("maskLimit at %gs = %g, want %g", tt.tau, got, tt.want)
+		}
+	}
+}
+
+func TestComputeMTIEMasks(t *testing.T) {
+	// A slow 0.02 ns/s ramp for 2000 s: MTIE grows to 20 ns at 1000 s, within the 40 ns of
+	// class A but beyond the 10 ns of class C
+	values := make([]float64, 2001)
+	for i := range values {
+		values[i] = 0.02 * float64(i)
+	}
+	var masks []MTIEMask
+	for _, mask := range BuiltinMTIEMasks {
+		if mask.Name == "class-a" || mask.Name == "class-c" {
+			masks = append(masks, mask)
+		}
+	}
+	stats, err := ComputeMTIE("offset", series(time.Second, values...), "ns", 0, masks)
+	if err != nil {
+		t.Fatalf("ComputeMTIE: %v", err)
+	}
+	for _, pt := range stats.Points {
+		if want := 0.02 * pt.TauSeconds; math.Abs(pt.MTIENs-want) > 0.05*want+0.01 {
+			t.Errorf("MTIE at %gs %g ns, want about %g ns", pt.TauSeconds, pt.MTIENs, want)
+		}
+	}
+	want := map[string]bool{"class-a": true, "class-c": false}
+	if len(stats.Masks) != len(want) {
+		t.Fatalf("%d mask results, want %d", len(stats.Masks), len(want))
+	}
+	for _, result := range stats.Masks {
+		if result.Pass != want[result.Mask] {
+			t.Errorf("mask %s pass=%v, want %v", result.Mask, result.Pass, want[result.Mask])
+		}
+		if last := result.Checks[len(result.Checks)-1]; last.TauSeconds != 1000 {
+			t.Errorf("mask %s checked up to %gs, want 1000s", result.Mask, last.TauSeconds)
+		}
+	}
+}
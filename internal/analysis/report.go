@@ -0,0 +1,83 @@
+package analysis
+
+import (
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"sort"
+	"time"
+)
+
+// BuildReport builds the analysis report from interleaved lines, per-tag reordering statistics,
+// and (optionally) the extracted metrics with their extraction statistics. seriesOrder lists
+// the series names in config order; value statistics are computed for the names in valueSeries.
+func BuildReport(lines []*parser.LogLine, reorderStats map[string]interleaver.ReorderStats,
+	metrics map[string][]pattern.MetricPoint, extraction map[string]pattern.ExtractionStats,
+	seriesOrder []string, valueSeries map[string]bool) *report.AnalysisReport {
+	r := &report.AnalysisReport{
+		GeneratedAt: time.Now().UTC(),
+		TotalLines:  len(lines),
+	}
+
+	tagStats := make(map[string]*report.TagStats)
+	for _, line := range lines {
+		stats, ok := tagStats[line.Tag]
+		if !ok {
+			stats = &report.TagStats{Tag: line.Tag}
+			tagStats[line.Tag] = stats
+		}
+		stats.Lines++
+
+		ts := line.GetTimestamp()
+		if ts == nil {
+			r.LinesWithoutTimestamp++
+			continue
+		}
+		r.LinesWithTimestamp++
+		stats.LinesWithTimestamp++
+		if r.StartTime == nil || ts.Time.Before(*r.StartTime) {
+			t := ts.Time
+			r.StartTime = &t
+		}
+		if r.EndTime == nil || ts.Time.After(*r.EndTime) {
+			t := ts.Time
+			r.EndTime = &t
+		}
+	}
+
+	for tag, reorder := range reorderStats {
+		stats, ok := tagStats[tag]
+		if !ok {
+			continue
+		}
+		stats.Reordered = reorder.Reordered
+		stats.MaxLatenessSeconds = reorder.MaxLateness.Seconds()
+		stats.LateBeyondWindow = reorder.Late
+	}
+
+	r.Tags = make([]report.TagStats, 0, len(tagStats))
+	for _, stats := range tagStats {
+		r.Tags = append(r.Tags, *stats)
+	}
+	sort.Slice(r.Tags, func(i, j int) bool {
+		return r.Tags[i].Tag < r.Tags[j].Tag
+	})
+
+	for _, name := range seriesOrder {
+		stats := extraction[name]
+		series := report.SeriesStats{
+			Name:       name,
+			Matches:    stats.Matches,
+			Points:     stats.Points,
+			Duplicates: stats.Duplicates,
+			Filtered:   stats.Filtered,
+		}
+		if valueSeries[name] {
+			series.Values = ComputeValueStats(metrics[name])
+		}
+		r.Series = append(r.Series, series)
+	}
+
+	return r
+}
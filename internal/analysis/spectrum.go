@@ -0,0 +1,168 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+const (
+	maxSpectrumSegment = 8192 // Longest FFT segment, bounding the points of a spectrum
+	spectrumPeakRatio  = 10   // Power over the median of the spectrum for a peak (10 dB)
+	maxSpectrumPeaks   = 5
+)
+
+// ComputeSpectrum computes the power spectral density of a time error (phase) series with values
+// in the given unit, to spot periodic disturbances such as PPS artifacts or temperature cycles.
+// The series is resampled like for ComputeStability and the PSD is estimated with Welch's method:
+// the average periodogram of half-overlapping, linearly detrended, Hann-windowed segments of the
+// largest power of two samples up to half the series (so at least three segments are averaged).
+// The PSD is one-sided, in the unit of the series squared per Hz. Peaks are the local maxima
+// standing 10 dB above the median of the spectrum, the strongest first.
+func ComputeSpectrum(name string, points []pattern.MetricPoint, unit string) (*report.SpectrumStats, error) {
+	if _, err := phaseScale(unit); err != nil {
+		return nil, err
+	}
+	if len(points) < 3 {
+		return nil, fmt.Errorf("series '%s' has %d data points, at least 3 are needed", name, len(points))
+	}
+	sorted := sortedByTime(points)
+	tau0 := medianInterval(sorted)
+	if tau0 <= 0 {
+		return nil, fmt.Errorf("series '%s' has no distinct timestamps", name)
+	}
+	x := resample(sorted, tau0)
+
+	segment := 8
+	for 2*segment <= len(x)/2 && 2*segment <= maxSpectrumSegment {
+		segment *= 2
+	}
+	if 2*segment > len(x) {
+		return nil, fmt.Errorf("series '%s' has %d samples after resampling, at least 16 are needed", name, len(x))
+	}
+
+	window := make([]float64, segment)
+	windowPower := 0.0
+	for i := range window {
+		window[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(segment)))
+		windowPower += window[i] * window[i]
+	}
+
+	power := make([]float64, segment/2+1)
+	segments := 0
+	buf := make([]complex128, segment)
+	for start := 0; start+segment <= len(x); start += segment / 2 {
+		detrended := detrend(x[start : start+segment])
+		for i, v := range detrended {
+			buf[i] = complex(v*window[i], 0)
+		}
+		fft(buf)
+		for k := range power {
+			power[k] += real(buf[k])*real(buf[k]) + imag(buf[k])*imag(buf[k])
+		}
+		segments++
+	}
+
+	rate := 1 / tau0
+	stats := &report.SpectrumStats{
+		Series:        name,
+		Samples:       len(x),
+		Tau0Seconds:   tau0,
+		SegmentLength: segment,
+		Segments:      segments,
+		ResolutionHz:  rate / float64(segment),
+		Unit:          unit,
+		Points:        make([]report.SpectrumPoint, len(power)),
+	}
+	for k, p := range power {
+		// One-sided: the power of the negative frequencies is folded onto the positive ones
+		scale := 2 / (rate * windowPower * float64(segments))
+		if k == 0 || k == segment/2 {
+			scale /= 2
+		}
+		stats.Points[k] = report.SpectrumPoint{FrequencyHz: float64(k) * stats.ResolutionHz, PSD: p * scale}
+	}
+	stats.Peaks = spectrumPeaks(stats.Points)
+	return stats, nil
+}
+
+// detrend returns the values less their least-squares line, so a frequency offset (a phase
+// ramp) does not leak into the low frequencies
+func detrend(values []float64) []float64 {
+	n := float64(len(values))
+	meanX := (n - 1) / 2
+	var meanY, sxy, sxx float64
+	for _, v := range values {
+		meanY += v
+	}
+	meanY /= n
+	for i, v := range values {
+		dx := float64(i) - meanX
+		sxy += dx * (v - meanY)
+		sxx += dx * dx
+	}
+	slope := sxy / sxx
+	detrended := make([]float64, len(values))
+	for i, v := range values {
+		detrended[i] = v - meanY - slope*(float64(i)-meanX)
+	}
+	return detrended
+}
+
+// fft computes the discrete Fourier transform of values of a power of two length in place
+// (iterative radix-2 Cooley-Tukey)
+func fft(values []complex128) {
+	n := len(values)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := values[start+k], w*values[start+k+size/2]
+				values[start+k], values[start+k+size/2] = even+odd, even-odd
+				w *= step
+			}
+		}
+	}
+}
+
+// spectrumPeaks returns the strongest local maxima of a spectrum above the peak ratio times its
+// median, leaving out the DC bin
+func spectrumPeaks(points []report.SpectrumPoint) []report.SpectrumPeak {
+	if len(points) < 3 {
+		return nil
+	}
+	psd := make([]float64, 0, len(points)-1)
+	for _, pt := range points[1:] {
+		psd = append(psd, pt.PSD)
+	}
+	sort.Float64s(psd)
+	threshold := spectrumPeakRatio * psd[len(psd)/2]
+
+	var peaks []report.SpectrumPeak
+	for k := 1; k < len(points); k++ {
+		pt := points[k]
+		if pt.PSD <= threshold || pt.PSD < points[k-1].PSD || k+1 < len(points) && pt.PSD < points[k+1].PSD {
+			continue
+		}
+		peaks = append(peaks, report.SpectrumPeak{FrequencyHz: pt.FrequencyHz, PeriodSeconds: 1 / pt.FrequencyHz, PSD: pt.PSD})
+	}
+	sort.SliceStable(peaks, func(i, j int) bool { return peaks[i].PSD > peaks[j].PSD })
+	if len(peaks) > maxSpectrumPeaks {
+		peaks = peaks[:maxSpectrumPeaks]
+	}
+	return peaks
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"log-interleaver/pkg/pattern"
+)
+
+func TestComputeSpectrumPeak(t *testing.T) {
+	// A 0.05 Hz disturbance (a 20 s period) sampled at 1 Hz over about an hour
+	const hz = 0.05
+	points := series(time.Second, sine(4096, time.Second, hz, 10)...)
+	stats, err := ComputeSpectrum("offset", points, "ns")
+	if err != nil {
+		t.Fatalf("ComputeSpectrum: %v", err)
+	}
+	if stats.Tau0Seconds != 1 || stats.Samples != 4096 {
+		t.Errorf("%d samples at %gs, want 4096 at 1s", stats.Samples, stats.Tau0Seconds)
+	}
+	if stats.Segments < 3 {
+		t.Errorf("%d segments averaged, want at least 3", stats.Segments)
+	}
+	if len(stats.Peaks) == 0 {
+		t.Fatal("no peaks")
+	}
+	if peak := stats.Peaks[0]; math.Abs(peak.FrequencyHz-hz) > stats.ResolutionHz {
+		t.Errorf("strongest peak at %g Hz, want %g Hz within %g Hz", peak.FrequencyHz, hz, stats.ResolutionHz)
+	}
+}
+
+func TestComputeSpectrumInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []pattern.MetricPoint
+		unit   string
+	}{
+		{"unit", series(time.Second, sine(64, time.Second, 0.1, 1)...), "ppb"},
+		{"too few points", series(time.Second, 1, 2), "ns"},
+		{"too few samples", series(time.Second, sine(10, time.Second, 0.1, 1)...), "ns"},
+		{"no distinct timestamps", series(0, 1, 2, 3, 4), "ns"},
+	}
+	for _, tt := range tests {
+		if _, err := ComputeSpectrum("offset", tt.points, tt.unit); err == nil {
+			t.Errorf("%s: ComputeSpectrum succeeded, want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"math"
+	"sort"
+	"time"
+)
+
+// phaseScale returns the factor converting the values of a time error (phase) series in the
+// unit to seconds
+func phaseScale(unit string) (float64, error) {
+	scale, err := pattern.UnitScale(unit, "s")
+	if err != nil {
+		return 0, fmt.Errorf("invalid phase unit '%s', expected ps, ns, us, ms, or s", unit)
+	}
+	return scale, nil
+}
+
+// ComputeStability computes the Allan deviation (ADEV), modified Allan deviation (MDEV), and
+// time deviation (TDEV) of a time error (phase) series with values in the given unit.
+// The series is resampled by linear interpolation onto a uniform grid at its median sample
+// interval (tau0). Each tau is rounded to a multiple of tau0, and taus too long for the series
+// are skipped; with no taus, octave-spaced multiples of tau0 are used up to a third of the
+// series length. TDEV is in the unit of the series.
+func ComputeStability(name string, points []pattern.MetricPoint, unit string, taus []time.Duration) (*report.StabilityStats, error) {
+	scale, err := phaseScale(unit)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) < 3 {
+		return nil, fmt.Errorf("series '%s' has %d data points, at least 3 are needed", name, len(points))
+	}
+
+	sorted := make([]pattern.MetricPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	tau0 := medianInterval(sorted)
+	if tau0 <= 0 {
+		return nil, fmt.Errorf("series '%s' has no distinct timestamps", name)
+	}
+	x := resample(sorted, tau0)
+
+	var multiples []int
+	if len(taus) == 0 {
+		for n := 1; 3*n <= len(x); n *= 2 {
+			multiples = append(multiples, n)
+		}
+	} else {
+		seen := make(map[int]bool)
+		for _, tau := range taus {
+			n := int(math.Round(tau.Seconds() / tau0))
+			if n >= 1 && 3*n <= len(x) && !seen[n] {
+				seen[n] = true
+				multiples = append(multiples, n)
+			}
+		}
+		sort.Ints(multiples)
+	}
+
+	stats := &report.StabilityStats{
+		Series:      name,
+		Samples:     len(x),
+		Tau0Seconds: tau0,
+		Unit:        unit,
+	}
+	for _, n := range multiples {
+		stats.Points = append(stats.Points, stabilityAt(x, n, tau0, scale))
+	}
+	return stats, nil
+}
+
+// stabilityAt computes ADEV, MDEV, and TDEV at tau = n*tau0 from uniformly sampled phase x
+func stabilityAt(x []float64, n int, tau0, scale float64) report.StabilityPoint {
+	tau := float64(n) * tau0
+	N := len(x)
+
+	// Second differences of the phase, the common term of all three deviations
+	d := make([]float64, N-2*n)
+	sumSq := 0.0
+	for i := range d {
+		d[i] = x[i+2*n] - 2*x[i+n] + x[i]
+		sumSq += d[i] * d[i]
+	}
+	avar := sumSq * scale * scale / (2 * tau * tau * float64(len(d)))
+
+	// MDEV averages the second differences over n adjacent samples before squaring
+	terms := N - 3*n + 1
+	window := 0.0
+	for i := 0; i < n; i++ {
+		window += d[i]
+	}
+	sumWindowSq := window * window
+	for j := 1; j < terms; j++ {
+		window += d[j+n-1] - d[j-1]
+		sumWindowSq += window * window
+	}
+	nf := float64(n)
+	tvar := sumWindowSq / (6 * nf * nf * float64(terms))
+	mvar := 3 * tvar * scale * scale / (tau * tau)
+
+	return report.StabilityPoint{
+		TauSeconds: tau,
+		ADEV:       math.Sqrt(avar),
+		MDEV:       math.Sqrt(mvar),
+		TDEV:       math.Sqrt(tvar),
+	}
+}
+
+// medianInterval returns the median interval between consecutive points in seconds,
+// ignoring points with the same timestamp
+func medianInterval(points []pattern.MetricPoint) float64 {
+	var intervals []float64
+	for i := 1; i < len(points); i++ {
+		if dt := points[i].Time.Sub(points[i-1].Time).Seconds(); dt > 0 {
+			intervals = append(intervals, dt)
+		}
+	}
+	if len(intervals) == 0 {
+		return 0
+	}
+	sort.Float64s(intervals)
+	return intervals[len(intervals)/2]
+}
+
+// resample linearly interpolates time-sorted points onto a uniform grid with the given step
+// in seconds, starting at the first point
+func resample(points []pattern.MetricPoint, step float64) []float64 {
+	start := points[0].Time
+	duration := points[len(points)-1].Time.Sub(start).Seconds()
+	x := make([]float64, int(math.Floor(duration/step+1e-9))+1)
+	j := 0
+	for i := range x {
+		t := float64(i) * step
+		for j < len(points)-2 && points[j+1].Time.Sub(start).Seconds() < t {
+			j++
+		}
+		t0 := points[j].Time.Sub(start).Seconds()
+		t1 := points[j+1].Time.Sub(start).Seconds()
+		if t1 <= t0 {
+			x[i] = points[j+1].Value
+			continue
+		}
+		frac := math.Max(0, math.Min(1, (t-t0)/(t1-t0)))
+		x[i] = points[j].Value + frac*(points[j+1].Value-points[j].Value)
+	}
+	return x
+}
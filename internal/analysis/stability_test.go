@@ -0,0 +1,144 @@
+package analysis
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"log-interleaver/pkg/pattern"
+)
+
+var seriesStart = time.Date(2026, 1, 11, 14, 0, 0, 0, time.UTC)
+
+// series returns points of the values at the interval from seriesStart
+func series(interval time.Duration, values ...float64) []pattern.MetricPoint {
+	points := make([]pattern.MetricPoint, len(values))
+	for i, value := range values {
+		points[i] = pattern.MetricPoint{Time: seriesStart.Add(time.Duration(i) * interval), Value: value}
+	}
+	return points
+}
+
+// whitePhase returns n samples of white phase noise with the standard deviation sigma
+func whitePhase(n int, sigma float64) []float64 {
+	r := rand.New(rand.NewSource(1))
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = sigma * r.NormFloat64()
+	}
+	return x
+}
+
+func TestStabilityAtWhitePhase(t *testing.T) {
+	// White phase noise: ADEV is sqrt(3)*sigma/tau and falls as 1/tau, MDEV as tau^-1.5, and
+	// TDEV as tau^-0.5
+	const sigma = 10.0
+	x := whitePhase(1<<16, sigma)
+	short, long := stabilityAt(x, 1, 1, 1e-9), stabilityAt(x, 64, 1, 1e-9)
+
+	if want := math.Sqrt(3) * sigma * 1e-9; math.Abs(short.ADEV-want) > 0.02*want {
+		t.Errorf("ADEV(1s) %g, want %g", short.ADEV, want)
+	}
+	slope := func(short, long float64) float64 {
+		return math.Log(long/short) / math.Log(64)
+	}
+	tests := []struct {
+		name        string
+		short, long float64
+		want        float64
+	}{
+		{"ADEV", short.ADEV, long.ADEV, -1},
+		{"MDEV", short.MDEV, long.MDEV, -1.5},
+		{"TDEV", short.TDEV, long.TDEV, -0.5},
+	}
+	for _, tt := range tests {
+		if got := slope(tt.short, tt.long); math.Abs(got-tt.want) > 0.05 {
+			t.Errorf("%s slope %.3f, want %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStabilityAtFrequencyDrift(t *testing.T) {
+	// Phase c*t^2 is a frequency drift of 2c per second, with ADEV sqrt(2)*c*tau
+	const c = 1e-3
+	x := make([]float64, 100)
+	for i := range x {
+		x[i] = c * float64(i*i)
+	}
+	for _, n := range []int{1, 4, 16} {
+		got := stabilityAt(x, n, 1, 1)
+		if want := math.Sqrt2 * c * float64(n); math.Abs(got.ADEV-want) > 1e-12 {
+			t.Errorf("ADEV(%ds) %g, want %g", n, got.ADEV, want)
+		}
+	}
+
+	// A constant frequency offset (a phase ramp) has no instability
+	for i := range x {
+		x[i] = 5 * float64(i)
+	}
+	if got := stabilityAt(x, 4, 1, 1); got.ADEV > 1e-12 || got.MDEV > 1e-12 || got.TDEV > 1e-12 {
+		t.Errorf("ramp ADEV %g, MDEV %g, TDEV %g, want 0", got.ADEV, got.MDEV, got.TDEV)
+	}
+}
+
+func TestComputeStability(t *testing.T) {
+	points := series(time.Second/8, whitePhase(100, 1)...)
+	stats, err := ComputeStability("offset", points, "ns", nil)
+	if err != nil {
+		t.Fatalf("ComputeStability: %v", err)
+	}
+	if stats.Tau0Seconds != 0.125 || stats.Samples != 100 {
+		t.Errorf("%d samples at %gs, want 100 at 0.125s", stats.Samples, stats.Tau0Seconds)
+	}
+	// Octaves of tau0 up to a third of the series
+	var taus []float64
+	for _, pt := range stats.Points {
+		taus = append(taus, pt.TauSeconds)
+	}
+	if want := []float64{0.125, 0.25, 0.5, 1, 2, 4}; !equalFloats(taus, want) {
+		t.Errorf("taus %v, want %v", taus, want)
+	}
+
+	// Taus are rounded to multiples of tau0 and those too long are skipped
+	stats, err = ComputeStability("offset", points, "ns", []time.Duration{time.Second, 260 * time.Millisecond, 250 * time.Millisecond, time.Minute})
+	if err != nil {
+		t.Fatalf("ComputeStability: %v", err)
+	}
+	taus = taus[:0]
+	for _, pt := range stats.Points {
+		taus = append(taus, pt.TauSeconds)
+	}
+	if want := []float64{0.25, 1}; !equalFloats(taus, want) {
+		t.Errorf("taus %v, want %v", taus, want)
+	}
+}
+
+func TestComputeStabilityInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []pattern.MetricPoint
+		unit   string
+	}{
+		{"unit", series(time.Second, 1, 2, 3), "ppb"},
+		{"too few points", series(time.Second, 1, 2), "ns"},
+		{"no distinct timestamps", series(0, 1, 2, 3), "ns"},
+	}
+	for _, tt := range tests {
+		if _, err := ComputeStability("offset", tt.points, tt.unit, nil); err == nil {
+			t.Errorf("%s: ComputeStability succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLockedStates are the states considered locked when a pattern has no locked_states:
+// the ptp4l servo state s2 and the DPLL lock states
+var DefaultLockedStates = []string{"s2", "LOCKED", "LOCKED_HO_ACQ"}
+
+// StateSegment is a period a state-mapped series spent in one state
+type StateSegment struct {
+	State string
+	Start time.Time
+	End   time.Time // Next transition, or the last point of the series
+}
+
+// StateSegments splits a state-mapped series into the periods between state transitions
+func StateSegments(points []pattern.MetricPoint) []StateSegment {
+	sorted := make([]pattern.MetricPoint, 0, len(points))
+	for _, pt := range points {
+		if pt.State != "" {
+			sorted = append(sorted, pt)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	var segments []StateSegment
+	for _, pt := range sorted {
+		if n := len(segments); n > 0 {
+			segments[n-1].End = pt.Time
+			if segments[n-1].State == pt.State {
+				continue
+			}
+		}
+		segments = append(segments, StateSegment{State: pt.State, Start: pt.Time, End: pt.Time})
+	}
+	return segments
+}
+
+// IsLocked reports whether a state is one of the locked states (case-insensitive)
+func IsLocked(state string, locked []string) bool {
+	for _, l := range locked {
+		if strings.EqualFold(state, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeStateTimeline computes the state transition timeline of a state-mapped series, or nil
+// if the series has no states. Locked states default to DefaultLockedStates.
+func ComputeStateTimeline(name string, points []pattern.MetricPoint, locked []string) *report.StateTimeline {
+	segments := StateSegments(points)
+	if len(segments) == 0 {
+		return nil
+	}
+	if len(locked) == 0 {
+		locked = DefaultLockedStates
+	}
+
+	timeline := &report.StateTimeline{
+		Series:       name,
+		StartTime:    segments[0].Start,
+		EndTime:      segments[len(segments)-1].End,
+		InitialState: segments[0].State,
+	}
+	durations := make(map[string]*report.StateDuration)
+	var order []string
+	total := timeline.EndTime.Sub(timeline.StartTime).Seconds()
+	for i, seg := range segments {
+		seconds := seg.End.Sub(seg.Start).Seconds()
+		if i > 0 {
+			prev := segments[i-1].State
+			timeline.Transitions = append(timeline.Transitions, report.StateTransition{
+				Time:            seg.Start,
+				From:            prev,
+				To:              seg.State,
+				DurationSeconds: seconds,
+			})
+			if IsLocked(prev, locked) && !IsLocked(seg.State, locked) {
+				timeline.Unlocks++
+			}
+		}
+		if timeline.TimeToLockSeconds == nil && IsLocked(seg.State, locked) {
+			ttl := seg.Start.Sub(timeline.StartTime).Seconds()
+			timeline.TimeToLockSeconds = &ttl
+		}
+
+		d, ok := durations[seg.State]
+		if !ok {
+			d = &report.StateDuration{State: seg.State}
+			durations[seg.State] = d
+			order = append(order, seg.State)
+		}
+		d.Entries++
+		d.Seconds += seconds
+	}
+	for _, state := range order {
+		d := durations[state]
+		if total > 0 {
+			d.Fraction = d.Seconds / total
+		}
+		timeline.States = append(timeline.States, *d)
+	}
+	return timeline
+}
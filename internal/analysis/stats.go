@@ -0,0 +1,53 @@
+package analysis
+
+import (
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"math"
+	"sort"
+)
+
+// ComputeValueStats computes summary statistics of the values of a series, or nil if the
+// series has no points. Percentiles interpolate linearly between the closest ranks, and the
+// standard deviation is the sample standard deviation (as in spreadsheets' STDEV).
+func ComputeValueStats(points []pattern.MetricPoint) *report.ValueStats {
+	if len(points) == 0 {
+		return nil
+	}
+
+	values := make([]float64, len(points))
+	sum := 0.0
+	for i, pt := range points {
+		values[i] = pt.Value
+		sum += pt.Value
+	}
+	sort.Float64s(values)
+
+	mean := sum / float64(len(values))
+	stddev := 0.0
+	if len(values) > 1 {
+		sumSq := 0.0
+		for _, v := range values {
+			sumSq += (v - mean) * (v - mean)
+		}
+		stddev = math.Sqrt(sumSq / float64(len(values)-1))
+	}
+
+	return &report.ValueStats{
+		Min:    values[0],
+		Max:    values[len(values)-1],
+		Mean:   mean,
+		Median: percentile(values, 50),
+		P95:    percentile(values, 95),
+		P99:    percentile(values, 99),
+		StdDev: stddev,
+	}
+}
+
+// percentile returns the p-th percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
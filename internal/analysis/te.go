@@ -0,0 +1,129 @@
+package analysis
+
+import (
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultTECutoffHz is the ITU-T G.8273.2 corner frequency separating dTE_L from dTE_H, the
+// default of the te_report config
+const DefaultTECutoffHz = config.DefaultTECutoffHz
+
+// ComputeTimeError computes time error statistics for a series. The series is split into
+// low- and high-frequency components with a first-order filter at cutoffHz (0 = default 0.1 Hz).
+// cTE is the mean over the measurement period, or with a cteWindow, the largest mean (by
+// magnitude) over consecutive windows of that length; a partial last window is left out.
+func ComputeTimeError(name string, points []pattern.MetricPoint, cutoffHz float64, cteWindow time.Duration) (*report.TimeErrorStats, error) {
+	if len(points) == 0 {
+		return nil, fmt.Errorf("series '%s' has no data points", name)
+	}
+	if cutoffHz <= 0 {
+		cutoffHz = DefaultTECutoffHz
+	}
+
+	sorted := make([]pattern.MetricPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	stats := &report.TimeErrorStats{
+		Series:          name,
+		Samples:         len(sorted),
+		DurationSeconds: sorted[len(sorted)-1].Time.Sub(sorted[0].Time).Seconds(),
+		CutoffHz:        cutoffHz,
+	}
+
+	// cTE and max|TE| on the unfiltered series
+	sum := 0.0
+	for _, pt := range sorted {
+		sum += pt.Value
+		stats.MaxAbsTE = math.Max(stats.MaxAbsTE, math.Abs(pt.Value))
+	}
+	stats.ConstantTE = sum / float64(len(sorted))
+	// Without a complete window, cTE stays the mean over the measurement period
+	if cteWindow > 0 {
+		if windows := windowMeans(sorted, cteWindow); len(windows) > 0 {
+			stats.CTEWindowSeconds = cteWindow.Seconds()
+			stats.CTEWindows = windows
+			for i, w := range windows {
+				if i == 0 || math.Abs(w.CTE) > math.Abs(stats.ConstantTE) {
+					stats.ConstantTE = w.CTE
+				}
+			}
+		}
+	}
+
+	// Split into low- and high-frequency components
+	low, high := splitTE(sorted, cutoffHz)
+	for _, v := range low {
+		stats.MaxAbsTEL = math.Max(stats.MaxAbsTEL, math.Abs(v))
+	}
+	stats.DynamicTELPkPk = peakToPeak(low)
+	stats.DynamicTEHPkPk = peakToPeak(high)
+
+	return stats, nil
+}
+
+// windowMeans returns the mean time error over consecutive windows of time-sorted points,
+// starting at the first point. Only complete windows are returned; the last window is complete
+// when its points span the window up to one sample interval.
+func windowMeans(points []pattern.MetricPoint, window time.Duration) []report.CTEWindow {
+	var windows []report.CTEWindow
+	start := points[0].Time
+	sum, count := 0.0, 0
+	for _, pt := range points {
+		for !pt.Time.Before(start.Add(window)) {
+			if count > 0 {
+				windows = append(windows, report.CTEWindow{StartTime: start, CTE: sum / float64(count)})
+			}
+			start = start.Add(window)
+			sum, count = 0, 0
+		}
+		sum += pt.Value
+		count++
+	}
+	last := points[len(points)-1].Time
+	interval := time.Duration(medianInterval(points) * float64(time.Second))
+	if count > 0 && !last.Add(interval).Before(start.Add(window)) {
+		windows = append(windows, report.CTEWindow{StartTime: start, CTE: sum / float64(count)})
+	}
+	return windows
+}
+
+// splitTE applies a first-order low-pass filter with the given corner frequency, returning the
+// low-pass output and the residual (high-pass) component. The filter accounts for irregular
+// sample spacing.
+func splitTE(points []pattern.MetricPoint, cutoffHz float64) ([]float64, []float64) {
+	rc := 1 / (2 * math.Pi * cutoffHz)
+	low := make([]float64, len(points))
+	high := make([]float64, len(points))
+
+	low[0] = points[0].Value
+	for i := 1; i < len(points); i++ {
+		dt := points[i].Time.Sub(points[i-1].Time).Seconds()
+		alpha := dt / (rc + dt)
+		low[i] = low[i-1] + alpha*(points[i].Value-low[i-1])
+		high[i] = points[i].Value - low[i]
+	}
+
+	return low, high
+}
+
+// peakToPeak returns the difference between the maximum and minimum value
+func peakToPeak(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	min, max := values[0], values[0]
+	for _, v := range values[1:] {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	return max - min
+}
@@ -0,0 +1,78 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// sine returns n samples of a sine of the frequency and amplitude at the sampling interval
+func sine(n int, interval time.Duration, hz, amplitude float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = amplitude * math.Sin(2*math.Pi*hz*float64(i)*interval.Seconds())
+	}
+	return values
+}
+
+func TestComputeTimeErrorCutoff(t *testing.T) {
+	// A slow wander (0.002 Hz) and fast noise (0.4 Hz) around a constant 20 ns, at 16 Hz: the
+	// default 0.1 Hz cutoff keeps the wander in dTE_L and the noise in dTE_H
+	const n = 16 * 1500
+	interval := time.Second / 16
+	slow, fast := sine(n, interval, 0.002, 30), sine(n, interval, 0.4, 5)
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = 20 + slow[i] + fast[i]
+	}
+	points := series(interval, values...)
+
+	tests := []struct {
+		name     string
+		cutoffHz float64
+		want     float64 // Cutoff reported
+		lowPkPk  [2]float64
+		highPkPk [2]float64
+	}{
+		{"default", 0, DefaultTECutoffHz, [2]float64{55, 65}, [2]float64{8, 13}},
+		{"above the noise", 10, 10, [2]float64{60, 71}, [2]float64{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := ComputeTimeError("offset", points, tt.cutoffHz, 0)
+			if err != nil {
+				t.Fatalf("ComputeTimeError: %v", err)
+			}
+			if stats.CutoffHz != tt.want {
+				t.Errorf("cutoff %g Hz, want %g Hz", stats.CutoffHz, tt.want)
+			}
+			if math.Abs(stats.ConstantTE-20) > 1 {
+				t.Errorf("cTE %g, want about 20", stats.ConstantTE)
+			}
+			if v := stats.DynamicTELPkPk; v < tt.lowPkPk[0] || v > tt.lowPkPk[1] {
+				t.Errorf("dTE_L pk-pk %g, want %g to %g", v, tt.lowPkPk[0], tt.lowPkPk[1])
+			}
+			if v := stats.DynamicTEHPkPk; v < tt.highPkPk[0] || v > tt.highPkPk[1] {
+				t.Errorf("dTE_H pk-pk %g, want %g to %g", v, tt.highPkPk[0], tt.highPkPk[1])
+			}
+		})
+	}
+}
+
+func TestComputeTimeErrorWindows(t *testing.T) {
+	// cTE is the largest window mean by magnitude; the partial last window is left out
+	points := series(time.Second, 1, 1, -5, -5, 2, 2, 100)
+	stats, err := ComputeTimeError("offset", points, 0, 2*time.Second)
+	if err != nil {
+		t.Fatalf("ComputeTimeError: %v", err)
+	}
+	if len(stats.CTEWindows) != 3 {
+		t.Fatalf("%d windows, want 3", len(stats.CTEWindows))
+	}
+	if stats.ConstantTE != -5 || stats.MaxAbsTE != 100 {
+		t.Errorf("cTE %g, max|TE| %g; want -5 and 100", stats.ConstantTE, stats.MaxAbsTE)
+	}
+	if _, err := ComputeTimeError("offset", nil, 0, 0); err == nil {
+		t.Error("ComputeTimeError without points succeeded, want an error")
+	}
+}
@@ -0,0 +1,193 @@
+// Package archive reads the log files of sosreport and must-gather archives without extracting
+// them, selecting the files by path globs that also name their tags
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule selects archive members by a path glob and names their tag. Glob segments are matched
+// with path.Match, and a "**" segment matches any number of directories. In the tag, $1, $2, ...
+// are replaced by the path segments matched by the glob's wildcard segments (those containing
+// *, ?, or [), in order; without a tag, members are tagged by their file name without extension.
+type Rule struct {
+	Glob string
+	Tag  string
+}
+
+// DefaultRules locate the logs relevant to PTP in sosreports (journal and syslog) and
+// must-gather archives (PTP operator pods, tagged by pod and container)
+var DefaultRules = []Rule{
+	{Glob: "**/sos_commands/logs/journalctl_--no-pager", Tag: "journal"},
+	{Glob: "**/var/log/messages", Tag: "messages"},
+	{Glob: "**/namespaces/openshift-ptp/pods/*/*/*/logs/current.log", Tag: "$1-$2"},
+	{Glob: "**/namespaces/openshift-ptp/pods/*/*/*/logs/previous.log", Tag: "$1-$2-previous"},
+}
+
+// ParseRule parses a rule written as glob or glob=tag
+func ParseRule(spec string) (Rule, error) {
+	glob, tag, _ := strings.Cut(spec, "=")
+	glob = strings.Trim(strings.TrimSpace(glob), "/")
+	if glob == "" {
+		return Rule{}, fmt.Errorf("invalid archive path '%s', expected glob or glob=tag", spec)
+	}
+	for _, segment := range strings.Split(glob, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return Rule{}, fmt.Errorf("invalid archive path glob '%s': %w", glob, err)
+		}
+	}
+	return Rule{Glob: glob, Tag: strings.TrimSpace(tag)}, nil
+}
+
+// File is a log file read from an archive
+type File struct {
+	Name    string // Path inside the archive
+	Tag     string
+	ModTime time.Time
+	Data    []byte
+}
+
+// IsArchive reports whether a path names a supported archive by its extension
+func IsArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.bz2", ".tbz2"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read returns the regular files of a .tar, .tar.gz, .tar.bz2, or .tar.xz archive matching the
+// first rule that applies to them. xz archives are decompressed with the xz command.
+func Read(ctx context.Context, name string, rules []Rule) ([]File, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive '%s': %w", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(lower, ".bz2") || strings.HasSuffix(lower, ".tbz2"):
+		r = bzip2.NewReader(file)
+	case strings.HasSuffix(lower, ".xz") || strings.HasSuffix(lower, ".txz"):
+		cmd := exec.CommandContext(ctx, "xz", "-dc")
+		cmd.Stdin = file
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress archive: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to decompress archive '%s' (the xz command is required): %w", name, err)
+		}
+		files, err := readTar(ctx, out, rules)
+		io.Copy(io.Discard, out)
+		if waitErr := cmd.Wait(); waitErr != nil && ctx.Err() == nil {
+			err = fmt.Errorf("failed to decompress archive '%s': %w: %s", name, waitErr, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return files, err
+	}
+	return readTar(ctx, r, rules)
+}
+
+// readTar reads the members of a tar stream matching the rules
+func readTar(ctx context.Context, r io.Reader, rules []Rule) ([]File, error) {
+	var files []File
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		tag, ok := match(rules, name)
+		if !ok {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s' from archive: %w", name, err)
+		}
+		files = append(files, File{Name: name, Tag: tag, ModTime: header.ModTime, Data: data})
+	}
+}
+
+// match returns the tag of the first rule matching a member path
+func match(rules []Rule, name string) (string, bool) {
+	segments := strings.Split(name, "/")
+	for _, rule := range rules {
+		captures, ok := matchSegments(strings.Split(rule.Glob, "/"), segments, nil)
+		if !ok {
+			continue
+		}
+		if rule.Tag == "" {
+			base := path.Base(name)
+			return strings.TrimSuffix(base, path.Ext(base)), true
+		}
+		tag := rule.Tag
+		// Replace higher numbers first, so $1 does not match the start of $10
+		for k := len(captures); k >= 1; k-- {
+			tag = strings.ReplaceAll(tag, "$"+strconv.Itoa(k), captures[k-1])
+		}
+		return tag, true
+	}
+	return "", false
+}
+
+// matchSegments matches path segments against glob segments, collecting the segments matched
+// by wildcard segments
+func matchSegments(globs, segments, captures []string) ([]string, bool) {
+	if len(globs) == 0 {
+		return captures, len(segments) == 0
+	}
+	if globs[0] == "**" {
+		for skip := 0; skip <= len(segments); skip++ {
+			if result, ok := matchSegments(globs[1:], segments[skip:], captures); ok {
+				return result, true
+			}
+		}
+		return nil, false
+	}
+	if len(segments) == 0 {
+		return nil, false
+	}
+	if ok, _ := path.Match(globs[0], segments[0]); !ok {
+		return nil, false
+	}
+	if strings.ContainsAny(globs[0], "*?[") {
+		captures = append(captures[:len(captures):len(captures)], segments[0])
+	}
+	return matchSegments(globs[1:], segments[1:], captures)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// ParseColor parses a plot color (e.g., "blue", "red", "#FF0000"), returning nil for colors the
+// plots do not support
+func ParseColor(colorStr string) color.Color {
+	colorStr = strings.ToLower(strings.TrimSpace(colorStr))
+
+	// Named colors
+	switch colorStr {
+	case "black":
+		return color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	case "white":
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	case "red":
+		return color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	case "green":
+		return color.RGBA{R: 0, G: 255, B: 0, A: 255}
+	case "blue":
+		return color.RGBA{R: 0, G: 0, B: 255, A: 255}
+	case "yellow":
+		return color.RGBA{R: 255, G: 255, B: 0, A: 255}
+	case "cyan":
+		return color.RGBA{R: 0, G: 255, B: 255, A: 255}
+	case "magenta":
+		return color.RGBA{R: 255, G: 0, B: 255, A: 255}
+	case "orange":
+		return color.RGBA{R: 255, G: 165, B: 0, A: 255}
+	case "purple":
+		return color.RGBA{R: 128, G: 0, B: 128, A: 255}
+	case "brown":
+		return color.RGBA{R: 165, G: 42, B: 42, A: 255}
+	case "pink":
+		return color.RGBA{R: 255, G: 192, B: 203, A: 255}
+	case "gray", "grey":
+		return color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	case "teal":
+		return color.RGBA{R: 0, G: 128, B: 128, A: 255}
+	}
+
+	// Try parsing as hex color (#RRGGBB)
+	if strings.HasPrefix(colorStr, "#") && len(colorStr) == 7 {
+		var r, g, b uint8
+		if _, err := fmt.Sscanf(colorStr, "#%02x%02x%02x", &r, &g, &b); err == nil {
+			return color.RGBA{R: r, G: g, B: b, A: 255}
+		}
+	}
+
+	return nil // Return nil if color cannot be parsed
+}
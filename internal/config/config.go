@@ -2,49 +2,361 @@ package config
 
 import (
 	"fmt"
+	"log-interleaver/pkg/pattern"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // PatternConfig defines a pattern for extracting metrics from log lines
 type PatternConfig struct {
-	Name         string            `yaml:"name"`          // Series name (e.g., "E830 offset")
-	Regex        string            `yaml:"regex"`        // Regex pattern to match
-	TagFilter    string            `yaml:"tag_filter"`   // Optional: filter by log tag (e.g., "e830", "daemon")
-	ValueGroup   int               `yaml:"value_group"`  // Regex capture group index for the value
-	StateGroup   int               `yaml:"state_group"`  // Optional: regex capture group for state (e.g., s0, s2)
-	StateMapping map[string]float64 `yaml:"state_mapping"` // Optional: map state strings to numeric values (e.g., {"s0": 10, "s1": 20})
-	Color        string            `yaml:"color"`         // Optional: matplotlib color
-	LineStyle    string            `yaml:"line_style"`   // Optional: matplotlib line style (e.g., "-", "--", ".")
-	Marker       string            `yaml:"marker"`       // Optional: matplotlib marker (e.g., ".", "o", "x")
-	Step         bool              `yaml:"step"`         // Optional: if true, use step plot (hold value between points)
-	YAxisLabel   string            `yaml:"yaxis_label"`  // Optional: Y-axis label for this series
-	YAxisIndex   int               `yaml:"yaxis_index"`  // Optional: which Y-axis to use (0=left, 1=right)
+	Name            string             `yaml:"name"`             // Series name (e.g., "E830 offset")
+	Type            string             `yaml:"type"`             // Optional: "metric" (default) or "event" (matches are drawn as labeled vertical lines, no value)
+	Regex           string             `yaml:"regex"`            // Regex pattern to match
+	TagFilter       string             `yaml:"tag_filter"`       // Optional: filter by log tag (e.g., "e830", "daemon")
+	Field           string             `yaml:"field"`            // Optional: match the regex against this field of JSON log lines (without regex: use the field value)
+	ValueGroup      int                `yaml:"value_group"`      // Regex capture group index for the value
+	StateGroup      int                `yaml:"state_group"`      // Optional: regex capture group for state (e.g., s0, s2)
+	StateMapping    map[string]float64 `yaml:"state_mapping"`    // Optional: map state strings to numeric values (e.g., {"s0": 10, "s1": 20}); states missing from it are discovered
+	Color           string             `yaml:"color"`            // Optional: matplotlib color
+	LineStyle       string             `yaml:"line_style"`       // Optional: matplotlib line style (e.g., "-", "--", ".")
+	Marker          string             `yaml:"marker"`           // Optional: matplotlib marker (e.g., ".", "o", "x")
+	MarkerSize      float64            `yaml:"marker_size"`      // Optional: marker diameter in points (default: by marker, 4–8)
+	LineWidth       float64            `yaml:"line_width"`       // Optional: line width in points, pixels in the HTML plot (default: 1 point, 2 pixels in the HTML plot)
+	Opacity         float64            `yaml:"opacity"`          // Optional: opacity of the markers and the line, above 0 up to 1 (default: 1)
+	Step            bool               `yaml:"step"`             // Optional: if true, use step plot (hold value between points)
+	YAxisLabel      string             `yaml:"yaxis_label"`      // Optional: Y-axis label for this series
+	YAxisIndex      int                `yaml:"yaxis_index"`      // Optional (deprecated, use axis): which Y-axis to use (0=left, 1=right)
+	Axis            string             `yaml:"axis"`             // Optional: name of the Y-axis (from the axes section) to plot on
+	Dedup           string             `yaml:"dedup"`            // Optional: collapse points at identical timestamps: "first", "last", or "mean"
+	Transform       string             `yaml:"transform"`        // Optional: convert values before plotting/exporting: "rate", "derivative", or "cumulative"
+	MinValue        *float64           `yaml:"min_value"`        // Optional: drop points below this value (with clamp: raise them to it)
+	MaxValue        *float64           `yaml:"max_value"`        // Optional: drop points above this value (with clamp: lower them to it)
+	OutlierSigma    float64            `yaml:"outlier_sigma"`    // Optional: drop points more than this many robust standard deviations from the median (with clamp: clamp them)
+	Clamp           bool               `yaml:"clamp"`            // Optional: clamp points outside min_value, max_value, and outlier_sigma instead of dropping them
+	Unit            string             `yaml:"unit"`             // Optional: unit of the extracted values: ps, ns, us, ms, s, ppb, or ppm
+	DisplayUnit     string             `yaml:"display_unit"`     // Optional: unit the values are converted to for plots and exports (default: unit)
+	MaxPoints       int                `yaml:"max_points"`       // Optional: target point count of the series in plots (overrides the global max_points, -1 = all points)
+	LabelGroup      int                `yaml:"label_group"`      // Optional: capture group appended to the label of event patterns (e.g., the new port state)
+	LaneGroup       int                `yaml:"lane_group"`       // Optional: capture group naming the lane of event patterns drawn as state lanes, with label_group as the state (e.g., the port)
+	Series          []SeriesConfig     `yaml:"series"`           // Optional: several series from the capture groups of one regex (replaces name and value_group)
+	Rolling         *RollingConfig     `yaml:"rolling"`          // Optional: overlay a moving average and a ±N·σ band
+	Band            *BandConfig        `yaml:"band"`             // Optional: shade a rolling percentile band (e.g., p5–p95) behind the series
+	LockedStates    []string           `yaml:"locked_states"`    // Optional: states of a state-mapped series that count as locked (default: s2, LOCKED, LOCKED_HO_ACQ)
+	HoldoverStates  []string           `yaml:"holdover_states"`  // Optional: states of a state-mapped series that count as holdover (default: states starting with HOLDOVER)
+	HoldoverPhase   string             `yaml:"holdover_phase"`   // Optional: series whose phase excursion is measured during holdover (default: the first te_report series)
+	MetricName      string             `yaml:"metric_name"`      // Optional: Prometheus metric name in the metric exports (default: the name in snake case; patterns may share one)
+	Plugin          string             `yaml:"plugin"`           // Optional: plugin (from the plugins section) extracting the values instead of regex or field
+	PluginSeries    string             `yaml:"plugin_series"`    // Optional: series name returned by the plugin (default: the pattern name)
+	Extractor       string             `yaml:"extractor"`        // Optional: built-in extractor of the values (e.g., "linuxptp_summary"); a regex only selects the lines
+	ExtractorSeries string             `yaml:"extractor_series"` // Optional: series of the built-in extractor (e.g., "rms"; default: the pattern name)
+	RollingStat     string             `yaml:"-"`                // Set on the derived series of a rolling overlay: "mean", "upper", or "lower"
+}
+
+// RollingConfig defines the rolling statistics overlaid on a series
+type RollingConfig struct {
+	Window time.Duration `yaml:"window"` // Trailing window of the moving average and standard deviation (e.g., "30s")
+	Sigma  float64       `yaml:"sigma"`  // Optional: width of the band in standard deviations (0 = no band)
+}
+
+// BandConfig defines a percentile band shaded behind a series, the percentiles computed over a
+// trailing window like the rolling statistics
+type BandConfig struct {
+	Window time.Duration `yaml:"window"` // Trailing window of the percentiles (e.g., "5m")
+	Low    float64       `yaml:"low"`    // Optional: lower percentile (default: 5)
+	High   float64       `yaml:"high"`   // Optional: upper percentile (default: 95)
+}
+
+// Percentiles returns the lower and upper percentile of the band, with their defaults
+func (b BandConfig) Percentiles() (low, high float64) {
+	low, high = b.Low, b.High
+	if low == 0 {
+		low = 5
+	}
+	if high == 0 {
+		high = 95
+	}
+	return low, high
+}
+
+// SeriesConfig defines one of several series extracted by a single pattern, each from its own
+// capture group. Unset fields are inherited from the pattern.
+type SeriesConfig struct {
+	Name         string             `yaml:"name"`          // Series name
+	ValueGroup   int                `yaml:"value_group"`   // Regex capture group index for the value
+	StateGroup   int                `yaml:"state_group"`   // Optional: regex capture group for state
+	StateMapping map[string]float64 `yaml:"state_mapping"` // Optional: map state strings to numeric values
+	Color        string             `yaml:"color"`         // Optional: series color
+	LineStyle    string             `yaml:"line_style"`    // Optional: series line style
+	Marker       string             `yaml:"marker"`        // Optional: series marker
+	MarkerSize   float64            `yaml:"marker_size"`   // Optional: series marker diameter in points
+	LineWidth    float64            `yaml:"line_width"`    // Optional: series line width in points
+	Opacity      float64            `yaml:"opacity"`       // Optional: series opacity
+	Step         bool               `yaml:"step"`          // Optional: if true, use step plot
+	YAxisLabel   string             `yaml:"yaxis_label"`   // Optional: Y-axis label for this series
+	YAxisIndex   int                `yaml:"yaxis_index"`   // Optional (deprecated, use axis): which Y-axis to use
+	Axis         string             `yaml:"axis"`          // Optional: name of the Y-axis to plot on
+	Dedup        string             `yaml:"dedup"`         // Optional: collapse points at identical timestamps
+	Transform    string             `yaml:"transform"`     // Optional: convert values before plotting/exporting
+	MinValue     *float64           `yaml:"min_value"`     // Optional: drop points below this value
+	MaxValue     *float64           `yaml:"max_value"`     // Optional: drop points above this value
+	OutlierSigma float64            `yaml:"outlier_sigma"` // Optional: drop points more than this many robust standard deviations from the median
+	Clamp        bool               `yaml:"clamp"`         // Optional: clamp points outside the bounds instead of dropping them
+	Unit         string             `yaml:"unit"`          // Optional: unit of the extracted values
+	DisplayUnit  string             `yaml:"display_unit"`  // Optional: unit the values are converted to
+	MaxPoints    int                `yaml:"max_points"`    // Optional: target point count of the series in plots
+	MetricName   string             `yaml:"metric_name"`   // Optional: Prometheus metric name in the metric exports
+}
+
+// IsEvent reports whether the pattern extracts discrete events rather than metric values
+func (p PatternConfig) IsEvent() bool {
+	return p.Type == "event"
+}
+
+// IsLane reports whether the pattern is an event pattern drawn as state lanes below the plot, one
+// lane per value of its lane group, in the states of its label group
+func (p PatternConfig) IsLane() bool {
+	return p.IsEvent() && p.LaneGroup > 0
+}
+
+// HasStates reports whether the pattern is a state-mapped series (e.g., a servo state), which
+// analyze reports a state transition timeline for
+func (p PatternConfig) HasStates() bool {
+	return p.StateGroup > 0 && (p.StateMapping != nil || p.DiscoversStates()) && !p.IsEvent() && p.RollingStat == ""
+}
+
+// DiscoversStates reports whether the states missing from the state_mapping of the pattern (all
+// states without one) are discovered from the data: whether it is a state series (see
+// pattern.IsStateSeries)
+func (p PatternConfig) DiscoversStates() bool {
+	return pattern.IsStateSeries(p.StateGroup, p.ValueGroup, p.IsEvent(), p.Extractor != "" || p.Plugin != "")
+}
+
+// TimestampFormatConfig declares a custom timestamp format for the lines of a log tag
+type TimestampFormatConfig struct {
+	Tag       string `yaml:"tag"`        // Log tag the format applies to (empty = all tags)
+	Layout    string `yaml:"layout"`     // Optional: Go time layout (e.g., "2006/01/02 15:04:05.000")
+	Regex     string `yaml:"regex"`      // Optional: regex locating the timestamp (capture group or named groups)
+	JSONField string `yaml:"json_field"` // Optional: timestamp field path of JSON log lines (e.g., "ts", "metadata.creationTime")
+	Plugin    string `yaml:"plugin"`     // Optional: plugin (from the plugins section) resolving the timestamps, before the built-in parsers
+}
+
+// CSVSourceConfig declares the CSV files of a tag (e.g., measurement exports of a GNSS simulator
+// or a TIE tester), whose rows are interleaved with the logs as structured lines with the columns
+// as fields
+type CSVSourceConfig struct {
+	Tag          string   `yaml:"tag"`           // Tag of the CSV files (e.g., "tie" for tie.csv)
+	TimeColumn   string   `yaml:"time_column"`   // Header of the time column
+	Layout       string   `yaml:"layout"`        // Optional: Go time layout of the times (default: RFC 3339, or epoch seconds)
+	Delimiter    string   `yaml:"delimiter"`     // Optional: column delimiter (default: ",")
+	ValueColumns []string `yaml:"value_columns"` // Optional: columns plotted as series named "<tag> <column>"
+}
+
+// PluginConfig declares an external program speaking JSON over stdin/stdout, which resolves
+// timestamps and extracts metrics of formats the built-in parsers and regexes cannot handle
+type PluginConfig struct {
+	Name    string   `yaml:"name"`    // Name referenced by timestamp formats and patterns
+	Command string   `yaml:"command"` // Executable (e.g., "/opt/vendor/log-plugin" or "python3")
+	Args    []string `yaml:"args"`    // Optional: arguments of the executable
+}
+
+// AxisConfig defines a named Y-axis that patterns can reference
+type AxisConfig struct {
+	Name  string `yaml:"name" json:"name"`   // Axis name referenced by patterns (e.g., "freq_ppb")
+	Label string `yaml:"label" json:"label"` // Optional: axis label
+	Side  string `yaml:"side" json:"side"`   // Optional: "left" (default) or "right"
+}
+
+// TagRuleConfig maps log file names to a tag
+type TagRuleConfig struct {
+	Glob  string `yaml:"glob"`  // Shell pattern matched against the file name (e.g., "ptp4l-eth0-*.log")
+	Regex string `yaml:"regex"` // Alternatively: regex matched against the whole file name
+	Tag   string `yaml:"tag"`   // Tag to assign; may reference regex capture groups ("$1")
+}
+
+// AnchorConfig declares lines of a tag that correspond to lines of a reference tag: the n-th
+// line of the tag matching the regex happened at the time of the n-th matching reference line
+type AnchorConfig struct {
+	Tag            string `yaml:"tag"`             // Tag whose timestamps are warped onto the reference
+	Regex          string `yaml:"regex"`           // Regex of the anchor lines of the tag
+	ReferenceTag   string `yaml:"reference_tag"`   // Tag of the reference lines (e.g., "daemon")
+	ReferenceRegex string `yaml:"reference_regex"` // Optional: regex of the reference lines (default: regex)
+}
+
+// DefaultTECutoffHz is the ITU-T G.8273.2 corner frequency separating dTE_L from dTE_H
+const DefaultTECutoffHz = 0.1
+
+// TEReportConfig selects the series included in the time error (G.8273.2) report
+type TEReportConfig struct {
+	Series    []string         `yaml:"series"`     // Pattern names of time error (offset) series
+	CutoffHz  float64          `yaml:"cutoff_hz"`  // Optional: dTE low/high-pass corner frequency (default: 0.1 Hz)
+	CTEWindow time.Duration    `yaml:"cte_window"` // Optional: report cTE as the largest mean over windows of this length (default: the whole measurement)
+	MTIEMasks []MTIEMaskConfig `yaml:"mtie_masks"` // Optional: custom MTIE masks, in addition to the built-in ones
+}
+
+// MTIEMaskConfig defines an MTIE mask as limits at increasing observation intervals; the limit
+// between two points is interpolated on log-log scales
+type MTIEMaskConfig struct {
+	Name   string                `yaml:"name"`
+	Points []MTIEMaskPointConfig `yaml:"points"`
+}
+
+// MTIEMaskPointConfig is one point of an MTIE mask
+type MTIEMaskPointConfig struct {
+	Tau     time.Duration `yaml:"tau"`      // Observation interval (e.g., "1s", "1000s")
+	LimitNs float64       `yaml:"limit_ns"` // MTIE limit in nanoseconds
+}
+
+// HeatmapConfig sets the buckets of the heatmap plot type, which counts the points of each series
+// in cells of a time bucket and a value bucket
+type HeatmapConfig struct {
+	TimeBins  int `yaml:"time_bins"`  // Optional: number of time buckets over the capture (default: 100)
+	ValueBins int `yaml:"value_bins"` // Optional: number of value buckets over the range of each series (default: 50)
+}
+
+// BoxConfig sets the windows of the box and violin plot types, which draw a box or violin plot of
+// the points of each series in each window of the capture
+type BoxConfig struct {
+	Window time.Duration `yaml:"window"` // Optional: length of the windows, from the start of the capture (default: 10m)
+}
+
+// LegendConfig places the legend of the plots of the series: the static plot types and the
+// interactive HTML plot. The MTIE, stability, spectrum, and comparison plots keep their legend in
+// the top-right corner.
+type LegendConfig struct {
+	Position string `yaml:"position" json:"position,omitempty"` // Optional: "top-left" (default), "top-right", "bottom-left", "bottom-right", "below" the plot, or "hidden"
+	Columns  int    `yaml:"columns" json:"columns,omitempty"`   // Optional: number of legend columns (default: 1, below the plot as many as fit)
+}
+
+// FontSizeConfig sets the font sizes of the plots of the series, in points in the static plot
+// types and in pixels in the interactive HTML plot. The MTIE, stability, spectrum, and comparison
+// plots keep the default sizes.
+type FontSizeConfig struct {
+	Title  float64 `yaml:"title" json:"title,omitempty"`   // Optional: plot title
+	Axis   float64 `yaml:"axis" json:"axis,omitempty"`     // Optional: axis labels and tick labels
+	Legend float64 `yaml:"legend" json:"legend,omitempty"` // Optional: legend entries
+}
+
+// SeverityConfig classifies log lines by severity for the error burst analysis
+type SeverityConfig struct {
+	Name  string `yaml:"name"`  // Severity name (e.g., "error", "warning")
+	Regex string `yaml:"regex"` // Lines matching this regex have the severity
+}
+
+// AssertionConfig is a threshold assertion evaluated by the check command
+type AssertionConfig struct {
+	Name   string `yaml:"name"`   // Optional: name in the results (default: the expression)
+	Series string `yaml:"series"` // Optional: series of expressions without one (e.g., "state != HOLDOVER for > 5s")
+	Expr   string `yaml:"expr"`   // Assertion, e.g. "max_abs(E810 offset) < 100" or "state(servo) != HOLDOVER for > 5s"
+}
+
+// assertionCall matches the function call of an assertion expression, with the series in group 1
+var assertionCall = regexp.MustCompile(`^\s*\w+\s*\((.*)\)\s*(?:<=|>=|==|!=|<|>)`)
+
+// AssertedSeries returns the series the assertion checks: the one of the expression, else Series.
+// fromExpr reports whether it is the one of the expression.
+func (a AssertionConfig) AssertedSeries() (series string, fromExpr bool) {
+	if m := assertionCall.FindStringSubmatch(a.Expr); m != nil {
+		return strings.TrimSpace(m[1]), true
+	}
+	return a.Series, false
 }
 
 // VisualizationConfig contains all pattern configurations
 type VisualizationConfig struct {
-	Title      string          `yaml:"title"`
-	XAxisLabel string          `yaml:"xaxis_label"`
-	YAxisLabel string          `yaml:"yaxis_label"`
-	Width      int             `yaml:"width"`
-	Height     int             `yaml:"height"`
-	DPI        int             `yaml:"dpi"`
-	Patterns   []PatternConfig `yaml:"patterns"`
+	Title            string                  `yaml:"title"`
+	XAxisLabel       string                  `yaml:"xaxis_label"`
+	YAxisLabel       string                  `yaml:"yaxis_label"`
+	XTickSpacing     time.Duration           `yaml:"x_tick_spacing"`  // Time between the X-axis ticks (default: automatic)
+	XTickFormat      string                  `yaml:"x_tick_format"`   // X tick labels: "seconds" since the start (default), "duration" since the start (e.g., 1h30m), or "clock" (time of day, UTC)
+	XTickRotation    float64                 `yaml:"x_tick_rotation"` // Counter-clockwise rotation of the X tick labels in degrees, for long labels
+	Width            int                     `yaml:"width"`           // Plot width in inches (default: 12); the HTML plot is at most width × dpi pixels wide
+	Height           int                     `yaml:"height"`          // Plot height in inches (default: 8)
+	DPI              int                     `yaml:"dpi"`             // Resolution of raster plots and of the HTML plot size (default: 100)
+	HTMLHeight       int                     `yaml:"html_height"`     // Height of the interactive HTML plot in pixels (default: height × dpi)
+	MaxPoints        int                     `yaml:"max_points"`      // Target point count per series in plots and HTML, downsampled with LTTB (default: 5000, -1 = all points)
+	Plugins          []PluginConfig          `yaml:"plugins"`         // External timestamp parsers and metric extractors
+	TimestampFormats []TimestampFormatConfig `yaml:"timestamp_formats"`
+	TagRules         []TagRuleConfig         `yaml:"tag_rules"`
+	CSVSources       []CSVSourceConfig       `yaml:"csv_sources"`  // Tags whose files are timestamped CSV files
+	TagColors        map[string]string       `yaml:"tag_colors"`   // Terminal colors per tag (name like "cyan" or an ANSI SGR code like "38;5;208")
+	TagPriority      []string                `yaml:"tag_priority"` // Order of tags for lines with equal timestamps
+	Timezones        map[string]string       `yaml:"timezones"`    // IANA timezone of wall-clock timestamps per tag (e.g., "America/New_York")
+	GNSSTags         []string                `yaml:"gnss_tags"`    // Tags of GNSS time source logs (NMEA or gpsd JSON), anchored to the GNSS time of their sentences
+	Anchors          []AnchorConfig          `yaml:"anchors"`      // Lines of tags corresponding to lines of a reference tag, warping the tags piecewise-linearly between them
+	Axes             []AxisConfig            `yaml:"axes"`
+	Subplots         bool                    `yaml:"subplots"`       // Render each Y-axis as its own panel, stacked with a shared X axis
+	StateTimeline    bool                    `yaml:"state_timeline"` // Draw a lane with the state timeline of the state-mapped series below the plots
+	GapThreshold     time.Duration           `yaml:"gap_threshold"`  // Shade periods longer than this without lines from a tag on the plots (also the default of analyze -gap-threshold)
+	Severities       []SeverityConfig        `yaml:"severities"`     // Severity regexes of analyze error bursts, tried in order (default: error and warning)
+	Theme            string                  `yaml:"theme"`          // Color theme of the interactive HTML export: "light" (default) or "dark"
+	PlotType         string                  `yaml:"plot_type"`      // Static plot type: "line" (default, the points over time), "heatmap" (the distribution of the values over time), "box" (box plots of time windows), or "violin" (violin plots of time windows)
+	Heatmap          HeatmapConfig           `yaml:"heatmap"`        // Buckets of the heatmap plot type
+	Box              BoxConfig               `yaml:"box"`            // Windows of the box and violin plot types
+	Legend           LegendConfig            `yaml:"legend"`         // Placement of the legend
+	FontSizes        FontSizeConfig          `yaml:"font_sizes"`     // Font sizes of the title, axes, and legend
+	Patterns         []PatternConfig         `yaml:"patterns"`
+	TEReport         *TEReportConfig         `yaml:"te_report"`
+	Assertions       []AssertionConfig       `yaml:"assertions"` // Threshold assertions of the check command
+	Plots            []PlotConfig            `yaml:"plots"`      // Plots generated by one run of the plot command instead of a plot of all series
+
+	logDir string // Log directory or archive of the run, for {{.LogDir}} (see Options)
 }
 
-// LoadConfig loads visualization configuration from a YAML file
-func LoadConfig(configPath string) (*VisualizationConfig, error) {
+// Options are the settings of a config given besides its file, such as by command line flags
+type Options struct {
+	Presets   Presets   // Presets added after the patterns of the config (-preset)
+	Overrides Overrides // Values set in the config before it is decoded (-set)
+	LogDir    string    // Log directory or archive read, for {{.LogDir}} in the texts (-logs)
+}
+
+// PlotConfig defines one of several plots of the plot command, each of a subset of the series
+type PlotConfig struct {
+	Title    string       `yaml:"title"`    // Optional: title of the plot (default: the title of the config)
+	Patterns []string     `yaml:"patterns"` // Optional: names of the series in the plot, including event series (default: all)
+	Axes     []AxisConfig `yaml:"axes"`     // Optional: Y-axes of the plot, replacing the axes of the config
+	Output   string       `yaml:"output"`   // Optional: output location of the plot (default: the plot command's -output with the plot number)
+
+	config *VisualizationConfig // Config of the plot, resolved with the config
+}
+
+// Config returns the config of the plot: the config with the title, series, and axes of the plot
+func (p *PlotConfig) Config() *VisualizationConfig {
+	return p.config
+}
+
+// LoadConfig loads visualization configuration from a YAML file, which may be missing when
+// presets are selected
+func LoadConfig(configPath string, opts Options) (*VisualizationConfig, error) {
 	data, err := os.ReadFile(configPath)
-	if err != nil {
+	if err != nil && !(os.IsNotExist(err) && len(opts.Presets) > 0) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	return ParseConfig(configPath, data, opts)
+}
 
-	var config VisualizationConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+// ParseConfig parses visualization configuration from YAML data with the overrides and presets
+// of the options. The name identifies the data in validation errors, like the path of a config
+// file.
+func ParseConfig(configPath string, data []byte, opts Options) (*VisualizationConfig, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := expandEnv(&doc); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	if err := applyOverrides(&doc, opts.Overrides); err != nil {
+		return nil, err
+	}
+	config := VisualizationConfig{logDir: opts.LogDir}
+	if err := decodeConfig(configPath, &doc, &config, opts.Presets); err != nil {
+		return nil, err
+	}
 
 	// Set defaults
 	if config.Title == "" {
@@ -65,6 +377,290 @@ func LoadConfig(configPath string) (*VisualizationConfig, error) {
 	if config.DPI == 0 {
 		config.DPI = 100
 	}
+	if config.MaxPoints == 0 {
+		config.MaxPoints = 5000
+	}
+	if config.Heatmap.TimeBins == 0 {
+		config.Heatmap.TimeBins = 100
+	}
+	if config.Heatmap.ValueBins == 0 {
+		config.Heatmap.ValueBins = 50
+	}
+	if config.Box.Window == 0 {
+		config.Box.Window = 10 * time.Minute
+	}
+
+	config.Patterns = expandSeries(append(config.Patterns, csvPatterns(&config)...))
+	for i := range config.Patterns {
+		// Set before the rolling overlays, whose derived series select the same plugin points
+		if p := &config.Patterns[i]; p.Plugin != "" && p.PluginSeries == "" {
+			p.PluginSeries = p.Name
+		}
+		if p := &config.Patterns[i]; p.Extractor != "" && p.ExtractorSeries == "" {
+			p.ExtractorSeries = p.Name
+		}
+		if p := &config.Patterns[i]; p.DisplayUnit == "" {
+			p.DisplayUnit = p.Unit
+		}
+	}
+	// The plots take their series before the rolling overlays are added and the axes resolved
+	base := config
+	base.Patterns = slices.Clone(config.Patterns)
+	base.Axes = slices.Clone(config.Axes)
+	base.Plots = nil
+
+	config.Patterns = expandRolling(config.Patterns)
+	resolveAxes(&config)
+	labelUnits(&config)
+
+	for i := range config.Plots {
+		config.Plots[i].config = base.plotConfig(config.Plots[i])
+	}
 
+	if config.TEReport != nil && config.TEReport.CutoffHz == 0 {
+		config.TEReport.CutoffHz = DefaultTECutoffHz
+	}
 	return &config, nil
 }
+
+// plotConfig returns the config of a plot from the config before the rolling overlays and axes:
+// its series with their rolling overlays on its axes
+func (c VisualizationConfig) plotConfig(plot PlotConfig) *VisualizationConfig {
+	if plot.Title != "" {
+		c.Title = plot.Title
+	}
+	if plot.Axes != nil {
+		c.Axes = slices.Clone(plot.Axes)
+	} else {
+		c.Axes = slices.Clone(c.Axes)
+	}
+	if len(plot.Patterns) > 0 {
+		var patterns []PatternConfig
+		for _, name := range plot.Patterns {
+			// Unknown series are reported by checkValues
+			if k := slices.IndexFunc(c.Patterns, func(p PatternConfig) bool { return p.Name == name }); k >= 0 {
+				patterns = append(patterns, c.Patterns[k])
+			}
+		}
+		c.Patterns = patterns
+	} else {
+		c.Patterns = slices.Clone(c.Patterns)
+	}
+
+	c.Patterns = expandRolling(c.Patterns)
+	resolveAxes(&c)
+	labelUnits(&c)
+	return &c
+}
+
+// csvPatterns returns the patterns of the value columns of the CSV sources, except those named
+// like a pattern of the config, which then defines the series
+func csvPatterns(config *VisualizationConfig) []PatternConfig {
+	defined := make(map[string]bool, len(config.Patterns))
+	for _, p := range config.Patterns {
+		defined[p.Name] = true
+	}
+	var patterns []PatternConfig
+	for _, source := range config.CSVSources {
+		for _, column := range source.ValueColumns {
+			name := source.Tag + " " + column
+			if !defined[name] {
+				patterns = append(patterns, PatternConfig{Name: name, TagFilter: source.Tag, Field: column})
+			}
+		}
+	}
+	return patterns
+}
+
+// expandSeries replaces each pattern with multiple series by one pattern per series, sharing
+// the regex (which the pattern matcher evaluates once per line for all of them)
+func expandSeries(patterns []PatternConfig) []PatternConfig {
+	var expanded []PatternConfig
+	for _, p := range patterns {
+		if len(p.Series) == 0 {
+			expanded = append(expanded, p)
+			continue
+		}
+		for _, series := range p.Series {
+			sp := p
+			sp.Series = nil
+			sp.Name = series.Name
+			sp.ValueGroup = series.ValueGroup
+			if series.StateGroup != 0 {
+				sp.StateGroup = series.StateGroup
+			}
+			if series.StateMapping != nil {
+				sp.StateMapping = series.StateMapping
+			}
+			if series.Color != "" {
+				sp.Color = series.Color
+			}
+			if series.LineStyle != "" {
+				sp.LineStyle = series.LineStyle
+			}
+			if series.Marker != "" {
+				sp.Marker = series.Marker
+			}
+			if series.MarkerSize != 0 {
+				sp.MarkerSize = series.MarkerSize
+			}
+			if series.LineWidth != 0 {
+				sp.LineWidth = series.LineWidth
+			}
+			if series.Opacity != 0 {
+				sp.Opacity = series.Opacity
+			}
+			sp.Step = sp.Step || series.Step
+			if series.YAxisLabel != "" {
+				sp.YAxisLabel = series.YAxisLabel
+			}
+			if series.YAxisIndex != 0 {
+				sp.YAxisIndex = series.YAxisIndex
+			}
+			if series.Axis != "" {
+				sp.Axis = series.Axis
+			}
+			if series.Dedup != "" {
+				sp.Dedup = series.Dedup
+			}
+			if series.Transform != "" {
+				sp.Transform = series.Transform
+			}
+			if series.MinValue != nil {
+				sp.MinValue = series.MinValue
+			}
+			if series.MaxValue != nil {
+				sp.MaxValue = series.MaxValue
+			}
+			if series.OutlierSigma != 0 {
+				sp.OutlierSigma = series.OutlierSigma
+			}
+			sp.Clamp = sp.Clamp || series.Clamp
+			if series.Unit != "" {
+				sp.Unit = series.Unit
+			}
+			if series.DisplayUnit != "" {
+				sp.DisplayUnit = series.DisplayUnit
+			}
+			if series.MaxPoints != 0 {
+				sp.MaxPoints = series.MaxPoints
+			}
+			if series.MetricName != "" {
+				sp.MetricName = series.MetricName
+			}
+			expanded = append(expanded, sp)
+		}
+	}
+	return expanded
+}
+
+// expandRolling adds the derived series of rolling overlays after their source series: the
+// moving average and, with a sigma, the upper and lower band
+func expandRolling(patterns []PatternConfig) []PatternConfig {
+	var expanded []PatternConfig
+	for _, p := range patterns {
+		expanded = append(expanded, p)
+		if p.Rolling == nil {
+			continue
+		}
+
+		derived := func(name, stat, lineStyle string) PatternConfig {
+			dp := p
+			dp.Name = name
+			dp.RollingStat = stat
+			dp.Band = nil
+			dp.LineStyle = lineStyle
+			dp.Marker = ""
+			dp.MarkerSize = 0
+			// The overlays stay opaque over a translucent series
+			dp.Opacity = 0
+			dp.Step = false
+			return dp
+		}
+		expanded = append(expanded, derived(fmt.Sprintf("%s %v mean", p.Name, p.Rolling.Window), "mean", "-"))
+		if p.Rolling.Sigma > 0 {
+			expanded = append(expanded,
+				derived(fmt.Sprintf("%s +%gσ", p.Name, p.Rolling.Sigma), "upper", ":"),
+				derived(fmt.Sprintf("%s -%gσ", p.Name, p.Rolling.Sigma), "lower", ":"))
+		}
+	}
+	return expanded
+}
+
+// resolveAxes resolves each pattern's axis reference to an axis index, defaulting the sides of
+// the axes; the references are checked by checkValues
+func resolveAxes(config *VisualizationConfig) {
+	if len(config.Axes) == 0 {
+		return
+	}
+
+	axisIndex := make(map[string]int, len(config.Axes))
+	for i := range config.Axes {
+		if config.Axes[i].Side == "" {
+			config.Axes[i].Side = "left"
+		}
+		axisIndex[config.Axes[i].Name] = i
+	}
+
+	for i := range config.Patterns {
+		p := &config.Patterns[i]
+		if p.Axis == "" && p.YAxisIndex >= 0 && p.YAxisIndex < len(config.Axes) {
+			p.Axis = config.Axes[p.YAxisIndex].Name
+		}
+		idx, ok := axisIndex[p.Axis]
+		if !ok {
+			continue
+		}
+		p.YAxisIndex = idx
+		if p.YAxisLabel == "" {
+			p.YAxisLabel = config.Axes[idx].Label
+		}
+	}
+}
+
+// labelUnits appends the display units to the Y-axis labels: to the label of each series with a
+// unit (the global label if it has none), and to the labels of the axes, or the global label
+// without axes, whose series all share a unit. Labels already naming the unit are kept.
+func labelUnits(config *VisualizationConfig) {
+	withUnit := func(label, unit string) string {
+		if unit == "" || strings.Contains(label, "("+unit+")") {
+			return label
+		}
+		return label + " (" + unit + ")"
+	}
+	// sharedUnit returns the display unit of the value series on an axis (-1 = all axes), or ""
+	// if they have different or no units
+	sharedUnit := func(axis int) string {
+		unit := ""
+		for _, p := range config.Patterns {
+			if p.IsEvent() || (axis >= 0 && p.YAxisIndex != axis) {
+				continue
+			}
+			if p.DisplayUnit == "" || (unit != "" && p.DisplayUnit != unit) {
+				return ""
+			}
+			unit = p.DisplayUnit
+		}
+		return unit
+	}
+
+	for i := range config.Patterns {
+		p := &config.Patterns[i]
+		if p.IsEvent() || p.DisplayUnit == "" {
+			continue
+		}
+		if p.YAxisLabel == "" {
+			p.YAxisLabel = config.YAxisLabel
+		}
+		p.YAxisLabel = withUnit(p.YAxisLabel, p.DisplayUnit)
+	}
+	if len(config.Axes) == 0 {
+		config.YAxisLabel = withUnit(config.YAxisLabel, sharedUnit(-1))
+		return
+	}
+	for i := range config.Axes {
+		if config.Axes[i].Label != "" {
+			config.Axes[i].Label = withUnit(config.Axes[i].Label, sharedUnit(i))
+		}
+	}
+}
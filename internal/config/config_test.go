@@ -0,0 +1,95 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseConfigOptions(t *testing.T) {
+	const data = `
+patterns:
+  - name: offset
+    regex: 'offset (\d+)'
+    value_group: 1
+`
+	var opts Options
+	for _, spec := range []string{"title=Run of {{.LogDir}}", "patterns[0].color=red"} {
+		if err := opts.Overrides.Add(spec); err != nil {
+			t.Fatalf("Overrides.Add(%q): %v", spec, err)
+		}
+	}
+	if err := opts.Presets.Add("ptp4l:e810"); err != nil {
+		t.Fatalf("Presets.Add: %v", err)
+	}
+	opts.LogDir = "/var/log/run1"
+
+	cfg, err := ParseConfig("test.yaml", []byte(data), opts)
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	if got := cfg.Patterns[0].Color; got != "red" {
+		t.Errorf("color = %q, want the override red", got)
+	}
+	if !slices.ContainsFunc(cfg.Patterns, func(p PatternConfig) bool { return p.Name == "e810 ptp4l offset" }) {
+		t.Errorf("no series 'e810 ptp4l offset' of the preset")
+	}
+	expanded, err := cfg.ExpandTemplates(cfg.RunMetadata(time.Time{}, time.Time{}))
+	if err != nil {
+		t.Fatalf("ExpandTemplates: %v", err)
+	}
+	if expanded.Title != "Run of /var/log/run1" {
+		t.Errorf("title = %q, want the log directory of the options", expanded.Title)
+	}
+
+	// The options of one config do not carry over to the next
+	cfg, err = ParseConfig("test.yaml", []byte(data), Options{})
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	if len(cfg.Patterns) != 1 || cfg.Patterns[0].Color != "" || cfg.Title != "PTP Log Analysis" {
+		t.Errorf("config without options = %d patterns, color %q, title %q; want 1, \"\", the default", len(cfg.Patterns), cfg.Patterns[0].Color, cfg.Title)
+	}
+}
+
+func TestExpandSeries(t *testing.T) {
+	patterns := []PatternConfig{
+		{Name: "offset", Regex: `offset (\d+)`, ValueGroup: 1},
+		{
+			Regex:     `rms (\d+) max (\d+) freq (\S+)`,
+			TagFilter: "daemon",
+			Color:     "blue",
+			Axis:      "ns",
+			Series: []SeriesConfig{
+				{Name: "rms", ValueGroup: 1},
+				{Name: "max", ValueGroup: 2, Color: "red"},
+				{Name: "freq", ValueGroup: 3, Axis: "ppb", Step: true},
+			},
+		},
+	}
+	expanded := expandSeries(patterns)
+	want := []struct {
+		name       string
+		valueGroup int
+		color      string
+		axis       string
+		step       bool
+	}{
+		{"offset", 1, "", "", false},
+		{"rms", 1, "blue", "ns", false},
+		{"max", 2, "red", "ns", false},
+		{"freq", 3, "blue", "ppb", true},
+	}
+	if len(expanded) != len(want) {
+		t.Fatalf("%d patterns, want %d", len(expanded), len(want))
+	}
+	for i, w := range want {
+		p := expanded[i]
+		if p.Name != w.name || p.ValueGroup != w.valueGroup || p.Color != w.color || p.Axis != w.axis || p.Step != w.step {
+			t.Errorf("pattern %d = %s group %d color %q axis %q step %v, want %+v", i, p.Name, p.ValueGroup, p.Color, p.Axis, p.Step, w)
+		}
+		if p.Regex != patterns[min(i, 1)].Regex || p.Series != nil {
+			t.Errorf("pattern %d: regex %q, series %v; want the regex of its pattern and no series", i, p.Regex, p.Series)
+		}
+	}
+}
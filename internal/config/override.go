@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envVarRegex matches ${VAR} and ${VAR:-default} references, and $${ escaping a literal ${
+var envVarRegex = regexp.MustCompile(`\$(\$)?\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// Overrides are values set in a config before it is decoded, in order
+type Overrides []override
+
+// override sets the value at a path of the config, such as patterns[0].color=red
+type override struct {
+	spec  string
+	path  []pathElement
+	value *yaml.Node
+}
+
+// pathElement is a mapping key, or a sequence index when key is empty
+type pathElement struct {
+	key   string
+	index int
+}
+
+// Add parses a path=value override and adds it after those already added, as the -set flags
+// do. The path is a dot-separated list of keys with [n] sequence indexes (e.g.,
+// patterns[0].tag_filter), and the value is YAML (e.g., 5, true, or [a, b]).
+func (o *Overrides) Add(spec string) error {
+	pathSpec, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		return fmt.Errorf("invalid override '%s', expected path=value (e.g., patterns[0].color=red)", spec)
+	}
+	path, err := parsePath(strings.TrimSpace(pathSpec))
+	if err != nil {
+		return fmt.Errorf("invalid override '%s': %w", spec, err)
+	}
+
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+	if value != "" {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+			return fmt.Errorf("invalid override '%s': %w", spec, err)
+		}
+		if len(doc.Content) > 0 {
+			node = doc.Content[0]
+		}
+	}
+	*o = append(*o, override{spec: spec, path: path, value: node})
+	return nil
+}
+
+// parsePath parses the path of an override
+func parsePath(spec string) ([]pathElement, error) {
+	var path []pathElement
+	for _, part := range strings.Split(spec, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" {
+			return nil, fmt.Errorf("empty key in path '%s'", spec)
+		}
+		path = append(path, pathElement{key: key})
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(index)
+			if !ok || err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid index '[%s' in path '%s', expected [n]", rest, spec)
+			}
+			path = append(path, pathElement{index: n})
+			if rest = after; rest != "" {
+				if rest, ok = strings.CutPrefix(rest, "["); !ok {
+					return nil, fmt.Errorf("invalid path '%s', expected . or [ after ]", spec)
+				}
+			}
+		}
+	}
+	return path, nil
+}
+
+// applyOverrides sets the overridden values in the document of a config file
+func applyOverrides(doc *yaml.Node, overrides Overrides) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+	if doc.Kind == 0 {
+		// Empty file
+		*doc = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(doc.Content) == 0 || (doc.Content[0].Kind == yaml.ScalarNode && doc.Content[0].Tag == "!!null") {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	for _, o := range overrides {
+		if err := setPath(doc.Content[0], o.path, o.value); err != nil {
+			return fmt.Errorf("failed to apply override '%s': %w", o.spec, err)
+		}
+	}
+	return nil
+}
+
+// setPath sets the value at the path below a node, adding missing mapping keys
+func setPath(node *yaml.Node, path []pathElement, value *yaml.Node) error {
+	elem := path[0]
+	var child **yaml.Node
+	if elem.key == "" {
+		if node.Kind != yaml.SequenceNode {
+			return fmt.Errorf("[%d] applied to a value that is not a list", elem.index)
+		}
+		if elem.index >= len(node.Content) {
+			return fmt.Errorf("index [%d] out of range, the list has %d entries", elem.index, len(node.Content))
+		}
+		child = &node.Content[elem.index]
+	} else {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("key '%s' applied to a value that is not a mapping", elem.key)
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == elem.key {
+				child = &node.Content[i+1]
+				break
+			}
+		}
+		if child == nil {
+			next := value
+			if len(path) > 1 {
+				if path[1].key == "" {
+					return fmt.Errorf("key '%s' not found, so [%d] has no list to index", elem.key, path[1].index)
+				}
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: elem.key}, next)
+			child = &node.Content[len(node.Content)-1]
+		}
+	}
+
+	if len(path) == 1 {
+		*child = value
+		return nil
+	}
+	return setPath(*child, path[1:], value)
+}
+
+// expandEnv replaces the ${VAR} and ${VAR:-default} references in the scalar values of a config
+// document with the environment variables. Unset variables without a default are an error.
+func expandEnv(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${") {
+		var missing []string
+		value := envVarRegex.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			m := envVarRegex.FindStringSubmatch(ref)
+			if m[1] != "" {
+				return ref[1:]
+			}
+			// As in the shell, the default also replaces an empty value
+			if value, ok := os.LookupEnv(m[2]); ok && (value != "" || m[3] == "") {
+				return value
+			}
+			if m[3] != "" {
+				return m[3][2:]
+			}
+			missing = append(missing, m[2])
+			return ref
+		})
+		if len(missing) > 0 {
+			return fmt.Errorf("line %d: environment variable '%s' is not set (use ${%s:-default} for a default)", node.Line, missing[0], missing[0])
+		}
+		if value != node.Value && node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			// Resolve the type of plain values again, so ${PORT} can be a number
+			node.Tag = ""
+		}
+		node.Value = value
+	}
+	for _, child := range node.Content {
+		if err := expandEnv(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []pathElement
+	}{
+		{"theme", []pathElement{{key: "theme"}}},
+		{"legend.position", []pathElement{{key: "legend"}, {key: "position"}}},
+		{"patterns[0].tag_filter", []pathElement{{key: "patterns"}, {index: 0}, {key: "tag_filter"}}},
+		{"plots[2].axes[1][3]", []pathElement{{key: "plots"}, {index: 2}, {key: "axes"}, {index: 1}, {index: 3}}},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.spec)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePath(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestParsePathInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"legend.",
+		".theme",
+		"[0].color",
+		"patterns[x].color",
+		"patterns[-1].color",
+		"patterns[0",
+		"patterns[0]color",
+	} {
+		if got, err := parsePath(spec); err == nil {
+			t.Errorf("parsePath(%q) = %+v, want an error", spec, got)
+		}
+	}
+}
+
+func TestOverridesAddInvalid(t *testing.T) {
+	var overrides Overrides
+	for _, spec := range []string{"theme", "patterns[x].color=red", "theme=[dark"} {
+		if err := overrides.Add(spec); err == nil {
+			t.Errorf("Add(%q) succeeded, want an error", spec)
+		}
+	}
+	if len(overrides) != 0 {
+		t.Errorf("%d overrides added by invalid specs", len(overrides))
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	const doc = `
+patterns:
+  - name: offset
+    color: blue
+legend:
+  position: top-left
+`
+	tests := []struct {
+		path  string
+		value string
+		want  string // A line of the resulting YAML
+	}{
+		{"patterns[0].color", "red", "      color: red"},
+		{"legend.position", "below", "    position: below"},
+		{"legend.columns", "2", "    columns: 2"},
+		{"plot.width", "800", "plot:\n    width: 800"},
+		{"patterns[0].tags", "[a, b]", "      tags: [a, b]"},
+	}
+	for _, tt := range tests {
+		var root yaml.Node
+		if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+			t.Fatal(err)
+		}
+		path, err := parsePath(tt.path)
+		if err != nil {
+			t.Fatalf("parsePath(%q): %v", tt.path, err)
+		}
+		var value yaml.Node
+		if err := yaml.Unmarshal([]byte(tt.value), &value); err != nil {
+			t.Fatal(err)
+		}
+		if err := setPath(root.Content[0], path, value.Content[0]); err != nil {
+			t.Errorf("setPath(%s): %v", tt.path, err)
+			continue
+		}
+		out, err := yaml.Marshal(&root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), tt.want+"\n") {
+			t.Errorf("setPath(%s=%s) gave\n%s\nwant a line %q", tt.path, tt.value, out, tt.want)
+		}
+	}
+}
+
+func TestSetPathInvalid(t *testing.T) {
+	const doc = `
+patterns:
+  - name: offset
+theme: dark
+`
+	for _, spec := range []string{"patterns[1].color", "theme.name", "theme[0]", "patterns.color", "axes[0].name"} {
+		var root yaml.Node
+		if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+			t.Fatal(err)
+		}
+		path, err := parsePath(spec)
+		if err != nil {
+			t.Fatalf("parsePath(%q): %v", spec, err)
+		}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "red"}
+		if err := setPath(root.Content[0], path, value); err == nil {
+			t.Errorf("setPath(%s) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("LI_PORT", "8080")
+	t.Setenv("LI_EMPTY", "")
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"${LI_PORT}", "8080", false},
+		{"http://localhost:${LI_PORT}/push", "http://localhost:8080/push", false},
+		{"${LI_UNSET:-dark}", "dark", false},
+		{"${LI_EMPTY:-dark}", "dark", false},
+		{"${LI_EMPTY}", "", false},
+		{"$${LI_PORT}", "${LI_PORT}", false},
+		{"${LI_UNSET}", "", true},
+	}
+	for _, tt := range tests {
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tt.value}
+		err := expandEnv(node)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && node.Value != tt.want) {
+			t.Errorf("expandEnv(%q) = %q, %v; want %q, error %v", tt.value, node.Value, err, tt.want, tt.wantErr)
+		}
+	}
+}
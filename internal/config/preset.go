@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// presetFiles are the bundled pattern presets, one file per daemon
+//
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+// Presets is a selection of presets, added to a config in order
+type Presets []presetRef
+
+// presetRef selects a preset, optionally limited to the lines of one tag
+type presetRef struct {
+	name string
+	tag  string
+}
+
+// presetConfig is the content of a preset file: the axes and patterns added to the config
+type presetConfig struct {
+	Axes     []AxisConfig    `yaml:"axes"`
+	Patterns []PatternConfig `yaml:"patterns"`
+}
+
+// PresetNames returns the names of the bundled presets
+func PresetNames() []string {
+	entries, _ := presetFiles.ReadDir("presets")
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+	return names
+}
+
+// ParsePresets parses comma-separated presets. A preset may be limited to one tag as name:tag
+// (e.g., ptp4l:e810), which also prefixes its series names with the tag, so the same preset can
+// be added for several tags.
+func ParsePresets(list string) (Presets, error) {
+	var parsed Presets
+	for _, spec := range SplitList(list) {
+		name, tag, _ := strings.Cut(spec, ":")
+		if !slices.Contains(PresetNames(), name) {
+			return nil, fmt.Errorf("invalid preset '%s', expected %s (optionally as name:tag)", name, strings.Join(PresetNames(), ", "))
+		}
+		parsed = append(parsed, presetRef{name: name, tag: tag})
+	}
+	return parsed, nil
+}
+
+// Add selects comma-separated presets (see ParsePresets) after those already selected, as the
+// -preset flags do
+func (p *Presets) Add(list string) error {
+	parsed, err := ParsePresets(list)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, parsed...)
+	return nil
+}
+
+// SplitList splits a comma-separated list, dropping empty entries
+func SplitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadPreset reads a bundled preset
+func loadPreset(name string) (*presetConfig, error) {
+	data, err := presetFiles.ReadFile(path.Join("presets", name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preset '%s': %w", name, err)
+	}
+	var preset presetConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&preset); err != nil {
+		return nil, fmt.Errorf("failed to parse preset '%s': %w", name, err)
+	}
+	return &preset, nil
+}
+
+// applyPresets adds the axes and patterns of presets after those of the config. Axes the config
+// already defines are kept as they are.
+func applyPresets(config *VisualizationConfig, presets Presets) error {
+	for _, ref := range presets {
+		preset, err := loadPreset(ref.name)
+		if err != nil {
+			return err
+		}
+		for _, axis := range preset.Axes {
+			if !slices.ContainsFunc(config.Axes, func(a AxisConfig) bool { return a.Name == axis.Name }) {
+				config.Axes = append(config.Axes, axis)
+			}
+		}
+		for _, p := range preset.Patterns {
+			if ref.tag != "" {
+				p.TagFilter = ref.tag
+				if p.Name != "" {
+					p.Name = ref.tag + " " + p.Name
+				}
+				if p.HoldoverPhase != "" {
+					p.HoldoverPhase = ref.tag + " " + p.HoldoverPhase
+				}
+				p.Series = slices.Clone(p.Series)
+				for i := range p.Series {
+					p.Series[i].Name = ref.tag + " " + p.Series[i].Name
+				}
+			}
+			config.Patterns = append(config.Patterns, p)
+		}
+	}
+	return nil
+}
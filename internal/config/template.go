@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateTime is a time in the title and label templates. It prints as "2006-01-02 15:04:05",
+// while the time.Time methods give other formats (e.g., {{.StartTime.Format "Jan 2 15:04"}}).
+type TemplateTime struct {
+	time.Time
+}
+
+// String formats the time, or returns "" for logs without timestamps
+func (t TemplateTime) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// RunMetadata is the data of the title and label templates, describing the run a plot is of
+type RunMetadata struct {
+	StartTime TemplateTime // Timestamp of the first line
+	EndTime   TemplateTime // Timestamp of the last line
+	LogDir    string       // Log directory or archive read ("" for stdin and remote files only)
+	Hostname  string       // Host generating the plot
+}
+
+// RunMetadata returns the metadata of a run of logs from start to end, with the log directory
+// the config was loaded for
+func (c *VisualizationConfig) RunMetadata(start, end time.Time) RunMetadata {
+	hostname, _ := os.Hostname()
+	return RunMetadata{StartTime: TemplateTime{start}, EndTime: TemplateTime{end}, LogDir: c.logDir, Hostname: hostname}
+}
+
+// ExpandTemplates returns a copy of the config with the templates in the title and the axis
+// labels filled from the metadata of the run, e.g. "Offset {{.StartTime}}–{{.EndTime}}"
+func (c *VisualizationConfig) ExpandTemplates(meta RunMetadata) (*VisualizationConfig, error) {
+	expanded := *c
+	expanded.Patterns = append([]PatternConfig(nil), c.Patterns...)
+	expanded.Axes = append([]AxisConfig(nil), c.Axes...)
+	texts := []*string{&expanded.Title, &expanded.XAxisLabel, &expanded.YAxisLabel}
+	for i := range expanded.Axes {
+		texts = append(texts, &expanded.Axes[i].Label)
+	}
+	for i := range expanded.Patterns {
+		texts = append(texts, &expanded.Patterns[i].YAxisLabel)
+	}
+	for _, text := range texts {
+		value, err := expandTemplate(*text, meta)
+		if err != nil {
+			return nil, err
+		}
+		*text = value
+	}
+	return &expanded, nil
+}
+
+// expandTemplate fills a text template from the metadata. Texts without actions are returned
+// as is.
+func expandTemplate(text string, meta RunMetadata) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("text").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template '%s': %w", text, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, meta); err != nil {
+		return "", fmt.Errorf("invalid template '%s': %w", text, err)
+	}
+	return sb.String(), nil
+}
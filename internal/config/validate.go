@@ -0,0 +1,768 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log-interleaver/pkg/pattern"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a mistake found in a config file
+type Problem struct {
+	Line    int // Line in the file (0 for values set by overrides)
+	Message string
+}
+
+// ValidationError lists all problems found in a config file
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if len(e.Problems) == 1 {
+		fmt.Fprintf(&b, "config file '%s' has 1 problem:", e.File)
+	} else {
+		fmt.Fprintf(&b, "config file '%s' has %d problems:", e.File, len(e.Problems))
+	}
+	for _, p := range e.Problems {
+		if p.Line > 0 {
+			fmt.Fprintf(&b, "\n  line %d: %s", p.Line, p.Message)
+		} else {
+			fmt.Fprintf(&b, "\n  %s", p.Message)
+		}
+	}
+	return b.String()
+}
+
+// Markers and line styles drawn by the plots
+var (
+	validMarkers    = []string{".", "point", "o", "O", "circle", "x", "X", "s", "S", "square", "d", "D", "diamond", "+"}
+	validLineStyles = []string{"-", "solid", "--", "dashed", ":", "dotted", "-.", "dashdot", "none"}
+)
+
+// typeErrorLine matches the line prefix of the errors of yaml.TypeError
+var typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// decodeConfig decodes a config document and adds the presets, returning all problems of the
+// config: values of the wrong type, unknown keys, and invalid settings
+func decodeConfig(configPath string, doc *yaml.Node, config *VisualizationConfig, presets Presets) error {
+	var problems []Problem
+	// An empty or missing file has no document to decode, leaving the config to the presets
+	if doc.Kind != 0 {
+		if err := doc.Decode(config); err != nil {
+			var typeErr *yaml.TypeError
+			if !errors.As(err, &typeErr) {
+				return fmt.Errorf("failed to parse config file: %w", err)
+			}
+			// Decoding continues after values of the wrong type, so the rest can still be checked
+			for _, msg := range typeErr.Errors {
+				p := Problem{Message: msg}
+				if m := typeErrorLine.FindStringSubmatch(msg); m != nil {
+					p.Line, _ = strconv.Atoi(m[1])
+					p.Message = m[2]
+				}
+				problems = append(problems, p)
+			}
+		}
+	}
+
+	// Presets come after the patterns of the file, so the problems of those keep their indexes
+	if err := applyPresets(config, presets); err != nil {
+		return err
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+		problems = append(problems, checkKeys(root, reflect.TypeOf(*config), "")...)
+	}
+	problems = append(problems, checkValues(root, config)...)
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return &ValidationError{File: configPath, Problems: problems}
+}
+
+// checkKeys reports the mapping keys below a node that are not fields of the config type
+func checkKeys(node *yaml.Node, t reflect.Type, path string) []Problem {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var problems []Problem
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := make(map[string]reflect.Type)
+		var names []string
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == "-" || !t.Field(i).IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(t.Field(i).Name)
+			}
+			fields[name] = t.Field(i).Type
+			names = append(names, name)
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if key == "<<" {
+				continue
+			}
+			fieldType, ok := fields[key]
+			if !ok {
+				msg := fmt.Sprintf("%sunknown key '%s'", pathPrefix(path), key)
+				if suggestion := closestName(key, names); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean '%s'?)", suggestion)
+				}
+				problems = append(problems, Problem{Line: node.Content[i].Line, Message: msg})
+				continue
+			}
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			problems = append(problems, checkKeys(node.Content[i+1], fieldType, childPath)...)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			problems = append(problems, checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			problems = append(problems, checkKeys(node.Content[i+1], t.Elem(), path+"."+node.Content[i].Value)...)
+		}
+	}
+	return problems
+}
+
+// pathPrefix returns the path of a problem as message prefix
+func pathPrefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + ": "
+}
+
+// closestName returns the name a mistyped key most likely meant, if one is close enough
+func closestName(key string, names []string) string {
+	best, bestDist := "", max(2, len(key)/3)+1
+	for _, name := range names {
+		if d := editDistance(key, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance of two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// nodeAt returns the node at a path of mapping keys (strings) and sequence indexes (ints) below
+// a node, or nil if there is none
+func nodeAt(node *yaml.Node, path ...any) *yaml.Node {
+	for _, elem := range path {
+		if node == nil {
+			return nil
+		}
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		var next *yaml.Node
+		switch elem := elem.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == elem {
+						next = node.Content[i+1]
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && elem < len(node.Content) {
+				next = node.Content[elem]
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+// problemList collects the problems of the values of a config, at the lines of the nearest nodes
+type problemList struct {
+	root *yaml.Node
+	list []Problem
+}
+
+// add adds a problem of the value at path, prefixed with the path, e.g. "patterns[0].color: ..."
+func (ps *problemList) add(path []any, format string, args ...any) {
+	line := 0
+	for n := len(path); n >= 0 && line == 0; n-- {
+		if node := nodeAt(ps.root, path[:n]...); node != nil {
+			line = node.Line
+		}
+	}
+	var b strings.Builder
+	for _, elem := range path {
+		switch elem := elem.(type) {
+		case string:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(elem)
+		case int:
+			fmt.Fprintf(&b, "[%d]", elem)
+		}
+	}
+	ps.list = append(ps.list, Problem{Line: line, Message: pathPrefix(b.String()) + fmt.Sprintf(format, args...)})
+}
+
+// checkValues reports invalid and conflicting settings of a config: unknown enum values,
+// invalid regexes, capture groups the regexes do not have, and unsupported colors and markers
+func checkValues(root *yaml.Node, config *VisualizationConfig) []Problem {
+	ps := &problemList{root: root}
+
+	if config.Theme != "" && config.Theme != "light" && config.Theme != "dark" {
+		ps.add([]any{"theme"}, "invalid theme '%s', expected light or dark", config.Theme)
+	}
+	if config.PlotType != "" && config.PlotType != "line" && config.PlotType != "heatmap" && config.PlotType != "box" && config.PlotType != "violin" {
+		ps.add([]any{"plot_type"}, "invalid plot_type '%s', expected line, heatmap, box, or violin", config.PlotType)
+	}
+	switch config.Legend.Position {
+	case "", "top-left", "top-right", "bottom-left", "bottom-right", "below", "hidden":
+	default:
+		ps.add([]any{"legend", "position"}, "invalid position '%s', expected top-left, top-right, bottom-left, bottom-right, below, or hidden", config.Legend.Position)
+	}
+	if config.Legend.Columns < 0 {
+		ps.add([]any{"legend", "columns"}, "invalid columns %d, expected a positive number of columns", config.Legend.Columns)
+	}
+	fontSizes := []struct {
+		key  string
+		size float64
+	}{{"title", config.FontSizes.Title}, {"axis", config.FontSizes.Axis}, {"legend", config.FontSizes.Legend}}
+	for _, f := range fontSizes {
+		if f.size < 0 {
+			ps.add([]any{"font_sizes", f.key}, "invalid font size %g, expected a positive size in points", f.size)
+		}
+	}
+	if config.HTMLHeight < 0 {
+		ps.add([]any{"html_height"}, "invalid html_height %d, expected a positive height in pixels", config.HTMLHeight)
+	}
+	if config.XTickSpacing < 0 {
+		ps.add([]any{"x_tick_spacing"}, "invalid x_tick_spacing %s, expected a positive duration", config.XTickSpacing)
+	}
+	if config.XTickFormat != "" && config.XTickFormat != "seconds" && config.XTickFormat != "duration" && config.XTickFormat != "clock" {
+		ps.add([]any{"x_tick_format"}, "invalid x_tick_format '%s', expected seconds, duration, or clock", config.XTickFormat)
+	}
+	if config.XTickRotation < -90 || config.XTickRotation > 90 {
+		ps.add([]any{"x_tick_rotation"}, "invalid x_tick_rotation %g, expected -90 to 90 degrees", config.XTickRotation)
+	}
+	if config.Heatmap.TimeBins < 0 {
+		ps.add([]any{"heatmap", "time_bins"}, "invalid time_bins %d, expected a positive number of buckets", config.Heatmap.TimeBins)
+	}
+	if config.Heatmap.ValueBins < 0 {
+		ps.add([]any{"heatmap", "value_bins"}, "invalid value_bins %d, expected a positive number of buckets", config.Heatmap.ValueBins)
+	}
+	if config.Box.Window < 0 {
+		ps.add([]any{"box", "window"}, "invalid window %s, expected a positive duration", config.Box.Window)
+	}
+
+	checkTemplate := func(path []any, text string) {
+		if _, err := expandTemplate(text, RunMetadata{}); err != nil {
+			ps.add(path, "%v", err)
+		}
+	}
+	checkTemplate([]any{"title"}, config.Title)
+	checkTemplate([]any{"xaxis_label"}, config.XAxisLabel)
+	checkTemplate([]any{"yaxis_label"}, config.YAxisLabel)
+	for i, a := range config.Axes {
+		checkTemplate([]any{"axes", i, "label"}, a.Label)
+	}
+	for i, p := range config.Patterns {
+		checkTemplate([]any{"patterns", i, "yaxis_label"}, p.YAxisLabel)
+		for j, s := range p.Series {
+			checkTemplate([]any{"patterns", i, "series", j, "yaxis_label"}, s.YAxisLabel)
+		}
+	}
+	for i, plot := range config.Plots {
+		checkTemplate([]any{"plots", i, "title"}, plot.Title)
+		for j, a := range plot.Axes {
+			checkTemplate([]any{"plots", i, "axes", j, "label"}, a.Label)
+		}
+	}
+
+	plugins := make(map[string]bool)
+	for i, p := range config.Plugins {
+		path := []any{"plugins", i}
+		if p.Name == "" || p.Command == "" {
+			ps.add(path, "name and command are required")
+		} else if plugins[p.Name] {
+			ps.add(append(path, "name"), "duplicate plugin '%s'", p.Name)
+		}
+		plugins[p.Name] = true
+	}
+	checkPlugin := func(path []any, name string) {
+		if name != "" && !plugins[name] {
+			ps.add(path, "unknown plugin '%s'", name)
+		}
+	}
+
+	seriesNames := make(map[string]bool)
+	for i, p := range config.Patterns {
+		path := []any{"patterns", i}
+		at := func(elems ...any) []any { return append(slices.Clip(path), elems...) }
+
+		if p.Type != "" && p.Type != "metric" && p.Type != "event" {
+			ps.add(at("type"), "invalid type '%s', expected metric or event", p.Type)
+		}
+		if p.Plugin != "" {
+			checkPlugin(at("plugin"), p.Plugin)
+			if p.Regex != "" || p.Field != "" || len(p.Series) > 0 || p.Extractor != "" {
+				ps.add(at("plugin"), "plugin cannot be combined with regex, field, series, or extractor")
+			}
+		} else if p.Extractor != "" {
+			if _, ok := pattern.BuiltinExtractor(p.Extractor); !ok {
+				ps.add(at("extractor"), "invalid extractor '%s', expected %s", p.Extractor, strings.Join(pattern.BuiltinExtractorNames(), ", "))
+			}
+			if p.Field != "" || len(p.Series) > 0 {
+				ps.add(at("extractor"), "extractor cannot be combined with field or series")
+			}
+		} else if p.Regex == "" && p.Field == "" {
+			ps.add(path, "regex, field, plugin, or extractor is required")
+		}
+		groups := -1 // Unknown, when the regex is invalid or a field value is used as is
+		if p.Regex != "" {
+			if re, err := regexp.Compile(p.Regex); err != nil {
+				ps.add(at("regex"), "invalid regex: %v", err)
+			} else {
+				groups = re.NumSubexp()
+			}
+		}
+		checkGroup := func(path []any, name string, group int) {
+			if group < 0 {
+				ps.add(path, "invalid %s %d, expected a capture group index from 1", name, group)
+			} else if groups >= 0 && group > groups {
+				ps.add(path, "%s %d does not exist, the regex has %d capture group(s)", name, group, groups)
+			}
+		}
+
+		if len(p.Series) > 0 {
+			if p.Name != "" || p.ValueGroup != 0 {
+				ps.add(path, "name and value_group conflict with series, which replaces them")
+			}
+			if p.IsEvent() {
+				ps.add(at("series"), "series does not apply to event patterns")
+			}
+			for j, s := range p.Series {
+				sp := func(key string) []any { return at("series", j, key) }
+				if s.Name == "" || s.ValueGroup <= 0 {
+					ps.add(at("series", j), "name and value_group are required")
+				}
+				seriesNames[s.Name] = true
+				checkGroup(sp("value_group"), "value_group", s.ValueGroup)
+				checkGroup(sp("state_group"), "state_group", s.StateGroup)
+				checkStyle(ps, sp, s.Color, s.Marker, s.LineStyle, s.Dedup, s.Transform)
+				checkSizes(ps, sp, s.MarkerSize, s.LineWidth, s.Opacity)
+				checkValueFilter(ps, sp, s.MinValue, s.MaxValue, s.OutlierSigma)
+				if s.Unit != "" || s.DisplayUnit != "" {
+					checkUnit(ps, sp, cmp.Or(s.Unit, p.Unit), cmp.Or(s.DisplayUnit, p.DisplayUnit))
+				}
+				if s.Axis != "" && s.YAxisIndex != 0 {
+					ps.add(sp("yaxis_index"), "axis and yaxis_index conflict, remove the deprecated yaxis_index")
+				}
+			}
+		} else {
+			if p.Name == "" {
+				ps.add(path, "name is required")
+			}
+			seriesNames[p.Name] = true
+		}
+		checkGroup(at("value_group"), "value_group", p.ValueGroup)
+		checkGroup(at("state_group"), "state_group", p.StateGroup)
+		checkGroup(at("label_group"), "label_group", p.LabelGroup)
+		checkGroup(at("lane_group"), "lane_group", p.LaneGroup)
+		if p.LaneGroup != 0 && (!p.IsEvent() || p.LabelGroup == 0) {
+			ps.add(at("lane_group"), "lane_group requires an event pattern with a label_group holding the state")
+		}
+		checkStyle(ps, func(key string) []any { return at(key) }, p.Color, p.Marker, p.LineStyle, p.Dedup, p.Transform)
+		checkSizes(ps, func(key string) []any { return at(key) }, p.MarkerSize, p.LineWidth, p.Opacity)
+		checkValueFilter(ps, func(key string) []any { return at(key) }, p.MinValue, p.MaxValue, p.OutlierSigma)
+		if p.IsEvent() && (p.MinValue != nil || p.MaxValue != nil || p.OutlierSigma != 0) {
+			ps.add(path, "min_value, max_value, and outlier_sigma do not apply to event patterns")
+		}
+		if p.Band != nil {
+			if p.IsEvent() {
+				ps.add(at("band"), "band does not apply to event patterns")
+			}
+			if p.Band.Window <= 0 {
+				ps.add(at("band", "window"), "invalid band window %s, expected a positive duration", p.Band.Window)
+			}
+			if low, high := p.Band.Percentiles(); low < 0 || high > 100 || low >= high {
+				ps.add(at("band"), "invalid band percentiles p%g–p%g, expected 0 <= low < high <= 100", low, high)
+			}
+		}
+		if p.Rolling != nil {
+			if p.IsEvent() {
+				ps.add(at("rolling"), "rolling does not apply to event patterns")
+			}
+			if p.Rolling.Window <= 0 {
+				ps.add(at("rolling", "window"), "invalid rolling window %s, expected a positive duration", p.Rolling.Window)
+			}
+		}
+		checkUnit(ps, func(key string) []any { return at(key) }, p.Unit, p.DisplayUnit)
+		if p.IsEvent() && (p.Unit != "" || p.DisplayUnit != "") {
+			ps.add(path, "unit and display_unit do not apply to event patterns")
+		}
+		if p.Axis != "" && p.YAxisIndex != 0 {
+			ps.add(at("yaxis_index"), "axis and yaxis_index conflict, remove the deprecated yaxis_index")
+		}
+	}
+
+	for _, p := range csvPatterns(config) {
+		seriesNames[p.Name] = true
+	}
+	checkAxisRefs(ps, config)
+
+	for i, p := range config.Patterns {
+		if p.HoldoverPhase != "" && !seriesNames[p.HoldoverPhase] {
+			ps.add([]any{"patterns", i, "holdover_phase"}, "unknown series '%s'", p.HoldoverPhase)
+		}
+	}
+
+	if config.TEReport != nil {
+		for i, name := range config.TEReport.Series {
+			if !seriesNames[name] {
+				ps.add([]any{"te_report", "series", i}, "unknown series '%s'", name)
+			}
+		}
+		for i, mask := range config.TEReport.MTIEMasks {
+			path := []any{"te_report", "mtie_masks", i}
+			if mask.Name == "" || len(mask.Points) == 0 {
+				ps.add(path, "name and points are required")
+			}
+			for j, pt := range mask.Points {
+				if pt.Tau <= 0 || pt.LimitNs <= 0 || (j > 0 && pt.Tau <= mask.Points[j-1].Tau) {
+					ps.add(append(path, "points", j), "points need positive limits at positive, increasing taus")
+					break
+				}
+			}
+		}
+	}
+
+	for i, plot := range config.Plots {
+		for j, name := range plot.Patterns {
+			if !seriesNames[name] {
+				ps.add([]any{"plots", i, "patterns", j}, "unknown series '%s'", name)
+			}
+		}
+	}
+
+	for i, a := range config.Assertions {
+		if a.Expr == "" {
+			ps.add([]any{"assertions", i}, "expr is required")
+			continue
+		}
+		series, fromExpr := a.AssertedSeries()
+		key := "series"
+		if fromExpr {
+			key = "expr"
+		}
+		if series != "" && !seriesNames[series] {
+			ps.add([]any{"assertions", i, key}, "unknown series '%s'", series)
+		}
+	}
+
+	for i, sev := range config.Severities {
+		path := []any{"severities", i}
+		if sev.Name == "" || sev.Regex == "" {
+			ps.add(path, "name and regex are required")
+		} else if _, err := regexp.Compile(sev.Regex); err != nil {
+			ps.add(append(path, "regex"), "invalid regex: %v", err)
+		}
+	}
+
+	for i, tf := range config.TimestampFormats {
+		path := []any{"timestamp_formats", i}
+		if tf.Plugin != "" {
+			checkPlugin(append(path, "plugin"), tf.Plugin)
+			if tf.Layout != "" || tf.Regex != "" || tf.JSONField != "" {
+				ps.add(path, "plugin cannot be combined with layout, regex, or json_field")
+			}
+		} else if tf.Layout == "" && tf.Regex == "" && tf.JSONField == "" {
+			ps.add(path, "layout, regex, json_field, or plugin is required")
+		}
+		if tf.JSONField != "" && tf.Regex != "" {
+			ps.add(path, "json_field cannot be combined with regex")
+		}
+		if tf.Regex != "" {
+			if _, err := regexp.Compile(tf.Regex); err != nil {
+				ps.add(append(path, "regex"), "invalid regex: %v", err)
+			}
+		}
+	}
+
+	anchored := make(map[string]bool)
+	for _, anchor := range config.Anchors {
+		anchored[anchor.Tag] = true
+	}
+	for i, anchor := range config.Anchors {
+		path := []any{"anchors", i}
+		if anchor.Tag == "" || anchor.Regex == "" || anchor.ReferenceTag == "" {
+			ps.add(path, "tag, regex, and reference_tag are required")
+		}
+		if anchor.Tag != "" && anchor.Tag == anchor.ReferenceTag {
+			ps.add(append(path, "reference_tag"), "tag '%s' cannot be its own reference", anchor.Tag)
+		} else if anchored[anchor.ReferenceTag] {
+			ps.add(append(path, "reference_tag"), "reference tag '%s' is anchored itself", anchor.ReferenceTag)
+		}
+		if _, err := regexp.Compile(anchor.Regex); err != nil {
+			ps.add(append(path, "regex"), "invalid regex: %v", err)
+		}
+		if _, err := regexp.Compile(anchor.ReferenceRegex); err != nil {
+			ps.add(append(path, "reference_regex"), "invalid regex: %v", err)
+		}
+	}
+
+	csvTags := make(map[string]bool)
+	for i, source := range config.CSVSources {
+		path := []any{"csv_sources", i}
+		if source.Tag == "" || source.TimeColumn == "" {
+			ps.add(path, "tag and time_column are required")
+		}
+		if csvTags[source.Tag] {
+			ps.add(append(path, "tag"), "duplicate CSV source for tag '%s'", source.Tag)
+		}
+		csvTags[source.Tag] = true
+		if utf8.RuneCountInString(source.Delimiter) > 1 || source.Delimiter == "\n" || source.Delimiter == "\"" {
+			ps.add(append(path, "delimiter"), "invalid delimiter '%s', expected a single character", source.Delimiter)
+		}
+		if slices.Contains(source.ValueColumns, source.TimeColumn) {
+			ps.add(append(path, "value_columns"), "time column '%s' cannot be a value column", source.TimeColumn)
+		}
+	}
+
+	for i, rule := range config.TagRules {
+		path := []any{"tag_rules", i}
+		if (rule.Glob == "") == (rule.Regex == "") || rule.Tag == "" {
+			ps.add(path, "tag and exactly one of glob or regex are required")
+		}
+		if rule.Glob != "" {
+			if _, err := filepath.Match(rule.Glob, ""); err != nil {
+				ps.add(append(path, "glob"), "invalid glob: %v", err)
+			}
+		}
+		if rule.Regex != "" {
+			if _, err := regexp.Compile(rule.Regex); err != nil {
+				ps.add(append(path, "regex"), "invalid regex: %v", err)
+			}
+		}
+	}
+
+	return ps.list
+}
+
+// axisRef is the axis reference of a series, with the paths of its axis and yaxis_index settings
+type axisRef struct {
+	series     string
+	axis       string
+	yaxisIndex int
+	axisPath   []any
+	indexPath  []any
+}
+
+// checkAxisRefs checks the axes of the config and of its plots, and that the series reference
+// axes that the config, or each plot with its own axes, defines
+func checkAxisRefs(ps *problemList, config *VisualizationConfig) {
+	var refs []axisRef
+	for i, p := range config.Patterns {
+		ref := axisRef{p.Name, p.Axis, p.YAxisIndex, []any{"patterns", i, "axis"}, []any{"patterns", i, "yaxis_index"}}
+		if len(p.Series) == 0 {
+			refs = append(refs, ref)
+			continue
+		}
+		for j, s := range p.Series {
+			sref := ref
+			sref.series = s.Name
+			if s.Axis != "" {
+				sref.axis, sref.axisPath = s.Axis, []any{"patterns", i, "series", j, "axis"}
+			}
+			if s.YAxisIndex != 0 {
+				sref.yaxisIndex, sref.indexPath = s.YAxisIndex, []any{"patterns", i, "series", j, "yaxis_index"}
+			}
+			refs = append(refs, sref)
+		}
+	}
+
+	// check reports the references to axes that are not defined: at the series, or for the
+	// series of a plot with its own axes, at the axes of the plot
+	check := func(axes []AxisConfig, refs []axisRef, plotPath []any) {
+		defined := make(map[string]bool, len(axes))
+		for _, axis := range axes {
+			defined[axis.Name] = true
+		}
+		for _, ref := range refs {
+			axisPath, indexPath, prefix := ref.axisPath, ref.indexPath, ""
+			if plotPath != nil {
+				axisPath, indexPath, prefix = plotPath, plotPath, fmt.Sprintf("series '%s': ", ref.series)
+			}
+			switch {
+			case len(axes) == 0:
+				if ref.axis != "" {
+					ps.add(axisPath, "%saxis '%s' is referenced but no axes are defined", prefix, ref.axis)
+				}
+			case ref.axis != "":
+				if !defined[ref.axis] {
+					ps.add(axisPath, "%sunknown axis '%s'", prefix, ref.axis)
+				}
+			case ref.yaxisIndex < 0 || ref.yaxisIndex >= len(axes):
+				ps.add(indexPath, "%sinvalid yaxis_index %d, expected 0 to %d", prefix, ref.yaxisIndex, len(axes)-1)
+			}
+		}
+	}
+	checkAxes(ps, []any{"axes"}, config.Axes)
+	check(config.Axes, refs, nil)
+	for i, plot := range config.Plots {
+		if plot.Axes == nil {
+			continue
+		}
+		checkAxes(ps, []any{"plots", i, "axes"}, plot.Axes)
+		plotRefs := refs
+		if len(plot.Patterns) > 0 {
+			plotRefs = nil
+			for _, ref := range refs {
+				if slices.Contains(plot.Patterns, ref.series) {
+					plotRefs = append(plotRefs, ref)
+				}
+			}
+		}
+		check(plot.Axes, plotRefs, []any{"plots", i, "axes"})
+	}
+}
+
+// checkAxes checks the names and sides of axes
+func checkAxes(ps *problemList, path []any, axes []AxisConfig) {
+	names := make(map[string]bool, len(axes))
+	for i, axis := range axes {
+		at := append(slices.Clip(path), i)
+		if axis.Name == "" {
+			ps.add(at, "name is required")
+		} else if names[axis.Name] {
+			ps.add(append(at, "name"), "duplicate axis '%s'", axis.Name)
+		}
+		names[axis.Name] = true
+		if axis.Side != "" && axis.Side != "left" && axis.Side != "right" {
+			ps.add(append(at, "side"), "invalid side '%s', expected left or right", axis.Side)
+		}
+	}
+}
+
+// checkValueFilter checks the bounds and the outlier threshold of a series
+func checkValueFilter(ps *problemList, at func(key string) []any, minValue, maxValue *float64, outlierSigma float64) {
+	if minValue != nil && maxValue != nil && *minValue > *maxValue {
+		ps.add(at("min_value"), "min_value %g is above max_value %g", *minValue, *maxValue)
+	}
+	if outlierSigma < 0 {
+		ps.add(at("outlier_sigma"), "invalid outlier_sigma %g, expected a positive number of standard deviations", outlierSigma)
+	}
+}
+
+// checkUnit checks the source and display units of a series: known units of the same dimension,
+// and a display unit only with a source unit to convert from
+func checkUnit(ps *problemList, at func(key string) []any, unit, displayUnit string) {
+	if unit == "" {
+		if displayUnit != "" {
+			ps.add(at("display_unit"), "display_unit requires the unit of the extracted values")
+		}
+		return
+	}
+	if _, err := pattern.UnitScale(unit, unit); err != nil {
+		ps.add(at("unit"), "%v", err)
+		return
+	}
+	if displayUnit != "" {
+		if _, err := pattern.UnitScale(unit, displayUnit); err != nil {
+			ps.add(at("display_unit"), "%v", err)
+		}
+	}
+}
+
+// checkSizes checks the marker size, line width, and opacity of a pattern or series
+func checkSizes(ps *problemList, at func(key string) []any, markerSize, lineWidth, opacity float64) {
+	if markerSize < 0 {
+		ps.add(at("marker_size"), "invalid marker_size %g, expected a positive size in points", markerSize)
+	}
+	if lineWidth < 0 {
+		ps.add(at("line_width"), "invalid line_width %g, expected a positive width in points", lineWidth)
+	}
+	// An unset opacity decodes as 0 too, so only the node tells an explicit 0 from the default
+	explicit := nodeAt(ps.root, at("opacity")...)
+	if opacity < 0 || opacity > 1 || opacity == 0 && explicit != nil && explicit.ShortTag() != "!!null" {
+		ps.add(at("opacity"), "invalid opacity %g, expected 0 < opacity <= 1", opacity)
+	}
+}
+
+// checkStyle checks the style, dedup, and transform settings of a pattern or series
+func checkStyle(ps *problemList, at func(key string) []any, color, marker, lineStyle, dedup, transform string) {
+	if color != "" && ParseColor(color) == nil {
+		ps.add(at("color"), "invalid color '%s', expected black, white, red, green, blue, yellow, cyan, magenta, orange, purple, brown, pink, gray, teal, or #RRGGBB", color)
+	}
+	if marker != "" && !slices.Contains(validMarkers, marker) {
+		ps.add(at("marker"), "invalid marker '%s', expected %s", marker, strings.Join(validMarkers, ", "))
+	}
+	if lineStyle != "" && !slices.Contains(validLineStyles, lineStyle) {
+		ps.add(at("line_style"), "invalid line_style '%s', expected %s", lineStyle, strings.Join(validLineStyles, ", "))
+	}
+	switch dedup {
+	case "", "first", "last", "mean":
+	default:
+		ps.add(at("dedup"), "invalid dedup '%s', expected first, last, or mean", dedup)
+	}
+	switch transform {
+	case "", "rate", "derivative", "cumulative":
+	default:
+		ps.add(at("transform"), "invalid transform '%s', expected rate, derivative, or cumulative", transform)
+	}
+}
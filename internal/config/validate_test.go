@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadConfigProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []Problem
+	}{
+		{
+			name: "valid",
+			yaml: `
+patterns:
+  - name: offset
+    regex: 'offset (\d+)'
+    value_group: 1
+    color: '#1f77b4'
+`,
+		},
+		{
+			name: "top-level values",
+			yaml: `
+theme: blue
+titel: Offsets
+width: wide
+`,
+			want: []Problem{
+				{Line: 2, Message: "theme: invalid theme 'blue', expected light or dark"},
+				{Line: 3, Message: "unknown key 'titel' (did you mean 'title'?)"},
+				{Line: 4, Message: "cannot unmarshal !!str `wide` into int"},
+			},
+		},
+		{
+			name: "pattern values",
+			yaml: `
+patterns:
+  - name: offset
+    regex: 'offset (\d+'
+  - name: delay
+    regex: 'delay (\d+)'
+    value_group: 2
+    color: mauve
+    marker: star
+  - regex: 'freq (\d+)'
+    value_group: 1
+`,
+			want: []Problem{
+				{Line: 4, Message: "patterns[0].regex: invalid regex: error parsing regexp: missing closing ): `offset (\\d+`"},
+				{Line: 7, Message: "patterns[1].value_group: value_group 2 does not exist, the regex has 1 capture group(s)"},
+				{Line: 8, Message: "patterns[1].color: invalid color 'mauve', expected black, white, red, green, blue, yellow, cyan, magenta, orange, purple, brown, pink, gray, teal, or #RRGGBB"},
+				{Line: 9, Message: "patterns[1].marker: invalid marker 'star', expected ., point, o, O, circle, x, X, s, S, square, d, D, diamond, +"},
+				{Line: 10, Message: "patterns[2]: name is required"},
+			},
+		},
+		{
+			name: "series",
+			yaml: `
+patterns:
+  - regex: 'port (\d+) (\d+)'
+    type: event
+    series:
+      - name: port
+        value_group: 1
+  - regex: 'rms (\d+) max (\d+)'
+    series:
+      - name: rms
+        value_group: 1
+      - value_group: 2
+`,
+			want: []Problem{
+				{Line: 6, Message: "patterns[0].series: series does not apply to event patterns"},
+				{Line: 12, Message: "patterns[1].series[1]: name and value_group are required"},
+			},
+		},
+		{
+			name: "rolling and axes",
+			yaml: `
+axes:
+  - name: ns
+  - name: ns
+    side: top
+patterns:
+  - name: offset
+    regex: 'offset (\d+)'
+    value_group: 1
+    axis: ppb
+    rolling:
+      window: 0s
+`,
+			want: []Problem{
+				{Line: 4, Message: "axes[1].name: duplicate axis 'ns'"},
+				{Line: 5, Message: "axes[1].side: invalid side 'top', expected left or right"},
+				{Line: 10, Message: "patterns[0].axis: unknown axis 'ppb'"},
+				{Line: 12, Message: "patterns[0].rolling.window: invalid rolling window 0s, expected a positive duration"},
+			},
+		},
+		{
+			name: "unknown series",
+			yaml: `
+patterns:
+  - name: offset
+    regex: 'offset (\d+)'
+    value_group: 1
+plots:
+  - patterns: [offset, delay]
+assertions:
+  - expr: max_abs(delay) < 100
+`,
+			want: []Problem{
+				{Line: 7, Message: "plots[0].patterns[1]: unknown series 'delay'"},
+				{Line: 9, Message: "assertions[0].expr: unknown series 'delay'"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadConfig(path, Options{})
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("LoadConfig: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("LoadConfig error = %v, want a ValidationError", err)
+			}
+			if !slices.Equal(validationErr.Problems, tt.want) {
+				t.Errorf("problems = %+v, want %+v", validationErr.Problems, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package csvsource converts timestamped CSV files (e.g., measurement exports of a GNSS
+// simulator or a TIE tester) into structured log lines, so external measurements can be
+// interleaved with the logs and plotted with field patterns. Each row becomes a JSON object line
+// with the columns as fields, named by the header row.
+package csvsource
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Source declares the layout of the CSV files of a tag
+type Source struct {
+	TimeColumn string // Header of the column with the time of each row
+	Layout     string // Go time layout of the times (empty = RFC 3339, or epoch numbers)
+	Delimiter  rune   // Column delimiter (0 = comma)
+}
+
+// Lines reads a CSV file with a header row and returns its rows as JSON object lines, one per
+// row, with the cells under the column headers. Empty cells are left out, and lines starting
+// with # are comments.
+func Lines(r io.Reader, source Source) ([]byte, error) {
+	reader := csv.NewReader(r)
+	if source.Delimiter != 0 {
+		reader.Comma = source.Delimiter
+	}
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1 // Instruments append columns to some rows
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for k := range header {
+		// Spreadsheet exports start with a byte order mark
+		header[k] = strings.TrimSpace(strings.TrimPrefix(header[k], "\ufeff"))
+	}
+	if !slices.Contains(header, source.TimeColumn) {
+		return nil, fmt.Errorf("CSV header has no time column '%s' (columns: %s)", source.TimeColumn, strings.Join(header, ", "))
+	}
+
+	var out bytes.Buffer
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row: %w", err)
+		}
+		// Written by hand to keep the columns in file order
+		out.WriteByte('{')
+		first := true
+		for k, cell := range row {
+			cell = strings.TrimSpace(cell)
+			if k >= len(header) || header[k] == "" || cell == "" {
+				continue
+			}
+			if !first {
+				out.WriteByte(',')
+			}
+			first = false
+			key, _ := json.Marshal(header[k])
+			value, _ := json.Marshal(cell)
+			out.Write(key)
+			out.WriteByte(':')
+			out.Write(value)
+		}
+		out.WriteString("}\n")
+	}
+	return out.Bytes(), nil
+}
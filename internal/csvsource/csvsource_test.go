@@ -0,0 +1,70 @@
+package csvsource
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		source Source
+		want   string
+	}{
+		{
+			name:   "rows",
+			input:  "time,tie,mtie\n2026-01-11T14:00:00Z,1.5,2\n2026-01-11T14:00:01Z,-0.5,2\n",
+			source: Source{TimeColumn: "time"},
+			want:   `{"time":"2026-01-11T14:00:00Z","tie":"1.5","mtie":"2"}` + "\n" + `{"time":"2026-01-11T14:00:01Z","tie":"-0.5","mtie":"2"}` + "\n",
+		},
+		{
+			name:   "byte order mark and spaces in the header",
+			input:  "\ufefftime , tie\n1768140000, 3\n",
+			source: Source{TimeColumn: "time"},
+			want:   `{"time":"1768140000","tie":"3"}` + "\n",
+		},
+		{
+			name:   "comments and empty cells",
+			input:  "# exported by the tester\ntime,tie,mtie\n# gap\n1768140000,,4\n",
+			source: Source{TimeColumn: "time"},
+			want:   `{"time":"1768140000","mtie":"4"}` + "\n",
+		},
+		{
+			name:   "delimiter",
+			input:  "time;tie\n1768140000;1,5\n",
+			source: Source{TimeColumn: "time", Delimiter: ';'},
+			want:   `{"time":"1768140000","tie":"1,5"}` + "\n",
+		},
+		{
+			name:   "extra and missing columns",
+			input:  "time,tie\n1768140000,1,extra\n1768140001\n",
+			source: Source{TimeColumn: "time"},
+			want:   `{"time":"1768140000","tie":"1"}` + "\n" + `{"time":"1768140001"}` + "\n",
+		},
+		{
+			name:   "empty",
+			input:  "",
+			source: Source{TimeColumn: "time"},
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lines(strings.NewReader(tt.input), tt.source)
+			if err != nil {
+				t.Fatalf("Lines: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesNoTimeColumn(t *testing.T) {
+	_, err := Lines(strings.NewReader("stamp,tie\n1768140000,1\n"), Source{TimeColumn: "time"})
+	if err == nil || !strings.Contains(err.Error(), "no time column 'time' (columns: stamp, tie)") {
+		t.Errorf("Lines error = %v, want a missing time column", err)
+	}
+}
@@ -0,0 +1,147 @@
+package interleaver
+
+import (
+	"fmt"
+	"log-interleaver/internal/parser"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Anchor declares that lines of a tag correspond to lines of a reference tag: the n-th line of
+// the tag matching a regex happened at the time of the n-th line of the reference tag matching
+// the reference regex
+type Anchor struct {
+	tag            string
+	regex          *regexp.Regexp
+	referenceTag   string
+	referenceRegex *regexp.Regexp
+}
+
+// NewAnchor creates an anchor of a tag to a reference tag. An empty reference regex matches the
+// reference lines with the regex of the tag.
+func NewAnchor(tag, pattern, referenceTag, referencePattern string) (*Anchor, error) {
+	if tag == "" || referenceTag == "" || pattern == "" {
+		return nil, fmt.Errorf("anchor requires a tag, a regex, and a reference tag")
+	}
+	if tag == referenceTag {
+		return nil, fmt.Errorf("anchor of tag '%s' cannot reference the tag itself", tag)
+	}
+	if referencePattern == "" {
+		referencePattern = pattern
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anchor regex '%s': %w", pattern, err)
+	}
+	referenceRegex, err := regexp.Compile(referencePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anchor regex '%s': %w", referencePattern, err)
+	}
+	return &Anchor{tag: tag, regex: regex, referenceTag: referenceTag, referenceRegex: referenceRegex}, nil
+}
+
+// AddAnchor adds an anchor of a tag. The timestamps of a tag with matched anchors are warped
+// piecewise-linearly so each anchor line lands on the time of its reference line, which follows
+// clocks that drift or step during the capture. Before the first and after the last anchor the
+// offset of the nearest anchor applies. Anchors take precedence over automatic alignment (the
+// reference lines are taken as aligned), and manual offsets over anchors.
+func (i *Interleaver) AddAnchor(anchor *Anchor) {
+	i.anchors = append(i.anchors, anchor)
+}
+
+// anchorPoint is a line of an anchored tag with the time of its reference line
+type anchorPoint struct {
+	index        int       // Index of the line in the lines of the tag
+	time         time.Time // Timestamp of the line
+	referenceTag string
+	reference    time.Time // Timestamp of the reference line, before the reference is aligned
+	offset       time.Duration
+}
+
+// findAnchors pairs the anchor lines of a tag with their reference lines, in the order of the
+// lines of the tag. Timestamps must be final (years inferred, timezones converted).
+func (i *Interleaver) findAnchors(tag string, linesByTag map[string][]*parser.LogLine) []anchorPoint {
+	var points []anchorPoint
+	for _, anchor := range i.anchors {
+		if anchor.tag != tag {
+			continue
+		}
+		var references []time.Time
+		for _, line := range linesByTag[anchor.referenceTag] {
+			if line.Timestamp != nil && anchor.referenceRegex.MatchString(line.OriginalLine) {
+				references = append(references, line.Timestamp.Time)
+			}
+		}
+		n := 0
+		for k, line := range linesByTag[tag] {
+			if n == len(references) {
+				break
+			}
+			if line.Timestamp != nil && anchor.regex.MatchString(line.OriginalLine) {
+				points = append(points, anchorPoint{index: k, time: line.Timestamp.Time, referenceTag: anchor.referenceTag, reference: references[n]})
+				n++
+			}
+		}
+	}
+
+	// A line matched by several anchors keeps the first
+	sort.SliceStable(points, func(a, b int) bool { return points[a].index < points[b].index })
+	unique := points[:0]
+	for _, point := range points {
+		if len(unique) == 0 || unique[len(unique)-1].index != point.index {
+			unique = append(unique, point)
+		}
+	}
+	return unique
+}
+
+// anchorReferences lists the reference tags of the anchor points
+func anchorReferences(points []anchorPoint) string {
+	var tags []string
+	for _, point := range points {
+		if !slices.Contains(tags, point.referenceTag) {
+			tags = append(tags, point.referenceTag)
+		}
+	}
+	return strings.Join(tags, ", ")
+}
+
+// resolveAnchors computes the offset of each anchor point from the aligned time of its
+// reference line, setting the offset of the tag to that of the last anchor (which continues
+// the alignment in later incremental runs)
+func (i *Interleaver) resolveAnchors(tag string) {
+	points := i.anchorPoints[tag]
+	for k := range points {
+		p := &points[k]
+		reference := p.reference
+		if drift, ok := i.drifts[p.referenceTag]; ok {
+			reference = reference.Add(drift.correction(reference))
+		}
+		p.offset = reference.Add(i.fileOffsets[p.referenceTag]).Sub(p.time)
+	}
+	i.fileOffsets[tag] = points[len(points)-1].offset
+}
+
+// anchorOffset returns the offset of the line at an index of an anchored tag, interpolated
+// between the anchor points around the line: by its timestamp, or by its position where the
+// clock stepped back between the anchors
+func anchorOffset(points []anchorPoint, index int, t time.Time) time.Duration {
+	next := sort.Search(len(points), func(k int) bool { return points[k].index >= index })
+	if next == 0 {
+		return points[0].offset
+	}
+	if next == len(points) {
+		return points[len(points)-1].offset
+	}
+	prev, p := points[next-1], points[next]
+	var fraction float64
+	if span := p.time.Sub(prev.time); span > 0 {
+		fraction = min(max(float64(t.Sub(prev.time))/float64(span), 0), 1)
+	} else {
+		fraction = float64(index-prev.index) / float64(p.index-prev.index)
+	}
+	return prev.offset + time.Duration(fraction*float64(p.offset-prev.offset))
+}
@@ -0,0 +1,206 @@
+package interleaver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-interleaver/internal/parser"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpointVersion is the version of the checkpoint file format
+const checkpointVersion = 1
+
+// headSize is the number of leading bytes of a file that identify it across runs
+const headSize = 4096
+
+// Checkpoint is the state of incremental processing: how far each file was read and the offsets
+// and boot times resolved so far. A Process run with a checkpoint only parses the data appended
+// to the files since the previous run and keeps the alignment of the lines written before.
+type Checkpoint struct {
+	Version   int                        `json:"version"`
+	Files     map[string]*FileCheckpoint `json:"files"`             // By file path
+	Offsets   map[string]Duration        `json:"offsets"`           // Applied offset per tag
+	Drifts    map[string]Drift           `json:"drifts,omitempty"`  // Applied clock drift correction per tag
+	BootTimes map[string]time.Time       `json:"boot_times"`        // Boot time per tag of resolved uptime timestamps
+	Written   *time.Time                 `json:"written,omitempty"` // Timestamp of the last line written, set by the caller
+}
+
+// FileCheckpoint is the read position of a file
+type FileCheckpoint struct {
+	Tag      string     `json:"tag"`
+	Offset   int64      `json:"offset"`             // Bytes of the complete lines read
+	Lines    int        `json:"lines"`              // Number of lines read
+	Head     string     `json:"head"`               // SHA-256 of the first bytes read, to detect replaced (rotated) files
+	Yearless *time.Time `json:"yearless,omitempty"` // Last yearless timestamp, with its inferred year
+	Replaced int        `json:"replaced,omitempty"` // Number of times the file was found replaced
+}
+
+// Duration is a time.Duration written as text (e.g., "5h0m0s") in checkpoint files
+type Duration time.Duration
+
+// MarshalText formats the duration
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses the duration
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// NewCheckpoint returns an empty checkpoint, which makes the first run read all files
+func NewCheckpoint() *Checkpoint {
+	return &Checkpoint{
+		Version:   checkpointVersion,
+		Files:     make(map[string]*FileCheckpoint),
+		Offsets:   make(map[string]Duration),
+		Drifts:    make(map[string]Drift),
+		BootTimes: make(map[string]time.Time),
+	}
+}
+
+// LoadCheckpoint reads a checkpoint file, returning an empty checkpoint if it does not exist
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewCheckpoint(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	c := NewCheckpoint()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint '%s': %w", path, err)
+	}
+	if c.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d in '%s', expected %d", c.Version, path, checkpointVersion)
+	}
+	return c, nil
+}
+
+// Save writes the checkpoint file, replacing it atomically so an interrupted save keeps the
+// previous state
+func (c *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".checkpoint-*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// SetCheckpoint enables incremental processing: Process resumes each file from the checkpoint
+// and updates the checkpoint with the new read positions, offsets, and boot times. Files that
+// shrank or whose first bytes changed (rotated or replaced) are read from the start.
+func (i *Interleaver) SetCheckpoint(c *Checkpoint) {
+	i.checkpoint = c
+}
+
+// resume positions the file at its checkpointed read position and returns the state to update
+// while reading
+func (c *Checkpoint) resume(file *os.File, path, tag string) (*FileCheckpoint, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	state, ok := c.Files[path]
+	if !ok {
+		state = &FileCheckpoint{}
+		c.Files[path] = state
+	}
+	if state.Offset > 0 {
+		digest, err := headDigest(file, state.Offset)
+		if err != nil {
+			return nil, err
+		}
+		if state.Offset > info.Size() || digest != state.Head {
+			// Rotated, truncated, or replaced: the content read before is gone
+			*state = FileCheckpoint{Replaced: state.Replaced + 1}
+		}
+	}
+	state.Tag = tag
+	if _, err := file.Seek(state.Offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek file: %w", err)
+	}
+	return state, nil
+}
+
+// advance moves the read position of a file past the complete lines read
+func (state *FileCheckpoint) advance(file *os.File, consumed int64, lines int) error {
+	state.Offset += consumed
+	state.Lines += lines
+	digest, err := headDigest(file, state.Offset)
+	if err != nil {
+		return err
+	}
+	state.Head = digest
+	return nil
+}
+
+// headDigest returns the SHA-256 of the first bytes of a file, up to its read position
+func headDigest(file *os.File, offset int64) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(file, 0, min(offset, headSize))); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// offset returns the offset of a tag applied by previous runs, if any
+func (c *Checkpoint) offset(tag string) (time.Duration, bool) {
+	if c == nil {
+		return 0, false
+	}
+	offset, ok := c.Offsets[tag]
+	return time.Duration(offset), ok
+}
+
+// yearlessLines returns the indexes of the first and last lines with yearless timestamps,
+// or -1 without such lines
+func yearlessLines(lines []*parser.LogLine) (first, last int) {
+	first, last = -1, -1
+	for k, line := range lines {
+		if line.Timestamp != nil && line.Timestamp.YearInferred {
+			if first < 0 {
+				first = k
+			}
+			last = k
+		}
+	}
+	return first, last
+}
+
+// recordBootTime stores the boot time of the last resolved uptime timestamp of a tag
+func (c *Checkpoint) recordBootTime(tag string, lines []*parser.LogLine) {
+	for k := len(lines) - 1; k >= 0; k-- {
+		line := lines[k]
+		if line.HasUptime && line.Timestamp != nil {
+			c.BootTimes[tag] = line.Timestamp.Time.Add(-time.Duration(line.UptimeSec * float64(time.Second)))
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package interleaver
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// colorCodes maps color names to ANSI SGR codes
+var colorCodes = map[string]string{
+	"black":          "30",
+	"red":            "31",
+	"green":          "32",
+	"yellow":         "33",
+	"blue":           "34",
+	"magenta":        "35",
+	"cyan":           "36",
+	"white":          "37",
+	"bright-black":   "90",
+	"bright-red":     "91",
+	"bright-green":   "92",
+	"bright-yellow":  "93",
+	"bright-blue":    "94",
+	"bright-magenta": "95",
+	"bright-cyan":    "96",
+	"bright-white":   "97",
+}
+
+// defaultPalette is assigned in order to tags without a configured color
+var defaultPalette = []string{"36", "33", "32", "35", "34", "91", "96", "93", "92", "95"}
+
+// sgrRe matches raw ANSI SGR parameter strings (e.g., "1;33", "38;5;208")
+var sgrRe = regexp.MustCompile(`^\d+(;\d+)*$`)
+
+// Colorizer colors interleaved lines by tag for terminal output
+type Colorizer struct {
+	colors map[string]string // SGR code per tag
+	next   int               // Next default palette entry
+}
+
+// NewColorizer creates a colorizer with configured colors per tag. Colors are names
+// ("cyan", "bright-red") or raw ANSI SGR codes ("1;33", "38;5;208"); tags without a
+// configured color get colors from a default palette in order of first appearance.
+func NewColorizer(tagColors map[string]string) (*Colorizer, error) {
+	c := &Colorizer{colors: make(map[string]string)}
+	for tag, color := range tagColors {
+		code, ok := colorCodes[strings.ToLower(color)]
+		if !ok {
+			if !sgrRe.MatchString(color) {
+				return nil, fmt.Errorf("invalid color '%s' for tag '%s'", color, tag)
+			}
+			code = color
+		}
+		c.colors[tag] = code
+	}
+	return c, nil
+}
+
+// AssignColors assigns default palette colors to the given tags in sorted order,
+// so the colors are stable across runs regardless of which tag appears first
+func (c *Colorizer) AssignColors(tags []string) {
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	for _, tag := range sorted {
+		c.colorFor(tag)
+	}
+}
+
+// Colorize wraps a formatted line in the color of its tag
+func (c *Colorizer) Colorize(tag, line string) string {
+	return "\x1b[" + c.colorFor(tag) + "m" + line + "\x1b[0m"
+}
+
+// colorFor returns the color of a tag, assigning the next palette color on first use
+func (c *Colorizer) colorFor(tag string) string {
+	if code, ok := c.colors[tag]; ok {
+		return code
+	}
+	code := defaultPalette[c.next%len(defaultPalette)]
+	c.next++
+	c.colors[tag] = code
+	return code
+}
@@ -0,0 +1,67 @@
+package interleaver
+
+import (
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/csvsource"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/timestamp"
+	"time"
+	"unicode/utf8"
+)
+
+// ApplyConfig applies the interleaving settings of a config: timestamp formats, tag rules, tag
+// priority, GNSS tags, anchors, CSV sources, and timezones. Timestamp formats naming a plugin use
+// its resolver from plugins, keyed by plugin name.
+func (i *Interleaver) ApplyConfig(cfg *config.VisualizationConfig, plugins map[string]TimestampResolver) error {
+	for _, tf := range cfg.TimestampFormats {
+		if tf.Plugin != "" {
+			resolver, ok := plugins[tf.Plugin]
+			if !ok {
+				return fmt.Errorf("invalid timestamp format for tag '%s': unknown plugin '%s'", tf.Tag, tf.Plugin)
+			}
+			i.SetTimestampResolver(tf.Tag, resolver)
+			continue
+		}
+		if tf.JSONField != "" {
+			i.AddJSONTimestampField(tf.Tag, parser.JSONTimestampField{Path: tf.JSONField, Layout: tf.Layout})
+			continue
+		}
+		format, err := timestamp.NewCustomFormat(tf.Layout, tf.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp format for tag '%s': %w", tf.Tag, err)
+		}
+		i.AddTimestampFormat(tf.Tag, format)
+	}
+	for _, tr := range cfg.TagRules {
+		rule, err := NewTagRule(tr.Glob, tr.Regex, tr.Tag)
+		if err != nil {
+			return fmt.Errorf("invalid tag rule: %w", err)
+		}
+		i.AddTagRule(rule)
+	}
+	i.SetTagPriority(cfg.TagPriority)
+	i.SetGNSSTags(cfg.GNSSTags)
+	for _, a := range cfg.Anchors {
+		anchor, err := NewAnchor(a.Tag, a.Regex, a.ReferenceTag, a.ReferenceRegex)
+		if err != nil {
+			return fmt.Errorf("invalid anchor: %w", err)
+		}
+		i.AddAnchor(anchor)
+	}
+	for _, src := range cfg.CSVSources {
+		source := csvsource.Source{TimeColumn: src.TimeColumn, Layout: src.Layout}
+		if src.Delimiter != "" {
+			source.Delimiter, _ = utf8.DecodeRuneInString(src.Delimiter)
+		}
+		i.SetCSVSource(src.Tag, source)
+	}
+	for tag, name := range cfg.Timezones {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return fmt.Errorf("invalid timezone for tag '%s': %w", tag, err)
+		}
+		i.SetTimezone(tag, loc)
+	}
+	return nil
+}
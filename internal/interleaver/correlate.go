@@ -0,0 +1,236 @@
+package interleaver
+
+import (
+	"log-interleaver/internal/parser"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlignMethod selects how automatic alignment offsets are computed
+type AlignMethod string
+
+const (
+	// AlignFirstTimestamp aligns the first timestamp of each file with the reference file
+	AlignFirstTimestamp AlignMethod = "first"
+	// AlignEvents aligns files by correlating events that appear in both the file and the reference
+	AlignEvents AlignMethod = "events"
+	// AlignAuto uses event correlation when enough shared events are found, otherwise first timestamps
+	AlignAuto AlignMethod = "auto"
+)
+
+const (
+	maxEventOccurrences = 3 // Messages repeated more often than this are too ambiguous to correlate
+	minEventVotes       = 2 // Matching event pairs needed to agree on an offset
+
+	minDriftPairs  = 4                // Matching event pairs needed to fit a clock drift
+	minDriftSpan   = 10 * time.Minute // Time the pairs of a drift fit must span to tell a drift from jitter
+	driftTolerance = 10 * time.Second // Largest difference from the offset of a pair used in a drift fit
+)
+
+var (
+	// Leading timestamp headers that differ between sources for the same message
+	eventHeaderRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d+)?\S*\s+)?([IEWD]\d{4}\s+\d{2}:\d{2}:\d{2}\.\d+\s+\d+\s+\S+\]\s*)?`)
+	// Program tag such as "ptp4l[1234.567]:"; anything before it (host names, prefixes) is dropped
+	eventProgramRe = regexp.MustCompile(`[A-Za-z][\w.-]*\[[^\]]*\]:`)
+	// Standalone numbers (values, uptimes, PIDs); numbers inside identifiers such as s2 or eno5 are kept
+	eventNumberRe = regexp.MustCompile(`(^|[^A-Za-z0-9_.])[-+]?\d+(\.\d+)?`)
+)
+
+// eventKey normalizes a log line into a key identifying the event it reports
+func eventKey(line string) string {
+	key := eventHeaderRe.ReplaceAllString(line, "")
+	if loc := eventProgramRe.FindStringIndex(key); loc != nil {
+		key = key[loc[0]:]
+	}
+	key = eventNumberRe.ReplaceAllString(key, "${1}#")
+	return strings.Join(strings.Fields(key), " ")
+}
+
+// correlateEvents estimates the offset that aligns lines with the reference lines by matching
+// rare events present in both. Each matching pair votes for an offset; the most supported offset
+// (in 1s bins) wins and is refined by the median of its votes. It returns the offset, the
+// number of votes supporting it, and the number of matching pairs, with ok=false when the
+// evidence is insufficient.
+func correlateEvents(reference, lines []*parser.LogLine) (offset time.Duration, votes, pairs int, ok bool) {
+	var deltas []time.Duration
+	for _, pair := range eventPairs(reference, lines) {
+		deltas = append(deltas, pair.delta())
+	}
+	if len(deltas) < minEventVotes {
+		return 0, 0, len(deltas), false
+	}
+
+	// Vote in 1s bins and pick the best supported bin
+	bins := make(map[time.Duration]int)
+	for _, d := range deltas {
+		bins[d.Round(time.Second)]++
+	}
+	var bestBin time.Duration
+	bestVotes := 0
+	for bin, count := range bins {
+		if count > bestVotes || (count == bestVotes && bin < bestBin) {
+			bestBin = bin
+			bestVotes = count
+		}
+	}
+	if bestVotes < minEventVotes {
+		return 0, bestVotes, len(deltas), false
+	}
+
+	// Refine with the median of the votes in the winning bin
+	var support []time.Duration
+	for _, d := range deltas {
+		if d.Round(time.Second) == bestBin {
+			support = append(support, d)
+		}
+	}
+	sort.Slice(support, func(i, j int) bool { return support[i] < support[j] })
+
+	return support[len(support)/2], len(support), len(deltas), true
+}
+
+// eventPair is an event found in both the lines and the reference lines
+type eventPair struct {
+	reference time.Time // Time of the event in the reference
+	time      time.Time // Time of the event in the lines
+}
+
+// delta returns the offset that aligns the event with the reference
+func (p eventPair) delta() time.Duration {
+	return p.reference.Sub(p.time)
+}
+
+// eventPairs matches the rare events of the lines with those of the reference lines. Events
+// occurring more than once pair every occurrence with every reference occurrence.
+func eventPairs(reference, lines []*parser.LogLine) []eventPair {
+	refEvents := collectEvents(reference)
+	var pairs []eventPair
+	for key, times := range collectEvents(lines) {
+		for _, rt := range refEvents[key] {
+			for _, t := range times {
+				pairs = append(pairs, eventPair{reference: rt, time: t})
+			}
+		}
+	}
+	return pairs
+}
+
+// Drift is a linear clock drift of a tag relative to the reference tag. The clock of the tag
+// gains Rate seconds per second; the drift correction is zero at Center, a time of the tag's
+// clock in the middle of the events it was fitted to.
+type Drift struct {
+	Rate   float64   `json:"rate"`
+	Center time.Time `json:"center"`
+}
+
+// PPM returns the drift rate in parts per million
+func (d Drift) PPM() float64 {
+	return d.Rate * 1e6
+}
+
+// correction returns the correction of a timestamp of the tag for the drift
+func (d Drift) correction(t time.Time) time.Duration {
+	return time.Duration(-d.Rate * float64(t.Sub(d.Center)))
+}
+
+// fitDrift fits a linear correction (offset and rate) to the events matched with the reference
+// lines, starting from the offset found by correlateEvents. Pairs further than driftTolerance
+// from the offset are mismatched occurrences, and pairs far off the first fit are dropped before
+// fitting again. It returns the drift, the offset at its center, and the number of pairs fitted,
+// or ok=false when the pairs are too few or too close in time to measure a drift.
+func fitDrift(reference, lines []*parser.LogLine, offset time.Duration) (drift Drift, centerOffset time.Duration, fitted int, ok bool) {
+	var pairs []eventPair
+	for _, pair := range eventPairs(reference, lines) {
+		if (pair.delta() - offset).Abs() <= driftTolerance {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	drift, centerOffset, ok = fitPairs(pairs, offset)
+	if !ok {
+		return Drift{}, 0, 0, false
+	}
+
+	// Drop the outliers: pairs off the fit by more than four times the median residual
+	residuals := make([]time.Duration, len(pairs))
+	for k, pair := range pairs {
+		residuals[k] = (pair.delta() - centerOffset - drift.correction(pair.time)).Abs()
+	}
+	sorted := slices.Clone(residuals)
+	slices.Sort(sorted)
+	limit := max(4*sorted[len(sorted)/2], time.Millisecond)
+	var kept []eventPair
+	for k, pair := range pairs {
+		if residuals[k] <= limit {
+			kept = append(kept, pair)
+		}
+	}
+	drift, centerOffset, ok = fitPairs(kept, offset)
+	return drift, centerOffset, len(kept), ok
+}
+
+// fitPairs fits the deltas of the pairs by least squares as a linear function of the time of
+// the tag. Deltas are taken relative to the offset, so the fit keeps sub-microsecond precision.
+func fitPairs(pairs []eventPair, offset time.Duration) (drift Drift, centerOffset time.Duration, ok bool) {
+	if len(pairs) < minDriftPairs {
+		return Drift{}, 0, false
+	}
+	first, last := pairs[0].time, pairs[0].time
+	for _, pair := range pairs {
+		if pair.time.Before(first) {
+			first = pair.time
+		}
+		if pair.time.After(last) {
+			last = pair.time
+		}
+	}
+	if last.Sub(first) < minDriftSpan {
+		return Drift{}, 0, false
+	}
+
+	// Center the times on their mean, which makes the intercept the mean delta
+	var sumX, sumY float64
+	for _, pair := range pairs {
+		sumX += pair.time.Sub(first).Seconds()
+		sumY += (pair.delta() - offset).Seconds()
+	}
+	n := float64(len(pairs))
+	center := first.Add(time.Duration(sumX / n * float64(time.Second)))
+	var sxx, sxy float64
+	for _, pair := range pairs {
+		x := pair.time.Sub(center).Seconds()
+		sxx += x * x
+		sxy += x * ((pair.delta() - offset).Seconds() - sumY/n)
+	}
+	if sxx == 0 {
+		return Drift{}, 0, false
+	}
+	// The correction grows as the clock of the tag falls behind, so the rate is the opposite slope
+	drift = Drift{Rate: -sxy / sxx, Center: center}
+	return drift, offset + time.Duration(sumY/n*float64(time.Second)), true
+}
+
+// collectEvents maps event keys to the times they occurred, keeping only rare events
+func collectEvents(lines []*parser.LogLine) map[string][]time.Time {
+	events := make(map[string][]time.Time)
+	for _, line := range lines {
+		if line.Timestamp == nil {
+			continue
+		}
+		key := eventKey(line.OriginalLine)
+		if key == "" {
+			continue
+		}
+		events[key] = append(events[key], line.Timestamp.Time)
+	}
+
+	for key, times := range events {
+		if len(times) > maxEventOccurrences {
+			delete(events, key)
+		}
+	}
+	return events
+}
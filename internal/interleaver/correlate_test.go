@@ -0,0 +1,125 @@
+package interleaver
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/timestamp"
+)
+
+var eventStart = time.Date(2026, 1, 11, 14, 0, 0, 0, time.UTC)
+
+// eventName returns a distinct event message made of letters, which eventKey keeps as is
+func eventName(k int) string {
+	return "ptp4l[1]: event " + string(rune('a'+k%26)) + string(rune('a'+k/26))
+}
+
+// eventLines returns one line per event at the times
+func eventLines(times []time.Time) []*parser.LogLine {
+	lines := make([]*parser.LogLine, len(times))
+	for k, t := range times {
+		lines[k] = &parser.LogLine{
+			OriginalLine: eventName(k),
+			LineNumber:   k + 1,
+			Timestamp:    &timestamp.Timestamp{Time: t, Type: timestamp.TypeAbsolute},
+		}
+	}
+	return lines
+}
+
+// driftingEvents returns reference lines every step and the lines of a tag whose clock is
+// offset behind the reference and gains rate seconds per second from eventStart
+func driftingEvents(n int, step, offset time.Duration, rate float64) (reference, lines []*parser.LogLine) {
+	refTimes := make([]time.Time, n)
+	times := make([]time.Time, n)
+	for k := range n {
+		elapsed := time.Duration(k) * step
+		refTimes[k] = eventStart.Add(elapsed)
+		times[k] = refTimes[k].Add(-offset + time.Duration(rate*float64(elapsed)))
+	}
+	return eventLines(refTimes), eventLines(times)
+}
+
+func TestEventKey(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"2026-01-11T14:05:54.000549Z host ptp4l[1234.567]: port 1: offset 12 s2", "ptp4l[#]: port #: offset # s2"},
+		{"I0111 14:05:54.000549 123 daemon.go:42] ptp4l[9]: eno5 link up", "ptp4l[#]: eno5 link up"},
+		{"phc2sys   rms  -1.5   max 3", "phc2sys rms # max #"},
+	}
+	for _, tt := range tests {
+		if got := eventKey(tt.line); got != tt.want {
+			t.Errorf("eventKey(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCorrelateEvents(t *testing.T) {
+	reference, lines := driftingEvents(5, time.Minute, 90*time.Second, 0)
+	offset, votes, pairs, ok := correlateEvents(reference, lines)
+	if !ok || offset != 90*time.Second || votes != 5 || pairs != 5 {
+		t.Errorf("correlateEvents = %v, %d votes, %d pairs, ok=%v; want 1m30s, 5, 5, true", offset, votes, pairs, ok)
+	}
+
+	// Events repeated more often than maxEventOccurrences are too ambiguous to vote
+	for k := range maxEventOccurrences + 1 {
+		repeated := &parser.LogLine{OriginalLine: "ptp4l[1]: event repeated", Timestamp: &timestamp.Timestamp{Time: eventStart.Add(time.Duration(k) * time.Hour)}}
+		reference = append(reference, repeated)
+		lines = append(lines, repeated)
+	}
+	if _, votes, _, _ := correlateEvents(reference, lines); votes != 5 {
+		t.Errorf("correlateEvents with a repeated event: %d votes, want 5", votes)
+	}
+
+	// A single shared event is not enough evidence
+	if _, _, _, ok := correlateEvents(reference[:1], lines[:1]); ok {
+		t.Error("correlateEvents with one pair: ok=true, want false")
+	}
+}
+
+func TestFitDrift(t *testing.T) {
+	const ppm = 50.0
+	offset := 90 * time.Second
+
+	tests := []struct {
+		name       string
+		n          int
+		step       time.Duration
+		outlier    bool
+		wantFitted int
+		wantOK     bool
+	}{
+		{"drift", 12, time.Minute, false, 12, true},
+		{"outlier dropped", 12, time.Minute, true, 11, true},
+		{"span too short", 12, 30 * time.Second, false, 0, false},
+		{"too few pairs", 3, 10 * time.Minute, false, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reference, lines := driftingEvents(tt.n, tt.step, offset, ppm*1e-6)
+			if tt.outlier {
+				// Within driftTolerance of the offset, but far off the drift
+				lines[5].Timestamp.Time = lines[5].Timestamp.Time.Add(2 * time.Second)
+			}
+			drift, centerOffset, fitted, ok := fitDrift(reference, lines, offset)
+			if ok != tt.wantOK || fitted != tt.wantFitted {
+				t.Fatalf("fitDrift: %d fitted, ok=%v; want %d, %v", fitted, ok, tt.wantFitted, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(drift.PPM()-ppm) > 0.01 {
+				t.Errorf("drift %.3f ppm, want %.0f", drift.PPM(), ppm)
+			}
+			// At the center, the clock has gained the rate times the reference time since eventStart
+			want := offset - time.Duration(ppm*1e-6*float64(drift.Center.Add(offset).Sub(eventStart)))
+			if (centerOffset - want).Abs() > time.Millisecond {
+				t.Errorf("center offset %v, want %v", centerOffset, want)
+			}
+		})
+	}
+}
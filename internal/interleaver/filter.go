@@ -0,0 +1,72 @@
+package interleaver
+
+import (
+	"fmt"
+	"log-interleaver/internal/parser"
+	"regexp"
+	"slices"
+)
+
+// Filter selects interleaved lines by tag and message
+type Filter struct {
+	includeTags []string       // Only keep these tags (empty = all)
+	excludeTags []string       // Drop these tags
+	grep        *regexp.Regexp // Only keep lines matching (nil = all)
+	grepV       *regexp.Regexp // Drop lines matching
+}
+
+// NewFilter creates a line filter. Empty tag lists and patterns don't filter.
+func NewFilter(includeTags, excludeTags []string, grep, grepV string) (*Filter, error) {
+	f := &Filter{includeTags: includeTags, excludeTags: excludeTags}
+	if grep != "" {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grep regex '%s': %w", grep, err)
+		}
+		f.grep = re
+	}
+	if grepV != "" {
+		re, err := regexp.Compile(grepV)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grep-v regex '%s': %w", grepV, err)
+		}
+		f.grepV = re
+	}
+	return f, nil
+}
+
+// IsEmpty reports whether the filter keeps all lines
+func (f *Filter) IsEmpty() bool {
+	return len(f.includeTags) == 0 && len(f.excludeTags) == 0 && f.grep == nil && f.grepV == nil
+}
+
+// Match reports whether the line passes the filter
+func (f *Filter) Match(line *parser.LogLine) bool {
+	if len(f.includeTags) > 0 && !slices.Contains(f.includeTags, line.Tag) {
+		return false
+	}
+	if slices.Contains(f.excludeTags, line.Tag) {
+		return false
+	}
+	if f.grep != nil && !f.grep.MatchString(line.OriginalLine) {
+		return false
+	}
+	if f.grepV != nil && f.grepV.MatchString(line.OriginalLine) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the lines passing the filter, keeping their order
+func (f *Filter) Apply(lines []*parser.LogLine) []*parser.LogLine {
+	if f.IsEmpty() {
+		return lines
+	}
+	var filtered []*parser.LogLine
+	for _, line := range lines {
+		if f.Match(line) {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,75 @@
+package interleaver
+
+import (
+	"fmt"
+	"log-interleaver/internal/nmea"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/timestamp"
+	"slices"
+	"time"
+)
+
+// SetGNSSTags sets the tags whose files are GNSS time source logs: NMEA sentences (e.g.,
+// gpspipe -r or a capture of the receiver's serial port) or gpsd JSON (gpspipe -w). Their lines
+// are anchored to the GNSS time the sentences carry: files without timestamps of their own are
+// timestamped by the GNSS times, and the offset of timestamped files is the median difference
+// between the GNSS times and the timestamps, rounded like automatic offsets. Anchored tags are
+// neither aligned with nor used as the reference of automatic alignment.
+func (i *Interleaver) SetGNSSTags(tags []string) {
+	i.gnssTags = tags
+}
+
+// anchorGNSS records the differences between the GNSS times and the timestamps of the lines of
+// a GNSS tag, or, for files without timestamps of their own (raw receiver output), sets the GNSS
+// times as the timestamps. Timestamps must be final (years inferred, timezones converted).
+func (i *Interleaver) anchorGNSS(tag string, lines []*parser.LogLine) {
+	if !slices.Contains(i.gnssTags, tag) {
+		return
+	}
+	timestamped := slices.ContainsFunc(lines, func(line *parser.LogLine) bool { return line.HasTime() })
+	decoder := nmea.NewDecoder()
+	var last time.Time
+	for _, line := range lines {
+		if fix, ok := decoder.Decode(line.OriginalLine); ok && !fix.Time.IsZero() {
+			last = fix.Time
+			if timestamped && line.Timestamp != nil {
+				i.gnssDeltas[tag] = append(i.gnssDeltas[tag], fix.Time.Sub(line.Timestamp.Time))
+			}
+		}
+		if !timestamped && !last.IsZero() {
+			// Sentences without a time (e.g., GSV, GSA) belong to the epoch of the preceding one
+			line.Timestamp = &timestamp.Timestamp{Time: last, Type: timestamp.TypeAbsolute, Zoned: true}
+			if _, ok := i.gnssDeltas[tag]; !ok {
+				i.gnssDeltas[tag] = nil
+			}
+		}
+	}
+}
+
+// gnssOffset returns the offset anchoring the timestamps of a GNSS tag to its GNSS times, or
+// ok=false for tags without GNSS times
+func (i *Interleaver) gnssOffset(tag string) (time.Duration, bool) {
+	deltas, ok := i.gnssDeltas[tag]
+	if !ok {
+		return 0, false
+	}
+	if len(deltas) == 0 {
+		// Only lines timestamped by their GNSS times
+		return 0, true
+	}
+	deltas = slices.Clone(deltas)
+	slices.Sort(deltas)
+	offset := deltas[len(deltas)/2]
+	if i.alignRounding > 0 {
+		offset = offset.Round(i.alignRounding)
+	}
+	return offset, true
+}
+
+// gnssEvidence describes what the offset of a GNSS tag is based on
+func (i *Interleaver) gnssEvidence(tag string) string {
+	if deltas := i.gnssDeltas[tag]; len(deltas) > 0 {
+		return fmt.Sprintf("median difference to %d GNSS times", len(deltas))
+	}
+	return "timestamped by the GNSS sentences"
+}
@@ -2,12 +2,20 @@ package interleaver
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log-interleaver/internal/csvsource"
 	"log-interleaver/internal/parser"
+	"log-interleaver/internal/pcap"
 	"log-interleaver/pkg/timestamp"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,112 +23,501 @@ import (
 // Interleaver merges and sorts log files by timestamp
 type Interleaver struct {
 	logDir      string
-	fileOffsets map[string]time.Duration // Offset per file tag (in hours, converted to duration)
-	autoAlign   bool                     // Whether to automatically align timezones
+	fileOffsets map[string]time.Duration               // Offset per file tag
+	autoAlign   bool                                   // Whether to automatically align timezones
+	formats     map[string][]*timestamp.CustomFormat   // User-defined timestamp formats per tag ("" = all tags)
+	jsonFields  map[string][]parser.JSONTimestampField // Timestamp fields of structured (JSON) logs per tag ("" = all tags)
+	resolvers   map[string]TimestampResolver           // External timestamp parsers per tag ("" = all tags)
+	csvSources  map[string]csvsource.Source            // Layout of the CSV files per tag
+	tagRules    []*TagRule                             // File name to tag mapping rules
+	files       []string                               // Explicit log files to read instead of scanning logDir
+	readers     []readerSource                         // Streams read in addition to the log files
+	extensions  []string                               // File extensions read when scanning logDir (empty = all)
+
+	alignMethod        AlignMethod                // How automatic offsets are computed
+	alignRounding      time.Duration              // Granularity automatic offsets are rounded to (0 = no rounding)
+	driftCorrection    bool                       // Whether tags aligned by events also get a linear clock drift correction
+	baseYear           int                        // Year of the first yearless timestamp in each file (0 = infer)
+	reorderWindow      time.Duration              // Maximum reordering window for out-of-order source lines
+	reorderStats       map[string]ReorderStats    // Reordering statistics per tag from the last Process run
+	dedupe             bool                       // Whether to drop duplicate lines from overlapping captures
+	tagPriority        map[string]int             // Sort rank of tags for lines with equal timestamps
+	groupContinuations bool                       // Whether timestamp-less lines are attached to the preceding entry
+	timezones          map[string]*time.Location  // Timezone of wall-clock timestamps per tag
+	bootTimes          map[string]time.Time       // Boot time per tag for resolving boot-relative timestamps
+	gnssTags           []string                   // Tags of GNSS time source logs, anchored to the GNSS time of their sentences
+	anchors            []*Anchor                  // Lines of tags corresponding to lines of reference tags
+	anchorPoints       map[string][]anchorPoint   // Matched anchor lines per anchored tag in the last Process run
+	gnssDeltas         map[string][]time.Duration // Differences between the GNSS times and the timestamps per GNSS tag in the last Process run
+	duplicates         int                        // Duplicate lines dropped by the last Process run
+	offsetSources      map[string]OffsetSource    // How the offset of each tag was determined in the last Process run
+	drifts             map[string]Drift           // Clock drift corrected per tag in the last Process run
+	evidence           map[string]string          // What the offset of each tag was based on in the last Process run
+	referenceTag       string                     // Reference tag of the automatic alignment in the last Process run
+	inputFiles         []InputFile                // Files read by the last Process run
+	logger             *slog.Logger               // Progress of the processing phases, at debug level
+	checkpoint         *Checkpoint                // Read positions and offsets of incremental runs (nil = read everything)
 }
 
-// NewInterleaver creates a new interleaver for the given log directory
+// progressInterval is the number of lines between progress messages while parsing a file
+const progressInterval = 1000000
+
+// cancelCheckInterval is the number of lines between checks for a cancelled context
+const cancelCheckInterval = 4096
+
+// NewInterleaver creates a new interleaver for the given log directory. An empty directory
+// reads only the sources added with AddReader.
 func NewInterleaver(logDir string) *Interleaver {
 	return &Interleaver{
 		logDir:      logDir,
 		fileOffsets: make(map[string]time.Duration),
 		autoAlign:   true,
+		formats:     make(map[string][]*timestamp.CustomFormat),
+		jsonFields:  make(map[string][]parser.JSONTimestampField),
+		resolvers:   make(map[string]TimestampResolver),
+		csvSources:  make(map[string]csvsource.Source),
+		timezones:   make(map[string]*time.Location),
+		bootTimes:   make(map[string]time.Time),
+		extensions:  []string{".txt", ".log"},
+
+		reorderWindow: 500 * time.Millisecond,
+		alignMethod:   AlignAuto,
+		alignRounding: time.Hour,
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+// SetLogger sets the logger that receives progress messages (files parsed, lines processed,
+// and the current phase) at debug level
+func (i *Interleaver) SetLogger(logger *slog.Logger) {
+	i.logger = logger
+}
+
+// SetAlignMethod sets how automatic alignment offsets are computed (default: AlignAuto)
+func (i *Interleaver) SetAlignMethod(method AlignMethod) {
+	i.alignMethod = method
+}
+
+// SetBaseYear sets the year assumed for the first yearless timestamp of each file.
+// With 0 (the default) the year is inferred from full-date timestamps or the file modification time.
+func (i *Interleaver) SetBaseYear(year int) {
+	i.baseYear = year
+}
+
+// SetReorderWindow sets the window used to reorder slightly out-of-order source lines
+func (i *Interleaver) SetReorderWindow(window time.Duration) {
+	i.reorderWindow = window
+}
+
+// SetDedupe enables or disables dropping duplicate lines, i.e. lines with the same timestamp,
+// tag, and content, as found when captures overlap (e.g., a rotated file plus the live file)
+func (i *Interleaver) SetDedupe(enabled bool) {
+	i.dedupe = enabled
+}
+
+// SetTagPriority sets the order of tags for lines with equal timestamps. Listed tags come first
+// in list order, followed by the remaining tags alphabetically; lines of the same tag keep their
+// file and line order. This makes the output byte-identical across runs.
+func (i *Interleaver) SetTagPriority(tags []string) {
+	i.tagPriority = make(map[string]int, len(tags))
+	for rank, tag := range tags {
+		if _, ok := i.tagPriority[tag]; !ok {
+			i.tagPriority[tag] = rank
+		}
 	}
 }
 
+// SetGroupContinuations enables or disables attaching timestamp-less continuation lines to the
+// preceding timestamped line of the same file, instead of moving them to the end of the output
+func (i *Interleaver) SetGroupContinuations(enabled bool) {
+	i.groupContinuations = enabled
+}
+
+// Duplicates returns the number of duplicate lines dropped by the last Process run
+func (i *Interleaver) Duplicates() int {
+	return i.duplicates
+}
+
+// ReorderStats returns per-tag statistics on how much reordering the source lines needed
+func (i *Interleaver) ReorderStats() map[string]ReorderStats {
+	return i.reorderStats
+}
+
+// AddTimestampFormat registers a user-defined timestamp format for a file tag.
+// An empty tag applies the format to all files.
+func (i *Interleaver) AddTimestampFormat(tag string, format *timestamp.CustomFormat) {
+	i.formats[tag] = append(i.formats[tag], format)
+}
+
+// AddJSONTimestampField registers the timestamp field of structured (JSON) log lines for a file tag.
+// An empty tag applies the field to all files.
+func (i *Interleaver) AddJSONTimestampField(tag string, field parser.JSONTimestampField) {
+	i.jsonFields[tag] = append(i.jsonFields[tag], field)
+}
+
+// SetCSVSource declares the files of a tag to be CSV files with a header row, read as structured
+// lines with the columns as fields and timestamped by the time column. CSV files of the tag are
+// read from the log directory whatever the extensions.
+func (i *Interleaver) SetCSVSource(tag string, source csvsource.Source) {
+	i.csvSources[tag] = source
+	i.AddJSONTimestampField(tag, parser.JSONTimestampField{Path: source.TimeColumn, Layout: source.Layout})
+}
+
+// TimestampResolver parses timestamps of formats the built-in parsers do not know, such as an
+// external plugin. It returns the timestamp of each line, or the zero time for lines without one.
+type TimestampResolver interface {
+	ResolveTimestamps(ctx context.Context, tag string, lines []string) ([]time.Time, error)
+}
+
+// SetTimestampResolver sets the external timestamp parser of a file tag; an empty tag applies
+// it to files without a resolver of their own. Its timestamps take precedence over those of the
+// built-in parsers and are kept as is (no timezone conversion or alignment).
+func (i *Interleaver) SetTimestampResolver(tag string, resolver TimestampResolver) {
+	i.resolvers[tag] = resolver
+}
+
+// SetTimezone sets the timezone that wall-clock timestamps of a file tag are written in.
+// They are converted to UTC (including DST transitions); epoch timestamps and timestamps
+// with an explicit UTC offset are left unchanged. Tags with a timezone are not auto-aligned.
+func (i *Interleaver) SetTimezone(tag string, loc *time.Location) {
+	i.timezones[tag] = loc
+}
+
+// SetBootTime sets the boot time used to resolve boot-relative timestamps (kernel "[ 1234.567890]"
+// timestamps and uptimes) of a file tag. An empty tag applies to all tags without their own boot
+// time. Boot times logged in the file ("Booted at ...", "btime ...") take precedence.
+func (i *Interleaver) SetBootTime(tag string, bootTime time.Time) {
+	i.bootTimes[tag] = bootTime
+}
+
 // SetFileOffset sets a manual offset (in hours) for a specific file tag
 func (i *Interleaver) SetFileOffset(tag string, hours float64) {
 	i.fileOffsets[tag] = time.Duration(hours * float64(time.Hour))
 }
 
+// SetFileOffsetDuration sets a manual offset for a specific file tag
+func (i *Interleaver) SetFileOffsetDuration(tag string, offset time.Duration) {
+	i.fileOffsets[tag] = offset
+}
+
+// SetAlignRounding sets the granularity automatic offsets are rounded to (default: 1h).
+// Use 0 to apply the measured offset unrounded.
+func (i *Interleaver) SetAlignRounding(rounding time.Duration) {
+	i.alignRounding = rounding
+}
+
+// SetDriftCorrection enables fitting a linear clock drift, besides the constant offset, to the
+// events shared by a tag with the reference tag, and correcting the timestamps of the tag for it
+func (i *Interleaver) SetDriftCorrection(enabled bool) {
+	i.driftCorrection = enabled
+}
+
+// ParseOffset parses an offset given either as a Go duration with an optional sign
+// ("+5h30m", "-37s", "250ms") or as a plain number of hours ("5", "-4.5")
+func ParseOffset(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if hours, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(hours * float64(time.Hour)), nil
+	}
+	offset, err := time.ParseDuration(strings.TrimPrefix(s, "+"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset '%s', expected hours or a duration like +5h30m, -37s, 250ms", s)
+	}
+	return offset, nil
+}
+
 // SetAutoAlign enables or disables automatic timezone alignment
 func (i *Interleaver) SetAutoAlign(enabled bool) {
 	i.autoAlign = enabled
 }
 
-// Process reads all log files, parses them, resolves timestamps, and returns sorted log lines
-func (i *Interleaver) Process() ([]*parser.LogLine, error) {
-	// Read all log files
-	files, err := os.ReadDir(i.logDir)
+// OffsetSource tells how the time offset of a tag was determined
+type OffsetSource string
+
+const (
+	// OffsetManual is an offset given with SetFileOffset or SetFileOffsetDuration
+	OffsetManual OffsetSource = "manual"
+	// OffsetEvents is an automatic offset from events shared with the reference tag
+	OffsetEvents OffsetSource = "events"
+	// OffsetFirstTimestamp is an automatic offset aligning the first timestamps with the reference tag
+	OffsetFirstTimestamp OffsetSource = "first"
+	// OffsetReference marks the reference tag of the automatic alignment
+	OffsetReference OffsetSource = "reference"
+	// OffsetGNSS is an offset anchoring the timestamps of a GNSS tag to the GNSS time of its sentences
+	OffsetGNSS OffsetSource = "gnss"
+	// OffsetTimezone marks tags with a declared timezone or zoned timestamps, which need no offset
+	OffsetTimezone OffsetSource = "timezone"
+	// OffsetAnchors marks tags warped between the anchor lines matched with their reference lines
+	OffsetAnchors OffsetSource = "anchors"
+	// OffsetCheckpoint is the offset of a previous incremental run, kept for consistent output
+	OffsetCheckpoint OffsetSource = "checkpoint"
+	// OffsetNone marks tags that were not aligned
+	OffsetNone OffsetSource = "none"
+)
+
+// AppliedOffset is the time offset applied to the lines of a tag
+type AppliedOffset struct {
+	Tag      string
+	Offset   time.Duration
+	Source   OffsetSource
+	DriftPPM float64 // Clock drift corrected on top of the offset, in ppm (0 = none)
+	Evidence string  // What the offset is based on (e.g., "14 of 15 shared event pairs agree")
+}
+
+// InputFile is a log file read by Process, with its tag
+type InputFile struct {
+	Path string
+	Tag  string
+}
+
+// Offsets returns the offsets applied to each tag by the last Process run, ordered by tag
+func (i *Interleaver) Offsets() []AppliedOffset {
+	var offsets []AppliedOffset
+	for tag, source := range i.offsetSources {
+		offsets = append(offsets, AppliedOffset{Tag: tag, Offset: i.fileOffsets[tag], Source: source, DriftPPM: i.drifts[tag].PPM(), Evidence: i.evidence[tag]})
+	}
+	sort.Slice(offsets, func(a, b int) bool { return offsets[a].Tag < offsets[b].Tag })
+	return offsets
+}
+
+// ReferenceTag returns the reference tag of the automatic alignment in the last Process run,
+// or "" when no tag was aligned automatically
+func (i *Interleaver) ReferenceTag() string {
+	return i.referenceTag
+}
+
+// InputFiles returns the files read by the last Process run
+func (i *Interleaver) InputFiles() []InputFile {
+	return i.inputFiles
+}
+
+// Process reads all log files, parses them, resolves timestamps, and returns sorted log lines.
+// It stops with the context's error when the context is cancelled.
+func (i *Interleaver) Process(ctx context.Context) ([]*parser.LogLine, error) {
+	// Determine the log files to read
+	files, err := i.collectFiles()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read log directory: %w", err)
+		return nil, err
 	}
 
 	// Map to store lines by tag
 	linesByTag := make(map[string][]*parser.LogLine)
 
 	// Process each log file
-	for _, file := range files {
-		if file.IsDir() {
-			continue
+	i.logger.Debug("Parsing log files", "files", len(files))
+	i.inputFiles = nil
+	i.gnssDeltas = make(map[string][]time.Duration)
+	for n, file := range files {
+		i.inputFiles = append(i.inputFiles, InputFile{Path: file.path, Tag: file.tag})
+		start := time.Now()
+		lines, err := i.parseFile(ctx, file)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to parse file %s: %w", filepath.Base(file.path), err)
 		}
+		i.logger.Debug("Parsed file", "file", filepath.Base(file.path), "tag", file.tag, "lines", len(lines),
+			"progress", fmt.Sprintf("%d/%d", n+1, len(files)), "elapsed", time.Since(start).Round(time.Millisecond).String())
+		tag := file.tag
 
-		// Skip non-text files
-		if !strings.HasSuffix(file.Name(), ".txt") {
+		// Several files may share a tag (e.g., rotated files)
+		linesByTag[tag] = append(linesByTag[tag], lines...)
+	}
+
+	// Resolve uptime (boot-relative) timestamps: from a known boot time where available,
+	// otherwise against the absolute timestamps in the same file
+	for tag, lines := range linesByTag {
+		if !parser.HasUnresolvedUptime(lines) {
 			continue
 		}
-
-		// Extract tag from filename (remove .txt extension)
-		tag := strings.TrimSuffix(file.Name(), ".txt")
-
-		filePath := filepath.Join(i.logDir, file.Name())
-		lines, err := i.parseFile(filePath, tag)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse file %s: %w", file.Name(), err)
+		bootTime, ok := i.bootTimes[tag]
+		if !ok && i.checkpoint != nil {
+			// The boot time resolved by the previous incremental run
+			bootTime, ok = i.checkpoint.BootTimes[tag]
+		}
+		if !ok {
+			bootTime = i.bootTimes[""]
+		}
+		parser.ResolveBootRelative(lines, bootTime)
+		if parser.HasUnresolvedUptime(lines) {
+			// Files without anchors are only an error for daemon, which is expected to have them
+			if err := parser.ResolveUptimeTimestamps(lines); err != nil && tag == "daemon" {
+				return nil, fmt.Errorf("failed to resolve uptime timestamps: %w", err)
+			}
+		}
+		if i.checkpoint != nil {
+			i.checkpoint.recordBootTime(tag, lines)
 		}
-
-		linesByTag[tag] = lines
 	}
 
-	// Resolve uptime timestamps for daemon.txt lines
-	if daemonLines, ok := linesByTag["daemon"]; ok && len(daemonLines) > 0 {
-		if err := parser.ResolveUptimeTimestamps(daemonLines); err != nil {
-			return nil, fmt.Errorf("failed to resolve uptime timestamps: %w", err)
+	// Tags start unaligned unless they have a manual offset
+	i.offsetSources = make(map[string]OffsetSource)
+	i.drifts = make(map[string]Drift)
+	i.anchorPoints = make(map[string][]anchorPoint)
+	i.evidence = make(map[string]string)
+	i.referenceTag = ""
+	for tag := range linesByTag {
+		i.offsetSources[tag] = OffsetNone
+		i.evidence[tag] = "automatic alignment disabled"
+		if _, hasManual := i.fileOffsets[tag]; hasManual {
+			i.offsetSources[tag] = OffsetManual
+			i.evidence[tag] = "given offset"
+		} else if points := i.findAnchors(tag, linesByTag); len(points) > 0 {
+			i.anchorPoints[tag] = points
+			i.offsetSources[tag] = OffsetAnchors
+			i.evidence[tag] = fmt.Sprintf("%d anchor lines matched with %s", len(points), anchorReferences(points))
+		} else if offset, ok := i.checkpoint.offset(tag); ok {
+			// Keep the alignment of the lines written by previous incremental runs
+			i.fileOffsets[tag] = offset
+			i.offsetSources[tag] = OffsetCheckpoint
+			i.evidence[tag] = "offset of the previous incremental runs"
+			if drift, ok := i.checkpoint.Drifts[tag]; ok {
+				i.drifts[tag] = drift
+			}
+		} else if offset, ok := i.gnssOffset(tag); ok {
+			i.fileOffsets[tag] = offset
+			i.offsetSources[tag] = OffsetGNSS
+			i.evidence[tag] = i.gnssEvidence(tag)
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Calculate automatic offsets if enabled
 	if i.autoAlign {
+		i.logger.Debug("Aligning sources", "tags", len(linesByTag), "method", string(i.alignMethod))
 		if err := i.calculateAutoOffsets(linesByTag); err != nil {
 			return nil, fmt.Errorf("failed to calculate auto offsets: %w", err)
 		}
 	}
 
+	// Anchor points follow their reference lines, now aligned
+	for tag := range i.anchorPoints {
+		i.resolveAnchors(tag)
+	}
+
 	// Apply offsets to all lines
 	var allLines []*parser.LogLine
 	for tag, lines := range linesByTag {
 		offset := i.fileOffsets[tag]
-		for _, line := range lines {
-			if line.Timestamp != nil {
-				line.Timestamp.Time = line.Timestamp.Time.Add(offset)
+		points := i.anchorPoints[tag]
+		drift, drifting := i.drifts[tag]
+		if i.checkpoint != nil && len(lines) > 0 {
+			i.checkpoint.Offsets[tag] = Duration(offset)
+			if drifting {
+				i.checkpoint.Drifts[tag] = drift
+			}
+		}
+		for k, line := range lines {
+			switch {
+			case line.Timestamp == nil:
+			case points != nil:
+				line.Timestamp.Time = line.Timestamp.Time.Add(anchorOffset(points, k, line.Timestamp.Time))
+			default:
+				t := line.Timestamp.Time
+				if drifting {
+					t = t.Add(drift.correction(t))
+				}
+				line.Timestamp.Time = t.Add(offset)
 			}
 			allLines = append(allLines, line)
 		}
 	}
 
-	// Sort by timestamp
-	sort.Slice(allLines, func(i, j int) bool {
-		tsI := allLines[i].GetTimestamp()
-		tsJ := allLines[j].GetTimestamp()
+	// Measure how far out of order each source was, as a streaming reorder buffer would see it
+	i.reorderStats = make(map[string]ReorderStats)
+	for tag, lines := range linesByTag {
+		buffer := NewReorderBuffer(i.reorderWindow)
+		for _, line := range lines {
+			buffer.Push(line)
+		}
+		i.reorderStats[tag] = buffer.Stats()[tag]
+	}
+
+	// Sort by timestamp, breaking ties deterministically
+	i.logger.Debug("Sorting lines", "lines", len(allLines))
+	sort.Slice(allLines, func(a, b int) bool {
+		tsA := allLines[a].GetTimestamp()
+		tsB := allLines[b].GetTimestamp()
 
 		// Lines without timestamps go to the end
-		if tsI == nil && tsJ == nil {
-			return allLines[i].LineNumber < allLines[j].LineNumber
-		}
-		if tsI == nil {
-			return false
+		if (tsA == nil) != (tsB == nil) {
+			return tsB == nil
 		}
-		if tsJ == nil {
-			return true
+		if tsA != nil && !tsA.Time.Equal(tsB.Time) {
+			return tsA.Time.Before(tsB.Time)
 		}
 
-		return tsI.Time.Before(tsJ.Time)
+		return i.lineLess(allLines[a], allLines[b])
 	})
 
+	// Drop duplicate lines from overlapping captures
+	i.duplicates = 0
+	if i.dedupe {
+		allLines = i.removeDuplicates(allLines)
+	}
+
 	return allLines, nil
 }
 
-// calculateAutoOffsets calculates timezone offsets automatically based on first timestamps
+// lineLess orders lines with equal timestamps: by tag priority, then tag name, file name,
+// and line number
+func (i *Interleaver) lineLess(a, b *parser.LogLine) bool {
+	if a.Tag != b.Tag {
+		rankA, listedA := i.tagPriority[a.Tag]
+		rankB, listedB := i.tagPriority[b.Tag]
+		if listedA != listedB {
+			return listedA
+		}
+		if listedA && rankA != rankB {
+			return rankA < rankB
+		}
+		return a.Tag < b.Tag
+	}
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	return a.LineNumber < b.LineNumber
+}
+
+// removeDuplicates drops lines with the same timestamp, tag, and content as an earlier line.
+// Lines must be sorted by timestamp. Lines without timestamps are kept, since identical
+// untimestamped lines (e.g., blank lines) are not necessarily duplicates.
+func (i *Interleaver) removeDuplicates(lines []*parser.LogLine) []*parser.LogLine {
+	result := lines[:0]
+	var groupTime time.Time
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		ts := line.GetTimestamp()
+		if ts == nil {
+			result = append(result, line)
+			continue
+		}
+
+		// Duplicates share a timestamp, so only lines within the same timestamp group are compared
+		if !ts.Time.Equal(groupTime) {
+			groupTime = ts.Time
+			clear(seen)
+		}
+		key := line.Tag + "\x00" + line.OriginalLine
+		if seen[key] {
+			i.duplicates++
+			continue
+		}
+		seen[key] = true
+		result = append(result, line)
+	}
+	return result
+}
+
+// evidenceTimeLayout formats the times in the evidence of offsets
+const evidenceTimeLayout = "2006-01-02 15:04:05.000"
+
+// calculateAutoOffsets calculates timezone offsets automatically, by correlating shared events
+// and/or aligning first timestamps depending on the align method.
 // Prefers daemon as reference, otherwise uses the file with the most timestamps
 func (i *Interleaver) calculateAutoOffsets(linesByTag map[string][]*parser.LogLine) error {
 	// Prefer daemon as reference, otherwise find the file with the most timestamps
@@ -141,6 +538,7 @@ func (i *Interleaver) calculateAutoOffsets(linesByTag map[string][]*parser.LogLi
 	}
 
 	// If daemon not found or has no timestamps, use the file with most timestamps
+	referenceEvidence := "daemon log"
 	if referenceTime == nil {
 		maxTimestampCount := 0
 		for tag, lines := range linesByTag {
@@ -155,31 +553,93 @@ func (i *Interleaver) calculateAutoOffsets(linesByTag map[string][]*parser.LogLi
 					}
 				}
 			}
-			if count > maxTimestampCount && firstTime != nil {
+			// Ties go to the alphabetically first tag, so the reference doesn't depend on map order.
+			// Anchored GNSS tags are already on the GNSS time.
+			if firstTime != nil && i.offsetSources[tag] != OffsetGNSS && i.offsetSources[tag] != OffsetAnchors && (count > maxTimestampCount || (count == maxTimestampCount && tag < referenceTag)) {
 				maxTimestampCount = count
 				referenceTime = firstTime
 				referenceTag = tag
 			}
 		}
+		referenceEvidence = fmt.Sprintf("most timestamps (%d)", maxTimestampCount)
 	}
 
+	// Tags not aligned below have no timestamps to align
+	for tag, source := range i.offsetSources {
+		if source == OffsetNone {
+			i.evidence[tag] = "no timestamps"
+		}
+	}
 	if referenceTime == nil {
 		// No timestamps found, nothing to align
 		return nil
 	}
+	i.referenceTag = referenceTag
 
 	// Calculate offsets for each tag (skip reference tag)
 	for tag, lines := range linesByTag {
-		// Skip if manual offset already set
-		if _, hasManual := i.fileOffsets[tag]; hasManual {
+		// Skip if manual offset already set or the tag is anchored
+		if _, hasManual := i.fileOffsets[tag]; hasManual || i.offsetSources[tag] == OffsetAnchors {
+			continue
+		}
+
+		// Skip tags with a declared timezone (already converted to UTC)
+		if location, hasTimezone := i.timezones[tag]; hasTimezone {
+			i.offsetSources[tag] = OffsetTimezone
+			i.evidence[tag] = "declared timezone " + location.String()
+			continue
+		}
+
+		// Skip tags whose timestamps all carry their zone (RFC 3339 with offset, epoch)
+		if allZoned(lines) {
+			i.offsetSources[tag] = OffsetTimezone
+			i.evidence[tag] = "all timestamps carry their zone"
 			continue
 		}
 
 		// Skip reference tag (no offset needed)
 		if tag == referenceTag {
+			i.offsetSources[tag] = OffsetReference
+			i.evidence[tag] = referenceEvidence
 			continue
 		}
 
+		// Correlate shared events with the reference if enabled
+		var fallback string
+		if i.alignMethod != AlignFirstTimestamp {
+			offset, votes, pairs, ok := correlateEvents(linesByTag[referenceTag], lines)
+			if ok {
+				i.evidence[tag] = fmt.Sprintf("%d of %d shared event pairs agree on %s", votes, pairs, offset)
+				if i.driftCorrection {
+					if drift, centerOffset, fitted, ok := fitDrift(linesByTag[referenceTag], lines, offset); ok {
+						offset = centerOffset
+						i.drifts[tag] = drift
+						i.evidence[tag] += fmt.Sprintf(", drift fitted to %d pairs", fitted)
+						i.logger.Debug("Fitted clock drift", "tag", tag, "ppm", drift.PPM(), "offset", centerOffset.String())
+					}
+				}
+				if i.alignRounding > 0 {
+					if rounded := offset.Round(i.alignRounding); rounded != offset {
+						i.evidence[tag] += fmt.Sprintf(", %s rounded to %s", offset, rounded)
+						offset = rounded
+					}
+				}
+				i.fileOffsets[tag] = offset
+				i.offsetSources[tag] = OffsetEvents
+				continue
+			}
+			if pairs == 0 {
+				fallback = "no events shared with " + referenceTag
+			} else {
+				fallback = fmt.Sprintf("%d shared event pairs, at most %d agreeing (%d needed)", pairs, votes, minEventVotes)
+			}
+			i.evidence[tag] = fallback
+			if i.alignMethod == AlignEvents {
+				// No fallback: leave the file unaligned
+				continue
+			}
+		}
+
 		// Find first timestamp in this file
 		var firstTime *time.Time
 		for _, line := range lines {
@@ -194,51 +654,273 @@ func (i *Interleaver) calculateAutoOffsets(linesByTag map[string][]*parser.LogLi
 		if firstTime != nil {
 			// Calculate offset needed to align with reference
 			offset := referenceTime.Sub(*firstTime)
-			// Round to nearest hour for cleaner alignment
-			offsetHours := offset.Hours()
-			roundedHours := float64(int(offsetHours + 0.5))
-			i.fileOffsets[tag] = time.Duration(roundedHours * float64(time.Hour))
+			i.evidence[tag] = fmt.Sprintf("first timestamp %s, %s in %s", firstTime.Format(evidenceTimeLayout), referenceTime.Format(evidenceTimeLayout), referenceTag)
+			// Round (to the nearest hour by default) for cleaner alignment
+			if i.alignRounding > 0 {
+				if rounded := offset.Round(i.alignRounding); rounded != offset {
+					i.evidence[tag] += fmt.Sprintf(", %s rounded to %s", offset, rounded)
+					offset = rounded
+				}
+			}
+			i.fileOffsets[tag] = offset
+			i.offsetSources[tag] = OffsetFirstTimestamp
+			if fallback != "" {
+				i.evidence[tag] = fallback + "; " + i.evidence[tag]
+			}
 		}
 	}
 
 	return nil
 }
 
-// parseFile reads and parses a single log file
-func (i *Interleaver) parseFile(filePath, tag string) ([]*parser.LogLine, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+// allZoned reports whether all timestamps of the lines are unambiguous instants
+// (with an explicit UTC offset, or epoch), which need no timezone alignment
+func allZoned(lines []*parser.LogLine) bool {
+	found := false
+	for _, line := range lines {
+		if ts := line.Timestamp; ts != nil {
+			if !ts.Zoned && ts.Type != timestamp.TypeLinux {
+				return false
+			}
+			found = true
+		}
 	}
-	defer file.Close()
+	return found
+}
 
-	p := parser.NewParser(tag)
+// parseFile reads and parses a single log file, or the stream of a reader source
+func (i *Interleaver) parseFile(ctx context.Context, source logFile) ([]*parser.LogLine, error) {
+	filePath, tag := source.path, source.tag
+	var file *os.File
+	var input io.Reader = source.reader
+	if input == nil {
+		var err error
+		if file, err = os.Open(filePath); err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+	// Packet captures are read in full as the lines of their PTP messages, CSV files as the
+	// JSON lines of their rows
+	converted := file != nil && pcap.IsCapture(filePath)
+	if converted {
+		data, err := pcap.Lines(file)
+		if err != nil {
+			return nil, err
+		}
+		input = bytes.NewReader(data)
+	}
+	if csvSource, ok := i.csvSources[tag]; ok {
+		data, err := csvsource.Lines(input, csvSource)
+		if err != nil {
+			return nil, err
+		}
+		input = bytes.NewReader(data)
+		converted = true
+	}
+
+	p := i.newParser(tag)
 	var lines []*parser.LogLine
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(input)
 	lineNum := 1
+
+	// Incremental runs continue after the complete lines read before, leaving an incomplete
+	// last line (still being written) for the next run. Streams are read in full.
+	var state *FileCheckpoint
+	var consumed int64
+	if i.checkpoint != nil && file != nil && !converted {
+		var err error
+		if state, err = i.checkpoint.resume(file, filePath, tag); err != nil {
+			return nil, err
+		}
+		lineNum = state.Lines + 1
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			if atEOF && !bytes.Contains(data, []byte{'\n'}) {
+				return 0, nil, nil
+			}
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			consumed += int64(advance)
+			return advance, token, err
+		})
+	}
 	for scanner.Scan() {
 		line := scanner.Text()
 		logLine := p.ParseLine(line, lineNum)
+		logLine.File = filepath.Base(filePath)
 		lines = append(lines, logLine)
+		if lineNum%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if lineNum%progressInterval == 0 {
+			i.logger.Debug("Parsing file", "file", filepath.Base(filePath), "lines", lineNum)
+		}
 		lineNum++
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
+	if state != nil {
+		if err := state.advance(file, consumed, len(lines)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.resolveTimestamps(ctx, tag, lines); err != nil {
+		return nil, err
+	}
 
+	// Fold continuation lines into their entries
+	if i.groupContinuations {
+		lines = parser.GroupContinuations(lines)
+	}
+
+	// Correct assumed years using the file's own context; streams are written up to now
+	modTime := source.modTime
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	if file != nil {
+		modTime = time.Time{}
+		if info, err := file.Stat(); err == nil {
+			modTime = info.ModTime()
+		}
+	}
+	// Incremental runs continue the years of the part read before
+	baseYear := i.baseYear
+	first, last := -1, -1
+	if state != nil {
+		first, last = yearlessLines(lines)
+		if state.Yearless != nil && first >= 0 {
+			baseYear = parser.NextYear(*state.Yearless, lines[first].Timestamp.Time)
+		}
+	}
+	parser.InferYears(lines, baseYear, modTime)
+	if state != nil && last >= 0 {
+		yearless := lines[last].Timestamp.Time
+		state.Yearless = &yearless
+	}
+
+	// Convert local wall-clock timestamps to UTC (after year inference, which DST depends on)
+	if loc, ok := i.timezones[tag]; ok {
+		for _, line := range lines {
+			if ts := line.Timestamp; ts != nil && ts.Type == timestamp.TypeAbsolute && !ts.Zoned {
+				ts.Time = timestamp.InLocation(ts.Time, loc)
+			}
+		}
+	}
+
+	i.anchorGNSS(tag, lines)
 	return lines, nil
 }
 
-// FormatLine formats a log line for output with timestamp prefix
-func FormatLine(line *parser.LogLine) string {
+// newParser creates a line parser with the timestamp formats and fields of a tag
+func (i *Interleaver) newParser(tag string) *parser.Parser {
+	p := parser.NewParser(tag)
+	for _, format := range i.formats[tag] {
+		p.AddFormat(format)
+	}
+	for _, field := range i.jsonFields[tag] {
+		p.AddJSONField(field)
+	}
+	if tag != "" {
+		for _, format := range i.formats[""] {
+			p.AddFormat(format)
+		}
+		for _, field := range i.jsonFields[""] {
+			p.AddJSONField(field)
+		}
+	}
+	return p
+}
+
+// resolveTimestamps sets the timestamps the external parser of the tag, if any, finds in the lines
+func (i *Interleaver) resolveTimestamps(ctx context.Context, tag string, lines []*parser.LogLine) error {
+	resolver, ok := i.resolvers[tag]
+	if !ok {
+		if resolver, ok = i.resolvers[""]; !ok {
+			return nil
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	text := make([]string, len(lines))
+	for k, line := range lines {
+		text[k] = line.OriginalLine
+	}
+	times, err := resolver.ResolveTimestamps(ctx, tag, text)
+	if err != nil {
+		return err
+	}
+	for k, t := range times {
+		if !t.IsZero() && k < len(lines) {
+			lines[k].Timestamp = &timestamp.Timestamp{Time: t, Type: timestamp.TypeAbsolute, Zoned: true}
+			lines[k].UptimeSec, lines[k].HasUptime = 0, false
+		}
+	}
+	return nil
+}
+
+// FormatLine formats a log line for output with timestamp prefix.
+// timeFormat is a Go time layout for the prefix; empty uses timestamp.DefaultOutputFormat.
+func FormatLine(line *parser.LogLine, timeFormat string) string {
 	ts := line.GetTimestamp()
 	if ts == nil {
 		// Lines without timestamps keep original format
 		return line.OriginalLine
 	}
 
-	timeStr := timestamp.FormatTimestamp(ts.Time)
-	return fmt.Sprintf("%s %s %s", timeStr, line.Tag, line.OriginalLine)
+	timeStr := timestamp.FormatTimestampLayout(ts.Time, timeFormat)
+	formatted := fmt.Sprintf("%s %s %s", timeStr, line.Tag, line.OriginalLine)
+
+	// Continuation lines follow their entry unprefixed, as in the source file
+	for _, continuation := range line.Continuation {
+		formatted += "\n" + continuation
+	}
+	return formatted
+}
+
+// JSONLine is the JSON Lines representation of an interleaved log line
+type JSONLine struct {
+	Time         *time.Time `json:"time"`                   // Resolved timestamp (null for lines without one)
+	Tag          string     `json:"tag"`                    // File tag
+	File         string     `json:"file"`                   // Source file name
+	SourceLine   int        `json:"source_line"`            // Line number in the source file
+	UptimeSec    *float64   `json:"uptime_sec"`             // Uptime value for uptime lines (null otherwise)
+	Raw          string     `json:"raw"`                    // Original line
+	Continuation []string   `json:"continuation,omitempty"` // Grouped continuation lines
+}
+
+// FormatLineJSON formats a log line as a single-line JSON object (one JSON Lines record)
+func FormatLineJSON(line *parser.LogLine) (string, error) {
+	data, err := json.Marshal(NewJSONLine(line))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode line: %w", err)
+	}
+	return string(data), nil
+}
+
+// NewJSONLine returns the JSON Lines representation of a log line
+func NewJSONLine(line *parser.LogLine) JSONLine {
+	record := JSONLine{
+		Tag:          line.Tag,
+		File:         line.File,
+		SourceLine:   line.LineNumber,
+		Raw:          line.OriginalLine,
+		Continuation: line.Continuation,
+	}
+	if ts := line.GetTimestamp(); ts != nil {
+		t := ts.Time.UTC()
+		record.Time = &t
+	}
+	if line.HasUptime {
+		uptime := line.UptimeSec
+		record.UptimeSec = &uptime
+	}
+	return record
 }
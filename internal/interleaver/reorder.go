@@ -0,0 +1,144 @@
+package interleaver
+
+import (
+	"container/heap"
+	"log-interleaver/internal/parser"
+	"time"
+)
+
+// ReorderStats summarizes how much reordering a source needed
+type ReorderStats struct {
+	Lines       int           // Timestamped lines seen
+	Reordered   int           // Lines that arrived after a line with a later timestamp
+	MaxLateness time.Duration // Largest amount a line arrived behind the newest line of its source
+	Late        int           // Lines that arrived too late for the window and were emitted out of order
+}
+
+// ReorderBuffer holds lines for a short time window so that slightly out-of-order lines
+// (e.g., from buffered writers in multi-threaded daemons) are emitted in timestamp order
+// when processing a stream. A line is held until a line at least the window newer arrives,
+// so the first out-of-order lines of a source are already sorted.
+type ReorderBuffer struct {
+	window      time.Duration
+	pending     lineHeap
+	seq         int
+	newest      time.Time            // Newest timestamp pushed across all sources
+	newestByTag map[string]time.Time // Newest timestamp pushed per source
+	lastEmitted time.Time
+	stats       map[string]*ReorderStats
+}
+
+// NewReorderBuffer creates a reorder buffer with the given window
+func NewReorderBuffer(window time.Duration) *ReorderBuffer {
+	return &ReorderBuffer{
+		window:      window,
+		newestByTag: make(map[string]time.Time),
+		stats:       make(map[string]*ReorderStats),
+	}
+}
+
+// Push adds a line to the buffer and returns the lines that have left the window, in order.
+// Lines without a timestamp are kept with the newest timestamp of their source.
+func (b *ReorderBuffer) Push(line *parser.LogLine) []*parser.LogLine {
+	stats, ok := b.stats[line.Tag]
+	if !ok {
+		stats = &ReorderStats{}
+		b.stats[line.Tag] = stats
+	}
+
+	var t time.Time
+	if ts := line.GetTimestamp(); ts != nil {
+		t = ts.Time
+		stats.Lines++
+
+		if newest, ok := b.newestByTag[line.Tag]; ok && t.Before(newest) {
+			lateness := newest.Sub(t)
+			stats.Reordered++
+			if lateness > stats.MaxLateness {
+				stats.MaxLateness = lateness
+			}
+		} else {
+			b.newestByTag[line.Tag] = t
+		}
+		if t.After(b.newest) {
+			b.newest = t
+		}
+		if !b.lastEmitted.IsZero() && t.Before(b.lastEmitted) {
+			stats.Late++
+		}
+	} else {
+		t = b.newestByTag[line.Tag]
+	}
+
+	heap.Push(&b.pending, pendingLine{line: line, time: t, seq: b.seq})
+	b.seq++
+
+	return b.emit(b.newest.Add(-b.window))
+}
+
+// Flush returns all remaining buffered lines in order
+func (b *ReorderBuffer) Flush() []*parser.LogLine {
+	var out []*parser.LogLine
+	for b.pending.Len() > 0 {
+		out = append(out, b.pop())
+	}
+	return out
+}
+
+// Window returns the window of the buffer
+func (b *ReorderBuffer) Window() time.Duration {
+	return b.window
+}
+
+// Stats returns the reordering statistics per tag
+func (b *ReorderBuffer) Stats() map[string]ReorderStats {
+	stats := make(map[string]ReorderStats, len(b.stats))
+	for tag, s := range b.stats {
+		stats[tag] = *s
+	}
+	return stats
+}
+
+// emit pops all lines at or before the cutoff
+func (b *ReorderBuffer) emit(cutoff time.Time) []*parser.LogLine {
+	var out []*parser.LogLine
+	for b.pending.Len() > 0 && !b.pending[0].time.After(cutoff) {
+		out = append(out, b.pop())
+	}
+	return out
+}
+
+func (b *ReorderBuffer) pop() *parser.LogLine {
+	p := heap.Pop(&b.pending).(pendingLine)
+	if p.time.After(b.lastEmitted) {
+		b.lastEmitted = p.time
+	}
+	return p.line
+}
+
+// pendingLine is a buffered line with its ordering key
+type pendingLine struct {
+	line *parser.LogLine
+	time time.Time
+	seq  int // Arrival order, used to keep equal timestamps stable
+}
+
+// lineHeap is a min-heap of pending lines ordered by time, then arrival
+type lineHeap []pendingLine
+
+func (h lineHeap) Len() int { return len(h) }
+func (h lineHeap) Less(i, j int) bool {
+	if !h[i].time.Equal(h[j].time) {
+		return h[i].time.Before(h[j].time)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h lineHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *lineHeap) Push(x interface{}) { *h = append(*h, x.(pendingLine)) }
+func (h *lineHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,85 @@
+package interleaver
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/timestamp"
+)
+
+// reorderLine returns a line of the tag at the seconds after eventStart, with the text as message
+func reorderLine(tag, text string, seconds float64) *parser.LogLine {
+	t := eventStart.Add(time.Duration(seconds * float64(time.Second)))
+	return &parser.LogLine{
+		OriginalLine: text,
+		Tag:          tag,
+		Timestamp:    &timestamp.Timestamp{Time: t, Type: timestamp.TypeAbsolute},
+	}
+}
+
+func TestReorderBuffer(t *testing.T) {
+	type pushed struct {
+		text    string
+		seconds float64
+	}
+	tests := []struct {
+		name      string
+		window    time.Duration
+		lines     []pushed
+		want      string // Texts in the order emitted
+		wantStats ReorderStats
+	}{
+		{
+			// The first lines are out of order before any lateness was observed
+			name:      "first lines out of order",
+			window:    time.Second,
+			lines:     []pushed{{"c", 3}, {"a", 1}, {"b", 2}},
+			want:      "abc",
+			wantStats: ReorderStats{Lines: 3, Reordered: 2, MaxLateness: 2 * time.Second},
+		},
+		{
+			name:      "within the window",
+			window:    5 * time.Second,
+			lines:     []pushed{{"c", 3}, {"a", 1}, {"b", 2}, {"d", 4}},
+			want:      "abcd",
+			wantStats: ReorderStats{Lines: 4, Reordered: 2, MaxLateness: 2 * time.Second},
+		},
+		{
+			name:      "in order",
+			window:    time.Second,
+			lines:     []pushed{{"a", 1}, {"b", 1}, {"c", 2}, {"d", 5}},
+			want:      "abcd",
+			wantStats: ReorderStats{Lines: 4},
+		},
+		{
+			// A line later than the window comes out after the newer lines already emitted
+			name:      "beyond the window",
+			window:    time.Second,
+			lines:     []pushed{{"a", 1}, {"b", 3}, {"c", 5}, {"x", 2}},
+			want:      "abxc",
+			wantStats: ReorderStats{Lines: 4, Reordered: 1, MaxLateness: 3 * time.Second, Late: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewReorderBuffer(tt.window)
+			var got []string
+			for _, p := range tt.lines {
+				for _, line := range b.Push(reorderLine("ptp4l", p.text, p.seconds)) {
+					got = append(got, line.OriginalLine)
+				}
+			}
+			for _, line := range b.Flush() {
+				got = append(got, line.OriginalLine)
+			}
+			if strings.Join(got, "") != tt.want {
+				t.Errorf("emitted %q, want %q", strings.Join(got, ""), tt.want)
+			}
+			if stats := b.Stats()["ptp4l"]; stats != tt.wantStats {
+				t.Errorf("stats %+v, want %+v", stats, tt.wantStats)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package interleaver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/timestamp"
+	"time"
+)
+
+// Stream merges a stream of a tag, read line by line as it is written (e.g., a followed pod log
+// piped to stdin), into the sorted lines of the other sources as returned by Process, passing
+// the merged lines to emit in timestamp order as soon as the reorder window allows. Lines of the
+// stream up to the reorder window out of order are emitted in order; later lines are emitted as
+// they come and counted as late in the ReorderStats of the tag. When the stream is idle for the
+// reorder window, the buffered lines are emitted.
+//
+// The stream is aligned by the manual offset of its tag only, and its timestamps are resolved
+// line by line: wall-clock timestamps in the timezone of the tag, yearless timestamps in the
+// current year, and uptimes from the boot time of the tag. It ends at the end of the stream, with
+// the remaining lines of the other sources, or with the context's error when it is cancelled.
+func (i *Interleaver) Stream(ctx context.Context, lines []*parser.LogLine, name, tag string, r io.Reader, emit func(*parser.LogLine) error) error {
+	type scanned struct {
+		text string
+		err  error
+		eof  bool
+	}
+	// Reading blocks until the writer produces a line, so it does not hold up cancellation
+	input := make(chan scanned)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case input <- scanned{text: scanner.Text()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case input <- scanned{err: scanner.Err(), eof: true}:
+		case <-ctx.Done():
+		}
+	}()
+
+	buffer := NewReorderBuffer(i.reorderWindow)
+	next := 0          // Next line of the other sources to emit
+	var last time.Time // Timestamp of the last stream line emitted
+	send := func(streamed []*parser.LogLine) error {
+		for _, line := range streamed {
+			if ts := line.GetTimestamp(); ts != nil {
+				last = ts.Time
+			}
+			for ; next < len(lines); next++ {
+				ts := lines[next].GetTimestamp()
+				if ts == nil || ts.Time.After(last) || ts.Time.Equal(last) && !i.lineLess(lines[next], line) {
+					break
+				}
+				if err := emit(lines[next]); err != nil {
+					return err
+				}
+			}
+			if err := emit(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	p := i.newParser(tag)
+	bootTime, ok := i.bootTimes[tag]
+	if !ok {
+		bootTime = i.bootTimes[""]
+	}
+	var idle <-chan time.Time
+	for lineNum := 1; ; {
+		var in scanned
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-idle:
+			idle = nil
+			if err := send(buffer.Flush()); err != nil {
+				return err
+			}
+			continue
+		case in = <-input:
+		}
+		if in.eof {
+			if in.err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, in.err)
+			}
+			break
+		}
+
+		line := p.ParseLine(in.text, lineNum)
+		line.File = name
+		lineNum++
+		if err := i.resolveStreamed(ctx, tag, line, bootTime); err != nil {
+			return err
+		}
+		if err := send(buffer.Push(line)); err != nil {
+			return err
+		}
+		idle = time.After(i.reorderWindow)
+	}
+
+	if err := send(buffer.Flush()); err != nil {
+		return err
+	}
+	for ; next < len(lines); next++ {
+		if err := emit(lines[next]); err != nil {
+			return err
+		}
+	}
+	if i.reorderStats == nil {
+		i.reorderStats = make(map[string]ReorderStats)
+	}
+	i.reorderStats[tag] = buffer.Stats()[tag]
+	return nil
+}
+
+// resolveStreamed resolves the timestamp of a line of a stream as Process does for the lines of
+// a file, as far as a single line allows, and applies the manual offset of the tag
+func (i *Interleaver) resolveStreamed(ctx context.Context, tag string, line *parser.LogLine, bootTime time.Time) error {
+	single := []*parser.LogLine{line}
+	if err := i.resolveTimestamps(ctx, tag, single); err != nil {
+		return err
+	}
+	if line.Timestamp == nil && !bootTime.IsZero() {
+		parser.ResolveBootRelative(single, bootTime)
+	}
+	// The line is written about now
+	parser.InferYears(single, i.baseYear, time.Now())
+	ts := line.Timestamp
+	if ts == nil {
+		return nil
+	}
+	if loc, ok := i.timezones[tag]; ok && ts.Type == timestamp.TypeAbsolute && !ts.Zoned {
+		ts.Time = timestamp.InLocation(ts.Time, loc)
+	}
+	ts.Time = ts.Time.Add(i.fileOffsets[tag])
+	return nil
+}
@@ -0,0 +1,179 @@
+package interleaver
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TagRule maps log file names to tags
+type TagRule struct {
+	glob  string
+	regex *regexp.Regexp
+	tag   string
+}
+
+// NewTagRule creates a rule mapping file names matching a glob (e.g., "ptp4l-eth0-*.log")
+// or an anchored regex to a tag. With a regex, the tag may reference capture groups ("$1", "${name}").
+func NewTagRule(glob, pattern, tag string) (*TagRule, error) {
+	if tag == "" {
+		return nil, fmt.Errorf("tag rule requires a tag")
+	}
+	if (glob == "") == (pattern == "") {
+		return nil, fmt.Errorf("tag rule for '%s' requires exactly one of glob or regex", tag)
+	}
+
+	rule := &TagRule{glob: glob, tag: tag}
+	if glob != "" {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob '%s': %w", glob, err)
+		}
+	} else {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex '%s': %w", pattern, err)
+		}
+		rule.regex = re
+	}
+
+	return rule, nil
+}
+
+// Match returns the tag for the file name if the rule matches it
+func (r *TagRule) Match(filename string) (string, bool) {
+	if r.regex == nil {
+		ok, _ := filepath.Match(r.glob, filename)
+		return r.tag, ok
+	}
+
+	matches := r.regex.FindStringSubmatchIndex(filename)
+	if matches == nil {
+		return "", false
+	}
+	return string(r.regex.ExpandString(nil, r.tag, filename, matches)), true
+}
+
+// AddTagRule registers a file name to tag mapping rule. Rules are tried in registration order;
+// files matching a rule are read regardless of their extension.
+func (i *Interleaver) AddTagRule(rule *TagRule) {
+	i.tagRules = append(i.tagRules, rule)
+}
+
+// SetFiles sets an explicit list of log files to read instead of scanning the log directory
+func (i *Interleaver) SetFiles(paths []string) {
+	i.files = paths
+}
+
+// SetExtensions sets the file extensions (e.g., ".txt", ".log") read when scanning the log
+// directory. An empty list reads all files.
+func (i *Interleaver) SetExtensions(extensions []string) {
+	i.extensions = make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		i.extensions = append(i.extensions, ext)
+	}
+}
+
+// logFile is a log file or reader source to read with its tag
+type logFile struct {
+	path   string
+	tag    string
+	reader io.Reader // Stream of a reader source, read instead of the file at path
+	// Modification time of a reader source, the reference for yearless timestamps (zero = now)
+	modTime time.Time
+}
+
+// readerSource is a stream added with AddReader
+type readerSource struct {
+	name    string
+	tag     string
+	reader  io.Reader
+	modTime time.Time
+}
+
+// AddReader adds a stream (e.g., standard input or a file of an archive) as a source with the
+// given tag, which is read to its end and merged with the log files. The name identifies the
+// source in the File of its lines and in InputFiles. The modification time is the reference for
+// yearless timestamps, like that of a log file; zero means the stream is written up to now.
+func (i *Interleaver) AddReader(name, tag string, r io.Reader, modTime time.Time) {
+	i.readers = append(i.readers, readerSource{name: name, tag: tag, reader: r, modTime: modTime})
+}
+
+// FileTag returns the tag of a file name: from the tag rules, or the name without extension.
+// It tags sources added with AddReader like the log files of a directory.
+func (i *Interleaver) FileTag(filename string) string {
+	tag, _ := i.tagForFile(filename, true)
+	return tag
+}
+
+// collectFiles returns the explicit file list, or the log files found in the log directory
+func (i *Interleaver) collectFiles() ([]logFile, error) {
+	var files []logFile
+	for _, r := range i.readers {
+		files = append(files, logFile{path: r.name, tag: r.tag, reader: r.reader, modTime: r.modTime})
+	}
+
+	if len(i.files) > 0 {
+		for _, path := range i.files {
+			tag, _ := i.tagForFile(filepath.Base(path), true)
+			files = append(files, logFile{path: path, tag: tag})
+		}
+		return files, nil
+	}
+	if i.logDir == "" {
+		// Reader sources only
+		return files, nil
+	}
+
+	entries, err := os.ReadDir(i.logDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		// Determine the tag from tag rules or the filename (skipping unrelated files)
+		tag, ok := i.tagForFile(entry.Name(), false)
+		if !ok {
+			continue
+		}
+		files = append(files, logFile{path: filepath.Join(i.logDir, entry.Name()), tag: tag})
+	}
+
+	return files, nil
+}
+
+// tagForFile determines the tag of a log file and whether the file should be read.
+// Explicitly listed files are always read.
+func (i *Interleaver) tagForFile(filename string, explicit bool) (string, bool) {
+	for _, rule := range i.tagRules {
+		if tag, ok := rule.Match(filename); ok {
+			return tag, true
+		}
+	}
+
+	// Default: files with an allowed extension are tagged with their name without extension,
+	// and CSV files of the tags with a CSV source are read as well
+	ext := filepath.Ext(filename)
+	tag := strings.TrimSuffix(filename, ext)
+	if _, csv := i.csvSources[tag]; csv && ext == ".csv" {
+		return tag, true
+	}
+	if !explicit && len(i.extensions) > 0 && !slices.Contains(i.extensions, ext) {
+		return "", false
+	}
+	return tag, true
+}
@@ -0,0 +1,61 @@
+package interleaver
+
+import (
+	"fmt"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/timestamp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateLine is the data of a line in output line templates
+type TemplateLine struct {
+	Time       string     // Timestamp formatted with the time format ("" for lines without one)
+	Timestamp  *time.Time // Resolved timestamp for other formats (e.g., {{with .Timestamp}}{{.UnixNano}}{{end}}); nil for lines without one
+	Tag        string
+	File       string            // Source file name
+	LineNumber int               // Line number in the source file
+	Raw        string            // Original line
+	Fields     map[string]string // Fields of structured (JSON) lines by dotted path
+}
+
+// LineTemplate formats output lines with a Go template instead of the fixed FormatLine prefix
+type LineTemplate struct {
+	tmpl       *template.Template
+	timeFormat string
+}
+
+// NewLineTemplate parses an output line template, e.g. "{{.Time}} [{{.Tag}}:{{.LineNumber}}] {{.Raw}}".
+// timeFormat is the Go time layout of .Time; empty uses timestamp.DefaultOutputFormat.
+func NewLineTemplate(text, timeFormat string) (*LineTemplate, error) {
+	tmpl, err := template.New("line").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid line template: %w", err)
+	}
+	return &LineTemplate{tmpl: tmpl, timeFormat: timeFormat}, nil
+}
+
+// Format formats a line with the template. Continuation lines follow it unprefixed, as in the
+// source file.
+func (t *LineTemplate) Format(line *parser.LogLine) (string, error) {
+	data := TemplateLine{
+		Tag:        line.Tag,
+		File:       line.File,
+		LineNumber: line.LineNumber,
+		Raw:        line.OriginalLine,
+		Fields:     line.Fields,
+	}
+	if ts := line.GetTimestamp(); ts != nil {
+		data.Time = timestamp.FormatTimestampLayout(ts.Time, t.timeFormat)
+		data.Timestamp = &ts.Time
+	}
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to format line %s:%d: %w", line.File, line.LineNumber, err)
+	}
+	for _, continuation := range line.Continuation {
+		sb.WriteString("\n" + continuation)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,154 @@
+// Package kube reads pod logs through the Kubernetes API, for collecting the logs of a
+// cluster's PTP daemons without kubectl or oc
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Client is a minimal Kubernetes API client for listing pods and reading their logs
+type Client struct {
+	Namespace string // Namespace of the kubeconfig context or service account, if any
+
+	server   string
+	token    string
+	username string
+	password string
+	http     *http.Client
+}
+
+// Pod is a pod with the containers whose logs can be read
+type Pod struct {
+	Name       string
+	Namespace  string
+	Node       string
+	Containers []Container
+}
+
+// Container is a container of a pod
+type Container struct {
+	Name     string
+	Restarts int // A container that restarted has the logs of its previous instance
+}
+
+// LogOptions selects the part of a container log to read
+type LogOptions struct {
+	Previous   bool          // Read the log of the previous (terminated) instance
+	Since      time.Duration // Only lines newer than this (0 = all)
+	TailLines  int           // Only the last lines (0 = all)
+	Timestamps bool          // Prefix each line with the RFC 3339 time the kubelet received it
+}
+
+// ListPods returns the pods of a namespace matching a label selector (empty = all pods)
+func (c *Client) ListPods(ctx context.Context, namespace, selector string) ([]Pod, error) {
+	query := url.Values{}
+	if selector != "" {
+		query.Set("labelSelector", selector)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	body, err := c.get(ctx, "/api/v1/namespaces/"+url.PathEscape(namespace)+"/pods", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	defer body.Close()
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName   string `json:"nodeName"`
+				Containers []struct {
+					Name string `json:"name"`
+				} `json:"containers"`
+			} `json:"spec"`
+			Status struct {
+				ContainerStatuses []struct {
+					Name         string `json:"name"`
+					RestartCount int    `json:"restartCount"`
+				} `json:"containerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to parse pod list: %w", err)
+	}
+
+	pods := make([]Pod, 0, len(list.Items))
+	for _, item := range list.Items {
+		restarts := make(map[string]int)
+		for _, status := range item.Status.ContainerStatuses {
+			restarts[status.Name] = status.RestartCount
+		}
+		pod := Pod{Name: item.Metadata.Name, Namespace: item.Metadata.Namespace, Node: item.Spec.NodeName}
+		for _, container := range item.Spec.Containers {
+			pod.Containers = append(pod.Containers, Container{Name: container.Name, Restarts: restarts[container.Name]})
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// Logs opens the log of a container. The caller must close the returned stream.
+func (c *Client) Logs(ctx context.Context, namespace, pod, container string, opts LogOptions) (io.ReadCloser, error) {
+	query := url.Values{"container": {container}}
+	if opts.Previous {
+		query.Set("previous", "true")
+	}
+	if opts.Since > 0 {
+		query.Set("sinceSeconds", strconv.Itoa(int(opts.Since.Seconds())))
+	}
+	if opts.TailLines > 0 {
+		query.Set("tailLines", strconv.Itoa(opts.TailLines))
+	}
+	if opts.Timestamps {
+		query.Set("timestamps", "true")
+	}
+	body, err := c.get(ctx, "/api/v1/namespaces/"+url.PathEscape(namespace)+"/pods/"+url.PathEscape(pod)+"/log", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log of %s/%s: %w", pod, container, err)
+	}
+	return body, nil
+}
+
+// get sends a GET request to the API server and returns the response body of a successful
+// request, or the status message of the API server as error
+func (c *Client) get(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &status) == nil && status.Message != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, status.Message)
+		}
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return resp.Body, nil
+}
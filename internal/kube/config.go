@@ -0,0 +1,267 @@
+package kube
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// inClusterDir holds the service account credentials mounted into pods
+const inClusterDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeconfig is the subset of the kubeconfig file format used to connect to a cluster
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string   `yaml:"name"`
+		User userInfo `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// userInfo holds the credentials of a kubeconfig user
+type userInfo struct {
+	ClientCertificate     string `yaml:"client-certificate"`
+	ClientCertificateData string `yaml:"client-certificate-data"`
+	ClientKey             string `yaml:"client-key"`
+	ClientKeyData         string `yaml:"client-key-data"`
+	Token                 string `yaml:"token"`
+	TokenFile             string `yaml:"tokenFile"`
+	Username              string `yaml:"username"`
+	Password              string `yaml:"password"`
+	Exec                  *struct {
+		Command string   `yaml:"command"`
+		Args    []string `yaml:"args"`
+		Env     []struct {
+			Name  string `yaml:"name"`
+			Value string `yaml:"value"`
+		} `yaml:"env"`
+	} `yaml:"exec"`
+}
+
+// DefaultKubeconfig returns the kubeconfig path used when none is given: the first existing
+// file of $KUBECONFIG, or ~/.kube/config. It returns "" if neither exists, which selects the
+// in-cluster service account.
+func DefaultKubeconfig() string {
+	for _, path := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		path := filepath.Join(home, ".kube", "config")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// NewClient creates a client for the cluster of a kubeconfig context (the current context if
+// contextName is empty). An empty path uses the service account of the pod the tool runs in.
+// Client certificates, bearer tokens, basic auth, and exec credential plugins (such as those
+// of cloud providers) are supported.
+func NewClient(path, contextName string) (*Client, error) {
+	if path == "" {
+		return inClusterClient()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	var cfg kubeconfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig '%s': %w", path, err)
+	}
+	dir := filepath.Dir(path)
+
+	if contextName == "" {
+		contextName = cfg.CurrentContext
+	}
+	if contextName == "" {
+		return nil, fmt.Errorf("no current context in kubeconfig '%s', use -context", path)
+	}
+	c := &Client{}
+	var clusterName, userName string
+	found := false
+	for _, ctx := range cfg.Contexts {
+		if ctx.Name == contextName {
+			clusterName, userName, c.Namespace = ctx.Context.Cluster, ctx.Context.User, ctx.Context.Namespace
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context '%s' not found in kubeconfig '%s'", contextName, path)
+	}
+
+	tlsConfig := &tls.Config{}
+	found = false
+	for _, cluster := range cfg.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		found = true
+		c.server = strings.TrimSuffix(cluster.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+		tlsConfig.ServerName = cluster.Cluster.TLSServerName
+		ca, err := readData(cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate authority: %w", err)
+		}
+		if ca != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("invalid certificate authority of cluster '%s'", clusterName)
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if !found || c.server == "" {
+		return nil, fmt.Errorf("cluster '%s' of context '%s' not found in kubeconfig '%s'", clusterName, contextName, path)
+	}
+
+	for _, user := range cfg.Users {
+		if user.Name == userName {
+			if err := c.authenticate(user.User, dir, tlsConfig); err != nil {
+				return nil, fmt.Errorf("failed to authenticate as user '%s': %w", userName, err)
+			}
+		}
+	}
+	c.http = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment}}
+	return c, nil
+}
+
+// authenticate sets up the credentials of a kubeconfig user
+func (c *Client) authenticate(user userInfo, dir string, tlsConfig *tls.Config) error {
+	if user.Exec != nil {
+		cmd := exec.Command(user.Exec.Command, user.Exec.Args...)
+		cmd.Env = os.Environ()
+		for _, env := range user.Exec.Env {
+			cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+		}
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("failed to run credential plugin '%s': %w", user.Exec.Command, err)
+		}
+		var credential struct {
+			Status struct {
+				Token                 string `json:"token"`
+				ClientCertificateData string `json:"clientCertificateData"`
+				ClientKeyData         string `json:"clientKeyData"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(out, &credential); err != nil {
+			return fmt.Errorf("failed to parse output of credential plugin '%s': %w", user.Exec.Command, err)
+		}
+		c.token = credential.Status.Token
+		if credential.Status.ClientCertificateData != "" {
+			cert, err := tls.X509KeyPair([]byte(credential.Status.ClientCertificateData), []byte(credential.Status.ClientKeyData))
+			if err != nil {
+				return fmt.Errorf("invalid client certificate of credential plugin: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		return nil
+	}
+
+	cert, err := readData(user.ClientCertificateData, user.ClientCertificate, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	key, err := readData(user.ClientKeyData, user.ClientKey, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read client key: %w", err)
+	}
+	if cert != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+
+	c.token = user.Token
+	if user.TokenFile != "" {
+		token, err := os.ReadFile(resolvePath(user.TokenFile, dir))
+		if err != nil {
+			return fmt.Errorf("failed to read token: %w", err)
+		}
+		c.token = strings.TrimSpace(string(token))
+	}
+	c.username, c.password = user.Username, user.Password
+	return nil
+}
+
+// inClusterClient creates a client from the service account mounted into the pod
+func inClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("no kubeconfig found ($KUBECONFIG, ~/.kube/config) and not running in a cluster, use -kubeconfig")
+	}
+	token, err := os.ReadFile(filepath.Join(inClusterDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	ca, err := os.ReadFile(filepath.Join(inClusterDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account certificate authority: %w", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+	namespace, _ := os.ReadFile(filepath.Join(inClusterDir, "namespace"))
+
+	return &Client{
+		Namespace: strings.TrimSpace(string(namespace)),
+		server:    "https://" + net.JoinHostPort(host, port),
+		token:     strings.TrimSpace(string(token)),
+		http:      &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}},
+	}, nil
+}
+
+// readData returns base64-encoded inline data, or the content of a file relative to the
+// kubeconfig directory, or nil if neither is set
+func readData(inline, path, dir string) ([]byte, error) {
+	if inline != "" {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(inline))
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return os.ReadFile(resolvePath(path, dir))
+}
+
+// resolvePath resolves a path relative to the kubeconfig directory
+func resolvePath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
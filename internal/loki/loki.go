@@ -0,0 +1,168 @@
+// Package loki pushes log lines to Grafana Loki over its HTTP push API, using the JSON encoding
+// of push requests
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-interleaver/internal/parser"
+	"maps"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pushPath is the path of the push API
+const pushPath = "/loki/api/v1/push"
+
+// batchSize is the maximum number of lines per push request
+const batchSize = 5000
+
+// Pusher sends lines to the push API of a Loki endpoint
+type Pusher struct {
+	endpoint string
+	tenant   string
+	labels   map[string]string
+	client   *http.Client
+}
+
+// NewPusher creates a pusher for a Loki endpoint (e.g., http://loki:3100; the push path is
+// added if missing). The labels are added to every stream (e.g., node), and the tenant, if any,
+// is sent as X-Scope-OrgID for multi-tenant setups. Credentials in the URL are sent as basic auth.
+func NewPusher(endpoint, tenant string, labels map[string]string) (*Pusher, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Loki endpoint '%s', expected an http:// or https:// URL", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, pushPath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + pushPath
+	}
+	return &Pusher{
+		endpoint: u.String(),
+		tenant:   tenant,
+		labels:   labels,
+		client:   &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+// Location returns the push URL without credentials, for user-facing messages
+func (p *Pusher) Location() string {
+	if u, err := url.Parse(p.endpoint); err == nil {
+		return u.Redacted()
+	}
+	return p.endpoint
+}
+
+// pushRequest is the JSON body of a push request
+type pushRequest struct {
+	Streams []stream `json:"streams"`
+}
+
+// stream is a set of lines with the same labels; values are [nanosecond timestamp, line] pairs
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Push sends the lines with their resolved timestamps, in streams labeled by tag, file name,
+// and the pusher's labels. Lines without a timestamp of their own get the timestamp of the
+// preceding line of their tag, so they stay in place; lines before the first timestamp of a
+// tag are skipped. Push returns the number of lines sent.
+func (p *Pusher) Push(ctx context.Context, lines []*parser.LogLine) (int, error) {
+	last := make(map[string]time.Time)
+	streams := make(map[string]*stream)
+	var order []string
+	pending, sent := 0, 0
+
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+		req := pushRequest{}
+		for _, key := range order {
+			req.Streams = append(req.Streams, *streams[key])
+		}
+		if err := p.send(ctx, req); err != nil {
+			return err
+		}
+		sent += pending
+		pending, streams, order = 0, make(map[string]*stream), nil
+		return nil
+	}
+
+	for _, line := range lines {
+		var ts time.Time
+		if t := line.GetTimestamp(); t != nil {
+			ts = t.Time
+			last[line.Tag] = ts
+		} else if ts = last[line.Tag]; ts.IsZero() {
+			continue
+		}
+
+		key := line.Tag + "\x00" + line.File
+		s, ok := streams[key]
+		if !ok {
+			labels := map[string]string{"tag": line.Tag, "filename": filepath.Base(line.File)}
+			maps.Copy(labels, p.labels)
+			s = &stream{Stream: labels}
+			streams[key] = s
+			order = append(order, key)
+		}
+
+		body := line.OriginalLine
+		if len(line.Continuation) > 0 {
+			body += "\n" + strings.Join(line.Continuation, "\n")
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(ts.UnixNano(), 10), body})
+		if pending++; pending >= batchSize {
+			if err := flush(); err != nil {
+				return sent, err
+			}
+		}
+	}
+	return sent, flush()
+}
+
+// send posts a gzip-compressed push request
+func (p *Pusher) send(ctx context.Context, request pushRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode Loki request: %w", err)
+	}
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("failed to compress Loki request: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress Loki request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create Loki request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	if p.tenant != "" {
+		req.Header.Set("X-Scope-OrgID", p.tenant)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Loki request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Loki request failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
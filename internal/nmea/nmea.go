@@ -0,0 +1,189 @@
+// Package nmea decodes the fix status and time of GNSS receivers from NMEA 0183 sentences (RMC,
+// GGA, ZDA of any talker, e.g., from gpspipe -r or a ts2phc serial capture) and gpsd JSON reports
+// (TPV, from gpspipe -w), wherever they appear in a log line, so a GNSS log can serve as a time
+// source: its sentences carry UTC as the receiver knows it.
+package nmea
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fix is the state a sentence reports
+type Fix struct {
+	Sentence      string    // RMC, GGA, ZDA, or TPV
+	Time          time.Time // GNSS time (UTC); zero when the sentence has none or its date is not known yet
+	Valid         bool      // Whether the receiver has a fix (RMC status A, GGA quality > 0, TPV mode 2 or 3)
+	HasFix        bool      // Whether the sentence reports the fix status (ZDA does not)
+	Satellites    int       // Satellites in use (GGA)
+	HasSatellites bool
+}
+
+// sentenceRe matches the NMEA sentences carrying a time: talker, type, fields, and optional checksum
+var sentenceRe = regexp.MustCompile(`\$([A-Z]{2})(RMC|GGA|ZDA),([^*\s]*)(?:\*([0-9A-Fa-f]{2}))?`)
+
+// Decoder decodes the sentences of one receiver in order. GGA sentences carry only the time of
+// day, so they are dated by the last RMC, ZDA, or TPV report.
+type Decoder struct {
+	last time.Time // Last full GNSS time
+}
+
+// NewDecoder creates a decoder for the sentences of one receiver
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// Decode returns the fix reported by the sentence in a line, if any. Sentences with a wrong
+// checksum are ignored.
+func (d *Decoder) Decode(line string) (Fix, bool) {
+	if strings.Contains(line, `"class":"TPV"`) {
+		return d.decodeTPV(line)
+	}
+	m := sentenceRe.FindStringSubmatchIndex(line)
+	if m == nil {
+		return Fix{}, false
+	}
+	if m[8] >= 0 && !checksumValid(line[m[0]+1:m[8]-1], line[m[8]:m[9]]) {
+		return Fix{}, false
+	}
+	fields := strings.Split(line[m[6]:m[7]], ",")
+	field := func(k int) string {
+		if k < len(fields) {
+			return fields[k]
+		}
+		return ""
+	}
+
+	fix := Fix{Sentence: line[m[4]:m[5]]}
+	switch fix.Sentence {
+	case "RMC":
+		// time, status, lat, N/S, lon, E/W, speed, course, date (ddmmyy), ...
+		fix.HasFix = field(1) != ""
+		fix.Valid = field(1) == "A"
+		if date, ok := parseDate(field(8)); ok {
+			fix.Time = d.dated(date, field(0))
+		}
+	case "GGA":
+		// time, lat, N/S, lon, E/W, quality, satellites, ...
+		if quality, err := strconv.Atoi(field(5)); err == nil {
+			fix.HasFix = true
+			fix.Valid = quality > 0
+		}
+		if satellites, err := strconv.Atoi(field(6)); err == nil {
+			fix.Satellites, fix.HasSatellites = satellites, true
+		}
+		if !d.last.IsZero() {
+			fix.Time = d.timeOfDay(field(0))
+		}
+	case "ZDA":
+		// time, day, month, year, zone hours, zone minutes
+		day, errDay := strconv.Atoi(field(1))
+		month, errMonth := strconv.Atoi(field(2))
+		year, errYear := strconv.Atoi(field(3))
+		if errDay == nil && errMonth == nil && errYear == nil {
+			fix.Time = d.dated(time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), field(0))
+		}
+	}
+	return fix, true
+}
+
+// decodeTPV decodes a gpsd time-position-velocity report
+func (d *Decoder) decodeTPV(line string) (Fix, bool) {
+	start := strings.Index(line, "{")
+	if start < 0 {
+		return Fix{}, false
+	}
+	var report struct {
+		Class string `json:"class"`
+		Time  string `json:"time"`
+		Mode  *int   `json:"mode"`
+	}
+	if err := json.NewDecoder(strings.NewReader(line[start:])).Decode(&report); err != nil || report.Class != "TPV" {
+		return Fix{}, false
+	}
+	fix := Fix{Sentence: "TPV"}
+	if report.Mode != nil {
+		fix.HasFix = true
+		fix.Valid = *report.Mode >= 2
+	}
+	if t, err := time.Parse(time.RFC3339Nano, report.Time); err == nil {
+		fix.Time = t.UTC()
+		d.last = fix.Time
+	}
+	return fix, true
+}
+
+// dated returns the time of day hhmmss.ss on a date, and remembers it for dating GGA sentences
+func (d *Decoder) dated(date time.Time, hhmmss string) time.Time {
+	tod, ok := parseTimeOfDay(hhmmss)
+	if !ok {
+		return time.Time{}
+	}
+	d.last = date.Add(tod)
+	return d.last
+}
+
+// timeOfDay returns the time of day hhmmss.ss nearest to the last full time, which dates it
+// across midnight
+func (d *Decoder) timeOfDay(hhmmss string) time.Time {
+	tod, ok := parseTimeOfDay(hhmmss)
+	if !ok {
+		return time.Time{}
+	}
+	day := d.last.Truncate(24 * time.Hour)
+	t := day.Add(tod)
+	if t.Sub(d.last) < -12*time.Hour {
+		t = t.Add(24 * time.Hour)
+	} else if t.Sub(d.last) > 12*time.Hour {
+		t = t.Add(-24 * time.Hour)
+	}
+	return t
+}
+
+// parseDate parses an RMC date (ddmmyy); two-digit years are in 2000-2099 as NMEA 0183 has no
+// century
+func parseDate(ddmmyy string) (time.Time, bool) {
+	if len(ddmmyy) != 6 {
+		return time.Time{}, false
+	}
+	value, err := strconv.Atoi(ddmmyy)
+	if err != nil {
+		return time.Time{}, false
+	}
+	day, month, year := value/10000, value/100%100, value%100
+	if day < 1 || day > 31 || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	return time.Date(2000+year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+}
+
+// parseTimeOfDay parses an NMEA time of day (hhmmss with optional fraction)
+func parseTimeOfDay(hhmmss string) (time.Duration, bool) {
+	if len(hhmmss) < 6 {
+		return 0, false
+	}
+	hours, errHours := strconv.Atoi(hhmmss[0:2])
+	minutes, errMinutes := strconv.Atoi(hhmmss[2:4])
+	seconds, errSeconds := strconv.ParseFloat(hhmmss[4:], 64)
+	if errHours != nil || errMinutes != nil || errSeconds != nil || hours > 23 || minutes > 59 || seconds >= 61 {
+		return 0, false
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)).Round(time.Microsecond), true
+}
+
+// checksumValid checks the XOR checksum of the characters between $ and *
+func checksumValid(body, checksum string) bool {
+	want, err := strconv.ParseUint(checksum, 16, 8)
+	if err != nil {
+		return false
+	}
+	var sum byte
+	for k := 0; k < len(body); k++ {
+		sum ^= body[k]
+	}
+	return sum == byte(want)
+}
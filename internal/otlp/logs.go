@@ -0,0 +1,97 @@
+package otlp
+
+import (
+	"context"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/parser"
+	"strings"
+	"time"
+)
+
+// logBatch is the maximum number of log records per request
+const logBatch = 2000
+
+// severityNumbers maps severity names to OTLP severity numbers
+var severityNumbers = map[string]int{
+	"trace":   1,
+	"debug":   5,
+	"info":    9,
+	"warn":    13,
+	"warning": 13,
+	"error":   17,
+	"fatal":   21,
+}
+
+type logsRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber,omitempty"`
+	SeverityText         string     `json:"severityText,omitempty"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes"`
+}
+
+// ExportLogs sends the lines as log records, timestamped with their resolved timestamps. The
+// records carry the tag, source file, and line number as attributes, and the first severity
+// the line matches (e.g., the burst severities of the config). Continuation lines are part
+// of the body of their entry.
+func (e *Exporter) ExportLogs(ctx context.Context, lines []*parser.LogLine, severities []analysis.Severity) error {
+	observed := unixNano(time.Now())
+	for start := 0; start < len(lines); start += logBatch {
+		batch := lines[start:min(start+logBatch, len(lines))]
+		records := make([]logRecord, len(batch))
+		for i, line := range batch {
+			records[i] = newLogRecord(line, severities, observed)
+		}
+		req := logsRequest{ResourceLogs: []resourceLogs{{
+			Resource:  e.resource,
+			ScopeLogs: []scopeLogs{{Scope: scope{Name: ScopeName}, LogRecords: records}},
+		}}}
+		if err := e.send(ctx, "/v1/logs", req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newLogRecord converts a line to a log record
+func newLogRecord(line *parser.LogLine, severities []analysis.Severity, observed string) logRecord {
+	body := line.OriginalLine
+	if len(line.Continuation) > 0 {
+		body += "\n" + strings.Join(line.Continuation, "\n")
+	}
+	record := logRecord{
+		ObservedTimeUnixNano: observed,
+		Body:                 stringValue(body),
+		Attributes: []keyValue{
+			{Key: "tag", Value: stringValue(line.Tag)},
+			{Key: "log.file.name", Value: stringValue(line.File)},
+			{Key: "log.file.line", Value: intValue(int64(line.LineNumber))},
+		},
+	}
+	if ts := line.GetTimestamp(); ts != nil {
+		record.TimeUnixNano = unixNano(ts.Time)
+	}
+	for _, sev := range severities {
+		if sev.Regex.MatchString(line.OriginalLine) {
+			record.SeverityText = strings.ToUpper(sev.Name)
+			record.SeverityNumber = severityNumbers[strings.ToLower(sev.Name)]
+			break
+		}
+	}
+	return record
+}
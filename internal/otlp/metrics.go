@@ -0,0 +1,120 @@
+package otlp
+
+import (
+	"context"
+	"time"
+)
+
+// metricBatch is the maximum number of data points per request
+const metricBatch = 5000
+
+// Metric is a metric to export: a gauge, or a monotonic cumulative counter
+type Metric struct {
+	Name        string
+	Description string
+	Counter     bool
+	Series      []Series
+}
+
+// Series is one time series of a metric, identified by its attributes
+type Series struct {
+	Attributes map[string]string
+	Points     []Point // In time order
+}
+
+// Point is a data point of a series
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+type metricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope        `json:"scope"`
+	Metrics []metricData `json:"metrics"`
+}
+
+type metricData struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Gauge       *gauge `json:"gauge,omitempty"`
+	Sum         *sum   `json:"sum,omitempty"`
+}
+
+type gauge struct {
+	DataPoints []dataPoint `json:"dataPoints"`
+}
+
+type sum struct {
+	DataPoints             []dataPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality"` // 2 = cumulative
+	IsMonotonic            bool        `json:"isMonotonic"`
+}
+
+type dataPoint struct {
+	Attributes        []keyValue `json:"attributes"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsDouble          float64    `json:"asDouble"`
+}
+
+// ExportMetrics sends the metrics in batches of data points. Counters start at the first point
+// of their series.
+func (e *Exporter) ExportMetrics(ctx context.Context, metrics []Metric) error {
+	var batch []metricData
+	points := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		req := metricsRequest{ResourceMetrics: []resourceMetrics{{
+			Resource:     e.resource,
+			ScopeMetrics: []scopeMetrics{{Scope: scope{Name: ScopeName}, Metrics: batch}},
+		}}}
+		if err := e.send(ctx, "/v1/metrics", req); err != nil {
+			return err
+		}
+		batch, points = nil, 0
+		return nil
+	}
+
+	for _, m := range metrics {
+		for _, series := range m.Series {
+			attrs := stringAttributes(series.Attributes)
+			var start string
+			if m.Counter && len(series.Points) > 0 {
+				start = unixNano(series.Points[0].Time)
+			}
+			for rest := series.Points; len(rest) > 0; {
+				n := min(len(rest), metricBatch-points)
+				dps := make([]dataPoint, n)
+				for i, pt := range rest[:n] {
+					dps[i] = dataPoint{Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: unixNano(pt.Time), AsDouble: pt.Value}
+				}
+				data := metricData{Name: m.Name, Description: m.Description}
+				if m.Counter {
+					data.Sum = &sum{DataPoints: dps, AggregationTemporality: 2, IsMonotonic: true}
+				} else {
+					data.Gauge = &gauge{DataPoints: dps}
+				}
+				batch = append(batch, data)
+				points += n
+				rest = rest[n:]
+				if points == metricBatch {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,158 @@
+// Package otlp sends log records and metrics to an OpenTelemetry collector over OTLP/HTTP,
+// using the JSON encoding of the OTLP protobuf messages
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScopeName is the instrumentation scope of the exported records
+const ScopeName = "log-interleaver"
+
+// Exporter sends OTLP requests to the signal paths (/v1/logs, /v1/metrics) of a collector endpoint
+type Exporter struct {
+	endpoint string
+	headers  map[string]string
+	resource resource
+	client   *http.Client
+}
+
+// NewExporter creates an exporter for an OTLP/HTTP endpoint (e.g., http://collector:4318).
+// The headers are sent with every request (e.g., Authorization), and the resource attributes
+// describe the source of the records; service.name defaults to log-interleaver.
+func NewExporter(endpoint string, headers, attributes map[string]string) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint '%s', expected an http:// or https:// URL", endpoint)
+	}
+	attrs := map[string]string{"service.name": ScopeName}
+	for name, value := range attributes {
+		attrs[name] = value
+	}
+	return &Exporter{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  headers,
+		resource: resource{Attributes: stringAttributes(attrs)},
+		client:   &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+// resource is an OTLP Resource
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+// scope is an OTLP InstrumentationScope
+type scope struct {
+	Name string `json:"name"`
+}
+
+// keyValue is an OTLP attribute
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue is an OTLP AnyValue holding a string or an integer (encoded as a JSON string)
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    string  `json:"intValue,omitempty"`
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+func intValue(n int64) anyValue {
+	return anyValue{IntValue: strconv.FormatInt(n, 10)}
+}
+
+// stringAttributes converts a map to attributes sorted by key
+func stringAttributes(attrs map[string]string) []keyValue {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	kvs := make([]keyValue, len(keys))
+	for i, key := range keys {
+		kvs[i] = keyValue{Key: key, Value: stringValue(attrs[key])}
+	}
+	return kvs
+}
+
+// unixNano formats a time as the JSON encoding of OTLP fixed64 timestamps ("" for zero times)
+func unixNano(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// send posts a gzip-compressed JSON request to a signal path of the endpoint
+func (e *Exporter) send(ctx context.Context, path string, request interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode OTLP request: %w", err)
+	}
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("failed to compress OTLP request: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress OTLP request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+path, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OTLP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("OTLP request failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// ParseHeaders parses comma-separated name=value header pairs (e.g., Authorization=Bearer abc),
+// the format of OTEL_EXPORTER_OTLP_HEADERS
+func ParseHeaders(list string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid OTLP header '%s', expected name=value", pair)
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = unescaped
+		}
+		headers[strings.TrimSpace(name)] = value
+	}
+	return headers, nil
+}
@@ -0,0 +1,322 @@
+// Package parquet writes flat tables as Apache Parquet files: PLAIN-encoded, gzip-compressed
+// columns of timestamps, doubles, and strings, which pandas, Polars, DuckDB, and Spark read
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Kind is the type of a column
+type Kind int
+
+const (
+	Timestamp Kind = iota // INT64 nanoseconds since the epoch, UTC
+	Double                // DOUBLE
+	String                // BYTE_ARRAY with the STRING logical type
+)
+
+// Column describes a column of the table
+type Column struct {
+	Name     string
+	Kind     Kind
+	Optional bool // Null values allowed
+}
+
+// Values holds the values of a column in a row group: the slice of the column's kind, and for
+// optional columns which rows are null (nil when none are)
+type Values struct {
+	Int64s  []int64
+	Doubles []float64
+	Strings []string
+	Nulls   []bool
+}
+
+// pageRows is the maximum number of values per data page
+const pageRows = 64 * 1024
+
+// Physical types, encodings, and codecs of the Parquet format
+const (
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+)
+
+// Writer writes a Parquet file row group by row group
+type Writer struct {
+	w         io.Writer
+	offset    int64
+	columns   []Column
+	rowGroups []rowGroup
+	numRows   int64
+}
+
+type rowGroup struct {
+	chunks  []columnChunk
+	numRows int64
+	size    int64
+}
+
+type columnChunk struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// magic starts and ends Parquet files
+const magic = "PAR1"
+
+// NewWriter starts a Parquet file with the columns
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	pw := &Writer{w: w, columns: columns}
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *Writer) write(data []byte) error {
+	n, err := pw.w.Write(data)
+	pw.offset += int64(n)
+	return err
+}
+
+// WriteRowGroup writes a row group of numRows rows, with the values of each column
+func (pw *Writer) WriteRowGroup(numRows int, values []Values) error {
+	if len(values) != len(pw.columns) {
+		return fmt.Errorf("row group has %d columns, expected %d", len(values), len(pw.columns))
+	}
+	group := rowGroup{numRows: int64(numRows)}
+	for i, col := range pw.columns {
+		start := pw.offset
+		chunk := columnChunk{offset: start, numValues: int64(numRows)}
+		for first := 0; first < numRows; first += pageRows {
+			last := min(first+pageRows, numRows)
+			page, err := encodePage(col, values[i], first, last)
+			if err != nil {
+				return fmt.Errorf("column '%s': %w", col.Name, err)
+			}
+			compressed, err := gzipData(page)
+			if err != nil {
+				return err
+			}
+			header := pageHeader(last-first, len(page), len(compressed))
+			if err := pw.write(header); err != nil {
+				return err
+			}
+			if err := pw.write(compressed); err != nil {
+				return err
+			}
+			chunk.uncompressedSize += int64(len(header) + len(page))
+			chunk.compressedSize += int64(len(header) + len(compressed))
+		}
+		group.size += chunk.uncompressedSize
+		group.chunks = append(group.chunks, chunk)
+	}
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.numRows += int64(numRows)
+	return nil
+}
+
+// Close writes the file metadata. It does not close the underlying writer.
+func (pw *Writer) Close() error {
+	footer := pw.fileMetadata()
+	if err := pw.write(footer); err != nil {
+		return err
+	}
+	if err := pw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return pw.write([]byte(magic))
+}
+
+// encodePage encodes the rows [first, last) of a column as data page: the definition levels of
+// optional columns, followed by the PLAIN-encoded non-null values
+func encodePage(col Column, values Values, first, last int) ([]byte, error) {
+	var page []byte
+	isNull := func(row int) bool { return col.Optional && values.Nulls != nil && values.Nulls[row] }
+	if values.Nulls != nil && len(values.Nulls) < last {
+		return nil, fmt.Errorf("%d null flags for %d rows", len(values.Nulls), last)
+	}
+	if col.Optional {
+		levels := encodeLevels(first, last, isNull)
+		page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+		page = append(page, levels...)
+	}
+
+	switch col.Kind {
+	case Timestamp:
+		if len(values.Int64s) < last {
+			return nil, fmt.Errorf("%d values for %d rows", len(values.Int64s), last)
+		}
+		for row := first; row < last; row++ {
+			if !isNull(row) {
+				page = binary.LittleEndian.AppendUint64(page, uint64(values.Int64s[row]))
+			}
+		}
+	case Double:
+		if len(values.Doubles) < last {
+			return nil, fmt.Errorf("%d values for %d rows", len(values.Doubles), last)
+		}
+		for row := first; row < last; row++ {
+			if !isNull(row) {
+				page = binary.LittleEndian.AppendUint64(page, math.Float64bits(values.Doubles[row]))
+			}
+		}
+	case String:
+		if len(values.Strings) < last {
+			return nil, fmt.Errorf("%d values for %d rows", len(values.Strings), last)
+		}
+		for row := first; row < last; row++ {
+			if !isNull(row) {
+				page = binary.LittleEndian.AppendUint32(page, uint32(len(values.Strings[row])))
+				page = append(page, values.Strings[row]...)
+			}
+		}
+	}
+	return page, nil
+}
+
+// encodeLevels encodes the definition levels (0 = null, 1 = defined) of rows [first, last) as
+// runs of the RLE/bit-packing hybrid encoding, with a bit width of 1
+func encodeLevels(first, last int, isNull func(int) bool) []byte {
+	var levels []byte
+	for row := first; row < last; {
+		null := isNull(row)
+		run := 1
+		for row+run < last && isNull(row+run) == null {
+			run++
+		}
+		levels = binary.AppendUvarint(levels, uint64(run)<<1)
+		if null {
+			levels = append(levels, 0)
+		} else {
+			levels = append(levels, 1)
+		}
+		row += run
+	}
+	return levels
+}
+
+// gzipData compresses a page with the GZIP codec
+func gzipData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pageHeader encodes the PageHeader of a data page
+func pageHeader(numValues, uncompressedSize, compressedSize int) []byte {
+	t := &thriftWriter{}
+	t.begin(0)
+	t.i32(1, 0) // DATA_PAGE
+	t.i32(2, int32(uncompressedSize))
+	t.i32(3, int32(compressedSize))
+	t.begin(5) // DataPageHeader
+	t.i32(1, int32(numValues))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE) // Definition levels
+	t.i32(4, encodingRLE) // Repetition levels
+	t.end()
+	t.end()
+	return t.buf
+}
+
+// physicalType returns the Parquet physical type of a column kind
+func physicalType(kind Kind) int32 {
+	switch kind {
+	case Timestamp:
+		return typeInt64
+	case Double:
+		return typeDouble
+	}
+	return typeByteArray
+}
+
+// fileMetadata encodes the FileMetaData of the footer
+func (pw *Writer) fileMetadata() []byte {
+	t := &thriftWriter{}
+	t.begin(0)
+	t.i32(1, 1) // Version
+
+	// Schema: the root element followed by the columns
+	t.list(2, thriftStruct, len(pw.columns)+1)
+	t.begin(0)
+	t.string(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.end()
+	for _, col := range pw.columns {
+		t.begin(0)
+		t.i32(1, physicalType(col.Kind))
+		repetition := int32(0) // REQUIRED
+		if col.Optional {
+			repetition = 1 // OPTIONAL
+		}
+		t.i32(3, repetition)
+		t.string(4, col.Name)
+		switch col.Kind {
+		case String:
+			t.i32(6, 0) // Converted type UTF8
+			t.begin(10) // LogicalType
+			t.begin(1)  // STRING
+			t.end()
+			t.end()
+		case Timestamp:
+			t.begin(10) // LogicalType
+			t.begin(8)  // TIMESTAMP
+			t.bool(1, true)
+			t.begin(2) // Unit
+			t.begin(3) // NANOS
+			t.end()
+			t.end()
+			t.end()
+			t.end()
+		}
+		t.end()
+	}
+
+	t.i64(3, pw.numRows)
+	t.list(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		t.begin(0)
+		t.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			col := pw.columns[i]
+			t.begin(0)
+			t.i64(2, chunk.offset)
+			t.begin(3) // ColumnMetaData
+			t.i32(1, physicalType(col.Kind))
+			t.i32List(2, []int32{encodingPlain, encodingRLE})
+			t.stringList(3, []string{col.Name})
+			t.i32(4, codecGzip)
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.uncompressedSize)
+			t.i64(7, chunk.compressedSize)
+			t.i64(9, chunk.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.numRows)
+		t.end()
+	}
+	t.string(6, "log-interleaver")
+	t.end()
+	return t.buf
+}
@@ -0,0 +1,261 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// thriftReader decodes Thrift compact protocol structs into maps of field IDs to values: int64
+// for integers, bool, string for binaries, []any for lists, and map[int16]any for structs
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Varint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var id int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.varint())
+		}
+		switch typ := header & 0x0f; typ {
+		case thriftTrue, thriftFalse:
+			fields[id] = typ == thriftTrue
+		default:
+			fields[id] = r.readValue(typ)
+		}
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return string(r.buf[r.pos-n : r.pos])
+	case thriftList:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = r.readValue(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic("unsupported thrift type")
+}
+
+// readFile decodes the footer of a Parquet file and the values of its columns, one slice per
+// column with nil for null values, and returns the number of data pages of each column
+func readFile(t *testing.T, file []byte) (meta map[int16]any, columns [][]any, pages []int) {
+	t.Helper()
+	if !bytes.HasPrefix(file, []byte(magic)) || !bytes.HasSuffix(file, []byte(magic)) {
+		t.Fatalf("file does not start and end with %s", magic)
+	}
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := &thriftReader{buf: file[len(file)-8-footerLen : len(file)-8]}
+	meta = footer.readStruct()
+	if footer.pos != footerLen {
+		t.Fatalf("footer decoded to %d of %d bytes", footer.pos, footerLen)
+	}
+
+	schema := meta[2].([]any)
+	columns = make([][]any, len(schema)-1)
+	pages = make([]int, len(schema)-1)
+	for _, group := range meta[4].([]any) {
+		for i, chunk := range group.(map[int16]any)[1].([]any) {
+			md := chunk.(map[int16]any)[3].(map[int16]any)
+			element := schema[i+1].(map[int16]any)
+			offset, size := int(md[9].(int64)), int(md[7].(int64))
+			for r := (&thriftReader{buf: file[offset : offset+size]}); r.pos < size; {
+				header := r.readStruct()
+				compressed := r.buf[r.pos : r.pos+int(header[3].(int64))]
+				r.pos += len(compressed)
+				zr, err := gzip.NewReader(bytes.NewReader(compressed))
+				if err != nil {
+					t.Fatalf("column %d: %v", i, err)
+				}
+				page, err := io.ReadAll(zr)
+				if err != nil || len(page) != int(header[2].(int64)) {
+					t.Fatalf("column %d: page of %d bytes (%v), want %d", i, len(page), err, header[2])
+				}
+				numValues := int(header[5].(map[int16]any)[1].(int64))
+				columns[i] = append(columns[i], decodePage(page, numValues, element[1].(int64), element[3] == int64(1))...)
+				pages[i]++
+			}
+		}
+	}
+	return meta, columns, pages
+}
+
+// decodePage decodes the values of a data page
+func decodePage(page []byte, numValues int, physical int64, optional bool) []any {
+	defined := make([]bool, 0, numValues)
+	if optional {
+		n := int(binary.LittleEndian.Uint32(page))
+		levels := &thriftReader{buf: page[4 : 4+n]}
+		for levels.pos < n {
+			run := int(levels.uvarint() >> 1)
+			level := levels.byte()
+			for range run {
+				defined = append(defined, level == 1)
+			}
+		}
+		page = page[4+n:]
+	} else {
+		for range numValues {
+			defined = append(defined, true)
+		}
+	}
+	values := make([]any, numValues)
+	for i, ok := range defined {
+		if !ok {
+			continue
+		}
+		switch physical {
+		case typeInt64:
+			values[i] = int64(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case typeDouble:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case typeByteArray:
+			n := binary.LittleEndian.Uint32(page)
+			values[i] = string(page[4 : 4+n])
+			page = page[4+n:]
+		}
+	}
+	return values
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	columns := []Column{
+		{Name: "time", Kind: Timestamp},
+		{Name: "value", Kind: Double},
+		{Name: "state", Kind: String, Optional: true},
+	}
+	groups := [][]Values{
+		{
+			{Int64s: []int64{1768140000000000000, 1768140001000000000, 1768140002500000000}},
+			{Doubles: []float64{-12.5, 0, math.Inf(1)}},
+			{Strings: []string{"s2", "", "FAULT"}, Nulls: []bool{false, true, false}},
+		},
+		{
+			{Int64s: []int64{-1, 0}},
+			{Doubles: []float64{1e-9, 3}},
+			{Strings: []string{"", ""}, Nulls: []bool{true, true}},
+		},
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, columns)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, values := range groups {
+		if err := w.WriteRowGroup(len(values[0].Int64s), values); err != nil {
+			t.Fatalf("WriteRowGroup: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	meta, got, _ := readFile(t, buf.Bytes())
+	if meta[1] != int64(1) || meta[3] != int64(5) || len(meta[4].([]any)) != 2 {
+		t.Errorf("version %v, %v rows, %d row groups; want 1, 5, 2", meta[1], meta[3], len(meta[4].([]any)))
+	}
+	var names []any
+	for _, element := range meta[2].([]any) {
+		names = append(names, element.(map[int16]any)[4])
+	}
+	if want := []any{"schema", "time", "value", "state"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("schema %v, want %v", names, want)
+	}
+	// Timestamps are UTC nanoseconds
+	timestamp := meta[2].([]any)[1].(map[int16]any)[10].(map[int16]any)[8].(map[int16]any)
+	if timestamp[1] != true || !reflect.DeepEqual(timestamp[2], map[int16]any{3: map[int16]any{}}) {
+		t.Errorf("timestamp type %v, want UTC nanoseconds", timestamp)
+	}
+	want := [][]any{
+		{int64(1768140000000000000), int64(1768140001000000000), int64(1768140002500000000), int64(-1), int64(0)},
+		{-12.5, 0.0, math.Inf(1), 1e-9, 3.0},
+		{"s2", nil, "FAULT", nil, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("columns %v, want %v", got, want)
+	}
+}
+
+func TestWriterPages(t *testing.T) {
+	// A row group of more than pageRows rows is split into data pages
+	const rows = pageRows + 10
+	doubles := make([]float64, rows)
+	for i := range doubles {
+		doubles[i] = float64(i)
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{{Name: "value", Kind: Double}})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteRowGroup(rows, []Values{{Doubles: doubles}}); err != nil {
+		t.Fatalf("WriteRowGroup: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	_, got, pages := readFile(t, buf.Bytes())
+	if pages[0] != 2 || len(got[0]) != rows || got[0][rows-1] != float64(rows-1) {
+		t.Errorf("%d pages of %d values, want 2 pages of %d", pages[0], len(got[0]), rows)
+	}
+}
+
+func TestWriteRowGroupInvalid(t *testing.T) {
+	w, err := NewWriter(io.Discard, []Column{{Name: "time", Kind: Timestamp}, {Name: "value", Kind: Double}})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteRowGroup(1, []Values{{Int64s: []int64{1}}}); err == nil {
+		t.Error("WriteRowGroup with a missing column succeeded, want an error")
+	}
+	if err := w.WriteRowGroup(2, []Values{{Int64s: []int64{1, 2}}, {Doubles: []float64{1}}}); err == nil {
+		t.Error("WriteRowGroup with too few values succeeded, want an error")
+	}
+}
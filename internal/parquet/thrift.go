@@ -0,0 +1,104 @@
+package parquet
+
+import (
+	"encoding/binary"
+)
+
+// Types of the Thrift compact protocol
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Thrift structs in the compact protocol, the encoding of the Parquet
+// page headers and file metadata
+type thriftWriter struct {
+	buf       []byte
+	lastField []int16 // Last field ID of each open struct
+}
+
+// field writes a field header, as a delta to the previous field ID of the struct when possible
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.lastField[len(t.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	*last = id
+}
+
+// begin starts a struct, as a field of the enclosing struct unless id is 0 (top level or list element)
+func (t *thriftWriter) begin(id int16) {
+	if id != 0 {
+		t.field(id, thriftStruct)
+	}
+	t.lastField = append(t.lastField, 0)
+}
+
+// end ends the current struct
+func (t *thriftWriter) end() {
+	t.buf = append(t.buf, 0)
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) string(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.rawString(s)
+}
+
+func (t *thriftWriter) rawString(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// list writes a list field header; the elements follow
+func (t *thriftWriter) list(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elemType)
+	} else {
+		t.buf = append(t.buf, 0xf0|elemType)
+		t.buf = binary.AppendUvarint(t.buf, uint64(size))
+	}
+}
+
+// i32List writes a list of i32 values
+func (t *thriftWriter) i32List(id int16, values []int32) {
+	t.list(id, thriftI32, len(values))
+	for _, v := range values {
+		t.buf = binary.AppendVarint(t.buf, int64(v))
+	}
+}
+
+// stringList writes a list of strings
+func (t *thriftWriter) stringList(id int16, values []string) {
+	t.list(id, thriftBinary, len(values))
+	for _, v := range values {
+		t.rawString(v)
+	}
+}
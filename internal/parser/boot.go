@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"log-interleaver/pkg/timestamp"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// bootTimeRe matches lines logging the boot time, e.g. "Booted at 2026-01-11 08:00:00" or
+// "boot time: 2026-01-11T08:00:00Z"
+var bootTimeRe = regexp.MustCompile(`(?i)\bboot(?:ed|[ _-]?time)\b\W*(?:at\W*)?(\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?)`)
+
+// btimeRe matches the boot time in seconds since the epoch from /proc/stat ("btime 1768118400")
+var btimeRe = regexp.MustCompile(`^btime\s+(\d+)$`)
+
+// bootTimeLayouts are the layouts tried for boot times found by bootTimeRe
+var bootTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"}
+
+// FindBootTime returns the boot time logged in the lines, if any
+func FindBootTime(lines []*LogLine) (time.Time, bool) {
+	for _, line := range lines {
+		if matches := btimeRe.FindStringSubmatch(line.OriginalLine); matches != nil {
+			secs, err := strconv.ParseInt(matches[1], 10, 64)
+			if err == nil {
+				return time.Unix(secs, 0).UTC(), true
+			}
+		}
+		if matches := bootTimeRe.FindStringSubmatch(line.OriginalLine); matches != nil {
+			for _, layout := range bootTimeLayouts {
+				if t, err := time.Parse(layout, matches[1]); err == nil {
+					return t.UTC(), true
+				}
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// ResolveBootRelative resolves uptime timestamps (kernel "[ 1234.567890]" timestamps and daemon
+// uptimes, both seconds since boot) as offsets from the boot time. Each boot segment uses the
+// boot time logged in it; the most recent segment falls back to bootTime when it is non-zero,
+// since a boot time taken at collection time (e.g., "uptime -s") describes the current boot.
+// Segments without a boot time are left unresolved.
+func ResolveBootRelative(lines []*LogLine, bootTime time.Time) {
+	segments := splitBootSegments(lines)
+	for i, segment := range segments {
+		boot, ok := FindBootTime(segment)
+		if !ok {
+			if i != len(segments)-1 || bootTime.IsZero() {
+				continue
+			}
+			boot = bootTime
+		}
+
+		for _, line := range segment {
+			if line.HasUptime && line.Timestamp == nil {
+				line.Timestamp = &timestamp.Timestamp{
+					Time:      boot.Add(time.Duration(math.Round(line.UptimeSec * float64(time.Second)))),
+					Type:      timestamp.TypeUptime,
+					UptimeSec: line.UptimeSec,
+				}
+			}
+		}
+	}
+}
+
+// HasUnresolvedUptime reports whether any uptime line has no resolved timestamp yet
+func HasUnresolvedUptime(lines []*LogLine) bool {
+	for _, line := range lines {
+		if line.HasUptime && line.Timestamp == nil {
+			return true
+		}
+	}
+	return false
+}
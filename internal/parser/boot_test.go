@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindBootTime(t *testing.T) {
+	tests := []struct {
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{"btime 1768118400", time.Date(2026, 1, 11, 8, 0, 0, 0, time.UTC), true},
+		{"Booted at 2026-01-11 08:00:00", time.Date(2026, 1, 11, 8, 0, 0, 0, time.UTC), true},
+		{"system boot time: 2026-01-11T08:00:00.5Z", time.Date(2026, 1, 11, 8, 0, 0, 500000000, time.UTC), true},
+		{"boot_time=2026-01-11T10:00:00+02:00", time.Date(2026, 1, 11, 8, 0, 0, 0, time.UTC), true},
+		{"[    0.000000] Linux version 5.14.0 (mockbuild@x86-vm-07)", time.Time{}, false},
+		{"bootstrap done at 2026-01-11 08:00:00", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := FindBootTime([]*LogLine{{OriginalLine: tt.line}})
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("FindBootTime(%q) = %v, %v; want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResolveBootRelative(t *testing.T) {
+	boot := time.Date(2026, 1, 11, 8, 0, 0, 0, time.UTC)
+	collected := time.Date(2026, 1, 11, 12, 0, 0, 0, time.UTC)
+	lines := []*LogLine{
+		// A boot with its boot time logged
+		{OriginalLine: "Booted at 2026-01-11 08:00:00"}, uptime(1.5), uptime(3600),
+		// A boot without one, followed by the current boot
+		uptime(2), uptime(120),
+		uptime(0.25), uptime(10),
+	}
+	ResolveBootRelative(lines, collected)
+
+	want := map[int]time.Time{
+		1: boot.Add(1500 * time.Millisecond),
+		2: boot.Add(time.Hour),
+		5: collected.Add(250 * time.Millisecond),
+		6: collected.Add(10 * time.Second),
+	}
+	for i, line := range lines {
+		w, resolved := want[i]
+		switch {
+		case resolved && line.Timestamp == nil:
+			t.Errorf("line %d: unresolved, want %v", i, w)
+		case resolved && !line.Timestamp.Time.Equal(w):
+			t.Errorf("line %d: %v, want %v", i, line.Timestamp.Time, w)
+		case !resolved && line.Timestamp != nil:
+			t.Errorf("line %d: resolved to %v, want it left unresolved", i, line.Timestamp.Time)
+		}
+	}
+	if !HasUnresolvedUptime(lines) {
+		t.Error("HasUnresolvedUptime = false, want true for the boot without a boot time")
+	}
+}
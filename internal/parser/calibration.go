@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"math"
+	"time"
+)
+
+// maxCalibrationSkew bounds the fitted uptime rate; fits deviating further from 1 s/s
+// are considered unreliable and fall back to an offset-only fit
+const maxCalibrationSkew = 0.01
+
+// uptimeAnchor pairs an absolute timestamp with an uptime value seen near it
+type uptimeAnchor struct {
+	uptime float64
+	time   time.Time
+	weight float64 // Higher weight for pairs found closer together in the file
+}
+
+// uptimeCalibration maps uptime seconds to wall-clock time: time = base + offset + rate*(uptime - uptime0)
+type uptimeCalibration struct {
+	base    time.Time
+	uptime0 float64
+	offset  float64 // Seconds
+	rate    float64 // Wall-clock seconds per uptime second
+}
+
+// fitUptimeCalibration fits a weighted least-squares mapping from uptime to wall-clock time
+// over all anchor pairs. It reports false if there are too few anchors to calibrate.
+func fitUptimeCalibration(anchors []uptimeAnchor) (uptimeCalibration, bool) {
+	if len(anchors) < 2 {
+		return uptimeCalibration{}, false
+	}
+
+	cal := uptimeCalibration{
+		base:    anchors[0].time,
+		uptime0: anchors[0].uptime,
+		rate:    1,
+	}
+
+	// Work relative to the first anchor to keep float precision
+	var sumW, sumX, sumY, sumXX, sumXY float64
+	for _, a := range anchors {
+		x := a.uptime - cal.uptime0
+		y := a.time.Sub(cal.base).Seconds()
+		sumW += a.weight
+		sumX += a.weight * x
+		sumY += a.weight * y
+		sumXX += a.weight * x * x
+		sumXY += a.weight * x * y
+	}
+	if sumW == 0 {
+		return uptimeCalibration{}, false
+	}
+
+	meanX := sumX / sumW
+	meanY := sumY / sumW
+	varX := sumXX/sumW - meanX*meanX
+	if varX > 1e-9 {
+		rate := (sumXY/sumW - meanX*meanY) / varX
+		if math.Abs(rate-1) <= maxCalibrationSkew {
+			cal.rate = rate
+		}
+	}
+	cal.offset = meanY - cal.rate*meanX
+
+	return cal, true
+}
+
+// resolve converts an uptime value to wall-clock time
+func (c uptimeCalibration) resolve(uptime float64) time.Time {
+	seconds := c.offset + c.rate*(uptime-c.uptime0)
+	return c.base.Add(time.Duration(seconds * float64(time.Second)))
+}
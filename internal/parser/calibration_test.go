@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"log-interleaver/pkg/timestamp"
+)
+
+var bootStart = time.Date(2026, 1, 11, 14, 0, 0, 0, time.UTC)
+
+// anchorsAt returns anchors of the uptimes to the wall clock of a machine booted at bootStart
+// whose clock runs rate wall-clock seconds per uptime second
+func anchorsAt(rate float64, uptimes ...float64) []uptimeAnchor {
+	anchors := make([]uptimeAnchor, len(uptimes))
+	for i, uptime := range uptimes {
+		anchors[i] = uptimeAnchor{uptime: uptime, time: bootStart.Add(time.Duration(rate * uptime * float64(time.Second))), weight: 1}
+	}
+	return anchors
+}
+
+// absolute returns a line with an absolute timestamp the seconds after bootStart
+func absolute(seconds float64) *LogLine {
+	return &LogLine{Timestamp: &timestamp.Timestamp{Time: bootStart.Add(time.Duration(seconds * float64(time.Second))), Type: timestamp.TypeAbsolute}}
+}
+
+// uptime returns a line with an uptime timestamp
+func uptime(seconds float64) *LogLine {
+	return &LogLine{UptimeSec: seconds, HasUptime: true}
+}
+
+func TestFitUptimeCalibration(t *testing.T) {
+	tests := []struct {
+		name     string
+		anchors  []uptimeAnchor
+		wantRate float64
+		uptime   float64
+		want     time.Time
+	}{
+		{
+			name:     "offset",
+			anchors:  anchorsAt(1, 100, 200, 300),
+			wantRate: 1,
+			uptime:   1000,
+			want:     bootStart.Add(1000 * time.Second),
+		},
+		{
+			name:     "rate",
+			anchors:  anchorsAt(1.0005, 100, 2000, 4000),
+			wantRate: 1.0005,
+			uptime:   10000,
+			want:     bootStart.Add(10005 * time.Second),
+		},
+		{
+			// A rate this far off 1 s/s is a mismatched anchor, not a clock error
+			name:     "rate beyond the skew",
+			anchors:  anchorsAt(1.05, 100, 300),
+			wantRate: 1,
+			uptime:   200,
+			want:     bootStart.Add(210 * time.Second),
+		},
+		{
+			name:     "equal uptimes",
+			anchors:  anchorsAt(1, 100, 100),
+			wantRate: 1,
+			uptime:   150,
+			want:     bootStart.Add(150 * time.Second),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cal, ok := fitUptimeCalibration(tt.anchors)
+			if !ok {
+				t.Fatal("fitUptimeCalibration: ok=false")
+			}
+			if math.Abs(cal.rate-tt.wantRate) > 1e-9 {
+				t.Errorf("rate %v, want %v", cal.rate, tt.wantRate)
+			}
+			if got := cal.resolve(tt.uptime); (got.Sub(tt.want)).Abs() > time.Microsecond {
+				t.Errorf("resolve(%v) = %v, want %v", tt.uptime, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitUptimeCalibrationWeights(t *testing.T) {
+	// An anchor 10 s off with a tenth of the weight moves the fit by a fraction of that
+	anchors := anchorsAt(1, 100, 200, 300)
+	anchors = append(anchors, uptimeAnchor{uptime: 400, time: bootStart.Add(410 * time.Second), weight: 0.1})
+	cal, ok := fitUptimeCalibration(anchors)
+	if !ok {
+		t.Fatal("fitUptimeCalibration: ok=false")
+	}
+	if got := cal.resolve(200).Sub(bootStart.Add(200 * time.Second)); got.Abs() > time.Second {
+		t.Errorf("resolve(200) off by %v, want less than 1s", got)
+	}
+
+	if _, ok := fitUptimeCalibration(anchorsAt(1, 100)); ok {
+		t.Error("fitUptimeCalibration with one anchor: ok=true, want false")
+	}
+}
+
+func TestResolveUptimeTimestamps(t *testing.T) {
+	// Absolute lines, each after an uptime line of a machine booted 29.9 s before bootStart
+	lines := []*LogLine{uptime(39.9), absolute(10), uptime(129.9), absolute(100), uptime(500), uptime(629.9), absolute(600)}
+	if err := ResolveUptimeTimestamps(lines); err != nil {
+		t.Fatalf("ResolveUptimeTimestamps: %v", err)
+	}
+	for i, want := range map[int]float64{0: 10, 2: 100, 4: 470.1, 5: 600} {
+		ts := lines[i].Timestamp
+		if ts == nil {
+			t.Errorf("line %d: no timestamp", i)
+			continue
+		}
+		if got := ts.Time.Sub(bootStart).Seconds(); math.Abs(got-want) > 1e-3 {
+			t.Errorf("line %d: %vs after bootStart, want %vs", i, got, want)
+		}
+	}
+
+	if err := ResolveUptimeTimestamps([]*LogLine{uptime(40)}); err == nil {
+		t.Error("ResolveUptimeTimestamps without absolute timestamps succeeded, want an error")
+	}
+}
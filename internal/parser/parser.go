@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"log-interleaver/pkg/timestamp"
+	"math"
 	"time"
 )
 
@@ -10,9 +11,37 @@ import (
 type LogLine struct {
 	OriginalLine string
 	Tag          string // Derived from filename (e.g., "daemon", "e825", "e830")
+	File         string // Source file name
 	Timestamp    *timestamp.Timestamp
 	UptimeSec    float64 // For uptime lines, store the uptime value
+	HasUptime    bool    // Whether the line carries an uptime, which is 0 s for the first kernel line
 	LineNumber   int
+	Continuation []string          // Following timestamp-less lines grouped into this entry (e.g., stack traces)
+	Fields       map[string]string // Fields of structured (JSON) log lines by dotted path
+}
+
+// HasTime reports whether the line carries a timestamp, either absolute or an uptime value
+// that is resolved later
+func (l *LogLine) HasTime() bool {
+	return l.Timestamp != nil || l.HasUptime
+}
+
+// GroupContinuations attaches timestamp-less lines to the preceding line with a timestamp,
+// so multi-line entries (Go panics, Java stack traces, indented dumps) stay together.
+// Lines before the first timestamped line are kept as separate lines.
+func GroupContinuations(lines []*LogLine) []*LogLine {
+	var grouped []*LogLine
+	var entry *LogLine
+	for _, line := range lines {
+		if line.HasTime() {
+			entry = line
+		} else if entry != nil {
+			entry.Continuation = append(entry.Continuation, line.OriginalLine)
+			continue
+		}
+		grouped = append(grouped, line)
+	}
+	return grouped
 }
 
 // GetTimestamp returns the timestamp, or nil if not available
@@ -22,7 +51,9 @@ func (l *LogLine) GetTimestamp() *timestamp.Timestamp {
 
 // Parser parses log lines and extracts timestamp information
 type Parser struct {
-	tag string
+	tag        string
+	formats    []*timestamp.CustomFormat // User-defined formats, tried before the built-in ones
+	jsonFields []JSONTimestampField      // Timestamp fields of structured (JSON) log lines
 }
 
 // NewParser creates a new parser for a specific log file tag
@@ -30,6 +61,12 @@ func NewParser(tag string) *Parser {
 	return &Parser{tag: tag}
 }
 
+// AddFormat registers a user-defined timestamp format for this parser.
+// Custom formats are tried in registration order before the built-in formats.
+func (p *Parser) AddFormat(format *timestamp.CustomFormat) {
+	p.formats = append(p.formats, format)
+}
+
 // ParseLine parses a single log line and extracts timestamp information
 func (p *Parser) ParseLine(line string, lineNum int) *LogLine {
 	logLine := &LogLine{
@@ -38,6 +75,19 @@ func (p *Parser) ParseLine(line string, lineNum int) *LogLine {
 		LineNumber:   lineNum,
 	}
 
+	// Structured (JSON) lines carry their timestamp in a field
+	if len(p.jsonFields) > 0 && p.parseStructured(logLine) {
+		return logLine
+	}
+
+	// Try user-defined formats first
+	for _, format := range p.formats {
+		if ts, err := format.Parse(line); err == nil {
+			logLine.Timestamp = ts
+			return logLine
+		}
+	}
+
 	// Try to parse different timestamp formats
 	// 1. Try absolute format (I0111 14:03:55.976211)
 	if ts, err := timestamp.ParseAbsolute(line); err == nil {
@@ -45,21 +95,39 @@ func (p *Parser) ParseLine(line string, lineNum int) *LogLine {
 		return logLine
 	}
 
-	// 2. Try full date-time format (2026-01-11 09:04:29)
+	// 2. Try RFC 3339 format (2026-01-12T11:36:14.788Z, 2026-01-12T11:36:14+02:00)
+	if ts, err := timestamp.ParseRFC3339(line); err == nil {
+		logLine.Timestamp = ts
+		return logLine
+	}
+
+	// 3. Try full date-time format (2026-01-11 09:04:29)
 	if ts, err := timestamp.ParseFullDateTime(line); err == nil {
 		logLine.Timestamp = ts
 		return logLine
 	}
 
-	// 3. Try Linux/Unix timestamp format (T-BC[1768140305]:)
+	// 4. Try leading epoch format (1768221374788, 1768221374.788123)
+	if ts, err := timestamp.ParseEpoch(line); err == nil {
+		logLine.Timestamp = ts
+		return logLine
+	}
+
+	// 5. Try Linux/Unix timestamp format (T-BC[1768140305]:)
 	if ts, err := timestamp.ParseLinux(line); err == nil {
 		logLine.Timestamp = ts
 		return logLine
 	}
 
-	// 4. Try uptime format (ptp4l[275313.748]:)
+	// 6. Try kernel log format ([ 1234.567890] or kmsg records), resolved later like uptime
+	if uptime, ok := timestamp.ParseKernel(line); ok {
+		logLine.UptimeSec, logLine.HasUptime = uptime, true
+		return logLine
+	}
+
+	// 7. Try uptime format (ptp4l[275313.748]:)
 	if uptime, ok := timestamp.ParseUptime(line); ok {
-		logLine.UptimeSec = uptime
+		logLine.UptimeSec, logLine.HasUptime = uptime, true
 		// Timestamp will be resolved later using nearest absolute timestamp
 		return logLine
 	}
@@ -68,15 +136,59 @@ func (p *Parser) ParseLine(line string, lineNum int) *LogLine {
 	return logLine
 }
 
-// ResolveUptimeTimestamps resolves uptime timestamps by finding the nearest absolute timestamp
-// This function processes all log lines and converts uptime timestamps to absolute timestamps
+// uptimeResetThreshold is how far (in seconds) an uptime value must drop below the highest
+// uptime seen so far to be considered a reboot rather than slightly out-of-order logging
+const uptimeResetThreshold = 60.0
+
+// ResolveUptimeTimestamps resolves uptime timestamps to absolute timestamps.
+// The lines are split into boot segments at uptime resets (reboots), and each segment is
+// resolved against its own absolute timestamps. Uptime lines in segments without absolute
+// timestamps are left unresolved.
 func ResolveUptimeTimestamps(lines []*LogLine) error {
+	resolved := false
+	for _, segment := range splitBootSegments(lines) {
+		if err := resolveUptimeSegment(segment); err == nil {
+			resolved = true
+		}
+	}
+	if !resolved {
+		return fmt.Errorf("no absolute timestamps found to resolve uptime timestamps")
+	}
+	return nil
+}
+
+// splitBootSegments splits lines at uptime resets, where an uptime value drops sharply
+// below the highest uptime seen in the current segment
+func splitBootSegments(lines []*LogLine) [][]*LogLine {
+	var segments [][]*LogLine
+	start := 0
+	maxUptime := 0.0
+	for i, line := range lines {
+		if !line.HasUptime {
+			continue
+		}
+		if line.UptimeSec < maxUptime-uptimeResetThreshold {
+			segments = append(segments, lines[start:i])
+			start = i
+			maxUptime = 0
+		}
+		maxUptime = math.Max(maxUptime, line.UptimeSec)
+	}
+	return append(segments, lines[start:])
+}
+
+// resolveUptimeSegment resolves the uptime timestamps of a single boot segment.
+// When enough absolute timestamps have a nearby uptime, a weighted least-squares mapping
+// (uptime → wall clock) is fitted over all of them; otherwise each uptime line is resolved
+// against its nearest absolute timestamp.
+func resolveUptimeSegment(lines []*LogLine) error {
 	// First pass: collect all absolute timestamps with their line numbers and uptimes
 	type absTimestamp struct {
-		lineNum   int
-		time      time.Time
-		uptime    float64
-		hasUptime bool
+		lineNum    int
+		time       time.Time
+		uptime     float64
+		hasUptime  bool
+		uptimeDist int // Distance in lines to the paired uptime line
 	}
 
 	var absTimestamps []absTimestamp
@@ -89,18 +201,20 @@ func ResolveUptimeTimestamps(lines []*LogLine) error {
 			// Check if there's an uptime timestamp nearby (within a few lines)
 			// Look backward for uptime
 			for j := i - 1; j >= 0 && j >= i-5; j-- {
-				if lines[j].UptimeSec > 0 {
+				if lines[j].HasUptime {
 					abs.uptime = lines[j].UptimeSec
 					abs.hasUptime = true
+					abs.uptimeDist = i - j
 					break
 				}
 			}
 			// If not found backward, look forward
 			if !abs.hasUptime {
 				for j := i + 1; j < len(lines) && j <= i+5; j++ {
-					if lines[j].UptimeSec > 0 {
+					if lines[j].HasUptime {
 						abs.uptime = lines[j].UptimeSec
 						abs.hasUptime = true
+						abs.uptimeDist = j - i
 						break
 					}
 				}
@@ -113,9 +227,33 @@ func ResolveUptimeTimestamps(lines []*LogLine) error {
 		return fmt.Errorf("no absolute timestamps found to resolve uptime timestamps")
 	}
 
+	// Fit a calibration from all anchor pairs, weighting pairs found closer together higher
+	var anchors []uptimeAnchor
+	for _, a := range absTimestamps {
+		if a.hasUptime {
+			anchors = append(anchors, uptimeAnchor{
+				uptime: a.uptime,
+				time:   a.time,
+				weight: 1 / float64(a.uptimeDist),
+			})
+		}
+	}
+	if cal, ok := fitUptimeCalibration(anchors); ok {
+		for _, line := range lines {
+			if line.HasUptime && line.Timestamp == nil {
+				line.Timestamp = &timestamp.Timestamp{
+					Time:      cal.resolve(line.UptimeSec),
+					Type:      timestamp.TypeAbsolute,
+					UptimeSec: line.UptimeSec,
+				}
+			}
+		}
+		return nil
+	}
+
 	// Second pass: resolve uptime timestamps
 	for i, line := range lines {
-		if line.UptimeSec > 0 && line.Timestamp == nil {
+		if line.HasUptime && line.Timestamp == nil {
 			// Find the nearest absolute timestamp
 			// Prefer forward-looking (as in the example: uptime line followed by absolute timestamp)
 			var nearestAbs *absTimestamp
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSplitBootSegments(t *testing.T) {
+	tests := []struct {
+		name    string
+		uptimes []float64 // 0 for lines without an uptime
+		want    []int     // Lengths of the segments
+	}{
+		{"one boot", []float64{10, 0, 20, 30}, []int{4}},
+		{"slightly out of order", []float64{100, 90, 110}, []int{3}},
+		{"reboot", []float64{100, 200, 0, 5, 10}, []int{3, 2}},
+		{"two reboots", []float64{500, 3, 4, 1000, 2}, []int{1, 3, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]*LogLine, len(tt.uptimes))
+			for i, u := range tt.uptimes {
+				lines[i] = &LogLine{}
+				if u != 0 {
+					lines[i] = uptime(u)
+				}
+			}
+			var got []int
+			for _, segment := range splitBootSegments(lines) {
+				got = append(got, len(segment))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("segment lengths %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveUptimeTimestampsReboot(t *testing.T) {
+	// Booted 29.9 s before bootStart, rebooted at 1000 s after it, and rebooted again into a
+	// segment without absolute timestamps
+	lines := []*LogLine{
+		uptime(39.9), absolute(10), uptime(129.9), absolute(100),
+		uptime(5.5), absolute(1005), uptime(200.5),
+		uptime(3),
+	}
+	if err := ResolveUptimeTimestamps(lines); err != nil {
+		t.Fatalf("ResolveUptimeTimestamps: %v", err)
+	}
+	for i, want := range map[int]float64{0: 10, 2: 100, 4: 1005, 6: 1200} {
+		ts := lines[i].Timestamp
+		if ts == nil {
+			t.Errorf("line %d: no timestamp", i)
+			continue
+		}
+		if got := ts.Time.Sub(bootStart).Seconds(); math.Abs(got-want) > 1e-3 {
+			t.Errorf("line %d: %vs after bootStart, want %vs", i, got, want)
+		}
+	}
+	if ts := lines[7].Timestamp; ts != nil {
+		t.Errorf("line 7: resolved to %v, want it left unresolved", ts.Time.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"log-interleaver/pkg/timestamp"
+	"strconv"
+	"strings"
+)
+
+// JSONTimestampField locates the timestamp of structured (JSON) log lines
+type JSONTimestampField struct {
+	Path   string // Dotted field path (e.g., "ts", "metadata.creationTime")
+	Layout string // Optional Go time layout for string values (default: RFC 3339, or epoch numbers)
+}
+
+// AddJSONField registers a timestamp field for structured log lines. Lines that are JSON objects
+// are decoded, their fields exposed in LogLine.Fields, and the timestamp read from the first
+// registered field present in the line.
+func (p *Parser) AddJSONField(field JSONTimestampField) {
+	p.jsonFields = append(p.jsonFields, field)
+}
+
+// parseStructured decodes a JSON object line into the log line's fields and resolves its
+// timestamp from the registered timestamp fields. It reports whether a timestamp was found.
+func (p *Parser) parseStructured(logLine *LogLine) bool {
+	line := strings.TrimSpace(logLine.OriginalLine)
+	if !strings.HasPrefix(line, "{") {
+		return false
+	}
+
+	logLine.Fields = DecodeFields(line)
+	if logLine.Fields == nil {
+		return false
+	}
+
+	for _, field := range p.jsonFields {
+		value, ok := logLine.Fields[field.Path]
+		if !ok {
+			continue
+		}
+		if ts, err := timestamp.ParseValue(value, field.Layout); err == nil {
+			logLine.Timestamp = ts
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeFields returns the fields of a JSON object line by dotted path, or nil if the line is
+// not a JSON object
+func DecodeFields(line string) map[string]string {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber() // Keep epoch timestamps exact
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil
+	}
+	fields := make(map[string]string)
+	flattenFields("", object, fields)
+	return fields
+}
+
+// flattenFields stores the leaf values of a decoded JSON value under dotted paths
+// ("metadata.creationTime", "items.0.name")
+func flattenFields(prefix string, value interface{}, fields map[string]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			flattenFields(join(key), child, fields)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenFields(join(strconv.Itoa(i)), child, fields)
+		}
+	case string:
+		fields[prefix] = v
+	case json.Number:
+		fields[prefix] = v.String()
+	case bool:
+		fields[prefix] = strconv.FormatBool(v)
+	case nil:
+		// Null values are omitted
+	default:
+		fields[prefix] = fmt.Sprint(v)
+	}
+}
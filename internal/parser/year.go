@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"log-interleaver/pkg/timestamp"
+	"time"
+)
+
+// yearRolloverThreshold is how far a yearless timestamp must jump backwards
+// (compared within the same year) to be treated as a Dec→Jan rollover
+const yearRolloverThreshold = 182 * 24 * time.Hour
+
+// InferYears corrects the year of timestamps whose format carries no year (e.g., "I0111 14:03:55").
+// The year of the first such line is taken from baseYear if set (> 0), otherwise from the
+// nearest full-date timestamp in the same file, otherwise from the reference time (typically the
+// file modification time), assuming the last line was written before it. Dec→Jan rollovers within
+// the file advance the year.
+func InferYears(lines []*LogLine, baseYear int, reference time.Time) {
+	// Collect lines with an assumed year, in file order
+	var inferred []int
+	for i, line := range lines {
+		if line.Timestamp != nil && line.Timestamp.YearInferred {
+			inferred = append(inferred, i)
+		}
+	}
+	if len(inferred) == 0 {
+		return
+	}
+
+	// Compute each line's year relative to the first one, counting rollovers
+	relYear := make([]int, len(inferred))
+	for k := 1; k < len(inferred); k++ {
+		prev := timestamp.WithYear(lines[inferred[k-1]].Timestamp.Time, 2000)
+		cur := timestamp.WithYear(lines[inferred[k]].Timestamp.Time, 2000)
+		relYear[k] = relYear[k-1]
+		if prev.Sub(cur) > yearRolloverThreshold {
+			relYear[k]++
+		}
+	}
+
+	firstYear := 0
+	switch {
+	case baseYear > 0:
+		firstYear = baseYear
+	case firstYearFromKnown(lines, inferred, relYear, &firstYear):
+	case !reference.IsZero():
+		// The last line must not be later than the reference time
+		k := len(inferred) - 1
+		lastYear := reference.Year()
+		if timestamp.WithYear(lines[inferred[k]].Timestamp.Time, lastYear).After(reference.Add(24 * time.Hour)) {
+			lastYear--
+		}
+		firstYear = lastYear - relYear[k]
+	default:
+		return
+	}
+
+	for k, idx := range inferred {
+		ts := lines[idx].Timestamp
+		ts.Time = timestamp.WithYear(ts.Time, firstYear+relYear[k])
+		ts.YearInferred = false
+	}
+}
+
+// NextYear returns the year of a yearless timestamp following the one at last in the same file:
+// the year of last, or the next year after a Dec→Jan rollover. It continues InferYears across
+// separately parsed parts of a growing file.
+func NextYear(last, next time.Time) int {
+	if timestamp.WithYear(last, 2000).Sub(timestamp.WithYear(next, 2000)) > yearRolloverThreshold {
+		return last.Year() + 1
+	}
+	return last.Year()
+}
+
+// firstYearFromKnown derives the year of the first yearless line from the full-date timestamp
+// closest (by line position) to any yearless line. It reports whether such a timestamp exists.
+func firstYearFromKnown(lines []*LogLine, inferred []int, relYear []int, firstYear *int) bool {
+	bestDist := -1
+	var known time.Time
+	var anchor int // Index into inferred of the yearless line nearest to the known timestamp
+	k := 0
+	for i, line := range lines {
+		if line.Timestamp == nil || line.Timestamp.YearInferred {
+			continue
+		}
+		// Advance to the yearless line nearest to line i
+		for k+1 < len(inferred) && inferred[k+1] <= i {
+			k++
+		}
+		for _, candidate := range []int{k, k + 1} {
+			if candidate >= len(inferred) {
+				continue
+			}
+			dist := abs(inferred[candidate] - i)
+			if bestDist < 0 || dist < bestDist {
+				bestDist = dist
+				known = line.Timestamp.Time
+				anchor = candidate
+			}
+		}
+	}
+	if bestDist < 0 {
+		return false
+	}
+
+	// Pick the year that puts the anchor line closest to the known timestamp
+	anchorTime := lines[inferred[anchor]].Timestamp.Time
+	bestYear := known.Year()
+	bestDiff := time.Duration(-1)
+	for year := known.Year() - 1; year <= known.Year()+1; year++ {
+		diff := timestamp.WithYear(anchorTime, year).Sub(known)
+		if diff < 0 {
+			diff = -diff
+		}
+		if bestDiff < 0 || diff < bestDiff {
+			bestDiff = diff
+			bestYear = year
+		}
+	}
+
+	*firstYear = bestYear - relYear[anchor]
+	return true
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"log-interleaver/pkg/timestamp"
+)
+
+// yearless returns a line with the time of a timestamp without a year, which the parser gives an
+// assumed year
+func yearless(month time.Month, day, hour, min int) *LogLine {
+	t := time.Date(2000, month, day, hour, min, 0, 0, time.UTC)
+	return &LogLine{Timestamp: &timestamp.Timestamp{Time: t, Type: timestamp.TypeAbsolute, YearInferred: true}}
+}
+
+// dated returns a line with a full-date timestamp
+func dated(year int, month time.Month, day, hour, min int) *LogLine {
+	t := time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	return &LogLine{Timestamp: &timestamp.Timestamp{Time: t, Type: timestamp.TypeAbsolute}}
+}
+
+func TestInferYears(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []*LogLine
+		baseYear  int
+		reference time.Time
+		want      []int // Year of each line, 0 for lines without a timestamp
+	}{
+		{
+			name:     "base year",
+			lines:    []*LogLine{yearless(time.March, 1, 10, 0), yearless(time.March, 1, 11, 0)},
+			baseYear: 2024,
+			want:     []int{2024, 2024},
+		},
+		{
+			name:     "Dec to Jan rollover",
+			lines:    []*LogLine{yearless(time.December, 31, 23, 59), {}, yearless(time.January, 1, 0, 1), yearless(time.January, 1, 0, 2)},
+			baseYear: 2025,
+			want:     []int{2025, 0, 2026, 2026},
+		},
+		{
+			name:     "out of order lines within a day",
+			lines:    []*LogLine{yearless(time.June, 1, 10, 5), yearless(time.June, 1, 10, 0)},
+			baseYear: 2025,
+			want:     []int{2025, 2025},
+		},
+		{
+			name:      "nearest full date",
+			lines:     []*LogLine{dated(2023, time.June, 1, 9, 0), yearless(time.June, 1, 10, 0), yearless(time.June, 1, 11, 0)},
+			reference: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:      []int{2023, 2023, 2023},
+		},
+		{
+			name:  "full date across the new year",
+			lines: []*LogLine{yearless(time.December, 31, 23, 50), dated(2026, time.January, 1, 0, 10), yearless(time.January, 1, 0, 20)},
+			want:  []int{2025, 2026, 2026},
+		},
+		{
+			name:      "reference time",
+			lines:     []*LogLine{yearless(time.January, 11, 14, 0)},
+			reference: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			want:      []int{2026},
+		},
+		{
+			name:      "reference time in the next year",
+			lines:     []*LogLine{yearless(time.December, 30, 14, 0), yearless(time.January, 2, 9, 0)},
+			reference: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+			want:      []int{2025, 2026},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			InferYears(tt.lines, tt.baseYear, tt.reference)
+			for i, line := range tt.lines {
+				if line.Timestamp == nil {
+					continue
+				}
+				if got := line.Timestamp.Time.Year(); got != tt.want[i] {
+					t.Errorf("line %d: year %d, want %d", i, got, tt.want[i])
+				}
+				if line.Timestamp.YearInferred {
+					t.Errorf("line %d: year still inferred", i)
+				}
+			}
+		})
+	}
+}
+
+func TestInferYearsWithoutContext(t *testing.T) {
+	lines := []*LogLine{yearless(time.January, 11, 14, 0)}
+	InferYears(lines, 0, time.Time{})
+	if ts := lines[0].Timestamp; ts.Time.Year() != 2000 || !ts.YearInferred {
+		t.Errorf("year %d, inferred %v; want the assumed year left as is", ts.Time.Year(), ts.YearInferred)
+	}
+}
@@ -0,0 +1,592 @@
+// Package pcap decodes the PTPv2 messages of packet captures (pcap and pcapng files, e.g., from
+// tcpdump -j adapter_unsynced --time-stamp-precision=nano) into synthetic log lines timestamped
+// by the capture (hardware) timestamps, so wire traffic can be interleaved with daemon logs.
+// PTP is found over Ethernet (ethertype 0x88F7, also VLAN-tagged), and UDP over IPv4 or IPv6
+// (ports 319 and 320), on Ethernet, Linux cooked, and raw IP links.
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageType is the type of a PTP message
+type MessageType uint8
+
+const (
+	Sync               MessageType = 0x0
+	DelayReq           MessageType = 0x1
+	PdelayReq          MessageType = 0x2
+	PdelayResp         MessageType = 0x3
+	FollowUp           MessageType = 0x8
+	DelayResp          MessageType = 0x9
+	PdelayRespFollowUp MessageType = 0xA
+	Announce           MessageType = 0xB
+	Signaling          MessageType = 0xC
+	Management         MessageType = 0xD
+)
+
+// messageNames are the message names written in the lines, as in IEEE 1588
+var messageNames = map[MessageType]string{
+	Sync:               "Sync",
+	DelayReq:           "Delay_Req",
+	PdelayReq:          "Pdelay_Req",
+	PdelayResp:         "Pdelay_Resp",
+	FollowUp:           "Follow_Up",
+	DelayResp:          "Delay_Resp",
+	PdelayRespFollowUp: "Pdelay_Resp_Follow_Up",
+	Announce:           "Announce",
+	Signaling:          "Signaling",
+	Management:         "Management",
+}
+
+// String returns the IEEE 1588 name of the message type
+func (t MessageType) String() string {
+	if name, ok := messageNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type_%d", uint8(t))
+}
+
+// PortIdentity identifies a PTP port by its clock identity and port number
+type PortIdentity struct {
+	Clock [8]byte
+	Port  uint16
+}
+
+// String formats the identity as linuxptp does, e.g., 507c6f.fffe.1fb1c8-1
+func (p PortIdentity) String() string {
+	c := p.Clock
+	return fmt.Sprintf("%02x%02x%02x.%02x%02x.%02x%02x%02x-%d", c[0], c[1], c[2], c[3], c[4], c[5], c[6], c[7], p.Port)
+}
+
+// AnnounceData is the body of an Announce message
+type AnnounceData struct {
+	UTCOffset     int16
+	Priority1     uint8
+	ClockClass    uint8
+	ClockAccuracy uint8
+	Variance      uint16 // offsetScaledLogVariance
+	Priority2     uint8
+	Grandmaster   [8]byte
+	StepsRemoved  uint16
+	TimeSource    uint8
+}
+
+// Message is a decoded PTP message with its capture time
+type Message struct {
+	Time        time.Time // Capture timestamp of the packet
+	Type        MessageType
+	Transport   string // "l2", "udp4", or "udp6"
+	From        string // Source MAC or IP address
+	Domain      uint8
+	Sequence    uint16
+	Source      PortIdentity
+	TwoStep     bool
+	Correction  float64 // correctionField in nanoseconds
+	LogInterval int8
+
+	// Timestamp carried by the body: origin (Sync, Delay_Req, Announce), precise origin
+	// (Follow_Up), receive (Delay_Resp), request receipt (Pdelay_Resp), or response origin
+	// (Pdelay_Resp_Follow_Up); zero for messages without one
+	Timestamp  time.Time
+	Requesting *PortIdentity // Requesting port of Delay_Resp, Pdelay_Resp, and Pdelay_Resp_Follow_Up
+	Announce   *AnnounceData
+
+	// Origin timestamp minus the capture time of the Sync it belongs to, for one-step Syncs and
+	// Follow_Ups matched to their Sync (the wire offset plus path delay, measured against the
+	// capture clock)
+	OriginDelta    time.Duration
+	HasOriginDelta bool
+}
+
+// String formats the message as a log line that starts with the capture time in RFC 3339 and
+// continues with key=value fields, e.g.:
+//
+//	2026-01-11T14:05:50.123456789Z Follow_Up domain=24 seq=1234 src=507c6f.fffe.1fb1c8-1 from=10.0.0.1 via=udp4 precise_origin=1768140350.123456700 correction=0 origin_delta=-89
+func (m Message) String() string {
+	var b strings.Builder
+	b.WriteString(m.Time.UTC().Format("2006-01-02T15:04:05.000000000Z"))
+	fmt.Fprintf(&b, " %s domain=%d seq=%d src=%s", m.Type, m.Domain, m.Sequence, m.Source)
+	if m.From != "" {
+		fmt.Fprintf(&b, " from=%s", m.From)
+	}
+	fmt.Fprintf(&b, " via=%s", m.Transport)
+	if m.TwoStep && (m.Type == Sync || m.Type == PdelayResp) {
+		b.WriteString(" two_step")
+	}
+	if !m.Timestamp.IsZero() {
+		key := "origin"
+		switch m.Type {
+		case FollowUp:
+			key = "precise_origin"
+		case DelayResp:
+			key = "receive"
+		case PdelayResp:
+			key = "request_receipt"
+		case PdelayRespFollowUp:
+			key = "response_origin"
+		}
+		fmt.Fprintf(&b, " %s=%d.%09d", key, m.Timestamp.Unix(), m.Timestamp.Nanosecond())
+	}
+	fmt.Fprintf(&b, " correction=%s", strconv.FormatFloat(m.Correction, 'f', -1, 64))
+	if m.Type != Signaling && m.Type != Management {
+		fmt.Fprintf(&b, " interval=%d", m.LogInterval)
+	}
+	if m.Requesting != nil {
+		fmt.Fprintf(&b, " requesting=%s", m.Requesting)
+	}
+	if a := m.Announce; a != nil {
+		g := a.Grandmaster
+		fmt.Fprintf(&b, " gm=%02x%02x%02x.%02x%02x.%02x%02x%02x class=%d accuracy=0x%02x variance=0x%04x priority1=%d priority2=%d steps=%d time_source=0x%02x utc_offset=%d",
+			g[0], g[1], g[2], g[3], g[4], g[5], g[6], g[7], a.ClockClass, a.ClockAccuracy, a.Variance, a.Priority1, a.Priority2, a.StepsRemoved, a.TimeSource, a.UTCOffset)
+	}
+	if m.HasOriginDelta {
+		fmt.Fprintf(&b, " origin_delta=%d", m.OriginDelta.Nanoseconds())
+	}
+	return b.String()
+}
+
+// Lines returns the lines of the PTP messages of a capture, one per message, for interleaving
+func Lines(r io.Reader) ([]byte, error) {
+	messages, err := Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, m := range messages {
+		b.WriteString(m.String())
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+// IsCapture reports whether a file name has a packet capture extension
+func IsCapture(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".pcap") || strings.HasSuffix(name, ".pcapng")
+}
+
+// Link types of the capture interfaces
+const (
+	linkRaw      = 101
+	linkEthernet = 1
+	linkSLL      = 113
+	linkSLL2     = 276
+	linkIPv4     = 228
+	linkIPv6     = 229
+)
+
+// packet is a captured frame
+type packet struct {
+	time time.Time
+	link uint32
+	data []byte
+}
+
+// Decode reads a pcap or pcapng capture and returns its PTP messages in capture order; other
+// packets are skipped
+func Decode(r io.Reader) ([]Message, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+
+	var messages []Message
+	syncs := make(map[syncKey]time.Time) // Capture times of two-step Syncs awaiting their Follow_Up
+	handle := func(p packet) {
+		m, ok := decodeFrame(p)
+		if !ok {
+			return
+		}
+		key := syncKey{m.Source, m.Domain, m.Sequence}
+		switch {
+		case m.Type == Sync && m.TwoStep:
+			syncs[key] = m.Time
+		case m.Type == Sync && !m.Timestamp.IsZero():
+			m.OriginDelta, m.HasOriginDelta = originDelta(m, m.Time), true
+		case m.Type == FollowUp:
+			if captured, ok := syncs[key]; ok {
+				m.OriginDelta, m.HasOriginDelta = originDelta(m, captured), true
+				delete(syncs, key)
+			}
+		}
+		messages = append(messages, m)
+	}
+
+	switch binary.LittleEndian.Uint32(magic) {
+	case 0x0A0D0D0A:
+		err = readPcapng(br, handle)
+	case 0xA1B2C3D4, 0xD4C3B2A1, 0xA1B23C4D, 0x4D3CB2A1:
+		err = readPcap(br, handle)
+	default:
+		return nil, fmt.Errorf("failed to read capture: not a pcap or pcapng file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// syncKey matches Follow_Up messages to their Sync
+type syncKey struct {
+	source   PortIdentity
+	domain   uint8
+	sequence uint16
+}
+
+// originDelta returns the origin timestamp plus the message's correction field, minus the
+// capture time
+func originDelta(m Message, captured time.Time) time.Duration {
+	return m.Timestamp.Sub(captured) + time.Duration(math.Round(m.Correction))
+}
+
+// readPcap reads the packets of a classic pcap file
+func readPcap(r io.Reader, handle func(packet)) error {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("failed to read pcap header: %w", err)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	magic := order.Uint32(header)
+	if magic == 0xD4C3B2A1 || magic == 0x4D3CB2A1 {
+		order = binary.BigEndian
+		magic = order.Uint32(header)
+	}
+	fraction := time.Microsecond
+	if magic == 0xA1B23C4D {
+		fraction = time.Nanosecond
+	}
+	link := order.Uint32(header[20:]) & 0x0FFFFFFF
+
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				// Captures cut off while being written
+				return nil
+			}
+			return fmt.Errorf("failed to read pcap record: %w", err)
+		}
+		sec, frac := order.Uint32(record), order.Uint32(record[4:])
+		length := order.Uint32(record[8:])
+		if length > 1<<24 {
+			return fmt.Errorf("failed to read pcap record: invalid length %d", length)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil
+		}
+		handle(packet{time: time.Unix(int64(sec), int64(frac)*int64(fraction)), link: link, data: data})
+	}
+}
+
+// pcapngInterface is an interface of a pcapng section
+type pcapngInterface struct {
+	link       uint32
+	resolution float64 // Seconds per timestamp unit
+	offset     int64   // Seconds added to the timestamps
+}
+
+// readPcapng reads the packets of a pcapng file
+func readPcapng(r io.Reader, handle func(packet)) error {
+	var order binary.ByteOrder = binary.LittleEndian
+	var interfaces []pcapngInterface
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read pcapng block: %w", err)
+		}
+		blockType := binary.LittleEndian.Uint32(header)
+		if blockType == 0x0A0D0D0A {
+			// A section header sets the byte order of its section, from its byte-order magic
+			magic := make([]byte, 4)
+			if _, err := io.ReadFull(r, magic); err != nil {
+				return fmt.Errorf("failed to read pcapng section header: %w", err)
+			}
+			order = binary.LittleEndian
+			if binary.BigEndian.Uint32(magic) == 0x1A2B3C4D {
+				order = binary.BigEndian
+			}
+			length := order.Uint32(header[4:])
+			if length < 28 || length > 1<<24 {
+				return fmt.Errorf("failed to read pcapng section header: invalid length %d", length)
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(length)-12); err != nil {
+				return nil
+			}
+			interfaces = nil
+			continue
+		}
+
+		blockType = order.Uint32(header)
+		length := order.Uint32(header[4:])
+		if length < 12 || length > 1<<24 {
+			return fmt.Errorf("failed to read pcapng block: invalid length %d", length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil
+		}
+		body = body[:len(body)-4] // Trailing block length
+
+		switch blockType {
+		case 1: // Interface Description Block
+			if len(body) < 8 {
+				continue
+			}
+			iface := pcapngInterface{link: uint32(order.Uint16(body)), resolution: 1e-6}
+			parseOptions(body[8:], order, func(code uint16, value []byte) {
+				switch {
+				case code == 9 && len(value) >= 1: // if_tsresol
+					if value[0]&0x80 != 0 {
+						iface.resolution = math.Pow(2, -float64(value[0]&0x7F))
+					} else {
+						iface.resolution = math.Pow(10, -float64(value[0]))
+					}
+				case code == 14 && len(value) >= 8: // if_tsoffset
+					iface.offset = int64(order.Uint64(value))
+				}
+			})
+			interfaces = append(interfaces, iface)
+		case 6, 2: // Enhanced Packet Block, obsolete Packet Block
+			if len(body) < 20 {
+				continue
+			}
+			id := order.Uint32(body)
+			if blockType == 2 {
+				id = uint32(order.Uint16(body))
+			}
+			if int(id) >= len(interfaces) {
+				continue
+			}
+			iface := interfaces[id]
+			ticks := uint64(order.Uint32(body[4:]))<<32 | uint64(order.Uint32(body[8:]))
+			captured := order.Uint32(body[12:])
+			if int(captured) > len(body)-20 {
+				continue
+			}
+			handle(packet{time: tickTime(ticks, iface), link: iface.link, data: body[20 : 20+captured]})
+		}
+	}
+}
+
+// tickTime converts a pcapng timestamp to a time, exactly for decimal resolutions
+func tickTime(ticks uint64, iface pcapngInterface) time.Time {
+	perSecond := math.Round(1 / iface.resolution)
+	if perSecond >= 1 && perSecond <= 1e9 && math.Abs(perSecond-1/iface.resolution) < 1e-6 {
+		units := uint64(perSecond)
+		sec := ticks / units
+		nsec := (ticks % units) * uint64(1e9) / units
+		return time.Unix(int64(sec)+iface.offset, int64(nsec))
+	}
+	seconds := float64(ticks) * iface.resolution
+	sec := math.Floor(seconds)
+	return time.Unix(int64(sec)+iface.offset, int64((seconds-sec)*1e9))
+}
+
+// parseOptions calls fn with the options of a pcapng block
+func parseOptions(data []byte, order binary.ByteOrder, fn func(code uint16, value []byte)) {
+	for len(data) >= 4 {
+		code, length := order.Uint16(data), int(order.Uint16(data[2:]))
+		if code == 0 || 4+length > len(data) {
+			return
+		}
+		fn(code, data[4:4+length])
+		data = data[4+(length+3)/4*4:]
+	}
+}
+
+// decodeFrame decodes the PTP message of a captured frame
+func decodeFrame(p packet) (Message, bool) {
+	data := p.data
+	var etherType uint16
+	from := ""
+	switch p.link {
+	case linkEthernet:
+		if len(data) < 14 {
+			return Message{}, false
+		}
+		from = net.HardwareAddr(data[6:12]).String()
+		etherType, data = binary.BigEndian.Uint16(data[12:]), data[14:]
+	case linkSLL:
+		if len(data) < 16 {
+			return Message{}, false
+		}
+		if n := binary.BigEndian.Uint16(data[4:]); n == 6 {
+			from = net.HardwareAddr(data[6:12]).String()
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:]), data[16:]
+	case linkSLL2:
+		if len(data) < 20 {
+			return Message{}, false
+		}
+		if data[11] == 6 {
+			from = net.HardwareAddr(data[12:18]).String()
+		}
+		etherType, data = binary.BigEndian.Uint16(data), data[20:]
+	case linkRaw, linkIPv4, linkIPv6:
+		if len(data) == 0 {
+			return Message{}, false
+		}
+		etherType = 0x0800
+		if data[0]>>4 == 6 {
+			etherType = 0x86DD
+		}
+	default:
+		return Message{}, false
+	}
+
+	// VLAN tags (802.1Q, 802.1ad)
+	for (etherType == 0x8100 || etherType == 0x88A8) && len(data) >= 4 {
+		etherType, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+	}
+
+	transport := "l2"
+	switch etherType {
+	case 0x88F7:
+	case 0x0800:
+		if len(data) < 20 || data[0]>>4 != 4 {
+			return Message{}, false
+		}
+		headerLen := int(data[0]&0x0F) * 4
+		fragmented := binary.BigEndian.Uint16(data[6:])&0x1FFF != 0
+		if data[9] != 17 || fragmented || len(data) < headerLen {
+			return Message{}, false
+		}
+		transport, from = "udp4", net.IP(data[12:16]).String()
+		var ok bool
+		if data, ok = udpPayload(data[headerLen:]); !ok {
+			return Message{}, false
+		}
+	case 0x86DD:
+		if len(data) < 40 || data[0]>>4 != 6 {
+			return Message{}, false
+		}
+		next := data[6]
+		transport, from = "udp6", net.IP(data[8:24]).String()
+		data = data[40:]
+		// Skip hop-by-hop, routing, and destination options extension headers
+		for (next == 0 || next == 43 || next == 60) && len(data) >= 8 {
+			length := (int(data[1]) + 1) * 8
+			if length > len(data) {
+				return Message{}, false
+			}
+			next, data = data[0], data[length:]
+		}
+		var ok bool
+		if next != 17 {
+			return Message{}, false
+		}
+		if data, ok = udpPayload(data); !ok {
+			return Message{}, false
+		}
+	default:
+		return Message{}, false
+	}
+
+	m, ok := decodePTP(data)
+	if !ok {
+		return Message{}, false
+	}
+	m.Time, m.Transport, m.From = p.time, transport, from
+	return m, true
+}
+
+// udpPayload returns the payload of a UDP datagram to or from the PTP event or general port
+func udpPayload(data []byte) ([]byte, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	src, dst := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+	if src != 319 && src != 320 && dst != 319 && dst != 320 {
+		return nil, false
+	}
+	return data[8:], true
+}
+
+// decodePTP decodes a PTPv2 message
+func decodePTP(data []byte) (Message, bool) {
+	if len(data) < 34 || data[1]&0x0F != 2 {
+		return Message{}, false
+	}
+	length := int(binary.BigEndian.Uint16(data[2:]))
+	if length < 34 || length > len(data) {
+		return Message{}, false
+	}
+	data = data[:length]
+
+	m := Message{
+		Type:        MessageType(data[0] & 0x0F),
+		Domain:      data[4],
+		TwoStep:     data[6]&0x02 != 0,
+		Correction:  float64(int64(binary.BigEndian.Uint64(data[8:]))) / 65536,
+		Sequence:    binary.BigEndian.Uint16(data[30:]),
+		LogInterval: int8(data[33]),
+	}
+	m.Source = portIdentity(data[20:])
+
+	switch m.Type {
+	case Sync, DelayReq, FollowUp, DelayResp, PdelayReq, PdelayResp, PdelayRespFollowUp, Announce:
+		if len(data) < 44 {
+			return m, true
+		}
+		m.Timestamp = ptpTimestamp(data[34:])
+	}
+	switch m.Type {
+	case DelayResp, PdelayResp, PdelayRespFollowUp:
+		if len(data) >= 54 {
+			requesting := portIdentity(data[44:])
+			m.Requesting = &requesting
+		}
+	case Announce:
+		if len(data) >= 64 {
+			m.Announce = &AnnounceData{
+				UTCOffset:     int16(binary.BigEndian.Uint16(data[44:])),
+				Priority1:     data[47],
+				ClockClass:    data[48],
+				ClockAccuracy: data[49],
+				Variance:      binary.BigEndian.Uint16(data[50:]),
+				Priority2:     data[52],
+				StepsRemoved:  binary.BigEndian.Uint16(data[61:]),
+				TimeSource:    data[63],
+			}
+			copy(m.Announce.Grandmaster[:], data[53:61])
+		}
+	}
+	return m, true
+}
+
+// portIdentity decodes a 10-byte port identity
+func portIdentity(data []byte) PortIdentity {
+	var p PortIdentity
+	copy(p.Clock[:], data[:8])
+	p.Port = binary.BigEndian.Uint16(data[8:])
+	return p
+}
+
+// ptpTimestamp decodes a 10-byte PTP timestamp (48-bit seconds, 32-bit nanoseconds); zero
+// timestamps (e.g., the origin of Delay_Req from most clocks) stay zero
+func ptpTimestamp(data []byte) time.Time {
+	sec := uint64(binary.BigEndian.Uint16(data))<<32 | uint64(binary.BigEndian.Uint32(data[2:]))
+	nsec := binary.BigEndian.Uint32(data[6:])
+	if sec == 0 && nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(sec), int64(nsec))
+}
@@ -0,0 +1,209 @@
+// Package plugin runs external programs that parse the timestamps and extract the metrics of
+// log formats the built-in parsers and regexes cannot handle (e.g., proprietary or binary-encoded
+// vendor logs). A plugin is any executable speaking JSON over its standard input and output: it
+// reads one request object per line and writes one response object per line, in order, until
+// its input is closed. Requests carry batches of lines:
+//
+//	{"method":"timestamps","tag":"vendor","lines":["line", ...]}
+//	{"timestamps":["2026-01-11T14:05:50.123456Z", "", ...]}
+//
+//	{"method":"metrics","lines":[{"tag":"vendor","line":"line","time":"2026-01-11T14:05:50Z"}, ...]}
+//	{"points":[[{"series":"vendor offset","value":12.5}], [], ...]}
+//
+// Responses have one entry per line: an RFC 3339 timestamp ("" = none), or the points of the
+// line, each with a series name, a value, and an optional state. A response {"error":"message"}
+// fails the run. The plugin's standard error is passed through.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/pattern"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// batchSize is the maximum number of lines per request
+const batchSize = 1000
+
+// Plugin is an external program resolving timestamps and extracting metrics. The program is
+// started for each set of lines (a file, or the lines of a metric extraction) and exits when
+// its input is closed, so it needs no state between calls.
+type Plugin struct {
+	Name    string
+	Command string
+	Args    []string
+}
+
+// FromConfig returns the plugins declared in the config by name
+func FromConfig(cfg *config.VisualizationConfig) map[string]*Plugin {
+	plugins := make(map[string]*Plugin, len(cfg.Plugins))
+	for _, p := range cfg.Plugins {
+		plugins[p.Name] = &Plugin{Name: p.Name, Command: p.Command, Args: p.Args}
+	}
+	return plugins
+}
+
+// timestampRequest asks for the timestamps of lines of a tag
+type timestampRequest struct {
+	Method string   `json:"method"`
+	Tag    string   `json:"tag"`
+	Lines  []string `json:"lines"`
+}
+
+// metricsRequest asks for the points of timestamped lines
+type metricsRequest struct {
+	Method string        `json:"method"`
+	Lines  []metricsLine `json:"lines"`
+}
+
+type metricsLine struct {
+	Tag  string `json:"tag"`
+	Line string `json:"line"`
+	Time string `json:"time"`
+}
+
+// response is the union of the responses
+type response struct {
+	Error      string    `json:"error"`
+	Timestamps []string  `json:"timestamps"`
+	Points     [][]point `json:"points"`
+}
+
+type point struct {
+	Series string  `json:"series"`
+	Value  float64 `json:"value"`
+	State  string  `json:"state"`
+}
+
+// ResolveTimestamps returns the timestamps the plugin finds in lines of a tag, with zero times
+// for lines it has none for
+func (p *Plugin) ResolveTimestamps(ctx context.Context, tag string, lines []string) ([]time.Time, error) {
+	times := make([]time.Time, 0, len(lines))
+	err := p.run(ctx, func(call func(request any) (*response, error)) error {
+		for start := 0; start < len(lines); start += batchSize {
+			batch := lines[start:min(start+batchSize, len(lines))]
+			resp, err := call(timestampRequest{Method: "timestamps", Tag: tag, Lines: batch})
+			if err != nil {
+				return err
+			}
+			if len(resp.Timestamps) != len(batch) {
+				return fmt.Errorf("plugin '%s' returned %d timestamps for %d lines", p.Name, len(resp.Timestamps), len(batch))
+			}
+			for _, value := range resp.Timestamps {
+				var t time.Time
+				if value != "" {
+					if t, err = time.Parse(time.RFC3339Nano, value); err != nil {
+						return fmt.Errorf("plugin '%s' returned an invalid timestamp: %w", p.Name, err)
+					}
+				}
+				times = append(times, t)
+			}
+		}
+		return nil
+	})
+	return times, err
+}
+
+// ExtractPoints returns the points the plugin extracts from each line. Points carry the series
+// name returned by the plugin and the timestamp of their line.
+func (p *Plugin) ExtractPoints(ctx context.Context, lines []*parser.LogLine) ([][]pattern.MetricPoint, error) {
+	points := make([][]pattern.MetricPoint, 0, len(lines))
+	err := p.run(ctx, func(call func(request any) (*response, error)) error {
+		for start := 0; start < len(lines); start += batchSize {
+			batch := lines[start:min(start+batchSize, len(lines))]
+			req := metricsRequest{Method: "metrics", Lines: make([]metricsLine, len(batch))}
+			for k, line := range batch {
+				req.Lines[k] = metricsLine{Tag: line.Tag, Line: line.OriginalLine}
+				if ts := line.GetTimestamp(); ts != nil {
+					req.Lines[k].Time = ts.Time.UTC().Format(time.RFC3339Nano)
+				}
+			}
+			resp, err := call(req)
+			if err != nil {
+				return err
+			}
+			if len(resp.Points) != len(batch) {
+				return fmt.Errorf("plugin '%s' returned points for %d of %d lines", p.Name, len(resp.Points), len(batch))
+			}
+			for k, linePoints := range resp.Points {
+				var converted []pattern.MetricPoint
+				for _, pt := range linePoints {
+					mp := pattern.MetricPoint{Value: pt.Value, State: pt.State, SeriesName: pt.Series}
+					if ts := batch[k].GetTimestamp(); ts != nil {
+						mp.Time = ts.Time
+					}
+					converted = append(converted, mp)
+				}
+				points = append(points, converted)
+			}
+		}
+		return nil
+	})
+	return points, err
+}
+
+// run starts the plugin, passes it a function sending a request and reading its response, and
+// waits for the plugin to exit after closing its input
+func (p *Plugin) run(ctx context.Context, fn func(call func(request any) (*response, error)) error) error {
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start plugin '%s': %w", p.Name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start plugin '%s': %w", p.Name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin '%s': %w", p.Name, err)
+	}
+
+	encoder := json.NewEncoder(stdin)
+	reader := bufio.NewReaderSize(stdout, 1<<20)
+	call := func(request any) (*response, error) {
+		// The request is written completely before the response is read, so plugins that
+		// read a whole line before answering cannot deadlock on full pipes
+		if err := encoder.Encode(request); err != nil {
+			return nil, fmt.Errorf("failed to send request to plugin '%s': %w", p.Name, err)
+		}
+		data, err := reader.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(data) == 0) {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("plugin '%s' exited without responding", p.Name)
+			}
+			return nil, fmt.Errorf("failed to read response of plugin '%s': %w", p.Name, err)
+		}
+		var resp response
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response of plugin '%s': %w", p.Name, err)
+		}
+		if resp.Error != "" {
+			return nil, fmt.Errorf("plugin '%s' failed: %s", p.Name, resp.Error)
+		}
+		return &resp, nil
+	}
+
+	err = fn(call)
+	stdin.Close()
+	io.Copy(io.Discard, stdout)
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	if waitErr != nil {
+		return fmt.Errorf("plugin '%s' failed: %w", p.Name, waitErr)
+	}
+	return nil
+}
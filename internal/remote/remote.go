@@ -0,0 +1,150 @@
+// Package remote fetches log files from other hosts over SSH, using the ssh command so that
+// ~/.ssh/config (keys, jump hosts, ports) and the SSH agent apply as for interactive logins
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Source is a file on a remote host
+type Source struct {
+	Spec string // The specification it was parsed from
+	User string
+	Host string
+	Port int
+	Path string
+}
+
+// IsRemote reports whether a file specification names a remote file
+func IsRemote(spec string) bool {
+	return strings.HasPrefix(spec, "ssh://")
+}
+
+// Parse parses ssh://[user@]host:/path (scp style) or ssh://[user@]host[:port]/path
+func Parse(spec string) (Source, error) {
+	rest, ok := strings.CutPrefix(spec, "ssh://")
+	if !ok {
+		return Source{}, fmt.Errorf("invalid remote file '%s', expected ssh://[user@]host:/path", spec)
+	}
+	s := Source{Spec: spec}
+	if user, host, ok := strings.Cut(rest, "@"); ok {
+		s.User, rest = user, host
+	}
+	slash := strings.Index(rest, "/")
+	if slash <= 0 {
+		return Source{}, fmt.Errorf("invalid remote file '%s', expected ssh://[user@]host:/path", spec)
+	}
+	host, path := strings.TrimSuffix(rest[:slash], ":"), rest[slash:]
+	if name, port, ok := strings.Cut(host, ":"); ok {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return Source{}, fmt.Errorf("invalid port '%s' in remote file '%s'", port, spec)
+		}
+		host, s.Port = name, p
+	}
+	if host == "" || path == "/" {
+		return Source{}, fmt.Errorf("invalid remote file '%s', expected ssh://[user@]host:/path", spec)
+	}
+	// ssh would take a host starting with - as an option
+	if strings.HasPrefix(host, "-") {
+		return Source{}, fmt.Errorf("invalid host '%s' in remote file '%s'", host, spec)
+	}
+	s.Host, s.Path = host, path
+	return s, nil
+}
+
+// ShortHost returns the host name without its domain (IP addresses are kept whole)
+func (s Source) ShortHost() string {
+	if strings.Trim(s.Host, "0123456789.") == "" || strings.Contains(s.Host, ":") {
+		return s.Host
+	}
+	short, _, _ := strings.Cut(s.Host, ".")
+	return short
+}
+
+// Tags returns the tags of the sources: the short host name, followed by the file name without
+// extension for hosts with several files (e.g., node1-ptp4l and node1-phc2sys)
+func Tags(sources []Source) []string {
+	files := make(map[string]int)
+	for _, s := range sources {
+		files[s.ShortHost()]++
+	}
+	tags := make([]string, len(sources))
+	for k, s := range sources {
+		tags[k] = s.ShortHost()
+		if files[tags[k]] > 1 {
+			base := path.Base(s.Path)
+			tags[k] += "-" + strings.TrimSuffix(base, path.Ext(base))
+		}
+	}
+	return tags
+}
+
+// Fetch reads the file, or its last lines if tail is positive. The ssh command runs in batch
+// mode, so hosts that would prompt for a password fail instead of waiting for input.
+func (s Source) Fetch(ctx context.Context, tail int) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "ssh", s.sshArgs(tail)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if message := bytes.TrimSpace(stderr.Bytes()); len(message) > 0 {
+			return nil, fmt.Errorf("failed to fetch '%s': %w: %s", s.Spec, err, message)
+		}
+		return nil, fmt.Errorf("failed to fetch '%s': %w", s.Spec, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// sshArgs returns the arguments of the ssh command fetching the file. The -- ends the options,
+// so the target is never taken for one.
+func (s Source) sshArgs(tail int) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if s.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(s.Port))
+	}
+	target := s.Host
+	if s.User != "" {
+		target = s.User + "@" + s.Host
+	}
+	command := "cat -- " + shellQuote(s.Path)
+	if tail > 0 {
+		command = fmt.Sprintf("tail -n %d -- %s", tail, shellQuote(s.Path))
+	}
+	return append(args, "--", target, command)
+}
+
+// FetchAll fetches the sources in parallel, returning their contents in order
+func FetchAll(ctx context.Context, sources []Source, tail int) ([][]byte, error) {
+	data := make([][]byte, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for k, s := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data[k], errs[k] = s.Fetch(ctx, tail)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// shellQuote quotes a path for the remote shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
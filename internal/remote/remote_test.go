@@ -0,0 +1,75 @@
+package remote
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Source
+	}{
+		{"ssh://core@node1.lab:/var/log/ptp4l.log", Source{User: "core", Host: "node1.lab", Path: "/var/log/ptp4l.log"}},
+		{"ssh://node1/var/log/messages", Source{Host: "node1", Path: "/var/log/messages"}},
+		{"ssh://root@10.0.0.7:2222/tmp/phc2sys.log", Source{User: "root", Host: "10.0.0.7", Port: 2222, Path: "/tmp/phc2sys.log"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		want := tt.want
+		want.Spec = tt.spec
+		if err != nil || got != want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v", tt.spec, got, err, want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"node1:/var/log/messages",
+		"ssh://node1",
+		"ssh://node1:/",
+		"ssh:///var/log/messages",
+		"ssh://node1:ssh/var/log/messages",
+		"ssh://node1:70000/var/log/messages",
+		"ssh://-oProxyCommand=touch%20x:/var/log/messages",
+		"ssh://core@-p2222:/var/log/messages",
+	} {
+		if got, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", spec, got)
+		}
+	}
+}
+
+func TestSSHArgs(t *testing.T) {
+	tests := []struct {
+		spec string
+		tail int
+		want []string
+	}{
+		{"ssh://node1:/var/log/messages", 0, []string{"-o", "BatchMode=yes", "--", "node1", "cat -- '/var/log/messages'"}},
+		{"ssh://core@node1:2222/var/log/it's.log", 100, []string{"-o", "BatchMode=yes", "-p", "2222", "--", "core@node1", `tail -n 100 -- '/var/log/it'\''s.log'`}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got := s.sshArgs(tt.tail); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ssh args %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	sources := []Source{
+		{Host: "node1.lab", Path: "/var/log/ptp4l.log"},
+		{Host: "node1.lab", Path: "/var/log/phc2sys.log"},
+		{Host: "node2.lab", Path: "/var/log/ptp4l.log"},
+		{Host: "10.0.0.7", Path: "/var/log/messages"},
+	}
+	want := []string{"node1-ptp4l", "node1-phc2sys", "node2", "10.0.0.7"}
+	if got := Tags(sources); !slices.Equal(got, want) {
+		t.Errorf("Tags = %q, want %q", got, want)
+	}
+}
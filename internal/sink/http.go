@@ -0,0 +1,86 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// httpSink buffers written data and uploads it with an HTTP PUT request on Close
+type httpSink struct {
+	bytes.Buffer
+	url    *url.URL
+	client *http.Client
+	closed bool
+}
+
+func newHTTPSink(u *url.URL) *httpSink {
+	return &httpSink{
+		url:    u,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Close uploads the buffered data
+func (h *httpSink) Close() error {
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+
+	req, err := http.NewRequest(http.MethodPut, h.url.String(), bytes.NewReader(h.Bytes()))
+	if err != nil {
+		return fmt.Errorf("failed to create PUT request: %w", err)
+	}
+	req.ContentLength = int64(h.Len())
+	req.Header.Set("Content-Type", contentType(h.url.Path))
+
+	return doUpload(h.client, req)
+}
+
+// Location returns the target URL without credentials
+func (h *httpSink) Location() string {
+	return h.url.Redacted()
+}
+
+// doUpload sends the request and converts non-2xx responses to errors
+func doUpload(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload failed: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// contentType guesses the content type from the target path extension
+func contentType(path string) string {
+	switch Ext(path) {
+	case ".png":
+		return "image/png"
+	case ".svg":
+		return "image/svg+xml"
+	case ".pdf":
+		return "application/pdf"
+	case ".html", ".htm":
+		return "text/html; charset=utf-8"
+	case ".json":
+		return "application/json"
+	case ".csv":
+		return "text/csv"
+	case ".parquet":
+		return "application/vnd.apache.parquet"
+	case ".log", ".txt":
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
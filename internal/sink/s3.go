@@ -0,0 +1,234 @@
+package sink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Sink buffers written data and uploads it as a single S3 PutObject request on Close.
+// Credentials and region are taken from the standard AWS environment variables
+// (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION).
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL selects an S3-compatible endpoint (e.g., MinIO),
+// which is addressed path-style.
+type s3Sink struct {
+	bytes.Buffer
+	location  string
+	bucket    string
+	key       string
+	region    string
+	endpoint  *url.URL
+	accessKey string
+	secretKey string
+	token     string
+	client    *http.Client
+	closed    bool
+}
+
+func newS3Sink(location string) (*s3Sink, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 URL '%s': %w", location, err)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 URL '%s', expected s3://bucket/key", location)
+	}
+
+	s := &s3Sink{
+		location:  location,
+		bucket:    u.Host,
+		key:       key,
+		region:    firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("S3 output requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	// Use a custom endpoint (path-style) if configured, otherwise virtual-hosted AWS style
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		ep, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 endpoint '%s': %w", endpoint, err)
+		}
+		ep.Path = "/" + s.bucket + "/" + s.key
+		ep.RawQuery = u.RawQuery
+		s.endpoint = ep
+	} else {
+		s.endpoint = &url.URL{
+			Scheme:   "https",
+			Host:     fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, s.region),
+			Path:     "/" + s.key,
+			RawQuery: u.RawQuery,
+		}
+	}
+
+	return s, nil
+}
+
+// Close uploads the buffered data
+func (s *s3Sink) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	body := s.Bytes()
+	req, err := http.NewRequest(http.MethodPut, s.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", contentType(s.key))
+	s.sign(req, body, time.Now().UTC())
+
+	return doUpload(s.client, req)
+}
+
+// Location returns the s3:// URL
+func (s *s3Sink) Location() string {
+	return s.location
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (s *s3Sink) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+	}
+
+	// Canonical headers must be lowercase and sorted
+	signedHeaders := []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	if s.token != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+	}
+	key := sigV4Key{accessKey: s.accessKey, secretKey: s.secretKey, region: s.region, service: "s3"}
+	key.sign(req, signedHeaders, payloadHash, now)
+}
+
+// sigV4Key is what AWS Signature Version 4 signs the requests of a service with
+type sigV4Key struct {
+	accessKey string
+	secretKey string
+	region    string
+	service   string
+}
+
+// sign adds the X-Amz-Date and Authorization headers of AWS Signature Version 4 to the request,
+// signing the headers named by signedHeaders (lowercase and sorted) and the payload hash
+func (k sigV4Key) sign(req *http.Request, signedHeaders []string, payloadHash string, now time.Time) {
+	const algorithm = "AWS4-HMAC-SHA256"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, k.region, k.service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+k.secretKey), date)
+	signingKey = hmacSHA256(signingKey, k.region)
+	signingKey = hmacSHA256(signingKey, k.service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, k.accessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+// canonicalPath returns the SigV4 canonical URI of an S3 request: the path with each segment
+// URI-encoded (EscapedPath leaves characters like '$' as is)
+func canonicalPath(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the SigV4 canonical query string: the parameters sorted by key (and
+// value), with keys and values URI-encoded leaving only the unreserved characters as is
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// uriEncode percent-encodes all but the unreserved characters A-Z, a-z, 0-9, '-', '_', '.', and
+// '~', as SigV4 requires (spaces are %20, not +)
+func uriEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
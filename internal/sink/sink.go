@@ -0,0 +1,151 @@
+package sink
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Sink is a destination that output artifacts (interleaved logs, plots, exports) are written to.
+// Data written to a sink is only guaranteed to be persisted once Close returns without error.
+type Sink interface {
+	io.WriteCloser
+	// Location returns the location the sink writes to, for user-facing messages
+	Location() string
+}
+
+// Open opens a sink for the given location. The backend is selected by URL scheme:
+//   - "-" or "stdout://": standard output
+//   - "file://path" or a plain path: local file
+//   - "s3://bucket/key": S3 (or S3-compatible) object storage
+//   - "http://..." or "https://...": HTTP PUT to the given URL
+func Open(location string) (Sink, error) {
+	if location == "" {
+		return nil, fmt.Errorf("empty output location")
+	}
+	if location == "-" {
+		return newStdoutSink(), nil
+	}
+
+	scheme, rest, hasScheme := strings.Cut(location, "://")
+	if !hasScheme {
+		return newFileSink(location)
+	}
+
+	switch strings.ToLower(scheme) {
+	case "stdout":
+		return newStdoutSink(), nil
+	case "file":
+		return newFileSink(rest)
+	case "s3":
+		return newS3Sink(location)
+	case "http", "https":
+		u, err := url.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL '%s': %w", location, err)
+		}
+		return newHTTPSink(u), nil
+	default:
+		return nil, fmt.Errorf("unsupported output scheme '%s'", scheme)
+	}
+}
+
+// OpenAppend opens a sink that appends to the given location, for outputs that grow across
+// incremental runs. Only local files and standard output can be appended to.
+func OpenAppend(location string) (Sink, error) {
+	if IsStdout(location) {
+		return newStdoutSink(), nil
+	}
+	path := location
+	if scheme, rest, hasScheme := strings.Cut(location, "://"); hasScheme {
+		if strings.ToLower(scheme) != "file" {
+			return nil, fmt.Errorf("cannot append to '%s', expected a file or stdout", location)
+		}
+		path = rest
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return &fileSink{File: file, path: path}, nil
+}
+
+// WithSuffix returns the location with a suffix inserted before the file extension of its
+// path component (e.g., out.log with suffix -2026-01-11T14 is out-2026-01-11T14.log)
+func WithSuffix(location, suffix string) string {
+	if u, err := url.Parse(location); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		ext := path.Ext(u.Path)
+		u.Path = strings.TrimSuffix(u.Path, ext) + suffix + ext
+		return u.String()
+	}
+	ext := filepath.Ext(location)
+	return strings.TrimSuffix(location, ext) + suffix + ext
+}
+
+// Ext returns the file extension (including the dot) of the location's path component,
+// which is used to pick the encoding for formats such as plot images
+func Ext(location string) string {
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" && u.Path != "" {
+		return filepath.Ext(u.Path)
+	}
+	return filepath.Ext(location)
+}
+
+// IsStdout reports whether the location refers to standard output
+func IsStdout(location string) bool {
+	return location == "-" || strings.HasPrefix(strings.ToLower(location), "stdout://")
+}
+
+// IsTerminal reports whether the location refers to standard output connected to a terminal
+func IsTerminal(location string) bool {
+	if !IsStdout(location) {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// fileSink writes to a local file
+type fileSink struct {
+	*os.File
+	path string
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return &fileSink{File: file, path: path}, nil
+}
+
+// Location returns the file path
+func (f *fileSink) Location() string {
+	return f.path
+}
+
+// stdoutSink writes to standard output; closing it leaves stdout open
+type stdoutSink struct {
+	io.Writer
+}
+
+func newStdoutSink() *stdoutSink {
+	return &stdoutSink{Writer: os.Stdout}
+}
+
+// Close is a no-op for stdout
+func (s *stdoutSink) Close() error {
+	return nil
+}
+
+// Location returns "stdout"
+func (s *stdoutSink) Location() string {
+	return "stdout"
+}
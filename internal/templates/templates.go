@@ -0,0 +1,219 @@
+// Package templates mines recurring line templates from logs: lines of the same shape are
+// clustered by their tokens, in the manner of the Drain algorithm, and the variable tokens of
+// each template are summarized to find numeric fields and states worth plotting
+package templates
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Wildcard is the template token of positions with differing tokens
+const Wildcard = "<*>"
+
+// maxDistinct is the number of distinct values kept per template position
+const maxDistinct = 16
+
+// numberToken matches a numeric token, optionally preceded by a key (e.g., offset=-12) and
+// followed by punctuation
+var numberToken = regexp.MustCompile(`^([^\d=:]*[=:])?([-+]?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?)([,;]?)$`)
+
+// Token is a position of a template
+type Token struct {
+	Text   string // Literal text, Wildcard, or the key and suffix around a number
+	Number bool   // Numeric position, whose value is between Prefix and Suffix
+	Prefix string // Key of key=value numbers (e.g., "offset=")
+	Suffix string // Punctuation after the number
+
+	Values   map[string]int // Distinct values with their counts, up to maxDistinct
+	Overflow bool           // More distinct values than kept
+	Min, Max float64        // Range of numeric values
+}
+
+// Distinct returns the number of distinct values seen, which is a lower bound with Overflow
+func (t *Token) Distinct() int {
+	return len(t.Values)
+}
+
+// Template is a cluster of lines with the same shape
+type Template struct {
+	Tag     string
+	Tokens  []*Token
+	Count   int
+	Example string // First line of the cluster
+}
+
+// Miner clusters lines into templates
+type Miner struct {
+	similarity float64
+	groups     map[string][]*Template // By tag, token count, and first token
+	templates  []*Template
+}
+
+// NewMiner returns a miner that adds a line to a template when at least the similarity fraction
+// of its tokens equals the template's (Drain uses 0.4-0.5)
+func NewMiner(similarity float64) *Miner {
+	return &Miner{similarity: similarity, groups: make(map[string][]*Template)}
+}
+
+// Add adds the message of a line of a tag, the part of the line after its timestamp
+func (m *Miner) Add(tag, message, line string) {
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		return
+	}
+	masked := make([]string, len(fields))
+	for i, field := range fields {
+		masked[i] = mask(field)
+	}
+
+	key := tag + "\x00" + strconv.Itoa(len(fields)) + "\x00" + masked[0]
+	var best *Template
+	bestScore := -1.0
+	for _, t := range m.groups[key] {
+		if score := t.similarity(masked); score >= m.similarity && score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+	if best == nil {
+		best = &Template{Tag: tag, Example: line}
+		for _, text := range masked {
+			best.Tokens = append(best.Tokens, newToken(text))
+		}
+		m.groups[key] = append(m.groups[key], best)
+		m.templates = append(m.templates, best)
+	}
+	best.add(fields, masked)
+}
+
+// Templates returns the templates with at least minCount lines, the most frequent first
+func (m *Miner) Templates(minCount int) []*Template {
+	var templates []*Template
+	for _, t := range m.templates {
+		if t.Count >= minCount {
+			templates = append(templates, t)
+		}
+	}
+	slices.SortStableFunc(templates, func(a, b *Template) int { return b.Count - a.Count })
+	return templates
+}
+
+// programToken matches the program name and process ID of syslog style lines (e.g., ptp4l[123.4]:)
+var programToken = regexp.MustCompile(`^([A-Za-z][\w.-]*)\[[\d.]+\](:?)$`)
+
+// pid replaces the process ID of program tokens in templates
+const pid = "<PID>"
+
+// prefixWords are the words of timestamps
+var prefixWords = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// Message returns the message of a line: the line after the leading tokens with digits or month
+// and weekday names, which are the timestamp, process IDs, and sources. A program token like
+// ptp4l[123.4]: among them starts the message, as it tells the messages of programs apart.
+func Message(line string) string {
+	rest, program := line, ""
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		field, after, _ := strings.Cut(rest, " ")
+		if field == "" || (!strings.ContainsAny(field, "0123456789") && !slices.Contains(prefixWords, field)) {
+			if program != "" {
+				return program
+			}
+			return rest
+		}
+		if programToken.MatchString(field) {
+			program = rest
+		}
+		rest = after
+	}
+}
+
+// mask replaces the variable part of a token: numbers become <NUM> (keeping a key and
+// punctuation around them), process IDs of program tokens <PID>, and other tokens with digits
+// the wildcard
+func mask(field string) string {
+	if m := programToken.FindStringSubmatch(field); m != nil {
+		return m[1] + "[" + pid + "]" + m[2]
+	}
+	if m := numberToken.FindStringSubmatch(field); m != nil {
+		return m[1] + "<NUM>" + m[3]
+	}
+	if strings.ContainsAny(field, "0123456789") {
+		return Wildcard
+	}
+	return field
+}
+
+func newToken(text string) *Token {
+	t := &Token{Text: text, Values: make(map[string]int)}
+	if prefix, suffix, ok := strings.Cut(text, "<NUM>"); ok {
+		t.Number, t.Prefix, t.Suffix = true, prefix, suffix
+	}
+	return t
+}
+
+// similarity returns the fraction of the masked tokens equal to the template's
+func (t *Template) similarity(masked []string) float64 {
+	same := 0
+	for i, text := range masked {
+		if t.Tokens[i].Text == text || t.Tokens[i].Text == Wildcard {
+			same++
+		}
+	}
+	return float64(same) / float64(len(masked))
+}
+
+// add merges a line into the template: differing positions become wildcards
+func (t *Template) add(fields, masked []string) {
+	t.Count++
+	for i, tok := range t.Tokens {
+		if tok.Text != masked[i] && tok.Text != Wildcard {
+			// The position varies between lines; its values so far are kept as they were
+			tok.Text, tok.Number, tok.Prefix, tok.Suffix = Wildcard, false, "", ""
+		}
+		value := fields[i]
+		if tok.Number {
+			value = strings.TrimSuffix(strings.TrimPrefix(value, tok.Prefix), tok.Suffix)
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				if len(tok.Values) == 0 && !tok.Overflow {
+					tok.Min, tok.Max = v, v
+				}
+				tok.Min, tok.Max = min(tok.Min, v), max(tok.Max, v)
+			}
+		}
+		if _, ok := tok.Values[value]; ok || len(tok.Values) < maxDistinct {
+			tok.Values[value]++
+		} else {
+			tok.Overflow = true
+		}
+	}
+}
+
+// Regex returns a regex matching the lines of the template, with capture groups for the
+// positions in groups, in order
+func (t *Template) Regex(groups []int) string {
+	parts := make([]string, len(t.Tokens))
+	for i, tok := range t.Tokens {
+		parts[i] = tok.regex(slices.Contains(groups, i))
+	}
+	return strings.Join(parts, `\s+`)
+}
+
+// regex returns the regex of a template position
+func (t *Token) regex(capture bool) string {
+	var expr string
+	switch {
+	case t.Number:
+		expr = `[-+]?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?`
+	case t.Text == Wildcard:
+		expr = `\S+`
+	default:
+		return strings.ReplaceAll(regexp.QuoteMeta(t.Text), pid, `[\d.]+`)
+	}
+	if capture {
+		expr = "(" + expr + ")"
+	}
+	return regexp.QuoteMeta(t.Prefix) + expr + regexp.QuoteMeta(t.Suffix)
+}
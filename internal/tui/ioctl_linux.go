@@ -0,0 +1,9 @@
+package tui
+
+import "syscall"
+
+// Termios requests of Linux
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// keyCode is a key of the terminal
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyBackspace
+	keyEscape
+	keyCtrlC
+	keyUnknown
+)
+
+// key is a key press, with its character for keyRune
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// is reports whether the key is a character
+func (k key) is(r rune) bool {
+	return k.code == keyRune && k.r == r
+}
+
+// escapeKeys maps the CSI and SS3 sequences of terminals (after the escape) to keys
+var escapeKeys = map[string]keyCode{
+	"[A": keyUp, "[B": keyDown, "[C": keyRight, "[D": keyLeft,
+	"OA": keyUp, "OB": keyDown, "OC": keyRight, "OD": keyLeft,
+	"[5~": keyPageUp, "[6~": keyPageDown,
+	"[H": keyHome, "[1~": keyHome, "[7~": keyHome, "OH": keyHome,
+	"[F": keyEnd, "[4~": keyEnd, "[8~": keyEnd, "OF": keyEnd,
+}
+
+// readKeys sends the keys read from the terminal until it fails
+func readKeys(r io.Reader, keys chan<- key) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseKeys parses the bytes of a terminal read into keys. Escape sequences arrive in one read,
+// so an escape at the end of the data is the escape key.
+func parseKeys(data []byte) []key {
+	var keys []key
+	for len(data) > 0 {
+		if data[0] == 0x1b {
+			if len(data) > 2 && (data[1] == '[' || data[1] == 'O') {
+				// Parameters, then a final byte in @..~
+				end := 2
+				for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+					end++
+				}
+				if end < len(data) {
+					code, ok := escapeKeys[string(data[1:end+1])]
+					if !ok {
+						code = keyUnknown
+					}
+					keys = append(keys, key{code: code})
+					data = data[end+1:]
+					continue
+				}
+			}
+			keys = append(keys, key{code: keyEscape})
+			data = data[1:]
+			continue
+		}
+
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		switch {
+		case r == '\r' || r == '\n':
+			keys = append(keys, key{code: keyEnter})
+		case r == 0x7f || r == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+		case r == 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+		case r == 0x02: // Ctrl-B
+			keys = append(keys, key{code: keyPageUp})
+		case r == 0x06: // Ctrl-F
+			keys = append(keys, key{code: keyPageDown})
+		case r >= 0x20 && r != utf8.RuneError:
+			keys = append(keys, key{code: keyRune, r: r})
+		default:
+			keys = append(keys, key{code: keyUnknown})
+		}
+	}
+	return keys
+}
@@ -0,0 +1,27 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+// termState is the saved state of a terminal
+type termState struct{}
+
+var resizeSignals []os.Signal
+
+func makeRaw(fd uintptr) (*termState, error) {
+	return nil, errors.New("the terminal UI is not supported on this platform")
+}
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func restore(fd uintptr, state *termState) {}
+
+func terminalSize(fd uintptr) (int, int) {
+	return 80, 24
+}
@@ -0,0 +1,60 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package tui
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// resizeSignals are the signals of terminal size changes
+var resizeSignals = []os.Signal{syscall.SIGWINCH}
+
+// makeRaw puts the terminal into raw mode (no echo, no line editing, no signals from keys),
+// returning the previous state
+func makeRaw(fd uintptr) (*syscall.Termios, error) {
+	var state syscall.Termios
+	if err := ioctl(fd, ioctlGetTermios, unsafe.Pointer(&state)); err != nil {
+		return nil, err
+	}
+	raw := state
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(fd, ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// isTerminal reports whether the file descriptor is a terminal
+func isTerminal(fd uintptr) bool {
+	var state syscall.Termios
+	return ioctl(fd, ioctlGetTermios, unsafe.Pointer(&state)) == nil
+}
+
+// restore restores the state of the terminal saved by makeRaw
+func restore(fd uintptr, state *syscall.Termios) {
+	ioctl(fd, ioctlSetTermios, unsafe.Pointer(state))
+}
+
+// terminalSize returns the columns and rows of the terminal, or 80x24 when unknown
+func terminalSize(fd uintptr) (int, int) {
+	var size struct{ rows, cols, x, y uint16 }
+	if err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil || size.cols == 0 || size.rows == 0 {
+		return 80, 24
+	}
+	return int(size.cols), int(size.rows)
+}
+
+func ioctl(fd, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
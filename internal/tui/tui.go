@@ -0,0 +1,549 @@
+// Package tui is a terminal browser of the interleaved log: the lines colored by tag, tag
+// filters, incremental search, and a sparkline of a metric. It draws with ANSI escape sequences
+// on the alternate screen, so it works over SSH on any terminal emulator.
+package tui
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/timestamp"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Metric is a series shown as a sparkline below the log
+type Metric struct {
+	Name   string
+	Unit   string
+	Points []pattern.MetricPoint // In time order
+}
+
+// Options configure a browser
+type Options struct {
+	Title      string
+	TimeFormat string                 // Go time layout of the line timestamps (default: timestamp.DefaultOutputFormat)
+	Colorizer  *interleaver.Colorizer // Colors of the tags, or nil for plain text
+	Metrics    []Metric
+	Metric     int // Index of the metric shown first
+}
+
+// row is a line of the log view: an interleaved line or one of its continuation lines
+type row struct {
+	text string    // Formatted text, with tabs expanded and control characters replaced
+	tag  int       // Index of the tag
+	time time.Time // Time of the line, or of the preceding line for lines without a timestamp
+}
+
+// Browser is the state of the terminal browser
+type Browser struct {
+	opts    Options
+	rows    []row
+	tags    []string
+	hidden  []bool // Tags whose lines are hidden
+	visible []int  // Indexes of the rows shown
+	start   time.Time
+	end     time.Time
+
+	width, height int
+	top           int // Position in visible of the first row on screen
+	left          int // Columns scrolled horizontally
+
+	searching bool // Whether the search query is being typed
+	origin    int  // Top row when the search started
+	query     string
+	re        *regexp.Regexp
+	searchErr string
+	match     int // Position in visible of the current match, or -1
+
+	metric int
+	spark  sparkline
+}
+
+// sparkline caches the sparkline of a metric for a width
+type sparkline struct {
+	metric, width int
+	levels        []rune
+	min, max      float64
+}
+
+// sparkLevels are the bars of a sparkline, lowest first
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// NewBrowser creates a browser of the lines
+func NewBrowser(lines []*parser.LogLine, opts Options) *Browser {
+	b := &Browser{opts: opts, match: -1, metric: opts.Metric, spark: sparkline{metric: -1}}
+	tagIndex := make(map[string]int)
+	for _, line := range lines {
+		if _, ok := tagIndex[line.Tag]; !ok {
+			tagIndex[line.Tag] = len(b.tags)
+			b.tags = append(b.tags, line.Tag)
+		}
+	}
+	sort.Strings(b.tags)
+	for i, tag := range b.tags {
+		tagIndex[tag] = i
+	}
+	b.hidden = make([]bool, len(b.tags))
+
+	var last time.Time
+	for _, line := range lines {
+		if ts := line.GetTimestamp(); ts != nil {
+			last = ts.Time
+			if b.start.IsZero() || last.Before(b.start) {
+				b.start = last
+			}
+			if last.After(b.end) {
+				b.end = last
+			}
+		}
+		formatted := interleaver.FormatLine(line, opts.TimeFormat)
+		for _, text := range strings.Split(formatted, "\n") {
+			b.rows = append(b.rows, row{text: printable(text), tag: tagIndex[line.Tag], time: last})
+		}
+	}
+	b.filter()
+	return b
+}
+
+// printable expands tabs and replaces control characters, which would move the cursor
+func printable(text string) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '?'
+		}
+		return r
+	}, text)
+}
+
+// OpenInput returns the terminal to read the keys from: stdin, or the controlling terminal when
+// stdin is not one (e.g., with the logs piped to stdin). Close it unless it is stdin.
+func OpenInput() (*os.File, error) {
+	if isTerminal(os.Stdin.Fd()) {
+		return os.Stdin, nil
+	}
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the terminal: %w", err)
+	}
+	return tty, nil
+}
+
+// Run shows the browser on a terminal until the user quits or ctx is done
+func (b *Browser) Run(ctx context.Context, in, out *os.File) error {
+	state, err := makeRaw(in.Fd())
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer restore(in.Fd(), state)
+
+	// Alternate screen without the cursor, restored on exit
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan key, 16)
+	go readKeys(in, keys)
+	resized := make(chan os.Signal, 1)
+	if len(resizeSignals) > 0 {
+		signal.Notify(resized, resizeSignals...)
+		defer signal.Stop(resized)
+	}
+
+	w := bufio.NewWriter(out)
+	for {
+		b.width, b.height = terminalSize(out.Fd())
+		b.draw(w)
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write to the terminal: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-resized:
+		case k, ok := <-keys:
+			if !ok || b.handle(k) {
+				return nil
+			}
+		}
+	}
+}
+
+// logHeight returns the number of log rows on screen
+func (b *Browser) logHeight() int {
+	height := b.height - 2 // Header and status bar
+	if len(b.opts.Metrics) > 0 {
+		height -= 2 // Sparkline and its cursor
+	}
+	return max(height, 1)
+}
+
+// handle updates the browser for a key, returning true to quit
+func (b *Browser) handle(k key) bool {
+	if b.searching {
+		b.handleSearch(k)
+		return false
+	}
+	page := b.logHeight()
+	switch {
+	case k.code == keyCtrlC || k.is('q'):
+		return true
+	case k.code == keyDown || k.code == keyEnter || k.is('j'):
+		b.scroll(1)
+	case k.code == keyUp || k.is('k'):
+		b.scroll(-1)
+	case k.code == keyPageDown || k.is(' ') || k.is('f'):
+		b.scroll(page)
+	case k.code == keyPageUp || k.is('b'):
+		b.scroll(-page)
+	case k.code == keyHome || k.is('g'):
+		b.top = 0
+	case k.code == keyEnd || k.is('G'):
+		b.scroll(len(b.visible))
+	case k.code == keyRight || k.is('l'):
+		b.left += 8
+	case k.code == keyLeft || k.is('h'):
+		b.left = max(b.left-8, 0)
+	case k.is('/'):
+		b.searching, b.origin, b.query, b.re, b.searchErr = true, b.top, "", nil, ""
+	case k.is('n'):
+		b.findMatch(b.matchStart(1), 1)
+	case k.is('N'):
+		b.findMatch(b.matchStart(-1), -1)
+	case k.is('m') && len(b.opts.Metrics) > 0:
+		b.metric = (b.metric + 1) % len(b.opts.Metrics)
+	case k.is('M') && len(b.opts.Metrics) > 0:
+		b.metric = (b.metric + len(b.opts.Metrics) - 1) % len(b.opts.Metrics)
+	case k.is('0'):
+		clear(b.hidden)
+		b.refilter()
+	case k.code == keyRune && k.r >= '1' && k.r <= '9':
+		if i := int(k.r - '1'); i < len(b.tags) {
+			b.hidden[i] = !b.hidden[i]
+			b.refilter()
+		}
+	}
+	return false
+}
+
+// handleSearch edits the search query, moving to the first match from where the search
+// started as it is typed
+func (b *Browser) handleSearch(k key) {
+	switch k.code {
+	case keyEnter:
+		b.searching = false
+		return
+	case keyEscape, keyCtrlC:
+		b.searching, b.query, b.re, b.searchErr, b.match = false, "", nil, "", -1
+		b.top = b.origin
+		return
+	case keyBackspace:
+		if b.query == "" {
+			return
+		}
+		_, size := utf8.DecodeLastRuneInString(b.query)
+		b.query = b.query[:len(b.query)-size]
+	case keyRune:
+		b.query += string(k.r)
+	default:
+		return
+	}
+
+	b.re, b.searchErr, b.match = nil, "", -1
+	if b.query == "" {
+		b.top = b.origin
+		return
+	}
+	re, err := regexp.Compile("(?i)" + b.query)
+	if err != nil {
+		b.searchErr = "invalid regex"
+		return
+	}
+	b.re = re
+	b.findMatch(b.origin, 1)
+}
+
+// matchStart returns the position the next match in a direction is searched from
+func (b *Browser) matchStart(step int) int {
+	if b.match >= 0 {
+		return b.match + step
+	}
+	return b.top
+}
+
+// findMatch moves to the first row matching the search from a position in a direction,
+// wrapping around the log
+func (b *Browser) findMatch(from, step int) {
+	n := len(b.visible)
+	if b.re == nil || n == 0 {
+		return
+	}
+	for k := range n {
+		pos := ((from+k*step)%n + n) % n
+		if b.re.MatchString(b.rows[b.visible[pos]].text) {
+			b.match = pos
+			// Show the match a few rows down, with the preceding lines as context
+			b.top = max(pos-b.logHeight()/4, 0)
+			return
+		}
+	}
+	b.searchErr = "no match"
+}
+
+// scroll moves the view by rows, keeping it within the log
+func (b *Browser) scroll(rows int) {
+	b.top = min(max(b.top+rows, 0), max(len(b.visible)-b.logHeight(), 0))
+}
+
+// filter computes the rows of the tags shown
+func (b *Browser) filter() {
+	b.visible = b.visible[:0]
+	for i, r := range b.rows {
+		if !b.hidden[r.tag] {
+			b.visible = append(b.visible, i)
+		}
+	}
+}
+
+// refilter applies a change of the tags shown, keeping the view at the same place in the log
+func (b *Browser) refilter() {
+	first := len(b.rows)
+	if b.top < len(b.visible) {
+		first = b.visible[b.top]
+	}
+	b.filter()
+	b.top = sort.SearchInts(b.visible, first)
+	b.match = -1
+	b.scroll(0)
+}
+
+// draw draws the whole screen
+func (b *Browser) draw(w io.Writer) {
+	line := 1
+	put := func(text string) {
+		fmt.Fprintf(w, "\x1b[%d;1H%s\x1b[0m\x1b[K", line, text)
+		line++
+	}
+
+	put(b.header())
+	for k := range b.logHeight() {
+		pos := b.top + k
+		if pos >= len(b.visible) {
+			put("\x1b[2m~")
+			continue
+		}
+		put(b.logRow(pos))
+	}
+	if len(b.opts.Metrics) > 0 {
+		spark, cursor := b.sparkRows()
+		put(spark)
+		put(cursor)
+	}
+	put(b.status())
+}
+
+// header returns the title and the tags with their toggle keys; hidden tags are dimmed
+func (b *Browser) header() string {
+	var sb strings.Builder
+	width := 0
+	add := func(text, styled string) bool {
+		n := utf8.RuneCountInString(text)
+		if width+n > b.width {
+			return false
+		}
+		sb.WriteString(styled)
+		width += n
+		return true
+	}
+	if b.opts.Title != "" {
+		add(b.opts.Title+"  ", "\x1b[1m"+b.opts.Title+"\x1b[22m  ")
+	}
+	for i, tag := range b.tags {
+		label := tag
+		if i < 9 {
+			label = strconv.Itoa(i+1) + ":" + tag
+		}
+		styled := "\x1b[2m" + label + "\x1b[22m"
+		if !b.hidden[i] {
+			styled = b.colorize(i, label)
+		}
+		if !add(label+" ", styled+" ") {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// colorize colors a text like the lines of a tag
+func (b *Browser) colorize(tag int, text string) string {
+	if b.opts.Colorizer == nil {
+		return text
+	}
+	return b.opts.Colorizer.Colorize(b.tags[tag], text)
+}
+
+// logRow returns a row of the log cut to the screen, with the search matches in reverse video
+func (b *Browser) logRow(pos int) string {
+	r := b.rows[b.visible[pos]]
+	runes := []rune(r.text)
+	if b.left >= len(runes) {
+		return ""
+	}
+	text := string(runes[b.left:min(len(runes), b.left+b.width)])
+	if b.re != nil {
+		var sb strings.Builder
+		from := 0
+		for _, m := range b.re.FindAllStringIndex(text, -1) {
+			if m[0] == m[1] {
+				continue
+			}
+			sb.WriteString(text[from:m[0]])
+			sb.WriteString("\x1b[7m" + text[m[0]:m[1]] + "\x1b[27m")
+			from = m[1]
+		}
+		sb.WriteString(text[from:])
+		text = sb.String()
+	}
+	if pos == b.match {
+		text = "\x1b[1m" + text
+	}
+	return b.colorize(r.tag, text)
+}
+
+// sparkRows returns the sparkline of the metric over the time of the log, and the row below it
+// with the cursor at the time of the top row and the value of the metric there
+func (b *Browser) sparkRows() (string, string) {
+	m := b.opts.Metrics[b.metric]
+	label := m.Name + " "
+	width := b.width - utf8.RuneCountInString(label)
+	if width < 8 {
+		return label, ""
+	}
+	if b.spark.metric != b.metric || b.spark.width != width {
+		b.spark = b.buildSparkline(m, width)
+		b.spark.metric = b.metric
+	}
+	spark := "\x1b[1m" + label + "\x1b[22m" + string(b.spark.levels)
+
+	var t time.Time
+	if b.top < len(b.visible) {
+		t = b.rows[b.visible[b.top]].time
+	}
+	column := b.column(t, width)
+	offset := utf8.RuneCountInString(label) + column
+	cursor := strings.Repeat(" ", offset) + "^"
+	info := fmt.Sprintf("range %s .. %s", formatValue(b.spark.min, m.Unit), formatValue(b.spark.max, m.Unit))
+	if k := sort.Search(len(m.Points), func(k int) bool { return m.Points[k].Time.After(t) }); k > 0 {
+		info = fmt.Sprintf("%s at %s, %s", formatValue(m.Points[k-1].Value, m.Unit),
+			timestamp.FormatTimestampLayout(m.Points[k-1].Time, b.opts.TimeFormat), info)
+	}
+	infoWidth := utf8.RuneCountInString(info)
+	if offset+2+infoWidth <= b.width {
+		cursor += " " + info
+	} else if infoWidth+1 <= offset {
+		cursor = strings.Repeat(" ", offset-infoWidth-1) + info + " ^"
+	}
+	return spark, "\x1b[2m" + cursor
+}
+
+// buildSparkline returns the sparkline of a metric: the mean value of the points in each column
+func (b *Browser) buildSparkline(m Metric, width int) sparkline {
+	sums := make([]float64, width)
+	counts := make([]int, width)
+	for _, pt := range m.Points {
+		column := b.column(pt.Time, width)
+		sums[column] += pt.Value
+		counts[column]++
+	}
+	s := sparkline{width: width, levels: make([]rune, width)}
+	first := true
+	for k := range sums {
+		if counts[k] == 0 {
+			continue
+		}
+		sums[k] /= float64(counts[k])
+		if first || sums[k] < s.min {
+			s.min = sums[k]
+		}
+		if first || sums[k] > s.max {
+			s.max = sums[k]
+		}
+		first = false
+	}
+	for k := range sums {
+		switch {
+		case counts[k] == 0:
+			s.levels[k] = ' '
+		case s.max == s.min:
+			s.levels[k] = sparkLevels[len(sparkLevels)/2]
+		default:
+			level := int((sums[k] - s.min) / (s.max - s.min) * float64(len(sparkLevels)-1))
+			s.levels[k] = sparkLevels[level]
+		}
+	}
+	return s
+}
+
+// column returns the sparkline column of a time
+func (b *Browser) column(t time.Time, width int) int {
+	span := b.end.Sub(b.start)
+	if span <= 0 {
+		return 0
+	}
+	column := int(float64(t.Sub(b.start)) / float64(span) * float64(width))
+	return min(max(column, 0), width-1)
+}
+
+// formatValue formats a metric value with its unit
+func formatValue(v float64, unit string) string {
+	text := strconv.FormatFloat(v, 'g', 6, 64)
+	if unit != "" {
+		text += " " + unit
+	}
+	return text
+}
+
+// status returns the status bar: the search being typed, or the position and the keys
+func (b *Browser) status() string {
+	var text string
+	switch {
+	case b.searching:
+		text = "/" + b.query
+		if b.searchErr != "" {
+			text += "  (" + b.searchErr + ")"
+		}
+	default:
+		position := "empty"
+		if b.top < len(b.visible) {
+			position = fmt.Sprintf("%d/%d", b.top+1, len(b.visible))
+			if t := b.rows[b.visible[b.top]].time; !t.IsZero() {
+				position += " " + timestamp.FormatTimestampLayout(t, b.opts.TimeFormat)
+			}
+		}
+		text = position
+		if b.query != "" {
+			text += "  /" + b.query
+			if b.searchErr != "" {
+				text += " (" + b.searchErr + ")"
+			}
+		}
+		text += "  | q quit  / search  n/N next/prev  1-9 toggle tag  0 all tags  ←/→ scroll"
+		if len(b.opts.Metrics) > 1 {
+			text += "  m metric"
+		}
+	}
+	if runes := []rune(text); len(runes) > b.width {
+		text = string(runes[:b.width])
+	}
+	return "\x1b[7m" + text + strings.Repeat(" ", max(b.width-utf8.RuneCountInString(text), 0))
+}
@@ -0,0 +1,156 @@
+package visualizer
+
+import (
+	"math"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// rightAxis is a secondary Y-axis drawn on the right-hand side of a plot, with its own scale,
+// label, and ticks. gonum/plot only draws a single Y-axis, so the series of the secondary axis
+// are kept here and drawn into the data area of the primary plot.
+type rightAxis struct {
+	plot.Axis
+	plotters   []plot.Plotter
+	xMin, xMax float64
+}
+
+// newRightAxis creates a secondary Y-axis with the default axis style of a plot
+func newRightAxis(label string) *rightAxis {
+	axis := &rightAxis{Axis: plot.New().Y, xMin: math.Inf(1), xMax: math.Inf(-1)}
+	axis.Label.Text = label
+	axis.Tick.Label.XAlign = draw.XLeft
+	return axis
+}
+
+// Add adds plotters to the secondary axis, extending its range to their data
+func (a *rightAxis) Add(ps ...plot.Plotter) {
+	for _, d := range ps {
+		if r, ok := d.(plot.DataRanger); ok {
+			xmin, xmax, ymin, ymax := r.DataRange()
+			a.xMin = math.Min(a.xMin, xmin)
+			a.xMax = math.Max(a.xMax, xmax)
+			a.Min = math.Min(a.Min, ymin)
+			a.Max = math.Max(a.Max, ymax)
+		}
+	}
+	a.plotters = append(a.plotters, ps...)
+}
+
+// sanitizeRange ensures that the range of the axis makes sense (as plot.Plot does for its axes)
+func (a *rightAxis) sanitizeRange() {
+	if math.IsInf(a.Min, 0) {
+		a.Min = 0
+	}
+	if math.IsInf(a.Max, 0) {
+		a.Max = 0
+	}
+	if a.Min > a.Max {
+		a.Min, a.Max = a.Max, a.Min
+	}
+	if a.Min == a.Max {
+		a.Min--
+		a.Max++
+	}
+}
+
+// width returns the width of the axis: ticks, tick labels, and the axis label
+func (a *rightAxis) width() vg.Length {
+	w := a.Tick.Length
+	if labelWidth := a.tickLabelWidth(); labelWidth > 0 {
+		w += a.Tick.Label.Width(" ") + labelWidth
+	}
+	if a.Label.Text != "" {
+		w += a.Label.Padding + a.Label.TextStyle.Height(a.Label.Text)
+	}
+	return w
+}
+
+// tickLabelWidth returns the width of the widest major tick label
+func (a *rightAxis) tickLabelWidth() vg.Length {
+	var w vg.Length
+	for _, t := range a.Tick.Marker.Ticks(a.Min, a.Max) {
+		if !t.IsMinor() {
+			w = max(w, a.Tick.Label.Width(t.Label))
+		}
+	}
+	return w
+}
+
+// prepare finalizes the range of the axis and extends the X range of the plot to the
+// secondary series
+func (a *rightAxis) prepare(p *plot.Plot) {
+	a.sanitizeRange()
+	p.X.Min = math.Min(p.X.Min, a.xMin)
+	p.X.Max = math.Max(p.X.Max, a.xMax)
+}
+
+// dataCanvas returns the data area of the plot when drawn with drawPlot
+func (a *rightAxis) dataCanvas(p *plot.Plot, dc draw.Canvas) draw.Canvas {
+	a.prepare(p)
+	return p.DataCanvas(draw.Crop(dc, 0, -a.width(), 0, 0))
+}
+
+// drawPlot draws the plot with the secondary axis on its right-hand side
+func (a *rightAxis) drawPlot(p *plot.Plot, dc draw.Canvas) {
+	a.prepare(p)
+	c := draw.Crop(dc, 0, -a.width(), 0, 0)
+
+	// Draw the legend last, so it stays on top of the secondary series
+	legend := p.Legend
+	p.Legend = plot.NewLegend()
+	p.Draw(c)
+	p.Legend = legend
+
+	data := p.DataCanvas(c)
+	secondary := plot.New()
+	secondary.X = p.X
+	secondary.Y = a.Axis
+	for _, d := range a.plotters {
+		d.Plot(data, secondary)
+	}
+	a.draw(dc, c.Max.X, data)
+	legend.Draw(data)
+}
+
+// draw draws the axis line at x, followed by the ticks, tick labels, and the axis label
+func (a *rightAxis) draw(c draw.Canvas, x vg.Length, data draw.Canvas) {
+	c.StrokeLine2(a.LineStyle, x, data.Min.Y, x, data.Max.Y)
+
+	marks := a.Tick.Marker.Ticks(a.Min, a.Max)
+	for _, t := range marks {
+		y := data.Y(a.Norm(t.Value))
+		if !data.ContainsY(y) {
+			continue
+		}
+		length := a.Tick.Length
+		if t.IsMinor() {
+			length /= 2
+		}
+		c.StrokeLine2(a.Tick.LineStyle, x, y, x+length, y)
+	}
+	x += a.Tick.Length
+
+	if labelWidth := a.tickLabelWidth(); labelWidth > 0 {
+		x += a.Tick.Label.Width(" ")
+		descent := a.Tick.Label.FontExtents().Descent
+		for _, t := range marks {
+			y := data.Y(a.Norm(t.Value))
+			if !data.ContainsY(y) || t.IsMinor() {
+				continue
+			}
+			c.FillText(a.Tick.Label, vg.Point{X: x, Y: y + descent}, t.Label)
+		}
+		x += labelWidth
+	}
+
+	if a.Label.Text != "" {
+		sty := a.Label.TextStyle
+		sty.Rotation += math.Pi / 2
+		x += a.Label.Padding + a.Label.TextStyle.Height(a.Label.Text)
+		descent := a.Label.TextStyle.FontExtents().Descent
+		c.FillText(sty, vg.Point{X: x - descent, Y: data.Center().Y}, a.Label.Text)
+	}
+}
@@ -0,0 +1,88 @@
+package visualizer
+
+import (
+	"fmt"
+	"image/color"
+	"log-interleaver/internal/config"
+	"log-interleaver/pkg/pattern"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"gonum.org/v1/plot/plotter"
+)
+
+// bandAlpha is the opacity of the percentile bands behind the series
+const bandAlpha = 0.25
+
+// percentileBand returns the lower and upper percentile of the values in the trailing window
+// (t-window, t] of each point of a time-sorted series, so like the rolling statistics each point
+// of the band only depends on the points up to it
+func percentileBand(points []pattern.MetricPoint, band *config.BandConfig) (lower, upper []pattern.MetricPoint) {
+	low, high := band.Percentiles()
+	lower = make([]pattern.MetricPoint, len(points))
+	upper = make([]pattern.MetricPoint, len(points))
+	var window []float64 // Values of the window, sorted
+	start := 0
+	for i, pt := range points {
+		window = slices.Insert(window, sort.SearchFloat64s(window, pt.Value), pt.Value)
+		for points[start].Time.Add(band.Window).Compare(pt.Time) <= 0 {
+			k := sort.SearchFloat64s(window, points[start].Value)
+			window = slices.Delete(window, k, k+1)
+			start++
+		}
+		lower[i], upper[i] = pt, pt
+		lower[i].Value = windowPercentile(window, low)
+		upper[i].Value = windowPercentile(window, high)
+	}
+	return lower, upper
+}
+
+// windowPercentile returns the p-th percentile of sorted values, interpolated between ranks
+func windowPercentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// bandColor returns the fill color of the band of a series of a color
+func bandColor(c color.Color) color.Color {
+	return withOpacity(c, bandAlpha)
+}
+
+// bandName names the band of a series in the legends
+func bandName(series string, band *config.BandConfig) string {
+	low, high := band.Percentiles()
+	return fmt.Sprintf("%s p%g–p%g", series, low, high)
+}
+
+// newBandPolygon creates the shaded percentile band of a time-sorted series, downsampled like
+// the series, behind which the series is drawn
+func newBandPolygon(points []pattern.MetricPoint, band *config.BandConfig, maxPoints int, startTime time.Time, c color.Color) (*plotter.Polygon, error) {
+	lower, upper := percentileBand(points, band)
+	lower, upper = DownsampleLTTB(lower, maxPoints), DownsampleLTTB(upper, maxPoints)
+	outline := make(plotter.XYs, 0, len(lower)+len(upper))
+	for _, pt := range upper {
+		outline = append(outline, plotter.XY{X: pt.Time.Sub(startTime).Seconds(), Y: pt.Value})
+	}
+	for _, pt := range slices.Backward(lower) {
+		outline = append(outline, plotter.XY{X: pt.Time.Sub(startTime).Seconds(), Y: pt.Value})
+	}
+	polygon, err := plotter.NewPolygon(outline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create band: %w", err)
+	}
+	polygon.Color = bandColor(c)
+	polygon.LineStyle.Width = 0
+	return polygon, nil
+}
+
+// pointOffsets returns the time offsets in seconds from the start time and the values of points
+func pointOffsets(points []pattern.MetricPoint, startTime time.Time) (x, y []float64) {
+	x, y = make([]float64, len(points)), make([]float64, len(points))
+	for i, pt := range points {
+		x[i], y[i] = pt.Time.Sub(startTime).Seconds(), pt.Value
+	}
+	return x, y
+}
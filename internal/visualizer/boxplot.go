@@ -0,0 +1,141 @@
+package visualizer
+
+import (
+	"fmt"
+	"log-interleaver/pkg/pattern"
+	"math"
+	"slices"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// boxRenderer builds the box plot type: a panel per series with values, stacked with a shared X
+// axis, with a box plot of the points of each window of the capture: the box spans the
+// quartiles around the median, the whiskers reach the furthest points within 1.5 times the
+// interquartile range, and points beyond are drawn as outliers. How the boxes shrink shows the
+// stability improving over a soak test.
+func (v *Visualizer) boxRenderer(metrics map[string][]pattern.MetricPoint) (func(draw.Canvas), error) {
+	startTime, endTime := captureRange(metrics)
+	window := v.config.Box.Window
+	windows := int(endTime.Sub(startTime)/window) + 1
+	// Leave half of the room of a window between the boxes
+	width := v.width() / vg.Length(2*windows)
+
+	return v.valuePanels(metrics, "box plot", func(panel *plot.Plot, points []pattern.MetricPoint) error {
+		values := windowValues(points, startTime, window, windows)
+		for k := range values {
+			if len(values[k]) == 0 {
+				continue
+			}
+			center := (time.Duration(k)*window + window/2).Seconds()
+			box, err := plotter.NewBoxPlot(width, center, values[k])
+			if err != nil {
+				return fmt.Errorf("failed to create box plot: %w", err)
+			}
+			box.FillColor = seriesColors[0]
+			box.MedianStyle.Color = seriesColors[1]
+			box.GlyphStyle.Radius = vg.Points(1.5)
+			panel.Add(box)
+		}
+		panel.Add(plotter.NewGrid())
+		return nil
+	})
+}
+
+// violinRenderer builds the violin plot type: the panels and windows of the box plot type, with
+// the density of the points of each window instead of a box, estimated with a Gaussian kernel and
+// mirrored around the center of the window, and a line at the median. Unlike a box, a violin
+// shows when the values of a window cluster at several levels (e.g., before and after a step).
+func (v *Visualizer) violinRenderer(metrics map[string][]pattern.MetricPoint) (func(draw.Canvas), error) {
+	startTime, endTime := captureRange(metrics)
+	window := v.config.Box.Window
+	windows := int(endTime.Sub(startTime)/window) + 1
+	// The widest violins take 80% of the room of a window
+	halfWidth := 0.4 * window.Seconds()
+
+	return v.valuePanels(metrics, "violin plot", func(panel *plot.Plot, points []pattern.MetricPoint) error {
+		for k, values := range windowValues(points, startTime, window, windows) {
+			if len(values) == 0 {
+				continue
+			}
+			center := (time.Duration(k)*window + window/2).Seconds()
+			sorted := slices.Sorted(slices.Values(values))
+			if outline := violinOutline(sorted, center, halfWidth); outline != nil {
+				violin, err := plotter.NewPolygon(outline)
+				if err != nil {
+					return fmt.Errorf("failed to create violin plot: %w", err)
+				}
+				violin.Color = seriesColors[0]
+				panel.Add(violin)
+			}
+			median := windowPercentile(sorted, 50)
+			line, err := plotter.NewLine(plotter.XYs{{X: center - halfWidth/2, Y: median}, {X: center + halfWidth/2, Y: median}})
+			if err != nil {
+				return fmt.Errorf("failed to create violin plot: %w", err)
+			}
+			line.Color = seriesColors[1]
+			line.Width = vg.Points(1.5)
+			panel.Add(line)
+		}
+		panel.Add(plotter.NewGrid())
+		return nil
+	})
+}
+
+// windowValues groups the values of points by the window of the capture they fall in
+func windowValues(points []pattern.MetricPoint, startTime time.Time, window time.Duration, windows int) []plotter.Values {
+	values := make([]plotter.Values, windows)
+	for _, pt := range points {
+		k := int(pt.Time.Sub(startTime) / window)
+		values[k] = append(values[k], pt.Value)
+	}
+	return values
+}
+
+// violinSamples is the number of values from the smallest to the largest one of a window the
+// density of its violin is estimated at
+const violinSamples = 64
+
+// violinOutline returns the outline of the violin of sorted values centered at a time, its widest
+// point halfWidth from the center, or nil when the values do not spread (a single value, or all
+// equal), which leaves only the median line. The bandwidth of the kernel follows Silverman's rule
+// of thumb, 1.06·σ·n^(-1/5).
+func violinOutline(sorted []float64, center, halfWidth float64) plotter.XYs {
+	n := float64(len(sorted))
+	var mean, variance float64
+	for _, value := range sorted {
+		mean += value / n
+	}
+	for _, value := range sorted {
+		variance += (value - mean) * (value - mean) / n
+	}
+	bandwidth := 1.06 * math.Sqrt(variance) * math.Pow(n, -0.2)
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if bandwidth == 0 || hi == lo {
+		return nil
+	}
+
+	levels := make([]float64, violinSamples)
+	density := make([]float64, violinSamples)
+	var peak float64
+	for i := range levels {
+		levels[i] = lo + (hi-lo)*float64(i)/(violinSamples-1)
+		for _, value := range sorted {
+			u := (levels[i] - value) / bandwidth
+			density[i] += math.Exp(-u * u / 2)
+		}
+		peak = math.Max(peak, density[i])
+	}
+	outline := make(plotter.XYs, 0, 2*violinSamples)
+	for i := range levels {
+		outline = append(outline, plotter.XY{X: center + halfWidth*density[i]/peak, Y: levels[i]})
+	}
+	for i := range slices.Backward(levels) {
+		outline = append(outline, plotter.XY{X: center - halfWidth*density[i]/peak, Y: levels[i]})
+	}
+	return outline
+}
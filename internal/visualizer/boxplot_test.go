@@ -0,0 +1,80 @@
+package visualizer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWindowValues(t *testing.T) {
+	values := windowValues(pointsAt(1, 2, 3, 4, 5), binStart, 2*time.Second, 3)
+	want := [][]float64{{1, 2}, {3, 4}, {5}}
+	if len(values) != len(want) {
+		t.Fatalf("%d windows, want %d", len(values), len(want))
+	}
+	for k := range want {
+		if len(values[k]) != len(want[k]) {
+			t.Errorf("window %d: %v, want %v", k, values[k], want[k])
+			continue
+		}
+		for i := range want[k] {
+			if values[k][i] != want[k][i] {
+				t.Errorf("window %d: %v, want %v", k, values[k], want[k])
+				break
+			}
+		}
+	}
+}
+
+func TestViolinOutline(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		spread bool // Whether the values get an outline, not just the median line
+	}{
+		{"single value", []float64{3}, false},
+		{"equal values", []float64{3, 3, 3}, false},
+		{"spread", []float64{1, 2, 2, 3, 3, 3, 4, 4, 5}, true},
+		{"two levels", []float64{-10, -10, -9, 9, 10, 10}, true},
+	}
+	const center, halfWidth = 300.0, 120.0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outline := violinOutline(tt.sorted, center, halfWidth)
+			if !tt.spread {
+				if outline != nil {
+					t.Errorf("outline of %d points, want none", len(outline))
+				}
+				return
+			}
+			if len(outline) != 2*violinSamples {
+				t.Fatalf("outline of %d points, want %d", len(outline), 2*violinSamples)
+			}
+			lo, hi := tt.sorted[0], tt.sorted[len(tt.sorted)-1]
+			widest := 0.0
+			for i, xy := range outline[:violinSamples] {
+				// The left side mirrors the right side, top down
+				mirror := outline[len(outline)-1-i]
+				if math.Abs((xy.X-center)+(mirror.X-center)) > 1e-9 || xy.Y != mirror.Y {
+					t.Fatalf("point %d (%g, %g) is not mirrored by (%g, %g)", i, xy.X, xy.Y, mirror.X, mirror.Y)
+				}
+				if xy.Y < lo || xy.Y > hi {
+					t.Errorf("point %d at %g, outside the values %g to %g", i, xy.Y, lo, hi)
+				}
+				widest = math.Max(widest, xy.X-center)
+			}
+			if math.Abs(widest-halfWidth) > 1e-9 {
+				t.Errorf("widest point %g from the center, want %g", widest, halfWidth)
+			}
+		})
+	}
+}
+
+func TestViolinOutlineTwoLevels(t *testing.T) {
+	// Values at two levels give a waist between them
+	outline := violinOutline([]float64{-10, -10, -10, -9, 9, 10, 10, 10}, 0, 1)
+	waist := outline[violinSamples/2].X
+	if low, high := outline[violinSamples/8].X, outline[violinSamples*7/8].X; waist >= low || waist >= high {
+		t.Errorf("width %g between the levels, want less than at the levels (%g, %g)", waist, low, high)
+	}
+}
@@ -0,0 +1,77 @@
+package visualizer
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// GenerateComparisonPlot overlays the series of two runs, one panel per series found in both,
+// on the time since the start of each run, so runs recorded at different times line up. The
+// format is given by the output extension when empty.
+func GenerateComparisonPlot(baseline, candidate []SeriesData, baselineName, candidateName, outputPath, format string) error {
+	candidates := make(map[string]SeriesData)
+	for _, s := range candidate {
+		if s.Type != "event" && len(s.Y) > 0 {
+			candidates[s.Name] = s
+		}
+	}
+
+	panels := make(map[int]*plot.Plot)
+	var order []int
+	for _, s := range baseline {
+		other, ok := candidates[s.Name]
+		if s.Type == "event" || len(s.Y) == 0 || !ok {
+			continue
+		}
+		p := plot.New()
+		p.Y.Label.Text = s.Name
+		if s.Unit != "" {
+			p.Y.Label.Text += " (" + s.Unit + ")"
+		}
+		p.Add(plotter.NewGrid())
+		p.Legend.Top = true
+		if err := addComparisonLine(p, baselineName, s, 0, nil); err != nil {
+			return err
+		}
+		if err := addComparisonLine(p, candidateName, other, 1, []vg.Length{vg.Points(4), vg.Points(2)}); err != nil {
+			return err
+		}
+		panels[len(order)] = p
+		order = append(order, len(order))
+	}
+	if len(order) == 0 {
+		return fmt.Errorf("no series with values in both runs to plot")
+	}
+
+	plots := stackPanels(panels, order)
+	plots[0].Title.Text = "Comparison"
+	plots[len(plots)-1].X.Label.Text = "Time since start (s)"
+	height := math.Max(4, 2.5*float64(len(plots)))
+	render := func(dc draw.Canvas) { drawStacked(plots, dc) }
+	return savePlot(render, 10*vg.Inch, vg.Length(height)*vg.Inch, 0, format, outputPath)
+}
+
+// addComparisonLine adds the series of one run to a comparison panel
+func addComparisonLine(p *plot.Plot, name string, s SeriesData, colorIdx int, dashes []vg.Length) error {
+	xys := make(plotter.XYs, len(s.Y))
+	for k := range s.Y {
+		xys[k] = plotter.XY{X: s.X[k], Y: s.Y[k]}
+	}
+	line, err := plotter.NewLine(xys)
+	if err != nil {
+		return fmt.Errorf("failed to create line: %w", err)
+	}
+	line.Color = seriesColors[colorIdx]
+	line.Dashes = dashes
+	if s.Step {
+		line.StepStyle = plotter.PostStep
+	}
+	p.Add(line)
+	p.Legend.Add(name, line)
+	return nil
+}
@@ -0,0 +1,69 @@
+package visualizer
+
+import (
+	"log-interleaver/internal/config"
+	"log-interleaver/pkg/pattern"
+	"math"
+)
+
+// maxPoints returns the target point count of a series for plots: the pattern's max_points,
+// else the config's. Zero or less disables downsampling.
+func maxPoints(cfg *config.VisualizationConfig, p *config.PatternConfig) int {
+	if p != nil && p.MaxPoints != 0 {
+		return p.MaxPoints
+	}
+	return cfg.MaxPoints
+}
+
+// DownsampleLTTB reduces time-sorted points to the threshold count with the
+// largest-triangle-three-buckets algorithm, which keeps the visual shape of the series
+// (peaks, steps, and excursions). The first and last points are always kept.
+// Points are returned unchanged when there are no more than the threshold.
+func DownsampleLTTB(points []pattern.MetricPoint, threshold int) []pattern.MetricPoint {
+	if threshold < 3 || len(points) <= threshold {
+		return points
+	}
+
+	x := func(i int) float64 {
+		return points[i].Time.Sub(points[0].Time).Seconds()
+	}
+
+	sampled := make([]pattern.MetricPoint, 0, threshold)
+	sampled = append(sampled, points[0])
+
+	// The points between the first and last are split into threshold-2 buckets; from each
+	// bucket, the point forming the largest triangle with the previously selected point and
+	// the average of the next bucket is selected
+	bucketSize := float64(len(points)-2) / float64(threshold-2)
+	selected := 0
+	for i := 0; i < threshold-2; i++ {
+		nextStart := int(float64(i+1)*bucketSize) + 1
+		nextEnd := min(int(float64(i+2)*bucketSize)+1, len(points))
+		var avgX, avgY float64
+		for j := nextStart; j < nextEnd; j++ {
+			avgX += x(j)
+			avgY += points[j].Value
+		}
+		if n := nextEnd - nextStart; n > 0 {
+			avgX /= float64(n)
+			avgY /= float64(n)
+		}
+
+		start := int(float64(i)*bucketSize) + 1
+		end := int(float64(i+1)*bucketSize) + 1
+		ax, ay := x(selected), points[selected].Value
+		maxArea := -1.0
+		next := start
+		for j := start; j < end; j++ {
+			area := math.Abs((ax-avgX)*(points[j].Value-ay) - (ax-x(j))*(avgY-ay))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+		sampled = append(sampled, points[next])
+		selected = next
+	}
+
+	return append(sampled, points[len(points)-1])
+}
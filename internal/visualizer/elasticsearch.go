@@ -0,0 +1,251 @@
+package visualizer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/pattern"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of a bulk request
+const (
+	esBatchDocs  = 5000
+	esBatchBytes = 5 << 20
+)
+
+// Attempts of a bulk request, with exponential backoff starting at esBackoff between them
+const (
+	esAttempts = 6
+	esBackoff  = time.Second
+)
+
+// esDocument is the document of a line
+type esDocument struct {
+	Timestamp  string             `json:"@timestamp"`
+	Tag        string             `json:"tag"`
+	Message    string             `json:"message"`
+	File       string             `json:"file"`
+	LineNumber int                `json:"line_number"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"` // Values extracted by the patterns
+	States     map[string]string  `json:"states,omitempty"`  // State strings of state series
+	Events     []string           `json:"events,omitempty"`  // Event patterns matching the line
+}
+
+// ExportElasticsearch bulk-indexes the lines as documents into an Elasticsearch or OpenSearch
+// index, given as URL http(s)://host:9200/index. Each document has the resolved timestamp, tag,
+// message, and the values the config patterns extract from the line (cfg may be nil). Lines
+// without a timestamp of their own get the timestamp of the preceding line of their tag.
+//
+// Documents have IDs derived from their source and position, so exporting a capture again
+// does not duplicate lines. Requests rejected for load (429, 502-504) and network errors are
+// retried with exponential backoff. Credentials in the URL are sent as basic auth, and the
+// ELASTICSEARCH_API_KEY environment variable as API key. It returns the number of documents
+// indexed by this call.
+func ExportElasticsearch(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig, target string) (int, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return 0, fmt.Errorf("invalid Elasticsearch target '%s', expected http(s)://host:9200/index", target)
+	}
+	index := strings.Trim(u.Path, "/")
+	if index == "" || strings.Contains(index, "/") {
+		return 0, fmt.Errorf("invalid Elasticsearch target '%s', expected http(s)://host:9200/index", target)
+	}
+	u.Path = "/" + index + "/_bulk"
+	endpoint := u.String()
+
+	var matcher *pattern.PatternMatcher
+	fields := make(map[string]string)
+	events := make(map[string]bool)
+	if cfg != nil && len(cfg.Patterns) > 0 {
+		if matcher, err = NewPatternMatcher(cfg); err != nil {
+			return 0, err
+		}
+		if err := matcher.Prepare(ctx, lines); err != nil {
+			return 0, err
+		}
+		for _, p := range cfg.Patterns {
+			fields[p.Name] = strings.Trim(invalidMetricChars.ReplaceAllString(strings.ToLower(p.Name), "_"), "_")
+			events[p.Name] = p.IsEvent()
+		}
+	}
+
+	var body bytes.Buffer
+	docs, indexed := 0, 0
+	flush := func() error {
+		if docs == 0 {
+			return nil
+		}
+		n, err := bulkIndex(ctx, endpoint, body.Bytes())
+		if err != nil {
+			return err
+		}
+		indexed += n
+		body.Reset()
+		docs = 0
+		return nil
+	}
+
+	last := make(map[string]time.Time)
+	for _, line := range lines {
+		var ts time.Time
+		if t := line.GetTimestamp(); t != nil {
+			ts = t.Time
+			last[line.Tag] = ts
+		} else if ts = last[line.Tag]; ts.IsZero() {
+			continue
+		}
+
+		doc := esDocument{
+			Timestamp:  ts.UTC().Format(time.RFC3339Nano),
+			Tag:        line.Tag,
+			Message:    line.OriginalLine,
+			File:       line.File,
+			LineNumber: line.LineNumber,
+		}
+		if len(line.Continuation) > 0 {
+			doc.Message += "\n" + strings.Join(line.Continuation, "\n")
+		}
+		if matcher != nil {
+			for _, point := range matcher.MatchLine(line) {
+				field := fields[point.SeriesName]
+				if events[point.SeriesName] {
+					doc.Events = append(doc.Events, field)
+					continue
+				}
+				if doc.Metrics == nil {
+					doc.Metrics = make(map[string]float64)
+				}
+				doc.Metrics[field] = point.Value
+				if point.State != "" {
+					if doc.States == nil {
+						doc.States = make(map[string]string)
+					}
+					doc.States[field] = point.State
+				}
+			}
+		}
+
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return indexed, fmt.Errorf("failed to encode document: %w", err)
+		}
+		id := sha256.Sum256([]byte(line.Tag + "\x00" + line.File + "\x00" + strconv.Itoa(line.LineNumber) + "\x00" + doc.Timestamp))
+		fmt.Fprintf(&body, "{\"create\":{\"_id\":\"%s\"}}\n%s\n", hex.EncodeToString(id[:16]), data)
+		if docs++; docs >= esBatchDocs || body.Len() >= esBatchBytes {
+			if err := flush(); err != nil {
+				return indexed, err
+			}
+		}
+	}
+	return indexed, flush()
+}
+
+// esBulkResponse is the part of a bulk response reporting failed items
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// bulkIndex sends a bulk request, retrying rejected requests and items, and returns the number
+// of documents created. Documents that already exist (status 409) are not errors.
+func bulkIndex(ctx context.Context, endpoint string, body []byte) (int, error) {
+	created := 0
+	delay := esBackoff
+	for attempt := 1; ; attempt++ {
+		status, resp, err := postBulk(ctx, endpoint, body)
+		switch {
+		case err != nil:
+			err = fmt.Errorf("bulk request failed: %w", err)
+		case status == http.StatusTooManyRequests, status == http.StatusBadGateway,
+			status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+			err = fmt.Errorf("bulk request failed: status %d: %s", status, bytes.TrimSpace(resp))
+		case status < 200 || status >= 300:
+			return created, fmt.Errorf("bulk request failed: status %d: %s", status, bytes.TrimSpace(resp))
+		default:
+			var result esBulkResponse
+			if err := json.Unmarshal(resp, &result); err != nil {
+				return created, fmt.Errorf("failed to parse bulk response: %w", err)
+			}
+			// Keep the items rejected for load for the next attempt
+			var rejected bytes.Buffer
+			actions := bytes.SplitAfter(body, []byte("\n"))
+			for k, item := range result.Items {
+				for _, r := range item {
+					switch {
+					case r.Status >= 200 && r.Status < 300:
+						created++
+					case r.Status == http.StatusConflict:
+					case r.Status == http.StatusTooManyRequests && 2*k+1 < len(actions):
+						rejected.Write(actions[2*k])
+						rejected.Write(actions[2*k+1])
+					case r.Error != nil:
+						return created, fmt.Errorf("failed to index document: %s: %s", r.Error.Type, r.Error.Reason)
+					default:
+						return created, fmt.Errorf("failed to index document: status %d", r.Status)
+					}
+				}
+			}
+			if rejected.Len() == 0 {
+				return created, nil
+			}
+			body = rejected.Bytes()
+			err = fmt.Errorf("%d documents rejected for load", bytes.Count(body, []byte("\n"))/2)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return created, err
+		}
+		if attempt == esAttempts {
+			return created, fmt.Errorf("%w (after %d attempts)", err, attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return created, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// postBulk posts a bulk request body and returns the status and response body
+func postBulk(ctx context.Context, endpoint string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create bulk request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if key := os.Getenv("ELASTICSEARCH_API_KEY"); key != "" {
+		req.Header.Set("Authorization", "ApiKey "+key)
+	}
+	resp, err := metricsClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, data, nil
+}
@@ -0,0 +1,104 @@
+package visualizer
+
+import (
+	"image/color"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/pattern"
+	"math"
+	"sort"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// eventLines draws the matches of an event pattern as vertical lines across the data area,
+// each labeled along the line
+type eventLines struct {
+	xs        []float64
+	labels    []string
+	LineStyle draw.LineStyle
+	TextStyle draw.TextStyle
+}
+
+// newEventLines creates the event lines of an event series, with X positions in seconds
+// from the start time
+func newEventLines(name string, points []pattern.MetricPoint, startTime time.Time, c color.Color) *eventLines {
+	e := &eventLines{
+		LineStyle: plotter.DefaultLineStyle,
+		TextStyle: plot.New().X.Tick.Label,
+	}
+	e.LineStyle.Color = c
+	e.LineStyle.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+	e.TextStyle.Color = c
+	e.TextStyle.Rotation = math.Pi / 2
+	e.TextStyle.XAlign = draw.XRight
+	e.TextStyle.YAlign = draw.YTop
+	for _, pt := range points {
+		e.xs = append(e.xs, pt.Time.Sub(startTime).Seconds())
+		e.labels = append(e.labels, eventLabel(name, pt))
+	}
+	return e
+}
+
+// gmChangeName names the event lines of the grandmaster changes
+const gmChangeName = "GM change"
+
+// gmChangePoints returns the grandmaster selections of the ptp4l instances in the lines as
+// event points labeled with the new grandmaster, so offset jumps can be told from BMCA decisions
+func gmChangePoints(lines []*parser.LogLine) []pattern.MetricPoint {
+	timelines, _ := analysis.DetectBMCA(lines)
+	var points []pattern.MetricPoint
+	for _, timeline := range timelines {
+		for _, period := range timeline.Periods {
+			points = append(points, pattern.MetricPoint{Time: period.Start, SeriesName: gmChangeName, State: period.ClockIdentity})
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points
+}
+
+// eventLabel returns the label of an event: the pattern name, with the label detail if any
+func eventLabel(name string, pt pattern.MetricPoint) string {
+	if pt.State != "" {
+		return name + ": " + pt.State
+	}
+	return name
+}
+
+// Plot implements plot.Plotter. Labels that would overlap the previous label are skipped.
+func (e *eventLines) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, _ := plt.Transforms(&c)
+	labelHeight := e.TextStyle.Height("M")
+	lastLabel := vg.Length(math.Inf(-1))
+	for i, v := range e.xs {
+		x := trX(v)
+		if !c.ContainsX(x) {
+			continue
+		}
+		c.StrokeLine2(e.LineStyle, x, c.Min.Y, x, c.Max.Y)
+		if x-lastLabel >= labelHeight {
+			c.FillText(e.TextStyle, vg.Point{X: x, Y: c.Max.Y}, e.labels[i])
+			lastLabel = x
+		}
+	}
+}
+
+// DataRange implements plot.DataRanger; events only extend the X range
+func (e *eventLines) DataRange() (xmin, xmax, ymin, ymax float64) {
+	xmin, xmax = math.Inf(1), math.Inf(-1)
+	for _, x := range e.xs {
+		xmin = math.Min(xmin, x)
+		xmax = math.Max(xmax, x)
+	}
+	return xmin, xmax, math.Inf(1), math.Inf(-1)
+}
+
+// Thumbnail implements plot.Thumbnailer, drawing a vertical line in the legend
+func (e *eventLines) Thumbnail(c *draw.Canvas) {
+	x := c.Center().X
+	c.StrokeLine2(e.LineStyle, x, c.Min.Y, x, c.Max.Y)
+}
@@ -1,64 +1,40 @@
 package visualizer
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"log-interleaver/internal/analysis"
 	"log-interleaver/internal/config"
 	"log-interleaver/internal/parser"
-	"log-interleaver/pkg/pattern"
-	"os"
+	"log-interleaver/internal/sink"
 	"sort"
 	"time"
 )
 
 // ExportData exports time series data to CSV format
-func ExportData(lines []*parser.LogLine, configPath, outputPath string) error {
+func ExportData(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath string) error {
 	// Load configuration
-	cfg, err := config.LoadConfig(configPath)
+	cfg, err := config.LoadConfig(configPath, configOpts)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Convert config patterns to pattern matcher format
-	patternConfigs := make([]pattern.PatternConfig, len(cfg.Patterns))
-	for i, p := range cfg.Patterns {
-		patternConfigs[i] = pattern.PatternConfig{
-			Name:         p.Name,
-			Regex:        p.Regex,
-			TagFilter:    p.TagFilter,
-			ValueGroup:   p.ValueGroup,
-			StateGroup:   p.StateGroup,
-			StateMapping: p.StateMapping,
-			Color:        p.Color,
-			LineStyle:    p.LineStyle,
-			Marker:       p.Marker,
-			YAxisLabel:   p.YAxisLabel,
-			YAxisIndex:   p.YAxisIndex,
-		}
-	}
-
-	// Create pattern matcher
-	matcher, err := pattern.NewPatternMatcher(patternConfigs)
-	if err != nil {
-		return fmt.Errorf("failed to create pattern matcher: %w", err)
-	}
-
 	// Extract metrics
-	metrics, err := matcher.ExtractMetrics(lines)
+	metrics, err := ExtractMetrics(ctx, lines, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to extract metrics: %w", err)
+		return err
 	}
 
-	// Create CSV file
-	file, err := os.Create(outputPath)
+	// Create CSV output
+	out, err := sink.Open(outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create CSV file: %w", err)
 	}
-	defer file.Close()
+	defer out.Close()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	writer := csv.NewWriter(out)
 
 	// Find earliest timestamp to use as reference
 	var earliestTime *time.Time
@@ -103,7 +79,11 @@ func ExportData(lines []*parser.LogLine, configPath, outputPath string) error {
 	header := []string{"Time", "TimeOffsetSeconds"}
 	for _, pattern := range cfg.Patterns {
 		if _, ok := metrics[pattern.Name]; ok {
-			header = append(header, pattern.Name)
+			if pattern.DisplayUnit != "" {
+				header = append(header, pattern.Name+" ("+pattern.DisplayUnit+")")
+			} else {
+				header = append(header, pattern.Name)
+			}
 		}
 	}
 	if err := writer.Write(header); err != nil {
@@ -138,7 +118,11 @@ func ExportData(lines []*parser.LogLine, configPath, outputPath string) error {
 					seriesIndices[seriesName] = idx
 
 					if idx < len(points) && points[idx].Time.Equal(t) {
-						value = fmt.Sprintf("%.6f", points[idx].Value)
+						if pattern.IsEvent() {
+							value = eventLabel(pattern.Name, points[idx])
+						} else {
+							value = fmt.Sprintf("%.6f", points[idx].Value)
+						}
 					} else {
 						value = "" // No data at this timestamp
 					}
@@ -152,59 +136,93 @@ func ExportData(lines []*parser.LogLine, configPath, outputPath string) error {
 		}
 	}
 
-	return nil
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV data: %w", err)
+	}
+	return out.Close()
 }
 
 // SeriesData represents a time series for JSON/HTML export
 type SeriesData struct {
-	Name         string            `json:"name"`
-	X            []float64         `json:"x"`            // Time offsets in seconds
-	Y            []float64         `json:"y"`            // Values
-	Color        string            `json:"color,omitempty"`
-	Marker       string            `json:"marker,omitempty"`
-	LineStyle    string            `json:"line_style,omitempty"`
-	Mode         string            `json:"mode"`         // "lines+markers", "lines", "markers"
-	Step         bool              `json:"step,omitempty"` // If true, use step plot (hold value between points)
-	YAxisLabel   string            `json:"yaxis_label,omitempty"` // Y-axis label for this series
+	Name         string             `json:"name"`
+	X            []float64          `json:"x"` // Time offsets in seconds
+	Y            []float64          `json:"y"` // Values
+	Color        string             `json:"color,omitempty"`
+	Marker       string             `json:"marker,omitempty"`
+	LineStyle    string             `json:"line_style,omitempty"`
+	MarkerSize   float64            `json:"marker_size,omitempty"` // Marker diameter (0 = default)
+	LineWidth    float64            `json:"line_width,omitempty"`  // Line width (0 = default)
+	Opacity      float64            `json:"opacity,omitempty"`     // Opacity of the markers and the line (0 = opaque)
+	Mode         string             `json:"mode"`                  // "lines+markers", "lines", "markers"
+	Step         bool               `json:"step,omitempty"`        // If true, use step plot (hold value between points)
+	YAxisLabel   string             `json:"yaxis_label,omitempty"` // Y-axis label for this series
+	YAxisIndex   int                `json:"yaxis_index"`           // Index of the Y-axis the series is plotted on
+	Unit         string             `json:"unit,omitempty"`        // Display unit of the values
 	StateMapping map[string]float64 `json:"state_mapping,omitempty"`
+	Type         string             `json:"type,omitempty"`   // "event" for event series (no Y values)
+	Labels       []string           `json:"labels,omitempty"` // Labels of the events of an event series
+	Fill         string             `json:"fill,omitempty"`   // Plotly fill mode, e.g., "tonexty" for the lower rolling band
+	Band         *BandData          `json:"band,omitempty"`   // Percentile band shaded behind the series
+}
+
+// BandData is the percentile band of a series, with time offsets in seconds. The bounds are
+// downsampled each on its own, so their offsets may differ.
+type BandData struct {
+	Name    string    `json:"name"`
+	Opacity float64   `json:"opacity"`
+	LowerX  []float64 `json:"lower_x"`
+	Lower   []float64 `json:"lower"`
+	UpperX  []float64 `json:"upper_x"`
+	Upper   []float64 `json:"upper"`
+}
+
+// XTickData is the X tick configuration of the plot
+type XTickData struct {
+	Spacing  float64 `json:"spacing,omitempty"`  // Seconds between the ticks (0 = automatic)
+	Format   string  `json:"format,omitempty"`   // Tick labels: "seconds", "duration", or "clock"
+	Rotation float64 `json:"rotation,omitempty"` // Counter-clockwise rotation of the labels in degrees
 }
 
 // ExportJSON exports time series data to JSON format
-func ExportJSON(lines []*parser.LogLine, configPath, outputPath string) error {
+func ExportJSON(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath string) error {
 	// Load configuration
-	cfg, err := config.LoadConfig(configPath)
+	cfg, err := config.LoadConfig(configPath, configOpts)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	if cfg, err = cfg.ExpandTemplates(runMetadata(cfg, lines)); err != nil {
+		return err
+	}
 
-	// Convert config patterns to pattern matcher format
-	patternConfigs := make([]pattern.PatternConfig, len(cfg.Patterns))
-	for i, p := range cfg.Patterns {
-		patternConfigs[i] = pattern.PatternConfig{
-			Name:         p.Name,
-			Regex:        p.Regex,
-			TagFilter:    p.TagFilter,
-			ValueGroup:   p.ValueGroup,
-			StateGroup:   p.StateGroup,
-			StateMapping: p.StateMapping,
-			Color:        p.Color,
-			LineStyle:    p.LineStyle,
-			Marker:       p.Marker,
-			YAxisLabel:   p.YAxisLabel,
-			YAxisIndex:   p.YAxisIndex,
-		}
+	output, err := buildJSONExport(ctx, lines, cfg, false)
+	if err != nil {
+		return err
 	}
 
-	// Create pattern matcher
-	matcher, err := pattern.NewPatternMatcher(patternConfigs)
+	// Write JSON
+	out, err := sink.Open(outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create pattern matcher: %w", err)
+		return fmt.Errorf("failed to create JSON file: %w", err)
 	}
+	defer out.Close()
 
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(output); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return out.Close()
+}
+
+// buildJSONExport extracts the configured series and builds the JSON export structure.
+// With downsample, series longer than their max_points are reduced for plotting.
+func buildJSONExport(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig, downsample bool) (map[string]interface{}, error) {
 	// Extract metrics
-	metrics, err := matcher.ExtractMetrics(lines)
+	metrics, err := ExtractMetrics(ctx, lines, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to extract metrics: %w", err)
+		return nil, err
 	}
 
 	// Find earliest timestamp
@@ -219,7 +237,7 @@ func ExportJSON(lines []*parser.LogLine, configPath, outputPath string) error {
 	}
 
 	if earliestTime == nil {
-		return fmt.Errorf("no timestamps found in data")
+		return nil, fmt.Errorf("no timestamps found in data")
 	}
 
 	// Build series data
@@ -235,6 +253,32 @@ func ExportJSON(lines []*parser.LogLine, configPath, outputPath string) error {
 		sort.Slice(points, func(i, j int) bool {
 			return points[i].Time.Before(points[j].Time)
 		})
+		if pattern.IsLane() {
+			continue // Drawn in the state timeline lane
+		}
+		if pattern.IsEvent() {
+			series := SeriesData{Name: seriesName, Color: pattern.Color, Type: "event", X: make([]float64, len(points))}
+			for i, pt := range points {
+				series.X[i] = pt.Time.Sub(*earliestTime).Seconds()
+				series.Labels = append(series.Labels, eventLabel(seriesName, pt))
+			}
+			seriesList = append(seriesList, series)
+			continue
+		}
+		mapping := stateMapping(&pattern, points)
+		var band *BandData
+		if pattern.Band != nil {
+			lower, upper := percentileBand(points, pattern.Band)
+			if downsample {
+				lower, upper = DownsampleLTTB(lower, maxPoints(cfg, &pattern)), DownsampleLTTB(upper, maxPoints(cfg, &pattern))
+			}
+			band = &BandData{Name: bandName(seriesName, pattern.Band), Opacity: bandAlpha}
+			band.LowerX, band.Lower = pointOffsets(lower, *earliestTime)
+			band.UpperX, band.Upper = pointOffsets(upper, *earliestTime)
+		}
+		if downsample {
+			points = DownsampleLTTB(points, maxPoints(cfg, &pattern))
+		}
 
 		// Extract X and Y arrays
 		x := make([]float64, len(points))
@@ -267,18 +311,38 @@ func ExportJSON(lines []*parser.LogLine, configPath, outputPath string) error {
 			Color:      pattern.Color,
 			Marker:     pattern.Marker,
 			LineStyle:  pattern.LineStyle,
+			MarkerSize: pattern.MarkerSize,
+			LineWidth:  pattern.LineWidth,
+			Opacity:    pattern.Opacity,
 			Mode:       mode,
 			Step:       pattern.Step,
 			YAxisLabel: pattern.YAxisLabel,
+			YAxisIndex: pattern.YAxisIndex,
+			Unit:       pattern.DisplayUnit,
+			Band:       band,
 		}
 
-		if pattern.StateMapping != nil {
-			series.StateMapping = pattern.StateMapping
+		if mapping != nil {
+			series.StateMapping = mapping
+		}
+		if pattern.RollingStat == "lower" {
+			// Shade the band between the upper series (the previous one) and the lower series
+			series.Fill = "tonexty"
 		}
 
 		seriesList = append(seriesList, series)
 	}
 
+	// Mark the grandmaster changes of ptp4l like events
+	if points := gmChangePoints(lines); len(points) > 0 {
+		series := SeriesData{Name: gmChangeName, Type: "event", X: make([]float64, len(points))}
+		for i, pt := range points {
+			series.X[i] = pt.Time.Sub(*earliestTime).Seconds()
+			series.Labels = append(series.Labels, eventLabel(gmChangeName, pt))
+		}
+		seriesList = append(seriesList, series)
+	}
+
 	// Create output structure
 	output := map[string]interface{}{
 		"title":       cfg.Title,
@@ -287,19 +351,36 @@ func ExportJSON(lines []*parser.LogLine, configPath, outputPath string) error {
 		"start_time":  earliestTime.Format(time.RFC3339Nano),
 		"series":      seriesList,
 	}
-
-	// Write JSON
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create JSON file: %w", err)
+	if len(cfg.Axes) > 0 {
+		output["axes"] = cfg.Axes
 	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(output); err != nil {
-		return fmt.Errorf("failed to encode JSON: %w", err)
+	if cfg.Subplots {
+		output["subplots"] = true
+	}
+	if cfg.Legend != (config.LegendConfig{}) {
+		output["legend"] = cfg.Legend
+	}
+	if cfg.FontSizes != (config.FontSizeConfig{}) {
+		output["font_sizes"] = cfg.FontSizes
+	}
+	if cfg.XTickSpacing > 0 || cfg.XTickFormat != "" || cfg.XTickRotation != 0 {
+		output["x_ticks"] = XTickData{Spacing: cfg.XTickSpacing.Seconds(), Format: cfg.XTickFormat, Rotation: cfg.XTickRotation}
+	}
+	if cfg.GapThreshold > 0 {
+		if gaps := analysis.DetectGaps(lines, cfg.GapThreshold); len(gaps) > 0 {
+			output["gaps"] = gapData(gaps, *earliestTime)
+		}
+	}
+	if rows := stateLanes(cfg, metrics); len(rows) > 0 {
+		output["state_timeline"] = laneData(rows, *earliestTime)
+	}
+	if cfg.TEReport != nil && len(cfg.TEReport.Series) > 0 {
+		timeError, err := ComputeTimeErrors(metrics, cfg)
+		if err != nil {
+			return nil, err
+		}
+		output["time_error"] = timeError
 	}
 
-	return nil
+	return output, nil
 }
@@ -0,0 +1,91 @@
+package visualizer
+
+import (
+	"image/color"
+	"log-interleaver/pkg/report"
+	"math"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// gapColor shades log gaps
+var gapColor = color.NRGBA{R: 127, G: 127, B: 127, A: 48}
+
+// gapSpans shades the log gaps of the tags across the data area, each labeled with its tag
+type gapSpans struct {
+	x0, x1    []float64
+	labels    []string
+	Color     color.Color
+	TextStyle draw.TextStyle
+}
+
+// newGapSpans creates the shaded spans of gap events, with X positions in seconds from the
+// start time
+func newGapSpans(gaps []report.Event, startTime time.Time) *gapSpans {
+	g := &gapSpans{Color: gapColor, TextStyle: plot.New().X.Tick.Label}
+	g.TextStyle.Color = color.Gray{Y: 96}
+	g.TextStyle.XAlign = draw.XLeft
+	g.TextStyle.YAlign = draw.YBottom
+	for _, gap := range gaps {
+		start := gap.Time.Sub(startTime).Seconds()
+		g.x0 = append(g.x0, start)
+		g.x1 = append(g.x1, start+gap.DurationSeconds)
+		g.labels = append(g.labels, gap.Tag+" gap")
+	}
+	return g
+}
+
+// Plot implements plot.Plotter. Labels are drawn at the bottom of the spans they fit in.
+func (g *gapSpans) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, _ := plt.Transforms(&c)
+	for i := range g.x0 {
+		x0, x1 := max(trX(g.x0[i]), c.Min.X), min(trX(g.x1[i]), c.Max.X)
+		if x1 <= x0 {
+			continue
+		}
+		rect := vg.Rectangle{Min: vg.Point{X: x0, Y: c.Min.Y}, Max: vg.Point{X: x1, Y: c.Max.Y}}
+		c.SetColor(g.Color)
+		c.Fill(rect.Path())
+		pad := vg.Points(2)
+		if g.TextStyle.Width(g.labels[i])+2*pad <= x1-x0 {
+			c.FillText(g.TextStyle, vg.Point{X: x0 + pad, Y: c.Min.Y + pad}, g.labels[i])
+		}
+	}
+}
+
+// DataRange implements plot.DataRanger; gaps only extend the X range
+func (g *gapSpans) DataRange() (xmin, xmax, ymin, ymax float64) {
+	xmin, xmax = math.Inf(1), math.Inf(-1)
+	for i := range g.x0 {
+		xmin = math.Min(xmin, g.x0[i])
+		xmax = math.Max(xmax, g.x1[i])
+	}
+	return xmin, xmax, math.Inf(1), math.Inf(-1)
+}
+
+// Thumbnail implements plot.Thumbnailer, drawing a shaded box in the legend
+func (g *gapSpans) Thumbnail(c *draw.Canvas) {
+	c.SetColor(g.Color)
+	c.Fill(c.Rectangle.Path())
+}
+
+// GapData is a log gap for the HTML export, with time offsets in seconds
+type GapData struct {
+	Tag     string  `json:"tag"`
+	X0      float64 `json:"x0"`
+	X1      float64 `json:"x1"`
+	Message string  `json:"message"`
+}
+
+// gapData converts gap events for export, relative to the start time
+func gapData(gaps []report.Event, startTime time.Time) []GapData {
+	data := make([]GapData, len(gaps))
+	for i, gap := range gaps {
+		start := gap.Time.Sub(startTime).Seconds()
+		data[i] = GapData{Tag: gap.Tag, X0: start, X1: start + gap.DurationSeconds, Message: gap.Message}
+	}
+	return data
+}
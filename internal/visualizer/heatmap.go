@@ -0,0 +1,198 @@
+package visualizer
+
+import (
+	"fmt"
+	"image/color"
+	"log-interleaver/pkg/pattern"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/palette/brewer"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// heatmapGrid counts the points of a series in cells of a time bucket and a value bucket. Empty
+// cells are NaN, so they show the plot background.
+type heatmapGrid struct {
+	counts   [][]float64 // Point count by time bucket, then value bucket
+	timeStep float64     // Width of a time bucket in seconds
+	minValue float64
+	step     float64 // Height of a value bucket
+	maxCount float64
+}
+
+// newHeatmapGrid buckets the points over the time span from the start time and the value range
+// of the points, leaving out non-finite values
+func newHeatmapGrid(points []pattern.MetricPoint, startTime time.Time, span float64, timeBins, valueBins int) (*heatmapGrid, error) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, pt := range points {
+		if isFinite(pt.Value) {
+			lo, hi = math.Min(lo, pt.Value), math.Max(hi, pt.Value)
+		}
+	}
+	if lo > hi {
+		return nil, fmt.Errorf("no finite values")
+	}
+	if hi == lo {
+		lo, hi = lo-0.5, hi+0.5
+	}
+	g := &heatmapGrid{timeStep: span / float64(timeBins), minValue: lo, step: (hi - lo) / float64(valueBins)}
+	g.counts = make([][]float64, timeBins)
+	for c := range g.counts {
+		g.counts[c] = make([]float64, valueBins)
+	}
+	for _, pt := range points {
+		if !isFinite(pt.Value) {
+			continue
+		}
+		c := min(int(pt.Time.Sub(startTime).Seconds()/g.timeStep), timeBins-1)
+		r := min(int((pt.Value-lo)/g.step), valueBins-1)
+		g.counts[c][r]++
+		g.maxCount = math.Max(g.maxCount, g.counts[c][r])
+	}
+	return g, nil
+}
+
+// isFinite reports whether a value is neither NaN nor infinite
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// Dims implements plotter.GridXYZ
+func (g *heatmapGrid) Dims() (c, r int) {
+	return len(g.counts), len(g.counts[0])
+}
+
+// Z implements plotter.GridXYZ
+func (g *heatmapGrid) Z(c, r int) float64 {
+	if g.counts[c][r] == 0 {
+		return math.NaN()
+	}
+	return g.counts[c][r]
+}
+
+// X implements plotter.GridXYZ, returning the center of a time bucket
+func (g *heatmapGrid) X(c int) float64 {
+	return (float64(c) + 0.5) * g.timeStep
+}
+
+// Y implements plotter.GridXYZ, returning the center of a value bucket
+func (g *heatmapGrid) Y(r int) float64 {
+	return g.minValue + (float64(r)+0.5)*g.step
+}
+
+// heatmapColors maps point counts from light (few points) to dark (many points), leaving out
+// the lightest colors of the scheme so single points stand out from the background
+func heatmapColors() (palette.Palette, error) {
+	scheme, err := brewer.GetPalette(brewer.TypeSequential, "YlGnBu", 9)
+	if err != nil {
+		return nil, err
+	}
+	// The luminance has to increase along the controls, so they go from dark to light
+	controls := slices.Clone(scheme.Colors()[2:])
+	slices.Reverse(controls)
+	colors, err := moreland.NewLuminance(controls)
+	if err != nil {
+		return nil, err
+	}
+	return palette.Reverse(colors).Palette(64), nil
+}
+
+// colorSwatch is a legend entry of a heatmap color
+type colorSwatch struct {
+	color color.Color
+}
+
+// Thumbnail implements plot.Thumbnailer, drawing a box of the color in the legend
+func (s colorSwatch) Thumbnail(c *draw.Canvas) {
+	c.SetColor(s.color)
+	c.Fill(c.Rectangle.Path())
+}
+
+// heatmapRenderer builds the heatmap plot type: a panel per series with values, stacked with a
+// shared X axis, counting the points of the series in cells of a time bucket over the capture
+// and a value bucket over the range of the series. Unlike a scatter plot, it shows how the
+// distribution of the values moves over long captures.
+func (v *Visualizer) heatmapRenderer(metrics map[string][]pattern.MetricPoint) (func(draw.Canvas), error) {
+	startTime, endTime := captureRange(metrics)
+	span := endTime.Sub(startTime).Seconds()
+	if span == 0 {
+		span = 1
+	}
+	pal, err := heatmapColors()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create heatmap colors: %w", err)
+	}
+	colors := pal.Colors()
+
+	return v.valuePanels(metrics, "heatmap", func(panel *plot.Plot, points []pattern.MetricPoint) error {
+		grid, err := newHeatmapGrid(points, startTime, span, v.config.Heatmap.TimeBins, v.config.Heatmap.ValueBins)
+		if err != nil {
+			return fmt.Errorf("failed to bucket the values of %s: %w", panel.Y.Label.Text, err)
+		}
+		heatmap := plotter.NewHeatMap(grid, pal)
+		heatmap.Min, heatmap.Max = 1, math.Max(grid.maxCount, 2)
+		panel.Add(heatmap)
+		v.addLegend(panel, "1 point", colorSwatch{colors[0]})
+		v.addLegend(panel, strconv.Itoa(int(heatmap.Max))+" points", colorSwatch{colors[len(colors)-1]})
+		return nil
+	})
+}
+
+// captureRange returns the times of the earliest and the latest point of any series
+func captureRange(metrics map[string][]pattern.MetricPoint) (start, end time.Time) {
+	for _, points := range metrics {
+		for _, pt := range points {
+			if start.IsZero() || pt.Time.Before(start) {
+				start = pt.Time
+			}
+			if pt.Time.After(end) {
+				end = pt.Time
+			}
+		}
+	}
+	return start, end
+}
+
+// valuePanels renders the distribution plot types: a panel per series with values (state and
+// event series are left out), filled by the add function and stacked with a shared X axis
+func (v *Visualizer) valuePanels(metrics map[string][]pattern.MetricPoint, plotType string, add func(*plot.Plot, []pattern.MetricPoint) error) (func(draw.Canvas), error) {
+	startTime, _ := captureRange(metrics)
+	panels := make(map[int]*plot.Plot)
+	var order []int
+	for _, p := range v.config.Patterns {
+		points := metrics[p.Name]
+		if p.IsEvent() || p.HasStates() || len(points) == 0 {
+			continue
+		}
+		panel := plot.New()
+		v.styleFonts(panel)
+		panel.Y.Label.Text = p.Name
+		if p.DisplayUnit != "" {
+			panel.Y.Label.Text += " (" + p.DisplayUnit + ")"
+		}
+		panel.Legend.Top = true
+		panel.Legend.Left = true
+		v.styleXAxis(&panel.X, startTime)
+		if err := add(panel, points); err != nil {
+			return nil, err
+		}
+		panels[len(order)] = panel
+		order = append(order, len(order))
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("no series with values for the %s", plotType)
+	}
+
+	v.placeLegends(nil)
+	plots := stackPanels(panels, order)
+	plots[0].Title.Text = v.config.Title
+	plots[len(plots)-1].X.Label.Text = v.config.XAxisLabel
+	return func(dc draw.Canvas) { drawStacked(plots, dc) }, nil
+}
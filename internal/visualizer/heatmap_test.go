@@ -0,0 +1,90 @@
+package visualizer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"log-interleaver/pkg/pattern"
+)
+
+var binStart = time.Date(2026, 1, 11, 14, 0, 0, 0, time.UTC)
+
+// pointsAt returns points of the values, one per second from binStart
+func pointsAt(values ...float64) []pattern.MetricPoint {
+	points := make([]pattern.MetricPoint, len(values))
+	for i, value := range values {
+		points[i] = pattern.MetricPoint{Time: binStart.Add(time.Duration(i) * time.Second), Value: value}
+	}
+	return points
+}
+
+func TestNewHeatmapGrid(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		counts   [][]float64 // By time bucket, then value bucket
+		minValue float64
+		step     float64
+	}{
+		{
+			name:     "spread",
+			values:   []float64{0, 1, 2, 3},
+			counts:   [][]float64{{2, 0}, {0, 2}},
+			minValue: 0,
+			step:     1.5,
+		},
+		{
+			// A single value gets a bucket range of 1 around it, and lands in the upper half
+			name:     "constant",
+			values:   []float64{5, 5, 5, 5},
+			counts:   [][]float64{{0, 2}, {0, 2}},
+			minValue: 4.5,
+			step:     0.5,
+		},
+		{
+			name:     "non-finite values skipped",
+			values:   []float64{0, math.NaN(), math.Inf(1), 3},
+			counts:   [][]float64{{1, 0}, {0, 1}},
+			minValue: 0,
+			step:     1.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newHeatmapGrid(pointsAt(tt.values...), binStart, 4, 2, 2)
+			if err != nil {
+				t.Fatalf("newHeatmapGrid: %v", err)
+			}
+			if g.minValue != tt.minValue || g.step != tt.step {
+				t.Errorf("value buckets from %g by %g, want from %g by %g", g.minValue, g.step, tt.minValue, tt.step)
+			}
+			for c := range tt.counts {
+				for r := range tt.counts[c] {
+					if got := g.counts[c][r]; got != tt.counts[c][r] {
+						t.Errorf("cell (%d, %d): %g points, want %g", c, r, got, tt.counts[c][r])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewHeatmapGridNoFiniteValues(t *testing.T) {
+	if _, err := newHeatmapGrid(pointsAt(math.NaN(), math.Inf(-1)), binStart, 2, 2, 2); err == nil {
+		t.Error("newHeatmapGrid of non-finite values succeeded, want an error")
+	}
+}
+
+func TestHeatmapGridEmptyCells(t *testing.T) {
+	g, err := newHeatmapGrid(pointsAt(0, 1), binStart, 2, 2, 2)
+	if err != nil {
+		t.Fatalf("newHeatmapGrid: %v", err)
+	}
+	if z := g.Z(0, 1); !math.IsNaN(z) {
+		t.Errorf("empty cell is %g, want NaN", z)
+	}
+	if z := g.Z(0, 0); z != 1 {
+		t.Errorf("cell of one point is %g, want 1", z)
+	}
+}
@@ -1,32 +1,58 @@
 package visualizer
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"log-interleaver/internal/config"
 	"log-interleaver/internal/parser"
-	"os"
+	"log-interleaver/internal/sink"
+	"strings"
 )
 
 // GenerateInteractiveHTML generates an interactive HTML plot using Plotly.js
-func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath string) error {
-	// Export to JSON first to get the data structure
-	jsonPath := outputPath + ".tmp.json"
-	if err := ExportJSON(lines, configPath, jsonPath); err != nil {
-		return fmt.Errorf("failed to export JSON data: %w", err)
-	}
-	defer os.Remove(jsonPath)
+func GenerateInteractiveHTML(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath string) error {
+	return GenerateInteractiveHTMLWithOptions(ctx, lines, configPath, configOpts, outputPath, HTMLOptions{})
+}
 
+// GenerateInteractiveHTMLWithOptions generates an interactive HTML plot using Plotly.js,
+// by default with the Plotly.js bundle inlined so the file works offline
+func GenerateInteractiveHTMLWithOptions(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath string, opts HTMLOptions) error {
 	// Load configuration for metadata
-	cfg, err := config.LoadConfig(configPath)
+	cfg, err := config.LoadConfig(configPath, configOpts)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Read JSON data
-	jsonData, err := os.ReadFile(jsonPath)
+	// Write HTML file
+	out, err := sink.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create HTML file: %w", err)
+	}
+	defer out.Close()
+
+	if err := WriteInteractiveHTML(ctx, lines, cfg, out, opts); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// WriteInteractiveHTML writes the interactive HTML plot of the lines to w
+func WriteInteractiveHTML(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig, w io.Writer, opts HTMLOptions) error {
+	cfg, err := cfg.ExpandTemplates(runMetadata(cfg, lines))
+	if err != nil {
+		return err
+	}
+	// Build the JSON export in memory to get the data structure (downsampled for rendering)
+	exportData, err := buildJSONExport(ctx, lines, cfg, true)
+	if err != nil {
+		return fmt.Errorf("failed to export JSON data: %w", err)
+	}
+	jsonData, err := json.Marshal(exportData)
 	if err != nil {
-		return fmt.Errorf("failed to read JSON data: %w", err)
+		return fmt.Errorf("failed to encode JSON data: %w", err)
 	}
 
 	// Generate HTML template
@@ -34,41 +60,78 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
 <html>
 <head>
     <title>{{.Title}}</title>
-    <script src="https://cdn.plot.ly/plotly-2.27.0.min.js"></script>
+    {{if .PlotlyJS}}<script>{{.PlotlyJS}}</script>{{else}}<script src="{{.PlotlyCDN}}"></script>{{end}}
     <style>
+        /* Theme colors; custom CSS can override them, including the plot colors */
+        :root {
+            --page-bg: #f5f5f5;
+            --panel-bg: white;
+            --border: #ddd;
+            --text: #333;
+            --info-bg: #e8f4f8;
+            --info-accent: #2196F3;
+            --button-bg: #4CAF50;
+            --button-hover-bg: #45a049;
+            --button-text: white;
+            --plot-bg: white;
+            --plot-grid: #e0e0e0;
+            --plot-legend-bg: rgba(255, 255, 255, 0.8);
+            --plot-legend-border: #ccc;
+            --plot-hover-bg: rgba(255, 255, 255, 0.95);
+            --plot-hover-border: #333;
+        }
+        body.theme-dark {
+            --page-bg: #181b1f;
+            --panel-bg: #22252b;
+            --border: #3a3f47;
+            --text: #d8d9da;
+            --info-bg: #1f2a33;
+            --info-accent: #5794f2;
+            --button-bg: #3274d9;
+            --button-hover-bg: #2b63ba;
+            --button-text: white;
+            --plot-bg: #22252b;
+            --plot-grid: #3a3f47;
+            --plot-legend-bg: rgba(34, 37, 43, 0.8);
+            --plot-legend-border: #3a3f47;
+            --plot-hover-bg: rgba(24, 27, 31, 0.95);
+            --plot-hover-border: #d8d9da;
+        }
         body {
             font-family: Arial, sans-serif;
             margin: 20px;
-            background-color: #f5f5f5;
+            background-color: var(--page-bg);
+            color: var(--text);
         }
         #plotly-div {
             width: 100%;
-            height: 800px;
-            background-color: white;
-            border: 1px solid #ddd;
+            max-width: {{.PlotWidth}}px;
+            height: {{.PlotHeight}}px;
+            background-color: var(--plot-bg);
+            border: 1px solid var(--border);
             border-radius: 5px;
             padding: 10px;
         }
         .controls {
             margin-bottom: 20px;
             padding: 15px;
-            background-color: white;
+            background-color: var(--panel-bg);
             border-radius: 5px;
-            border: 1px solid #ddd;
+            border: 1px solid var(--border);
         }
         .info {
             margin-top: 20px;
             padding: 15px;
-            background-color: #e8f4f8;
+            background-color: var(--info-bg);
             border-radius: 5px;
-            border-left: 4px solid #2196F3;
+            border-left: 4px solid var(--info-accent);
         }
         h1 {
-            color: #333;
+            color: var(--text);
         }
         button {
-            background-color: #4CAF50;
-            color: white;
+            background-color: var(--button-bg);
+            color: var(--button-text);
             padding: 10px 20px;
             border: none;
             border-radius: 4px;
@@ -77,17 +140,40 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
             font-size: 14px;
         }
         button:hover {
-            background-color: #45a049;
+            background-color: var(--button-hover-bg);
+        }
+        .te-report {
+            margin-top: 20px;
+            padding: 15px;
+            background-color: var(--panel-bg);
+            border-radius: 5px;
+            border: 1px solid var(--border);
+        }
+        .te-report table {
+            border-collapse: collapse;
+        }
+        .te-report th, .te-report td {
+            padding: 6px 12px;
+            border-bottom: 1px solid var(--border);
+            text-align: right;
+        }
+        .te-report th:first-child, .te-report td:first-child {
+            text-align: left;
         }
     </style>
+    {{if .CustomCSS}}<style>
+{{.CustomCSS}}
+    </style>{{end}}
 </head>
-<body>
+<body class="theme-{{.Theme}}">
     <h1>{{.Title}}</h1>
     
     <div class="controls">
         <button onclick="resetZoom()">Reset Zoom</button>
         <button onclick="toggleSeries()">Toggle Series Visibility</button>
-        <button onclick="exportData()">Export Data (CSV)</button>
+        <button onclick="exportData()">Export Data (CSV)</button>{{if .LiveURL}}
+        <button id="live-toggle" onclick="toggleLive()">Pause</button>
+        <span id="live-state">Connecting...</span>{{end}}
     </div>
     
     <div id="plotly-div"></div>
@@ -102,10 +188,18 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
             <li><strong>Hover:</strong> Hover over data points to see exact values</li>
         </ul>
     </div>
+    {{if .TEReport}}
+    {{.TEReport}}
+    {{end}}
 
     <script>
         const data = {{.JSONData}};
-        const series = data.series;
+        
+        // Plot colors follow the page theme (CSS variables)
+        const pageStyle = getComputedStyle(document.body);
+        const themeColor = name => pageStyle.getPropertyValue(name).trim();
+        const series = data.series.filter(s => s.type !== 'event');
+        const events = data.series.filter(s => s.type === 'event');
         
         // Prepare Plotly traces
         const traces = series.map((s, idx) => {
@@ -125,20 +219,23 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
             const yLabel = s.yaxis_label || data.yaxis_label || 'Value';
             const hoverTemplate = '<b>%{fullData.name}</b><br>' +
                 data.xaxis_label + ': %{x:.6f}<br>' +
-                yLabel + ': %{y:.6f}<extra></extra>';
+                yLabel + ': %{y:.6f}' + (s.unit ? ' ' + s.unit : '') + '<extra></extra>';
             
             const trace = {
                 x: s.x,
                 y: s.y,
+                yaxis: s.yaxis_index > 0 ? 'y' + (s.yaxis_index + 1) : 'y',
                 name: legendName,
                 type: 'scatter',
                 mode: s.mode || 'lines+markers',
+                fill: s.fill || 'none',
+                opacity: s.opacity || 1,
                 hovertemplate: hoverTemplate,
                 hoverlabel: {
                     namelength: -1  // Don't truncate series names
                 },
                 marker: s.mode && s.mode.includes('markers') ? {
-                    size: 5,
+                    size: s.marker_size || 5,
                     symbol: s.marker === 'o' || s.marker === 'O' || s.marker === 'circle' ? 'circle' : 
                             s.marker === 'x' || s.marker === 'X' ? 'x' : 
                             s.marker === 's' || s.marker === 'S' || s.marker === 'square' ? 'square' : 
@@ -147,7 +244,7 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
                             s.marker === '.' || s.marker === 'point' ? 'circle' : 'circle'
                 } : undefined,
                 line: s.mode && s.mode.includes('lines') ? {
-                    width: 2,
+                    width: s.line_width || 2,
                     dash: s.line_style === '--' || s.line_style === 'dashed' ? 'dash' : 
                           s.line_style === ':' || s.line_style === 'dotted' ? 'dot' : 
                           s.line_style === '-.' || s.line_style === 'dashdot' ? 'dashdot' : 'solid',
@@ -183,23 +280,56 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
             return trace;
         });
         
+        // Percentile bands: filled areas between the bounds, placed before the series so they are
+        // drawn below them, which shifts the trace index of each series by the number of band
+        // traces. The series keep the default colors of their original trace index.
+        const defaultColors = ['#1f77b4', '#ff7f0e', '#2ca02c', '#d62728', '#9467bd', '#8c564b', '#e377c2', '#7f7f7f', '#bcbd22', '#17becf'];
+        const bandTraces = [];
+        if (series.some(s => s.band)) {
+            series.forEach((s, idx) => {
+                const trace = traces[idx];
+                const color = (trace.line && trace.line.color) || (trace.marker && trace.marker.color) || defaultColors[idx % defaultColors.length];
+                if (trace.line) {
+                    trace.line.color = color;
+                }
+                if (trace.marker) {
+                    trace.marker.color = color;
+                }
+                if (!s.band) {
+                    return;
+                }
+                const bound = (x, y) => ({
+                    x: x, y: y, yaxis: trace.yaxis, type: 'scatter', mode: 'lines',
+                    line: { width: 0, color: color }, legendgroup: s.band.name, hoverinfo: 'skip'
+                });
+                bandTraces.push(Object.assign(bound(s.band.lower_x, s.band.lower), { showlegend: false }),
+                    Object.assign(bound(s.band.upper_x, s.band.upper), {
+                        name: s.band.name, fill: 'tonexty', fillcolor: color, opacity: s.band.opacity
+                    }));
+            });
+            traces.unshift(...bandTraces);
+        }
+        
         const layout = {
             title: data.title,
+            paper_bgcolor: themeColor('--plot-bg'),
+            plot_bgcolor: themeColor('--plot-bg'),
+            font: { color: themeColor('--text') },
             xaxis: {
                 title: data.xaxis_label,
                 showgrid: true,
-                gridcolor: '#e0e0e0'
+                gridcolor: themeColor('--plot-grid')
             },
             yaxis: {
                 title: data.yaxis_label,
                 showgrid: true,
-                gridcolor: '#e0e0e0'
+                gridcolor: themeColor('--plot-grid')
             },
             hovermode: 'closest',
             hoverlabel: {
                 namelength: -1,  // Don't truncate series names in hover
-                bgcolor: 'rgba(255, 255, 255, 0.95)',
-                bordercolor: '#333',
+                bgcolor: themeColor('--plot-hover-bg'),
+                bordercolor: themeColor('--plot-hover-border'),
                 font: {
                     size: 12
                 }
@@ -207,8 +337,8 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
             legend: {
                 x: 0,
                 y: 1,
-                bgcolor: 'rgba(255, 255, 255, 0.8)',
-                bordercolor: '#ccc',
+                bgcolor: themeColor('--plot-legend-bg'),
+                bordercolor: themeColor('--plot-legend-border'),
                 borderwidth: 1
             },
             margin: {
@@ -219,6 +349,228 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
             }
         };
         
+        // Y-axes: named axes from the config, or the legacy left (0) / right (1) pair
+        const axes = data.axes || [
+            { name: 'left', label: data.yaxis_label, side: 'left' },
+            { name: 'right', label: '', side: 'right' }
+        ];
+        const primarySide = axes[0].side || 'left';
+        layout.yaxis.title = axes[0].label || data.yaxis_label;
+        layout.yaxis.side = primarySide;
+        
+        const extraAxes = Array.from(new Set(series.map(s => s.yaxis_index || 0)))
+            .filter(idx => idx > 0)
+            .sort((a, b) => a - b);
+        const axisSide = idx => (axes[idx] && axes[idx].side) || 'right';
+        
+        if (data.subplots && extraAxes.length > 0) {
+            // Subplots: one panel per axis, stacked top to bottom and sharing the X axis
+            const panels = [0].concat(extraAxes);
+            const panelGap = 0.04;
+            const panelHeight = (1 - panelGap * (panels.length - 1)) / panels.length;
+            panels.forEach((idx, n) => {
+                const top = 1 - n * (panelHeight + panelGap);
+                const name = idx > 0 ? 'yaxis' + (idx + 1) : 'yaxis';
+                layout[name] = Object.assign(layout[name] || {}, {
+                    title: (axes[idx] && axes[idx].label) || (idx === 0 ? data.yaxis_label : ''),
+                    side: idx > 0 ? axisSide(idx) : primarySide,
+                    showgrid: true,
+                    gridcolor: themeColor('--plot-grid'),
+                    anchor: 'x',
+                    domain: [Math.max(0, top - panelHeight), top]
+                });
+            });
+            if (panels.some(idx => idx > 0 && axisSide(idx) === 'right')) {
+                layout.margin.r = 60;
+            }
+        } else {
+            // The first axis on each side is anchored to the plot; further axes are stacked outside it
+            const sideCounts = { left: 0, right: 0 };
+            sideCounts[primarySide] = 1;
+            extraAxes.forEach(idx => sideCounts[axisSide(idx)]++);
+            const axisGap = 0.06;
+            const freeLeft = Math.max(0, sideCounts.left - 1);
+            const freeRight = Math.max(0, sideCounts.right - 1);
+            layout.xaxis.domain = [axisGap * freeLeft, 1 - axisGap * freeRight];
+        
+            const placed = { left: 0, right: 0 };
+            placed[primarySide] = 1;
+            extraAxes.forEach(idx => {
+                const side = axisSide(idx);
+                const n = placed[side]++;
+                layout['yaxis' + (idx + 1)] = {
+                    title: (axes[idx] && axes[idx].label) || '',
+                    overlaying: 'y',
+                    side: side,
+                    showgrid: false,
+                    anchor: n === 0 ? 'x' : 'free',
+                    position: side === 'left' ? axisGap * (freeLeft - n) : 1 - axisGap * (freeRight - n)
+                };
+            });
+            if (sideCounts.right > 0) {
+                layout.margin.r = 60;
+            }
+        }
+        
+        // State timeline lane: one row of bars per state-mapped series, below the plot
+        if (data.state_timeline && data.state_timeline.length > 0) {
+            const lanes = data.state_timeline;
+            const laneTop = Math.min(0.3, 0.05 * (lanes.length + 1));
+            const laneGap = 0.08;
+            Object.keys(layout).filter(k => k.startsWith('yaxis') && !layout[k].overlaying).forEach(k => {
+                const d = layout[k].domain || [0, 1];
+                const scale = 1 - laneTop - laneGap;
+                layout[k].domain = [laneTop + laneGap + d[0] * scale, laneTop + laneGap + d[1] * scale];
+            });
+            const laneIdx = Math.max(1, ...extraAxes) + 2;
+            layout['yaxis' + laneIdx] = {
+                domain: [0, laneTop],
+                anchor: 'x',
+                type: 'category',
+                categoryorder: 'array',
+                categoryarray: lanes.map(l => l.name),
+                autorange: 'reversed',
+                showgrid: false,
+                fixedrange: true
+            };
+            layout.xaxis.anchor = 'y' + laneIdx;
+            lanes.forEach(lane => {
+                traces.push({
+                    type: 'bar',
+                    orientation: 'h',
+                    yaxis: 'y' + laneIdx,
+                    name: lane.name,
+                    showlegend: false,
+                    base: lane.segments.map(seg => seg.x0),
+                    x: lane.segments.map(seg => seg.x1 - seg.x0),
+                    y: lane.segments.map(() => lane.name),
+                    text: lane.segments.map(seg => seg.state),
+                    customdata: lane.segments.map(seg => [seg.x0, seg.x1]),
+                    textposition: 'inside',
+                    insidetextanchor: 'middle',
+                    marker: { color: lane.segments.map(seg => seg.color) },
+                    hovertemplate: '<b>%{y}</b><br>%{text}: %{customdata[0]:.3f} – %{customdata[1]:.3f}<extra></extra>'
+                });
+            });
+        }
+        
+        // Events: labeled vertical lines spanning all axes
+        layout.shapes = [];
+        layout.annotations = [];
+        const eventColors = ['rgb(127, 127, 127)', 'rgb(214, 39, 40)', 'rgb(148, 103, 189)', 'rgb(140, 86, 75)'];
+        events.forEach((e, idx) => {
+            const color = e.color || eventColors[idx % eventColors.length];
+            e.x.forEach((x, i) => {
+                layout.shapes.push({
+                    type: 'line', xref: 'x', yref: 'paper', x0: x, x1: x, y0: 0, y1: 1,
+                    line: { color: color, width: 1, dash: 'dot' }
+                });
+                layout.annotations.push({
+                    x: x, y: 1, xref: 'x', yref: 'paper', text: e.labels[i], hovertext: e.labels[i],
+                    textangle: -90, xanchor: 'left', yanchor: 'top', showarrow: false,
+                    font: { size: 10, color: color }
+                });
+            });
+        });
+        
+        // Log gaps: shaded spans below the series
+        (data.gaps || []).forEach(g => {
+            layout.shapes.push({
+                type: 'rect', xref: 'x', yref: 'paper', x0: g.x0, x1: g.x1, y0: 0, y1: 1, layer: 'below',
+                fillcolor: 'rgba(127, 127, 127, 0.2)', line: { width: 0 }
+            });
+            layout.annotations.push({
+                x: g.x0, y: 0, xref: 'x', yref: 'paper', text: g.tag + ' gap', hovertext: g.tag + ': ' + g.message,
+                xanchor: 'left', yanchor: 'bottom', showarrow: false, font: { size: 10, color: 'rgb(127, 127, 127)' }
+            });
+        });
+        
+        // Legend placement and font sizes. Legends in columns are horizontal, with entries of a
+        // fraction of the plot width.
+        const legendConfig = data.legend || {};
+        const legendPositions = {
+            'top-left': { x: 0, y: 1, xanchor: 'left', yanchor: 'top' },
+            'top-right': { x: 1, y: 1, xanchor: 'right', yanchor: 'top' },
+            'bottom-left': { x: 0, y: 0, xanchor: 'left', yanchor: 'bottom' },
+            'bottom-right': { x: 1, y: 0, xanchor: 'right', yanchor: 'bottom' },
+            // At the bottom of the page, which reserves its room below the X axis, its tick labels
+            // (rotated or not), and the state lane
+            'below': { x: 0, y: 0, yref: 'container', xanchor: 'left', yanchor: 'bottom', orientation: 'h' }
+        };
+        if (legendConfig.position === 'hidden') {
+            layout.showlegend = false;
+        } else if (legendPositions[legendConfig.position]) {
+            Object.assign(layout.legend, legendPositions[legendConfig.position]);
+        }
+        if (legendConfig.columns > 1) {
+            Object.assign(layout.legend, { orientation: 'h', entrywidthmode: 'fraction', entrywidth: 1 / legendConfig.columns });
+        }
+        const fontSizes = data.font_sizes || {};
+        if (fontSizes.title) {
+            layout.title = { text: layout.title, font: { size: fontSizes.title } };
+        }
+        if (fontSizes.axis) {
+            Object.keys(layout).filter(k => /^[xy]axis[0-9]*$/.test(k)).forEach(k => {
+                const axis = layout[k];
+                axis.title = typeof axis.title === 'object' ? Object.assign({}, axis.title, { font: { size: fontSizes.axis } })
+                    : { text: axis.title, font: { size: fontSizes.axis } };
+                axis.tickfont = { size: fontSizes.axis };
+            });
+        }
+        if (fontSizes.legend) {
+            layout.legend.font = { size: fontSizes.legend };
+        }
+        
+        // X tick settings. The ticks are placed for the visible range, so they are recomputed on
+        // zoom and pan; a spacing leaving fewer than two ticks in view gives way to a finer one.
+        const xTicks = data.x_ticks || {};
+        if (xTicks.rotation) {
+            layout.xaxis.tickangle = -xTicks.rotation;
+        }
+        const xTickPlaced = xTicks.spacing > 0 || xTicks.format !== undefined;
+        
+        function durationLabel(seconds) {
+            const sign = seconds < 0 ? '-' : '';
+            seconds = Math.round(Math.abs(seconds) * 1000) / 1000;
+            const h = Math.floor(seconds / 3600), m = Math.floor(seconds % 3600 / 60);
+            const sec = Math.round(seconds % 60 * 1000) / 1000;
+            const label = (h > 0 ? h + 'h' : '') + (m > 0 ? m + 'm' : '');
+            return sign + label + (sec > 0 || label === '' ? sec + 's' : '');
+        }
+        
+        function xTickLabel(seconds) {
+            if (xTicks.format === 'clock') {
+                return new Date(Date.parse(data.start_time) + Math.round(seconds * 1000)).toISOString().substring(11, 19);
+            }
+            if (xTicks.format === 'duration') {
+                return durationLabel(seconds);
+            }
+            return String(Math.round(seconds * 1000) / 1000);
+        }
+        
+        // niceTickSpacing rounds a spacing in seconds up to whole seconds, minutes, or hours
+        function niceTickSpacing(target) {
+            const steps = [1, 2, 5, 10, 15, 30, 60, 120, 300, 600, 900, 1800, 3600, 7200, 10800, 21600, 43200, 86400];
+            if (target < 1) {
+                return Math.pow(10, Math.ceil(Math.log10(target)));
+            }
+            const step = steps.find(s => s >= target);
+            return step || Math.ceil(target / 86400) * 86400;
+        }
+        
+        function xTickUpdate(range) {
+            let spacing = xTicks.spacing;
+            const span = range[1] - range[0];
+            if (!spacing || span / spacing > 200 || span / spacing < 2) {
+                spacing = niceTickSpacing(span / 8);
+            }
+            const tickvals = [];
+            for (let n = Math.ceil(range[0] / spacing); n * spacing <= range[1]; n++) {
+                tickvals.push(n * spacing);
+            }
+            return { 'xaxis.tickmode': 'array', 'xaxis.tickvals': tickvals, 'xaxis.ticktext': tickvals.map(xTickLabel) };
+        }
+        
         const config = {
             responsive: true,
             displayModeBar: true,
@@ -227,6 +579,16 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
         };
         
         Plotly.newPlot('plotly-div', traces, layout, config);
+        if (xTickPlaced) {
+            const div = document.getElementById('plotly-div');
+            Plotly.relayout(div, xTickUpdate(div.layout.xaxis.range));
+            div.on('plotly_relayout', function(eventData) {
+                // Tick updates carry no range, so they do not trigger another one
+                if (Object.keys(eventData).some(k => k.startsWith('xaxis.range') || k === 'xaxis.autorange')) {
+                    Plotly.relayout(div, xTickUpdate(div.layout.xaxis.range));
+                }
+            });
+        }
         
         let currentLayout = layout;
         
@@ -285,7 +647,69 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
                     yaxis: { range: [eventData['yaxis.range[0]'], eventData['yaxis.range[1]']] }
                 });
             }
-        });
+        });{{if .LiveURL}}
+        
+        // Live updates: new points of the series streamed while the logs grow. Points received
+        // while paused are kept and added on resume.
+        const liveState = document.getElementById('live-state');
+        const liveLast = {};
+        series.forEach(s => { liveLast[s.name] = s.x.length > 0 ? s.x[s.x.length - 1] : null; });
+        let livePaused = false;
+        let livePending = [];
+        
+        function applyLive(points) {
+            const update = { x: [], y: [] };
+            const indices = [];
+            series.forEach((s, idx) => {
+                const p = points[s.name];
+                if (p && p.x.length > 0) {
+                    update.x.push(p.x);
+                    update.y.push(p.y);
+                    indices.push(bandTraces.length + idx); // The series traces follow the bands
+                    liveLast[s.name] = p.x[p.x.length - 1];
+                }
+            });
+            if (indices.length > 0) {
+                Plotly.extendTraces('plotly-div', update, indices);
+                // Extending grows the autoranged axis without a relayout event
+                if (xTickPlaced) {
+                    const div = document.getElementById('plotly-div');
+                    Plotly.relayout(div, xTickUpdate(div.layout.xaxis.range));
+                }
+            }
+        }
+        
+        function toggleLive() {
+            livePaused = !livePaused;
+            document.getElementById('live-toggle').textContent = livePaused ? 'Resume' : 'Pause';
+            if (!livePaused) {
+                livePending.forEach(applyLive);
+                livePending = [];
+            }
+            liveState.textContent = livePaused ? 'Paused' : 'Live';
+        }
+        
+        function connectLive() {
+            const ws = new WebSocket((location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + {{.LiveURL}});
+            ws.onopen = () => {
+                // The server sends the points after the last ones of the page
+                ws.send(JSON.stringify({ start_time: data.start_time, last: liveLast }));
+                liveState.textContent = livePaused ? 'Paused' : 'Live';
+            };
+            ws.onmessage = event => {
+                const message = JSON.parse(event.data);
+                if (livePaused) {
+                    livePending.push(message.points);
+                } else {
+                    applyLive(message.points);
+                }
+            };
+            ws.onclose = () => {
+                liveState.textContent = 'Disconnected, reconnecting...';
+                setTimeout(connectLive, 5000);
+            };
+        }
+        connectLive();{{end}}
     </script>
 </body>
 </html>`
@@ -296,25 +720,69 @@ func GenerateInteractiveHTML(lines []*parser.LogLine, configPath, outputPath str
 		return fmt.Errorf("failed to parse HTML template: %w", err)
 	}
 
+	// Render the time error report section if configured
+	var teReport template.HTML
+	if cfg.TEReport != nil && len(cfg.TEReport.Series) > 0 {
+		report, err := buildTEReport(ctx, lines, cfg, false)
+		if err != nil {
+			return fmt.Errorf("failed to build time error report: %w", err)
+		}
+		if len(report.Series) > 0 {
+			if teReport, err = RenderTEReportHTML(report); err != nil {
+				return err
+			}
+		}
+	}
+
+	plotlyJS, err := plotlyScript(opts)
+	if err != nil {
+		return err
+	}
+
+	theme := cfg.Theme
+	if opts.Theme != "" {
+		theme = opts.Theme
+	}
+	if theme == "" {
+		theme = ThemeLight
+	}
+	if theme != ThemeLight && theme != ThemeDark {
+		return fmt.Errorf("invalid theme '%s', expected light or dark", theme)
+	}
+
 	// Prepare template data
 	templateData := struct {
-		Title    string
-		JSONData template.JS
+		Title     string
+		PlotlyJS  template.JS
+		PlotlyCDN string
+		JSONData  template.JS
+		TEReport  template.HTML
+		Theme     string
+		CustomCSS template.CSS
+		LiveURL   string
+		// Size of the plot in pixels: the size of the static plot at its dpi, with the width
+		// shrinking to the page
+		PlotWidth  int
+		PlotHeight int
 	}{
-		Title:    cfg.Title,
-		JSONData: template.JS(string(jsonData)),
+		Title:     cfg.Title,
+		PlotlyJS:  plotlyJS,
+		PlotlyCDN: plotlyCDN,
+		JSONData:  template.JS(string(jsonData)),
+		TEReport:  teReport,
+		Theme:     theme,
+		// The custom CSS is trusted page content; only a closing style tag would break out of it
+		CustomCSS:  template.CSS(strings.ReplaceAll(opts.CSS, "</style", `<\/style`)),
+		LiveURL:    opts.LiveURL,
+		PlotWidth:  cfg.Width * cfg.DPI,
+		PlotHeight: cfg.Height * cfg.DPI,
 	}
-
-	// Write HTML file
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create HTML file: %w", err)
+	if cfg.HTMLHeight > 0 {
+		templateData.PlotHeight = cfg.HTMLHeight
 	}
-	defer file.Close()
 
-	if err := tmpl.Execute(file, templateData); err != nil {
+	if err := tmpl.Execute(w, templateData); err != nil {
 		return fmt.Errorf("failed to execute HTML template: %w", err)
 	}
-
 	return nil
 }
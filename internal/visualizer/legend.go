@@ -0,0 +1,208 @@
+package visualizer
+
+import (
+	"math"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/font"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// legendEntry is an entry of a plot legend
+type legendEntry struct {
+	name   string
+	thumbs []plot.Thumbnailer
+}
+
+// plotLegend is the legend of a plot as added by the renderers. gonum/plot legends do not expose
+// their entries, so they are recorded for the layouts gonum/plot does not draw: columns and a
+// legend below the plot.
+type plotLegend struct {
+	plot    *plot.Plot
+	entries []legendEntry
+}
+
+// addLegend adds an entry to the legend of a plot
+func (v *Visualizer) addLegend(p *plot.Plot, name string, thumbs ...plot.Thumbnailer) {
+	p.Legend.Add(name, thumbs...)
+	for _, l := range v.legends {
+		if l.plot == p {
+			l.entries = append(l.entries, legendEntry{name, thumbs})
+			return
+		}
+	}
+	v.legends = append(v.legends, &plotLegend{plot: p, entries: []legendEntry{{name, thumbs}}})
+}
+
+// placeLegends places the legends of the plots in the corner of the legend settings, in columns
+// added to the plots by the add function (nil adds them to the plot itself), or hides them.
+// Legends below the plot are drawn by legendBelow instead.
+func (v *Visualizer) placeLegends(add func(*plot.Plot, plot.Plotter)) {
+	position := v.config.Legend.Position
+	if position == "below" {
+		return
+	}
+	if add == nil {
+		add = func(p *plot.Plot, columns plot.Plotter) { p.Add(columns) }
+	}
+	for _, l := range v.legends {
+		legend := &l.plot.Legend
+		if position == "hidden" {
+			*legend = emptyLegend(*legend)
+			continue
+		}
+		if position != "" {
+			legend.Top = position == "top-left" || position == "top-right"
+			legend.Left = position == "top-left" || position == "bottom-left"
+		}
+		if v.config.Legend.Columns > 1 {
+			columns := &legendColumns{style: *legend, entries: l.entries, columns: v.config.Legend.Columns}
+			*legend = emptyLegend(*legend)
+			add(l.plot, columns)
+		}
+	}
+}
+
+// legendBelow returns the render function drawing the plot above a legend of the entries of all
+// plots, in as many columns as fit the width unless the legend settings give the columns
+func (v *Visualizer) legendBelow(render func(draw.Canvas)) func(draw.Canvas) {
+	if v.config.Legend.Position != "below" || len(v.legends) == 0 {
+		return render
+	}
+	style := v.legends[0].plot.Legend
+	var entries []legendEntry
+	for _, l := range v.legends {
+		entries = append(entries, l.entries...)
+		l.plot.Legend = emptyLegend(l.plot.Legend)
+	}
+	style.Top, style.Left = true, true
+	legend := &legendColumns{style: style, entries: entries, columns: v.config.Legend.Columns}
+
+	return func(dc draw.Canvas) {
+		legend := *legend
+		if legend.columns == 0 {
+			legend.columns = legend.fit(dc.Size().X)
+		}
+		padding := style.TextStyle.Rectangle("M").Max.Y
+		height := legend.height() + 2*padding
+		dc.SetColor(plot.New().BackgroundColor)
+		dc.Fill(draw.Crop(dc, 0, 0, 0, -(dc.Size().Y - height)).Rectangle.Path())
+		render(draw.Crop(dc, 0, 0, height, 0))
+
+		area := draw.Crop(dc, 0, 0, padding, -(dc.Size().Y - height + padding))
+		// Center the columns under the plot
+		margin := (area.Size().X - legend.width()) / 2
+		legend.draw(draw.Crop(area, margin, 0, 0, 0))
+	}
+}
+
+// emptyLegend returns a legend without entries in the style of a legend
+func emptyLegend(style plot.Legend) plot.Legend {
+	legend := plot.NewLegend()
+	legend.TextStyle, legend.Top, legend.Left = style.TextStyle, style.Top, style.Left
+	return legend
+}
+
+// legendColumns draws legend entries in columns, row by row, in the style and the corner of a
+// plot legend
+type legendColumns struct {
+	style   plot.Legend
+	entries []legendEntry
+	columns int
+}
+
+// split returns the legends of the columns. The rows are completed with blank entries, so the
+// entries of a row line up also in the bottom corners.
+func (l *legendColumns) split() []plot.Legend {
+	columns := min(l.columns, len(l.entries))
+	rows := (len(l.entries) + columns - 1) / columns
+	legends := make([]plot.Legend, columns)
+	for c := range legends {
+		legends[c] = emptyLegend(l.style)
+		legends[c].Left = true
+		for r := 0; r < rows; r++ {
+			if k := r*columns + c; k < len(l.entries) {
+				legends[c].Add(l.entries[k].name, l.entries[k].thumbs...)
+			} else {
+				legends[c].Add("")
+			}
+		}
+	}
+	return legends
+}
+
+// columnGap returns the space between two columns
+func (l *legendColumns) columnGap() vg.Length {
+	return l.style.TextStyle.Rectangle("MM").Max.X
+}
+
+// width returns the width of the columns with the gaps between them
+func (l *legendColumns) width() vg.Length {
+	var width vg.Length
+	for k, legend := range l.split() {
+		if k > 0 {
+			width += l.columnGap()
+		}
+		width += legend.Rectangle(draw.Canvas{}).Size().X
+	}
+	return width
+}
+
+// height returns the height of the rows
+func (l *legendColumns) height() vg.Length {
+	var height vg.Length
+	for _, legend := range l.split() {
+		height = max(height, legend.Rectangle(draw.Canvas{}).Size().Y)
+	}
+	return height
+}
+
+// fit returns the number of columns of the widest entry that fit the width
+func (l *legendColumns) fit(width vg.Length) int {
+	single := legendColumns{style: l.style, entries: l.entries, columns: 1}
+	entry := single.width() + l.columnGap()
+	return max(1, min(len(l.entries), int(math.Floor(float64((width+l.columnGap())/entry)))))
+}
+
+// Plot implements plot.Plotter, drawing the columns in the corner of the legend style
+func (l *legendColumns) Plot(c draw.Canvas, _ *plot.Plot) {
+	l.draw(c)
+}
+
+// draw draws the columns from the left of the canvas, or ending at its right in right corners
+func (l *legendColumns) draw(c draw.Canvas) {
+	x := c.Min.X
+	if !l.style.Left {
+		x = c.Max.X - l.width()
+	}
+	for _, legend := range l.split() {
+		column := c
+		column.Min.X = x
+		legend.Top = l.style.Top
+		legend.Draw(column)
+		x += legend.Rectangle(draw.Canvas{}).Size().X + l.columnGap()
+	}
+}
+
+// styleFonts sets the font sizes of the config on a plot
+func (v *Visualizer) styleFonts(p *plot.Plot) {
+	sizes := v.config.FontSizes
+	if sizes.Title > 0 {
+		p.Title.TextStyle.Font.Size = font.Length(sizes.Title)
+	}
+	v.styleAxisFonts(&p.X)
+	v.styleAxisFonts(&p.Y)
+	if sizes.Legend > 0 {
+		p.Legend.TextStyle.Font.Size = font.Length(sizes.Legend)
+	}
+}
+
+// styleAxisFonts sets the axis font size of the config on the label and the tick labels of an
+// axis
+func (v *Visualizer) styleAxisFonts(axis *plot.Axis) {
+	if size := v.config.FontSizes.Axis; size > 0 {
+		axis.Label.TextStyle.Font.Size = font.Length(size)
+		axis.Tick.Label.Font.Size = font.Length(size)
+	}
+}
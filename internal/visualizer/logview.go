@@ -0,0 +1,128 @@
+package visualizer
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// LogPage is a page of the interleaved log in the dashboard of the serve command
+type LogPage struct {
+	Title    string
+	Query    string // Regex of the lines to keep
+	Exclude  string // Regex of the lines to drop
+	Tags     []LogPageTag
+	Lines    []LogPageLine
+	Total    int // Lines of the log
+	Matched  int // Lines passing the filters
+	Page     int // 1-based
+	Pages    int
+	PrevURL  string
+	NextURL  string
+	Error    string // Invalid filter
+	Loaded   string // Time the logs were processed
+	HasPlots bool   // Whether the config has patterns to plot
+}
+
+// LogPageTag is a tag filter checkbox, with the color of the tag's lines
+type LogPageTag struct {
+	Name     string
+	Selected bool
+	Color    template.CSS
+}
+
+// LogPageLine is a line of the log page
+type LogPageLine struct {
+	Number int // Line number in the interleaved log
+	Time   string
+	Tag    string
+	Color  template.CSS
+	Text   string // Line with its continuation lines
+	File   string // Source file and line number
+}
+
+// TagColor returns the color of the i-th tag of the log page: hues spread by the golden angle
+func TagColor(i int) template.CSS {
+	return template.CSS(fmt.Sprintf("hsl(%d, 60%%, 38%%)", (i*137+200)%360))
+}
+
+// WriteLogPageHTML writes a log page of the dashboard
+func WriteLogPageHTML(page *LogPage, w io.Writer) error {
+	tmpl, err := template.New("log").Parse(logPageTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+	if err := tmpl.Execute(w, page); err != nil {
+		return fmt.Errorf("failed to execute HTML template: %w", err)
+	}
+	return nil
+}
+
+const logPageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>{{.Title}}</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 0; background: #f5f5f5; color: #333; }
+        nav { background: #22252b; padding: 10px 20px; }
+        nav a { color: #d8d9da; margin-right: 20px; text-decoration: none; }
+        nav a:hover { color: white; }
+        nav form.reload { display: inline; background: none; border: 0; padding: 0; margin: 0 20px 0 0; }
+        nav form.reload button { background: none; border: 0; padding: 0; color: #d8d9da; font: inherit; cursor: pointer; }
+        nav form.reload button:hover { color: white; }
+        nav .loaded { color: #8e9196; float: right; font-size: 13px; }
+        main { padding: 0 20px 20px; }
+        form { background: white; border: 1px solid #ddd; border-radius: 5px; padding: 12px; margin: 15px 0; }
+        form input[type=text] { width: 300px; padding: 4px; font-family: monospace; }
+        form label { margin-right: 12px; white-space: nowrap; }
+        .tags { margin-top: 8px; }
+        .error { color: #c62828; margin-top: 8px; }
+        .summary { margin: 10px 0; }
+        table { border-collapse: collapse; width: 100%; background: white; font-family: monospace; font-size: 13px; }
+        td { padding: 1px 8px; vertical-align: top; border-bottom: 1px solid #f0f0f0; }
+        td.num, td.time { color: #888; white-space: nowrap; }
+        td.tag { font-weight: bold; white-space: nowrap; }
+        td.text { white-space: pre-wrap; word-break: break-all; }
+        .pager a { margin-right: 15px; }
+    </style>
+</head>
+<body>
+    <nav>
+        <a href="/">Log</a>
+        {{if .HasPlots}}<a href="/plot">Interactive plot</a>
+        <a href="/plot.png">Plot image</a>{{end}}
+        <a href="/report">Report</a>
+        <a href="/analysis">Analysis</a>
+        <form class="reload" method="post" action="/reload"><button type="submit">Reload</button></form>
+        <span class="loaded">Processed {{.Loaded}}</span>
+    </nav>
+    <main>
+    <form method="get" action="/">
+        <label>Search <input type="text" name="q" value="{{.Query}}" placeholder="regex"></label>
+        <label>Exclude <input type="text" name="v" value="{{.Exclude}}" placeholder="regex"></label>
+        <button type="submit">Filter</button>
+        <a href="/">Clear</a>
+        <div class="tags">Tags:
+            {{range .Tags}}<label style="color: {{.Color}}"><input type="checkbox" name="tag" value="{{.Name}}"{{if .Selected}} checked{{end}}> {{.Name}}</label>{{end}}
+        </div>
+        {{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+    </form>
+    <div class="summary">{{.Matched}} of {{.Total}} lines{{if gt .Pages 1}}, page {{.Page}} of {{.Pages}}{{end}}</div>
+    <table>
+        {{range .Lines}}<tr>
+            <td class="num">{{.Number}}</td>
+            <td class="time">{{.Time}}</td>
+            <td class="tag" style="color: {{.Color}}" title="{{.File}}">{{.Tag}}</td>
+            <td class="text">{{.Text}}</td>
+        </tr>
+        {{end}}
+    </table>
+    <div class="pager summary">
+        {{if .PrevURL}}<a href="{{.PrevURL}}">&larr; Previous</a>{{end}}
+        {{if .NextURL}}<a href="{{.NextURL}}">Next &rarr;</a>{{end}}
+    </div>
+    </main>
+</body>
+</html>
+`
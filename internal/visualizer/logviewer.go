@@ -0,0 +1,598 @@
+package visualizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"log-interleaver/pkg/timestamp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// logViewerData is the data embedded into the log viewer page. Lines are arrays of seconds
+// since the first timestamped line (null without a timestamp), tag index, formatted time,
+// text with its continuation lines, and source file and line number.
+type logViewerData struct {
+	Title      string         `json:"title"`
+	StartTime  string         `json:"start_time,omitempty"`
+	Tags       []logViewerTag `json:"tags"`
+	Lines      [][]any        `json:"lines"`
+	Plot       map[string]any `json:"plot,omitempty"`
+	PlotOffset float64        `json:"plot_offset,omitempty"` // Start of the plot data (seconds since the first line)
+}
+
+// logViewerTag is a tag of the log viewer with the color of its lines
+type logViewerTag struct {
+	Name  string       `json:"name"`
+	Color template.CSS `json:"color"`
+}
+
+// GenerateLogViewerHTML writes the HTML log viewer of the lines to a location
+func GenerateLogViewerHTML(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig, outputPath string, opts HTMLOptions) error {
+	out, err := sink.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create HTML file: %w", err)
+	}
+	defer out.Close()
+
+	if err := WriteLogViewerHTML(ctx, lines, cfg, out, opts); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// WriteLogViewerHTML writes a self-contained HTML viewer of the interleaved log: lines colored
+// by tag in a virtually scrolled list, text search, tag toggles, and a time slider. When the
+// config has patterns, the page includes their plot, whose cursor follows the log position and
+// which moves the log to the clicked time. cfg may be nil.
+func WriteLogViewerHTML(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig, w io.Writer, opts HTMLOptions) error {
+	theme := opts.Theme
+	data := logViewerData{Title: "Log", Tags: []logViewerTag{}, Lines: make([][]any, 0, len(lines))}
+	if cfg != nil {
+		if cfg.Title != "" {
+			data.Title = cfg.Title
+		}
+		if theme == "" {
+			theme = cfg.Theme
+		}
+	}
+	if theme == "" {
+		theme = ThemeLight
+	}
+	if theme != ThemeLight && theme != ThemeDark {
+		return fmt.Errorf("invalid theme '%s', expected light or dark", theme)
+	}
+
+	var tags []string
+	var start *time.Time
+	for _, line := range lines {
+		if !slices.Contains(tags, line.Tag) {
+			tags = append(tags, line.Tag)
+		}
+		if t := line.GetTimestamp(); t != nil && (start == nil || t.Time.Before(*start)) {
+			start = &t.Time
+		}
+	}
+	slices.Sort(tags)
+	for i, tag := range tags {
+		data.Tags = append(data.Tags, logViewerTag{Name: tag, Color: logViewerColor(i, theme)})
+	}
+	if start != nil {
+		data.StartTime = start.Format(time.RFC3339Nano)
+	}
+	for _, line := range lines {
+		text := line.OriginalLine
+		if len(line.Continuation) > 0 {
+			text += "\n" + strings.Join(line.Continuation, "\n")
+		}
+		var offset any
+		var ts string
+		if t := line.GetTimestamp(); t != nil {
+			offset = t.Time.Sub(*start).Seconds()
+			ts = timestamp.FormatTimestampLayout(t.Time, timestamp.DefaultOutputFormat)
+		}
+		data.Lines = append(data.Lines, []any{offset, slices.Index(tags, line.Tag), ts, text,
+			fmt.Sprintf("%s:%d", line.File, line.LineNumber)})
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var plotlyJS template.JS
+	if cfg != nil && len(cfg.Patterns) > 0 && start != nil {
+		cfg, err := cfg.ExpandTemplates(runMetadata(cfg, lines))
+		if err != nil {
+			return err
+		}
+		plot, err := buildJSONExport(ctx, lines, cfg, true)
+		if err != nil {
+			return fmt.Errorf("failed to export JSON data: %w", err)
+		}
+		// The plot is drawn against the log time, so its cursor and the slider agree
+		plotStart, err := time.Parse(time.RFC3339Nano, plot["start_time"].(string))
+		if err != nil {
+			return fmt.Errorf("failed to parse plot start time: %w", err)
+		}
+		data.Plot = plot
+		data.PlotOffset = plotStart.Sub(*start).Seconds()
+		if plotlyJS, err = plotlyScript(opts); err != nil {
+			return err
+		}
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode log data: %w", err)
+	}
+	tmpl, err := template.New("viewer").Parse(logViewerTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+	templateData := struct {
+		Title     string
+		HasPlot   bool
+		PlotlyJS  template.JS
+		PlotlyCDN string
+		JSONData  template.JS
+		Theme     string
+		CustomCSS template.CSS
+	}{
+		Title:     data.Title,
+		HasPlot:   data.Plot != nil,
+		PlotlyJS:  plotlyJS,
+		PlotlyCDN: plotlyCDN,
+		JSONData:  template.JS(string(jsonData)),
+		Theme:     theme,
+		// The custom CSS is trusted page content; only a closing style tag would break out of it
+		CustomCSS: template.CSS(strings.ReplaceAll(opts.CSS, "</style", `<\/style`)),
+	}
+	if err := tmpl.Execute(w, templateData); err != nil {
+		return fmt.Errorf("failed to execute HTML template: %w", err)
+	}
+	return nil
+}
+
+// logViewerColor returns the color of the i-th tag, lightened on the dark theme
+func logViewerColor(i int, theme string) template.CSS {
+	if theme == ThemeDark {
+		return template.CSS(fmt.Sprintf("hsl(%d, 60%%, 65%%)", (i*137+200)%360))
+	}
+	return TagColor(i)
+}
+
+const logViewerTemplate = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>{{.Title}}</title>
+    {{if .HasPlot}}{{if .PlotlyJS}}<script>{{.PlotlyJS}}</script>{{else}}<script src="{{.PlotlyCDN}}"></script>{{end}}{{end}}
+    <style>
+        /* Theme colors; custom CSS can override them */
+        :root {
+            --page-bg: #f5f5f5;
+            --panel-bg: white;
+            --border: #ddd;
+            --text: #333;
+            --muted: #888;
+            --match-bg: #fff3a0;
+            --current-bg: #ffc94d;
+            --selected-bg: #e8f4f8;
+            --plot-grid: #e0e0e0;
+        }
+        body.theme-dark {
+            --page-bg: #181b1f;
+            --panel-bg: #22252b;
+            --border: #3a3f47;
+            --text: #d8d9da;
+            --muted: #8e9196;
+            --match-bg: #5c5424;
+            --current-bg: #8a6d1f;
+            --selected-bg: #1f2a33;
+            --plot-grid: #3a3f47;
+        }
+        html, body { height: 100%; }
+        body {
+            font-family: Arial, sans-serif;
+            margin: 0;
+            display: flex;
+            flex-direction: column;
+            background-color: var(--page-bg);
+            color: var(--text);
+        }
+        .bar {
+            display: flex;
+            flex-wrap: wrap;
+            align-items: center;
+            gap: 6px 14px;
+            padding: 8px 12px;
+            background-color: var(--panel-bg);
+            border-bottom: 1px solid var(--border);
+            font-size: 14px;
+        }
+        .bar h1 { font-size: 16px; margin: 0 10px 0 0; }
+        .bar input[type=text] { width: 280px; padding: 3px; font-family: monospace; }
+        .bar label { white-space: nowrap; }
+        .bar .muted { color: var(--muted); }
+        .bar .error { color: #c62828; }
+        .tags label { font-weight: bold; }
+        .slider input { width: 420px; vertical-align: middle; }
+        #plot { height: 260px; flex: none; border-bottom: 1px solid var(--border); }
+        #log {
+            flex: 1;
+            overflow-y: auto;
+            position: relative;
+            background-color: var(--panel-bg);
+            font-family: monospace;
+            font-size: 13px;
+        }
+        #spacer { position: relative; }
+        .row {
+            position: absolute;
+            left: 0;
+            right: 0;
+            height: 18px;
+            line-height: 18px;
+            white-space: nowrap;
+            overflow: hidden;
+            text-overflow: ellipsis;
+            cursor: pointer;
+        }
+        .row:hover, .row.selected { background-color: var(--selected-bg); }
+        .row span { padding-right: 10px; }
+        .row .num, .row .time, .row .more { color: var(--muted); }
+        .row .tag { font-weight: bold; }
+        mark { background-color: var(--match-bg); color: inherit; }
+        .row.current mark { background-color: var(--current-bg); }
+        #detail {
+            flex: none;
+            max-height: 30%;
+            overflow: auto;
+            margin: 0;
+            padding: 8px 12px;
+            border-top: 1px solid var(--border);
+            background-color: var(--page-bg);
+            font-size: 13px;
+            white-space: pre-wrap;
+            word-break: break-all;
+        }
+        #detail:empty { display: none; }
+    </style>
+    {{if .CustomCSS}}<style>
+{{.CustomCSS}}
+    </style>{{end}}
+</head>
+<body class="theme-{{.Theme}}">
+    <div class="bar">
+        <h1>{{.Title}}</h1>
+        <input type="text" id="search" placeholder="Search (Enter: next, Shift+Enter: previous)">
+        <label><input type="checkbox" id="regex"> Regex</label>
+        <label><input type="checkbox" id="only"> Only matching lines</label>
+        <button id="prev">&uarr;</button>
+        <button id="next">&darr;</button>
+        <span id="matches" class="muted"></span>
+        <span id="error" class="error"></span>
+    </div>
+    <div class="bar tags" id="tags">Tags:</div>
+    <div class="bar slider">
+        <label>Time <input type="range" id="slider" min="0" max="0" step="any" value="0"></label>
+        <span id="position"></span>
+        <span id="count" class="muted"></span>
+    </div>
+    {{if .HasPlot}}<div id="plot"></div>{{end}}
+    <div id="log"><div id="spacer"></div></div>
+    <pre id="detail"></pre>
+
+    <script>
+        const data = {{.JSONData}};
+        const rowHeight = 18;
+        const lines = data.lines;
+
+        // Lines without a timestamp take the time of the preceding line, so every line has a
+        // position on the slider and the times only go forward
+        const times = new Float64Array(lines.length);
+        let last = 0;
+        lines.forEach((line, i) => {
+            last = Math.max(last, line[0] === null ? last : line[0]);
+            times[i] = last;
+        });
+        const duration = last;
+
+        const logDiv = document.getElementById('log');
+        const spacer = document.getElementById('spacer');
+        const slider = document.getElementById('slider');
+        const position = document.getElementById('position');
+        const search = document.getElementById('search');
+        const regexBox = document.getElementById('regex');
+        const onlyBox = document.getElementById('only');
+        const detail = document.getElementById('detail');
+        slider.max = duration;
+
+        const shown = new Set(data.tags.map((tag, i) => i));
+        let visible = [];   // Indexes of the lines shown
+        let matches = [];   // Positions in visible of the lines matching the search
+        let current = -1;   // Position in matches of the current match
+        let selected = -1;  // Index of the line shown in the detail pane
+        let query = null;
+
+        // Tag toggles
+        const tagsDiv = document.getElementById('tags');
+        data.tags.forEach((tag, i) => {
+            const label = document.createElement('label');
+            label.style.color = tag.color;
+            const box = document.createElement('input');
+            box.type = 'checkbox';
+            box.checked = true;
+            box.onchange = () => {
+                box.checked ? shown.add(i) : shown.delete(i);
+                update(true);
+            };
+            label.append(box, ' ' + tag.name);
+            tagsDiv.append(label);
+        });
+
+        // compileQuery returns the search as a global regex, or null without a search
+        function compileQuery() {
+            const error = document.getElementById('error');
+            error.textContent = '';
+            if (search.value === '') {
+                return null;
+            }
+            const source = regexBox.checked ? search.value : search.value.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+            try {
+                return new RegExp(source, 'gi');
+            } catch (e) {
+                error.textContent = 'Invalid regex: ' + e.message;
+                return null;
+            }
+        }
+
+        function matchesQuery(text) {
+            query.lastIndex = 0;
+            return query.test(text);
+        }
+
+        // update recomputes the shown lines after a change of the tags or the search, keeping
+        // the line at the top of the view in place
+        function update(keepPosition) {
+            const top = visible[Math.floor(logDiv.scrollTop / rowHeight)];
+            query = compileQuery();
+            visible = [];
+            matches = [];
+            for (let i = 0; i < lines.length; i++) {
+                if (!shown.has(lines[i][1])) {
+                    continue;
+                }
+                const match = query !== null && matchesQuery(lines[i][3]);
+                if (onlyBox.checked && query !== null && !match) {
+                    continue;
+                }
+                if (match) {
+                    matches.push(visible.length);
+                }
+                visible.push(i);
+            }
+            current = -1;
+            spacer.style.height = (visible.length * rowHeight) + 'px';
+            document.getElementById('count').textContent = visible.length + ' of ' + lines.length + ' lines';
+            showMatches();
+            if (keepPosition && top !== undefined) {
+                scrollToTime(times[top]);
+            }
+            render();
+        }
+
+        function showMatches() {
+            const text = query === null ? '' : matches.length === 0 ? 'No matches' :
+                (current >= 0 ? (current + 1) + ' of ' : '') + matches.length + ' matches';
+            document.getElementById('matches').textContent = text;
+        }
+
+        // goToMatch moves to the next (1) or previous (-1) match from the current one, or from
+        // the top of the view
+        function goToMatch(step) {
+            if (matches.length === 0) {
+                return;
+            }
+            if (current < 0) {
+                const top = Math.floor(logDiv.scrollTop / rowHeight);
+                const after = matches.findIndex(k => k >= top);
+                if (step > 0) {
+                    current = Math.max(after, 0);
+                } else {
+                    current = (after <= 0 ? matches.length : after) - 1;
+                }
+            } else {
+                current = (current + step + matches.length) % matches.length;
+            }
+            const k = matches[current];
+            logDiv.scrollTop = Math.max(0, k * rowHeight - logDiv.clientHeight / 3);
+            select(visible[k]);
+            showMatches();
+            render();
+        }
+
+        // appendText appends the text to the element with the search matches highlighted
+        function appendText(element, text) {
+            if (query === null) {
+                element.append(text);
+                return;
+            }
+            let from = 0;
+            query.lastIndex = 0;
+            for (let m; (m = query.exec(text)) !== null && m[0] !== '';) {
+                element.append(text.slice(from, m.index));
+                const mark = document.createElement('mark');
+                mark.textContent = m[0];
+                element.append(mark);
+                from = m.index + m[0].length;
+            }
+            element.append(text.slice(from));
+        }
+
+        // render draws the rows in and around the view
+        let rendering = false;
+        function render() {
+            rendering = false;
+            const first = Math.max(0, Math.floor(logDiv.scrollTop / rowHeight) - 20);
+            const end = Math.min(visible.length, Math.ceil((logDiv.scrollTop + logDiv.clientHeight) / rowHeight) + 20);
+            const currentRow = current >= 0 ? matches[current] : -1;
+            const rows = [];
+            for (let k = first; k < end; k++) {
+                const i = visible[k];
+                const [offset, tag, ts, text, file] = lines[i];
+                const row = document.createElement('div');
+                row.className = 'row' + (i === selected ? ' selected' : '') + (k === currentRow ? ' current' : '');
+                row.style.top = (k * rowHeight) + 'px';
+                row.title = file;
+                row.onclick = () => { select(i); render(); };
+                const cell = (cls, content) => {
+                    const span = document.createElement('span');
+                    span.className = cls;
+                    span.textContent = content;
+                    row.append(span);
+                    return span;
+                };
+                cell('num', i + 1);
+                cell('time', ts);
+                cell('tag', data.tags[tag].name).style.color = data.tags[tag].color;
+                const newline = text.indexOf('\n');
+                appendText(cell('text', ''), newline < 0 ? text : text.slice(0, newline));
+                if (newline >= 0) {
+                    cell('more', '(+' + (text.split('\n').length - 1) + ' lines)');
+                }
+                rows.push(row);
+            }
+            spacer.replaceChildren(...rows);
+            followView();
+        }
+
+        function select(i) {
+            selected = i;
+            const [offset, tag, ts, text, file] = lines[i];
+            detail.textContent = file + (ts ? '  ' + ts : '') + '\n' + text;
+        }
+
+        // scrollToTime scrolls the log to the first shown line at or after a time
+        function scrollToTime(t) {
+            let low = 0, high = visible.length;
+            while (low < high) {
+                const mid = (low + high) >> 1;
+                if (times[visible[mid]] < t) {
+                    low = mid + 1;
+                } else {
+                    high = mid;
+                }
+            }
+            logDiv.scrollTop = low * rowHeight;
+        }
+
+        // followView moves the slider and the plot cursor to the time of the top line
+        function followView() {
+            const top = visible[Math.min(visible.length - 1, Math.floor(logDiv.scrollTop / rowHeight))];
+            if (top === undefined) {
+                position.textContent = '';
+                return;
+            }
+            const t = times[top];
+            if (document.activeElement !== slider) {
+                slider.value = t;
+            }
+            position.textContent = (lines[top][2] || '') + ' (+' + t.toFixed(3) + ' s)';
+            moveCursor(t);
+        }
+
+        logDiv.addEventListener('scroll', () => {
+            if (!rendering) {
+                rendering = true;
+                requestAnimationFrame(render);
+            }
+        });
+        window.addEventListener('resize', render);
+        slider.addEventListener('input', () => scrollToTime(parseFloat(slider.value)));
+        search.addEventListener('input', () => update(false));
+        search.addEventListener('keydown', e => {
+            if (e.key === 'Enter') {
+                goToMatch(e.shiftKey ? -1 : 1);
+            }
+        });
+        regexBox.onchange = () => update(false);
+        onlyBox.onchange = () => update(true);
+        document.getElementById('prev').onclick = () => goToMatch(-1);
+        document.getElementById('next').onclick = () => goToMatch(1);
+
+        // Plot of the config patterns, against the log time, with a cursor at the log position
+        let moveCursor = () => {};
+        if (data.plot) {
+            const plotDiv = document.getElementById('plot');
+            const pageStyle = getComputedStyle(document.body);
+            const themeColor = name => pageStyle.getPropertyValue(name).trim();
+            const shift = x => x.map(v => v + data.plot_offset);
+            const traces = [];
+            const shapes = [{ type: 'line', xref: 'x', yref: 'paper', x0: 0, x1: 0, y0: 0, y1: 1,
+                line: { color: themeColor('--muted'), width: 1.5 } }];
+            const layout = {
+                paper_bgcolor: themeColor('--panel-bg'),
+                plot_bgcolor: themeColor('--panel-bg'),
+                font: { color: themeColor('--text'), size: 11 },
+                margin: { l: 60, r: 60, t: 10, b: 35 },
+                showlegend: true,
+                legend: { orientation: 'h', y: 1, yanchor: 'bottom' },
+                hovermode: 'closest',
+                xaxis: { title: 'Time since ' + data.start_time + ' (s)', range: [0, duration], gridcolor: themeColor('--plot-grid') },
+                yaxis: { title: data.plot.yaxis_label, gridcolor: themeColor('--plot-grid') }
+            };
+            const axes = data.plot.axes || [];
+            data.plot.series.forEach(s => {
+                if (s.type === 'event') {
+                    s.x.forEach(x => shapes.push({ type: 'line', xref: 'x', yref: 'paper', x0: x + data.plot_offset,
+                        x1: x + data.plot_offset, y0: 0, y1: 1, line: { color: s.color || '#999', width: 1, dash: 'dot' } }));
+                    return;
+                }
+                const idx = s.yaxis_index || 0;
+                if (idx > 0 && !layout['yaxis' + (idx + 1)]) {
+                    layout['yaxis' + (idx + 1)] = { title: axes[idx] ? axes[idx].label : '', overlaying: 'y',
+                        side: (axes[idx] && axes[idx].side) || 'right', showgrid: false };
+                }
+                traces.push({
+                    x: shift(s.x),
+                    y: s.y,
+                    name: s.name,
+                    yaxis: idx > 0 ? 'y' + (idx + 1) : 'y',
+                    type: 'scatter',
+                    mode: s.mode || 'lines',
+                    line: { color: s.color, shape: s.step ? 'hv' : 'linear', width: 1.5 },
+                    marker: { color: s.color, size: 4 },
+                    hovertemplate: '<b>%{fullData.name}</b><br>%{x:.6f} s: %{y}' + (s.unit ? ' ' + s.unit : '') + '<extra></extra>'
+                });
+            });
+            layout.shapes = shapes;
+            Plotly.newPlot(plotDiv, traces, layout, { responsive: true });
+            // Clicking the plot moves the log to the clicked time
+            plotDiv.on('plotly_click', e => {
+                if (e.points.length > 0) {
+                    scrollToTime(e.points[0].x);
+                }
+            });
+            let cursor = null;
+            moveCursor = t => {
+                if (cursor === null) {
+                    requestAnimationFrame(() => {
+                        Plotly.relayout(plotDiv, { 'shapes[0].x0': cursor, 'shapes[0].x1': cursor });
+                        cursor = null;
+                    });
+                }
+                cursor = t;
+            };
+        }
+
+        update(false);
+    </script>
+</body>
+</html>
+`
@@ -0,0 +1,95 @@
+package visualizer
+
+import (
+	"context"
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/plugin"
+	"log-interleaver/pkg/pattern"
+)
+
+// ExtractMetrics extracts the metric series defined by the config patterns from log lines
+func ExtractMetrics(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig) (map[string][]pattern.MetricPoint, error) {
+	metrics, _, err := ExtractMetricsWithStats(ctx, lines, cfg)
+	return metrics, err
+}
+
+// ExtractMetricsWithStats extracts the metric series and also returns per-series extraction statistics
+func ExtractMetricsWithStats(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig) (map[string][]pattern.MetricPoint, map[string]pattern.ExtractionStats, error) {
+	matcher, err := NewPatternMatcher(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Extract metrics
+	metrics, err := matcher.ExtractMetrics(ctx, lines)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extract metrics: %w", err)
+	}
+
+	return metrics, matcher.Stats(), nil
+}
+
+// NewPatternMatcher creates a pattern matcher for the patterns of the config, with their plugins
+// and built-in extractors
+func NewPatternMatcher(cfg *config.VisualizationConfig) (*pattern.PatternMatcher, error) {
+	// Convert config patterns to pattern matcher format
+	patternConfigs := make([]pattern.PatternConfig, len(cfg.Patterns))
+	plugins := plugin.FromConfig(cfg)
+	for i, p := range cfg.Patterns {
+		patternConfigs[i] = pattern.PatternConfig{
+			Name:         p.Name,
+			Regex:        p.Regex,
+			TagFilter:    p.TagFilter,
+			Field:        p.Field,
+			ValueGroup:   p.ValueGroup,
+			StateGroup:   p.StateGroup,
+			StateMapping: p.StateMapping,
+			Color:        p.Color,
+			LineStyle:    p.LineStyle,
+			Marker:       p.Marker,
+			YAxisLabel:   p.YAxisLabel,
+			YAxisIndex:   p.YAxisIndex,
+			Dedup:        p.Dedup,
+			Transform:    p.Transform,
+			MinValue:     p.MinValue,
+			MaxValue:     p.MaxValue,
+			OutlierSigma: p.OutlierSigma,
+			Clamp:        p.Clamp,
+			Unit:         p.Unit,
+			DisplayUnit:  p.DisplayUnit,
+			Rolling:      p.RollingStat,
+			Event:        p.IsEvent(),
+			LabelGroup:   p.LabelGroup,
+			LaneGroup:    p.LaneGroup,
+		}
+		if p.Plugin != "" {
+			extractor, ok := plugins[p.Plugin]
+			if !ok {
+				return nil, fmt.Errorf("pattern '%s': unknown plugin '%s'", p.Name, p.Plugin)
+			}
+			patternConfigs[i].Extractor = extractor
+			patternConfigs[i].ExtractorSeries = p.PluginSeries
+		}
+		if p.Extractor != "" {
+			extractor, ok := pattern.BuiltinExtractor(p.Extractor)
+			if !ok {
+				return nil, fmt.Errorf("pattern '%s': unknown extractor '%s'", p.Name, p.Extractor)
+			}
+			patternConfigs[i].Extractor = extractor
+			patternConfigs[i].ExtractorSeries = p.ExtractorSeries
+		}
+		if p.Rolling != nil {
+			patternConfigs[i].RollingWindow = p.Rolling.Window
+			patternConfigs[i].RollingSigma = p.Rolling.Sigma
+		}
+	}
+
+	// Create pattern matcher
+	matcher, err := pattern.NewPatternMatcher(patternConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pattern matcher: %w", err)
+	}
+	return matcher, nil
+}
@@ -0,0 +1,61 @@
+package visualizer
+
+import (
+	"fmt"
+	"log-interleaver/pkg/report"
+
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// GenerateMTIEPlot draws the MTIE curves of the series on log-log axes, with the masks they were
+// checked against as dashed lines. The format is given by the output extension when empty.
+func GenerateMTIEPlot(stats []report.MTIEStats, outputPath, format string) error {
+	p := newLogLogPanel("MTIE (ns)")
+	p.Title.Text = "MTIE (dTE_L)"
+	p.X.Label.Text = "Tau (s)"
+
+	points := 0
+	masks := make(map[string]bool)
+	for i, s := range stats {
+		xys := make(plotter.XYs, len(s.Points))
+		for j, pt := range s.Points {
+			xys[j] = plotter.XY{X: pt.TauSeconds, Y: pt.MTIENs}
+		}
+		points += len(s.Points)
+		if err := addLogLogLine(p, s.Series, xys, seriesColors[i%len(seriesColors)], nil); err != nil {
+			return err
+		}
+
+		// Masks are shared by the series; draw each once
+		for _, mask := range s.Masks {
+			if masks[mask.Mask] {
+				continue
+			}
+			masks[mask.Mask] = true
+			limits := make(plotter.XYs, len(mask.Limits))
+			for j, limit := range mask.Limits {
+				limits[j] = plotter.XY{X: limit.TauSeconds, Y: limit.LimitNs}
+			}
+			line, err := plotter.NewLine(limits)
+			if err != nil {
+				return fmt.Errorf("failed to create mask line: %w", err)
+			}
+			line.Color = seriesColors[len(seriesColors)-1-(len(masks)-1)%len(seriesColors)]
+			line.Width = vg.Points(1.5)
+			line.Dashes = []vg.Length{vg.Points(6), vg.Points(3)}
+			p.Add(line)
+			p.Legend.Add("mask "+mask.Mask, line)
+		}
+	}
+	if points == 0 {
+		return fmt.Errorf("no MTIE data to plot")
+	}
+
+	// Keep the masks off the frame
+	p.Y.Min /= 1.25
+	p.Y.Max *= 1.25
+
+	return savePlot(func(dc draw.Canvas) { p.Draw(dc) }, 8*vg.Inch, 6*vg.Inch, 0, format, outputPath)
+}
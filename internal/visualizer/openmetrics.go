@@ -0,0 +1,228 @@
+package visualizer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"log-interleaver/pkg/pattern"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricFamily is a Prometheus metric family: the series of the patterns sharing a metric name
+type metricFamily struct {
+	name   string
+	typ    string   // "gauge", or "counter" for event patterns
+	help   []string // Names of the patterns of the family
+	series []metricSeries
+}
+
+// metricSeries is one labeled time series of a metric family
+type metricSeries struct {
+	labels  []metricLabel // Sorted by name
+	samples []metricSample
+}
+
+type metricLabel struct {
+	name, value string
+}
+
+type metricSample struct {
+	time  time.Time
+	value float64
+}
+
+// invalidMetricChars matches runs of characters that are not allowed in metric names
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// metricName returns the Prometheus metric name of a pattern: its metric_name, or the pattern
+// name in snake case. Event patterns count their matches, so their names end in _total.
+func metricName(p *config.PatternConfig) string {
+	name := p.MetricName
+	if name == "" {
+		name = strings.Trim(invalidMetricChars.ReplaceAllString(strings.ToLower(p.Name), "_"), "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "series_" + name
+		}
+	}
+	if p.IsEvent() && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return name
+}
+
+// validMetricName matches valid Prometheus metric names
+var validMetricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// validLabelName matches valid Prometheus label names
+var validLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// buildMetricFamilies converts the extracted series to metric families, labeled with the
+// series name, the tag filter of the pattern, and the extra labels. Samples at identical
+// timestamps keep the last value, as Prometheus stores one sample per timestamp. Derived
+// rolling series are left out (Prometheus computes them with avg_over_time and stddev_over_time).
+func buildMetricFamilies(metrics map[string][]pattern.MetricPoint, cfg *config.VisualizationConfig, extraLabels map[string]string) ([]*metricFamily, error) {
+	for name := range extraLabels {
+		if !validLabelName.MatchString(name) || name == "series" || name == "tag" {
+			return nil, fmt.Errorf("invalid metric label '%s', expected a label name other than series and tag", name)
+		}
+	}
+
+	families := make(map[string]*metricFamily)
+	var order []string
+	for i := range cfg.Patterns {
+		p := &cfg.Patterns[i]
+		points := metrics[p.Name]
+		if p.RollingStat != "" || len(points) == 0 {
+			continue
+		}
+
+		name := metricName(p)
+		if !validMetricName.MatchString(name) {
+			return nil, fmt.Errorf("pattern '%s': invalid metric name '%s'", p.Name, name)
+		}
+		typ := "gauge"
+		if p.IsEvent() {
+			typ = "counter"
+		}
+		family, ok := families[name]
+		if !ok {
+			family = &metricFamily{name: name, typ: typ}
+			families[name] = family
+			order = append(order, name)
+		} else if family.typ != typ {
+			return nil, fmt.Errorf("pattern '%s': metric '%s' is shared by metric and event patterns", p.Name, name)
+		}
+		family.help = append(family.help, p.Name)
+
+		labels := []metricLabel{{name: "series", value: p.Name}}
+		if p.TagFilter != "" {
+			labels = append(labels, metricLabel{name: "tag", value: p.TagFilter})
+		}
+		for name, value := range extraLabels {
+			labels = append(labels, metricLabel{name: name, value: value})
+		}
+		sort.Slice(labels, func(a, b int) bool { return labels[a].name < labels[b].name })
+
+		sorted := make([]pattern.MetricPoint, len(points))
+		copy(sorted, points)
+		sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].Time.Before(sorted[b].Time) })
+		var samples []metricSample
+		for n, pt := range sorted {
+			value := pt.Value
+			if p.IsEvent() {
+				value = float64(n + 1)
+			}
+			// Timestamps have millisecond resolution in Prometheus
+			t := pt.Time.Truncate(time.Millisecond)
+			if k := len(samples); k > 0 && samples[k-1].time.Equal(t) {
+				samples[k-1].value = value
+				continue
+			}
+			samples = append(samples, metricSample{time: t, value: value})
+		}
+		family.series = append(family.series, metricSeries{labels: labels, samples: samples})
+	}
+
+	result := make([]*metricFamily, 0, len(order))
+	for _, name := range order {
+		result = append(result, families[name])
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no metric points found in data")
+	}
+	return result, nil
+}
+
+// ParseMetricLabels parses comma-separated name=value label pairs (e.g., host=node1,run=42)
+func ParseMetricLabels(list string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid metric label '%s', expected name=value", pair)
+		}
+		labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// ExportOpenMetrics exports the extracted series as OpenMetrics text with sample timestamps,
+// which can be backfilled into Prometheus with "promtool tsdb create-blocks-from openmetrics"
+func ExportOpenMetrics(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath string, labels map[string]string) error {
+	families, err := loadMetricFamilies(ctx, lines, configPath, configOpts, labels)
+	if err != nil {
+		return err
+	}
+
+	out, err := sink.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create OpenMetrics file: %w", err)
+	}
+	defer out.Close()
+
+	if err := writeOpenMetrics(out, families); err != nil {
+		return fmt.Errorf("failed to write OpenMetrics: %w", err)
+	}
+	return out.Close()
+}
+
+// writeOpenMetrics writes metric families in the OpenMetrics text format, with timestamps
+func writeOpenMetrics(w io.Writer, families []*metricFamily) error {
+	bw := bufio.NewWriter(w)
+	for _, family := range families {
+		// OpenMetrics names counter families without the _total suffix of their samples
+		familyName := family.name
+		if family.typ == "counter" {
+			familyName = strings.TrimSuffix(familyName, "_total")
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", familyName, family.typ)
+		fmt.Fprintf(bw, "# HELP %s %s\n", familyName, escapeLabelValue(strings.Join(family.help, ", ")))
+		for _, series := range family.series {
+			labels := formatLabels(series.labels)
+			for _, s := range series.samples {
+				fmt.Fprintf(bw, "%s%s %s %s\n", family.name, labels, formatSampleValue(s.value),
+					strconv.FormatFloat(float64(s.time.UnixMilli())/1000, 'f', 3, 64))
+			}
+		}
+	}
+	fmt.Fprintf(bw, "# EOF\n")
+	return bw.Flush()
+}
+
+// formatLabels formats a label set as {name="value",...}
+func formatLabels(labels []metricLabel) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.name + `="` + escapeLabelValue(l.value) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatSampleValue formats a sample value in the exposition formats
+func formatSampleValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabelValue escapes backslashes, quotes, and newlines in label values
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp escapes backslashes and newlines in help texts of the Prometheus text format
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
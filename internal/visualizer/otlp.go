@@ -0,0 +1,39 @@
+package visualizer
+
+import (
+	"context"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/otlp"
+	"log-interleaver/internal/parser"
+	"strings"
+)
+
+// ExportOTLPMetrics sends the extracted series as OTel metrics: gauges, and counters of the
+// event patterns, with the series and tag attributes of the Prometheus exports
+func ExportOTLPMetrics(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, exporter *otlp.Exporter) error {
+	families, err := loadMetricFamilies(ctx, lines, configPath, configOpts, nil)
+	if err != nil {
+		return err
+	}
+
+	var metrics []otlp.Metric
+	for _, family := range families {
+		m := otlp.Metric{Name: family.name, Description: strings.Join(family.help, ", "), Counter: family.typ == "counter"}
+		if m.Counter {
+			// OTel counters have no _total suffix, which Prometheus adds back
+			m.Name = strings.TrimSuffix(m.Name, "_total")
+		}
+		for _, series := range family.series {
+			s := otlp.Series{Attributes: make(map[string]string)}
+			for _, l := range series.labels {
+				s.Attributes[l.name] = l.value
+			}
+			for _, sample := range series.samples {
+				s.Points = append(s.Points, otlp.Point{Time: sample.time, Value: sample.value})
+			}
+			m.Series = append(m.Series, s)
+		}
+		metrics = append(metrics, m)
+	}
+	return exporter.ExportMetrics(ctx, metrics)
+}
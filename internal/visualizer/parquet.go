@@ -0,0 +1,109 @@
+package visualizer
+
+import (
+	"context"
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parquet"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"slices"
+	"sort"
+	"time"
+)
+
+// parquetRowGroup is the number of rows per Parquet row group
+const parquetRowGroup = 1 << 20
+
+// parquetColumns are the columns of the Parquet export, one row per metric point
+var parquetColumns = []parquet.Column{
+	{Name: "series", Kind: parquet.String},
+	{Name: "time", Kind: parquet.Timestamp},
+	{Name: "offset_seconds", Kind: parquet.Double},
+	{Name: "value", Kind: parquet.Double, Optional: true}, // Null for events
+	{Name: "state", Kind: parquet.String, Optional: true}, // State string, or the label detail of events
+	{Name: "tag", Kind: parquet.String, Optional: true},   // Tag filter of the pattern
+}
+
+// ExportParquet exports the metric points in long format (one row per point, sorted by series
+// name and time) to a Parquet file, which stays compact for high-frequency series
+func ExportParquet(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(configPath, configOpts)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	metrics, err := ExtractMetrics(ctx, lines, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Find earliest timestamp to use as reference
+	var earliestTime time.Time
+	for _, points := range metrics {
+		for _, pt := range points {
+			if earliestTime.IsZero() || pt.Time.Before(earliestTime) {
+				earliestTime = pt.Time
+			}
+		}
+	}
+	if earliestTime.IsZero() {
+		return fmt.Errorf("no timestamps found in data")
+	}
+
+	out, err := sink.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create Parquet file: %w", err)
+	}
+	defer out.Close()
+
+	pw, err := parquet.NewWriter(out, parquetColumns)
+	if err != nil {
+		return fmt.Errorf("failed to write Parquet: %w", err)
+	}
+
+	values := make([]parquet.Values, len(parquetColumns))
+	rows := 0
+	flush := func() error {
+		if rows == 0 {
+			return nil
+		}
+		if err := pw.WriteRowGroup(rows, values); err != nil {
+			return fmt.Errorf("failed to write Parquet: %w", err)
+		}
+		values = make([]parquet.Values, len(parquetColumns))
+		rows = 0
+		return nil
+	}
+
+	patterns := slices.Clone(cfg.Patterns)
+	sort.SliceStable(patterns, func(i, j int) bool { return patterns[i].Name < patterns[j].Name })
+	for _, pattern := range patterns {
+		points := metrics[pattern.Name]
+		sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+		for _, pt := range points {
+			values[0].Strings = append(values[0].Strings, pattern.Name)
+			values[1].Int64s = append(values[1].Int64s, pt.Time.UnixNano())
+			values[2].Doubles = append(values[2].Doubles, pt.Time.Sub(earliestTime).Seconds())
+			values[3].Doubles = append(values[3].Doubles, pt.Value)
+			values[3].Nulls = append(values[3].Nulls, pattern.IsEvent())
+			values[4].Strings = append(values[4].Strings, pt.State)
+			values[4].Nulls = append(values[4].Nulls, pt.State == "")
+			values[5].Strings = append(values[5].Strings, pattern.TagFilter)
+			values[5].Nulls = append(values[5].Nulls, pattern.TagFilter == "")
+			if rows++; rows == parquetRowGroup {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if err := pw.Close(); err != nil {
+		return fmt.Errorf("failed to write Parquet: %w", err)
+	}
+	return out.Close()
+}
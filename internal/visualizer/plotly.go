@@ -0,0 +1,80 @@
+package visualizer
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+)
+
+//go:generate curl -sSfL -o assets/plotly-2.27.0.min.js https://cdn.plot.ly/plotly-2.27.0.min.js
+
+// PlotlyVersion is the Plotly.js version used by the interactive HTML export
+const PlotlyVersion = "2.27.0"
+
+// plotlyCDN is the CDN URL of the Plotly.js bundle
+const plotlyCDN = "https://cdn.plot.ly/plotly-" + PlotlyVersion + ".min.js"
+
+//go:embed assets
+var assets embed.FS
+
+// Plotly.js source modes for the interactive HTML export
+const (
+	PlotlyAuto  = "auto"  // Inline the bundle of -plotly-js or the embedded one; load it from the CDN with a warning if neither is available
+	PlotlyEmbed = "embed" // Inline the bundle; fail if it is not available
+	PlotlyCDN   = "cdn"   // Load the bundle from the CDN
+)
+
+// Color themes of the interactive HTML export
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+// HTMLOptions controls how the interactive HTML plot is generated
+type HTMLOptions struct {
+	PlotlyMode string // PlotlyAuto (default), PlotlyEmbed, or PlotlyCDN
+	PlotlyJS   string // Optional path of a local Plotly.js bundle to inline instead of the embedded one
+	Theme      string // Optional ThemeLight or ThemeDark, overriding the config theme
+	CSS        string // Optional custom CSS appended after the built-in styles
+	LiveURL    string // Optional WebSocket path streaming new points into the plot (serve command)
+}
+
+// HasPlotlyBundle reports whether the Plotly.js bundle is embedded in this build
+func HasPlotlyBundle() bool {
+	_, err := assets.Open("assets/plotly-" + PlotlyVersion + ".min.js")
+	return err == nil
+}
+
+// plotlyScript returns the inline Plotly.js source, or "" when the page loads it from the CDN
+func plotlyScript(opts HTMLOptions) (template.JS, error) {
+	mode := opts.PlotlyMode
+	if mode == "" {
+		mode = PlotlyAuto
+	}
+	if mode != PlotlyAuto && mode != PlotlyEmbed && mode != PlotlyCDN {
+		return "", fmt.Errorf("invalid Plotly mode '%s', expected auto, embed, or cdn", mode)
+	}
+	if mode == PlotlyCDN {
+		return "", nil
+	}
+
+	var bundle []byte
+	var err error
+	if opts.PlotlyJS != "" {
+		if bundle, err = os.ReadFile(opts.PlotlyJS); err != nil {
+			return "", fmt.Errorf("failed to read Plotly bundle: %w", err)
+		}
+	} else if bundle, err = assets.ReadFile("assets/plotly-" + PlotlyVersion + ".min.js"); err != nil {
+		// Only embed promises a file working offline, auto keeps builds without the bundle usable
+		if mode == PlotlyEmbed {
+			return "", fmt.Errorf("plotly.js bundle is not embedded in this build (run go generate ./internal/visualizer before building, pass -plotly-js, or use -plotly cdn to load it from the CDN)")
+		}
+		logger.Warn("Plotly.js bundle is not embedded in this build, loading it from " + plotlyCDN + " (run go generate ./internal/visualizer before building or pass -plotly-js for files working offline)")
+		return "", nil
+	}
+
+	// Keep the bundle from closing the inline <script> element early
+	return template.JS(strings.ReplaceAll(string(bundle), "</script", `<\/script`)), nil
+}
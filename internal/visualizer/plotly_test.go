@@ -0,0 +1,43 @@
+package visualizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlotlyScript(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "plotly.min.js")
+	if err := os.WriteFile(bundle, []byte(`var s = "</script>";`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if HasPlotlyBundle() {
+		t.Skip("the Plotly.js bundle is embedded in this build")
+	}
+
+	tests := []struct {
+		name    string
+		opts    HTMLOptions
+		want    string
+		wantErr bool
+	}{
+		{name: "auto without a bundle loads the CDN", opts: HTMLOptions{}},
+		{name: "embed without a bundle", opts: HTMLOptions{PlotlyMode: PlotlyEmbed}, wantErr: true},
+		{name: "cdn", opts: HTMLOptions{PlotlyMode: PlotlyCDN, PlotlyJS: bundle}},
+		{name: "local bundle", opts: HTMLOptions{PlotlyJS: bundle}, want: `var s = "<\/script>";`},
+		{name: "embed local bundle", opts: HTMLOptions{PlotlyMode: PlotlyEmbed, PlotlyJS: bundle}, want: `var s = "<\/script>";`},
+		{name: "missing local bundle", opts: HTMLOptions{PlotlyJS: bundle + ".missing"}, wantErr: true},
+		{name: "invalid mode", opts: HTMLOptions{PlotlyMode: "inline"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plotlyScript(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("plotlyScript error = %v, want error %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("plotlyScript = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
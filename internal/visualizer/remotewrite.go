@@ -0,0 +1,191 @@
+package visualizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// remoteWriteBatch is the maximum number of samples per remote-write request
+const remoteWriteBatch = 5000
+
+// metricsClient sends the remote-write and Pushgateway requests
+var metricsClient = &http.Client{Timeout: time.Minute}
+
+// PushRemoteWrite sends the extracted series with their timestamps to a Prometheus remote-write
+// endpoint (Prometheus with --web.enable-remote-write-receiver, Mimir, Thanos, VictoriaMetrics, ...).
+// Samples are sent in batches, each series in time order. Endpoints only accept samples older
+// than their head block when out-of-order ingestion is enabled.
+func PushRemoteWrite(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, endpoint string, labels map[string]string) error {
+	families, err := loadMetricFamilies(ctx, lines, configPath, configOpts, labels)
+	if err != nil {
+		return err
+	}
+
+	var batch []remoteSeries
+	samples := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		body := snappyEncode(encodeWriteRequest(batch))
+		headers := map[string]string{
+			"Content-Type":                      "application/x-protobuf",
+			"Content-Encoding":                  "snappy",
+			"X-Prometheus-Remote-Write-Version": "0.1.0",
+		}
+		if err := sendMetrics(ctx, http.MethodPost, endpoint, headers, body); err != nil {
+			return err
+		}
+		batch, samples = nil, 0
+		return nil
+	}
+
+	for _, family := range families {
+		for _, series := range family.series {
+			labels := append([]metricLabel{{name: "__name__", value: family.name}}, series.labels...)
+			sort.Slice(labels, func(a, b int) bool { return labels[a].name < labels[b].name })
+			for rest := series.samples; len(rest) > 0; {
+				n := min(len(rest), remoteWriteBatch-samples)
+				batch = append(batch, remoteSeries{labels: labels, samples: rest[:n]})
+				samples += n
+				rest = rest[n:]
+				if samples == remoteWriteBatch {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return flush()
+}
+
+// PushGateway pushes the latest value of each extracted series to a Prometheus Pushgateway,
+// replacing the metrics of the job's group. The Pushgateway does not accept sample timestamps,
+// so only the last sample of each series is pushed (use remote write or OpenMetrics to backfill).
+func PushGateway(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, gateway, job string, labels map[string]string) error {
+	families, err := loadMetricFamilies(ctx, lines, configPath, configOpts, labels)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, family := range families {
+		fmt.Fprintf(&buf, "# HELP %s %s\n", family.name, escapeHelp(strings.Join(family.help, ", ")))
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", family.name, family.typ)
+		for _, series := range family.series {
+			last := series.samples[len(series.samples)-1]
+			fmt.Fprintf(&buf, "%s%s %s\n", family.name, formatLabels(series.labels), formatSampleValue(last.value))
+		}
+	}
+
+	endpoint := strings.TrimSuffix(gateway, "/") + "/metrics/job/" + url.PathEscape(job)
+	headers := map[string]string{"Content-Type": "text/plain; version=0.0.4"}
+	return sendMetrics(ctx, http.MethodPut, endpoint, headers, buf.Bytes())
+}
+
+// loadMetricFamilies loads the config and converts the series extracted from the lines to
+// metric families
+func loadMetricFamilies(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, labels map[string]string) ([]*metricFamily, error) {
+	cfg, err := config.LoadConfig(configPath, configOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	metrics, err := ExtractMetrics(ctx, lines, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return buildMetricFamilies(metrics, cfg, labels)
+}
+
+// sendMetrics sends a request and converts non-2xx responses to errors
+func sendMetrics(ctx context.Context, method, endpoint string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := metricsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// remoteSeries is a time series of a remote-write request, with its labels including __name__
+type remoteSeries struct {
+	labels  []metricLabel
+	samples []metricSample
+}
+
+// encodeWriteRequest encodes a Prometheus remote-write WriteRequest protobuf message:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }  // Timestamp in milliseconds
+func encodeWriteRequest(series []remoteSeries) []byte {
+	var req []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var label []byte
+			label = appendBytesField(label, 1, []byte(l.name))
+			label = appendBytesField(label, 2, []byte(l.value))
+			ts = appendBytesField(ts, 1, label)
+		}
+		for _, sample := range s.samples {
+			var pb []byte
+			pb = binary.AppendUvarint(pb, 1<<3|1) // Field 1, 64-bit
+			pb = binary.LittleEndian.AppendUint64(pb, math.Float64bits(sample.value))
+			pb = binary.AppendUvarint(pb, 2<<3|0) // Field 2, varint
+			pb = binary.AppendUvarint(pb, uint64(sample.time.UnixMilli()))
+			ts = appendBytesField(ts, 2, pb)
+		}
+		req = appendBytesField(req, 1, ts)
+	}
+	return req
+}
+
+// appendBytesField appends a length-delimited protobuf field
+func appendBytesField(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// snappyEncode encodes data in the snappy block format required by remote write. The data is
+// stored as literals only: valid for every snappy decoder, though not compressed.
+func snappyEncode(data []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(data)))
+	for len(data) > 0 {
+		n := min(len(data), 65536)
+		// Literal tag: the length minus one, inline below 60 or in the following 2 bytes
+		if n <= 60 {
+			out = append(out, byte(n-1)<<2)
+		} else {
+			out = append(out, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		out = append(out, data[:n]...)
+		data = data[n:]
+	}
+	return out
+}
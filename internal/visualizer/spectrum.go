@@ -0,0 +1,71 @@
+package visualizer
+
+import (
+	"fmt"
+	"log-interleaver/pkg/report"
+
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// GenerateSpectrumPlot draws the power spectral densities of the series on log-log axes, with
+// their peaks marked. The format is given by the output extension when empty.
+func GenerateSpectrumPlot(stats []report.SpectrumStats, outputPath, format string) error {
+	// Label the axis with the unit when the series share it
+	unit := "unit"
+	for i, s := range stats {
+		if i == 0 {
+			unit = s.Unit
+		} else if s.Unit != unit {
+			unit = "unit"
+		}
+	}
+	p := newLogLogPanel(fmt.Sprintf("PSD (%s²/Hz)", unit))
+	p.Title.Text = "Power Spectral Density"
+	p.X.Label.Text = "Frequency (Hz)"
+
+	points := 0
+	for i, s := range stats {
+		// The DC bin has no place on a logarithmic frequency axis
+		var xys plotter.XYs
+		for _, pt := range s.Points {
+			if pt.FrequencyHz > 0 && pt.PSD > 0 {
+				xys = append(xys, plotter.XY{X: pt.FrequencyHz, Y: pt.PSD})
+			}
+		}
+		if len(xys) == 0 {
+			continue
+		}
+		points += len(xys)
+		c := seriesColors[i%len(seriesColors)]
+		line, err := plotter.NewLine(xys)
+		if err != nil {
+			return fmt.Errorf("failed to create line: %w", err)
+		}
+		line.Color = c
+		p.Add(line)
+		p.Legend.Add(s.Series, line)
+
+		if len(s.Peaks) == 0 {
+			continue
+		}
+		peaks := make(plotter.XYs, len(s.Peaks))
+		for j, peak := range s.Peaks {
+			peaks[j] = plotter.XY{X: peak.FrequencyHz, Y: peak.PSD}
+		}
+		scatter, err := plotter.NewScatter(peaks)
+		if err != nil {
+			return fmt.Errorf("failed to create peak markers: %w", err)
+		}
+		scatter.Color = c
+		scatter.Shape = draw.CircleGlyph{}
+		scatter.Radius = vg.Points(3)
+		p.Add(scatter)
+	}
+	if points == 0 {
+		return fmt.Errorf("no spectrum data to plot")
+	}
+
+	return savePlot(func(dc draw.Canvas) { p.Draw(dc) }, 8*vg.Inch, 6*vg.Inch, 0, format, outputPath)
+}
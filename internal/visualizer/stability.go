@@ -0,0 +1,128 @@
+package visualizer
+
+import (
+	"fmt"
+	"image/color"
+	"log-interleaver/pkg/report"
+	"math"
+	"strconv"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// GenerateStabilityPlot draws the stability of the series on log-log axes: ADEV and MDEV in
+// the top panel and TDEV in the bottom panel, sharing the tau axis. The format is given by
+// the output extension when empty.
+func GenerateStabilityPlot(stats []report.StabilityStats, outputPath, format string) error {
+	frequency := newLogLogPanel("ADEV / MDEV")
+	phase := newLogLogPanel("TDEV")
+	phase.X.Label.Text = "Tau (s)"
+
+	points := 0
+	for i, s := range stats {
+		if len(s.Points) == 0 {
+			continue
+		}
+		c := seriesColors[i%len(seriesColors)]
+		adev := make(plotter.XYs, len(s.Points))
+		mdev := make(plotter.XYs, len(s.Points))
+		tdev := make(plotter.XYs, len(s.Points))
+		for j, pt := range s.Points {
+			adev[j] = plotter.XY{X: pt.TauSeconds, Y: pt.ADEV}
+			mdev[j] = plotter.XY{X: pt.TauSeconds, Y: pt.MDEV}
+			tdev[j] = plotter.XY{X: pt.TauSeconds, Y: pt.TDEV}
+		}
+		points += len(s.Points)
+
+		if err := addLogLogLine(frequency, s.Series+" ADEV", adev, c, nil); err != nil {
+			return err
+		}
+		if err := addLogLogLine(frequency, s.Series+" MDEV", mdev, c, []vg.Length{vg.Points(4), vg.Points(2)}); err != nil {
+			return err
+		}
+		if err := addLogLogLine(phase, fmt.Sprintf("%s TDEV (%s)", s.Series, s.Unit), tdev, c, nil); err != nil {
+			return err
+		}
+	}
+	if points == 0 {
+		return fmt.Errorf("no stability data to plot")
+	}
+
+	plots := stackPanels(map[int]*plot.Plot{0: frequency, 1: phase}, []int{0, 1})
+	frequency.Title.Text = "Stability"
+	render := func(dc draw.Canvas) { drawStacked(plots, dc) }
+	return savePlot(render, 8*vg.Inch, 8*vg.Inch, 0, format, outputPath)
+}
+
+// newLogLogPanel creates a plot with logarithmic axes
+func newLogLogPanel(yLabel string) *plot.Plot {
+	p := plot.New()
+	p.Y.Label.Text = yLabel
+	p.X.Scale = plot.LogScale{}
+	p.Y.Scale = plot.LogScale{}
+	p.X.Tick.Marker = logTicks{}
+	p.Y.Tick.Marker = logTicks{}
+	p.Add(plotter.NewGrid())
+	p.Legend.Top = true
+	return p
+}
+
+// addLogLogLine adds a curve with markers to a log-log panel, leaving out the non-positive values
+// that a logarithmic axis cannot show
+func addLogLogLine(p *plot.Plot, name string, xys plotter.XYs, c color.Color, dashes []vg.Length) error {
+	var positive plotter.XYs
+	for _, xy := range xys {
+		if xy.Y > 0 {
+			positive = append(positive, xy)
+		}
+	}
+	if len(positive) == 0 {
+		return nil
+	}
+	line, scatter, err := plotter.NewLinePoints(positive)
+	if err != nil {
+		return fmt.Errorf("failed to create line: %w", err)
+	}
+	line.Color = c
+	line.Dashes = dashes
+	scatter.Color = c
+	scatter.Radius = vg.Points(2)
+	p.Add(line, scatter)
+	p.Legend.Add(name, line, scatter)
+	return nil
+}
+
+// logTicks marks logarithmic axes at 1, 2, and 5 times the powers of ten, with minor ticks at
+// the other integer multiples, so ranges within a decade still get labels. Over more than
+// three decades only the powers of ten are labeled.
+type logTicks struct{}
+
+// Ticks implements plot.Ticker
+func (logTicks) Ticks(min, max float64) []plot.Tick {
+	if min <= 0 || max <= 0 {
+		return plot.LogTicks{Prec: -1}.Ticks(math.Max(min, 1e-300), math.Max(max, 1e-300))
+	}
+	labeled := map[int]bool{1: true, 2: true, 5: true}
+	if math.Log10(max/min) > 3 {
+		labeled = map[int]bool{1: true}
+	}
+	var ticks []plot.Tick
+	for exp := math.Floor(math.Log10(min)); exp <= math.Ceil(math.Log10(max)); exp++ {
+		decade := math.Pow(10, exp)
+		for m := 1; m < 10; m++ {
+			v := float64(m) * decade
+			if v < min*(1-1e-9) || v > max*(1+1e-9) {
+				continue
+			}
+			tick := plot.Tick{Value: v}
+			if labeled[m] {
+				tick.Label = strconv.FormatFloat(v, 'g', 6, 64)
+			}
+			ticks = append(ticks, tick)
+		}
+	}
+	return ticks
+}
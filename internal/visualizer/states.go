@@ -0,0 +1,293 @@
+package visualizer
+
+import (
+	"fmt"
+	"image/color"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/config"
+	"log-interleaver/pkg/pattern"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// laneRowHeight is the height of one series in the state timeline lane
+const laneRowHeight = 0.3 * vg.Inch
+
+// laneRow is the state timeline of one state-mapped series, or of one lane of a lane pattern, in
+// the lane
+type laneRow struct {
+	name     string
+	segments []analysis.StateSegment
+	colors   []color.Color // Color of each segment
+}
+
+// stateLanes returns the lane rows of the state-mapped series when the config enables the state
+// timeline, followed by those of the lane patterns (e.g., the port states), or nil
+func stateLanes(cfg *config.VisualizationConfig, metrics map[string][]pattern.MetricPoint) []laneRow {
+	palette := newStatePalette()
+	var rows []laneRow
+	for _, p := range cfg.Patterns {
+		if p.IsLane() {
+			rows = append(rows, eventLanes(p, metrics, palette)...)
+			continue
+		}
+		if !cfg.StateTimeline || !p.HasStates() {
+			continue
+		}
+		segments := analysis.StateSegments(metrics[p.Name])
+		if len(segments) == 0 {
+			continue
+		}
+		row := laneRow{name: p.Name, segments: segments}
+		for _, seg := range segments {
+			row.colors = append(row.colors, palette.color(seg.State, p.LockedStates))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// eventLanes returns a lane row per lane of the events of a lane pattern, sorted by lane. Events
+// only mark the transitions, so the last state of each lane lasts until the end of the capture.
+func eventLanes(p config.PatternConfig, metrics map[string][]pattern.MetricPoint, palette *statePalette) []laneRow {
+	var end time.Time
+	for _, points := range metrics {
+		for _, pt := range points {
+			if pt.Time.After(end) {
+				end = pt.Time
+			}
+		}
+	}
+	byLane := make(map[string][]pattern.MetricPoint)
+	for _, pt := range metrics[p.Name] {
+		byLane[pt.Lane] = append(byLane[pt.Lane], pt)
+	}
+	lanes := make([]string, 0, len(byLane))
+	for lane := range byLane {
+		lanes = append(lanes, lane)
+	}
+	sort.Strings(lanes)
+
+	var rows []laneRow
+	for _, lane := range lanes {
+		segments := analysis.StateSegments(byLane[lane])
+		if len(segments) == 0 {
+			continue
+		}
+		segments[len(segments)-1].End = end
+		row := laneRow{name: p.Name + ": " + lane, segments: segments}
+		if lane == "" {
+			row.name = p.Name
+		}
+		for _, seg := range segments {
+			row.colors = append(row.colors, palette.color(seg.State, p.LockedStates))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// stateMapping returns the state mapping of a series: the configured one, extended by the levels
+// discovered for the states of its points
+func stateMapping(p *config.PatternConfig, points []pattern.MetricPoint) map[string]float64 {
+	if !p.DiscoversStates() {
+		return p.StateMapping
+	}
+	levels := pattern.StateLevels(points)
+	if p.StateMapping == nil {
+		return levels
+	}
+	mapping := maps.Clone(p.StateMapping)
+	maps.Copy(mapping, levels)
+	return mapping
+}
+
+// statePalette assigns colors to states: green to locked states, red to free-running and
+// orange to holdover states, the colors of portStateColors to PTP port states, and the
+// remaining colors to other states in order of appearance
+type statePalette struct {
+	assigned map[string]color.Color
+	next     int
+}
+
+// stateOthers are the colors of states that are not locked, free-running, or in holdover
+var stateOthers = []color.Color{seriesColors[4], seriesColors[5], seriesColors[6], seriesColors[7], seriesColors[0]}
+
+// portStateColors are the colors of the PTP port states: green for a port synchronized to its
+// master, blue for a master port, orange while the port gets there, red for a faulty port
+var portStateColors = map[string]color.Color{
+	"SLAVE":            seriesColors[2],
+	"TIME_RECEIVER":    seriesColors[2],
+	"MASTER":           seriesColors[0],
+	"TIME_TRANSMITTER": seriesColors[0],
+	"GRAND_MASTER":     seriesColors[0],
+	"LISTENING":        seriesColors[1],
+	"UNCALIBRATED":     seriesColors[1],
+	"PASSIVE":          seriesColors[7],
+	"FAULTY":           seriesColors[3],
+}
+
+func newStatePalette() *statePalette {
+	return &statePalette{assigned: make(map[string]color.Color)}
+}
+
+// color returns the color of a state
+func (sp *statePalette) color(state string, locked []string) color.Color {
+	if c, ok := sp.assigned[state]; ok {
+		return c
+	}
+	if len(locked) == 0 {
+		locked = analysis.DefaultLockedStates
+	}
+	c, ok := portStateColors[strings.ToUpper(state)]
+	switch {
+	case ok:
+	case analysis.IsLocked(state, locked):
+		c = seriesColors[2] // green
+	case state == "s0" || strings.EqualFold(state, "FREERUN"):
+		c = seriesColors[3] // red
+	case state == "s1" || analysis.IsHoldover(state, nil):
+		c = seriesColors[1] // orange
+	default:
+		c = stateOthers[sp.next%len(stateOthers)]
+		sp.next++
+	}
+	sp.assigned[state] = c
+	return c
+}
+
+// StateLaneData is the state timeline of one series for the lane of the HTML export
+type StateLaneData struct {
+	Name     string             `json:"name"`
+	Segments []StateSegmentData `json:"segments"`
+}
+
+// StateSegmentData is a period in one state, with time offsets in seconds
+type StateSegmentData struct {
+	State string  `json:"state"`
+	X0    float64 `json:"x0"`
+	X1    float64 `json:"x1"`
+	Color string  `json:"color"`
+}
+
+// laneData converts the lane rows for export, relative to the start time
+func laneData(rows []laneRow, startTime time.Time) []StateLaneData {
+	lanes := make([]StateLaneData, len(rows))
+	for i, row := range rows {
+		lanes[i].Name = row.name
+		for j, seg := range row.segments {
+			lanes[i].Segments = append(lanes[i].Segments, StateSegmentData{
+				State: seg.State,
+				X0:    seg.Start.Sub(startTime).Seconds(),
+				X1:    seg.End.Sub(startTime).Seconds(),
+				Color: cssColor(row.colors[j]),
+			})
+		}
+	}
+	return lanes
+}
+
+// cssColor formats a color for the HTML export
+func cssColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("rgb(%d, %d, %d)", r>>8, g>>8, b>>8)
+}
+
+// stateBands draws the state segments of the lane rows as colored bands, from the top row
+// down, labeled with the state where the label fits
+type stateBands struct {
+	rows      []laneRow
+	startTime time.Time
+	TextStyle draw.TextStyle
+}
+
+// Plot implements plot.Plotter
+func (b *stateBands) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+	for i, row := range b.rows {
+		top := float64(len(b.rows) - i)
+		y0, y1 := trY(top-0.9), trY(top-0.1)
+		for j, seg := range row.segments {
+			x0 := trX(seg.Start.Sub(b.startTime).Seconds())
+			x1 := trX(seg.End.Sub(b.startTime).Seconds())
+			x0, x1 = vg.Length(math.Max(float64(x0), float64(c.Min.X))), vg.Length(math.Min(float64(x1), float64(c.Max.X)))
+			if x1 <= x0 {
+				continue
+			}
+			rect := vg.Rectangle{Min: vg.Point{X: x0, Y: y0}, Max: vg.Point{X: x1, Y: y1}}
+			c.SetColor(row.colors[j])
+			c.Fill(rect.Path())
+			if b.TextStyle.Width(seg.State)+vg.Points(4) <= x1-x0 {
+				c.FillText(b.TextStyle, vg.Point{X: (x0 + x1) / 2, Y: (y0 + y1) / 2}, seg.State)
+			}
+		}
+	}
+}
+
+// DataRange implements plot.DataRanger
+func (b *stateBands) DataRange() (xmin, xmax, ymin, ymax float64) {
+	xmin, xmax = math.Inf(1), math.Inf(-1)
+	for _, row := range b.rows {
+		for _, seg := range row.segments {
+			xmin = math.Min(xmin, seg.Start.Sub(b.startTime).Seconds())
+			xmax = math.Max(xmax, seg.End.Sub(b.startTime).Seconds())
+		}
+	}
+	return xmin, xmax, 0, float64(len(b.rows))
+}
+
+// newStateLane creates the lane plot of the state timelines, with the series names as Y
+// tick labels. Its X range is set to the plot's before drawing.
+func newStateLane(rows []laneRow, startTime time.Time, xLabel string) *plot.Plot {
+	lane := plot.New()
+	lane.X.Label.Text = xLabel
+	bands := &stateBands{rows: rows, startTime: startTime, TextStyle: lane.X.Tick.Label}
+	bands.TextStyle.Color = color.White
+	bands.TextStyle.XAlign = draw.XCenter
+	bands.TextStyle.YAlign = draw.YCenter
+	lane.Add(bands)
+
+	var ticks []plot.Tick
+	for i, row := range rows {
+		ticks = append(ticks, plot.Tick{Value: float64(len(rows)-i) - 0.5, Label: row.name})
+	}
+	lane.Y.Tick.Marker = plot.ConstantTicks(ticks)
+	lane.Y.Tick.Length = 0
+	lane.Y.Min, lane.Y.Max = 0, float64(len(rows))
+	return lane
+}
+
+// drawWithLane draws a plot above the state timeline lane. dataArea returns the data area the
+// plot has when drawn on a canvas; the lane's data area is aligned with it horizontally.
+func drawWithLane(dc draw.Canvas, render func(draw.Canvas), dataArea func(draw.Canvas) draw.Canvas, lane *plot.Plot, rows int) {
+	dc.SetColor(lane.BackgroundColor)
+	dc.Fill(dc.Rectangle.Path())
+
+	// Size the lane for its rows plus its axes, measured on a trial canvas
+	trial := draw.Crop(dc, 0, 0, 0, -(dc.Size().Y - vg.Inch))
+	laneData := lane.DataCanvas(trial)
+	laneHeight := trial.Size().Y - laneData.Size().Y + vg.Length(rows)*laneRowHeight
+	left := laneData.Min.X - trial.Min.X
+	right := trial.Max.X - laneData.Max.X
+
+	main := draw.Crop(dc, 0, 0, laneHeight, 0)
+	data := dataArea(main)
+	if shift := left - (data.Min.X - dc.Min.X); shift > 0 {
+		// Make room for the series names of the lane
+		main = draw.Crop(main, shift, 0, 0, 0)
+		data = dataArea(main)
+	}
+	render(main)
+
+	laneCanvas := draw.Crop(dc, 0, 0, 0, -(dc.Size().Y - laneHeight))
+	laneCanvas.Min.X = data.Min.X - left
+	laneCanvas.Max.X = data.Max.X + right
+	lane.Draw(laneCanvas)
+}
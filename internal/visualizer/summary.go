@@ -0,0 +1,446 @@
+package visualizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/pkg/report"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SummaryFormats lists the formats of the summary report
+var SummaryFormats = []string{"html", "markdown"}
+
+// summaryData is the data of the summary templates
+type summaryData struct {
+	*report.Summary
+	Gaps []report.Event
+	Plot htmltemplate.URL // PNG plot as a data URI (HTML) or the plot location (Markdown); empty = no plot
+}
+
+// summaryFuncs are the template functions of the summary templates
+var summaryFuncs = template.FuncMap{
+	"stat": func(v float64) string { return strconv.FormatFloat(v, 'g', 6, 64) },
+	"dur": func(s float64) string {
+		return time.Duration(s * float64(time.Second)).Round(time.Millisecond).String()
+	},
+	"offset": func(s float64) string {
+		d := time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+		if d > 0 {
+			return "+" + d.String()
+		}
+		return d.String()
+	},
+	"drift": func(ppm float64) string {
+		if ppm == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(ppm, 'f', 2, 64) + " ppm"
+	},
+	"percent": func(f float64) string { return strconv.FormatFloat(100*f, 'f', 1, 64) + "%" },
+	"time":    func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05.000") },
+	"clock":   func(t time.Time) string { return t.UTC().Format("15:04:05.000") },
+	"cell":    func(s string) string { return strings.ReplaceAll(s, "|", `\|`) },
+	"severities": func(counts map[string]int) string {
+		var parts []string
+		for _, name := range sortedKeys(counts) {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[name], name))
+		}
+		return strings.Join(parts, ", ")
+	},
+	"join": strings.Join,
+}
+
+// sortedKeys returns the keys of a map in alphabetical order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newSummaryData prepares the template data of a summary report
+func newSummaryData(s *report.Summary, plot string) *summaryData {
+	data := &summaryData{Summary: s, Plot: htmltemplate.URL(plot)}
+	for _, e := range s.Analysis.Events {
+		if e.Type == analysis.EventGap {
+			data.Gaps = append(data.Gaps, e)
+		}
+	}
+	return data
+}
+
+// WriteSummaryHTML writes the summary report as a self-contained HTML page, with the PNG plot
+// (if any) embedded
+func WriteSummaryHTML(s *report.Summary, plotPNG []byte, w io.Writer) error {
+	var plot string
+	if len(plotPNG) > 0 {
+		plot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(plotPNG)
+	}
+	tmpl, err := htmltemplate.New("summary").Funcs(htmltemplate.FuncMap(summaryFuncs)).Parse(summaryHTMLTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse summary template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, newSummaryData(s, plot)); err != nil {
+		return fmt.Errorf("failed to execute summary template: %w", err)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WriteSummaryMarkdown writes the summary report as Markdown, linking the plot at plotPath
+// (if not empty)
+func WriteSummaryMarkdown(s *report.Summary, plotPath string, w io.Writer) error {
+	tmpl, err := template.New("summary").Funcs(summaryFuncs).Parse(summaryMarkdownTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse summary template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, newSummaryData(s, plotPath)); err != nil {
+		return fmt.Errorf("failed to execute summary template: %w", err)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+const summaryHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>{{.Title}}</title>
+    <style>
+        body {
+            font-family: Arial, sans-serif;
+            margin: 20px;
+            background-color: #f5f5f5;
+            color: #333;
+        }
+        section {
+            margin-bottom: 20px;
+            padding: 15px;
+            background-color: white;
+            border: 1px solid #ddd;
+            border-radius: 5px;
+        }
+        table {
+            border-collapse: collapse;
+        }
+        th, td {
+            padding: 6px 12px;
+            border-bottom: 1px solid #ddd;
+            text-align: right;
+        }
+        th:first-child, td:first-child, td.text {
+            text-align: left;
+        }
+        code {
+            font-size: 13px;
+            word-break: break-all;
+        }
+        img {
+            max-width: 100%;
+        }
+        .fail {
+            color: #c62828;
+        }
+    </style>
+</head>
+<body>
+    <h1>{{.Title}}</h1>
+    <section>
+        <h2>Run</h2>
+        <p>Generated: {{time .GeneratedAt}} UTC<br>
+        Command: <code>{{.Command}}</code><br>
+        {{with .Analysis}}{{if .StartTime}}Capture: {{time .StartTime}} &ndash; {{time .EndTime}} UTC<br>{{end}}
+        Lines: {{.TotalLines}} ({{.LinesWithoutTimestamp}} without timestamp{{if .DuplicateLines}}, {{.DuplicateLines}} duplicates dropped{{end}}){{end}}</p>
+        <table>
+            <thead><tr><th>File</th><th>Tag</th></tr></thead>
+            <tbody>
+            {{- range .Files}}
+                <tr><td>{{.Path}}</td><td class="text">{{.Tag}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+    </section>
+    <section>
+        <h2>Alignment</h2>
+        <table>
+            <thead><tr><th>Tag</th><th>Offset</th><th>Drift</th><th>Source</th><th>Evidence</th></tr></thead>
+            <tbody>
+            {{- range .Offsets}}
+                <tr><td>{{.Tag}}</td><td>{{offset .OffsetSeconds}}</td><td>{{drift .DriftPPM}}</td><td class="text">{{.Source}}</td><td class="text">{{.Evidence}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+    </section>
+    <section>
+        <h2>Tags</h2>
+        <table>
+            <thead><tr><th>Tag</th><th>Lines</th><th>With timestamp</th><th>Out of order</th><th>Max lateness</th></tr></thead>
+            <tbody>
+            {{- range .Analysis.Tags}}
+                <tr><td>{{.Tag}}</td><td>{{.Lines}}</td><td>{{.LinesWithTimestamp}}</td><td>{{.Reordered}}</td><td>{{dur .MaxLatenessSeconds}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+    </section>
+    {{- if .Plot}}
+    <section>
+        <h2>Plot</h2>
+        <img src="{{.Plot}}" alt="{{.Title}}">
+    </section>
+    {{- end}}
+    {{- if .Analysis.Series}}
+    <section>
+        <h2>Metrics</h2>
+        <table>
+            <thead><tr><th>Series</th><th>Matches</th><th>Points</th><th>Min</th><th>Max</th><th>Mean</th><th>Median</th><th>P95</th><th>P99</th><th>StdDev</th></tr></thead>
+            <tbody>
+            {{- range .Analysis.Series}}
+                <tr><td>{{.Name}}</td><td>{{.Matches}}</td><td>{{.Points}}</td>{{with .Values}}<td>{{stat .Min}}</td><td>{{stat .Max}}</td><td>{{stat .Mean}}</td><td>{{stat .Median}}</td><td>{{stat .P95}}</td><td>{{stat .P99}}</td><td>{{stat .StdDev}}</td>{{else}}<td colspan="7"></td>{{end}}</tr>
+            {{- end}}
+            </tbody>
+        </table>
+        {{- if .Analysis.TimeError}}
+        <h3>Time error</h3>
+        <table>
+            <thead><tr><th>Series</th><th>Samples</th><th>max|TE|</th><th>max|TE<sub>L</sub>|</th><th>cTE</th><th>dTE<sub>L</sub> pk-pk</th><th>dTE<sub>H</sub> pk-pk</th></tr></thead>
+            <tbody>
+            {{- range .Analysis.TimeError}}
+                <tr><td>{{.Series}}</td><td>{{.Samples}}</td><td>{{stat .MaxAbsTE}}</td><td>{{stat .MaxAbsTEL}}</td><td>{{stat .ConstantTE}}</td><td>{{stat .DynamicTELPkPk}}</td><td>{{stat .DynamicTEHPkPk}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+        {{- end}}
+        {{- range .Analysis.States}}
+        <h3>States of {{.Series}}</h3>
+        <p>{{len .Transitions}} transitions, {{.Unlocks}} unlocks, {{with .TimeToLockSeconds}}time to lock {{dur .}}{{else}}<span class="fail">never locked</span>{{end}}</p>
+        <table>
+            <thead><tr><th>State</th><th>Time</th><th>Share</th><th>Entries</th></tr></thead>
+            <tbody>
+            {{- range .States}}
+                <tr><td>{{.State}}</td><td>{{dur .Seconds}}</td><td>{{percent .Fraction}}</td><td>{{.Entries}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+        {{- end}}
+        {{- range .Analysis.Holdovers}}
+        <h3>Holdover of {{.Series}}</h3>
+        <p>{{len .Episodes}} episodes, {{dur .TotalSeconds}} in holdover, longest {{dur .LongestSeconds}}{{with .MaxExcursion}}, max excursion {{stat .}}{{end}}{{if .MaxExcursion}}{{with .PhaseUnit}} {{.}}{{end}} of {{.PhaseSeries}}{{end}}</p>
+        <table>
+            <thead><tr><th>Start</th><th>Duration</th><th>From</th><th>To</th><th>Recovery</th><th>Max excursion</th></tr></thead>
+            <tbody>
+            {{- range .Episodes}}
+                <tr><td>{{time .Start}}</td><td>{{dur .DurationSeconds}}</td><td class="text">{{.EntryState}}</td><td class="text">{{with .ExitState}}{{.}}{{else}}(ongoing){{end}}</td><td>{{with .RecoverySeconds}}{{dur .}}{{else}}{{if .ExitState}}<span class="fail">never</span>{{end}}{{end}}</td><td>{{with .MaxExcursion}}{{stat .}}{{end}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+        {{- end}}
+    </section>
+    {{- end}}
+    {{- if .Analysis.Checks}}
+    <section>
+        <h2>Checks</h2>
+        <table>
+            <thead><tr><th>Check</th><th>Result</th><th>Details</th></tr></thead>
+            <tbody>
+            {{- range .Analysis.Checks}}
+                <tr><td>{{.Name}}</td><td class="text">{{if .Passed}}PASS{{else}}<span class="fail">FAIL</span>{{end}}</td><td class="text">{{.Message}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+    </section>
+    {{- end}}
+    {{- if .Analysis.Grandmasters}}
+    <section>
+        <h2>Grandmasters</h2>
+        {{- range .Analysis.Grandmasters}}
+        <h3>{{.Tag}}{{with .Instance}} {{.}}{{end}}</h3>
+        <p>{{.Changes}} changes, {{len .ForeignMasters}} new foreign masters</p>
+        {{- if .Periods}}
+        <table>
+            <thead><tr><th>Start</th><th>Grandmaster</th><th>Selected for</th></tr></thead>
+            <tbody>
+            {{- range .Periods}}
+                <tr><td>{{time .Start}}</td><td class="text">{{.ClockIdentity}}{{if .Local}} (local clock){{end}}</td><td>{{dur .DurationSeconds}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+        {{- end}}
+        {{- end}}
+    </section>
+    {{- end}}
+    <section>
+        <h2>Anomalies</h2>
+        <h3>Log gaps (longer than {{dur .Analysis.GapThresholdSeconds}})</h3>
+        {{- if .Gaps}}
+        <table>
+            <thead><tr><th>Start</th><th>Tag</th><th>Gap</th></tr></thead>
+            <tbody>
+            {{- range .Gaps}}
+                <tr><td>{{time .Time}}</td><td class="text">{{.Tag}}</td><td class="text">{{.Message}}</td></tr>
+            {{- end}}
+            </tbody>
+        </table>
+        {{- else}}
+        <p>None</p>
+        {{- end}}
+        <h3>Error bursts (lines at most {{dur .Analysis.BurstGapSeconds}} apart{{if lt (len .Analysis.Bursts) .Analysis.BurstCount}}, largest {{len .Analysis.Bursts}} of {{.Analysis.BurstCount}}{{end}})</h3>
+        {{- range .Analysis.Bursts}}
+        <p><b>{{time .StartTime}} &ndash; {{clock .EndTime}}</b>: {{.Lines}} lines ({{severities .Severities}}) from {{join .Tags ", "}}</p>
+        <ul>
+            {{- range .Messages}}
+            <li>{{.Count}}&times; {{.Tag}}: <code>{{.Message}}</code></li>
+            {{- end}}
+        </ul>
+        {{- else}}
+        <p>None</p>
+        {{- end}}
+    </section>
+</body>
+</html>
+`
+
+const summaryMarkdownTemplate = `# {{.Title}}
+
+## Run
+
+- Generated: {{time .GeneratedAt}} UTC
+- Command: ` + "`{{.Command}}`" + `
+{{- with .Analysis}}{{if .StartTime}}
+- Capture: {{time .StartTime}} – {{time .EndTime}} UTC{{end}}
+- Lines: {{.TotalLines}} ({{.LinesWithoutTimestamp}} without timestamp{{if .DuplicateLines}}, {{.DuplicateLines}} duplicates dropped{{end}}){{end}}
+
+| File | Tag |
+|---|---|
+{{- range .Files}}
+| {{cell .Path}} | {{cell .Tag}} |
+{{- end}}
+
+## Alignment
+
+| Tag | Offset | Drift | Source | Evidence |
+|---|--:|--:|---|---|
+{{- range .Offsets}}
+| {{cell .Tag}} | {{offset .OffsetSeconds}} | {{drift .DriftPPM}} | {{.Source}} | {{cell .Evidence}} |
+{{- end}}
+
+## Tags
+
+| Tag | Lines | With timestamp | Out of order | Max lateness |
+|---|--:|--:|--:|--:|
+{{- range .Analysis.Tags}}
+| {{cell .Tag}} | {{.Lines}} | {{.LinesWithTimestamp}} | {{.Reordered}} | {{dur .MaxLatenessSeconds}} |
+{{- end}}
+{{- if .Plot}}
+
+## Plot
+
+![{{.Title}}]({{.Plot}})
+{{- end}}
+{{- if .Analysis.Series}}
+
+## Metrics
+
+| Series | Matches | Points | Min | Max | Mean | Median | P95 | P99 | StdDev |
+|---|--:|--:|--:|--:|--:|--:|--:|--:|--:|
+{{- range .Analysis.Series}}
+| {{cell .Name}} | {{.Matches}} | {{.Points}} |{{with .Values}} {{stat .Min}} | {{stat .Max}} | {{stat .Mean}} | {{stat .Median}} | {{stat .P95}} | {{stat .P99}} | {{stat .StdDev}} |{{else}} | | | | | | |{{end}}
+{{- end}}
+{{- if .Analysis.TimeError}}
+
+### Time error
+
+| Series | Samples | max\|TE\| | max\|TE_L\| | cTE | dTE_L pk-pk | dTE_H pk-pk |
+|---|--:|--:|--:|--:|--:|--:|
+{{- range .Analysis.TimeError}}
+| {{cell .Series}} | {{.Samples}} | {{stat .MaxAbsTE}} | {{stat .MaxAbsTEL}} | {{stat .ConstantTE}} | {{stat .DynamicTELPkPk}} | {{stat .DynamicTEHPkPk}} |
+{{- end}}
+{{- end}}
+{{- range .Analysis.States}}
+
+### States of {{.Series}}
+
+{{len .Transitions}} transitions, {{.Unlocks}} unlocks, {{with .TimeToLockSeconds}}time to lock {{dur .}}{{else}}**never locked**{{end}}
+
+| State | Time | Share | Entries |
+|---|--:|--:|--:|
+{{- range .States}}
+| {{cell .State}} | {{dur .Seconds}} | {{percent .Fraction}} | {{.Entries}} |
+{{- end}}
+{{- end}}
+{{- range .Analysis.Holdovers}}
+
+### Holdover of {{.Series}}
+
+{{len .Episodes}} episodes, {{dur .TotalSeconds}} in holdover, longest {{dur .LongestSeconds}}{{with .MaxExcursion}}, max excursion {{stat .}}{{end}}{{if .MaxExcursion}}{{with .PhaseUnit}} {{.}}{{end}} of {{.PhaseSeries}}{{end}}
+
+| Start | Duration | From | To | Recovery | Max excursion |
+|---|--:|---|---|--:|--:|
+{{- range .Episodes}}
+| {{time .Start}} | {{dur .DurationSeconds}} | {{cell .EntryState}} | {{with .ExitState}}{{cell .}}{{else}}(ongoing){{end}} | {{with .RecoverySeconds}}{{dur .}}{{else}}{{if .ExitState}}**never**{{end}}{{end}} | {{with .MaxExcursion}}{{stat .}}{{end}} |
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Analysis.Checks}}
+
+## Checks
+
+| Check | Result | Details |
+|---|---|---|
+{{- range .Analysis.Checks}}
+| {{cell .Name}} | {{if .Passed}}PASS{{else}}**FAIL**{{end}} | {{cell .Message}} |
+{{- end}}
+{{- end}}
+{{- if .Analysis.Grandmasters}}
+
+## Grandmasters
+{{- range .Analysis.Grandmasters}}
+
+### {{.Tag}}{{with .Instance}} {{.}}{{end}}
+
+{{.Changes}} changes, {{len .ForeignMasters}} new foreign masters
+{{- if .Periods}}
+
+| Start | Grandmaster | Selected for |
+|---|---|--:|
+{{- range .Periods}}
+| {{time .Start}} | {{.ClockIdentity}}{{if .Local}} (local clock){{end}} | {{dur .DurationSeconds}} |
+{{- end}}
+{{- end}}
+{{- end}}
+{{- end}}
+
+## Anomalies
+
+### Log gaps (longer than {{dur .Analysis.GapThresholdSeconds}})
+{{if .Gaps}}
+| Start | Tag | Gap |
+|---|---|---|
+{{- range .Gaps}}
+| {{time .Time}} | {{cell .Tag}} | {{.Message}} |
+{{- end}}
+{{- else}}
+None
+{{- end}}
+
+### Error bursts (lines at most {{dur .Analysis.BurstGapSeconds}} apart{{if lt (len .Analysis.Bursts) .Analysis.BurstCount}}, largest {{len .Analysis.Bursts}} of {{.Analysis.BurstCount}}{{end}})
+{{range .Analysis.Bursts}}
+**{{time .StartTime}} – {{clock .EndTime}}**: {{.Lines}} lines ({{severities .Severities}}) from {{join .Tags ", "}}
+
+{{range .Messages}}- {{.Count}}× {{.Tag}}: ` + "`{{.Message}}`" + `
+{{end}}
+{{- else}}
+None
+{{end -}}
+`
@@ -0,0 +1,196 @@
+package visualizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log-interleaver/internal/analysis"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/sink"
+	"log-interleaver/pkg/pattern"
+	"log-interleaver/pkg/report"
+)
+
+// BuildTEReport computes the time error report for the series selected in the config's te_report
+// section. A series without data is an error.
+func BuildTEReport(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig) (*report.TEReport, error) {
+	return buildTEReport(ctx, lines, cfg, true)
+}
+
+// buildTEReport computes the time error report; unless strict, series without data are left out
+// with a warning (see ComputeTimeErrors)
+func buildTEReport(ctx context.Context, lines []*parser.LogLine, cfg *config.VisualizationConfig, strict bool) (*report.TEReport, error) {
+	if cfg.TEReport == nil || len(cfg.TEReport.Series) == 0 {
+		return nil, fmt.Errorf("config has no te_report series")
+	}
+
+	metrics, err := ExtractMetrics(ctx, lines, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if strict {
+		for _, name := range cfg.TEReport.Series {
+			if len(metrics[name]) == 0 {
+				return nil, fmt.Errorf("te_report series '%s' has no data (check the pattern name)", name)
+			}
+		}
+	}
+
+	teReport := &report.TEReport{
+		Title:    cfg.Title,
+		Standard: "ITU-T G.8273.2",
+	}
+	if teReport.Series, err = ComputeTimeErrors(metrics, cfg); err != nil {
+		return nil, err
+	}
+
+	// Track the overall measurement period
+	for _, name := range cfg.TEReport.Series {
+		for _, pt := range metrics[name] {
+			if teReport.StartTime.IsZero() || pt.Time.Before(teReport.StartTime) {
+				teReport.StartTime = pt.Time
+			}
+			if pt.Time.After(teReport.EndTime) {
+				teReport.EndTime = pt.Time
+			}
+		}
+	}
+
+	return teReport, nil
+}
+
+// ComputeTimeErrors computes the time error statistics of the te_report series from the
+// extracted metrics. Series without data (e.g., a pattern that did not match the logs at hand)
+// are left out with a warning.
+func ComputeTimeErrors(metrics map[string][]pattern.MetricPoint, cfg *config.VisualizationConfig) ([]report.TimeErrorStats, error) {
+	var series []report.TimeErrorStats
+	for _, name := range cfg.TEReport.Series {
+		points := metrics[name]
+		if len(points) == 0 {
+			logger.Warn(fmt.Sprintf("te_report series '%s' has no data (check the pattern name), left out of the time error report", name))
+			continue
+		}
+		stats, err := analysis.ComputeTimeError(name, points, cfg.TEReport.CutoffHz, cfg.TEReport.CTEWindow)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, *stats)
+	}
+	return series, nil
+}
+
+// ExportTEReport exports the time error report to JSON format
+func ExportTEReport(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(configPath, configOpts)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	teReport, err := BuildTEReport(ctx, lines, cfg)
+	if err != nil {
+		return err
+	}
+
+	out, err := sink.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer out.Close()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(teReport); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	return out.Close()
+}
+
+// ExportTEReportHTML exports the time error report as a formatted HTML section,
+// suitable for embedding into other HTML documents
+func ExportTEReportHTML(ctx context.Context, lines []*parser.LogLine, configPath string, configOpts config.Options, outputPath string) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(configPath, configOpts)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	teReport, err := BuildTEReport(ctx, lines, cfg)
+	if err != nil {
+		return err
+	}
+
+	section, err := RenderTEReportHTML(teReport)
+	if err != nil {
+		return err
+	}
+
+	out, err := sink.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := out.Write([]byte(section)); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return out.Close()
+}
+
+// RenderTEReportHTML renders the time error report as an HTML <section>
+func RenderTEReportHTML(teReport *report.TEReport) (template.HTML, error) {
+	tmpl, err := template.New("te-report").Parse(teReportTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse report template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, teReport); err != nil {
+		return "", fmt.Errorf("failed to execute report template: %w", err)
+	}
+
+	return template.HTML(buf.String()), nil
+}
+
+const teReportTemplate = `<section class="te-report">
+    <h2>Time Error Performance ({{.Standard}})</h2>
+    <p>Measurement period: {{.StartTime.Format "2006-01-02 15:04:05"}} &ndash; {{.EndTime.Format "2006-01-02 15:04:05"}} UTC</p>
+    <table>
+        <thead>
+            <tr>
+                <th>Series</th>
+                <th>Samples</th>
+                <th>Duration (s)</th>
+                <th>max|TE|</th>
+                <th>max|TE<sub>L</sub>|</th>
+                <th>cTE</th>
+                <th>dTE<sub>L</sub> pk-pk</th>
+                <th>dTE<sub>H</sub> pk-pk</th>
+            </tr>
+        </thead>
+        <tbody>
+            {{- range .Series}}
+            <tr>
+                <td>{{.Series}}</td>
+                <td>{{.Samples}}</td>
+                <td>{{printf "%.1f" .DurationSeconds}}</td>
+                <td>{{printf "%.3f" .MaxAbsTE}}</td>
+                <td>{{printf "%.3f" .MaxAbsTEL}}</td>
+                <td>{{printf "%.3f" .ConstantTE}}</td>
+                <td>{{printf "%.3f" .DynamicTELPkPk}}</td>
+                <td>{{printf "%.3f" .DynamicTEHPkPk}}</td>
+            </tr>
+            {{- end}}
+        </tbody>
+    </table>
+    <p class="te-note">cTE is the mean time error over the measurement period{{with index .Series 0}}{{if .CTEWindowSeconds}}, or the largest
+    mean over windows of {{.CTEWindowSeconds}} s{{end}}{{end}}. dTE<sub>L</sub> and dTE<sub>H</sub>
+    are the low- and high-frequency components split by a first-order filter at {{with index .Series 0}}{{.CutoffHz}}{{end}} Hz.
+    Values are in the unit of each series.</p>
+</section>
+`
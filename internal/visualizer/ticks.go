@@ -0,0 +1,107 @@
+package visualizer
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// maxXTicks bounds the ticks of an X tick spacing, which falls back to automatic ticks for
+// spacings far too small for the capture
+const maxXTicks = 200
+
+// timeTicks marks an X axis of seconds since the start time at multiples of the spacing from the
+// start, or at automatic ticks without one, and labels the ticks in the tick format. The ticks
+// are the ones of the HTML plot.
+type timeTicks struct {
+	spacing float64 // Seconds between the ticks (0 = automatic ticks)
+	format  string  // "seconds", "duration", or "clock"
+	start   time.Time
+}
+
+// Ticks implements plot.Ticker. Spacings giving more than maxXTicks ticks or fewer than two fall
+// back to automatic ticks: about eight, at a nice spacing.
+func (t timeTicks) Ticks(min, max float64) []plot.Tick {
+	if max <= min {
+		return nil
+	}
+	spacing := t.spacing
+	if span := max - min; spacing <= 0 || span/spacing > maxXTicks || span/spacing < 2 {
+		spacing = niceTickSpacing(span / 8)
+	}
+	var ticks []plot.Tick
+	for n := math.Ceil(min / spacing); n*spacing <= max; n++ {
+		ticks = append(ticks, plot.Tick{Value: n * spacing, Label: t.label(n * spacing)})
+	}
+	return ticks
+}
+
+// niceTickSteps are the tick spacings in seconds automatic ticks round up to, before whole days
+var niceTickSteps = []float64{1, 2, 5, 10, 15, 30, 60, 120, 300, 600, 900, 1800, 3600, 7200, 10800, 21600, 43200, 86400}
+
+// niceTickSpacing rounds a spacing in seconds up to whole seconds, minutes, hours, or days, or
+// below a second to a power of ten
+func niceTickSpacing(target float64) float64 {
+	if target < 1 {
+		return math.Pow(10, math.Ceil(math.Log10(target)))
+	}
+	for _, step := range niceTickSteps {
+		if step >= target {
+			return step
+		}
+	}
+	return math.Ceil(target/86400) * 86400
+}
+
+// label formats a tick at the given seconds since the start
+func (t timeTicks) label(seconds float64) string {
+	switch t.format {
+	case "duration":
+		return durationLabel(seconds)
+	case "clock":
+		return t.start.Add(time.Duration(math.Round(seconds*1e3)) * time.Millisecond).UTC().Format("15:04:05")
+	}
+	return strconv.FormatFloat(math.Round(seconds*1e3)/1e3, 'f', -1, 64)
+}
+
+// durationLabel formats seconds as a compact duration, e.g. 1h30m, 45m, or 1m7.5s
+func durationLabel(seconds float64) string {
+	sign := ""
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	seconds = math.Round(seconds*1e3) / 1e3
+	h, m := math.Floor(seconds/3600), math.Floor(math.Mod(seconds, 3600)/60)
+	s := math.Round(math.Mod(seconds, 60)*1e3) / 1e3
+	label := ""
+	if h > 0 {
+		label += strconv.FormatFloat(h, 'f', -1, 64) + "h"
+	}
+	if m > 0 {
+		label += strconv.FormatFloat(m, 'f', -1, 64) + "m"
+	}
+	if s > 0 || label == "" {
+		label += strconv.FormatFloat(s, 'f', -1, 64) + "s"
+	}
+	return sign + label
+}
+
+// styleXAxis sets the ticks and the tick label rotation of the config on an X axis of seconds
+// since the start time. Without tick settings, the axis keeps the default ticks.
+func (v *Visualizer) styleXAxis(axis *plot.Axis, startTime time.Time) {
+	if v.config.XTickSpacing > 0 || v.config.XTickFormat != "" {
+		axis.Tick.Marker = timeTicks{spacing: v.config.XTickSpacing.Seconds(), format: v.config.XTickFormat, start: startTime}
+	}
+	if v.config.XTickRotation != 0 {
+		// Rotated labels end at their tick, so they do not run into the neighbouring labels
+		axis.Tick.Label.Rotation = v.config.XTickRotation * math.Pi / 180
+		axis.Tick.Label.XAlign = draw.XRight
+		axis.Tick.Label.YAlign = draw.YCenter
+		if v.config.XTickRotation < 0 {
+			axis.Tick.Label.XAlign = draw.XLeft
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package visualizer
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTimeTicks(t *testing.T) {
+	start := time.Date(2026, 1, 11, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		ticks    timeTicks
+		min, max float64
+		values   []float64
+		labels   []string
+	}{
+		{
+			name:   "spacing",
+			ticks:  timeTicks{spacing: 600, format: "duration"},
+			min:    0,
+			max:    1800,
+			values: []float64{0, 600, 1200, 1800},
+			labels: []string{"0s", "10m", "20m", "30m"},
+		},
+		{
+			name:   "clock",
+			ticks:  timeTicks{spacing: 3600, format: "clock", start: start},
+			min:    100,
+			max:    7300,
+			values: []float64{3600, 7200},
+			labels: []string{"15:00:00", "16:00:00"},
+		},
+		{
+			// Fewer than two ticks of the spacing: about eight at a nice spacing
+			name:   "spacing longer than the span",
+			ticks:  timeTicks{spacing: 3600},
+			min:    0,
+			max:    120,
+			values: []float64{0, 15, 30, 45, 60, 75, 90, 105, 120},
+			labels: []string{"0", "15", "30", "45", "60", "75", "90", "105", "120"},
+		},
+		{
+			name:   "too many ticks",
+			ticks:  timeTicks{spacing: 1},
+			min:    0,
+			max:    4000,
+			values: []float64{0, 600, 1200, 1800, 2400, 3000, 3600},
+		},
+		{
+			name:   "automatic",
+			ticks:  timeTicks{format: "seconds"},
+			min:    0,
+			max:    0.65,
+			values: []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6},
+		},
+		{
+			name:  "empty range",
+			ticks: timeTicks{spacing: 60},
+			min:   5,
+			max:   5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var values []float64
+			var labels []string
+			for _, tick := range tt.ticks.Ticks(tt.min, tt.max) {
+				values = append(values, tick.Value)
+				labels = append(labels, tick.Label)
+			}
+			if len(values) != len(tt.values) {
+				t.Fatalf("ticks at %v, want %v", values, tt.values)
+			}
+			for i := range values {
+				// The automatic spacing below a second is a power of ten, multiplied out
+				if diff := values[i] - tt.values[i]; diff > 1e-9 || diff < -1e-9 {
+					t.Fatalf("ticks at %v, want %v", values, tt.values)
+				}
+			}
+			if tt.labels != nil && !slices.Equal(labels, tt.labels) {
+				t.Errorf("labels %q, want %q", labels, tt.labels)
+			}
+		})
+	}
+}
+
+func TestNiceTickSpacing(t *testing.T) {
+	tests := []struct {
+		target, want float64
+	}{
+		{0.03, 0.1},
+		{0.5, 1},
+		{1, 1},
+		{7, 10},
+		{200, 300},
+		{4000, 7200},
+		{100000, 172800},
+	}
+	for _, tt := range tests {
+		if got := niceTickSpacing(tt.target); got != tt.want {
+			t.Errorf("niceTickSpacing(%g) = %g, want %g", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestDurationLabel(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "0s"},
+		{45, "45s"},
+		{67.5, "1m7.5s"},
+		{2700, "45m"},
+		{5400, "1h30m"},
+		{3601, "1h1s"},
+		{-90, "-1m30s"},
+	}
+	for _, tt := range tests {
+		if got := durationLabel(tt.seconds); got != tt.want {
+			t.Errorf("durationLabel(%g) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -1,24 +1,44 @@
 package visualizer
 
 import (
+	"context"
 	"fmt"
 	"image/color"
+	"io"
+	"log-interleaver/internal/analysis"
 	"log-interleaver/internal/config"
 	"log-interleaver/internal/parser"
-	"log-interleaver/pkg/pattern"
+	"log-interleaver/internal/sink"
+	"log/slog"
+	"math"
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+	"gonum.org/v1/plot/vg/vgpdf"
+	"gonum.org/v1/plot/vg/vgsvg"
 )
 
+// logger receives the warnings of the visualizer, such as a te_report series without data
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// SetLogger sets the logger that receives the warnings of the visualizer
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
 // Visualizer creates plots from log data
 type Visualizer struct {
 	config *config.VisualizationConfig
+	format string // Static plot format; empty = from the output extension
+
+	legends []*plotLegend // Legends of the plots of the current render
 }
 
 // NewVisualizer creates a new visualizer with the given configuration
@@ -26,79 +46,168 @@ func NewVisualizer(cfg *config.VisualizationConfig) *Visualizer {
 	return &Visualizer{config: cfg}
 }
 
+// SetFormat sets the static plot format (see PlotFormats), overriding the output extension.
+// An empty format selects the format from the output extension.
+func (v *Visualizer) SetFormat(format string) {
+	v.format = strings.ToLower(format)
+}
+
 // GeneratePlot generates a plot from log lines and saves it to a file
-func (v *Visualizer) GeneratePlot(lines []*parser.LogLine, outputPath string) error {
-	// Convert config patterns to pattern matcher format
-	patternConfigs := make([]pattern.PatternConfig, len(v.config.Patterns))
-	for i, p := range v.config.Patterns {
-		patternConfigs[i] = pattern.PatternConfig{
-			Name:         p.Name,
-			Regex:        p.Regex,
-			TagFilter:    p.TagFilter,
-			ValueGroup:   p.ValueGroup,
-			StateGroup:   p.StateGroup,
-			StateMapping: p.StateMapping,
-			Color:        p.Color,
-			LineStyle:    p.LineStyle,
-			Marker:       p.Marker,
-			YAxisLabel:   p.YAxisLabel,
-			YAxisIndex:   p.YAxisIndex,
-		}
+func (v *Visualizer) GeneratePlot(ctx context.Context, lines []*parser.LogLine, outputPath string) error {
+	render, err := v.renderer(ctx, lines)
+	if err != nil {
+		return err
 	}
 
-	// Create pattern matcher
-	matcher, err := pattern.NewPatternMatcher(patternConfigs)
+	// Save plot (format is chosen from the output extension unless set explicitly)
+	if err := savePlot(render, v.width(), v.height(), v.config.DPI, v.format, outputPath); err != nil {
+		return fmt.Errorf("failed to save plot: %w", err)
+	}
+
+	return nil
+}
+
+// WritePlot generates a plot from log lines and writes it to w in the given format
+func (v *Visualizer) WritePlot(ctx context.Context, lines []*parser.LogLine, w io.Writer, format string) error {
+	render, err := v.renderer(ctx, lines)
 	if err != nil {
-		return fmt.Errorf("failed to create pattern matcher: %w", err)
+		return err
+	}
+	if err := writePlot(render, v.width(), v.height(), v.config.DPI, format, w); err != nil {
+		return fmt.Errorf("failed to write plot: %w", err)
 	}
+	return nil
+}
 
+// width returns the width of the plot
+func (v *Visualizer) width() vg.Length {
+	return vg.Length(v.config.Width) * vg.Inch
+}
+
+// height returns the height of the plot
+func (v *Visualizer) height() vg.Length {
+	return vg.Length(v.config.Height) * vg.Inch
+}
+
+// renderer builds the plot of the log lines with the templates of the title and labels
+// filled from the run, and returns its draw function
+func (v *Visualizer) renderer(ctx context.Context, lines []*parser.LogLine) (func(draw.Canvas), error) {
+	cfg, err := v.config.ExpandTemplates(runMetadata(v.config, lines))
+	if err != nil {
+		return nil, err
+	}
+	expanded := *v
+	expanded.config = cfg
+	render, err := expanded.render(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+	return expanded.legendBelow(render), nil
+}
+
+// render builds the plot of the log lines and returns its draw function
+func (v *Visualizer) render(ctx context.Context, lines []*parser.LogLine) (func(draw.Canvas), error) {
 	// Extract metrics
-	metrics, err := matcher.ExtractMetrics(lines)
+	metrics, err := ExtractMetrics(ctx, lines, v.config)
 	if err != nil {
-		return fmt.Errorf("failed to extract metrics: %w", err)
+		return nil, err
+	}
+	switch v.config.PlotType {
+	case "heatmap":
+		return v.heatmapRenderer(metrics)
+	case "box":
+		return v.boxRenderer(metrics)
+	case "violin":
+		return v.violinRenderer(metrics)
 	}
 
 	// Create plot
 	p := plot.New()
+	v.styleFonts(p)
 	p.Title.Text = v.config.Title
 	p.X.Label.Text = v.config.XAxisLabel
 	p.Y.Label.Text = v.config.YAxisLabel
 
-	// Group series by Y-axis index
+	// Group series by Y-axis index; event patterns are drawn across all axes, and lane patterns
+	// in the state timeline lane
 	seriesByAxis := make(map[int][]string)
-	for _, pattern := range v.config.Patterns {
+	var axisOrder []int
+	var events []*config.PatternConfig
+	for i, pattern := range v.config.Patterns {
+		if pattern.IsLane() {
+			continue
+		}
+		if pattern.IsEvent() {
+			events = append(events, &v.config.Patterns[i])
+			continue
+		}
 		axisIdx := pattern.YAxisIndex
 		if axisIdx < 0 {
 			axisIdx = 0
 		}
 		if _, ok := seriesByAxis[axisIdx]; !ok {
 			seriesByAxis[axisIdx] = make([]string, 0)
+			axisOrder = append(axisOrder, axisIdx)
 		}
 		seriesByAxis[axisIdx] = append(seriesByAxis[axisIdx], pattern.Name)
 	}
+	sort.Ints(axisOrder)
+
+	// All series and events are placed relative to the earliest point of any series
+	var startTime time.Time
+	for _, points := range metrics {
+		for _, pt := range points {
+			if startTime.IsZero() || pt.Time.Before(startTime) {
+				startTime = pt.Time
+			}
+		}
+	}
 
-	// Create secondary Y-axis if needed
-	var rightAxis *plot.Axis
-	if len(seriesByAxis) > 1 {
-		rightAxis = &plot.Axis{}
-		p.Y.Label.Text = v.config.YAxisLabel // Left axis label
+	// In subplot mode, each Y-axis group gets its own panel; the panels share the X axis
+	panels := make(map[int]*plot.Plot)
+	if v.config.Subplots && len(axisOrder) > 1 {
+		for _, axisIdx := range axisOrder {
+			panels[axisIdx] = v.newPanel(axisIdx, seriesByAxis[axisIdx])
+		}
 	}
 
-	// Plot each series
-	colors := []color.Color{
-		color.RGBA{R: 31, G: 119, B: 180, A: 255},  // blue
-		color.RGBA{R: 255, G: 127, B: 14, A: 255},  // orange
-		color.RGBA{R: 44, G: 160, B: 44, A: 255},   // green
-		color.RGBA{R: 214, G: 39, B: 40, A: 255},   // red
-		color.RGBA{R: 148, G: 103, B: 189, A: 255}, // purple
-		color.RGBA{R: 140, G: 86, B: 75, A: 255},   // brown
-		color.RGBA{R: 227, G: 119, B: 194, A: 255}, // pink
-		color.RGBA{R: 127, G: 127, B: 127, A: 255}, // gray
+	// Create secondary Y-axis if needed: series on axis 1 get their own scale on the right.
+	// Series on further axes share the left axis (use subplots to separate them).
+	var secondary *rightAxis
+	if _, ok := seriesByAxis[1]; ok && len(panels) == 0 {
+		if label := v.axisLabel(0, seriesByAxis[0]); label != "" {
+			p.Y.Label.Text = label
+		}
+		secondary = newRightAxis(v.axisLabel(1, seriesByAxis[1]))
+		v.styleAxisFonts(&secondary.Axis)
+	}
+
+	// Shade log gaps below the series
+	var gaps *gapSpans
+	if v.config.GapThreshold > 0 {
+		if events := analysis.DetectGaps(lines, v.config.GapThreshold); len(events) > 0 {
+			gaps = newGapSpans(events, startTime)
+			if len(panels) == 0 {
+				p.Add(gaps)
+			}
+			for _, panel := range panels {
+				panel.Add(gaps)
+			}
+		}
 	}
+
+	// Plot each series
+	colors := seriesColors
 	colorIdx := 0
 
-	for axisIdx, seriesNames := range seriesByAxis {
-		for _, seriesName := range seriesNames {
+	for _, axisIdx := range axisOrder {
+		var target plotAdder = p
+		if panel, ok := panels[axisIdx]; ok {
+			target = panel
+		} else if axisIdx == 1 && secondary != nil {
+			target = secondary
+		}
+		for _, seriesName := range seriesByAxis[axisIdx] {
 			points, ok := metrics[seriesName]
 			if !ok || len(points) == 0 {
 				continue
@@ -117,14 +226,19 @@ func (v *Visualizer) GeneratePlot(lines []*parser.LogLine, outputPath string) er
 			sort.Slice(points, func(i, j int) bool {
 				return points[i].Time.Before(points[j].Time)
 			})
+			var mapping map[string]float64
+			if patternCfg != nil {
+				mapping = stateMapping(patternCfg, points)
+			}
+			allPoints := points
+			points = DownsampleLTTB(points, maxPoints(v.config, patternCfg))
 
 			// Convert to plotter.XYs
 			var xy plotter.XYs
-			startTime := points[0].Time
-			
+
 			// Check if step plot is requested
 			useStep := patternCfg != nil && patternCfg.Step
-			
+
 			if useStep && len(points) > 1 {
 				// For step plots, create horizontal-vertical steps
 				// Each point needs a horizontal segment to the next x value
@@ -132,10 +246,10 @@ func (v *Visualizer) GeneratePlot(lines []*parser.LogLine, outputPath string) er
 				for i, pt := range points {
 					x := pt.Time.Sub(startTime).Seconds()
 					y := pt.Value
-					
+
 					// Add the point
 					xy = append(xy, plotter.XY{X: x, Y: y})
-					
+
 					// Add horizontal segment to next point (if not last point)
 					if i < len(points)-1 {
 						nextX := points[i+1].Time.Sub(startTime).Seconds()
@@ -153,10 +267,10 @@ func (v *Visualizer) GeneratePlot(lines []*parser.LogLine, outputPath string) er
 
 			// Build legend label with state mapping if available
 			legendLabel := seriesName
-			if patternCfg != nil && patternCfg.StateMapping != nil && len(patternCfg.StateMapping) > 0 {
+			if len(mapping) > 0 {
 				// Create mapping string for legend
-				mappingParts := make([]string, 0, len(patternCfg.StateMapping))
-				for state, value := range patternCfg.StateMapping {
+				mappingParts := make([]string, 0, len(mapping))
+				for state, value := range mapping {
 					mappingParts = append(mappingParts, fmt.Sprintf("%s=%.0f", state, value))
 				}
 				// Sort for consistent display
@@ -171,7 +285,7 @@ func (v *Visualizer) GeneratePlot(lines []*parser.LogLine, outputPath string) er
 			// Determine color
 			plotColor := colors[colorIdx%len(colors)]
 			if patternCfg != nil && patternCfg.Color != "" {
-				if parsedColor := parseColor(patternCfg.Color); parsedColor != nil {
+				if parsedColor := config.ParseColor(patternCfg.Color); parsedColor != nil {
 					plotColor = parsedColor
 				}
 			}
@@ -207,14 +321,22 @@ func (v *Visualizer) GeneratePlot(lines []*parser.LogLine, outputPath string) er
 				// Default marker if none specified
 				markerShape = draw.CircleGlyph{}
 			}
+			if patternCfg != nil && patternCfg.MarkerSize > 0 {
+				markerRadius = vg.Points(patternCfg.MarkerSize / 2)
+			}
+			// The band keeps the plot color, the series is drawn in the opacity of the config
+			seriesColor := plotColor
+			if patternCfg != nil && patternCfg.Opacity > 0 {
+				seriesColor = withOpacity(plotColor, patternCfg.Opacity)
+			}
 
 			// Create scatter plot (dots)
 			scatter, err := plotter.NewScatter(xy)
 			if err != nil {
-				return fmt.Errorf("failed to create scatter plot: %w", err)
+				return nil, fmt.Errorf("failed to create scatter plot: %w", err)
 			}
 			scatter.GlyphStyle.Radius = markerRadius
-			scatter.GlyphStyle.Color = plotColor
+			scatter.GlyphStyle.Color = seriesColor
 			if markerShape != nil {
 				scatter.GlyphStyle.Shape = markerShape
 			}
@@ -245,52 +367,318 @@ func (v *Visualizer) GeneratePlot(lines []*parser.LogLine, outputPath string) er
 						lineStyle.Dashes = []vg.Length{vg.Points(5), vg.Points(2), vg.Points(2), vg.Points(2)}
 					}
 				}
-				lineStyle.Color = plotColor
+				lineStyle.Color = seriesColor
 				lineStyle.Width = vg.Points(1)
+				if patternCfg != nil && patternCfg.LineWidth > 0 {
+					lineStyle.Width = vg.Points(patternCfg.LineWidth)
+				}
 
 				line, err = plotter.NewLine(xy)
 				if err != nil {
-					return fmt.Errorf("failed to create line plot: %w", err)
+					return nil, fmt.Errorf("failed to create line plot: %w", err)
 				}
 				line.LineStyle = lineStyle
 			}
 
 			// Add to plot
-			if drawLines && line != nil {
-				p.Add(scatter, line)
-				p.Legend.Add(legendLabel, scatter, line)
+			// The legend of the secondary axis series is part of the main plot legend
+			legendPlot := p
+			if panel, ok := panels[axisIdx]; ok {
+				legendPlot = panel
+			}
+			if patternCfg != nil && patternCfg.Band != nil {
+				band, err := newBandPolygon(allPoints, patternCfg.Band, maxPoints(v.config, patternCfg), startTime, plotColor)
+				if err != nil {
+					return nil, err
+				}
+				target.Add(band)
+				v.addLegend(legendPlot, bandName(seriesName, patternCfg.Band), band)
+			}
+			if drawLines && line != nil && patternCfg != nil && patternCfg.RollingStat != "" {
+				// Rolling statistics overlays are lines only
+				target.Add(line)
+				v.addLegend(legendPlot, legendLabel, line)
+			} else if drawLines && line != nil {
+				target.Add(scatter, line)
+				v.addLegend(legendPlot, legendLabel, scatter, line)
 			} else {
 				// Markers only
-				p.Add(scatter)
-				p.Legend.Add(legendLabel, scatter)
+				target.Add(scatter)
+				v.addLegend(legendPlot, legendLabel, scatter)
 			}
 
-			// Use right axis if specified
-			if axisIdx == 1 && rightAxis != nil {
-				// Note: gonum/plot doesn't directly support dual Y-axes easily
-				// For now, we'll use the same axis but could enhance this later
+			colorIdx++
+		}
+	}
+
+	// Draw events as labeled vertical lines on every panel
+	for _, eventCfg := range events {
+		points := metrics[eventCfg.Name]
+		if len(points) == 0 {
+			continue
+		}
+		sort.Slice(points, func(i, j int) bool {
+			return points[i].Time.Before(points[j].Time)
+		})
+		plotColor := colors[colorIdx%len(colors)]
+		if eventCfg.Color != "" {
+			if parsedColor := config.ParseColor(eventCfg.Color); parsedColor != nil {
+				plotColor = parsedColor
 			}
+		}
+		colorIdx++
 
-			colorIdx++
+		eventLines := newEventLines(eventCfg.Name, points, startTime, plotColor)
+		if len(panels) == 0 {
+			p.Add(eventLines)
+			v.addLegend(p, eventCfg.Name, eventLines)
+			continue
+		}
+		for _, axisIdx := range axisOrder {
+			panels[axisIdx].Add(eventLines)
+		}
+		v.addLegend(panels[axisOrder[0]], eventCfg.Name, eventLines)
+	}
+
+	// Mark the grandmaster changes of ptp4l like events
+	if points := gmChangePoints(lines); len(points) > 0 {
+		gmLines := newEventLines(gmChangeName, points, startTime, colors[colorIdx%len(colors)])
+		colorIdx++
+		if len(panels) == 0 {
+			p.Add(gmLines)
+			v.addLegend(p, gmChangeName, gmLines)
+		} else {
+			for _, axisIdx := range axisOrder {
+				panels[axisIdx].Add(gmLines)
+			}
+			v.addLegend(panels[axisOrder[0]], gmChangeName, gmLines)
+		}
+	}
+
+	if gaps != nil {
+		if len(panels) == 0 {
+			v.addLegend(p, "Log gaps", gaps)
+		} else {
+			v.addLegend(panels[axisOrder[0]], "Log gaps", gaps)
 		}
 	}
 
 	// Set legend position
 	p.Legend.Top = true
 	p.Legend.Left = true
+	v.styleXAxis(&p.X, startTime)
+	for _, panel := range panels {
+		v.styleXAxis(&panel.X, startTime)
+	}
+	v.placeLegends(func(target *plot.Plot, columns plot.Plotter) {
+		if target == p && secondary != nil {
+			// Drawn after the series of the secondary axis, as the legend of the plot
+			secondary.Add(columns)
+		} else {
+			target.Add(columns)
+		}
+	})
+
+	render := p.Draw
+	dataArea := p.DataCanvas
+	xAxis := &p.X
+	if secondary != nil {
+		render = func(dc draw.Canvas) { secondary.drawPlot(p, dc) }
+		dataArea = func(dc draw.Canvas) draw.Canvas { return secondary.dataCanvas(p, dc) }
+	} else if len(panels) > 0 {
+		plots := stackPanels(panels, axisOrder)
+		plots[0].Title.Text = v.config.Title
+		plots[len(plots)-1].X.Label.Text = v.config.XAxisLabel
+		render = func(dc draw.Canvas) { drawStacked(plots, dc) }
+		dataArea = func(dc draw.Canvas) draw.Canvas {
+			canvases := stackedCanvases(plots, dc)
+			return plots[len(plots)-1].DataCanvas(canvases[len(plots)-1])
+		}
+		xAxis = &plots[len(plots)-1].X
+	}
 
-	// Save plot
-	if err := p.Save(vg.Length(v.config.Width)*vg.Inch, vg.Length(v.config.Height)*vg.Inch, outputPath); err != nil {
-		return fmt.Errorf("failed to save plot: %w", err)
+	// The state timeline lane goes below the plot and takes over the X axis label
+	if rows := stateLanes(v.config, metrics); len(rows) > 0 {
+		lane := newStateLane(rows, startTime, xAxis.Label.Text)
+		v.styleAxisFonts(&lane.X)
+		v.styleAxisFonts(&lane.Y)
+		v.styleXAxis(&lane.X, startTime)
+		xAxis.Label.Text = ""
+		plotRender, plotArea := render, dataArea
+		render = func(dc draw.Canvas) {
+			if secondary != nil {
+				secondary.prepare(p)
+			}
+			lane.X.Min, lane.X.Max = xAxis.Min, xAxis.Max
+			drawWithLane(dc, plotRender, plotArea, lane, len(rows))
+		}
 	}
 
-	return nil
+	return render, nil
+}
+
+// plotAdder is a plot or a secondary axis that series are added to
+type plotAdder interface {
+	Add(...plot.Plotter)
+}
+
+// withOpacity returns the color at the opacity, from 0 (transparent) to 1 (opaque)
+func withOpacity(c color.Color, opacity float64) color.Color {
+	r, g, b, _ := c.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(math.Round(opacity * 255))}
+}
+
+// axisLabel returns the label of a Y-axis: the named axis label, or else the yaxis_label of
+// its first series (empty if neither is set)
+func (v *Visualizer) axisLabel(axisIdx int, seriesNames []string) string {
+	if axisIdx < len(v.config.Axes) && v.config.Axes[axisIdx].Label != "" {
+		return v.config.Axes[axisIdx].Label
+	}
+	for _, pattern := range v.config.Patterns {
+		if len(seriesNames) > 0 && pattern.Name == seriesNames[0] {
+			return pattern.YAxisLabel
+		}
+	}
+	return ""
+}
+
+// newPanel creates the subplot panel of a Y-axis group, labeled with the axis label
+func (v *Visualizer) newPanel(axisIdx int, seriesNames []string) *plot.Plot {
+	panel := plot.New()
+	v.styleFonts(panel)
+	panel.Y.Label.Text = v.config.YAxisLabel
+	if label := v.axisLabel(axisIdx, seriesNames); label != "" {
+		panel.Y.Label.Text = label
+	}
+	panel.Legend.Top = true
+	panel.Legend.Left = true
+	return panel
+}
+
+// stackPanels orders the subplot panels top to bottom by axis index and gives them a
+// common X range, so the panels line up in time
+func stackPanels(panels map[int]*plot.Plot, axisOrder []int) []*plot.Plot {
+	plots := make([]*plot.Plot, 0, len(panels))
+	for _, axisIdx := range axisOrder {
+		plots = append(plots, panels[axisIdx])
+	}
+
+	xMin, xMax := math.Inf(1), math.Inf(-1)
+	for _, panel := range plots {
+		xMin = math.Min(xMin, panel.X.Min)
+		xMax = math.Max(xMax, panel.X.Max)
+	}
+	for _, panel := range plots {
+		panel.X.Min, panel.X.Max = xMin, xMax
+	}
+	return plots
+}
+
+// seriesColors is the default color cycle of plotted series
+var seriesColors = []color.Color{
+	color.RGBA{R: 31, G: 119, B: 180, A: 255},  // blue
+	color.RGBA{R: 255, G: 127, B: 14, A: 255},  // orange
+	color.RGBA{R: 44, G: 160, B: 44, A: 255},   // green
+	color.RGBA{R: 214, G: 39, B: 40, A: 255},   // red
+	color.RGBA{R: 148, G: 103, B: 189, A: 255}, // purple
+	color.RGBA{R: 140, G: 86, B: 75, A: 255},   // brown
+	color.RGBA{R: 227, G: 119, B: 194, A: 255}, // pink
+	color.RGBA{R: 127, G: 127, B: 127, A: 255}, // gray
+}
+
+// PlotFormats lists the supported static plot formats
+var PlotFormats = []string{"png", "svg", "pdf", "eps", "jpg", "jpeg", "tif", "tiff"}
+
+// drawStacked draws the plots stacked vertically, with their axes aligned
+func drawStacked(plots []*plot.Plot, dc draw.Canvas) {
+	// Fill the gaps between the panels with the plot background
+	dc.SetColor(plots[0].BackgroundColor)
+	dc.Fill(dc.Rectangle.Path())
+
+	for i, c := range stackedCanvases(plots, dc) {
+		plots[i].Draw(c)
+	}
+}
+
+// stackedCanvases returns the canvases of plots stacked vertically with their axes aligned
+func stackedCanvases(plots []*plot.Plot, dc draw.Canvas) []draw.Canvas {
+	rows := make([][]*plot.Plot, len(plots))
+	for i, panel := range plots {
+		rows[i] = []*plot.Plot{panel}
+	}
+	tiles := draw.Tiles{Rows: len(plots), Cols: 1, PadY: vg.Points(6)}
+	aligned := plot.Align(rows, tiles, dc)
+	canvases := make([]draw.Canvas, len(plots))
+	for i := range plots {
+		canvases[i] = aligned[i][0]
+	}
+	return canvases
+}
+
+// savePlot renders a plot with the given draw function in the given format (or the format
+// given by the output extension when empty) and writes it to the sink. Raster formats are
+// rendered at the dpi (0 = the gonum/plot default).
+func savePlot(render func(draw.Canvas), width, height vg.Length, dpi int, format, outputPath string) error {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(sink.Ext(outputPath)), ".")
+	}
+	if format == "" {
+		format = "png"
+	}
+
+	out, err := sink.Open(outputPath)
+	if err != nil {
+		return err
+	}
+	if err := writePlot(render, width, height, dpi, format, out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writePlot renders a plot with the given draw function in the given format and writes it to w
+func writePlot(render func(draw.Canvas), width, height vg.Length, dpi int, format string, w io.Writer) error {
+	c, err := newCanvas(width, height, dpi, format)
+	if err != nil {
+		return err
+	}
+	render(draw.New(c))
+	_, err = c.WriteTo(w)
+	return err
+}
+
+// newCanvas creates a canvas of the given format, with raster formats at the dpi (0 = the
+// gonum/plot default). Vector formats embed their fonts, so text keeps its size and metrics in
+// viewers and documents that don't have the plot fonts installed.
+func newCanvas(width, height vg.Length, dpi int, format string) (vg.CanvasWriterTo, error) {
+	if dpi <= 0 {
+		dpi = vgimg.DefaultDPI
+	}
+	switch format {
+	case "svg":
+		return vgsvg.NewWith(vgsvg.UseWH(width, height), vgsvg.EmbedFonts(true)), nil
+	case "pdf":
+		c := vgpdf.New(width, height)
+		c.EmbedFonts(true)
+		return c, nil
+	case "eps":
+		return draw.NewFormattedCanvas(width, height, format)
+	case "png":
+		return vgimg.PngCanvas{Canvas: vgimg.NewWith(vgimg.UseWH(width, height), vgimg.UseDPI(dpi))}, nil
+	case "jpg", "jpeg":
+		return vgimg.JpegCanvas{Canvas: vgimg.NewWith(vgimg.UseWH(width, height), vgimg.UseDPI(dpi))}, nil
+	case "tif", "tiff":
+		return vgimg.TiffCanvas{Canvas: vgimg.NewWith(vgimg.UseWH(width, height), vgimg.UseDPI(dpi))}, nil
+	default:
+		return nil, fmt.Errorf("unsupported plot format '%s', expected one of %s", format, strings.Join(PlotFormats, ", "))
+	}
 }
 
 // GeneratePlotFromFile generates a plot from an interleaved log file
-func GeneratePlotFromFile(logPath, configPath, outputPath string) error {
+func GeneratePlotFromFile(ctx context.Context, logPath, configPath string, configOpts config.Options, outputPath string) error {
 	// Load configuration
-	cfg, err := config.LoadConfig(configPath)
+	cfg, err := config.LoadConfig(configPath, configOpts)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -314,7 +702,7 @@ func GeneratePlotFromFile(logPath, configPath, outputPath string) error {
 	viz := NewVisualizer(cfg)
 
 	// Generate plot
-	return viz.GeneratePlot(lines, outputPath)
+	return viz.GeneratePlot(ctx, lines, outputPath)
 }
 
 // parseInterleavedLog parses an interleaved log file
@@ -325,47 +713,21 @@ func parseInterleavedLog(file *os.File) ([]*parser.LogLine, error) {
 	return nil, fmt.Errorf("not implemented - use Process() from interleaver instead")
 }
 
-// parseColor parses a color string (e.g., "blue", "red", "#FF0000") and returns a color.Color
-func parseColor(colorStr string) color.Color {
-	colorStr = strings.ToLower(strings.TrimSpace(colorStr))
-
-	// Named colors
-	switch colorStr {
-	case "black":
-		return color.RGBA{R: 0, G: 0, B: 0, A: 255}
-	case "white":
-		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
-	case "red":
-		return color.RGBA{R: 255, G: 0, B: 0, A: 255}
-	case "green":
-		return color.RGBA{R: 0, G: 255, B: 0, A: 255}
-	case "blue":
-		return color.RGBA{R: 0, G: 0, B: 255, A: 255}
-	case "yellow":
-		return color.RGBA{R: 255, G: 255, B: 0, A: 255}
-	case "cyan":
-		return color.RGBA{R: 0, G: 255, B: 255, A: 255}
-	case "magenta":
-		return color.RGBA{R: 255, G: 0, B: 255, A: 255}
-	case "orange":
-		return color.RGBA{R: 255, G: 165, B: 0, A: 255}
-	case "purple":
-		return color.RGBA{R: 128, G: 0, B: 128, A: 255}
-	case "brown":
-		return color.RGBA{R: 165, G: 42, B: 42, A: 255}
-	case "pink":
-		return color.RGBA{R: 255, G: 192, B: 203, A: 255}
-	case "gray", "grey":
-		return color.RGBA{R: 128, G: 128, B: 128, A: 255}
-	}
-
-	// Try parsing as hex color (#RRGGBB)
-	if strings.HasPrefix(colorStr, "#") && len(colorStr) == 7 {
-		var r, g, b uint8
-		if _, err := fmt.Sscanf(colorStr, "#%02x%02x%02x", &r, &g, &b); err == nil {
-			return color.RGBA{R: r, G: g, B: b, A: 255}
+// runMetadata returns the metadata of the run of the log lines for the title and label
+// templates of the config: the first and the last timestamp, the log directory, and the host
+func runMetadata(cfg *config.VisualizationConfig, lines []*parser.LogLine) config.RunMetadata {
+	var start, end time.Time
+	for _, line := range lines {
+		ts := line.GetTimestamp()
+		if ts == nil {
+			continue
+		}
+		if start.IsZero() || ts.Time.Before(start) {
+			start = ts.Time
+		}
+		if ts.Time.After(end) {
+			end = ts.Time
 		}
 	}
-
-	return nil // Return nil if color cannot be parsed
+	return cfg.RunMetadata(start, end)
 }
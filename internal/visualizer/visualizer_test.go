@@ -0,0 +1,78 @@
+package visualizer
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// titledPlot renders an empty plot with a title, so the output has text
+func titledPlot(dc draw.Canvas) {
+	p := plot.New()
+	p.Title.Text = "offset"
+	p.Draw(dc)
+}
+
+func TestWritePlot(t *testing.T) {
+	tests := []struct {
+		format string
+		magic  string // Start of the file
+		font   string // Marker of an embedded font, for vector formats
+	}{
+		{"svg", "<?xml", "@font-face"},
+		{"pdf", "%PDF-", "/FontFile"},
+		{"eps", "%%!PS-Adobe", ""}, // The header as gonum/plot writes it
+		{"png", "\x89PNG", ""},
+		{"jpg", "\xff\xd8\xff", ""},
+		{"jpeg", "\xff\xd8\xff", ""},
+		{"tiff", "II*\x00", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writePlot(titledPlot, 2*vg.Inch, vg.Inch, 0, tt.format, &out); err != nil {
+				t.Fatalf("writePlot: %v", err)
+			}
+			if !bytes.HasPrefix(out.Bytes(), []byte(tt.magic)) {
+				t.Errorf("output starts with %q, want %q", out.Bytes()[:min(out.Len(), 10)], tt.magic)
+			}
+			if tt.font != "" && !bytes.Contains(out.Bytes(), []byte(tt.font)) {
+				t.Errorf("output has no embedded font (%q)", tt.font)
+			}
+		})
+	}
+}
+
+func TestWritePlotDPI(t *testing.T) {
+	tests := []struct {
+		dpi   int
+		width int // Pixels of a 2 inch wide image
+	}{
+		{0, 192}, // gonum/plot default of 96 dpi
+		{100, 200},
+		{300, 600},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := writePlot(titledPlot, 2*vg.Inch, vg.Inch, tt.dpi, "png", &out); err != nil {
+			t.Fatalf("writePlot(dpi %d): %v", tt.dpi, err)
+		}
+		img, err := png.Decode(&out)
+		if err != nil {
+			t.Fatalf("dpi %d: invalid png: %v", tt.dpi, err)
+		}
+		if got := img.Bounds().Dx(); got != tt.width {
+			t.Errorf("dpi %d: width %d px, want %d", tt.dpi, got, tt.width)
+		}
+	}
+}
+
+func TestNewCanvasUnsupportedFormat(t *testing.T) {
+	if _, err := newCanvas(vg.Inch, vg.Inch, 0, "bmp"); err == nil {
+		t.Error("newCanvas(bmp) succeeded, want an error")
+	}
+}
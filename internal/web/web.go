@@ -0,0 +1,122 @@
+// Package web interleaves and plots log files held in memory, for the browser (WebAssembly)
+// build: the files come from the page instead of a directory, and the results are returned as
+// strings instead of being written to files
+package web
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log-interleaver/internal/config"
+	"log-interleaver/internal/interleaver"
+	"log-interleaver/internal/parser"
+	"log-interleaver/internal/visualizer"
+	"slices"
+	"strings"
+	"time"
+)
+
+// File is a log file dropped into the page
+type File struct {
+	Name    string
+	Data    []byte
+	ModTime time.Time // Reference for yearless timestamps (zero = now)
+}
+
+// Options control a run
+type Options struct {
+	Config     string // Optional YAML config: timestamp formats, tag rules, timezones, and patterns
+	Presets    string // Optional comma-separated pattern presets (e.g., "ptp4l,phc2sys")
+	TimeFormat string // Optional Go layout of the output timestamps
+	Tags       string // Optional comma-separated tags to keep
+	Grep       string // Optional regex lines must match
+	Theme      string // Optional theme of the plot: "light" or "dark"
+}
+
+// Result is the outcome of a run
+type Result struct {
+	Output string   // Interleaved lines
+	Lines  int      // Number of interleaved lines
+	Tags   []string // Tags of the files, in order
+	HTML   string   // Interactive plot page (Plot only)
+}
+
+// Interleave merges the files by their timestamps into the interleaved output
+func Interleave(ctx context.Context, files []File, opts Options) (*Result, error) {
+	lines, tags, _, err := process(ctx, files, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	for _, line := range lines {
+		out.WriteString(interleaver.FormatLine(line, opts.TimeFormat))
+		out.WriteByte('\n')
+	}
+	return &Result{Output: out.String(), Lines: len(lines), Tags: tags}, nil
+}
+
+// Plot merges the files and renders the interactive plot of the config's patterns, as a page
+// that loads Plotly.js from the CDN unless the bundle is compiled in
+func Plot(ctx context.Context, files []File, opts Options) (*Result, error) {
+	lines, tags, cfg, err := process(ctx, files, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Patterns) == 0 {
+		return nil, fmt.Errorf("no patterns to plot, add patterns to the config or select presets")
+	}
+
+	htmlOpts := visualizer.HTMLOptions{PlotlyMode: visualizer.PlotlyCDN, Theme: opts.Theme}
+	if visualizer.HasPlotlyBundle() {
+		htmlOpts.PlotlyMode = visualizer.PlotlyEmbed
+	}
+	var page bytes.Buffer
+	if err := visualizer.WriteInteractiveHTML(ctx, lines, cfg, &page, htmlOpts); err != nil {
+		return nil, err
+	}
+	return &Result{Lines: len(lines), Tags: tags, HTML: page.String()}, nil
+}
+
+// process reads the files and returns the filtered, interleaved lines with the tags of the
+// files and the config
+func process(ctx context.Context, files []File, opts Options) ([]*parser.LogLine, []string, *config.VisualizationConfig, error) {
+	if len(files) == 0 {
+		return nil, nil, nil, fmt.Errorf("no log files given")
+	}
+	presets, err := config.ParsePresets(opts.Presets)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cfg, err := config.ParseConfig("config.yaml", []byte(opts.Config), config.Options{Presets: presets})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Plugins are external programs, which a browser cannot run
+	if len(cfg.Plugins) > 0 {
+		return nil, nil, nil, fmt.Errorf("plugins are not supported in the browser")
+	}
+
+	iv := interleaver.NewInterleaver("")
+	if err := iv.ApplyConfig(cfg, nil); err != nil {
+		return nil, nil, nil, err
+	}
+	var tags []string
+	for _, f := range files {
+		tag := iv.FileTag(f.Name)
+		iv.AddReader(f.Name, tag, bytes.NewReader(f.Data), f.ModTime)
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	lines, err := iv.Process(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to process logs: %w", err)
+	}
+	filter, err := interleaver.NewFilter(config.SplitList(opts.Tags), nil, opts.Grep, "")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return filter.Apply(lines), tags, cfg, nil
+}
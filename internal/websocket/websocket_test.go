@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// clientFrame returns a frame as a client sends it, masked with the key of RFC 6455, section 5.7
+func clientFrame(fin bool, opcode byte, payload []byte) []byte {
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	if len(payload) < 126 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// dial opens a WebSocket to an echo server with the handshake of RFC 6455, section 1.3, and
+// returns the connection, its reader, and the read errors of the server
+func dial(t *testing.T) (net.Conn, *bufio.Reader, <-chan error) {
+	t.Helper()
+	errs := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer conn.conn.Close()
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := conn.WriteText(message); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	host := server.Listener.Addr().String()
+	request := "GET /live HTTP/1.1\r\n" +
+		"Host: " + host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Origin: http://" + host + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status %d, want 101", resp.StatusCode)
+	}
+	if got, want := resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Fatalf("Sec-WebSocket-Accept %q, want %q", got, want)
+	}
+	return conn, reader, errs
+}
+
+// expectFrame reads a frame from the server and compares it byte by byte
+func expectFrame(t *testing.T, reader *bufio.Reader, want []byte) {
+	t.Helper()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(reader, got); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("frame % x, want % x", got, want)
+	}
+}
+
+func TestConnFrames(t *testing.T) {
+	conn, reader, errs := dial(t)
+	send := func(frame []byte) {
+		if _, err := conn.Write(frame); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	// The masked and unmasked "Hello" frames of RFC 6455, section 5.7
+	send([]byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58})
+	expectFrame(t, reader, []byte{0x81, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f})
+
+	// A fragmented message, with a ping in between that is answered with a pong
+	send(clientFrame(false, opText, []byte("Hel")))
+	send(clientFrame(true, opPing, []byte("hi")))
+	send(clientFrame(true, opContinuation, []byte("lo")))
+	expectFrame(t, reader, []byte{0x8a, 0x02, 'h', 'i'})
+	expectFrame(t, reader, []byte{0x81, 0x05, 'H', 'e', 'l', 'l', 'o'})
+
+	// A 256-byte message takes the 16-bit length
+	long := bytes.Repeat([]byte("x"), 256)
+	send(clientFrame(true, opBinary, long))
+	expectFrame(t, reader, append([]byte{0x81, 0x7e, 0x01, 0x00}, long...))
+
+	// The close frame with status 1000 is echoed, and the server read ends
+	send(clientFrame(true, opClose, []byte{0x03, 0xe8, 'b', 'y', 'e'}))
+	expectFrame(t, reader, []byte{0x88, 0x02, 0x03, 0xe8})
+	if err := <-errs; !errors.Is(err, io.EOF) {
+		t.Errorf("server read error %v, want io.EOF", err)
+	}
+}
+
+func TestConnUnmaskedFrame(t *testing.T) {
+	conn, _, errs := dial(t)
+	if _, err := conn.Write([]byte{0x81, 0x05, 'H', 'e', 'l', 'l', 'o'}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "unmasked") {
+		t.Errorf("server read error %v, want an unmasked frame", err)
+	}
+}
+
+func TestUpgradeRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   int
+	}{
+		{"not an upgrade", map[string]string{"Sec-WebSocket-Version": "13"}, http.StatusBadRequest},
+		{"version", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8"}, http.StatusUpgradeRequired},
+		{"no key", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13"}, http.StatusBadRequest},
+		{"cross origin", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13",
+			"Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==", "Origin": "http://evil.example"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/live", nil)
+		for name, value := range tt.header {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		if _, err := Upgrade(w, r); err == nil || w.Code != tt.want {
+			t.Errorf("%s: status %d (%v), want %d and an error", tt.name, w.Code, err, tt.want)
+		}
+	}
+}
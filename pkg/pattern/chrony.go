@@ -0,0 +1,143 @@
+package pattern
+
+import (
+	"context"
+	"log-interleaver/internal/parser"
+	"strconv"
+	"strings"
+)
+
+// ChronyRecord is a line of the column logs of chronyd (log tracking measurements statistics),
+// e.g.:
+//
+//	2026-01-11 14:05:50 192.168.1.1      2     -3.970      0.131  6.123e-06 N  1  4.340e-06 -1.418e-06  4.608e-04  1.217e-04  2.330e-04
+//	2026-01-11 14:05:50 192.168.1.1     N  2 111 111 1111   6  6 0.00 -1.029e-04  2.567e-04  1.670e-06  1.190e-02  3.006e-04 C0A80101 4B D K
+//	2026-01-11 14:05:50 192.168.1.1     6.650e-06 -1.452e-06  1.242e-05 -1.231e-02  4.048e-01  7.9e-03  6   0   3  0.00
+//
+// Values are keyed by the Chrony* series names; offsets, delays, and dispersions are in seconds,
+// frequencies and skews in ppm.
+type ChronyRecord struct {
+	Log    string // "tracking", "measurements", or "statistics"
+	Source string // IP address or reference ID of the source
+	Values map[string]float64
+}
+
+// Series of the points of the chrony extractor
+const (
+	ChronyTrackingFreq           = "tracking_freq"
+	ChronyTrackingSkew           = "tracking_skew"
+	ChronyTrackingOffset         = "tracking_offset"
+	ChronyTrackingOffsetSD       = "tracking_offset_sd"
+	ChronyTrackingRemainingCorr  = "tracking_remaining_correction"
+	ChronyTrackingRootDelay      = "tracking_root_delay"
+	ChronyTrackingRootDispersion = "tracking_root_dispersion"
+	ChronyTrackingMaxError       = "tracking_max_error"
+
+	ChronyMeasurementsScore          = "measurements_score"
+	ChronyMeasurementsOffset         = "measurements_offset"
+	ChronyMeasurementsPeerDelay      = "measurements_peer_delay"
+	ChronyMeasurementsPeerDispersion = "measurements_peer_dispersion"
+	ChronyMeasurementsRootDelay      = "measurements_root_delay"
+	ChronyMeasurementsRootDispersion = "measurements_root_dispersion"
+
+	ChronyStatisticsStdDev    = "statistics_std_dev"
+	ChronyStatisticsEstOffset = "statistics_est_offset"
+	ChronyStatisticsOffsetSD  = "statistics_offset_sd"
+	ChronyStatisticsDiffFreq  = "statistics_diff_freq"
+	ChronyStatisticsEstSkew   = "statistics_est_skew"
+	ChronyStatisticsStress    = "statistics_stress"
+)
+
+// Columns after date, time, and source of each log, by series name ("" = not a number series)
+var (
+	chronyTrackingColumns = []string{"", ChronyTrackingFreq, ChronyTrackingSkew, ChronyTrackingOffset, "", "",
+		ChronyTrackingOffsetSD, ChronyTrackingRemainingCorr, ChronyTrackingRootDelay, ChronyTrackingRootDispersion, ChronyTrackingMaxError}
+	chronyMeasurementsColumns = []string{"", "", "", "", "", "", "", ChronyMeasurementsScore, ChronyMeasurementsOffset,
+		ChronyMeasurementsPeerDelay, ChronyMeasurementsPeerDispersion, ChronyMeasurementsRootDelay, ChronyMeasurementsRootDispersion}
+	chronyStatisticsColumns = []string{ChronyStatisticsStdDev, ChronyStatisticsEstOffset, ChronyStatisticsOffsetSD,
+		ChronyStatisticsDiffFreq, ChronyStatisticsEstSkew, ChronyStatisticsStress}
+)
+
+// ParseChronyLog parses a line of the tracking, measurements, or statistics log of chronyd.
+// The logs are told apart by their columns: tracking has the stratum then numbers, measurements
+// the leap status then the stratum and test bits, statistics numbers only. Banner lines are not
+// records.
+func ParseChronyLog(line string) (ChronyRecord, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 || !isChronyDate(fields[0]) {
+		return ChronyRecord{}, false
+	}
+	record := ChronyRecord{Source: fields[2], Values: make(map[string]float64)}
+	columns := fields[3:]
+	var names []string
+	switch {
+	case isLeapStatus(columns[0]) && len(columns) >= 13 && isTestBits(columns[2]):
+		record.Log, names = "measurements", chronyMeasurementsColumns
+	case isInteger(columns[0]) && len(columns) >= 4:
+		record.Log, names = "tracking", chronyTrackingColumns
+	case isNumber(columns[0]) && len(columns) >= 6:
+		record.Log, names = "statistics", chronyStatisticsColumns
+	default:
+		return ChronyRecord{}, false
+	}
+	for k, name := range names {
+		if name == "" || k >= len(columns) {
+			continue
+		}
+		if v, err := strconv.ParseFloat(columns[k], 64); err == nil {
+			record.Values[name] = v
+		}
+	}
+	if len(record.Values) == 0 {
+		return ChronyRecord{}, false
+	}
+	return record, true
+}
+
+// isChronyDate reports whether a field is a date as chronyd logs it (YYYY-MM-DD)
+func isChronyDate(field string) bool {
+	return len(field) == 10 && field[4] == '-' && field[7] == '-' && isInteger(field[:4])
+}
+
+// isLeapStatus reports whether a field is a leap status of the measurements log
+func isLeapStatus(field string) bool {
+	return field == "N" || field == "+" || field == "-" || field == "?"
+}
+
+// isTestBits reports whether a field is a group of test results (e.g., 111) of the measurements log
+func isTestBits(field string) bool {
+	return strings.Trim(field, "01") == ""
+}
+
+func isInteger(field string) bool {
+	_, err := strconv.Atoi(field)
+	return err == nil
+}
+
+func isNumber(field string) bool {
+	_, err := strconv.ParseFloat(field, 64)
+	return err == nil
+}
+
+// chronyExtractor extracts the columns of the chronyd logs
+type chronyExtractor struct{}
+
+// ExtractPoints returns the columns of each record as points of the Chrony* series
+func (chronyExtractor) ExtractPoints(ctx context.Context, lines []*parser.LogLine) ([][]MetricPoint, error) {
+	points := make([][]MetricPoint, len(lines))
+	for n, line := range lines {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		record, ok := ParseChronyLog(line.OriginalLine)
+		if !ok {
+			continue
+		}
+		for series, value := range record.Values {
+			points[n] = append(points[n], MetricPoint{Time: line.Timestamp.Time, Value: value, SeriesName: series})
+		}
+	}
+	return points, nil
+}
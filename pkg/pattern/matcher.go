@@ -1,63 +1,198 @@
 package pattern
 
 import (
+	"context"
 	"fmt"
 	"log-interleaver/internal/parser"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"time"
 )
 
 // MetricPoint represents a single data point extracted from a log line
 type MetricPoint struct {
-	Time      time.Time
-	Value     float64
-	State     string // Optional state value (e.g., "s0", "s2")
+	Time       time.Time
+	Value      float64
+	State      string // Optional state value (e.g., "s0", "s2"), or the label detail of an event
 	SeriesName string
+	Lane       string // Optional lane of an event drawn as a state lane (e.g., the port of a port state change)
 }
 
 // PatternMatcher extracts metrics from log lines based on regex patterns
 type PatternMatcher struct {
-	patterns []CompiledPattern
+	patterns  []CompiledPattern
+	stats     map[string]ExtractionStats                            // Statistics from the last ExtractMetrics call
+	extracted map[MetricExtractor]map[*parser.LogLine][]MetricPoint // Points of the extractors from the last Prepare call
+	found     map[int]map[string]bool                               // States of the current match pass missing from the mappings, by pattern
+}
+
+// MetricExtractor extracts points from lines of formats regexes cannot handle, such as an
+// external plugin. It returns the points of each line, named by the series they belong to.
+type MetricExtractor interface {
+	ExtractPoints(ctx context.Context, lines []*parser.LogLine) ([][]MetricPoint, error)
 }
 
 // CompiledPattern is a compiled regex pattern with metadata
 type CompiledPattern struct {
-	Name         string
-	Regex        *regexp.Regexp
-	TagFilter    string
-	ValueGroup   int
-	StateGroup   int
-	StateMapping map[string]float64
-	Color        string
-	LineStyle    string
-	Marker       string
-	YAxisLabel   string
-	YAxisIndex   int
+	Name            string
+	Regex           *regexp.Regexp
+	TagFilter       string
+	Field           string // Match against this structured log field instead of the whole line
+	ValueGroup      int
+	StateGroup      int
+	StateMapping    map[string]float64
+	DiscoverStates  bool // States missing from the configured mapping are discovered from the data (state series)
+	Color           string
+	LineStyle       string
+	Marker          string
+	YAxisLabel      string
+	YAxisIndex      int
+	Dedup           DedupPolicy
+	Transform       Transform   // Conversion applied to the series after deduplication
+	MinValue        *float64    // Points below are dropped or clamped, before deduplication
+	MaxValue        *float64    // Points above are dropped or clamped, before deduplication
+	OutlierSigma    float64     // Points further from the median (in robust standard deviations) are dropped or clamped
+	Clamp           bool        // Clamp points outside the bounds instead of dropping them
+	Unit            string      // Unit of the values after conversion ("" = unknown)
+	UnitScale       float64     // Factor converting the extracted values to Unit
+	Rolling         RollingStat // Rolling statistic computed from the series (after the transform)
+	RollingWindow   time.Duration
+	RollingSigma    float64
+	Event           bool            // Matches are discrete events without a value
+	LabelGroup      int             // Optional: capture group with the event label detail (stored in MetricPoint.State)
+	LaneGroup       int             // Optional: capture group with the lane of the event (stored in MetricPoint.Lane)
+	Extractor       MetricExtractor // Extracts the points instead of the regex
+	ExtractorSeries string          // Series name of the extractor's points that belong to the pattern
+
+	configuredStates map[string]float64 // Configured state mapping, which discovered states extend
+}
+
+// DedupPolicy selects how points of a series sharing the same timestamp are collapsed
+type DedupPolicy string
+
+const (
+	DedupNone  DedupPolicy = ""      // Keep all points
+	DedupFirst DedupPolicy = "first" // Keep the first point at each timestamp
+	DedupLast  DedupPolicy = "last"  // Keep the last point at each timestamp
+	DedupMean  DedupPolicy = "mean"  // Replace points at each timestamp with their mean value
+)
+
+// Transform selects a conversion of the values of a series, e.g., from counters to rates
+type Transform string
+
+const (
+	TransformNone       Transform = ""           // Keep the values
+	TransformRate       Transform = "rate"       // Per-second increase of a counter; counter resets count from zero
+	TransformDerivative Transform = "derivative" // Per-second change of the value (may be negative)
+	TransformCumulative Transform = "cumulative" // Running sum of the values
+)
+
+// RollingStat selects a rolling statistic of a series over a trailing time window
+type RollingStat string
+
+const (
+	RollingNone  RollingStat = ""      // Keep the values
+	RollingMean  RollingStat = "mean"  // Moving average
+	RollingUpper RollingStat = "upper" // Moving average plus sigma standard deviations
+	RollingLower RollingStat = "lower" // Moving average minus sigma standard deviations
+)
+
+// ExtractionStats reports how a pattern performed during metric extraction
+type ExtractionStats struct {
+	Matches    int // Lines matched by the pattern
+	Points     int // Points in the series after deduplication and transform
+	Duplicates int // Points collapsed by deduplication
+	Filtered   int // Points dropped or clamped by the value bounds and outlier rejection
 }
 
 // NewPatternMatcher creates a new pattern matcher from configuration
 func NewPatternMatcher(patterns []PatternConfig) (*PatternMatcher, error) {
 	compiled := make([]CompiledPattern, 0, len(patterns))
 
+	// Patterns with the same expression share the compiled regex, so a line is only matched
+	// once for all of them (e.g., several series extracted from one summary line)
+	regexes := make(map[string]*regexp.Regexp)
 	for _, p := range patterns {
-		regex, err := regexp.Compile(p.Regex)
-		if err != nil {
-			return nil, fmt.Errorf("invalid regex pattern '%s': %w", p.Regex, err)
+		expr := p.Regex
+		if expr == "" && p.Field != "" {
+			// Without a regex, the whole field value is the value
+			expr = `(?s)^.*$`
+		}
+		regex, ok := regexes[expr]
+		if !ok && (p.Extractor == nil || expr != "") {
+			var err error
+			if regex, err = regexp.Compile(expr); err != nil {
+				return nil, fmt.Errorf("invalid regex pattern '%s': %w", p.Regex, err)
+			}
+			regexes[expr] = regex
+		}
+
+		transform := Transform(p.Transform)
+		switch transform {
+		case TransformNone, TransformRate, TransformDerivative, TransformCumulative:
+		default:
+			return nil, fmt.Errorf("invalid transform '%s' for pattern '%s', expected rate, derivative, or cumulative", p.Transform, p.Name)
+		}
+
+		dedup := DedupPolicy(p.Dedup)
+		switch dedup {
+		case DedupNone, DedupFirst, DedupLast, DedupMean:
+		default:
+			return nil, fmt.Errorf("invalid dedup policy '%s' for pattern '%s', expected first, last, or mean", p.Dedup, p.Name)
+		}
+
+		if p.MinValue != nil && p.MaxValue != nil && *p.MinValue > *p.MaxValue {
+			return nil, fmt.Errorf("invalid bounds for pattern '%s': min_value %g is above max_value %g", p.Name, *p.MinValue, *p.MaxValue)
+		}
+		if p.OutlierSigma < 0 {
+			return nil, fmt.Errorf("invalid outlier_sigma %g for pattern '%s', expected a positive number of standard deviations", p.OutlierSigma, p.Name)
+		}
+
+		unitScale, displayUnit := 1.0, p.Unit
+		if p.DisplayUnit != "" {
+			displayUnit = p.DisplayUnit
+		}
+		if p.Unit != "" {
+			var err error
+			if unitScale, err = UnitScale(p.Unit, displayUnit); err != nil {
+				return nil, fmt.Errorf("invalid unit for pattern '%s': %w", p.Name, err)
+			}
 		}
 
 		compiled = append(compiled, CompiledPattern{
-			Name:         p.Name,
-			Regex:        regex,
-			TagFilter:    p.TagFilter,
-			ValueGroup:   p.ValueGroup,
-			StateGroup:   p.StateGroup,
-			StateMapping: p.StateMapping,
-			Color:        p.Color,
-			LineStyle:    p.LineStyle,
-			Marker:       p.Marker,
-			YAxisLabel:   p.YAxisLabel,
-			YAxisIndex:   p.YAxisIndex,
+			Name:            p.Name,
+			Regex:           regex,
+			TagFilter:       p.TagFilter,
+			Field:           p.Field,
+			ValueGroup:      p.ValueGroup,
+			StateGroup:      p.StateGroup,
+			StateMapping:    p.StateMapping,
+			DiscoverStates:  IsStateSeries(p.StateGroup, p.ValueGroup, p.Event, p.Extractor != nil),
+			Color:           p.Color,
+			LineStyle:       p.LineStyle,
+			Marker:          p.Marker,
+			YAxisLabel:      p.YAxisLabel,
+			YAxisIndex:      p.YAxisIndex,
+			Dedup:           dedup,
+			Transform:       transform,
+			MinValue:        p.MinValue,
+			MaxValue:        p.MaxValue,
+			OutlierSigma:    p.OutlierSigma,
+			Clamp:           p.Clamp,
+			Unit:            displayUnit,
+			UnitScale:       unitScale,
+			Rolling:         RollingStat(p.Rolling),
+			RollingWindow:   p.RollingWindow,
+			RollingSigma:    p.RollingSigma,
+			Event:           p.Event,
+			LabelGroup:      p.LabelGroup,
+			LaneGroup:       p.LaneGroup,
+			Extractor:       p.Extractor,
+			ExtractorSeries: p.ExtractorSeries,
+
+			configuredStates: p.StateMapping,
 		})
 	}
 
@@ -66,114 +201,485 @@ func NewPatternMatcher(patterns []PatternConfig) (*PatternMatcher, error) {
 
 // PatternConfig is the configuration for a pattern (imported from config package)
 type PatternConfig struct {
-	Name         string
-	Regex        string
-	TagFilter    string
-	ValueGroup   int
-	StateGroup   int
-	StateMapping map[string]float64
-	Color        string
-	LineStyle    string
-	Marker       string
-	YAxisLabel   string
-	YAxisIndex   int
-}
-
-// ExtractMetrics processes log lines and extracts metrics based on patterns
-func (pm *PatternMatcher) ExtractMetrics(lines []*parser.LogLine) (map[string][]MetricPoint, error) {
+	Name            string
+	Regex           string
+	TagFilter       string
+	Field           string // Match against this structured log field instead of the whole line
+	ValueGroup      int
+	StateGroup      int
+	StateMapping    map[string]float64
+	Color           string
+	LineStyle       string
+	Marker          string
+	YAxisLabel      string
+	YAxisIndex      int
+	Dedup           string
+	Transform       string
+	MinValue        *float64 // Optional: lower bound of the values
+	MaxValue        *float64 // Optional: upper bound of the values
+	OutlierSigma    float64  // Optional: outlier threshold in robust standard deviations from the median
+	Clamp           bool     // Clamp points outside the bounds instead of dropping them
+	Unit            string   // Optional: unit of the extracted values (e.g., "ps")
+	DisplayUnit     string   // Optional: unit the values are converted to (default: Unit)
+	Rolling         string   // Rolling statistic: "mean", "upper", or "lower"
+	RollingWindow   time.Duration
+	RollingSigma    float64
+	Event           bool            // Matches are discrete events without a value
+	LabelGroup      int             // Optional: capture group with the event label detail
+	LaneGroup       int             // Optional: capture group with the lane of the event
+	Extractor       MetricExtractor // Optional: extracts the points instead of the regex, which then only selects the lines
+	ExtractorSeries string          // Series name of the extractor's points that belong to the pattern
+}
+
+// cancelCheckInterval is the number of lines between checks for a cancelled context
+const cancelCheckInterval = 4096
+
+// ExtractMetrics processes log lines and extracts metrics based on patterns. It stops with the
+// context's error when the context is cancelled.
+func (pm *PatternMatcher) ExtractMetrics(ctx context.Context, lines []*parser.LogLine) (map[string][]MetricPoint, error) {
+	if err := pm.runExtractors(ctx, lines); err != nil {
+		return nil, err
+	}
 	metrics := make(map[string][]MetricPoint)
+	pm.stats = make(map[string]ExtractionStats)
 
-	for _, line := range lines {
-		// Skip lines without timestamps
-		if line.Timestamp == nil {
+	// The states missing from the mappings are collected while matching, and the points of
+	// their series get their levels once all states are known
+	pm.resetStates()
+	for n, line := range lines {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		for _, point := range pm.MatchLine(line) {
+			metrics[point.SeriesName] = append(metrics[point.SeriesName], point)
+		}
+	}
+	for _, k := range pm.discoverStates() {
+		pattern := pm.patterns[k]
+		for i, pt := range metrics[pattern.Name] {
+			metrics[pattern.Name][i].Value = pattern.StateMapping[pt.State]
+		}
+	}
+
+	// Collapse duplicate timestamps and record statistics
+	for _, pattern := range pm.patterns {
+		points, ok := metrics[pattern.Name]
+		if !ok {
 			continue
 		}
+		stats := ExtractionStats{Matches: len(points)}
+		if pattern.hasValueFilter() && !pattern.Event {
+			points, stats.Filtered = filterPoints(points, pattern)
+			metrics[pattern.Name] = points
+		}
+		extracted := len(points)
+		if pattern.Dedup != DedupNone {
+			points = dedupPoints(points, pattern.Dedup)
+			metrics[pattern.Name] = points
+		}
+		stats.Duplicates = extracted - len(points)
+		if pattern.Transform != TransformNone && !pattern.Event {
+			points = transformPoints(points, pattern.Transform)
+			metrics[pattern.Name] = points
+		}
+		if pattern.Rolling != RollingNone && !pattern.Event {
+			points = rollingPoints(points, pattern.Rolling, pattern.RollingWindow, pattern.RollingSigma)
+			metrics[pattern.Name] = points
+		}
+		stats.Points = len(points)
+		pm.stats[pattern.Name] = stats
+	}
 
-		// Try each pattern
-		for _, pattern := range pm.patterns {
-			// Check tag filter
-			if pattern.TagFilter != "" && line.Tag != pattern.TagFilter {
-				continue
+	return metrics, nil
+}
+
+// Prepare runs the extractors of the patterns over the timestamped lines their tag filters
+// accept, for MatchLine to look up, and discovers the states of state series missing from their
+// mappings. Callers of MatchLine need to call it first when patterns have extractors or are state
+// series; ExtractMetrics does the same while matching.
+func (pm *PatternMatcher) Prepare(ctx context.Context, lines []*parser.LogLine) error {
+	if err := pm.runExtractors(ctx, lines); err != nil {
+		return err
+	}
+	pm.resetStates()
+	for n, line := range lines {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
+		}
+		pm.MatchLine(line)
+	}
+	pm.discoverStates()
+	return nil
+}
+
+// runExtractors runs the extractors of the patterns over the timestamped lines their tag filters
+// accept, for MatchLine to look up
+func (pm *PatternMatcher) runExtractors(ctx context.Context, lines []*parser.LogLine) error {
+	pm.extracted = nil
+	tagFilters := make(map[MetricExtractor][]string)
+	var extractors []MetricExtractor
+	for _, pattern := range pm.patterns {
+		if pattern.Extractor == nil {
+			continue
+		}
+		if _, ok := tagFilters[pattern.Extractor]; !ok {
+			extractors = append(extractors, pattern.Extractor)
+		}
+		tagFilters[pattern.Extractor] = append(tagFilters[pattern.Extractor], pattern.TagFilter)
+	}
 
-			// Match pattern
-			matches := pattern.Regex.FindStringSubmatch(line.OriginalLine)
-			if len(matches) == 0 {
+	for _, extractor := range extractors {
+		var selected []*parser.LogLine
+		for _, line := range lines {
+			if line.Timestamp == nil {
 				continue
 			}
+			for _, tag := range tagFilters[extractor] {
+				if tag == "" || tag == line.Tag {
+					selected = append(selected, line)
+					break
+				}
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+		points, err := extractor.ExtractPoints(ctx, selected)
+		if err != nil {
+			return err
+		}
+		byLine := make(map[*parser.LogLine][]MetricPoint, len(selected))
+		for k, linePoints := range points {
+			if k < len(selected) && len(linePoints) > 0 {
+				byLine[selected[k]] = linePoints
+			}
+		}
+		if pm.extracted == nil {
+			pm.extracted = make(map[MetricExtractor]map[*parser.LogLine][]MetricPoint)
+		}
+		pm.extracted[extractor] = byLine
+	}
+	return nil
+}
 
-			// Extract value
-			if pattern.ValueGroup >= len(matches) {
+// MatchLine returns the points the patterns extract from a single line, before deduplication,
+// transforms, and rolling statistics. Lines without timestamps have no points.
+func (pm *PatternMatcher) MatchLine(line *parser.LogLine) []MetricPoint {
+	if line.Timestamp == nil {
+		return nil
+	}
+
+	var points []MetricPoint
+	// Try each pattern, reusing the match of the previous pattern with the same regex and text
+	var lastRegex *regexp.Regexp
+	var lastText string
+	var lastMatches []string
+	for k, pattern := range pm.patterns {
+		// Check tag filter
+		if pattern.TagFilter != "" && line.Tag != pattern.TagFilter {
+			continue
+		}
+
+		// Take the points of the pattern's series from its extractor, from the lines matching
+		// the regex, if any
+		if pattern.Extractor != nil {
+			if pattern.Regex != nil && !pattern.Regex.MatchString(line.OriginalLine) {
 				continue
 			}
-
-			valueStr := matches[pattern.ValueGroup]
-			
-			// Extract state if configured
-			state := ""
-			if pattern.StateGroup > 0 && pattern.StateGroup < len(matches) {
-				state = matches[pattern.StateGroup]
-			}
-			
-			var value float64
-			var valueParsed bool
-			
-			// If this is a state series (state_group is set and matches value_group), handle state mapping first
-			if pattern.StateGroup > 0 && pattern.StateGroup == pattern.ValueGroup {
-				// This is a state series - use state mapping or extract from state string
-				if pattern.StateMapping != nil {
-					if mappedValue, ok := pattern.StateMapping[valueStr]; ok {
-						value = mappedValue
-						valueParsed = true
-					} else {
-						// Fallback: try to extract numeric part from state string (e.g., "s0" -> 0)
-						if len(valueStr) > 1 && valueStr[0] == 's' {
-							if stateVal, err := strconv.ParseFloat(valueStr[1:], 64); err == nil {
-								value = stateVal
-								valueParsed = true
-							}
-						}
-					}
-				} else {
-					// No mapping configured, try to extract numeric part (e.g., "s0" -> 0)
-					if len(valueStr) > 1 && valueStr[0] == 's' {
-						if stateVal, err := strconv.ParseFloat(valueStr[1:], 64); err == nil {
-							value = stateVal
-							valueParsed = true
-						}
-					}
+			for _, point := range pm.extracted[pattern.Extractor][line] {
+				if point.SeriesName != pattern.ExtractorSeries {
+					continue
 				}
-				
-				if !valueParsed {
-					continue // Skip if we can't map/parse the state
+				point.Time, point.SeriesName = line.Timestamp.Time, pattern.Name
+				if pattern.Event {
+					point.Value = 0
+				} else if point.State == "" {
+					if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+						continue // Left out like non-finite parsed values
+					}
+					point.Value *= pattern.UnitScale
 				}
+				points = append(points, point)
+			}
+			continue
+		}
+
+		// Match pattern against the line, or the configured structured log field
+		text := line.OriginalLine
+		if pattern.Field != "" {
+			value, ok := line.Fields[pattern.Field]
+			if !ok {
+				continue
+			}
+			text = value
+		}
+		matches := lastMatches
+		if pattern.Regex != lastRegex || text != lastText {
+			matches = pattern.Regex.FindStringSubmatch(text)
+			lastRegex, lastText, lastMatches = pattern.Regex, text, matches
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		// Events have no value; the optional label group details the event
+		if pattern.Event {
+			point := MetricPoint{Time: line.Timestamp.Time, SeriesName: pattern.Name}
+			if pattern.LabelGroup > 0 && pattern.LabelGroup < len(matches) {
+				point.State = matches[pattern.LabelGroup]
+			}
+			if pattern.LaneGroup > 0 && pattern.LaneGroup < len(matches) {
+				point.Lane = matches[pattern.LaneGroup]
+			}
+			points = append(points, point)
+			continue
+		}
+
+		// Extract value
+		if pattern.ValueGroup >= len(matches) {
+			continue
+		}
+
+		valueStr := matches[pattern.ValueGroup]
+
+		// Extract state if configured
+		state := ""
+		if pattern.StateGroup > 0 && pattern.StateGroup < len(matches) {
+			state = matches[pattern.StateGroup]
+		}
+
+		var value float64
+		// A state series takes the level of the state from the mapping. States missing from it
+		// are recorded, and get their levels when the states of the pass are known.
+		if pattern.StateGroup > 0 && pattern.StateGroup == pattern.ValueGroup {
+			if mappedValue, ok := pattern.StateMapping[valueStr]; ok {
+				value = mappedValue
+			} else if pattern.DiscoverStates {
+				pm.foundState(k, valueStr)
 			} else {
-				// Regular numeric value - try to parse as float/int
-				var err error
-				value, err = strconv.ParseFloat(valueStr, 64)
-				if err != nil {
-					// Try parsing as integer first
-					if intVal, err2 := strconv.ParseInt(valueStr, 10, 64); err2 == nil {
-						value = float64(intVal)
-						valueParsed = true
-					} else {
-						continue // Skip if we can't parse the value
-					}
-				} else {
-					valueParsed = true
+				continue // Skip if we can't map the state
+			}
+		} else {
+			// Regular numeric value - try to parse as float/int
+			var err error
+			value, err = strconv.ParseFloat(valueStr, 64)
+			if err != nil {
+				// Try parsing as integer first
+				intVal, err2 := strconv.ParseInt(valueStr, 10, 64)
+				if err2 != nil {
+					continue // Skip if we can't parse the value
 				}
+				value = float64(intVal)
+			}
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue // E.g., "NaN" in a CSV column, which no renderer or statistic takes
 			}
+			value *= pattern.UnitScale
+		}
+
+		point := MetricPoint{
+			Time:       line.Timestamp.Time,
+			Value:      value,
+			State:      state,
+			SeriesName: pattern.Name,
+		}
+
+		points = append(points, point)
+	}
+	return points
+}
+
+// Stats returns per-series extraction statistics from the last ExtractMetrics call
+func (pm *PatternMatcher) Stats() map[string]ExtractionStats {
+	return pm.stats
+}
+
+// hasValueFilter reports whether the pattern bounds its values or rejects outliers
+func (p CompiledPattern) hasValueFilter() bool {
+	return p.MinValue != nil || p.MaxValue != nil || p.OutlierSigma > 0
+}
+
+// filterPoints drops the points outside the value bounds of the pattern, then the outliers:
+// points further from the median than OutlierSigma robust standard deviations (1.4826 times the
+// median absolute deviation, which a few huge spikes do not inflate; the standard deviation when
+// most values are equal). With Clamp the points are moved to the bounds instead. It returns the
+// points and how many were dropped or clamped.
+func filterPoints(points []MetricPoint, p CompiledPattern) ([]MetricPoint, int) {
+	low, high := math.Inf(-1), math.Inf(1)
+	if p.MinValue != nil {
+		low = *p.MinValue
+	}
+	if p.MaxValue != nil {
+		high = *p.MaxValue
+	}
+	result, filtered := boundPoints(points, low, high, p.Clamp)
+	if p.OutlierSigma > 0 && len(result) > 0 {
+		center, spread := robustSpread(result)
+		if spread > 0 {
+			var outliers int
+			result, outliers = boundPoints(result, center-p.OutlierSigma*spread, center+p.OutlierSigma*spread, p.Clamp)
+			filtered += outliers
+		}
+	}
+	return result, filtered
+}
+
+// boundPoints drops (or clamps) the points outside [low, high]
+func boundPoints(points []MetricPoint, low, high float64, clamp bool) ([]MetricPoint, int) {
+	result := make([]MetricPoint, 0, len(points))
+	filtered := 0
+	for _, pt := range points {
+		if pt.Value >= low && pt.Value <= high {
+			result = append(result, pt)
+			continue
+		}
+		filtered++
+		if clamp {
+			pt.Value = math.Max(low, math.Min(high, pt.Value))
+			result = append(result, pt)
+		}
+	}
+	return result, filtered
+}
+
+// robustSpread returns the median of the values and their robust standard deviation
+func robustSpread(points []MetricPoint) (median, spread float64) {
+	values := make([]float64, len(points))
+	for i, pt := range points {
+		values[i] = pt.Value
+	}
+	median = medianOf(values)
+	deviations := make([]float64, len(values))
+	var sumSq float64
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+		sumSq += (v - median) * (v - median)
+	}
+	if mad := medianOf(deviations); mad > 0 {
+		return median, 1.4826 * mad
+	}
+	return median, math.Sqrt(sumSq / float64(len(values)))
+}
+
+// medianOf returns the median of the values, reordering them
+func medianOf(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
 
-			point := MetricPoint{
-				Time:       line.Timestamp.Time,
-				Value:      value,
-				State:      state,
-				SeriesName: pattern.Name,
+// dedupPoints collapses points sharing the same timestamp according to the policy
+func dedupPoints(points []MetricPoint, policy DedupPolicy) []MetricPoint {
+	sorted := make([]MetricPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	result := make([]MetricPoint, 0, len(sorted))
+	for start := 0; start < len(sorted); {
+		end := start + 1
+		for end < len(sorted) && sorted[end].Time.Equal(sorted[start].Time) {
+			end++
+		}
+
+		group := sorted[start:end]
+		switch policy {
+		case DedupFirst:
+			result = append(result, group[0])
+		case DedupLast:
+			result = append(result, group[len(group)-1])
+		case DedupMean:
+			point := group[0]
+			sum := 0.0
+			for _, pt := range group {
+				sum += pt.Value
 			}
+			point.Value = sum / float64(len(group))
+			result = append(result, point)
+		}
+		start = end
+	}
 
-			metrics[pattern.Name] = append(metrics[pattern.Name], point)
+	return result
+}
+
+// transformPoints converts the values of a series. Rates and derivatives are placed at the
+// later point of each pair, so the series loses its first point.
+func transformPoints(points []MetricPoint, transform Transform) []MetricPoint {
+	sorted := make([]MetricPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	if transform == TransformCumulative {
+		sum := 0.0
+		for i := range sorted {
+			sum += sorted[i].Value
+			sorted[i].Value = sum
 		}
+		return sorted
 	}
 
-	return metrics, nil
+	result := make([]MetricPoint, 0, len(sorted))
+	for i := 1; i < len(sorted); i++ {
+		dt := sorted[i].Time.Sub(sorted[i-1].Time).Seconds()
+		if dt <= 0 {
+			continue // No rate between points at the same timestamp
+		}
+		delta := sorted[i].Value - sorted[i-1].Value
+		if transform == TransformRate && delta < 0 {
+			// The counter was reset (e.g., process restart) and counts from zero again
+			delta = sorted[i].Value
+		}
+		point := sorted[i]
+		point.Value = delta / dt
+		result = append(result, point)
+	}
+	return result
+}
+
+// rollingPoints replaces each value by a statistic of the values in the trailing window
+// (t-window, t], so each point only depends on the points up to it
+func rollingPoints(points []MetricPoint, stat RollingStat, window time.Duration, sigma float64) []MetricPoint {
+	sorted := make([]MetricPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	result := make([]MetricPoint, len(sorted))
+	var sum, sumSq float64
+	start := 0
+	for i, pt := range sorted {
+		sum += pt.Value
+		sumSq += pt.Value * pt.Value
+		for sorted[start].Time.Add(window).Compare(pt.Time) <= 0 {
+			sum -= sorted[start].Value
+			sumSq -= sorted[start].Value * sorted[start].Value
+			start++
+		}
+
+		n := float64(i - start + 1)
+		mean := sum / n
+		stddev := math.Sqrt(math.Max(0, sumSq/n-mean*mean))
+		result[i] = pt
+		switch stat {
+		case RollingMean:
+			result[i].Value = mean
+		case RollingUpper:
+			result[i].Value = mean + sigma*stddev
+		case RollingLower:
+			result[i].Value = mean - sigma*stddev
+		}
+	}
+	return result
 }
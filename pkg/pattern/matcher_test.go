@@ -0,0 +1,325 @@
+package pattern
+
+import (
+	"context"
+	"maps"
+	"math"
+	"testing"
+	"time"
+
+	"log-interleaver/internal/parser"
+	"log-interleaver/pkg/timestamp"
+)
+
+var lineStart = time.Date(2026, 1, 11, 14, 0, 0, 0, time.UTC)
+
+// timedLines returns lines of the tag, one per second from lineStart
+func timedLines(tag string, texts ...string) []*parser.LogLine {
+	lines := make([]*parser.LogLine, len(texts))
+	for i, text := range texts {
+		lines[i] = &parser.LogLine{
+			OriginalLine: text,
+			Tag:          tag,
+			LineNumber:   i + 1,
+			Timestamp:    &timestamp.Timestamp{Time: lineStart.Add(time.Duration(i) * time.Second), Type: timestamp.TypeAbsolute},
+		}
+	}
+	return lines
+}
+
+// extract returns the points of the patterns in the lines
+func extract(t *testing.T, patterns []PatternConfig, lines []*parser.LogLine) map[string][]MetricPoint {
+	t.Helper()
+	pm, err := NewPatternMatcher(patterns)
+	if err != nil {
+		t.Fatalf("NewPatternMatcher: %v", err)
+	}
+	metrics, err := pm.ExtractMetrics(context.Background(), lines)
+	if err != nil {
+		t.Fatalf("ExtractMetrics: %v", err)
+	}
+	return metrics
+}
+
+// values returns the values of points
+func values(points []MetricPoint) []float64 {
+	v := make([]float64, len(points))
+	for i, pt := range points {
+		v[i] = pt.Value
+	}
+	return v
+}
+
+func equalValues(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExtractMetricsValueGroups(t *testing.T) {
+	lines := timedLines("daemon",
+		"ptp4l[1.0]: master offset -12 s2 freq +100 path delay 500",
+		"ptp4l[2.0]: master offset 7 s2 freq -50 path delay 501",
+		"phc2sys[3.0]: CLOCK_REALTIME phc offset 3 s2 freq 0 delay 0",
+		"ptp4l[4.0]: master offset NaN s2 freq +Inf path delay 502",
+		"ptp4l[5.0]: master offset 1.5e3 s2 freq 7 path delay 503",
+	)
+	tests := []struct {
+		name    string
+		pattern PatternConfig
+		want    []float64
+	}{
+		{
+			name:    "value group",
+			pattern: PatternConfig{Name: "offset", Regex: `master offset\s+(\S+)`, ValueGroup: 1},
+			want:    []float64{-12, 7, 1500}, // NaN left out
+		},
+		{
+			name:    "second group",
+			pattern: PatternConfig{Name: "freq", Regex: `master offset\s+\S+\s+s\d\s+freq\s+(\S+)`, ValueGroup: 1},
+			want:    []float64{100, -50, 7}, // +Inf left out
+		},
+		{
+			name:    "unit conversion",
+			pattern: PatternConfig{Name: "delay", Regex: `path delay\s+(\d+)`, ValueGroup: 1, Unit: "ns", DisplayUnit: "us"},
+			want:    []float64{0.5, 0.501, 0.502, 0.503},
+		},
+		{
+			name:    "tag filter",
+			pattern: PatternConfig{Name: "phc", Regex: `offset\s+(-?\d+)`, ValueGroup: 1, TagFilter: "e810"},
+			want:    []float64{},
+		},
+		{
+			name:    "no such group",
+			pattern: PatternConfig{Name: "missing", Regex: `master offset\s+(\S+)`, ValueGroup: 2},
+			want:    []float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := extract(t, []PatternConfig{tt.pattern}, lines)
+			if got := values(metrics[tt.pattern.Name]); !equalValues(got, tt.want) {
+				t.Errorf("values %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// bound returns a pointer to the value bound v
+func bound(v float64) *float64 {
+	return &v
+}
+
+func TestExtractMetricsValueFilters(t *testing.T) {
+	lines := timedLines("daemon",
+		"offset 10", "offset 12", "offset 11", "offset 9", "offset 500", "offset -3", "offset 10",
+	)
+	// The median is 10 and the median absolute deviation 1, which the spike does not inflate
+	const spread = 1.4826
+	tests := []struct {
+		name         string
+		pattern      PatternConfig
+		want         []float64
+		wantFiltered int
+	}{
+		{
+			name:         "bounds",
+			pattern:      PatternConfig{MinValue: bound(0), MaxValue: bound(100)},
+			want:         []float64{10, 12, 11, 9, 10},
+			wantFiltered: 2,
+		},
+		{
+			name:         "bounds clamped",
+			pattern:      PatternConfig{MinValue: bound(0), MaxValue: bound(100), Clamp: true},
+			want:         []float64{10, 12, 11, 9, 100, 0, 10},
+			wantFiltered: 2,
+		},
+		{
+			name:         "outliers",
+			pattern:      PatternConfig{OutlierSigma: 3},
+			want:         []float64{10, 12, 11, 9, 10},
+			wantFiltered: 2,
+		},
+		{
+			name:         "outliers clamped",
+			pattern:      PatternConfig{OutlierSigma: 3, Clamp: true},
+			want:         []float64{10, 12, 11, 9, 10 + 3*spread, 10 - 3*spread, 10},
+			wantFiltered: 2,
+		},
+		{
+			name: "no filter",
+			want: []float64{10, 12, 11, 9, 500, -3, 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.pattern
+			p.Name, p.Regex, p.ValueGroup = "offset", `offset (-?\d+)`, 1
+			pm, err := NewPatternMatcher([]PatternConfig{p})
+			if err != nil {
+				t.Fatalf("NewPatternMatcher: %v", err)
+			}
+			metrics, err := pm.ExtractMetrics(context.Background(), lines)
+			if err != nil {
+				t.Fatalf("ExtractMetrics: %v", err)
+			}
+			if got := values(metrics["offset"]); !equalValues(got, tt.want) {
+				t.Errorf("values %v, want %v", got, tt.want)
+			}
+			if got := pm.Stats()["offset"].Filtered; got != tt.wantFiltered {
+				t.Errorf("%d filtered, want %d", got, tt.wantFiltered)
+			}
+		})
+	}
+}
+
+func TestNewPatternMatcherInvalidFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern PatternConfig
+	}{
+		{"min above max", PatternConfig{MinValue: bound(10), MaxValue: bound(-10)}},
+		{"negative outlier sigma", PatternConfig{OutlierSigma: -1}},
+	}
+	for _, tt := range tests {
+		p := tt.pattern
+		p.Name, p.Regex, p.ValueGroup = "offset", `offset (-?\d+)`, 1
+		if _, err := NewPatternMatcher([]PatternConfig{p}); err == nil {
+			t.Errorf("%s: NewPatternMatcher succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestExtractMetricsFieldNaN(t *testing.T) {
+	lines := timedLines("tie", "", "", "")
+	for i, value := range []string{"12.5", "NaN", "-3"} {
+		lines[i].Fields = map[string]string{"TIE": value}
+	}
+	metrics := extract(t, []PatternConfig{{Name: "tie TIE", Field: "TIE", Regex: `^(.+)$`, ValueGroup: 1, TagFilter: "tie"}}, lines)
+	if got, want := values(metrics["tie TIE"]), []float64{12.5, -3}; !equalValues(got, want) {
+		t.Errorf("values %v, want %v", got, want)
+	}
+}
+
+func TestExtractMetricsSharedRegex(t *testing.T) {
+	// The series of one pattern share its regex, each taking its own capture group
+	const summary = `rms\s+(\d+)\s+max\s+(\d+)\s+freq\s+([-+]?\d+)`
+	lines := timedLines("daemon",
+		"ptp4l[1.0]: rms 12 max 20 freq -100 +/- 5",
+		"ptp4l[2.0]: master offset 3 s2 freq -90",
+		"ptp4l[3.0]: rms 8 max 15 freq -95 +/- 4",
+	)
+	metrics := extract(t, []PatternConfig{
+		{Name: "rms", Regex: summary, ValueGroup: 1},
+		{Name: "max", Regex: summary, ValueGroup: 2},
+		{Name: "offset", Regex: `master offset\s+(-?\d+)`, ValueGroup: 1},
+		{Name: "freq", Regex: summary, ValueGroup: 3},
+	}, lines)
+	want := map[string][]float64{"rms": {12, 8}, "max": {20, 15}, "offset": {3}, "freq": {-100, -95}}
+	for name, w := range want {
+		if got := values(metrics[name]); !equalValues(got, w) {
+			t.Errorf("%s: values %v, want %v", name, got, w)
+		}
+	}
+}
+
+func TestExtractMetricsStateDiscovery(t *testing.T) {
+	lines := timedLines("daemon",
+		"state s2", "state s0", "state FAULT", "state s1", "state s2", "state s3",
+	)
+	tests := []struct {
+		name    string
+		mapping map[string]float64
+		want    map[string]float64
+	}{
+		{
+			// Numbered states keep their numbers only when all states are numbered
+			name: "no mapping",
+			want: map[string]float64{"FAULT": 0, "s0": 1, "s1": 2, "s2": 3, "s3": 4},
+		},
+		{
+			// The configured levels stay; new states follow in order of name
+			name:    "partial mapping",
+			mapping: map[string]float64{"s2": 30},
+			want:    map[string]float64{"s2": 30, "FAULT": 31, "s0": 32, "s1": 33, "s3": 34},
+		},
+		{
+			name:    "full mapping",
+			mapping: map[string]float64{"s0": 0, "s1": 1, "s2": 2, "s3": 3, "FAULT": -1},
+			want:    map[string]float64{"s0": 0, "s1": 1, "s2": 2, "s3": 3, "FAULT": -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PatternConfig{Name: "state", Regex: `state (\S+)`, ValueGroup: 1, StateGroup: 1, StateMapping: tt.mapping}
+			points := extract(t, []PatternConfig{p}, lines)["state"]
+			if len(points) != len(lines) {
+				t.Fatalf("%d points, want %d", len(points), len(lines))
+			}
+			if got := StateLevels(points); !maps.Equal(got, tt.want) {
+				t.Errorf("levels %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverStateMapping(t *testing.T) {
+	tests := []struct {
+		states []string
+		want   map[string]float64
+	}{
+		{[]string{"s2", "s0", "s1"}, map[string]float64{"s0": 0, "s1": 1, "s2": 2}},
+		{[]string{"s3"}, map[string]float64{"s3": 3}},
+		{[]string{"LOCKED", "FREERUN", "HOLDOVER", "LOCKED"}, map[string]float64{"FREERUN": 0, "HOLDOVER": 1, "LOCKED": 2}},
+		{[]string{"s2", "sx"}, map[string]float64{"s2": 0, "sx": 1}},
+	}
+	for _, tt := range tests {
+		if got := DiscoverStateMapping(tt.states); !maps.Equal(got, tt.want) {
+			t.Errorf("DiscoverStateMapping(%v) = %v, want %v", tt.states, got, tt.want)
+		}
+	}
+}
+
+func TestIsStateSeries(t *testing.T) {
+	tests := []struct {
+		name                   string
+		stateGroup, valueGroup int
+		event, extracted       bool
+		want                   bool
+	}{
+		{"state of the value group", 1, 1, false, false, true},
+		{"state of another group", 2, 1, false, false, false},
+		{"no state group", 0, 0, false, false, false},
+		{"event", 1, 1, true, false, false},
+		{"extracted", 1, 1, false, true, false},
+	}
+	for _, tt := range tests {
+		if got := IsStateSeries(tt.stateGroup, tt.valueGroup, tt.event, tt.extracted); got != tt.want {
+			t.Errorf("%s: IsStateSeries = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExtractMetricsEvents(t *testing.T) {
+	lines := timedLines("daemon",
+		"port 1: LISTENING to UNCALIBRATED on INIT_COMPLETE",
+		"master offset 3 s2",
+		"port 1: UNCALIBRATED to SLAVE on MASTER_CLOCK_SELECTED",
+	)
+	p := PatternConfig{Name: "port state", Regex: `port \d+: \S+ to (\S+)`, Event: true, LabelGroup: 1}
+	points := extract(t, []PatternConfig{p}, lines)["port state"]
+	if len(points) != 2 {
+		t.Fatalf("%d events, want 2", len(points))
+	}
+	for i, want := range []string{"UNCALIBRATED", "SLAVE"} {
+		if points[i].State != want || points[i].Value != 0 {
+			t.Errorf("event %d: label %q value %g, want %q and 0", i, points[i].State, points[i].Value, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package pattern
+
+import (
+	"context"
+	"log-interleaver/internal/nmea"
+	"log-interleaver/internal/parser"
+)
+
+// Series of the points of the nmea extractor
+const (
+	NMEAFix        = "fix"         // 1 with a valid fix, 0 without
+	NMEASatellites = "satellites"  // Satellites in use (GGA)
+	NMEATimeOffset = "time_offset" // Timestamp of the line minus the GNSS time of its sentence (s)
+)
+
+// nmeaExtractor extracts the fix status and GNSS time of NMEA sentences and gpsd reports
+type nmeaExtractor struct{}
+
+// ExtractPoints returns the fix status, satellites, and time offset of each sentence as points
+// of the NMEA* series. The sentences of each tag are decoded in order, so GGA sentences are
+// dated by the preceding RMC or ZDA sentences of their receiver.
+func (nmeaExtractor) ExtractPoints(ctx context.Context, lines []*parser.LogLine) ([][]MetricPoint, error) {
+	points := make([][]MetricPoint, len(lines))
+	decoders := make(map[string]*nmea.Decoder)
+	for n, line := range lines {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		decoder, ok := decoders[line.Tag]
+		if !ok {
+			decoder = nmea.NewDecoder()
+			decoders[line.Tag] = decoder
+		}
+		fix, ok := decoder.Decode(line.OriginalLine)
+		if !ok {
+			continue
+		}
+		add := func(series string, value float64) {
+			points[n] = append(points[n], MetricPoint{Time: line.Timestamp.Time, Value: value, SeriesName: series})
+		}
+		if fix.HasFix {
+			valid := 0.0
+			if fix.Valid {
+				valid = 1
+			}
+			add(NMEAFix, valid)
+		}
+		if fix.HasSatellites {
+			add(NMEASatellites, float64(fix.Satellites))
+		}
+		if !fix.Time.IsZero() {
+			add(NMEATimeOffset, line.Timestamp.Time.Sub(fix.Time).Seconds())
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,128 @@
+package pattern
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// DiscoverStateMapping assigns numeric levels to the distinct states of a state series without
+// a configured mapping. States that are all numbered like the servo states (s0, s1, s2) keep
+// their number; any other set of states is sorted by name and numbered from 0, so the same
+// states get the same levels whatever order they appear in.
+func DiscoverStateMapping(states []string) map[string]float64 {
+	mapping := make(map[string]float64, len(states))
+	numbered := true
+	for _, state := range states {
+		number, ok := stateNumber(state)
+		if !ok {
+			numbered = false
+			break
+		}
+		mapping[state] = number
+	}
+	if numbered {
+		return mapping
+	}
+
+	sorted := slices.Clone(states)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	clear(mapping)
+	for level, state := range sorted {
+		mapping[state] = float64(level)
+	}
+	return mapping
+}
+
+// stateNumber returns the number of a numbered state (e.g., "s2" -> 2)
+func stateNumber(state string) (float64, bool) {
+	if len(state) < 2 || state[0] != 's' {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(state[1:], 64)
+	return number, err == nil
+}
+
+// StateLevels returns the level of each state among the points of a state series, e.g., to
+// report the mapping discovered for it (nil if the points have no states)
+func StateLevels(points []MetricPoint) map[string]float64 {
+	var levels map[string]float64
+	for _, pt := range points {
+		if pt.State == "" {
+			continue
+		}
+		if levels == nil {
+			levels = make(map[string]float64)
+		}
+		if _, ok := levels[pt.State]; !ok {
+			levels[pt.State] = pt.Value
+		}
+	}
+	return levels
+}
+
+// IsStateSeries reports whether a pattern is a state series, whose values are the levels of the
+// states of its value group: the state group is the value group, and the pattern is neither an
+// event nor extracted by an extractor. The states missing from its mapping are discovered from
+// the data.
+func IsStateSeries(stateGroup, valueGroup int, event, extracted bool) bool {
+	return stateGroup > 0 && stateGroup == valueGroup && !event && !extracted
+}
+
+// ExtendStateMapping returns a mapping with the levels of states missing from a configured
+// mapping: after the configured levels, in order of name, so the configured states keep their
+// levels. Without a configured mapping, the levels are those of DiscoverStateMapping.
+func ExtendStateMapping(configured map[string]float64, states []string) map[string]float64 {
+	if configured == nil {
+		return DiscoverStateMapping(states)
+	}
+	mapping := maps.Clone(configured)
+	next := 0.0
+	for _, level := range configured {
+		next = max(next, level+1)
+	}
+	sorted := slices.Clone(states)
+	slices.Sort(sorted)
+	for _, state := range slices.Compact(sorted) {
+		if _, ok := mapping[state]; !ok {
+			mapping[state] = next
+			next++
+		}
+	}
+	return mapping
+}
+
+// resetStates starts a match pass with the configured state mappings
+func (pm *PatternMatcher) resetStates() {
+	pm.found = nil
+	for i := range pm.patterns {
+		if pm.patterns[i].DiscoverStates {
+			pm.patterns[i].StateMapping = pm.patterns[i].configuredStates
+		}
+	}
+}
+
+// foundState records a state of a pattern missing from its mapping
+func (pm *PatternMatcher) foundState(k int, state string) {
+	if pm.found == nil {
+		pm.found = make(map[int]map[string]bool)
+	}
+	if pm.found[k] == nil {
+		pm.found[k] = make(map[string]bool)
+	}
+	pm.found[k][state] = true
+}
+
+// discoverStates extends the state mappings by the states found in the match pass, returning the
+// indexes of the patterns whose mappings changed
+func (pm *PatternMatcher) discoverStates() []int {
+	var changed []int
+	for k, states := range pm.found {
+		pattern := &pm.patterns[k]
+		pattern.StateMapping = ExtendStateMapping(pattern.configuredStates, slices.Collect(maps.Keys(states)))
+		changed = append(changed, k)
+	}
+	pm.found = nil
+	return changed
+}
@@ -0,0 +1,143 @@
+package pattern
+
+import (
+	"context"
+	"log-interleaver/internal/parser"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Summary is a periodic statistics line of ptp4l or phc2sys (summary_interval), e.g.:
+//
+//	ptp4l[1234.567]: rms 8 max 15 freq +123 +/- 4 delay 201 +/- 2
+//	phc2sys[1234.567]: CLOCK_REALTIME rms 8 max 15 freq -2034 +/- 3 delay 510 +/- 1
+//
+// The deviations and the delay are missing when the interval had a single sample or no delay
+// measurement.
+type Summary struct {
+	RMS, Max    float64 // Offset statistics (ns)
+	Freq        float64 // Mean frequency adjustment (ppb)
+	FreqDev     float64 // Standard deviation of the frequency adjustment (ppb)
+	Delay       float64 // Mean path delay (ns)
+	DelayDev    float64 // Standard deviation of the path delay (ns)
+	HasFreqDev  bool
+	HasDelay    bool
+	HasDelayDev bool
+}
+
+// ParseSummary parses the statistics of a linuxptp summary line
+func ParseSummary(line string) (Summary, bool) {
+	start := strings.Index(line, " rms ")
+	if start < 0 {
+		return Summary{}, false
+	}
+	fields := strings.Fields(line[start:])
+	number := func(k int) (float64, bool) {
+		if k >= len(fields) {
+			return 0, false
+		}
+		v, err := strconv.ParseFloat(fields[k], 64)
+		return v, err == nil
+	}
+	// deviation parses an optional "+/- value" at k
+	deviation := func(k int) (float64, bool) {
+		if k >= len(fields) || fields[k] != "+/-" {
+			return 0, false
+		}
+		return number(k + 1)
+	}
+
+	var s Summary
+	var ok bool
+	if len(fields) < 6 || fields[0] != "rms" || fields[2] != "max" || fields[4] != "freq" {
+		return Summary{}, false
+	}
+	if s.RMS, ok = number(1); !ok {
+		return Summary{}, false
+	}
+	if s.Max, ok = number(3); !ok {
+		return Summary{}, false
+	}
+	if s.Freq, ok = number(5); !ok {
+		return Summary{}, false
+	}
+	k := 6
+	if s.FreqDev, s.HasFreqDev = deviation(k); s.HasFreqDev {
+		k += 2
+	}
+	if k < len(fields) && fields[k] == "delay" {
+		if s.Delay, s.HasDelay = number(k + 1); s.HasDelay {
+			s.DelayDev, s.HasDelayDev = deviation(k + 2)
+		}
+	}
+	return s, true
+}
+
+// Series of the points of the linuxptp_summary extractor
+const (
+	SummaryRMS      = "rms"
+	SummaryMax      = "max"
+	SummaryFreq     = "freq"
+	SummaryFreqDev  = "freq_dev"
+	SummaryDelay    = "delay"
+	SummaryDelayDev = "delay_dev"
+)
+
+// summaryExtractor extracts the statistics of linuxptp summary lines
+type summaryExtractor struct{}
+
+// ExtractPoints returns the statistics of each summary line as points of the Summary* series
+func (summaryExtractor) ExtractPoints(ctx context.Context, lines []*parser.LogLine) ([][]MetricPoint, error) {
+	points := make([][]MetricPoint, len(lines))
+	for n, line := range lines {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		s, ok := ParseSummary(line.OriginalLine)
+		if !ok {
+			continue
+		}
+		add := func(series string, value float64) {
+			points[n] = append(points[n], MetricPoint{Time: line.Timestamp.Time, Value: value, SeriesName: series})
+		}
+		add(SummaryRMS, s.RMS)
+		add(SummaryMax, s.Max)
+		add(SummaryFreq, s.Freq)
+		if s.HasFreqDev {
+			add(SummaryFreqDev, s.FreqDev)
+		}
+		if s.HasDelay {
+			add(SummaryDelay, s.Delay)
+		}
+		if s.HasDelayDev {
+			add(SummaryDelayDev, s.DelayDev)
+		}
+	}
+	return points, nil
+}
+
+// builtinExtractors are the extractors patterns can select by name instead of a regex
+var builtinExtractors = map[string]MetricExtractor{
+	"chrony":           chronyExtractor{},
+	"linuxptp_summary": summaryExtractor{},
+	"nmea":             nmeaExtractor{},
+}
+
+// BuiltinExtractor returns a built-in extractor by name
+func BuiltinExtractor(name string) (MetricExtractor, bool) {
+	extractor, ok := builtinExtractors[name]
+	return extractor, ok
+}
+
+// BuiltinExtractorNames returns the names of the built-in extractors
+func BuiltinExtractorNames() []string {
+	var names []string
+	for name := range builtinExtractors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
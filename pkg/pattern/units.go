@@ -0,0 +1,52 @@
+package pattern
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unit is a unit of extracted values: its dimension and its size in the dimension's base unit
+type unit struct {
+	dimension string
+	size      float64
+}
+
+// unitNames are the supported units, in the order of error messages
+var unitNames = []string{"ps", "ns", "us", "ms", "s", "ppb", "ppm"}
+
+var units = map[string]unit{
+	"ps":  {"time", 1e-12},
+	"ns":  {"time", 1e-9},
+	"us":  {"time", 1e-6},
+	"ms":  {"time", 1e-3},
+	"s":   {"time", 1},
+	"ppb": {"frequency", 1e-9},
+	"ppm": {"frequency", 1e-6},
+}
+
+// UnitNames returns the supported units of pattern values
+func UnitNames() []string {
+	return unitNames
+}
+
+// UnitScale returns the factor converting values in one unit to another unit of the same
+// dimension (time or frequency)
+func UnitScale(from, to string) (float64, error) {
+	fromUnit, ok := units[from]
+	if !ok {
+		return 0, unknownUnit(from)
+	}
+	toUnit, ok := units[to]
+	if !ok {
+		return 0, unknownUnit(to)
+	}
+	if fromUnit.dimension != toUnit.dimension {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, fromUnit.dimension, to, toUnit.dimension)
+	}
+	return fromUnit.size / toUnit.size, nil
+}
+
+func unknownUnit(name string) error {
+	last := len(unitNames) - 1
+	return fmt.Errorf("invalid unit '%s', expected %s, or %s", name, strings.Join(unitNames[:last], ", "), unitNames[last])
+}
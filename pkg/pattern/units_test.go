@@ -0,0 +1,29 @@
+package pattern
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnitScale(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     float64
+		wantErr  bool
+	}{
+		{"ns", "us", 1e-3, false},
+		{"ps", "ns", 1e-3, false},
+		{"s", "ms", 1e3, false},
+		{"ppm", "ppb", 1e3, false},
+		{"ns", "ns", 1, false},
+		{"ns", "ppb", 0, true},
+		{"ns", "minutes", 0, true},
+		{"", "ns", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := UnitScale(tt.from, tt.to)
+		if (err != nil) != tt.wantErr || math.Abs(got-tt.want) > 1e-12*tt.want {
+			t.Errorf("UnitScale(%q, %q) = %g, %v; want %g, error %v", tt.from, tt.to, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
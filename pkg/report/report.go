@@ -0,0 +1,372 @@
+// Package report defines the analysis result types produced by log-interleaver.
+// The JSON field names are stable so downstream consumers can unmarshal results
+// from the CLI (analyze -format json or yaml) without maintaining parallel type definitions.
+package report
+
+import "time"
+
+// AnalysisReport is the result of analyzing a set of interleaved logs
+type AnalysisReport struct {
+	GeneratedAt           time.Time        `json:"generated_at"`
+	TotalLines            int              `json:"total_lines"`
+	LinesWithTimestamp    int              `json:"lines_with_timestamp"`
+	LinesWithoutTimestamp int              `json:"lines_without_timestamp"`
+	DuplicateLines        int              `json:"duplicate_lines"`      // Duplicate lines dropped with -dedupe
+	StartTime             *time.Time       `json:"start_time,omitempty"` // Earliest resolved timestamp
+	EndTime               *time.Time       `json:"end_time,omitempty"`   // Latest resolved timestamp
+	Tags                  []TagStats       `json:"tags"`
+	Series                []SeriesStats    `json:"series,omitempty"`
+	Checks                []CheckResult    `json:"checks,omitempty"`
+	Events                []Event          `json:"events,omitempty"`
+	GapThresholdSeconds   float64          `json:"gap_threshold_seconds,omitempty"` // Threshold of the "gap" events
+	TimeError             []TimeErrorStats `json:"time_error,omitempty"`            // Time error of the te_report series of the config
+	States                []StateTimeline  `json:"states,omitempty"`                // State transition timelines of the state-mapped series
+	Holdovers             []HoldoverStats  `json:"holdovers,omitempty"`             // Holdover episodes of the state-mapped series
+	Grandmasters          []GMTimeline     `json:"grandmasters,omitempty"`          // Grandmasters selected by the ptp4l instances (BMCA)
+	Stability             []StabilityStats `json:"stability,omitempty"`             // Frequency and phase stability with analyze -stability
+	MTIE                  []MTIEStats      `json:"mtie,omitempty"`                  // MTIE and mask checks with analyze -mtie
+	Spectrum              []SpectrumStats  `json:"spectrum,omitempty"`              // Power spectral density with analyze -spectrum
+	Correlations          []Correlation    `json:"correlations,omitempty"`          // Coupling of series pairs with analyze -correlate
+	Bursts                []Burst          `json:"bursts,omitempty"`                // Largest bursts of error and warning lines
+	BurstCount            int              `json:"burst_count,omitempty"`           // Bursts found, including those not in Bursts
+	BurstGapSeconds       float64          `json:"burst_gap_seconds,omitempty"`     // Maximum spacing of the lines of a burst
+}
+
+// TagStats summarizes the lines of one log tag
+type TagStats struct {
+	Tag                string  `json:"tag"`
+	Lines              int     `json:"lines"`
+	LinesWithTimestamp int     `json:"lines_with_timestamp"`
+	Reordered          int     `json:"reordered"`            // Lines that arrived after a later-timestamped line
+	MaxLatenessSeconds float64 `json:"max_lateness_seconds"` // Largest out-of-order lateness
+	LateBeyondWindow   int     `json:"late_beyond_window"`   // Lines too late for the reorder window
+}
+
+// SeriesStats summarizes one extracted metric series
+type SeriesStats struct {
+	Name       string      `json:"name"`
+	Matches    int         `json:"matches"`          // Lines matched by the pattern
+	Points     int         `json:"points"`           // Points after deduplication
+	Duplicates int         `json:"duplicates"`       // Points collapsed by deduplication
+	Filtered   int         `json:"filtered"`         // Points dropped or clamped by the value bounds and outlier rejection
+	Values     *ValueStats `json:"values,omitempty"` // Value statistics; absent for event series and series without points
+}
+
+// StateTimeline is the timeline of the state transitions of a state-mapped series (e.g., the
+// ptp4l servo state s0/s1/s2 or the DPLL state LOCKED/HOLDOVER/FREERUN)
+type StateTimeline struct {
+	Series            string            `json:"series"`
+	StartTime         time.Time         `json:"start_time"`
+	EndTime           time.Time         `json:"end_time"`
+	InitialState      string            `json:"initial_state"`
+	Transitions       []StateTransition `json:"transitions"`
+	States            []StateDuration   `json:"states"`                         // Time per state, in order of first appearance
+	TimeToLockSeconds *float64          `json:"time_to_lock_seconds,omitempty"` // From the first point to the first locked state; absent if never locked
+	Unlocks           int               `json:"unlocks"`                        // Transitions from a locked to an unlocked state
+}
+
+// StateTransition is a change of state
+type StateTransition struct {
+	Time            time.Time `json:"time"`
+	From            string    `json:"from"`
+	To              string    `json:"to"`
+	DurationSeconds float64   `json:"duration_seconds"` // Time spent in the new state
+}
+
+// StateDuration is the total time spent in a state
+type StateDuration struct {
+	State    string  `json:"state"`
+	Entries  int     `json:"entries"`
+	Seconds  float64 `json:"seconds"`
+	Fraction float64 `json:"fraction"` // Share of the timeline
+}
+
+// HoldoverStats holds the holdover episodes of a state-mapped series
+type HoldoverStats struct {
+	Series         string            `json:"series"`
+	PhaseSeries    string            `json:"phase_series,omitempty"` // Series the phase excursions are measured on
+	PhaseUnit      string            `json:"phase_unit,omitempty"`   // Display unit of the phase series
+	Episodes       []HoldoverEpisode `json:"episodes"`
+	TotalSeconds   float64           `json:"total_seconds"`           // Time spent in holdover
+	LongestSeconds float64           `json:"longest_seconds"`         // Duration of the longest episode
+	MaxExcursion   *float64          `json:"max_excursion,omitempty"` // Largest phase excursion of the episodes, in the unit of the phase series
+}
+
+// HoldoverEpisode is a period a series spent in holdover states
+type HoldoverEpisode struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"` // Exit from holdover, or the last point of the series if still in holdover
+	DurationSeconds float64   `json:"duration_seconds"`
+	EntryState      string    `json:"entry_state,omitempty"`      // State before the holdover
+	ExitState       string    `json:"exit_state,omitempty"`       // State after the holdover; absent if still in holdover
+	MaxExcursion    *float64  `json:"max_excursion,omitempty"`    // Largest deviation of the phase from its value at the start
+	RecoverySeconds *float64  `json:"recovery_seconds,omitempty"` // From the exit to the next locked state; absent if not locked again
+}
+
+// StabilityStats holds the stability (ADEV, MDEV, TDEV) of a time error series over tau
+type StabilityStats struct {
+	Series      string           `json:"series"`
+	Samples     int              `json:"samples"`      // Samples after resampling onto a uniform grid
+	Tau0Seconds float64          `json:"tau0_seconds"` // Sampling interval of the uniform grid
+	Unit        string           `json:"unit"`         // Unit of the time error values and TDEV
+	Points      []StabilityPoint `json:"points"`
+}
+
+// StabilityPoint holds the deviations at one observation interval tau
+type StabilityPoint struct {
+	TauSeconds float64 `json:"tau_seconds"`
+	ADEV       float64 `json:"adev"` // Overlapping Allan deviation (fractional frequency)
+	MDEV       float64 `json:"mdev"` // Modified Allan deviation (fractional frequency)
+	TDEV       float64 `json:"tdev"` // Time deviation, in the unit of the series
+}
+
+// MTIEStats holds the MTIE of the low-frequency time error (dTE_L) of a series and its
+// checks against MTIE masks
+type MTIEStats struct {
+	Series      string       `json:"series"`
+	Samples     int          `json:"samples"`      // Samples after resampling onto a uniform grid
+	Tau0Seconds float64      `json:"tau0_seconds"` // Sampling interval of the uniform grid
+	CutoffHz    float64      `json:"cutoff_hz"`    // Low-pass corner frequency applied before MTIE
+	Points      []MTIEPoint  `json:"points"`
+	Masks       []MaskResult `json:"masks,omitempty"`
+}
+
+// SpectrumStats holds the power spectral density of a time error series, estimated with Welch's
+// method, and its peaks
+type SpectrumStats struct {
+	Series        string          `json:"series"`
+	Samples       int             `json:"samples"`        // Samples after resampling onto a uniform grid
+	Tau0Seconds   float64         `json:"tau0_seconds"`   // Sampling interval of the uniform grid
+	SegmentLength int             `json:"segment_length"` // Samples of each averaged segment
+	Segments      int             `json:"segments"`       // Half-overlapping segments averaged
+	ResolutionHz  float64         `json:"resolution_hz"`  // Spacing of the frequencies
+	Unit          string          `json:"unit"`           // Unit of the time error values; the PSD is in unit²/Hz
+	Points        []SpectrumPoint `json:"points"`
+	Peaks         []SpectrumPeak  `json:"peaks,omitempty"` // Strongest periodic components, the strongest first
+}
+
+// SpectrumPoint holds the power spectral density at one frequency
+type SpectrumPoint struct {
+	FrequencyHz float64 `json:"frequency_hz"`
+	PSD         float64 `json:"psd"`
+}
+
+// SpectrumPeak is a local maximum of a spectrum standing out from its median
+type SpectrumPeak struct {
+	FrequencyHz   float64 `json:"frequency_hz"`
+	PeriodSeconds float64 `json:"period_seconds"`
+	PSD           float64 `json:"psd"`
+}
+
+// Correlation holds the coupling of two series: their Pearson correlation and the lag at which
+// their cross-correlation peaks
+type Correlation struct {
+	SeriesA         string  `json:"series_a"`
+	SeriesB         string  `json:"series_b"`
+	Samples         int     `json:"samples"`          // Samples of the overlap of the series on the common grid
+	IntervalSeconds float64 `json:"interval_seconds"` // Sampling interval of the common grid
+	Pearson         float64 `json:"pearson"`          // Pearson correlation at zero lag
+	LagSeconds      float64 `json:"lag_seconds"`      // Lag of B behind A at the peak of the cross-correlation
+	LagCorrelation  float64 `json:"lag_correlation"`  // Correlation at that lag
+	MaxLagSeconds   float64 `json:"max_lag_seconds"`  // Largest lag searched
+}
+
+// MTIEPoint holds the MTIE at one observation interval tau
+type MTIEPoint struct {
+	TauSeconds float64 `json:"tau_seconds"`
+	MTIENs     float64 `json:"mtie_ns"`
+}
+
+// MaskResult is the outcome of checking an MTIE curve against a mask. A mask passes when
+// every check passes; a mask without checks (the measurement is shorter than the mask range)
+// does not pass.
+type MaskResult struct {
+	Mask   string      `json:"mask"`
+	Pass   bool        `json:"pass"`
+	Limits []MaskLimit `json:"limits"` // Mask definition
+	Checks []MaskCheck `json:"checks"`
+}
+
+// MaskLimit is one point of an MTIE mask
+type MaskLimit struct {
+	TauSeconds float64 `json:"tau_seconds"`
+	LimitNs    float64 `json:"limit_ns"`
+}
+
+// MaskCheck compares the MTIE at one tau against the mask limit
+type MaskCheck struct {
+	TauSeconds float64 `json:"tau_seconds"`
+	MTIENs     float64 `json:"mtie_ns"`
+	LimitNs    float64 `json:"limit_ns"`
+	Pass       bool    `json:"pass"`
+}
+
+// ValueStats summarizes the values of a metric series, in the unit of the series
+type ValueStats struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	StdDev float64 `json:"stddev"` // Sample standard deviation
+}
+
+// CheckResult is the outcome of evaluating one assertion against the logs
+type CheckResult struct {
+	Name    string   `json:"name"`
+	Expr    string   `json:"expr"`
+	Passed  bool     `json:"passed"`
+	Value   *float64 `json:"value,omitempty"` // Measured value, if the check is numeric
+	Message string   `json:"message,omitempty"`
+}
+
+// CheckReport is the outcome of evaluating the assertions of a config with the check command
+type CheckReport struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	Passed      bool          `json:"passed"`
+	Checks      []CheckResult `json:"checks"`
+	Failures    []CheckResult `json:"failures"` // The checks that did not pass
+}
+
+// Event is a notable occurrence detected in the logs (state transition, gap, error burst, ...)
+type Event struct {
+	Time            time.Time `json:"time"`
+	Type            string    `json:"type"`
+	Tag             string    `json:"tag,omitempty"`
+	Series          string    `json:"series,omitempty"`
+	Message         string    `json:"message,omitempty"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"`
+}
+
+// GMTimeline is the sequence of grandmasters selected by the best master clock algorithm of a
+// ptp4l instance
+type GMTimeline struct {
+	Tag            string          `json:"tag"`
+	Instance       string          `json:"instance,omitempty"` // ptp4l config of the instance (e.g., ptp4l.0.config), if logged
+	Changes        int             `json:"changes"`            // Grandmaster changes after the first selection
+	Periods        []GMPeriod      `json:"periods,omitempty"`
+	ForeignMasters []ForeignMaster `json:"foreign_masters,omitempty"` // New foreign masters heard by the ports
+}
+
+// ForeignMaster is a new foreign master heard by a port: the announce messages of another master
+// clock, a candidate of the next BMCA decision
+type ForeignMaster struct {
+	Time         time.Time `json:"time"`
+	Port         string    `json:"port"`          // Port as logged, e.g. "port 1 (ens1f0)"
+	PortIdentity string    `json:"port_identity"` // Port identity of the foreign master (clock identity-port number)
+}
+
+// GMPeriod is a period in which one grandmaster was selected
+type GMPeriod struct {
+	ClockIdentity   string    `json:"clock_identity"`
+	Local           bool      `json:"local,omitempty"` // The local clock was the best master
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"` // Next change, or the last line of the tag
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+// Burst is a cluster of error and warning lines close together in time
+type Burst struct {
+	StartTime       time.Time      `json:"start_time"`
+	EndTime         time.Time      `json:"end_time"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Lines           int            `json:"lines"`
+	Severities      map[string]int `json:"severities"` // Line counts by severity
+	Tags            []string       `json:"tags"`
+	Messages        []BurstMessage `json:"messages"` // Representative messages, most frequent first
+}
+
+// BurstMessage is a representative message of a burst, standing for the lines that differ
+// from it only in numbers
+type BurstMessage struct {
+	Message string `json:"message"`
+	Tag     string `json:"tag"`
+	Count   int    `json:"count"`
+}
+
+// TimeErrorStats summarizes the time error (TE) of an offset series using ITU-T G.8273.2
+// terminology. Values are in the unit of the series (typically ns).
+type TimeErrorStats struct {
+	Series           string      `json:"series"`
+	Samples          int         `json:"samples"`
+	DurationSeconds  float64     `json:"duration_seconds"`
+	CutoffHz         float64     `json:"cutoff_hz"`
+	MaxAbsTE         float64     `json:"max_abs_te"`                   // max|TE|: maximum absolute unfiltered time error
+	MaxAbsTEL        float64     `json:"max_abs_te_l"`                 // max|TE_L|: maximum absolute low-pass filtered time error
+	ConstantTE       float64     `json:"cte"`                          // cTE: mean time error over the measurement period, or the largest window mean
+	DynamicTELPkPk   float64     `json:"dte_l_pk_pk"`                  // dTE_L: peak-to-peak of the low-pass filtered TE
+	DynamicTEHPkPk   float64     `json:"dte_h_pk_pk"`                  // dTE_H: peak-to-peak of the high-pass filtered TE
+	CTEWindowSeconds float64     `json:"cte_window_seconds,omitempty"` // Window of the cTE means (0 = measurement period)
+	CTEWindows       []CTEWindow `json:"cte_windows,omitempty"`
+}
+
+// CTEWindow is the mean time error over one cTE window
+type CTEWindow struct {
+	StartTime time.Time `json:"start_time"`
+	CTE       float64   `json:"cte"`
+}
+
+// TEReport is a PTP performance report over one or more time error series
+type TEReport struct {
+	Title     string           `json:"title"`
+	Standard  string           `json:"standard"`
+	StartTime time.Time        `json:"start_time"`
+	EndTime   time.Time        `json:"end_time"`
+	Series    []TimeErrorStats `json:"series"`
+}
+
+// Summary is a self-contained report of a run for attaching to bug reports: the run
+// metadata, the alignment applied to each tag, and the analysis results
+type Summary struct {
+	Title       string          `json:"title"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Command     string          `json:"command"` // Command line of the run
+	Files       []InputFile     `json:"files"`
+	Offsets     []AppliedOffset `json:"offsets"`
+	Analysis    *AnalysisReport `json:"analysis"`
+}
+
+// InputFile is a log file read in a run
+type InputFile struct {
+	Path string `json:"path"`
+	Tag  string `json:"tag"`
+}
+
+// AppliedOffset is the time offset applied to the lines of a tag
+type AppliedOffset struct {
+	Tag           string  `json:"tag"`
+	OffsetSeconds float64 `json:"offset_seconds"`
+	Source        string  `json:"source"`              // manual, events, first, reference, gnss, timezone, checkpoint, or none
+	DriftPPM      float64 `json:"drift_ppm,omitempty"` // Clock drift corrected on top of the offset (-align-drift)
+	Evidence      string  `json:"evidence,omitempty"`  // What the offset is based on (e.g., "14 of 15 shared event pairs agree on 5h0m0.2s")
+}
+
+// Comparison compares the metric series of two runs, such as before and after a firmware or
+// config change (compare command)
+type Comparison struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Baseline    string             `json:"baseline"`  // Analysis JSON, JSON export, or log directory of the baseline run
+	Candidate   string             `json:"candidate"` // Same for the candidate run
+	Series      []SeriesComparison `json:"series"`
+}
+
+// SeriesComparison compares a series between the runs of a comparison
+type SeriesComparison struct {
+	Name      string          `json:"name"`
+	Baseline  *RunSeriesStats `json:"baseline,omitempty"`  // Absent when the baseline has no values of the series
+	Candidate *RunSeriesStats `json:"candidate,omitempty"` // Absent when the candidate has no values of the series
+	Delta     *RunSeriesStats `json:"delta,omitempty"`     // Candidate minus baseline, when both runs have the series
+}
+
+// RunSeriesStats are the compared statistics of a series in a run, in the unit of the series
+type RunSeriesStats struct {
+	Points            int      `json:"points"`
+	Mean              float64  `json:"mean"`
+	Median            float64  `json:"median"`
+	StdDev            float64  `json:"stddev"`
+	P99               float64  `json:"p99"`
+	MaxAbs            float64  `json:"max_abs"`                        // max|value|, the max|TE| of time error series
+	TimeToLockSeconds *float64 `json:"time_to_lock_seconds,omitempty"` // State-mapped series that locked
+	Unlocks           *int     `json:"unlocks,omitempty"`              // State-mapped series
+}
@@ -0,0 +1,211 @@
+package timestamp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CustomFormat is a user-defined timestamp format, declared either as a Go time layout,
+// a regex with named capture groups, or a regex whose capture group is parsed with a layout
+type CustomFormat struct {
+	layout      string
+	layoutWords int
+	regex       *regexp.Regexp
+	group       int  // Capture group holding the timestamp text when a layout is combined with a regex
+	zoned       bool // Timestamps carry their own zone (UTC offset or zone name in the layout, or Unix epoch)
+}
+
+// NewCustomFormat creates a custom timestamp format.
+//
+// With only a layout, the layout is matched against the leading whitespace-separated
+// fields of the line (e.g., "2006/01/02 15:04:05.000" consumes the first two fields).
+//
+// With a regex and a layout, the capture group named "ts" (or the first capture group)
+// is parsed with the layout.
+//
+// With only a regex, the timestamp is assembled from named capture groups: "year", "month"
+// (number or name), "day", "hour", "minute", "second", "frac" (fractional second digits),
+// or alternatively "unix" (epoch seconds, optionally with a fraction).
+func NewCustomFormat(layout, pattern string) (*CustomFormat, error) {
+	if layout == "" && pattern == "" {
+		return nil, fmt.Errorf("timestamp format requires a layout or a regex")
+	}
+
+	f := &CustomFormat{layout: layout}
+	if layout != "" {
+		f.layoutWords = len(strings.Fields(layout))
+		f.zoned = LayoutHasZone(layout)
+	}
+
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp regex '%s': %w", pattern, err)
+		}
+		f.regex = re
+
+		if layout != "" {
+			if re.NumSubexp() == 0 {
+				return nil, fmt.Errorf("timestamp regex '%s' needs a capture group for the layout", pattern)
+			}
+			f.group = 1
+			if idx := re.SubexpIndex("ts"); idx > 0 {
+				f.group = idx
+			}
+		} else if re.SubexpIndex("unix") < 0 && (re.SubexpIndex("hour") < 0 || re.SubexpIndex("minute") < 0) {
+			return nil, fmt.Errorf("timestamp regex '%s' needs named groups hour and minute (or unix)", pattern)
+		} else {
+			f.zoned = re.SubexpIndex("unix") > 0
+		}
+	}
+
+	return f, nil
+}
+
+// LayoutHasZone reports whether a Go time layout has a zone field (a UTC offset such as -0700 or
+// Z07:00, or a zone name such as MST), so the times it parses are unambiguous instants. The
+// layout is tokenized as the time package does, by formatting the same wall clock in two zones:
+// only zone fields tell them apart, while literal text (e.g., a "Z" suffix) does not.
+func LayoutHasZone(layout string) bool {
+	a := time.Date(2006, 1, 2, 15, 4, 5, 0, time.FixedZone("AAA", 3600))
+	b := time.Date(2006, 1, 2, 15, 4, 5, 0, time.FixedZone("BBB", 7200))
+	return a.Format(layout) != b.Format(layout)
+}
+
+// Parse extracts a timestamp from the line using the custom format
+func (f *CustomFormat) Parse(line string) (*Timestamp, error) {
+	var t time.Time
+	var err error
+
+	switch {
+	case f.regex == nil:
+		t, err = f.parseLayoutPrefix(line)
+	case f.layout != "":
+		matches := f.regex.FindStringSubmatch(line)
+		if matches == nil {
+			return nil, fmt.Errorf("line does not match timestamp regex")
+		}
+		t, err = time.Parse(f.layout, matches[f.group])
+	default:
+		t, err = f.parseGroups(line)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Layouts without a year parse as year 0; assume the current year like ParseAbsolute
+	yearInferred := t.Year() == 0
+	if yearInferred {
+		t = WithYear(t, time.Now().Year())
+	}
+
+	return &Timestamp{
+		Time:         t,
+		Type:         TypeAbsolute,
+		YearInferred: yearInferred,
+		Zoned:        f.zoned,
+	}, nil
+}
+
+// parseLayoutPrefix parses the leading fields of the line with the layout
+func (f *CustomFormat) parseLayoutPrefix(line string) (time.Time, error) {
+	fields := strings.Fields(line)
+	if len(fields) < f.layoutWords {
+		return time.Time{}, fmt.Errorf("line too short for timestamp layout")
+	}
+	layout := strings.Join(strings.Fields(f.layout), " ")
+	return time.Parse(layout, strings.Join(fields[:f.layoutWords], " "))
+}
+
+// parseGroups assembles a timestamp from named capture groups
+func (f *CustomFormat) parseGroups(line string) (time.Time, error) {
+	matches := f.regex.FindStringSubmatch(line)
+	if matches == nil {
+		return time.Time{}, fmt.Errorf("line does not match timestamp regex")
+	}
+	group := func(name string) string {
+		if idx := f.regex.SubexpIndex(name); idx > 0 {
+			return matches[idx]
+		}
+		return ""
+	}
+
+	if unix := group("unix"); unix != "" {
+		secs, err := strconv.ParseFloat(unix, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix timestamp: %w", err)
+		}
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9)).UTC(), nil
+	}
+
+	number := func(name string, def int) (int, error) {
+		s := group(name)
+		if s == "" {
+			return def, nil
+		}
+		return strconv.Atoi(s)
+	}
+
+	year, err := number("year", 0)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid year: %w", err)
+	}
+	month, err := parseMonth(group("month"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, err := number("day", 1)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid day: %w", err)
+	}
+	hour, err := number("hour", 0)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid hour: %w", err)
+	}
+	min, err := number("minute", 0)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid minute: %w", err)
+	}
+	sec, err := number("second", 0)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid second: %w", err)
+	}
+
+	nsec := 0
+	if frac := group("frac"); frac != "" {
+		// Right-pad (or truncate) the fractional digits to nanoseconds
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		frac += strings.Repeat("0", 9-len(frac))
+		if nsec, err = strconv.Atoi(frac); err != nil {
+			return time.Time{}, fmt.Errorf("invalid fractional seconds: %w", err)
+		}
+	}
+
+	return time.Date(year, month, day, hour, min, sec, nsec, time.UTC), nil
+}
+
+// parseMonth parses a month given as a number (1-12) or an English name/abbreviation
+func parseMonth(s string) (time.Month, error) {
+	if s == "" {
+		return time.January, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || n > 12 {
+			return 0, fmt.Errorf("invalid month %d", n)
+		}
+		return time.Month(n), nil
+	}
+	for m := time.January; m <= time.December; m++ {
+		name := m.String()
+		if strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid month '%s'", s)
+}
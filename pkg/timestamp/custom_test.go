@@ -0,0 +1,147 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCustomFormatParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  string
+		pattern string
+		line    string
+		want    time.Time
+		zoned   bool
+	}{
+		{
+			name:   "layout of the leading fields",
+			layout: "2006/01/02 15:04:05.000",
+			line:   "2026/01/11 14:05:54.788 ptp4l[1]: port 1: MASTER to SLAVE",
+			want:   time.Date(2026, 1, 11, 14, 5, 54, 788000000, time.UTC),
+		},
+		{
+			name:   "layout with a UTC offset",
+			layout: "2006-01-02T15:04:05-0700",
+			line:   "2026-01-11T16:05:54+0200 ptp4l[1]: port 1: MASTER to SLAVE",
+			want:   time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+			zoned:  true,
+		},
+		{
+			name:   "layout with a literal Z",
+			layout: "2006-01-02T15:04:05Z",
+			line:   "2026-01-11T14:05:54Z ptp4l[1]: port 1: MASTER to SLAVE",
+			want:   time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+		},
+		{
+			name:    "layout of the first capture group",
+			layout:  "02.01.2006 15:04:05",
+			pattern: `^\[(\S+ \S+)\]`,
+			line:    "[11.01.2026 14:05:54] phc2sys: CLOCK_REALTIME phc offset 12",
+			want:    time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+		},
+		{
+			name:    "layout of the ts capture group",
+			layout:  "2006-01-02 15:04:05",
+			pattern: `^(\w+) (?P<ts>\S+ \S+)`,
+			line:    "INFO 2026-01-11 14:05:54 servo locked",
+			want:    time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+		},
+		{
+			name:    "named groups",
+			pattern: `^(?P<day>\d+)-(?P<month>\w+)-(?P<year>\d{4}) (?P<hour>\d+):(?P<minute>\d+):(?P<second>\d+)\.(?P<frac>\d+)`,
+			line:    "11-January-2026 14:05:54.0005 gnss: fix 3D",
+			want:    time.Date(2026, 1, 11, 14, 5, 54, 500000, time.UTC),
+		},
+		{
+			name:    "month abbreviation and truncated fraction",
+			pattern: `^(?P<year>\d{4}) (?P<month>\w{3}) (?P<day>\d+) (?P<hour>\d+):(?P<minute>\d+):(?P<second>\d+)\.(?P<frac>\d+)`,
+			line:    "2026 jan 11 14:05:54.1234567891 dpll: locked",
+			want:    time.Date(2026, 1, 11, 14, 5, 54, 123456789, time.UTC),
+		},
+		{
+			name:    "unix group",
+			pattern: `ts=(?P<unix>[\d.]+)`,
+			line:    "level=info ts=1768140354.25 msg=locked",
+			want:    time.Date(2026, 1, 11, 14, 5, 54, 250000000, time.UTC),
+			zoned:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewCustomFormat(tt.layout, tt.pattern)
+			if err != nil {
+				t.Fatalf("NewCustomFormat: %v", err)
+			}
+			ts, err := f.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.line, err)
+			}
+			if !ts.Time.Equal(tt.want) {
+				t.Errorf("time %v, want %v", ts.Time, tt.want)
+			}
+			if ts.Type != TypeAbsolute {
+				t.Errorf("type %v, want %v", ts.Type, TypeAbsolute)
+			}
+			if ts.Zoned != tt.zoned {
+				t.Errorf("zoned %v, want %v", ts.Zoned, tt.zoned)
+			}
+		})
+	}
+}
+
+func TestCustomFormatParseNoMatch(t *testing.T) {
+	tests := []struct {
+		layout, pattern, line string
+	}{
+		{"2006/01/02 15:04:05", "", "2026/01/11"},
+		{"2006/01/02 15:04:05", "", "ptp4l[1]: port 1: MASTER to SLAVE"},
+		{"2006-01-02 15:04:05", `^\[(.+?)\]`, "no brackets"},
+		{"", `^(?P<hour>\d+):(?P<minute>\d+) (?P<month>\w+)`, "14:05 Smarch"},
+	}
+	for _, tt := range tests {
+		f, err := NewCustomFormat(tt.layout, tt.pattern)
+		if err != nil {
+			t.Fatalf("NewCustomFormat(%q, %q): %v", tt.layout, tt.pattern, err)
+		}
+		if ts, err := f.Parse(tt.line); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", tt.line, ts.Time)
+		}
+	}
+}
+
+func TestNewCustomFormatInvalid(t *testing.T) {
+	tests := []struct {
+		name, layout, pattern string
+	}{
+		{"empty", "", ""},
+		{"invalid regex", "", `(`},
+		{"layout without a capture group", "15:04:05", `^\d+`},
+		{"groups without hour and minute", "", `^(?P<year>\d{4})`},
+	}
+	for _, tt := range tests {
+		if _, err := NewCustomFormat(tt.layout, tt.pattern); err == nil {
+			t.Errorf("%s: NewCustomFormat(%q, %q) succeeded, want an error", tt.name, tt.layout, tt.pattern)
+		}
+	}
+}
+
+func TestLayoutHasZone(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   bool
+	}{
+		{"2006-01-02 15:04:05", false},
+		{"2006-01-02T15:04:05Z", false},
+		{"Jan _2 15:04:05", false},
+		{"2006-01-02T15:04:05Z07:00", true},
+		{"2006-01-02 15:04:05 -0700", true},
+		{"2006-01-02 15:04:05 MST", true},
+		{time.RFC3339Nano, true},
+	}
+	for _, tt := range tests {
+		if got := LayoutHasZone(tt.layout); got != tt.want {
+			t.Errorf("LayoutHasZone(%q) = %v, want %v", tt.layout, got, tt.want)
+		}
+	}
+}
@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Timestamp represents a parsed timestamp with its type
 type Timestamp struct {
-	Time      time.Time
-	Type      Type
-	UptimeSec float64 // For uptime timestamps, store the uptime value
+	Time         time.Time
+	Type         Type
+	UptimeSec    float64 // For uptime timestamps, store the uptime value
+	YearInferred bool    // The log line carries no year; it was assumed and may be corrected later
+	Zoned        bool    // The log line carries its own zone (UTC offset or epoch); no timezone conversion applies
 }
 
 // Type represents the type of timestamp
@@ -41,13 +44,14 @@ func ParseAbsolute(line string) (*Timestamp, error) {
 	sec, _ := strconv.Atoi(matches[4])
 	micro, _ := strconv.Atoi(matches[5])
 
-	// Assume current year (or we could parse from context)
+	// Assume current year; callers can correct it from context (see parser.InferYears)
 	now := time.Now()
 	t := time.Date(now.Year(), time.Month(month), day, hour, min, sec, micro*1000, time.UTC)
 
 	return &Timestamp{
-		Time: t,
-		Type: TypeAbsolute,
+		Time:         t,
+		Type:         TypeAbsolute,
+		YearInferred: true,
 	}, nil
 }
 
@@ -71,6 +75,27 @@ func ParseUptime(line string) (float64, bool) {
 	return uptime, true
 }
 
+// ParseKernel parses kernel log timestamps (seconds since boot): dmesg "[ 1234.567890] ..."
+// (optionally after a syslog priority like "<6>") or /dev/kmsg records "6,339,5140900,-;..."
+// (microseconds since boot). Returns the time since boot in seconds.
+func ParseKernel(line string) (float64, bool) {
+	// Pattern: [ seconds.micro] at the start of the line
+	re := regexp.MustCompile(`^(?:<\d+>)?\[\s*(\d+\.\d+)\]`)
+	if matches := re.FindStringSubmatch(line); len(matches) == 2 {
+		secs, err := strconv.ParseFloat(matches[1], 64)
+		return secs, err == nil
+	}
+
+	// Pattern: priority,sequence,microseconds,flags;message
+	kmsgRe := regexp.MustCompile(`^\d+,\d+,(\d+),[^;]*;`)
+	if matches := kmsgRe.FindStringSubmatch(line); len(matches) == 2 {
+		micros, err := strconv.ParseInt(matches[1], 10, 64)
+		return float64(micros) / 1e6, err == nil
+	}
+
+	return 0, false
+}
+
 // ParseLinux parses Linux/Unix timestamp format: "T-BC[1768140305]:"
 func ParseLinux(line string) (*Timestamp, error) {
 	// Pattern: [unix_timestamp]:
@@ -115,7 +140,139 @@ func ParseFullDateTime(line string) (*Timestamp, error) {
 	}, nil
 }
 
+// WithYear returns t moved to the given year, keeping month, day, and time of day
+func WithYear(t time.Time, year int) time.Time {
+	return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// ParseRFC3339 parses a leading RFC 3339 timestamp: "2026-01-12T11:36:14.788Z" or
+// "2026-01-12T11:36:14+02:00" (as written by container runtimes and "kubectl logs --timestamps").
+// Timestamps without a zone are taken as wall-clock time.
+func ParseRFC3339(line string) (*Timestamp, error) {
+	// Pattern: YYYY-MM-DDTHH:MM:SS[.frac][Z|±HH:MM]
+	re := regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:[.,]\d+)?)(Z|[+-]\d{2}:?\d{2})?(?:\s|$)`)
+	matches := re.FindStringSubmatch(line)
+	if len(matches) != 3 {
+		return nil, fmt.Errorf("invalid RFC 3339 timestamp format")
+	}
+
+	value := strings.Replace(matches[1], ",", ".", 1)
+	zone := matches[2]
+	if zone == "" {
+		t, err := time.Parse("2006-01-02T15:04:05.999999999", value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RFC 3339 timestamp: %w", err)
+		}
+		return &Timestamp{Time: t, Type: TypeAbsolute}, nil
+	}
+
+	if zone != "Z" && !strings.Contains(zone, ":") {
+		zone = zone[:3] + ":" + zone[3:]
+	}
+	t, err := time.Parse(time.RFC3339Nano, value+zone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RFC 3339 timestamp: %w", err)
+	}
+	return &Timestamp{Time: t.UTC(), Type: TypeAbsolute, Zoned: true}, nil
+}
+
+// ParseEpoch parses a leading epoch timestamp in seconds (with a fraction), milliseconds,
+// microseconds, or nanoseconds: "1768221374788 ptp4l ..." or "1768221374.788123 ...".
+// The unit is inferred from the magnitude; values outside the years 2000-2100 are rejected
+// to avoid mistaking other leading numbers for timestamps.
+func ParseEpoch(line string) (*Timestamp, error) {
+	// Pattern: at least 10 digits, optional fraction, followed by whitespace
+	re := regexp.MustCompile(`^(\d{10,19}(?:\.\d+)?)(?:\s|$)`)
+	matches := re.FindStringSubmatch(line)
+	if len(matches) != 2 {
+		return nil, fmt.Errorf("invalid epoch timestamp format")
+	}
+
+	t, ok := parseEpoch(matches[1])
+	if !ok || t.Year() < 2000 || t.Year() > 2100 {
+		return nil, fmt.Errorf("invalid epoch timestamp '%s'", matches[1])
+	}
+	return &Timestamp{Time: t, Type: TypeLinux}, nil
+}
+
+// ParseValue parses a standalone timestamp value, such as a structured log field. With a layout
+// the value is parsed with it; otherwise RFC 3339 ("2026-01-11T14:05:54.000549Z"), date-time
+// ("2026-01-11 14:05:54.000549"), and epoch numbers are accepted. The unit of epoch numbers
+// (seconds, milliseconds, microseconds, or nanoseconds) is inferred from their magnitude.
+func ParseValue(value, layout string) (*Timestamp, error) {
+	value = strings.TrimSpace(value)
+	if layout != "" {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp '%s' for layout '%s': %w", value, layout, err)
+		}
+		// A layout with a zone field gives instants, including values in UTC ("Z", "+00:00")
+		return &Timestamp{Time: t, Type: TypeAbsolute, Zoned: LayoutHasZone(layout)}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return &Timestamp{Time: t.UTC(), Type: TypeAbsolute, Zoned: true}, nil
+	}
+	if t, err := time.Parse("2006-01-02 15:04:05.999999999", value); err == nil {
+		return &Timestamp{Time: t, Type: TypeAbsolute}, nil
+	}
+	if t, ok := parseEpoch(value); ok {
+		return &Timestamp{Time: t, Type: TypeLinux}, nil
+	}
+
+	return nil, fmt.Errorf("unrecognized timestamp '%s'", value)
+}
+
+// parseEpoch parses an epoch number, inferring its unit from the magnitude
+func parseEpoch(value string) (time.Time, bool) {
+	whole, frac, _ := strings.Cut(value, ".")
+	secs, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || secs < 0 {
+		return time.Time{}, false
+	}
+
+	// Nanoseconds per unit: values beyond year ~5000 in seconds are finer units
+	var unit int64
+	switch {
+	case secs >= 1e17:
+		unit = 1
+	case secs >= 1e14:
+		unit = 1e3
+	case secs >= 1e11:
+		unit = 1e6
+	default:
+		unit = 1e9
+	}
+	nanos := secs * unit
+	if frac != "" {
+		f, err := strconv.ParseFloat("0."+frac, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		nanos += int64(f * float64(unit))
+	}
+	return time.Unix(0, nanos).UTC(), true
+}
+
+// InLocation reinterprets the wall-clock time of t (parsed without a zone) as local time in loc,
+// returning the corresponding instant in UTC. DST transitions are handled by the location rules.
+func InLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc).UTC()
+}
+
+// DefaultOutputFormat is the default Go layout for output timestamps: "14:05:54.000549"
+const DefaultOutputFormat = "15:04:05.000000"
+
 // FormatTimestamp formats a timestamp for output: "14:05:54.000549"
 func FormatTimestamp(t time.Time) string {
-	return t.Format("15:04:05.000000")
+	return t.Format(DefaultOutputFormat)
+}
+
+// FormatTimestampLayout formats a timestamp for output with the given Go layout
+// (e.g., "2006-01-02 15:04:05.000000000"), falling back to DefaultOutputFormat if empty
+func FormatTimestampLayout(t time.Time, layout string) string {
+	if layout == "" {
+		layout = DefaultOutputFormat
+	}
+	return t.Format(layout)
 }
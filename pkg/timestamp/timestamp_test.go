@@ -0,0 +1,240 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	tests := []struct {
+		name string
+		wall time.Time
+		want time.Time
+	}{
+		{"standard time", time.Date(2026, 1, 11, 9, 5, 54, 0, time.UTC), time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC)},
+		{"daylight saving time", time.Date(2026, 7, 11, 9, 5, 54, 0, time.UTC), time.Date(2026, 7, 11, 13, 5, 54, 0, time.UTC)},
+		{"after the spring transition", time.Date(2026, 3, 8, 3, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := InLocation(tt.wall, newYork); !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("%s: InLocation(%v) = %v, want %v", tt.name, tt.wall, got, tt.want)
+		}
+	}
+}
+
+func TestParseKernel(t *testing.T) {
+	tests := []struct {
+		line string
+		want float64
+		ok   bool
+	}{
+		{"[    0.000000] Linux version 5.14.0", 0, true},
+		{"[ 1234.567890] ice 0000:51:00.0: PTP reset successful", 1234.56789, true},
+		{"<6>[  12.5] ice: DPLL locked", 12.5, true},
+		{"6,339,5140900,-;ice 0000:51:00.0: PTP reset successful", 5.1409, true},
+		{"ptp4l[275313.748]: master offset 12", 0, false},
+		{"[1234] not a kernel timestamp", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseKernel(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseKernel(%q) = %v, %v; want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		layout string
+		want   time.Time
+		typ    Type
+		zoned  bool
+	}{
+		{
+			name:  "RFC 3339 in UTC",
+			value: "2026-01-11T14:05:54.000549Z",
+			want:  time.Date(2026, 1, 11, 14, 5, 54, 549000, time.UTC),
+			typ:   TypeAbsolute,
+			zoned: true,
+		},
+		{
+			name:  "RFC 3339 with an offset",
+			value: "2026-01-11T15:05:54+01:00",
+			want:  time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+			typ:   TypeAbsolute,
+			zoned: true,
+		},
+		{
+			name:  "date-time",
+			value: " 2026-01-11 14:05:54.5 ",
+			want:  time.Date(2026, 1, 11, 14, 5, 54, 500000000, time.UTC),
+			typ:   TypeAbsolute,
+		},
+		{
+			name:  "epoch seconds",
+			value: "1768140354",
+			want:  time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+			typ:   TypeLinux,
+		},
+		{
+			name:  "epoch seconds with a fraction",
+			value: "1768140354.25",
+			want:  time.Date(2026, 1, 11, 14, 5, 54, 250000000, time.UTC),
+			typ:   TypeLinux,
+		},
+		{
+			name:  "epoch milliseconds",
+			value: "1768140354788",
+			want:  time.Date(2026, 1, 11, 14, 5, 54, 788000000, time.UTC),
+			typ:   TypeLinux,
+		},
+		{
+			name:  "epoch microseconds",
+			value: "1768140354788123",
+			want:  time.Date(2026, 1, 11, 14, 5, 54, 788123000, time.UTC),
+			typ:   TypeLinux,
+		},
+		{
+			name:  "epoch nanoseconds",
+			value: "1768140354788123456",
+			want:  time.Date(2026, 1, 11, 14, 5, 54, 788123456, time.UTC),
+			typ:   TypeLinux,
+		},
+		{
+			name:   "layout without a zone",
+			value:  "11/01/2026 14:05:54",
+			layout: "02/01/2006 15:04:05",
+			want:   time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+			typ:    TypeAbsolute,
+		},
+		{
+			name:   "layout with a zone",
+			value:  "2026-01-11 16:05:54 +02:00",
+			layout: "2006-01-02 15:04:05 -07:00",
+			want:   time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+			typ:    TypeAbsolute,
+			zoned:  true,
+		},
+		{
+			// An explicit UTC zone is an instant like any other offset
+			name:   "layout with a zone, in UTC",
+			value:  "2026-01-11 14:05:54 +00:00",
+			layout: "2006-01-02 15:04:05 -07:00",
+			want:   time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+			typ:    TypeAbsolute,
+			zoned:  true,
+		},
+		{
+			name:   "layout with a Z zone",
+			value:  "2026-01-11T14:05:54Z",
+			layout: "2006-01-02T15:04:05Z07:00",
+			want:   time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+			typ:    TypeAbsolute,
+			zoned:  true,
+		},
+		{
+			name:   "layout with a literal Z",
+			value:  "2026-01-11T14:05:54Z",
+			layout: "2006-01-02T15:04:05Z",
+			want:   time.Date(2026, 1, 11, 14, 5, 54, 0, time.UTC),
+			typ:    TypeAbsolute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := ParseValue(tt.value, tt.layout)
+			if err != nil {
+				t.Fatalf("ParseValue(%q, %q): %v", tt.value, tt.layout, err)
+			}
+			if !ts.Time.Equal(tt.want) {
+				t.Errorf("time %v, want %v", ts.Time, tt.want)
+			}
+			if ts.Type != tt.typ {
+				t.Errorf("type %v, want %v", ts.Type, tt.typ)
+			}
+			if ts.Zoned != tt.zoned {
+				t.Errorf("zoned %v, want %v", ts.Zoned, tt.zoned)
+			}
+		})
+	}
+}
+
+func TestParseValueInvalid(t *testing.T) {
+	tests := []struct {
+		value, layout string
+	}{
+		{"", ""},
+		{"yesterday", ""},
+		{"-1768140354", ""},
+		{"2026-01-11 14:05", "2006-01-02 15:04:05"},
+	}
+	for _, tt := range tests {
+		if ts, err := ParseValue(tt.value, tt.layout); err == nil {
+			t.Errorf("ParseValue(%q, %q) = %v, want an error", tt.value, tt.layout, ts.Time)
+		}
+	}
+}
+
+func TestParseRFC3339(t *testing.T) {
+	tests := []struct {
+		line  string
+		want  time.Time
+		zoned bool
+	}{
+		{"2026-01-12T11:36:14.788Z stdout F ptp4l[1]: port 1: MASTER to SLAVE", time.Date(2026, 1, 12, 11, 36, 14, 788000000, time.UTC), true},
+		{"2026-01-12T13:36:14+02:00 phc2sys: offset 5", time.Date(2026, 1, 12, 11, 36, 14, 0, time.UTC), true},
+		{"2026-01-12T13:36:14,5+0200 gpsd: fix", time.Date(2026, 1, 12, 11, 36, 14, 500000000, time.UTC), true},
+		{"2026-01-12T11:36:14.000549 chronyd: selected source", time.Date(2026, 1, 12, 11, 36, 14, 549000, time.UTC), false},
+	}
+	for _, tt := range tests {
+		ts, err := ParseRFC3339(tt.line)
+		if err != nil {
+			t.Errorf("ParseRFC3339(%q): %v", tt.line, err)
+			continue
+		}
+		if !ts.Time.Equal(tt.want) || ts.Zoned != tt.zoned {
+			t.Errorf("ParseRFC3339(%q) = %v, zoned %v; want %v, %v", tt.line, ts.Time, ts.Zoned, tt.want, tt.zoned)
+		}
+	}
+
+	for _, line := range []string{"2026-01-12 11:36:14 ptp4l", "2026-01-12T11:36:14.788Zptp4l", "I0112 11:36:14.788 daemon"} {
+		if _, err := ParseRFC3339(line); err == nil {
+			t.Errorf("ParseRFC3339(%q) succeeded, want an error", line)
+		}
+	}
+}
+
+func TestParseEpoch(t *testing.T) {
+	tests := []struct {
+		line string
+		want time.Time
+	}{
+		{"1768221374 ptp4l[1]: port 1: MASTER to SLAVE", time.Date(2026, 1, 12, 12, 36, 14, 0, time.UTC)},
+		{"1768221374.788123 phc2sys: offset 5", time.Date(2026, 1, 12, 12, 36, 14, 788123000, time.UTC)},
+		{"1768221374788 ts2phc: offset 3", time.Date(2026, 1, 12, 12, 36, 14, 788000000, time.UTC)},
+		{"1768221374788123456", time.Date(2026, 1, 12, 12, 36, 14, 788123456, time.UTC)},
+	}
+	for _, tt := range tests {
+		ts, err := ParseEpoch(tt.line)
+		if err != nil {
+			t.Errorf("ParseEpoch(%q): %v", tt.line, err)
+			continue
+		}
+		if !ts.Time.Equal(tt.want) || ts.Type != TypeLinux {
+			t.Errorf("ParseEpoch(%q) = %v, %v; want %v, %v", tt.line, ts.Time, ts.Type, tt.want, TypeLinux)
+		}
+	}
+
+	// Other leading numbers, and epochs outside 2000-2100
+	for _, line := range []string{"12345 packets", "0000000001 seq", "9999999999 far future", "1768221374ms"} {
+		if ts, err := ParseEpoch(line); err == nil {
+			t.Errorf("ParseEpoch(%q) = %v, want an error", line, ts.Time)
+		}
+	}
+}